@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -44,12 +45,31 @@ type ImageBuildSpec struct {
 	// StorageClass is the name of the storage class to use for the build PVC
 	StorageClass string `json:"storageClass,omitempty"`
 
+	// WorkspaceAccessMode is the access mode of the build PVC. ReadWriteMany lets the artifact be
+	// served, published and copied from pods on any node; ReadWriteOnce ties them to the node that
+	// mounts the PVC. When empty, ReadWriteMany is used if the storage class supports it.
+	// +kubebuilder:validation:Enum=ReadWriteOnce;ReadWriteMany
+	// +optional
+	WorkspaceAccessMode string `json:"workspaceAccessMode,omitempty"`
+
+	// WorkspaceProtection keeps the image content of sensitive programs off shared storage in recoverable form
+	// +optional
+	WorkspaceProtection *WorkspaceProtection `json:"workspaceProtection,omitempty"`
+
+	// Signing signs the artifact with cosign once the build completed
+	// +optional
+	Signing *ArtifactSigning `json:"signing,omitempty"`
+
 	// AutomotiveImageBuilder specifies the image to use for building
 	AutomotiveImageBuilder string `json:"automotiveImageBuilder,omitempty"`
 
 	// ManifestConfigMap specifies the name of the ConfigMap containing the manifest configuration
 	ManifestConfigMap string `json:"manifestConfigMap,omitempty"`
 
+	// Source is where the manifest comes from when the manifest ConfigMap does not hold it
+	// +optional
+	Source *BuildSource `json:"source,omitempty"`
+
 	// Publishers defines where to publish the built artifacts
 	Publishers *Publishers `json:"publishers,omitempty"`
 
@@ -74,15 +94,332 @@ type ImageBuildSpec struct {
 	EnvSecretRef string `json:"envSecretRef,omitempty"`
 
 	// Compression specifies the compression algorithm for artifacts
-	// +kubebuilder:validation:Enum=lz4;gzip
+	// +kubebuilder:validation:Enum=lz4;gzip;zstd
 	// +kubebuilder:default=gzip
 	Compression string `json:"compression,omitempty"`
+
+	// FirstBoot is a first-boot provisioning payload embedded into the image or attached as a secondary artifact
+	// +optional
+	FirstBoot *FirstBoot `json:"firstBoot,omitempty"`
+
+	// HardeningProfiles selects hardening profiles (e.g. minimal-services, readonly-rootfs, audit, kernel-sysctl)
+	// applied to the manifest at build time; a compliance report is published next to the image
+	// +optional
+	HardeningProfiles []string `json:"hardeningProfiles,omitempty"`
+
+	// Compliance runs an OpenSCAP evaluation of the built root filesystem after the build
+	// +optional
+	Compliance *ComplianceScan `json:"compliance,omitempty"`
+
+	// SizeBudget limits the size of the built root filesystem; a per-package and per-directory
+	// size breakdown is published next to the image when set
+	// +optional
+	SizeBudget *SizeBudget `json:"sizeBudget,omitempty"`
+
+	// BootTest boots the built image in QEMU after the build and records how long it takes to boot
+	// +optional
+	BootTest *BootTest `json:"bootTest,omitempty"`
+
+	// Debug keeps the build pod running after the build step fails so its workspace can be inspected
+	// +optional
+	Debug *BuildDebug `json:"debug,omitempty"`
+
+	// Webhooks are notified of every phase change of the build
+	// +optional
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+
+	// CommitStatus reports the build as a status of the commit of a GitHub or GitLab repository it
+	// was built for, with the credentials of spec.commitStatus of the OperatorConfig
+	// +optional
+	CommitStatus *CommitStatus `json:"commitStatus,omitempty"`
+
+	// Priority orders the builds waiting for a build slot when spec.osBuilds.maxConcurrentBuilds
+	// of the OperatorConfig is reached; higher-priority builds start first
+	// +kubebuilder:validation:Enum=low;normal;high
+	// +kubebuilder:default=normal
+	// +optional
+	Priority string `json:"priority,omitempty"`
+
+	// Retention deletes the artifacts of the completed build after a while or once newer builds replaced it
+	// +optional
+	Retention *Retention `json:"retention,omitempty"`
+
+	// RemoteFiles are the add_files entries of the manifest whose source_path is an http(s) or s3
+	// URL; the upload pod downloads them into the workspace instead of the client uploading them
+	// +optional
+	RemoteFiles *RemoteFiles `json:"remoteFiles,omitempty"`
+
+	// NetworkExceptions names exceptions of spec.osBuilds.networkPolicy of the OperatorConfig whose
+	// destinations the build pod may reach besides the egress allowlist. The build fails when one of
+	// them is not granted to its namespace.
+	// +optional
+	NetworkExceptions []string `json:"networkExceptions,omitempty"`
+}
+
+// BuildSource is where the manifest of a build comes from instead of its manifest ConfigMap
+type BuildSource struct {
+	// Git is a git repository the build clones into its workspace
+	// +optional
+	Git *GitSource `json:"git,omitempty"`
+}
+
+// GitSource is a manifest in a git repository. Relative add_files source paths of the manifest
+// are resolved against its directory in the repository.
+type GitSource struct {
+	// URL is the http(s) URL of the repository
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// Ref is the branch, tag or commit SHA to build; the default branch when empty
+	// +optional
+	Ref string `json:"ref,omitempty"`
+
+	// Path is the path of the manifest in the repository
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+}
+
+// RemoteFiles lists the files the upload pod downloads before the build starts
+type RemoteFiles struct {
+	// URLs are the http, https or s3 URLs to download, from the remoteFileHosts of the OperatorConfig.
+	// Each is stored in the workspace under remote-files/<host>/<path>, where the build finds it.
+	// +kubebuilder:validation:MinItems=1
+	URLs []string `json:"urls"`
+
+	// SecretRef names a Secret in the namespace of the build with the credentials: token, a bearer
+	// token for http(s) URLs, and AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and optionally
+	// AWS_DEFAULT_REGION and AWS_ENDPOINT_URL for s3 URLs
+	// +optional
+	SecretRef string `json:"secretRef,omitempty"`
+}
+
+// Retention limits how long the workspace holding the artifacts of a completed build is kept. The
+// build itself is kept; its artifacts are no longer served or downloadable.
+type Retention struct {
+	// KeepFor is how long after completion the artifacts are kept, e.g. "72h"
+	// +optional
+	KeepFor *metav1.Duration `json:"keepFor,omitempty"`
+
+	// KeepLast keeps the artifacts of the newest KeepLast completed builds in the namespace whose name
+	// starts with NamePrefix and whose retention names the same prefix; older ones are deleted
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	KeepLast int32 `json:"keepLast,omitempty"`
+
+	// NamePrefix groups the builds KeepLast counts, e.g. "nightly-"
+	// +optional
+	NamePrefix string `json:"namePrefix,omitempty"`
+}
+
+// Webhook is an HTTP endpoint that receives a signed JSON payload when a build changes phase
+type Webhook struct {
+	// URL receives the payload in a POST request
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// SecretRef selects a key of a Secret whose value signs the payload with HMAC-SHA256. The Secret
+	// is looked up in the namespace of the build, or of the operator for OperatorConfig webhooks.
+	// +optional
+	SecretRef *corev1.SecretKeySelector `json:"secretRef,omitempty"`
+}
+
+// CommitStatus is the CI metadata of a build made for a commit of a GitHub or GitLab repository
+type CommitStatus struct {
+	// Provider is github or gitlab
+	// +kubebuilder:validation:Enum=github;gitlab
+	Provider string `json:"provider"`
+
+	// Repository is owner/name on GitHub or the path of the project on GitLab
+	// +kubebuilder:validation:MinLength=1
+	Repository string `json:"repository"`
+
+	// SHA is the commit the status is posted to
+	// +kubebuilder:validation:Pattern=`^[0-9a-f]{7,64}$`
+	SHA string `json:"sha"`
+
+	// Branch is the branch the commit was built on, for builds of a branch rather than of a pull or
+	// merge request. The latest completed build of a branch is its golden build.
+	// +optional
+	Branch string `json:"branch,omitempty"`
+
+	// TargetBranch is the branch a pull or merge request targets; the artifact size, root
+	// filesystem size and boot time of the build are compared with its golden build
+	// +optional
+	TargetBranch string `json:"targetBranch,omitempty"`
+
+	// Context names the status, so builds of several targets report separately; defaults to
+	// automotive/<target>-<architecture>
+	// +optional
+	Context string `json:"context,omitempty"`
+}
+
+// BuildDebug configures how long a failed build pod is kept for debugging
+type BuildDebug struct {
+	// HoldMinutes is how long the pod is kept after the build step fails
+	// +kubebuilder:default=60
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=720
+	// +optional
+	HoldMinutes int32 `json:"holdMinutes,omitempty"`
+}
+
+// WorkspaceProtection requires an encrypted workspace and scrubs it once the build no longer needs it
+type WorkspaceProtection struct {
+	// RequireEncryption fails the build unless the storage class of the workspace encrypts its volumes at rest
+	// +optional
+	RequireEncryption bool `json:"requireEncryption,omitempty"`
+
+	// Scrub overwrites every file of the workspace and then deletes the PVC. Failed and cancelled builds are
+	// scrubbed right away, completed builds once the artifact is no longer served and every publication finished.
+	// +optional
+	Scrub bool `json:"scrub,omitempty"`
+}
+
+// ArtifactSigning selects how the artifact of a completed build is signed with cosign: with a
+// private key stored in a Secret, or keyless with a short-lived Fulcio certificate issued for the
+// service account the signing TaskRun runs as
+type ArtifactSigning struct {
+	// KeySecretRef names a Secret in the namespace of the build holding the cosign private key in
+	// cosign.key and its password, if any, in cosign.password
+	// +optional
+	KeySecretRef string `json:"keySecretRef,omitempty"`
+
+	// Keyless signs with a certificate Fulcio issues for a service account token of the cluster.
+	// Fulcio must trust the service account issuer of the cluster.
+	// +optional
+	Keyless bool `json:"keyless,omitempty"`
+
+	// ServiceAccountName is the service account a keyless signature is issued for. Defaults to the
+	// service account TaskRuns of the namespace run as.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// FulcioURL is the Fulcio instance issuing keyless certificates
+	// +kubebuilder:default="https://fulcio.sigstore.dev"
+	// +optional
+	FulcioURL string `json:"fulcioURL,omitempty"`
+
+	// RekorURL is the transparency log keyless signatures are recorded in. Defaults to the Rekor of
+	// spec.osBuilds.signing of the OperatorConfig, else https://rekor.sigstore.dev. Key-based
+	// signatures are only recorded in the Rekor of the OperatorConfig.
+	// +optional
+	RekorURL string `json:"rekorURL,omitempty"`
+}
+
+// BootTest configures the QEMU boot of the built image and the boot time threshold
+type BootTest struct {
+	// TimeoutSeconds is how long the image may take to reach the ready marker
+	// +kubebuilder:default=300
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// ReadyMarker is an extended regular expression matched on the serial console that marks the
+	// end of boot. Defaults to the multi-user target being reached or a login prompt.
+	// +optional
+	ReadyMarker string `json:"readyMarker,omitempty"`
+
+	// MaxKernelToReady is the largest allowed time from the kernel banner to the ready marker (e.g. "15s")
+	// +optional
+	MaxKernelToReady string `json:"maxKernelToReady,omitempty"`
+
+	// Enforce fails the build when the image does not boot or exceeds MaxKernelToReady
+	// +optional
+	Enforce bool `json:"enforce,omitempty"`
+}
+
+// SizeBudget is the largest root filesystem an image may ship and what happens when it is exceeded
+type SizeBudget struct {
+	// MaxSize is the largest allowed size of the installed root filesystem content
+	// Example: "1536Mi"
+	MaxSize string `json:"maxSize"`
+
+	// Action taken when the budget is exceeded: Fail marks the build Failed, Warn only reports it
+	// +kubebuilder:validation:Enum=Fail;Warn
+	// +kubebuilder:default=Fail
+	// +optional
+	Action string `json:"action,omitempty"`
+}
+
+// ComplianceScan selects the OpenSCAP profile the built image is evaluated against
+type ComplianceScan struct {
+	// Profile is the XCCDF profile ID or its short name (e.g. "cis", "ospp")
+	Profile string `json:"profile"`
+
+	// DataStream is the path of the SCAP source data stream in the build image.
+	// Defaults to the scap-security-guide content matching the image OS (e.g. ssg-cs9-ds.xml).
+	// +optional
+	DataStream string `json:"dataStream,omitempty"`
+
+	// Enforce fails the build when the scan does not pass
+	// +optional
+	Enforce bool `json:"enforce,omitempty"`
+}
+
+// FirstBoot defines a first-boot provisioning payload. Exactly one of Inline, ConfigMapRef or FileName must be set.
+type FirstBoot struct {
+	// Format of the payload
+	// +kubebuilder:validation:Enum=ignition;cloud-init;combustion
+	Format string `json:"format"`
+
+	// Mode selects whether the payload is written into the image (embed) or published next to it (attach)
+	// +kubebuilder:validation:Enum=attach;embed
+	// +kubebuilder:default=attach
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// Inline holds the payload content
+	// +optional
+	Inline string `json:"inline,omitempty"`
+
+	// ConfigMapRef selects a key of a ConfigMap in the build namespace holding the payload
+	// +optional
+	ConfigMapRef *corev1.ConfigMapKeySelector `json:"configMapRef,omitempty"`
+
+	// FileName is the name of an uploaded file in the shared workspace holding the payload
+	// +optional
+	FileName string `json:"fileName,omitempty"`
 }
 
 // Publishers defines the configuration for artifact publishing
 type Publishers struct {
-	// Registry configuration for publishing to an OCI registry
+	// Registry configuration for publishing to an OCI registry; it is published as a target named registry
 	Registry *RegistryPublisher `json:"registry,omitempty"`
+
+	// Targets receive the artifact once the build completed. All targets are published at the
+	// same time and report their outcome independently in status.publications.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=10
+	// +optional
+	Targets []PublishTarget `json:"targets,omitempty"`
+}
+
+// PublishTarget is a destination of the artifact; exactly one of registry, s3 and pxe is set
+// +kubebuilder:validation:XValidation:rule="[has(self.registry), has(self.s3), has(self.pxe)].filter(x, x).size() == 1",message="exactly one of registry, s3 and pxe must be set"
+type PublishTarget struct {
+	// Name identifies the target in status.publications
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=30
+	Name string `json:"name"`
+
+	// Registry pushes the artifact to an OCI registry with ORAS
+	// +optional
+	Registry *RegistryPublisher `json:"registry,omitempty"`
+
+	// S3 uploads the artifact to an S3 compatible bucket
+	// +optional
+	S3 *S3Publisher `json:"s3,omitempty"`
+
+	// PXE uploads the artifact to a PXE boot server with HTTP PUT
+	// +optional
+	PXE *PXEPublisher `json:"pxe,omitempty"`
+
+	// Retries is how often a failed publication is retried before the target is reported as failed
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +kubebuilder:default=2
+	// +optional
+	Retries *int32 `json:"retries,omitempty"`
 }
 
 // RegistryPublisher defines the configuration for publishing to an OCI registry
@@ -92,11 +429,54 @@ type RegistryPublisher struct {
 
 	// Secret is the name of the secret containing registry credentials
 	Secret string `json:"secret"`
+
+	// ImmutableTags are shell patterns of release tags that are never overwritten, e.g. v* or
+	// release-*. Publishing to a tag that matches one and already exists in the repository fails
+	// without retries, and the Published condition reports ImmutableTagExists; publish a new version
+	// instead.
+	// +kubebuilder:validation:MaxItems=20
+	// +kubebuilder:validation:items:Pattern=`^[A-Za-z0-9_.*?-]+$`
+	// +kubebuilder:validation:items:MaxLength=128
+	// +optional
+	ImmutableTags []string `json:"immutableTags,omitempty"`
+}
+
+// S3Publisher defines the configuration for uploading to an S3 compatible bucket
+type S3Publisher struct {
+	// Bucket receives the artifact
+	Bucket string `json:"bucket"`
+
+	// Prefix is prepended to the artifact file name to form the object key, e.g. releases/2026.10
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Endpoint is the URL of S3 compatible storage other than AWS, e.g. MinIO or Ceph RGW
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Region of the bucket
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// Secret is the name of the secret holding AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+	Secret string `json:"secret"`
+}
+
+// PXEPublisher defines the configuration for uploading to a PXE boot server
+type PXEPublisher struct {
+	// URL of the directory the artifact is uploaded to; the file name is appended
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// Secret is the name of a secret whose token key is sent as bearer token
+	// +optional
+	Secret string `json:"secret,omitempty"`
 }
 
 // ImageBuildStatus defines the observed state of ImageBuild
 type ImageBuildStatus struct {
-	// Phase represents the current phase of the build (Building, Completed, Failed)
+	// Phase represents the current phase of the build (Queued, Uploading, Building, Completed, Failed, Cancelled)
 	Phase string `json:"phase,omitempty"`
 
 	// StartTime is when the build started
@@ -108,20 +488,461 @@ type ImageBuildStatus struct {
 	// Message provides more detail about the current phase
 	Message string `json:"message,omitempty"`
 
+	// QueuePosition is the position of the build among the builds waiting for a build slot,
+	// starting at 1; unset once the build got a slot
+	QueuePosition int32 `json:"queuePosition,omitempty"`
+
 	// PVCName is the name of the PVC where the artifact is stored
 	PVCName string `json:"pvcName,omitempty"`
 
+	// WorkspaceAccessMode is the access mode chosen for the build PVC
+	WorkspaceAccessMode string `json:"workspaceAccessMode,omitempty"`
+
+	// WorkspaceScrub attests to the scrub of the workspace requested by spec.workspaceProtection
+	WorkspaceScrub *WorkspaceScrubStatus `json:"workspaceScrub,omitempty"`
+
+	// BuildRecord is the record of the build in the history of its namespace, kept when the
+	// OperatorConfig sets spec.osBuilds.buildRecords
+	BuildRecord *BuildRecordStatus `json:"buildRecord,omitempty"`
+
+	// Signature records the cosign signature of the artifact requested by spec.signing
+	Signature *ArtifactSignatureStatus `json:"signature,omitempty"`
+
+	// ArtifactArchive reports the move of the artifacts to the archive storage class of the
+	// OperatorConfig; once archived, status.pvcName is the archive PVC
+	ArtifactArchive *ArtifactArchiveStatus `json:"artifactArchive,omitempty"`
+
 	// ArtifactPath is the path inside the PVC where the artifact is stored
 	ArtifactPath string `json:"artifactPath,omitempty"`
 
 	// ArtifactFileName is the name of the artifact file inside the PVC
 	ArtifactFileName string `json:"artifactFileName,omitempty"`
 
+	// ArtifactSizeBytes is the size of the final (compressed) artifact
+	ArtifactSizeBytes int64 `json:"artifactSizeBytes,omitempty"`
+
+	// ArtifactSHA256 is the hex encoded SHA-256 digest of the final (compressed) artifact
+	ArtifactSHA256 string `json:"artifactSHA256,omitempty"`
+
+	// ArtifactDigests maps the other files of the build, e.g. the parts of the artifact and its
+	// reports, to their hex encoded SHA-256, as the build API computed them while serving them
+	ArtifactDigests map[string]string `json:"artifactDigests,omitempty"`
+
 	// TaskRunName is the name of the active TaskRun for this build
 	TaskRunName string `json:"taskRunName,omitempty"`
 
+	// NetworkPolicyName is the NetworkPolicy restricting the egress of the pods of the build, when
+	// spec.osBuilds.networkPolicy of the OperatorConfig is set
+	NetworkPolicyName string `json:"networkPolicyName,omitempty"`
+
 	// ArtifactURL is the route URL created to expose the artifacts
 	ArtifactURL string `json:"artifactURL,omitempty"`
+
+	// Downloads counts the downloads of the artifacts through the build API
+	Downloads *ArtifactDownloadStatus `json:"downloads,omitempty"`
+
+	// StageTimings records how long each stage of the build step took (e.g. "build": "12m3s", "package": "41s")
+	StageTimings map[string]string `json:"stageTimings,omitempty"`
+
+	// FirstBootFileName is the secondary artifact holding the attached first-boot payload
+	FirstBootFileName string `json:"firstBootFileName,omitempty"`
+
+	// HardeningReportFileName is the secondary artifact holding the hardening compliance report
+	HardeningReportFileName string `json:"hardeningReportFileName,omitempty"`
+
+	// PackagesFileName is the inventory of the RPMs installed in the image, which the SBOM of the
+	// build is generated from
+	PackagesFileName string `json:"packagesFileName,omitempty"`
+
+	// GitCommit is the commit spec.source.git.ref resolved to, which the build was made from
+	GitCommit string `json:"gitCommit,omitempty"`
+
+	// Compliance holds the outcome of the OpenSCAP scan requested by spec.compliance
+	Compliance *ComplianceStatus `json:"compliance,omitempty"`
+
+	// Size holds the measured root filesystem size when spec.sizeBudget is set
+	Size *SizeStatus `json:"size,omitempty"`
+
+	// Boot holds the outcome of the boot test requested by spec.bootTest
+	Boot *BootTestStatus `json:"boot,omitempty"`
+
+	// Debug is set while a failed build pod is kept for debugging
+	Debug *DebugStatus `json:"debug,omitempty"`
+
+	// Environment records the node and tools the build ran with, once its TaskRun finished
+	Environment *BuildEnvironment `json:"environment,omitempty"`
+
+	// LogArchive describes the compressed logs of the build pod, kept once its TaskRun finished
+	LogArchive *LogArchiveStatus `json:"logArchive,omitempty"`
+
+	// Retention reports when spec.retention deletes, or deleted, the artifacts of the build
+	Retention *RetentionStatus `json:"retention,omitempty"`
+
+	// CommitStatus is the last commit status posted for spec.commitStatus
+	CommitStatus *CommitStatusReport `json:"commitStatus,omitempty"`
+
+	// Plugins records the outcome of the notifier, publisher and scanner plugins called for the finished build
+	Plugins []PluginResult `json:"plugins,omitempty"`
+
+	// Publications report the publication of the artifact to each target of spec.publishers
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Publications []PublicationStatus `json:"publications,omitempty"`
+
+	// Conditions describe additional aspects of the build; ComplianceScanPassed reports the scan outcome,
+	// SizeWithinBudget the size budget check, BootTestPassed the boot test, Published the publications,
+	// WorkspaceStorageReady whether the storage class supports the workspace and WorkspaceScrubbed its scrub
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ComplianceStatus is the outcome of an OpenSCAP scan and where its results are stored
+type ComplianceStatus struct {
+	// Profile is the evaluated profile
+	Profile string `json:"profile"`
+
+	// Result is pass, fail or error
+	Result string `json:"result"`
+
+	// ARFFileName is the secondary artifact holding the ARF results
+	ARFFileName string `json:"arfFileName,omitempty"`
+
+	// ReportFileName is the secondary artifact holding the HTML report
+	ReportFileName string `json:"reportFileName,omitempty"`
+}
+
+// BuildEnvironment describes where and with what a build ran, so builds that behave differently on
+// two clusters or nodes can be compared. Fields that could not be read are empty.
+type BuildEnvironment struct {
+	// NodeName is the node the build pod ran on
+	NodeName string `json:"nodeName,omitempty"`
+
+	// KernelVersion, OSImage and ContainerRuntime describe the node as its status reports them
+	KernelVersion    string `json:"kernelVersion,omitempty"`
+	OSImage          string `json:"osImage,omitempty"`
+	ContainerRuntime string `json:"containerRuntime,omitempty"`
+
+	// BuilderImage is the automotive-image-builder image of the build step
+	BuilderImage string `json:"builderImage,omitempty"`
+
+	// BuilderImageDigest is the image the node ran for BuilderImage, by digest
+	BuilderImageDigest string `json:"builderImageDigest,omitempty"`
+
+	// OSBuildVersion and AIBVersion are the osbuild and automotive-image-builder versions the
+	// build step reported
+	OSBuildVersion string `json:"osbuildVersion,omitempty"`
+	AIBVersion     string `json:"aibVersion,omitempty"`
+
+	// SELinux is the SELinux mode of the node seen by the build step: Enforcing, Permissive or Disabled
+	SELinux string `json:"selinux,omitempty"`
+
+	// Sysctls are the kernel settings osbuild depends on, as the build step read them
+	Sysctls map[string]string `json:"sysctls,omitempty"`
+}
+
+// LogArchiveStatus describes the gzip archive of the step logs of a build, split into the
+// ConfigMaps <build>-logs-0 to <build>-logs-<parts-1>
+type LogArchiveStatus struct {
+	// Parts is the number of ConfigMaps holding the archive
+	Parts int32 `json:"parts"`
+
+	// Size is the size of the archive in bytes
+	Size int64 `json:"size"`
+
+	// LogSize is the size of the logs before compression
+	LogSize int64 `json:"logSize"`
+
+	// SHA256 is the hex encoded SHA-256 of the archive
+	SHA256 string `json:"sha256"`
+
+	// Truncated is set when the end of the logs did not fit in the archive
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// CommitStatusReport is the last commit status posted for a build
+type CommitStatusReport struct {
+	// State is pending, running, success, failure, error or cancelled
+	State string `json:"state"`
+
+	// Description is the summary posted with the state, with the deltas to the golden build
+	Description string `json:"description,omitempty"`
+
+	// GoldenBuild is the golden build of spec.commitStatus.targetBranch the build was compared with
+	GoldenBuild string `json:"goldenBuild,omitempty"`
+
+	// Error is why the status could not be posted; empty once it was
+	Error string `json:"error,omitempty"`
+
+	// UpdateTime is when the status was last posted or tried
+	UpdateTime *metav1.Time `json:"updateTime,omitempty"`
+}
+
+// BootTestStatus is the outcome of booting the built image in QEMU
+type BootTestStatus struct {
+	// Result is booted, timeout or error
+	Result string `json:"result"`
+
+	// Timings are the measured boot durations: firmware (QEMU start to kernel banner),
+	// kernelToReady (kernel banner to ready marker) and total
+	Timings map[string]string `json:"timings,omitempty"`
+
+	// Accelerator is kvm or tcg; timings measured with tcg (emulation) are not comparable to real hardware
+	Accelerator string `json:"accelerator,omitempty"`
+
+	// ConsoleLogFileName is the secondary artifact holding the serial console output
+	ConsoleLogFileName string `json:"consoleLogFileName,omitempty"`
+}
+
+// PublicationStatus is the progress of publishing the artifact to one target
+type PublicationStatus struct {
+	// Name is the name of the target
+	Name string `json:"name"`
+
+	// Type is registry, s3 or pxe
+	Type string `json:"type"`
+
+	// Phase is Publishing, Succeeded or Failed
+	Phase string `json:"phase"`
+
+	// Attempts is the number of publications started, including retries
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// TaskRunName is the TaskRun of the latest attempt
+	TaskRunName string `json:"taskRunName,omitempty"`
+
+	// Message describes the outcome of the latest attempt
+	Message string `json:"message,omitempty"`
+
+	// Reason is ImmutableTag when the target refused the artifact because it would overwrite an
+	// immutable tag; such publications are not retried
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Location is where the artifact was published, e.g. an image reference or object URL
+	Location string `json:"location,omitempty"`
+
+	// CompletionTime is when the target succeeded or finally failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// RetentionStatus reports the retention of the artifacts of a completed build
+type RetentionStatus struct {
+	// ExpiryTime is when spec.retention.keepFor deletes the artifacts
+	// +optional
+	ExpiryTime *metav1.Time `json:"expiryTime,omitempty"`
+
+	// Reason is KeepFor or KeepLast once the retention policy expired the artifacts
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// DeletionTime is when the artifacts were deleted
+	// +optional
+	DeletionTime *metav1.Time `json:"deletionTime,omitempty"`
+}
+
+// WorkspaceScrubStatus records how the workspace of a build was scrubbed
+type WorkspaceScrubStatus struct {
+	// Phase is Scrubbing, Scrubbed or Failed
+	Phase string `json:"phase"`
+
+	// PVCName is the scrubbed PVC, kept after status.pvcName is cleared
+	PVCName string `json:"pvcName"`
+
+	// TaskRunName is the TaskRun overwriting the workspace
+	TaskRunName string `json:"taskRunName,omitempty"`
+
+	// Method describes how files were overwritten
+	Method string `json:"method,omitempty"`
+
+	// FilesScrubbed is the number of files overwritten and removed
+	FilesScrubbed int64 `json:"filesScrubbed,omitempty"`
+
+	// BytesScrubbed is the total size of the overwritten files
+	BytesScrubbed int64 `json:"bytesScrubbed,omitempty"`
+
+	// StartTime is when the scrub started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the workspace was found empty and the PVC deleted, or the scrub failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Message describes the outcome
+	Message string `json:"message,omitempty"`
+}
+
+// BuildRecordStatus locates the record of a build in the history of its namespace
+type BuildRecordStatus struct {
+	// Sequence is the position of the record in the history, from 1
+	Sequence int64 `json:"sequence"`
+
+	// Digest is the SHA-256 of the record, which covers the digest of the record before it
+	Digest string `json:"digest"`
+
+	// Segment is the ConfigMap holding the record
+	Segment string `json:"segment"`
+
+	// RekorLogIndex is the index of the entry of the digest in the Rekor log
+	// +optional
+	RekorLogIndex *int64 `json:"rekorLogIndex,omitempty"`
+
+	// RekorUUID is the UUID of the entry of the digest in the Rekor log
+	// +optional
+	RekorUUID string `json:"rekorUUID,omitempty"`
+
+	// AnchorMessage is why the digest is not in the Rekor log yet
+	// +optional
+	AnchorMessage string `json:"anchorMessage,omitempty"`
+}
+
+// ArtifactSignatureStatus records the cosign signature of the artifact of a build and who signed it
+type ArtifactSignatureStatus struct {
+	// Phase is Signing, Signed or Failed
+	Phase string `json:"phase"`
+
+	// Method is key or keyless
+	Method string `json:"method"`
+
+	// TaskRunName is the TaskRun signing the artifact
+	TaskRunName string `json:"taskRunName,omitempty"`
+
+	// BundleFileName is the cosign bundle next to the artifact in the workspace
+	BundleFileName string `json:"bundleFileName,omitempty"`
+
+	// Identity is the signer: the SHA-256 fingerprint of the public key, or the subject of the
+	// Fulcio certificate of a keyless signature
+	Identity string `json:"identity,omitempty"`
+
+	// Issuer is the OIDC issuer of the identity of a keyless signature
+	Issuer string `json:"issuer,omitempty"`
+
+	// StartTime is when signing started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the artifact was signed, or signing failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Message describes the outcome
+	Message string `json:"message,omitempty"`
+}
+
+// ArtifactArchiveStatus reports the move of the artifacts of a completed build from its workspace
+// PVC to a PVC of the archive storage class, and the downloads that mounted the archive again
+type ArtifactArchiveStatus struct {
+	// Phase is Archiving, Archived or Failed
+	Phase string `json:"phase"`
+
+	// StorageClass is the storage class of the archive PVC
+	StorageClass string `json:"storageClass"`
+
+	// PVCName is the archive PVC the artifacts are copied to
+	PVCName string `json:"pvcName"`
+
+	// TaskRunName is the TaskRun copying the artifacts
+	TaskRunName string `json:"taskRunName,omitempty"`
+
+	// Bytes is the total size of the archived files
+	Bytes int64 `json:"bytes,omitempty"`
+
+	// StartTime is when the copy started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the workspace PVC was replaced by the archive PVC, or the copy failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// RehydrationTime is when a download last started the artifact pod on the archive PVC
+	RehydrationTime *metav1.Time `json:"rehydrationTime,omitempty"`
+
+	// Message describes the outcome
+	Message string `json:"message,omitempty"`
+}
+
+// ArtifactDownloadStatus records who downloaded the artifacts of a build. A download is counted
+// when a file is served from its first byte, so resumed and ranged requests do not count again.
+type ArtifactDownloadStatus struct {
+	// Count is the number of downloads of any file of the build
+	Count int64 `json:"count"`
+
+	// LastDownloadTime is when a file of the build was last downloaded
+	LastDownloadTime *metav1.Time `json:"lastDownloadTime,omitempty"`
+
+	// Users counts the downloads of each user, the most recent downloader first; the least recent
+	// are dropped beyond 50 users
+	Users []ArtifactDownloader `json:"users,omitempty"`
+
+	// Recent are the latest 20 downloads, the most recent first
+	Recent []ArtifactDownload `json:"recent,omitempty"`
+}
+
+// ArtifactDownloader is how often one user downloaded the artifacts of a build
+type ArtifactDownloader struct {
+	// User is the Kubernetes user of the download request
+	User string `json:"user"`
+
+	// Count is the number of downloads of the user
+	Count int64 `json:"count"`
+
+	// LastDownloadTime is the latest download of the user
+	LastDownloadTime metav1.Time `json:"lastDownloadTime"`
+}
+
+// ArtifactDownload is one download of a file of a build
+type ArtifactDownload struct {
+	// User is the Kubernetes user of the download request
+	User string `json:"user"`
+
+	// File is the downloaded file
+	File string `json:"file"`
+
+	// Time is when the download started
+	Time metav1.Time `json:"time"`
+
+	// Bytes is how much of the file was sent; it is less than the file size for interrupted downloads
+	Bytes int64 `json:"bytes,omitempty"`
+}
+
+// DebugStatus locates a build pod kept for debugging
+type DebugStatus struct {
+	// PodName is the held build pod
+	PodName string `json:"podName"`
+
+	// HeldUntil is when the pod is released and the build finishes as Failed
+	HeldUntil metav1.Time `json:"heldUntil"`
+}
+
+// PluginResult is the outcome of calling one operator plugin for a finished build
+type PluginResult struct {
+	// Plugin is the configured plugin name
+	Plugin string `json:"plugin"`
+
+	// Kind is notifier, publisher or scanner
+	Kind string `json:"kind"`
+
+	// Succeeded is false when the call failed or a scan did not pass
+	Succeeded bool `json:"succeeded"`
+
+	// Message is the error, publish message or scan summary
+	Message string `json:"message,omitempty"`
+
+	// Location is where a publisher published the artifact
+	Location string `json:"location,omitempty"`
+}
+
+// SizeStatus is the measured size of the built root filesystem against spec.sizeBudget
+type SizeStatus struct {
+	// RootFSBytes is the size of the installed root filesystem content
+	RootFSBytes int64 `json:"rootfsBytes,omitempty"`
+
+	// BudgetBytes is spec.sizeBudget.maxSize in bytes
+	BudgetBytes int64 `json:"budgetBytes"`
+
+	// Exceeded is true when RootFSBytes is larger than BudgetBytes
+	Exceeded bool `json:"exceeded,omitempty"`
+
+	// ReportFileName is the secondary artifact holding the per-package and per-directory breakdown
+	ReportFileName string `json:"reportFileName,omitempty"`
 }
 
 // +kubebuilder:object:root=true
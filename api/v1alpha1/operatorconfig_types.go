@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -55,6 +56,214 @@ type OperatorConfigSpec struct {
 	// OSBuilds defines the configuration for OS build operations
 	// +optional
 	OSBuilds *OSBuildsConfig `json:"osBuilds,omitempty"`
+
+	// Maintenance puts the build API into maintenance mode
+	// +optional
+	Maintenance *MaintenanceConfig `json:"maintenance,omitempty"`
+
+	// RateLimit bounds how much of the build API each client may use
+	// +optional
+	RateLimit *RateLimitConfig `json:"rateLimit,omitempty"`
+
+	// TrustedProxies are the addresses or CIDRs of proxies in front of the build API, such as the
+	// ingress controller, whose X-Forwarded-For header identifies clients for rate limits and audit
+	// events. The OAuth proxy in the build API pod is always trusted; the header is ignored otherwise.
+	// +optional
+	TrustedProxies []string `json:"trustedProxies,omitempty"`
+
+	// Audit records every mutating build API call: who, when, on which build, from where and its outcome
+	// +optional
+	Audit *AuditConfig `json:"audit,omitempty"`
+
+	// OIDC lets the build API accept tokens of an OIDC issuer such as Keycloak or Dex in addition to
+	// Kubernetes tokens, for clusters without OpenShift OAuth
+	// +optional
+	OIDC *OIDCConfig `json:"oidc,omitempty"`
+
+	// BuildNamespaces are the namespaces clients may create and use builds in besides the operator
+	// namespace, selected with the namespace parameter of the build API; "*" allows every namespace.
+	// Callers also need permissions on ImageBuilds in the namespace.
+	// +optional
+	BuildNamespaces []string `json:"buildNamespaces,omitempty"`
+
+	// Webhooks are notified of every phase change of every build, in addition to the webhooks of the build
+	// +optional
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+
+	// CommitStatus holds the credentials builds with spec.commitStatus post commit statuses with
+	// +optional
+	CommitStatus *CommitStatusConfig `json:"commitStatus,omitempty"`
+
+	// DrainPeriodSeconds is how long a stopping build API replica, e.g. during a rolling update, lets
+	// in-flight uploads, downloads and log streams finish before closing their connections.
+	// Default: 30
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=3600
+	// +optional
+	DrainPeriodSeconds int32 `json:"drainPeriodSeconds,omitempty"`
+}
+
+// CommitStatusConfig configures how the operator posts the commit statuses of builds. The
+// credentials are Secrets of the operator namespace, so builds cannot post with other credentials.
+type CommitStatusConfig struct {
+	// GitHub is a GitHub App with the commit statuses write permission, installed on the
+	// repositories of the builds
+	// +optional
+	GitHub *GitHubAppConfig `json:"github,omitempty"`
+
+	// GitLab is an access token with the api scope on the projects of the builds
+	// +optional
+	GitLab *GitLabConfig `json:"gitlab,omitempty"`
+
+	// TargetURL is a text/template for the link of a status, with .Name and .Namespace of the
+	// build, e.g. https://ado.example.com/builds/{{.Name}}?namespace={{.Namespace}}
+	// +optional
+	TargetURL string `json:"targetURL,omitempty"`
+
+	// Repositories grants the builds of namespaces commit statuses on repositories. Builds post no
+	// status on a repository that is not granted to their namespace, so without grants none.
+	// +optional
+	Repositories []CommitStatusGrant `json:"repositories,omitempty"`
+
+	// ContextPrefix starts the context of every status, so builds cannot post statuses under the
+	// contexts of other CI systems, e.g. a required check. Contexts builds name are appended to it.
+	// Default: "automotive/"
+	// +kubebuilder:validation:Pattern=`^[^\s]+$`
+	// +optional
+	ContextPrefix string `json:"contextPrefix,omitempty"`
+}
+
+// CommitStatusGrant grants the builds of a namespace commit statuses on repositories
+type CommitStatusGrant struct {
+	// Namespace is the build namespace, "*" for every namespace
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// Repositories are owner/name on GitHub or project paths on GitLab; "owner/*" grants every
+	// repository of an owner or group, including subgroups
+	// +kubebuilder:validation:MinItems=1
+	Repositories []string `json:"repositories"`
+}
+
+// GitHubAppConfig identifies an installation of a GitHub App
+type GitHubAppConfig struct {
+	// APIURL defaults to https://api.github.com; GitHub Enterprise Server uses https://<host>/api/v3
+	// +optional
+	APIURL string `json:"apiURL,omitempty"`
+
+	// AppID is the ID of the app
+	AppID int64 `json:"appID"`
+
+	// InstallationID is the installation of the app on the organization or user of the repositories
+	InstallationID int64 `json:"installationID"`
+
+	// PrivateKeySecretRef selects the PEM encoded private key of the app
+	PrivateKeySecretRef corev1.SecretKeySelector `json:"privateKeySecretRef"`
+}
+
+// GitLabConfig identifies a GitLab instance and the token to post statuses with
+type GitLabConfig struct {
+	// URL defaults to https://gitlab.com
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// TokenSecretRef selects a project, group or personal access token
+	TokenSecretRef corev1.SecretKeySelector `json:"tokenSecretRef"`
+}
+
+// MaintenanceConfig controls the build API during maintenance windows
+type MaintenanceConfig struct {
+	// ReadOnly rejects requests that create or change builds; listing, status, logs and downloads keep working
+	// +optional
+	ReadOnly bool `json:"readOnly,omitempty"`
+
+	// Banner is a message returned by /v1/info and printed by the CLI, e.g. the maintenance schedule
+	// +optional
+	Banner string `json:"banner,omitempty"`
+}
+
+// RateLimitConfig limits the requests of each build API client, identified by the user its token
+// belongs to or, for requests without a token, by its IP address. Clients over a limit get 429 Too
+// Many Requests with a Retry-After header.
+type RateLimitConfig struct {
+	// RequestsPerSecond is the sustained request rate of each client; 0 disables the limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RequestsPerSecond int32 `json:"requestsPerSecond,omitempty"`
+
+	// Burst is how many requests a client may make at once before RequestsPerSecond applies;
+	// defaults to RequestsPerSecond
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Burst int32 `json:"burst,omitempty"`
+
+	// RequestsPerSecondPerIP is the sustained request rate of each client IP address, counted before
+	// the token of a request is checked; defaults to 10 times RequestsPerSecond, as several users
+	// may share an address
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RequestsPerSecondPerIP int32 `json:"requestsPerSecondPerIP,omitempty"`
+
+	// MaxConcurrentDownloads is how many artifact downloads and log streams each client may have
+	// open at the same time; 0 disables the limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConcurrentDownloads int32 `json:"maxConcurrentDownloads,omitempty"`
+}
+
+// AuditConfig selects where the build API writes audit events
+type AuditConfig struct {
+	// Sinks are where audit events are written: stdout for JSON lines in the build API log, file for
+	// JSON lines in /var/log/build-api/audit.log and events for Kubernetes Events on the ImageBuild
+	// +kubebuilder:validation:items:Enum=stdout;file;events
+	// +listType=set
+	// +optional
+	Sinks []string `json:"sinks,omitempty"`
+
+	// ClaimName is a PersistentVolumeClaim the file sink writes to, so the audit log outlives the
+	// build API pod; without it the file is on an emptyDir volume
+	// +optional
+	ClaimName string `json:"claimName,omitempty"`
+}
+
+// OIDCConfig configures the validation of tokens of an OIDC issuer
+type OIDCConfig struct {
+	// IssuerURL is the issuer tokens must name in their iss claim; its discovery document at
+	// /.well-known/openid-configuration names the keys tokens are signed with
+	// +kubebuilder:validation:Pattern=`^https://`
+	IssuerURL string `json:"issuerURL"`
+
+	// Audience is the client ID tokens must be issued for, checked against their aud claim
+	// +kubebuilder:validation:MinLength=1
+	Audience string `json:"audience"`
+
+	// UsernameClaim is the claim holding the user name. With email, tokens must also carry
+	// email_verified set to true.
+	// Default: "sub"
+	// +optional
+	UsernameClaim string `json:"usernameClaim,omitempty"`
+
+	// UsernamePrefix is prepended to user names, e.g. "oidc:", so they cannot collide with
+	// Kubernetes users in RoleBindings. "-" prepends nothing. User and group names starting with
+	// "system:" are rejected.
+	// Default: the issuer URL followed by "#", or nothing for the email claim
+	// +optional
+	UsernamePrefix string `json:"usernamePrefix,omitempty"`
+
+	// GroupsClaim is the claim holding the groups of the user, a string or a list of strings. Nested
+	// claims are separated by dots, e.g. "realm_access.roles" for Keycloak realm roles.
+	// Default: "groups"
+	// +optional
+	GroupsClaim string `json:"groupsClaim,omitempty"`
+
+	// GroupsPrefix is prepended to group names
+	// +optional
+	GroupsPrefix string `json:"groupsPrefix,omitempty"`
+
+	// CAConfigMap is a ConfigMap in the operator namespace whose ca.crt verifies the TLS certificate
+	// of the issuer, for issuers with a private CA
+	// +optional
+	CAConfigMap string `json:"caConfigMap,omitempty"`
 }
 
 // OSBuildsConfig defines configuration for OS build operations
@@ -82,10 +291,232 @@ type OSBuildsConfig struct {
 	// +optional
 	RuntimeClassName string `json:"runtimeClassName,omitempty"`
 
+	// AllowedRuntimeClasses are the runtime classes builds may choose with spec.runtimeClassName,
+	// e.g. gVisor or Kata Containers for untrusted or partner builds; builds naming another class
+	// fail. Builds may name any class when empty.
+	// +optional
+	AllowedRuntimeClasses []string `json:"allowedRuntimeClasses,omitempty"`
+
 	// ServeExpiryHours specifies how long to serve build artifacts before automatic cleanup
 	// Default: 24
 	// +optional
 	ServeExpiryHours int32 `json:"serveExpiryHours,omitempty"`
+
+	// WorkspaceStorageClass is the storage class of the workspace PVCs of builds that name none, e.g.
+	// a fast SSD class for the active workspace; the default storage class of the cluster when empty
+	// +optional
+	WorkspaceStorageClass string `json:"workspaceStorageClass,omitempty"`
+
+	// ArtifactArchive moves the artifacts of completed builds to cheaper storage once they are no
+	// longer new
+	// +optional
+	ArtifactArchive *ArtifactArchiveConfig `json:"artifactArchive,omitempty"`
+
+	// BuildRecords chains a record of every finished build and its publications into a
+	// tamper-evident history per namespace, kept in ConfigMaps named build-records-<n>
+	// +optional
+	BuildRecords *BuildRecordsConfig `json:"buildRecords,omitempty"`
+
+	// MaxConcurrentBuilds limits how many builds run at the same time across all namespaces; further
+	// builds wait Queued and start by spec.priority, then in creation order. 0 means no limit.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConcurrentBuilds int32 `json:"maxConcurrentBuilds,omitempty"`
+
+	// BuildQueue selects where the builds waiting for one of the maxConcurrentBuilds slots are
+	// queued; by default the controller orders the Queued ImageBuilds itself
+	// +optional
+	BuildQueue *BuildQueueConfig `json:"buildQueue,omitempty"`
+
+	// NetworkPolicy restricts the egress of build pods to the repositories, registries and proxies
+	// it lists, so malicious manifest content cannot reach anything else
+	// +optional
+	NetworkPolicy *BuildNetworkPolicyConfig `json:"networkPolicy,omitempty"`
+
+	// PartnerIsolation is the hardened profile of the builds of external partners, e.g. suppliers
+	// using builds as a service
+	// +optional
+	PartnerIsolation *PartnerIsolationConfig `json:"partnerIsolation,omitempty"`
+
+	// Signing configures the cosign signatures of build artifacts
+	// +optional
+	Signing *SigningConfig `json:"signing,omitempty"`
+
+	// RemoteFileHosts are the hosts the upload pod may download add_files sources given as URLs
+	// from, e.g. "artifacts.example.com", or "*.example.com" for its subdomains, and the buckets of
+	// s3 URLs and the hosts of their endpoints. Builds cannot download files without hosts.
+	// +optional
+	RemoteFileHosts []string `json:"remoteFileHosts,omitempty"`
+
+	// TargetDefines is a catalog of default AIB defines (KEY=VALUE) per build target, e.g. "rpi4".
+	// Builds for a target inherit these defines; a define with the same KEY in the build request overrides the default.
+	// +optional
+	TargetDefines map[string][]string `json:"targetDefines,omitempty"`
+}
+
+// ArtifactArchiveConfig selects the storage tier artifacts of completed builds are moved to. Archived
+// artifacts are served as before: a download starts the artifact pod on the archive PVC again.
+type ArtifactArchiveConfig struct {
+	// StorageClass is the storage class of archive PVCs, e.g. an HDD or object storage backed class
+	// +kubebuilder:validation:MinLength=1
+	StorageClass string `json:"storageClass"`
+
+	// AfterDays is how many days after its completion a build's artifacts are archived
+	// Default: 7
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	AfterDays *int32 `json:"afterDays,omitempty"`
+
+	// RehydratedMinutes is how long the artifact pod of an archived build keeps running after the
+	// download that started it; later downloads extend it
+	// Default: 60
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RehydratedMinutes int32 `json:"rehydratedMinutes,omitempty"`
+}
+
+// BuildNetworkPolicyConfig is the egress allowlist of build pods. Every build gets a NetworkPolicy
+// selecting all of its pods that allows DNS and the destinations of Egress, plus those of the
+// Exceptions the build names in spec.networkExceptions; all other egress is denied.
+type BuildNetworkPolicyConfig struct {
+	// Egress lists the destinations every build pod may connect to
+	// +optional
+	Egress []BuildEgressRule `json:"egress,omitempty"`
+
+	// Exceptions are destinations builds only reach when they ask for them by name
+	// +optional
+	Exceptions []BuildEgressException `json:"exceptions,omitempty"`
+}
+
+// PartnerIsolationConfig is the tenancy profile of partner namespaces. Every build in them runs in
+// the sandboxed runtime class, on the dedicated storage class and behind a NetworkPolicy allowing
+// only Egress, whatever the build asks for. The build API also rejects what partners could use to
+// reach beyond their build, limits how many builds they run at once and watermarks their images.
+type PartnerIsolationConfig struct {
+	// Namespaces are the build namespaces of external partners
+	// +kubebuilder:validation:MinItems=1
+	Namespaces []string `json:"namespaces"`
+
+	// RuntimeClassName is the sandboxed runtime class partner build pods run with, e.g. kata
+	// +kubebuilder:validation:MinLength=1
+	RuntimeClassName string `json:"runtimeClassName"`
+
+	// StorageClass provides the workspaces of partner builds, so their data stays off the storage of
+	// other builds
+	// +kubebuilder:validation:MinLength=1
+	StorageClass string `json:"storageClass"`
+
+	// Egress lists the destinations partner build pods may connect to besides DNS; the egress rules
+	// and exceptions of networkPolicy do not apply to them
+	// +optional
+	Egress []BuildEgressRule `json:"egress,omitempty"`
+
+	// MaxActiveBuilds is how many unfinished builds a partner namespace may have.
+	// Default: 2
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxActiveBuilds int32 `json:"maxActiveBuilds,omitempty"`
+
+	// Watermark is a notice written with the namespace, name and requester of the build to
+	// /etc/automotive-partner-build in partner images, e.g. the terms the images are built under
+	// +optional
+	Watermark string `json:"watermark,omitempty"`
+}
+
+// BuildEgressRule allows connections to a set of destinations
+type BuildEgressRule struct {
+	// Name describes the destinations, e.g. "rpm-mirror" or "proxy"
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// CIDRs are the address ranges of the destinations, e.g. "10.0.12.0/24"
+	// +optional
+	CIDRs []string `json:"cidrs,omitempty"`
+
+	// Namespaces are namespaces of the cluster whose pods are destinations, e.g. of an in-cluster
+	// registry or proxy
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Ports are the TCP ports allowed; every port when empty
+	// +optional
+	Ports []int32 `json:"ports,omitempty"`
+}
+
+// BuildEgressException is an egress rule granted to the builds that name it
+type BuildEgressException struct {
+	BuildEgressRule `json:",inline"`
+
+	// BuildNamespaces limits the namespaces whose builds may name the exception; every namespace
+	// when empty
+	// +optional
+	BuildNamespaces []string `json:"buildNamespaces,omitempty"`
+}
+
+// BuildQueueConfig selects the backend of the build queue
+type BuildQueueConfig struct {
+	// Backend is InCluster, which orders the Queued ImageBuilds in the controller, or Redis, which
+	// admits them atomically in a Redis server shared by every controller replica and shard
+	// +kubebuilder:validation:Enum=InCluster;Redis
+	// +kubebuilder:default=InCluster
+	// +optional
+	Backend string `json:"backend,omitempty"`
+
+	// Redis is the server of the Redis backend
+	// +optional
+	Redis *RedisQueueConfig `json:"redis,omitempty"`
+}
+
+// RedisQueueConfig locates the Redis server of the build queue
+type RedisQueueConfig struct {
+	// Address is the host:port of the server
+	// +kubebuilder:validation:MinLength=1
+	Address string `json:"address"`
+
+	// TLS connects to the server with TLS
+	// +optional
+	TLS bool `json:"tls,omitempty"`
+
+	// PasswordSecretRef names a Secret in the operator namespace holding the password under password
+	// and, for Redis ACL users, the user under username
+	// +optional
+	PasswordSecretRef string `json:"passwordSecretRef,omitempty"`
+
+	// KeyPrefix prefixes the keys of the queue, so several operators can share a server
+	// +kubebuilder:default="ado:buildqueue"
+	// +optional
+	KeyPrefix string `json:"keyPrefix,omitempty"`
+}
+
+// SigningConfig configures the cosign signatures of build artifacts
+type SigningConfig struct {
+	// RekorURL is the transparency log signatures are recorded in. Key-based signatures are only
+	// recorded when it is set, so signing with a private key publishes nothing by default; keyless
+	// signatures use https://rekor.sigstore.dev without it.
+	// +kubebuilder:validation:Pattern=`^https://`
+	// +optional
+	RekorURL string `json:"rekorURL,omitempty"`
+}
+
+// BuildRecordsConfig enables the history of build records; an empty value records builds without
+// anchoring them
+type BuildRecordsConfig struct {
+	// Anchor records the digest of every build record in a Rekor transparency log
+	// +optional
+	Anchor *BuildRecordAnchor `json:"anchor,omitempty"`
+}
+
+// BuildRecordAnchor selects the Rekor log build records are anchored in and the key signing the entries
+type BuildRecordAnchor struct {
+	// KeySecretRef names a Secret in the operator namespace holding an unencrypted ECDSA private key
+	// in PEM form under key.pem
+	// +kubebuilder:validation:MinLength=1
+	KeySecretRef string `json:"keySecretRef"`
+
+	// RekorURL is the Rekor instance the digests are logged in
+	// +kubebuilder:default="https://rekor.sigstore.dev"
+	// +optional
+	RekorURL string `json:"rekorURL,omitempty"`
 }
 
 // OperatorConfigStatus defines the observed state of OperatorConfig
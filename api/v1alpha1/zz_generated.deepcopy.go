@@ -21,46 +21,1256 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArtifactArchiveConfig) DeepCopyInto(out *ArtifactArchiveConfig) {
+	*out = *in
+	if in.AfterDays != nil {
+		in, out := &in.AfterDays, &out.AfterDays
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArtifactArchiveConfig.
+func (in *ArtifactArchiveConfig) DeepCopy() *ArtifactArchiveConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactArchiveConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArtifactArchiveStatus) DeepCopyInto(out *ArtifactArchiveStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.RehydrationTime != nil {
+		in, out := &in.RehydrationTime, &out.RehydrationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArtifactArchiveStatus.
+func (in *ArtifactArchiveStatus) DeepCopy() *ArtifactArchiveStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactArchiveStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArtifactDownload) DeepCopyInto(out *ArtifactDownload) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArtifactDownload.
+func (in *ArtifactDownload) DeepCopy() *ArtifactDownload {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactDownload)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArtifactDownloadStatus) DeepCopyInto(out *ArtifactDownloadStatus) {
+	*out = *in
+	if in.LastDownloadTime != nil {
+		in, out := &in.LastDownloadTime, &out.LastDownloadTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]ArtifactDownloader, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Recent != nil {
+		in, out := &in.Recent, &out.Recent
+		*out = make([]ArtifactDownload, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArtifactDownloadStatus.
+func (in *ArtifactDownloadStatus) DeepCopy() *ArtifactDownloadStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactDownloadStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArtifactDownloader) DeepCopyInto(out *ArtifactDownloader) {
+	*out = *in
+	in.LastDownloadTime.DeepCopyInto(&out.LastDownloadTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArtifactDownloader.
+func (in *ArtifactDownloader) DeepCopy() *ArtifactDownloader {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactDownloader)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArtifactSignatureStatus) DeepCopyInto(out *ArtifactSignatureStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArtifactSignatureStatus.
+func (in *ArtifactSignatureStatus) DeepCopy() *ArtifactSignatureStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactSignatureStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArtifactSigning) DeepCopyInto(out *ArtifactSigning) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArtifactSigning.
+func (in *ArtifactSigning) DeepCopy() *ArtifactSigning {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactSigning)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuditConfig) DeepCopyInto(out *AuditConfig) {
+	*out = *in
+	if in.Sinks != nil {
+		in, out := &in.Sinks, &out.Sinks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuditConfig.
+func (in *AuditConfig) DeepCopy() *AuditConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AuditConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootTest) DeepCopyInto(out *BootTest) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootTest.
+func (in *BootTest) DeepCopy() *BootTest {
+	if in == nil {
+		return nil
+	}
+	out := new(BootTest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootTestStatus) DeepCopyInto(out *BootTestStatus) {
+	*out = *in
+	if in.Timings != nil {
+		in, out := &in.Timings, &out.Timings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootTestStatus.
+func (in *BootTestStatus) DeepCopy() *BootTestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BootTestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildDebug) DeepCopyInto(out *BuildDebug) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildDebug.
+func (in *BuildDebug) DeepCopy() *BuildDebug {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildDebug)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BuildConfig) DeepCopyInto(out *BuildConfig) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildConfig.
-func (in *BuildConfig) DeepCopy() *BuildConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildConfig.
+func (in *BuildConfig) DeepCopy() *BuildConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildEgressException) DeepCopyInto(out *BuildEgressException) {
+	*out = *in
+	in.BuildEgressRule.DeepCopyInto(&out.BuildEgressRule)
+	if in.BuildNamespaces != nil {
+		in, out := &in.BuildNamespaces, &out.BuildNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildEgressException.
+func (in *BuildEgressException) DeepCopy() *BuildEgressException {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildEgressException)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildEgressRule) DeepCopyInto(out *BuildEgressRule) {
+	*out = *in
+	if in.CIDRs != nil {
+		in, out := &in.CIDRs, &out.CIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildEgressRule.
+func (in *BuildEgressRule) DeepCopy() *BuildEgressRule {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildEgressRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildEnvironment) DeepCopyInto(out *BuildEnvironment) {
+	*out = *in
+	if in.Sysctls != nil {
+		in, out := &in.Sysctls, &out.Sysctls
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildEnvironment.
+func (in *BuildEnvironment) DeepCopy() *BuildEnvironment {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildEnvironment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildNetworkPolicyConfig) DeepCopyInto(out *BuildNetworkPolicyConfig) {
+	*out = *in
+	if in.Egress != nil {
+		in, out := &in.Egress, &out.Egress
+		*out = make([]BuildEgressRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Exceptions != nil {
+		in, out := &in.Exceptions, &out.Exceptions
+		*out = make([]BuildEgressException, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildNetworkPolicyConfig.
+func (in *BuildNetworkPolicyConfig) DeepCopy() *BuildNetworkPolicyConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildNetworkPolicyConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildQueueConfig) DeepCopyInto(out *BuildQueueConfig) {
+	*out = *in
+	if in.Redis != nil {
+		in, out := &in.Redis, &out.Redis
+		*out = new(RedisQueueConfig)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildQueueConfig.
+func (in *BuildQueueConfig) DeepCopy() *BuildQueueConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildQueueConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildRecordAnchor) DeepCopyInto(out *BuildRecordAnchor) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildRecordAnchor.
+func (in *BuildRecordAnchor) DeepCopy() *BuildRecordAnchor {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildRecordAnchor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildRecordStatus) DeepCopyInto(out *BuildRecordStatus) {
+	*out = *in
+	if in.RekorLogIndex != nil {
+		in, out := &in.RekorLogIndex, &out.RekorLogIndex
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildRecordStatus.
+func (in *BuildRecordStatus) DeepCopy() *BuildRecordStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildRecordStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildRecordsConfig) DeepCopyInto(out *BuildRecordsConfig) {
+	*out = *in
+	if in.Anchor != nil {
+		in, out := &in.Anchor, &out.Anchor
+		*out = new(BuildRecordAnchor)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildRecordsConfig.
+func (in *BuildRecordsConfig) DeepCopy() *BuildRecordsConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildRecordsConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildSource) DeepCopyInto(out *BuildSource) {
+	*out = *in
+	if in.Git != nil {
+		in, out := &in.Git, &out.Git
+		*out = new(GitSource)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildSource.
+func (in *BuildSource) DeepCopy() *BuildSource {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CommitStatus) DeepCopyInto(out *CommitStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommitStatus.
+func (in *CommitStatus) DeepCopy() *CommitStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CommitStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CommitStatusConfig) DeepCopyInto(out *CommitStatusConfig) {
+	*out = *in
+	if in.GitHub != nil {
+		in, out := &in.GitHub, &out.GitHub
+		*out = new(GitHubAppConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GitLab != nil {
+		in, out := &in.GitLab, &out.GitLab
+		*out = new(GitLabConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]CommitStatusGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommitStatusConfig.
+func (in *CommitStatusConfig) DeepCopy() *CommitStatusConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CommitStatusConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CommitStatusGrant) DeepCopyInto(out *CommitStatusGrant) {
+	*out = *in
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommitStatusGrant.
+func (in *CommitStatusGrant) DeepCopy() *CommitStatusGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(CommitStatusGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CommitStatusReport) DeepCopyInto(out *CommitStatusReport) {
+	*out = *in
+	if in.UpdateTime != nil {
+		in, out := &in.UpdateTime, &out.UpdateTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommitStatusReport.
+func (in *CommitStatusReport) DeepCopy() *CommitStatusReport {
+	if in == nil {
+		return nil
+	}
+	out := new(CommitStatusReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComplianceScan) DeepCopyInto(out *ComplianceScan) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComplianceScan.
+func (in *ComplianceScan) DeepCopy() *ComplianceScan {
+	if in == nil {
+		return nil
+	}
+	out := new(ComplianceScan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComplianceStatus) DeepCopyInto(out *ComplianceStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComplianceStatus.
+func (in *ComplianceStatus) DeepCopy() *ComplianceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComplianceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DebugStatus) DeepCopyInto(out *DebugStatus) {
+	*out = *in
+	in.HeldUntil.DeepCopyInto(&out.HeldUntil)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DebugStatus.
+func (in *DebugStatus) DeepCopy() *DebugStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DebugStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirstBoot) DeepCopyInto(out *FirstBoot) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirstBoot.
+func (in *FirstBoot) DeepCopy() *FirstBoot {
+	if in == nil {
+		return nil
+	}
+	out := new(FirstBoot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubAppConfig) DeepCopyInto(out *GitHubAppConfig) {
+	*out = *in
+	in.PrivateKeySecretRef.DeepCopyInto(&out.PrivateKeySecretRef)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubAppConfig.
+func (in *GitHubAppConfig) DeepCopy() *GitHubAppConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubAppConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitLabConfig) DeepCopyInto(out *GitLabConfig) {
+	*out = *in
+	in.TokenSecretRef.DeepCopyInto(&out.TokenSecretRef)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitLabConfig.
+func (in *GitLabConfig) DeepCopy() *GitLabConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GitLabConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitSource) DeepCopyInto(out *GitSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitSource.
+func (in *GitSource) DeepCopy() *GitSource {
+	if in == nil {
+		return nil
+	}
+	out := new(GitSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Image) DeepCopyInto(out *Image) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Image.
+func (in *Image) DeepCopy() *Image {
+	if in == nil {
+		return nil
+	}
+	out := new(Image)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Image) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageBuild) DeepCopyInto(out *ImageBuild) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuild.
+func (in *ImageBuild) DeepCopy() *ImageBuild {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageBuild)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageBuild) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageBuildList) DeepCopyInto(out *ImageBuildList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageBuild, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuildList.
+func (in *ImageBuildList) DeepCopy() *ImageBuildList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageBuildList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageBuildList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageBuildSpec) DeepCopyInto(out *ImageBuildSpec) {
+	*out = *in
+	if in.WorkspaceProtection != nil {
+		in, out := &in.WorkspaceProtection, &out.WorkspaceProtection
+		*out = new(WorkspaceProtection)
+		**out = **in
+	}
+	if in.Signing != nil {
+		in, out := &in.Signing, &out.Signing
+		*out = new(ArtifactSigning)
+		**out = **in
+	}
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(BuildSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Publishers != nil {
+		in, out := &in.Publishers, &out.Publishers
+		*out = new(Publishers)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FirstBoot != nil {
+		in, out := &in.FirstBoot, &out.FirstBoot
+		*out = new(FirstBoot)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HardeningProfiles != nil {
+		in, out := &in.HardeningProfiles, &out.HardeningProfiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Compliance != nil {
+		in, out := &in.Compliance, &out.Compliance
+		*out = new(ComplianceScan)
+		**out = **in
+	}
+	if in.SizeBudget != nil {
+		in, out := &in.SizeBudget, &out.SizeBudget
+		*out = new(SizeBudget)
+		**out = **in
+	}
+	if in.BootTest != nil {
+		in, out := &in.BootTest, &out.BootTest
+		*out = new(BootTest)
+		**out = **in
+	}
+	if in.Debug != nil {
+		in, out := &in.Debug, &out.Debug
+		*out = new(BuildDebug)
+		**out = **in
+	}
+	if in.Webhooks != nil {
+		in, out := &in.Webhooks, &out.Webhooks
+		*out = make([]Webhook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CommitStatus != nil {
+		in, out := &in.CommitStatus, &out.CommitStatus
+		*out = new(CommitStatus)
+		**out = **in
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(Retention)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RemoteFiles != nil {
+		in, out := &in.RemoteFiles, &out.RemoteFiles
+		*out = new(RemoteFiles)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NetworkExceptions != nil {
+		in, out := &in.NetworkExceptions, &out.NetworkExceptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuildSpec.
+func (in *ImageBuildSpec) DeepCopy() *ImageBuildSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageBuildSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageBuildStatus) DeepCopyInto(out *ImageBuildStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.WorkspaceScrub != nil {
+		in, out := &in.WorkspaceScrub, &out.WorkspaceScrub
+		*out = new(WorkspaceScrubStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BuildRecord != nil {
+		in, out := &in.BuildRecord, &out.BuildRecord
+		*out = new(BuildRecordStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Signature != nil {
+		in, out := &in.Signature, &out.Signature
+		*out = new(ArtifactSignatureStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ArtifactArchive != nil {
+		in, out := &in.ArtifactArchive, &out.ArtifactArchive
+		*out = new(ArtifactArchiveStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ArtifactDigests != nil {
+		in, out := &in.ArtifactDigests, &out.ArtifactDigests
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Downloads != nil {
+		in, out := &in.Downloads, &out.Downloads
+		*out = new(ArtifactDownloadStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StageTimings != nil {
+		in, out := &in.StageTimings, &out.StageTimings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Compliance != nil {
+		in, out := &in.Compliance, &out.Compliance
+		*out = new(ComplianceStatus)
+		**out = **in
+	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		*out = new(SizeStatus)
+		**out = **in
+	}
+	if in.Boot != nil {
+		in, out := &in.Boot, &out.Boot
+		*out = new(BootTestStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Debug != nil {
+		in, out := &in.Debug, &out.Debug
+		*out = new(DebugStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Environment != nil {
+		in, out := &in.Environment, &out.Environment
+		*out = new(BuildEnvironment)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LogArchive != nil {
+		in, out := &in.LogArchive, &out.LogArchive
+		*out = new(LogArchiveStatus)
+		**out = **in
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(RetentionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CommitStatus != nil {
+		in, out := &in.CommitStatus, &out.CommitStatus
+		*out = new(CommitStatusReport)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Plugins != nil {
+		in, out := &in.Plugins, &out.Plugins
+		*out = make([]PluginResult, len(*in))
+		copy(*out, *in)
+	}
+	if in.Publications != nil {
+		in, out := &in.Publications, &out.Publications
+		*out = make([]PublicationStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuildStatus.
+func (in *ImageBuildStatus) DeepCopy() *ImageBuildStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageBuildStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageList) DeepCopyInto(out *ImageList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Image, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageList.
+func (in *ImageList) DeepCopy() *ImageList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageLocation) DeepCopyInto(out *ImageLocation) {
+	*out = *in
+	if in.Registry != nil {
+		in, out := &in.Registry, &out.Registry
+		*out = new(RegistryLocation)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageLocation.
+func (in *ImageLocation) DeepCopy() *ImageLocation {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageLocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageMetadata) DeepCopyInto(out *ImageMetadata) {
+	*out = *in
+	if in.BuildDate != nil {
+		in, out := &in.BuildDate, &out.BuildDate
+		*out = (*in).DeepCopy()
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageMetadata.
+func (in *ImageMetadata) DeepCopy() *ImageMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSize) DeepCopyInto(out *ImageSize) {
+	*out = *in
+	if in.CompressedBytes != nil {
+		in, out := &in.CompressedBytes, &out.CompressedBytes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.UncompressedBytes != nil {
+		in, out := &in.UncompressedBytes, &out.UncompressedBytes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.VirtualBytes != nil {
+		in, out := &in.VirtualBytes, &out.VirtualBytes
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSize.
+func (in *ImageSize) DeepCopy() *ImageSize {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSize)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSpec) DeepCopyInto(out *ImageSpec) {
+	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		*out = new(ImageSize)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Location.DeepCopyInto(&out.Location)
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(ImageMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSpec.
+func (in *ImageSpec) DeepCopy() *ImageSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(BuildConfig)
+	out := new(ImageSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Image) DeepCopyInto(out *Image) {
+func (in *ImageStatus) DeepCopyInto(out *ImageStatus) {
+	*out = *in
+	if in.LastVerified != nil {
+		in, out := &in.LastVerified, &out.LastVerified
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastAccessed != nil {
+		in, out := &in.LastAccessed, &out.LastAccessed
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageStatus.
+func (in *ImageStatus) DeepCopy() *ImageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogArchiveStatus) DeepCopyInto(out *LogArchiveStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogArchiveStatus.
+func (in *LogArchiveStatus) DeepCopy() *LogArchiveStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LogArchiveStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceConfig) DeepCopyInto(out *MaintenanceConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceConfig.
+func (in *MaintenanceConfig) DeepCopy() *MaintenanceConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCConfig) DeepCopyInto(out *OIDCConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCConfig.
+func (in *OIDCConfig) DeepCopy() *OIDCConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OSBuildsConfig) DeepCopyInto(out *OSBuildsConfig) {
+	*out = *in
+	if in.AllowedRuntimeClasses != nil {
+		in, out := &in.AllowedRuntimeClasses, &out.AllowedRuntimeClasses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ArtifactArchive != nil {
+		in, out := &in.ArtifactArchive, &out.ArtifactArchive
+		*out = new(ArtifactArchiveConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BuildRecords != nil {
+		in, out := &in.BuildRecords, &out.BuildRecords
+		*out = new(BuildRecordsConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BuildQueue != nil {
+		in, out := &in.BuildQueue, &out.BuildQueue
+		*out = new(BuildQueueConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NetworkPolicy != nil {
+		in, out := &in.NetworkPolicy, &out.NetworkPolicy
+		*out = new(BuildNetworkPolicyConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PartnerIsolation != nil {
+		in, out := &in.PartnerIsolation, &out.PartnerIsolation
+		*out = new(PartnerIsolationConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Signing != nil {
+		in, out := &in.Signing, &out.Signing
+		*out = new(SigningConfig)
+		**out = **in
+	}
+	if in.RemoteFileHosts != nil {
+		in, out := &in.RemoteFileHosts, &out.RemoteFileHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TargetDefines != nil {
+		in, out := &in.TargetDefines, &out.TargetDefines
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OSBuildsConfig.
+func (in *OSBuildsConfig) DeepCopy() *OSBuildsConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(OSBuildsConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfig) DeepCopyInto(out *OperatorConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	out.Status = in.Status
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Image.
-func (in *Image) DeepCopy() *Image {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfig.
+func (in *OperatorConfig) DeepCopy() *OperatorConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(Image)
+	out := new(OperatorConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Image) DeepCopyObject() runtime.Object {
+func (in *OperatorConfig) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -68,26 +1278,31 @@ func (in *Image) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageBuild) DeepCopyInto(out *ImageBuild) {
+func (in *OperatorConfigList) DeepCopyInto(out *OperatorConfigList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]OperatorConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuild.
-func (in *ImageBuild) DeepCopy() *ImageBuild {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigList.
+func (in *OperatorConfigList) DeepCopy() *OperatorConfigList {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageBuild)
+	out := new(OperatorConfigList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ImageBuild) DeepCopyObject() runtime.Object {
+func (in *OperatorConfigList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -95,411 +1310,462 @@ func (in *ImageBuild) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageBuildList) DeepCopyInto(out *ImageBuildList) {
+func (in *OperatorConfigSpec) DeepCopyInto(out *OperatorConfigSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]ImageBuild, len(*in))
+	if in.OSBuilds != nil {
+		in, out := &in.OSBuilds, &out.OSBuilds
+		*out = new(OSBuildsConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Maintenance != nil {
+		in, out := &in.Maintenance, &out.Maintenance
+		*out = new(MaintenanceConfig)
+		**out = **in
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(RateLimitConfig)
+		**out = **in
+	}
+	if in.TrustedProxies != nil {
+		in, out := &in.TrustedProxies, &out.TrustedProxies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Audit != nil {
+		in, out := &in.Audit, &out.Audit
+		*out = new(AuditConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OIDC != nil {
+		in, out := &in.OIDC, &out.OIDC
+		*out = new(OIDCConfig)
+		**out = **in
+	}
+	if in.BuildNamespaces != nil {
+		in, out := &in.BuildNamespaces, &out.BuildNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Webhooks != nil {
+		in, out := &in.Webhooks, &out.Webhooks
+		*out = make([]Webhook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CommitStatus != nil {
+		in, out := &in.CommitStatus, &out.CommitStatus
+		*out = new(CommitStatusConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigSpec.
+func (in *OperatorConfigSpec) DeepCopy() *OperatorConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigStatus) DeepCopyInto(out *OperatorConfigStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigStatus.
+func (in *OperatorConfigStatus) DeepCopy() *OperatorConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PXEPublisher) DeepCopyInto(out *PXEPublisher) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PXEPublisher.
+func (in *PXEPublisher) DeepCopy() *PXEPublisher {
+	if in == nil {
+		return nil
+	}
+	out := new(PXEPublisher)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PartnerIsolationConfig) DeepCopyInto(out *PartnerIsolationConfig) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Egress != nil {
+		in, out := &in.Egress, &out.Egress
+		*out = make([]BuildEgressRule, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuildList.
-func (in *ImageBuildList) DeepCopy() *ImageBuildList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PartnerIsolationConfig.
+func (in *PartnerIsolationConfig) DeepCopy() *PartnerIsolationConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageBuildList)
+	out := new(PartnerIsolationConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ImageBuildList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PluginResult) DeepCopyInto(out *PluginResult) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PluginResult.
+func (in *PluginResult) DeepCopy() *PluginResult {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(PluginResult)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageBuildSpec) DeepCopyInto(out *ImageBuildSpec) {
+func (in *PublicationStatus) DeepCopyInto(out *PublicationStatus) {
 	*out = *in
-	if in.Publishers != nil {
-		in, out := &in.Publishers, &out.Publishers
-		*out = new(Publishers)
-		(*in).DeepCopyInto(*out)
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuildSpec.
-func (in *ImageBuildSpec) DeepCopy() *ImageBuildSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PublicationStatus.
+func (in *PublicationStatus) DeepCopy() *PublicationStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageBuildSpec)
+	out := new(PublicationStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageBuildStatus) DeepCopyInto(out *ImageBuildStatus) {
+func (in *PublishTarget) DeepCopyInto(out *PublishTarget) {
 	*out = *in
-	if in.StartTime != nil {
-		in, out := &in.StartTime, &out.StartTime
-		*out = (*in).DeepCopy()
+	if in.Registry != nil {
+		in, out := &in.Registry, &out.Registry
+		*out = new(RegistryPublisher)
+		(*in).DeepCopyInto(*out)
 	}
-	if in.CompletionTime != nil {
-		in, out := &in.CompletionTime, &out.CompletionTime
-		*out = (*in).DeepCopy()
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3Publisher)
+		**out = **in
+	}
+	if in.PXE != nil {
+		in, out := &in.PXE, &out.PXE
+		*out = new(PXEPublisher)
+		**out = **in
+	}
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		*out = new(int32)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBuildStatus.
-func (in *ImageBuildStatus) DeepCopy() *ImageBuildStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PublishTarget.
+func (in *PublishTarget) DeepCopy() *PublishTarget {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageBuildStatus)
+	out := new(PublishTarget)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageList) DeepCopyInto(out *ImageList) {
+func (in *Publishers) DeepCopyInto(out *Publishers) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]Image, len(*in))
+	if in.Registry != nil {
+		in, out := &in.Registry, &out.Registry
+		*out = new(RegistryPublisher)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]PublishTarget, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageList.
-func (in *ImageList) DeepCopy() *ImageList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Publishers.
+func (in *Publishers) DeepCopy() *Publishers {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageList)
+	out := new(Publishers)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ImageList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageLocation) DeepCopyInto(out *ImageLocation) {
+func (in *RateLimitConfig) DeepCopyInto(out *RateLimitConfig) {
 	*out = *in
-	if in.Registry != nil {
-		in, out := &in.Registry, &out.Registry
-		*out = new(RegistryLocation)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageLocation.
-func (in *ImageLocation) DeepCopy() *ImageLocation {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitConfig.
+func (in *RateLimitConfig) DeepCopy() *RateLimitConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageLocation)
+	out := new(RateLimitConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageMetadata) DeepCopyInto(out *ImageMetadata) {
+func (in *RedisQueueConfig) DeepCopyInto(out *RedisQueueConfig) {
 	*out = *in
-	if in.BuildDate != nil {
-		in, out := &in.BuildDate, &out.BuildDate
-		*out = (*in).DeepCopy()
-	}
-	if in.Labels != nil {
-		in, out := &in.Labels, &out.Labels
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.Annotations != nil {
-		in, out := &in.Annotations, &out.Annotations
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageMetadata.
-func (in *ImageMetadata) DeepCopy() *ImageMetadata {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisQueueConfig.
+func (in *RedisQueueConfig) DeepCopy() *RedisQueueConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageMetadata)
+	out := new(RedisQueueConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageSize) DeepCopyInto(out *ImageSize) {
+func (in *RegistryLocation) DeepCopyInto(out *RegistryLocation) {
 	*out = *in
-	if in.CompressedBytes != nil {
-		in, out := &in.CompressedBytes, &out.CompressedBytes
-		*out = new(int64)
-		**out = **in
-	}
-	if in.UncompressedBytes != nil {
-		in, out := &in.UncompressedBytes, &out.UncompressedBytes
-		*out = new(int64)
-		**out = **in
-	}
-	if in.VirtualBytes != nil {
-		in, out := &in.VirtualBytes, &out.VirtualBytes
-		*out = new(int64)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSize.
-func (in *ImageSize) DeepCopy() *ImageSize {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryLocation.
+func (in *RegistryLocation) DeepCopy() *RegistryLocation {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageSize)
+	out := new(RegistryLocation)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageSpec) DeepCopyInto(out *ImageSpec) {
+func (in *RegistryPublisher) DeepCopyInto(out *RegistryPublisher) {
 	*out = *in
-	if in.Size != nil {
-		in, out := &in.Size, &out.Size
-		*out = new(ImageSize)
-		(*in).DeepCopyInto(*out)
-	}
-	in.Location.DeepCopyInto(&out.Location)
-	if in.Metadata != nil {
-		in, out := &in.Metadata, &out.Metadata
-		*out = new(ImageMetadata)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Tags != nil {
-		in, out := &in.Tags, &out.Tags
+	if in.ImmutableTags != nil {
+		in, out := &in.ImmutableTags, &out.ImmutableTags
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSpec.
-func (in *ImageSpec) DeepCopy() *ImageSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryPublisher.
+func (in *RegistryPublisher) DeepCopy() *RegistryPublisher {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageSpec)
+	out := new(RegistryPublisher)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageStatus) DeepCopyInto(out *ImageStatus) {
+func (in *S3Publisher) DeepCopyInto(out *S3Publisher) {
 	*out = *in
-	if in.LastVerified != nil {
-		in, out := &in.LastVerified, &out.LastVerified
-		*out = (*in).DeepCopy()
-	}
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	if in.LastAccessed != nil {
-		in, out := &in.LastAccessed, &out.LastAccessed
-		*out = (*in).DeepCopy()
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageStatus.
-func (in *ImageStatus) DeepCopy() *ImageStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3Publisher.
+func (in *S3Publisher) DeepCopy() *S3Publisher {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageStatus)
+	out := new(S3Publisher)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OSBuildsConfig) DeepCopyInto(out *OSBuildsConfig) {
+func (in *SigningConfig) DeepCopyInto(out *SigningConfig) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OSBuildsConfig.
-func (in *OSBuildsConfig) DeepCopy() *OSBuildsConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SigningConfig.
+func (in *SigningConfig) DeepCopy() *SigningConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(OSBuildsConfig)
+	out := new(SigningConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatorConfig) DeepCopyInto(out *OperatorConfig) {
+func (in *SizeBudget) DeepCopyInto(out *SizeBudget) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfig.
-func (in *OperatorConfig) DeepCopy() *OperatorConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SizeBudget.
+func (in *SizeBudget) DeepCopy() *SizeBudget {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatorConfig)
+	out := new(SizeBudget)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *OperatorConfig) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatorConfigList) DeepCopyInto(out *OperatorConfigList) {
+func (in *RemoteFiles) DeepCopyInto(out *RemoteFiles) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]OperatorConfig, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.URLs != nil {
+		in, out := &in.URLs, &out.URLs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigList.
-func (in *OperatorConfigList) DeepCopy() *OperatorConfigList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteFiles.
+func (in *RemoteFiles) DeepCopy() *RemoteFiles {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatorConfigList)
+	out := new(RemoteFiles)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *OperatorConfigList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Retention) DeepCopyInto(out *Retention) {
+	*out = *in
+	if in.KeepFor != nil {
+		in, out := &in.KeepFor, &out.KeepFor
+		*out = new(v1.Duration)
+		**out = **in
 	}
-	return nil
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Retention.
+func (in *Retention) DeepCopy() *Retention {
+	if in == nil {
+		return nil
+	}
+	out := new(Retention)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatorConfigSpec) DeepCopyInto(out *OperatorConfigSpec) {
+func (in *RetentionStatus) DeepCopyInto(out *RetentionStatus) {
 	*out = *in
-	if in.OSBuilds != nil {
-		in, out := &in.OSBuilds, &out.OSBuilds
-		*out = new(OSBuildsConfig)
-		**out = **in
+	if in.ExpiryTime != nil {
+		in, out := &in.ExpiryTime, &out.ExpiryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.DeletionTime != nil {
+		in, out := &in.DeletionTime, &out.DeletionTime
+		*out = (*in).DeepCopy()
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigSpec.
-func (in *OperatorConfigSpec) DeepCopy() *OperatorConfigSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetentionStatus.
+func (in *RetentionStatus) DeepCopy() *RetentionStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatorConfigSpec)
+	out := new(RetentionStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatorConfigStatus) DeepCopyInto(out *OperatorConfigStatus) {
+func (in *SizeStatus) DeepCopyInto(out *SizeStatus) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigStatus.
-func (in *OperatorConfigStatus) DeepCopy() *OperatorConfigStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SizeStatus.
+func (in *SizeStatus) DeepCopy() *SizeStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatorConfigStatus)
+	out := new(SizeStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Publishers) DeepCopyInto(out *Publishers) {
+func (in *Webhook) DeepCopyInto(out *Webhook) {
 	*out = *in
-	if in.Registry != nil {
-		in, out := &in.Registry, &out.Registry
-		*out = new(RegistryPublisher)
-		**out = **in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Publishers.
-func (in *Publishers) DeepCopy() *Publishers {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Webhook.
+func (in *Webhook) DeepCopy() *Webhook {
 	if in == nil {
 		return nil
 	}
-	out := new(Publishers)
+	out := new(Webhook)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RegistryLocation) DeepCopyInto(out *RegistryLocation) {
+func (in *WorkspaceProtection) DeepCopyInto(out *WorkspaceProtection) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryLocation.
-func (in *RegistryLocation) DeepCopy() *RegistryLocation {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceProtection.
+func (in *WorkspaceProtection) DeepCopy() *WorkspaceProtection {
 	if in == nil {
 		return nil
 	}
-	out := new(RegistryLocation)
+	out := new(WorkspaceProtection)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RegistryPublisher) DeepCopyInto(out *RegistryPublisher) {
+func (in *WorkspaceScrubStatus) DeepCopyInto(out *WorkspaceScrubStatus) {
 	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryPublisher.
-func (in *RegistryPublisher) DeepCopy() *RegistryPublisher {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceScrubStatus.
+func (in *WorkspaceScrubStatus) DeepCopy() *WorkspaceScrubStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(RegistryPublisher)
+	out := new(WorkspaceScrubStatus)
 	in.DeepCopyInto(out)
 	return out
 }
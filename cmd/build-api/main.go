@@ -12,6 +12,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/version"
 )
 
 func main() {
@@ -20,6 +21,23 @@ func main() {
 		kubeconfigPath = flag.String("kubeconfig-path", "", "Path to kubeconfig file")
 		port           = flag.String("port", "", "Port to listen on (default: 8080)")
 		namespace      = flag.String("namespace", "automotive-dev-operator-system", "Kubernetes namespace to use")
+		rateLimit      = flag.String("rate-limit", "", "Requests per second each client may make (default: unlimited)")
+		rateBurst      = flag.String("rate-limit-burst", "", "Requests each client may make at once (default: the rate limit)")
+		maxDownloads   = flag.String("max-concurrent-downloads", "", "Artifact downloads and log streams each client may have open (default: unlimited)")
+		rateLimitPerIP = flag.String("rate-limit-per-ip", "", "Requests per second each IP address may make before authentication (default: 10 times the rate limit)")
+		trustedProxies = flag.String("trusted-proxies", "", "Comma separated addresses and CIDRs of proxies whose X-Forwarded-For header identifies clients (default: loopback only)")
+		auditSinks     = flag.String("audit-sinks", "", "Comma separated audit sinks: stdout, file, events (default: no audit log)")
+		auditFile      = flag.String("audit-file", "", "File the file audit sink appends to (default: /var/log/build-api/audit.log)")
+		imagePreflight = flag.String("image-preflight", "", "Set to false to create builds without resolving the container images of their manifests")
+		oidcIssuer     = flag.String("oidc-issuer-url", "", "Accept tokens of this OIDC issuer in addition to Kubernetes tokens")
+		oidcAudience   = flag.String("oidc-audience", "", "Client ID OIDC tokens must be issued for")
+		oidcUserClaim  = flag.String("oidc-username-claim", "", "Claim of OIDC tokens holding the user name (default: sub)")
+		oidcUserPrefix = flag.String("oidc-username-prefix", "", "Prefix of user names of OIDC tokens, e.g. oidc:")
+		oidcGroupClaim = flag.String("oidc-groups-claim", "", "Claim of OIDC tokens holding the groups, nested claims separated by dots (default: groups)")
+		oidcGroupPfx   = flag.String("oidc-groups-prefix", "", "Prefix of group names of OIDC tokens")
+		oidcCAFile     = flag.String("oidc-ca-file", "", "CA certificate verifying the OIDC issuer (default: system roots)")
+		namespaces     = flag.String("namespaces", "", "Comma separated namespaces clients may create and use builds in besides --namespace; * for all")
+		drainPeriod    = flag.String("drain-period", "", "How long in-flight uploads, downloads and log streams may finish on shutdown (default: 30s)")
 	)
 	flag.Parse()
 
@@ -45,16 +63,44 @@ func main() {
 		os.Setenv("BUILD_API_NAMESPACE", *namespace)
 	}
 
+	// Flags override the settings the operator passes in the environment
+	for env, value := range map[string]string{
+		"BUILD_API_RATE_LIMIT":               *rateLimit,
+		"BUILD_API_RATE_LIMIT_BURST":         *rateBurst,
+		"BUILD_API_MAX_CONCURRENT_DOWNLOADS": *maxDownloads,
+		"BUILD_API_RATE_LIMIT_PER_IP":        *rateLimitPerIP,
+		"BUILD_API_TRUSTED_PROXIES":          *trustedProxies,
+		"BUILD_API_AUDIT_SINKS":              *auditSinks,
+		"BUILD_API_AUDIT_FILE":               *auditFile,
+		"BUILD_API_IMAGE_PREFLIGHT":          *imagePreflight,
+		"BUILD_API_OIDC_ISSUER_URL":          *oidcIssuer,
+		"BUILD_API_OIDC_AUDIENCE":            *oidcAudience,
+		"BUILD_API_OIDC_USERNAME_CLAIM":      *oidcUserClaim,
+		"BUILD_API_OIDC_USERNAME_PREFIX":     *oidcUserPrefix,
+		"BUILD_API_OIDC_GROUPS_CLAIM":        *oidcGroupClaim,
+		"BUILD_API_OIDC_GROUPS_PREFIX":       *oidcGroupPfx,
+		"BUILD_API_OIDC_CA_FILE":             *oidcCAFile,
+		"BUILD_API_NAMESPACES":               *namespaces,
+		"BUILD_API_DRAIN_PERIOD":             *drainPeriod,
+	} {
+		if value != "" {
+			os.Setenv(env, value)
+		}
+	}
+
 	// Set Gin mode for development/testing
 	if os.Getenv("GIN_MODE") == "" {
 		os.Setenv("GIN_MODE", "debug")
 	}
 
 	slog.Info("starting build-api server",
+		"version", version.Version,
 		"addr", addr,
 		"gin_mode", os.Getenv("GIN_MODE"),
 		"kubeconfig", os.Getenv("KUBECONFIG"),
-		"namespace", os.Getenv("BUILD_API_NAMESPACE"))
+		"namespace", os.Getenv("BUILD_API_NAMESPACE"),
+		"rate_limit", os.Getenv("BUILD_API_RATE_LIMIT"),
+		"max_concurrent_downloads", os.Getenv("BUILD_API_MAX_CONCURRENT_DOWNLOADS"))
 
 	apiServer := buildapi.NewAPIServer(addr, logger)
 
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// newBuildRecordsCmd returns the "build-records" command
+func newBuildRecordsCmd() *cobra.Command {
+	buildRecordsCmd := &cobra.Command{
+		Use:   "build-records",
+		Short: "Show and verify the tamper-evident history of builds in the namespace",
+		Long: `Show the build records of the namespace, oldest first: what every finished build produced,
+who signed it and where it was published. Each record holds the digest of the record before it, so
+a changed, removed or reordered record breaks the chain; caib exits with status 1 when it is broken.
+Records anchored in a Rekor transparency log show their log index, which
+rekor-cli get --log-index looks up. The history is kept when the OperatorConfig sets
+spec.osBuilds.buildRecords.`,
+		Example: `  caib build-records
+  caib build-records --namespace release`,
+		Args: cobra.NoArgs,
+		Run:  runBuildRecords,
+	}
+	buildRecordsCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	buildRecordsCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	return buildRecordsCmd
+}
+
+func runBuildRecords(_ *cobra.Command, _ []string) {
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	resp, err := api.GetBuildRecords(ctx)
+	if err != nil {
+		handleError(err)
+	}
+	if len(resp.Records) == 0 {
+		fmt.Printf("No build records in %s\n", resp.Namespace)
+	} else {
+		fmt.Printf("%-6s %-32s %-10s %-21s %-20s %s\n", "SEQ", "BUILD", "PHASE", "TIME", "DIGEST", "REKOR")
+		for _, r := range resp.Records {
+			digest := strings.TrimPrefix(r.Digest, "sha256:")
+			if len(digest) > 12 {
+				digest = digest[:12]
+			}
+			rekor := "-"
+			if r.Rekor != nil {
+				rekor = fmt.Sprintf("%d", r.Rekor.LogIndex)
+			}
+			fmt.Printf("%-6d %-32s %-10s %-21s %-20s %s\n", r.Sequence, r.Build, r.Phase, r.Time, "sha256:"+digest, rekor)
+		}
+	}
+	if !resp.Verified {
+		fmt.Fprintln(os.Stderr, "Build record history is broken:")
+		for _, p := range resp.Problems {
+			fmt.Fprintf(os.Stderr, "  %s\n", p)
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("Verified %d build records\n", len(resp.Records))
+}
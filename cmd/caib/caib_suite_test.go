@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// runCaibEnv makes the test binary run caib with its arguments instead of the specs, for specs
+// that check what a command prints and its exit status
+const runCaibEnv = "CAIB_TEST_RUN_CAIB"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runCaibEnv) == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestCaib(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "caib Suite")
+}
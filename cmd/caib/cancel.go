@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// newCancelCmd returns the "cancel" command, which stops a running build
+func newCancelCmd() *cobra.Command {
+	cancelCmd := &cobra.Command{
+		Use:   "cancel <name>",
+		Short: "Cancel a running build",
+		Long: `Stop the pod of a build that is still uploading or building, release its workspace and mark
+it Cancelled. Builds that already completed or failed cannot be cancelled.`,
+		Args: cobra.ExactArgs(1),
+		Run:  runCancel,
+	}
+	cancelCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	cancelCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	return cancelCmd
+}
+
+func runCancel(_ *cobra.Command, args []string) {
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	resp, err := api.CancelBuild(context.Background(), args[0])
+	if err != nil {
+		handleError(err)
+	}
+	fmt.Printf("Cancellation of %s requested (phase: %s)\n", resp.Name, renderer.Phase(resp.Phase))
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// artifactExts are the file extensions of the compressions caib can decompress
+var artifactExts = map[string]string{"gzip": ".gz", "zstd": ".zst"}
+
+// defaultAcceptCompression are the compressions downloads accept without --accept-compression.
+// They are weighted equally, so the server sends the artifact as it is stored and answers 406
+// rather than sending an algorithm caib does not know.
+var defaultAcceptCompression = []string{"gzip", "lz4", "zstd", "none"}
+
+// newDecompressor returns a reader of the data compressed with compression in r
+func newDecompressor(r io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case "gzip":
+		return gzip.NewReader(r)
+	case "zstd":
+		dec, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("unknown compression %q", compression)
+}
+
+// decodeContentEncoding replaces the body of an artifact the server compressed for the transfer
+// with its decoded content, so digests and file names apply as for any other download
+func decodeContentEncoding(resp *http.Response) error {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" {
+		return nil
+	}
+	dec, err := newDecompressor(resp.Body, encoding)
+	if err != nil {
+		return fmt.Errorf("content encoding %s: %w", encoding, err)
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{dec, closeBoth{dec, resp.Body}}
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	return nil
+}
+
+// closeBoth closes a decoder and the body it reads
+type closeBoth [2]io.Closer
+
+func (c closeBoth) Close() error {
+	err := c[0].Close()
+	if err2 := c[1].Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// decompressingWriter decompresses what is written to it with compression into dst. Close waits
+// until everything written was decompressed and returns the first error.
+type decompressingWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func newDecompressingWriter(dst io.Writer, compression string) *decompressingWriter {
+	pr, pw := io.Pipe()
+	w := &decompressingWriter{pw: pw, done: make(chan error, 1)}
+	go func() {
+		dec, err := newDecompressor(pr, compression)
+		if err == nil {
+			_, err = io.Copy(dst, dec)
+			dec.Close()
+		}
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w
+}
+
+func (w *decompressingWriter) Write(b []byte) (int, error) {
+	return w.pw.Write(b)
+}
+
+func (w *decompressingWriter) Close() error {
+	w.pw.Close()
+	return <-w.done
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/conformance"
+	"github.com/spf13/cobra"
+)
+
+// conformanceOpts configures caib conformance
+var conformanceOpts struct {
+	targets    []string
+	exports    []string
+	arches     []string
+	distro     string
+	aibImage   string
+	storage    string
+	timeout    int
+	skipBuilds bool
+	keepBuilds bool
+	output     string
+	signingKey string
+	publicKey  string
+}
+
+// newConformanceCmd returns the "conformance" command
+func newConformanceCmd() *cobra.Command {
+	conformanceCmd := &cobra.Command{
+		Use:   "conformance",
+		Short: "Certify a build API deployment with an end-to-end conformance suite",
+		Long: `Run the conformance suite against a build API deployment: health, rejection of anonymous
+and invalid credentials, a small real build for every --target, --export and --arch combination,
+a local file uploaded into the first of them, and a download of every artifact checked against
+its digest. The builds are deleted afterwards unless --keep-builds is set.
+
+The results are written to a signed bundle (a gzipped tar holding report.json, junit.xml, the
+status of every build and SHA256SUMS with its signature). Sign it with --signing-key to attribute
+it to your team; without one a key is generated for the run and only tampering can be detected.
+Check a bundle with "caib conformance verify". caib exits non-zero when a check failed.`,
+		Example: `  caib conformance --signing-key team.key
+  caib conformance --target qemu --export image --export qcow2 --arch amd64 --arch arm64
+  caib conformance verify caib-conformance-0a1b2c3d.tar.gz --public-key team.pub`,
+		Args: cobra.NoArgs,
+		Run:  runConformance,
+	}
+	conformanceCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	conformanceCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	conformanceCmd.Flags().StringSliceVar(&conformanceOpts.targets, "target", []string{"qemu"}, "targets to build, repeatable")
+	conformanceCmd.Flags().StringSliceVar(&conformanceOpts.exports, "export", []string{"image", "qcow2"}, "export formats to build, repeatable")
+	conformanceCmd.Flags().StringSliceVar(&conformanceOpts.arches, "arch", []string{"amd64"}, "architectures to build, repeatable")
+	conformanceCmd.Flags().StringVar(&conformanceOpts.distro, "distro", "", "distribution of the builds (default: the server default)")
+	conformanceCmd.Flags().StringVar(&conformanceOpts.aibImage, "automotive-image-builder", "", "automotive-image-builder image of the builds (default: the server default)")
+	conformanceCmd.Flags().StringVar(&conformanceOpts.storage, "storage-class", "", "storage class of the build workspaces")
+	conformanceCmd.Flags().IntVar(&conformanceOpts.timeout, "timeout", 60, "minutes each build may take")
+	conformanceCmd.Flags().BoolVar(&conformanceOpts.skipBuilds, "skip-builds", false, "only run the checks that do not build")
+	conformanceCmd.Flags().BoolVar(&conformanceOpts.keepBuilds, "keep-builds", false, "keep the builds of the run for inspection")
+	conformanceCmd.Flags().StringVarP(&conformanceOpts.output, "output", "o", "", "file to write the results bundle to (default: caib-conformance-<run-id>.tar.gz)")
+	conformanceCmd.Flags().StringVar(&conformanceOpts.signingKey, "signing-key", "", "PEM private key (Ed25519, ECDSA or RSA) to sign the results bundle with")
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify <bundle>",
+		Short: "Check the signature of a conformance results bundle and print its results",
+		Long: `Check that a conformance results bundle was not modified since it was signed and print
+its results. With --public-key the bundle must also be signed by that key. caib exits non-zero
+when the bundle is invalid or records a failed check.`,
+		Args: cobra.ExactArgs(1),
+		Run:  runConformanceVerify,
+	}
+	verifyCmd.Flags().StringVar(&conformanceOpts.publicKey, "public-key", "", "PEM public key the bundle must be signed with")
+	conformanceCmd.AddCommand(verifyCmd)
+	return conformanceCmd
+}
+
+// conformanceBuildCases returns every combination of the --target, --export and --arch flags
+func conformanceBuildCases() []conformance.BuildCase {
+	if conformanceOpts.skipBuilds {
+		return nil
+	}
+	var cases []conformance.BuildCase
+	for _, target := range conformanceOpts.targets {
+		for _, export := range conformanceOpts.exports {
+			for _, arch := range conformanceOpts.arches {
+				cases = append(cases, conformance.BuildCase{Target: target, ExportFormat: export, Architecture: arch})
+			}
+		}
+	}
+	return cases
+}
+
+func runConformance(_ *cobra.Command, _ []string) {
+	var signer crypto.Signer
+	var err error
+	if conformanceOpts.signingKey != "" {
+		data, err := os.ReadFile(conformanceOpts.signingKey)
+		if err != nil {
+			handleError(err)
+		}
+		if signer, err = conformance.LoadSigner(data); err != nil {
+			handleError(fmt.Errorf("--signing-key: %w", err))
+		}
+	} else if signer, err = conformance.GenerateSigner(); err != nil {
+		handleError(err)
+	}
+
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	cases := conformanceBuildCases()
+	if len(cases) > 0 {
+		fmt.Printf("Running the conformance suite with %d builds, this takes as long as the slowest build...\n", len(cases))
+	}
+	report := conformance.Run(context.Background(), conformance.Options{
+		Server:                 serverURL,
+		Client:                 api,
+		Builds:                 cases,
+		Distro:                 conformanceOpts.distro,
+		AutomotiveImageBuilder: conformanceOpts.aibImage,
+		StorageClass:           conformanceOpts.storage,
+		BuildTimeout:           time.Duration(conformanceOpts.timeout) * time.Minute,
+		KeepBuilds:             conformanceOpts.keepBuilds,
+		ClientVersion:          cliVersion(),
+		Namespace:              strings.TrimSpace(buildNamespace),
+		Progress:               os.Stdout,
+	})
+
+	output := conformanceOpts.output
+	if output == "" {
+		output = fmt.Sprintf("caib-conformance-%s.tar.gz", report.RunID)
+	}
+	var bundle bytes.Buffer
+	if err := conformance.WriteBundle(&bundle, report, signer); err != nil {
+		handleError(err)
+	}
+	if err := os.WriteFile(output, bundle.Bytes(), 0o644); err != nil {
+		handleError(err)
+	}
+	fingerprint, err := conformance.KeyFingerprint(signer.Public())
+	if err != nil {
+		handleError(err)
+	}
+
+	fmt.Printf("\n%d passed, %d failed, %d skipped\n", report.Passed, report.Failed, report.Skipped)
+	fmt.Printf("Results bundle: %s\n", output)
+	if conformanceOpts.signingKey != "" {
+		fmt.Printf("Signed by: %s\n", fingerprint)
+	} else {
+		fmt.Printf("Signed by a key generated for this run: %s\n", fingerprint)
+	}
+	if !report.Succeeded() {
+		os.Exit(1)
+	}
+}
+
+func runConformanceVerify(_ *cobra.Command, args []string) {
+	var pub crypto.PublicKey
+	if conformanceOpts.publicKey != "" {
+		data, err := os.ReadFile(conformanceOpts.publicKey)
+		if err != nil {
+			handleError(err)
+		}
+		if pub, err = conformance.LoadPublicKey(data); err != nil {
+			handleError(fmt.Errorf("--public-key: %w", err))
+		}
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		handleError(err)
+	}
+	defer f.Close()
+	report, signer, err := conformance.VerifyBundle(f, pub)
+	if err != nil {
+		handleError(fmt.Errorf("%s: %w", args[0], err))
+	}
+	fingerprint, err := conformance.KeyFingerprint(signer)
+	if err != nil {
+		handleError(err)
+	}
+
+	fmt.Printf("%-12s %s\n", "Run:", report.RunID)
+	fmt.Printf("%-12s %s\n", "Server:", report.Server)
+	if v := report.Versions; v != nil {
+		fmt.Printf("%-12s %s\n", "Build API:", v.BuildAPI)
+		fmt.Printf("%-12s %s\n", "Operator:", valueOr(v.Operator, "unknown"))
+	}
+	fmt.Printf("%-12s %s\n", "Completed:", report.CompletionTime.Format(time.RFC3339))
+	if pub != nil {
+		fmt.Printf("%-12s %s (matches --public-key)\n", "Signed by:", fingerprint)
+	} else {
+		fmt.Printf("%-12s %s\n", "Signed by:", fingerprint)
+	}
+	fmt.Println()
+	for _, res := range report.Results {
+		line := fmt.Sprintf("%-8s %s", strings.ToUpper(res.Status), res.Name)
+		if res.Message != "" {
+			line += ": " + res.Message
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("\n%d passed, %d failed, %d skipped\n", report.Passed, report.Failed, report.Skipped)
+	if !report.Succeeded() {
+		os.Exit(1)
+	}
+}
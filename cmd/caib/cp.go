@@ -0,0 +1,143 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	buildapiclient "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi/client"
+	"github.com/spf13/cobra"
+)
+
+// newCpCmd returns the "cp" command, which copies files out of a build workspace
+func newCpCmd() *cobra.Command {
+	cpCmd := &cobra.Command{
+		Use:   "cp <name>:<path> <dest>",
+		Short: "Copy a file or directory out of a build workspace",
+		Long: `Copy a file or directory out of a build, e.g. the osbuild manifest (image.json), reports or
+partial images that are not published as artifacts. Relative paths are relative to the build
+workspace (/workspace/shared); /output, /_build and /manifest-work can be copied while the build
+pod runs or is held with --debug-hold. Use - as dest to write a tar archive to stdout.`,
+		Args: cobra.ExactArgs(2),
+		Run:  runCp,
+	}
+	cpCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	cpCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	return cpCmd
+}
+
+func runCp(_ *cobra.Command, args []string) {
+	name, src, ok := strings.Cut(args[0], ":")
+	if !ok || name == "" || src == "" {
+		handleError(fmt.Errorf("source must be <name>:<path>, got %q", args[0]))
+	}
+	dest := args[1]
+
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	ctx := context.Background()
+
+	if dest == "-" {
+		if err := api.CopyFromWorkspace(ctx, name, src, os.Stdout); err != nil {
+			handleError(err)
+		}
+		return
+	}
+
+	files, err := copyFromBuild(ctx, api, name, src, dest)
+	if err != nil {
+		handleError(err)
+	}
+	fmt.Printf("Copied %d files from %s:%s to %s\n", files, name, src, dest)
+}
+
+// copyFromBuild extracts src of a build to dest like cp -r: into dest when it is an existing
+// directory, otherwise as dest. It returns the number of regular files written.
+func copyFromBuild(ctx context.Context, api *buildapiclient.Client, name, src, dest string) (int, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(api.CopyFromWorkspace(ctx, name, src, pw))
+	}()
+	defer pr.Close()
+
+	root := path.Base(path.Clean("/" + src))
+	info, err := os.Stat(dest)
+	intoDir := err == nil && info.IsDir()
+	target := func(entry string) (string, error) {
+		entry = path.Clean(entry)
+		if entry != root && !strings.HasPrefix(entry, root+"/") {
+			return "", fmt.Errorf("unexpected archive entry %q", entry)
+		}
+		if intoDir {
+			return filepath.Join(dest, filepath.FromSlash(entry)), nil
+		}
+		return filepath.Join(dest, filepath.FromSlash(strings.TrimPrefix(entry, root))), nil
+	}
+
+	// Symlinks are created last so no file is written through a link from the archive
+	type link struct{ path, target string }
+	var links []link
+	files := 0
+	tr := tar.NewReader(pr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return files, err
+		}
+		out, err := target(hdr.Name)
+		if err != nil {
+			return files, err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(out, 0o755); err != nil {
+				return files, err
+			}
+		case tar.TypeReg:
+			if err := writeTarFile(tr, out, hdr.FileInfo().Mode().Perm()); err != nil {
+				return files, err
+			}
+			files++
+		case tar.TypeSymlink:
+			links = append(links, link{path: out, target: hdr.Linkname})
+		default:
+			fmt.Fprintf(os.Stderr, "Skipping %s: unsupported file type\n", hdr.Name)
+		}
+	}
+	for _, l := range links {
+		if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+			return files, err
+		}
+		_ = os.Remove(l.path)
+		if err := os.Symlink(l.target, l.path); err != nil {
+			return files, err
+		}
+	}
+	return files, nil
+}
+
+func writeTarFile(r io.Reader, out string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode|0o200)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("resolveDefines", func() {
+	// resolve writes files as --define-file files and resolves them with profiles and --define
+	resolve := func(files []string, profiles, defines []string) ([]string, error) {
+		dir := GinkgoT().TempDir()
+		paths := make([]string, 0, len(files))
+		for i, content := range files {
+			path := filepath.Join(dir, string(rune('a'+i))+".yaml")
+			Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+			paths = append(paths, path)
+		}
+		setFlag(&defineFiles, paths)
+		setFlag(&defineProfiles, profiles)
+		setFlag(&customDefs, defines)
+		return resolveDefines()
+	}
+
+	// defines keep the position of their first appearance when later ones override them
+	DescribeTable("parses define files",
+		func(files, profiles, defines, expected []string) {
+			Expect(resolve(files, profiles, defines)).To(Equal(expected))
+		},
+		Entry("a plain mapping, sorted by key",
+			[]string{"use_debug: 'true'\nimage_size: 8G\n"}, nil, nil,
+			[]string{"image_size=8G", "use_debug=true"}),
+		Entry("comments and blank lines",
+			[]string{"# defines of the CI builds\n\nimage_size: 8G  # big enough for the debug RPMs\n\n\n# use_debug: true\n"}, nil, nil,
+			[]string{"image_size=8G"}),
+		Entry("lists, numbers, booleans and empty values",
+			[]string{"extra_rpms: [gdb, strace]\npartitions: 3\nuse_debug: true\nhostname:\n"}, nil, nil,
+			[]string{`extra_rpms=["gdb","strace"]`, "hostname=", "partitions=3", "use_debug=true"}),
+		Entry("an empty file", []string{"# nothing yet\n"}, nil, nil, []string{}),
+		Entry("defines with the selected profiles on top",
+			[]string{"defines:\n  image_size: 8G\n  use_debug: 'false'\nprofiles:\n  debug:\n    use_debug: 'true'\n  ci:\n    image_size: 4G\n"},
+			[]string{"debug"}, nil,
+			[]string{"image_size=8G", "use_debug=true"}),
+		Entry("profiles in the order given",
+			[]string{"profiles:\n  small:\n    image_size: 4G\n  large:\n    image_size: 16G\n"},
+			[]string{"large", "small"}, nil,
+			[]string{"image_size=4G"}),
+		Entry("later files over earlier ones",
+			[]string{"image_size: 8G\nuse_debug: 'true'\n", "image_size: 16G\n"}, nil, nil,
+			[]string{"image_size=16G", "use_debug=true"}),
+		Entry("--define over the files and their profiles",
+			[]string{"defines:\n  image_size: 8G\nprofiles:\n  ci:\n    image_size: 4G\n    hostname: ci\n"},
+			[]string{"ci"}, []string{"image_size=2G", "extra=1"},
+			[]string{"image_size=2G", "hostname=ci", "extra=1"}),
+		Entry("--define alone", nil, nil, []string{"image_size=2G"}, []string{"image_size=2G"}),
+	)
+
+	DescribeTable("rejects bad define files",
+		func(files, profiles, defines []string, message string) {
+			_, err := resolve(files, profiles, defines)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("invalid YAML", []string{"image_size: [8G\n"}, nil, nil, "error parsing define file"),
+		Entry("a list instead of a mapping", []string{"- image_size=8G\n"}, nil, nil, "error parsing define file"),
+		Entry("a key with =", []string{"'image=size': 8G\n"}, nil, nil, `invalid define key "image=size"`),
+		Entry("a profile no file has", []string{"profiles:\n  ci:\n    image_size: 4G\n"}, []string{"nightly"}, nil, `define profile "nightly" not found`),
+		Entry("a --define without =", []string{"image_size: 8G\n"}, nil, []string{"use_debug"}, `invalid --define "use_debug"`),
+	)
+
+	It("names a define file that cannot be read", func() {
+		setFlag(&defineFiles, []string{filepath.Join(GinkgoT().TempDir(), "missing.yaml")})
+		setFlag(&defineProfiles, nil)
+		setFlag(&customDefs, nil)
+		_, err := resolveDefines()
+		Expect(err).To(MatchError(ContainSubstring("error reading define file")))
+	})
+})
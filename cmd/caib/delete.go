@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	buildapiclient "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi/client"
+	"github.com/spf13/cobra"
+)
+
+var deleteOpts buildapiclient.DeleteBuildOptions
+
+// newDeleteCmd returns the "delete" command, which deletes builds and their artifacts
+func newDeleteCmd() *cobra.Command {
+	deleteCmd := &cobra.Command{
+		Use:   "delete <name>...",
+		Short: "Delete builds and their artifacts",
+		Long: `Delete builds together with their workspace, artifact and serving resources. Artifacts pushed
+to registries are not touched. Builds that are still running are refused unless --force is given,
+which cancels them first.`,
+		Args: cobra.MinimumNArgs(1),
+		Run:  runDelete,
+	}
+	deleteCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	deleteCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	deleteCmd.Flags().BoolVar(&deleteOpts.KeepArtifact, "keep-artifact", false, "keep the workspace PVC holding the artifact of a completed build")
+	deleteCmd.Flags().BoolVar(&deleteOpts.Force, "force", false, "cancel and delete builds that are still running")
+	return deleteCmd
+}
+
+func runDelete(_ *cobra.Command, args []string) {
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	failed := false
+	for _, name := range args {
+		resp, err := api.DeleteBuild(context.Background(), name, deleteOpts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: %s\n", resp.Name, resp.Message)
+		if resp.ArtifactPath != "" {
+			fmt.Printf("  artifact: %s on PVC %s\n", resp.ArtifactPath, resp.ArtifactPVC)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	buildapitypes "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+)
+
+var _ = Describe("download --stdout", func() {
+	const artifact = "disk image"
+	var (
+		srvURL   string
+		checksum string
+	)
+
+	BeforeEach(func() {
+		sum := sha256.Sum256([]byte(artifact))
+		checksum = hex.EncodeToString(sum[:])
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v1/info":
+				_ = json.NewEncoder(w).Encode(buildapitypes.InfoResponse{})
+			case "/v1/builds/radio":
+				_ = json.NewEncoder(w).Encode(buildapitypes.BuildResponse{Name: "radio", Phase: "Completed"})
+			case "/v1/builds/radio/artifact":
+				w.Header().Set("Content-Disposition", `attachment; filename="radio.raw"`)
+				w.Header().Set("X-AIB-Compression", "none")
+				w.Header().Set("X-AIB-Checksum", "sha256:"+checksum)
+				_, _ = io.WriteString(w, artifact)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		DeferCleanup(srv.Close)
+		srvURL = srv.URL
+	})
+
+	// download runs caib download --stdout in a process of its own and returns its stdout, stderr
+	// and exit status
+	download := func() (string, string, int) {
+		cmd := exec.Command(os.Args[0], "download", "--name", "radio", "--stdout", "--server", srvURL, "--token", "t")
+		cmd.Env = append(os.Environ(), runCaibEnv+"=1", "HOME="+GinkgoT().TempDir(), "KUBECONFIG=")
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err := cmd.Run()
+		if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) {
+			return stdout.String(), stderr.String(), exitErr.ExitCode()
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return stdout.String(), stderr.String(), 0
+	}
+
+	It("writes an artifact matching its checksum to stdout", func() {
+		stdout, stderr, code := download()
+		Expect(code).To(BeZero(), stderr)
+		Expect(stdout).To(Equal(artifact))
+		Expect(stderr).To(ContainSubstring("Checksum verified: sha256 " + checksum))
+	})
+
+	It("fails when the streamed artifact does not match its checksum", func() {
+		checksum = hex.EncodeToString(make([]byte, sha256.Size))
+		_, stderr, code := download()
+		Expect(code).NotTo(BeZero())
+		Expect(stderr).To(ContainSubstring("Download failed: checksum mismatch: expected sha256 " + checksum))
+	})
+})
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/render"
+	"github.com/spf13/cobra"
+)
+
+// eventsWarnings restricts caib events to warnings
+var eventsWarnings bool
+
+// newEventsCmd returns the "events" command
+func newEventsCmd() *cobra.Command {
+	eventsCmd := &cobra.Command{
+		Use:   "events <build-name>",
+		Short: "Show what happened to a build, e.g. pod scheduling, image pulls and volume binding",
+		Long: `Show the milestones of a build (created, started, condition changes, completion) together
+with the Kubernetes Events of the build, its TaskRun, its pods and its workspace, oldest first.
+This is the context of a build stuck in Pending or Building that otherwise needs kubectl access
+to the namespace. Kubernetes keeps Events for about an hour.`,
+		Example: `  caib events my-build
+  caib events my-build --warnings`,
+		Args: cobra.ExactArgs(1),
+		Run:  runEvents,
+	}
+	eventsCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	eventsCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	eventsCmd.Flags().BoolVar(&eventsWarnings, "warnings", false, "only show warnings")
+	return eventsCmd
+}
+
+func runEvents(_ *cobra.Command, args []string) {
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	eventType := ""
+	if eventsWarnings {
+		eventType = "Warning"
+	}
+	resp, err := api.GetBuildEvents(ctx, args[0], eventType)
+	if err != nil {
+		handleError(err)
+	}
+	if len(resp.Events) == 0 {
+		fmt.Printf("No events for %s\n", args[0])
+		return
+	}
+	now := time.Now()
+	fmt.Printf("%-10s %-8s %-24s %-40s %s\n", "AGE", "TYPE", "REASON", "OBJECT", "MESSAGE")
+	for _, ev := range resp.Events {
+		message := ev.Message
+		if ev.Count > 1 {
+			message = fmt.Sprintf("%s (x%d)", message, ev.Count)
+		}
+		fmt.Printf("%-10s %-8s %-24s %-40s %s\n", render.Timestamp(ev.Time, now), ev.Type, ev.Reason, ev.Object, message)
+	}
+}
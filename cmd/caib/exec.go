@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	buildapitypes "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+	buildapiclient "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi/client"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+var (
+	execContainer  string
+	execTTY        bool
+	debugRerunHold int32
+	debugName      string
+)
+
+// defaultExecShell is started when no command is given; bash is not available in every builder image
+var defaultExecShell = []string{"/bin/sh", "-c", "command -v bash >/dev/null && exec bash || exec sh"}
+
+// newExecCmd returns the "exec" command, which runs a command in the pod of a running or held build
+func newExecCmd() *cobra.Command {
+	execCmd := &cobra.Command{
+		Use:   "exec <name> [-- command...]",
+		Short: "Run a command or open a shell in the pod of a running build",
+		Long: `Run a command in the pod of a running build, or in the pod of a failed build started
+with --debug-hold. Without a command an interactive shell is opened. The osbuild workspace is
+in /_build, outputs in /output and the manifest in /manifest-work.`,
+		Args: cobra.MinimumNArgs(1),
+		Run:  runExec,
+	}
+	execCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	execCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	execCmd.Flags().StringVarP(&execContainer, "container", "c", "", "step container to run in (default: the debug hold or the running step)")
+	execCmd.Flags().BoolVarP(&execTTY, "tty", "t", false, "allocate a terminal (default when stdin is a terminal and no command is given)")
+	return execCmd
+}
+
+// newDebugCmd returns the "debug" command, which reruns a failed build with its pod kept alive and opens a shell in it
+func newDebugCmd() *cobra.Command {
+	debugCmd := &cobra.Command{
+		Use:   "debug <name>",
+		Short: "Open a shell in the pod of a failed build, rerunning it with the pod kept alive",
+		Long: `Open a shell in the pod of a failed build. Build pods are gone once a build finished, so
+unless the build was started with --debug-hold it is rerun under a new name with the same inputs
+and the pod is kept for --hold minutes after the build step fails.`,
+		Args: cobra.ExactArgs(1),
+		Run:  runDebug,
+	}
+	debugCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	debugCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	debugCmd.Flags().Int32Var(&debugRerunHold, "hold", 60, "minutes to keep the pod after the build step fails")
+	debugCmd.Flags().StringVar(&debugName, "name", "", "name of the rerun build (default: <name>-debug-<timestamp>)")
+	debugCmd.Flags().IntVar(&timeout, "timeout", 60, "minutes to wait for the rerun build to fail")
+	return debugCmd
+}
+
+func runExec(cmd *cobra.Command, args []string) {
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	command := args[1:]
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		command = args[dash:]
+	}
+	if err := execInBuild(api, args[0], command, cmd.Flags().Changed("tty")); err != nil {
+		handleError(err)
+	}
+}
+
+func runDebug(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	name := args[0]
+	st, err := api.GetBuild(ctx, name)
+	if err != nil {
+		handleError(err)
+	}
+
+	switch {
+	case st.DebugPod != "":
+		fmt.Printf("Build %s is held for debugging until %s\n", name, st.DebugHeldUntil)
+	case st.Phase == "Completed":
+		handleError(fmt.Errorf("build %s completed successfully, nothing to debug", name))
+	case st.Phase == "Cancelled":
+		handleError(fmt.Errorf("build %s was cancelled, rerun it to debug", name))
+	case st.Phase == "Failed":
+		if debugName == "" {
+			debugName = fmt.Sprintf("%s-debug-%s", name, time.Now().Format("20060102-150405"))
+		}
+		patch := fmt.Sprintf(`{"debug":{"holdMinutes":%d}}`, debugRerunHold)
+		resp, err := api.CloneBuild(ctx, name, buildapitypes.BuildCloneRequest{Name: debugName, Patch: patch})
+		if err != nil {
+			handleError(err)
+		}
+		fmt.Printf("Rerunning %s as %s with the pod kept %d minutes after a failure\n", name, resp.Name, debugRerunHold)
+		name = resp.Name
+		if st, err = waitForDebugHold(ctx, api, name); err != nil {
+			handleError(err)
+		}
+		fmt.Printf("Build step failed; pod %s is held until %s\n", st.DebugPod, st.DebugHeldUntil)
+	default:
+		fmt.Printf("Build %s is still running; its pod is only kept after a failure when it was started with --debug-hold\n", name)
+	}
+
+	fmt.Println("Run 'touch /tmp/release-debug-hold' to release the pod early")
+	if err := execInBuild(api, name, nil, false); err != nil {
+		handleError(err)
+	}
+}
+
+// waitForDebugHold polls a build started with a debug hold until its pod is held or the build finishes
+func waitForDebugHold(ctx context.Context, api *buildapiclient.Client, name string) (*buildapitypes.BuildResponse, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Minute)
+	defer cancel()
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	var lastPhase string
+	for {
+		select {
+		case <-timeoutCtx.Done():
+			return nil, fmt.Errorf("timed out waiting for build %s to fail", name)
+		case <-ticker.C:
+			st, err := api.GetBuild(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			if st.Phase != lastPhase {
+				fmt.Printf("status: %s\n", st.Phase)
+				lastPhase = st.Phase
+			}
+			switch {
+			case st.DebugPod != "":
+				return st, nil
+			case st.Phase == "Completed":
+				return nil, fmt.Errorf("build %s completed successfully this time, nothing to debug", name)
+			case st.Phase == "Failed":
+				return nil, fmt.Errorf("build %s failed before a pod could be held: %s", name, st.Message)
+			case st.Phase == "Cancelled":
+				return nil, fmt.Errorf("build %s was cancelled: %s", name, st.Message)
+			}
+		}
+	}
+}
+
+// execInBuild runs command in the build pod, or an interactive shell when command is empty
+func execInBuild(api *buildapiclient.Client, name string, command []string, ttyRequested bool) error {
+	stdinFd := int(os.Stdin.Fd())
+	tty := execTTY
+	if !ttyRequested {
+		tty = len(command) == 0 && term.IsTerminal(stdinFd)
+	}
+	if len(command) == 0 {
+		command = defaultExecShell
+	}
+
+	opts := buildapiclient.ExecOptions{
+		Command:   command,
+		Container: strings.TrimSpace(execContainer),
+		Stdin:     os.Stdin,
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
+		TTY:       tty,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+	if tty && term.IsTerminal(stdinFd) {
+		state, err := term.MakeRaw(stdinFd)
+		if err != nil {
+			return fmt.Errorf("set terminal to raw mode: %w", err)
+		}
+		defer term.Restore(stdinFd, state)
+		opts.TerminalSizeQueue = newTerminalSizeQueue(ctx, int(os.Stdout.Fd()))
+	}
+	return api.Exec(ctx, name, opts)
+}
+
+// terminalSizeQueue reports the local terminal size initially and whenever it changes
+type terminalSizeQueue struct {
+	ctx   context.Context
+	fd    int
+	last  remotecommand.TerminalSize
+	first bool
+}
+
+func newTerminalSizeQueue(ctx context.Context, fd int) *terminalSizeQueue {
+	return &terminalSizeQueue{ctx: ctx, fd: fd, first: true}
+}
+
+// Next blocks until the terminal size changes; polling keeps this portable to platforms without SIGWINCH
+func (q *terminalSizeQueue) Next() *remotecommand.TerminalSize {
+	for {
+		w, h, err := term.GetSize(q.fd)
+		if err == nil {
+			size := remotecommand.TerminalSize{Width: uint16(w), Height: uint16(h)}
+			if q.first || size != q.last {
+				q.first = false
+				q.last = size
+				return &size
+			}
+		}
+		select {
+		case <-q.ctx.Done():
+			return nil
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// keyringService is the service name under which caib stores tokens in the OS keyring
+const keyringService = "caib"
+
+var errKeyringUnsupported = errors.New("OS keyring is not supported on this platform")
+
+// errKeyringNotFound is returned by the keyring helpers when there is no entry for the server
+var errKeyringNotFound = errors.New("no keyring entry")
+
+// keyringOS selects the keyring helper; tests point it at other platforms
+var keyringOS = runtime.GOOS
+
+// exit statuses of the keyring helpers for missing entries: errSecItemNotFound of security(1),
+// and the failure of secret-tool(1), which then prints nothing
+const (
+	securityNotFound   = 44
+	secretToolNotFound = 1
+)
+
+// keyringAccount normalizes a server URL so tokens are stored per scheme+host+path
+func keyringAccount(server string) string {
+	s := strings.TrimSpace(server)
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return strings.TrimRight(s, "/")
+	}
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + strings.TrimRight(u.Path, "/")
+}
+
+// keyringGet returns the token stored for server. Missing entries return an empty token and no
+// error; failures of the keyring, e.g. a locked keychain, are returned with the helper's message.
+// macOS uses the login Keychain via security(1), Linux the Secret Service via secret-tool(1) and
+// Windows the Credential Manager.
+func keyringGet(server string) (string, error) {
+	account := keyringAccount(server)
+	var token string
+	var err error
+	switch keyringOS {
+	case "darwin":
+		token, err = runKeyringHelper(exec.Command("security", "find-generic-password", "-s", keyringService, "-a", account, "-w"),
+			func(status int, _ string) bool { return status == securityNotFound })
+	case "linux", "freebsd", "openbsd", "netbsd":
+		token, err = runKeyringHelper(exec.Command("secret-tool", "lookup", "service", keyringService, "server", account),
+			func(status int, stderr string) bool { return status == secretToolNotFound && stderr == "" })
+	case "windows":
+		token, err = wincredGet(account)
+	default:
+		return "", errKeyringUnsupported
+	}
+	if errors.Is(err, errKeyringNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("keyring lookup failed: %w", err)
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// keyringSet stores token for server, replacing any existing entry
+func keyringSet(server, token string) error {
+	account := keyringAccount(server)
+	var err error
+	switch keyringOS {
+	case "darwin":
+		// Interactive mode reads the command from stdin, keeping the token out of the process list;
+		// -X takes the password hex-encoded so it needs no quoting
+		cmd := exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %q -X %s\n",
+			keyringService, account, hex.EncodeToString([]byte(token))))
+		_, err = runKeyringHelper(cmd, nil)
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd := exec.Command("secret-tool", "store", "--label", "caib token for "+account, "service", keyringService, "server", account)
+		cmd.Stdin = strings.NewReader(token)
+		_, err = runKeyringHelper(cmd, nil)
+	case "windows":
+		err = wincredSet(account, token)
+	default:
+		return errKeyringUnsupported
+	}
+	if err != nil {
+		return fmt.Errorf("keyring store failed: %w", err)
+	}
+	return nil
+}
+
+// keyringDelete removes the token stored for server; deleting a missing entry is not an error
+func keyringDelete(server string) error {
+	account := keyringAccount(server)
+	var err error
+	switch keyringOS {
+	case "darwin":
+		_, err = runKeyringHelper(exec.Command("security", "delete-generic-password", "-s", keyringService, "-a", account),
+			func(status int, _ string) bool { return status == securityNotFound })
+	case "linux", "freebsd", "openbsd", "netbsd":
+		_, err = runKeyringHelper(exec.Command("secret-tool", "clear", "service", keyringService, "server", account),
+			func(status int, stderr string) bool { return status == secretToolNotFound && stderr == "" })
+	case "windows":
+		err = wincredDelete(account)
+	default:
+		return errKeyringUnsupported
+	}
+	if err != nil && !errors.Is(err, errKeyringNotFound) {
+		return fmt.Errorf("keyring delete failed: %w", err)
+	}
+	return nil
+}
+
+// runKeyringHelper runs a keyring helper and returns what it printed. A failure notFound accepts,
+// by exit status and stderr, is errKeyringNotFound; other failures carry the helper's stderr.
+func runKeyringHelper(cmd *exec.Cmd, notFound func(status int, stderr string) bool) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err == nil {
+		return stdout.String(), nil
+	}
+	msg := strings.TrimSpace(stderr.String())
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && notFound != nil && notFound(exitErr.ExitCode(), msg) {
+		return "", errKeyringNotFound
+	}
+	if msg != "" {
+		return "", fmt.Errorf("%s: %w: %s", cmd.Args[0], err, msg)
+	}
+	return "", fmt.Errorf("%s: %w", cmd.Args[0], err)
+}
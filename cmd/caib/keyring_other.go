@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+func wincredGet(string) (string, error) {
+	return "", errKeyringUnsupported
+}
+
+func wincredSet(string, string) error {
+	return errKeyringUnsupported
+}
+
+func wincredDelete(string) error {
+	return errKeyringUnsupported
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = DescribeTable("keyringAccount",
+	func(server, account string) {
+		Expect(keyringAccount(server)).To(Equal(account))
+	},
+	Entry("host", "https://Build-API.Example.com", "https://build-api.example.com"),
+	Entry("trailing slash", " https://build-api.example.com/ ", "https://build-api.example.com"),
+	Entry("path", "HTTPS://build-api.example.com/team-a/", "https://build-api.example.com/team-a"),
+	Entry("not a URL", "build-api:8080/", "build-api:8080"),
+)
+
+var _ = Describe("keyring", func() {
+	var dir, cat string
+
+	// helper installs a fake keyring helper that records its arguments and stdin, then runs script
+	helper := func(name, script string) {
+		Expect(os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+
+			`printf '%s\n' "$@" > '`+filepath.Join(dir, "args")+"'\n"+
+			cat+` > '`+filepath.Join(dir, "stdin")+"'\n"+
+			script+"\n"), 0o755)).To(Succeed())
+	}
+	recorded := func(file string) string {
+		b, err := os.ReadFile(filepath.Join(dir, file))
+		Expect(err).NotTo(HaveOccurred())
+		return string(b)
+	}
+
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip("the keyring helpers are faked with shell scripts")
+		}
+		var err error
+		cat, err = exec.LookPath("cat")
+		Expect(err).NotTo(HaveOccurred())
+		// only the fake helpers can be found
+		dir = GinkgoT().TempDir()
+		GinkgoT().Setenv("PATH", dir)
+	})
+
+	Context("with the Secret Service", func() {
+		BeforeEach(func() {
+			setFlag(&keyringOS, "linux")
+		})
+
+		It("looks up the token of the server", func() {
+			helper("secret-tool", "echo sha256~token")
+			Expect(keyringGet("https://Build-API.example.com/")).To(Equal("sha256~token"))
+			Expect(recorded("args")).To(Equal("lookup\nservice\ncaib\nserver\nhttps://build-api.example.com\n"))
+		})
+
+		It("returns no token without an entry", func() {
+			helper("secret-tool", "exit 1")
+			Expect(keyringGet("https://build-api.example.com")).To(BeEmpty())
+		})
+
+		It("returns failures of the Secret Service with its message", func() {
+			helper("secret-tool", "echo 'Cannot autolaunch D-Bus without X11 $DISPLAY' >&2; exit 1")
+			_, err := keyringGet("https://build-api.example.com")
+			Expect(err).To(MatchError("keyring lookup failed: secret-tool: exit status 1: Cannot autolaunch D-Bus without X11 $DISPLAY"))
+		})
+
+		It("stores the token from stdin", func() {
+			helper("secret-tool", "")
+			Expect(keyringSet("https://build-api.example.com", "sha256~token")).To(Succeed())
+			Expect(recorded("stdin")).To(Equal("sha256~token"))
+			Expect(recorded("args")).To(ContainSubstring("store\n--label\ncaib token for https://build-api.example.com\n"))
+		})
+
+		It("reports why storing failed", func() {
+			helper("secret-tool", "echo 'Prompt dismissed' >&2; exit 1")
+			Expect(keyringSet("https://build-api.example.com", "t")).To(MatchError(ContainSubstring("keyring store failed: secret-tool: exit status 1: Prompt dismissed")))
+		})
+
+		It("deletes entries, missing ones too", func() {
+			helper("secret-tool", "")
+			Expect(keyringDelete("https://build-api.example.com")).To(Succeed())
+			Expect(recorded("args")).To(HavePrefix("clear\n"))
+
+			helper("secret-tool", "exit 1")
+			Expect(keyringDelete("https://build-api.example.com")).To(Succeed())
+
+			helper("secret-tool", "echo 'The collection is locked' >&2; exit 1")
+			Expect(keyringDelete("https://build-api.example.com")).To(MatchError(ContainSubstring("The collection is locked")))
+		})
+
+		It("fails when the helper is not installed", func() {
+			_, err := keyringGet("https://build-api.example.com")
+			Expect(errors.Is(err, exec.ErrNotFound)).To(BeTrue(), "%v", err)
+		})
+	})
+
+	Context("with the macOS Keychain", func() {
+		BeforeEach(func() {
+			setFlag(&keyringOS, "darwin")
+		})
+
+		It("looks up the token of the server", func() {
+			helper("security", "echo sha256~token")
+			Expect(keyringGet("https://build-api.example.com")).To(Equal("sha256~token"))
+			Expect(recorded("args")).To(Equal("find-generic-password\n-s\ncaib\n-a\nhttps://build-api.example.com\n-w\n"))
+		})
+
+		It("returns no token without an entry", func() {
+			helper("security", "echo 'security: SecKeychainSearchCopyNext: The specified item could not be found in the keychain.' >&2; exit 44")
+			Expect(keyringGet("https://build-api.example.com")).To(BeEmpty())
+		})
+
+		It("returns failures of the Keychain with its message", func() {
+			helper("security", "echo 'security: SecKeychainSearchCopyNext: User interaction is not allowed.' >&2; exit 36")
+			_, err := keyringGet("https://build-api.example.com")
+			Expect(err).To(MatchError(ContainSubstring("security: exit status 36: security: SecKeychainSearchCopyNext: User interaction is not allowed.")))
+		})
+
+		It("stores the token hex-encoded through stdin", func() {
+			helper("security", "")
+			Expect(keyringSet("https://build-api.example.com", `to"ken`)).To(Succeed())
+			Expect(recorded("args")).To(Equal("-i\n"))
+			Expect(recorded("stdin")).To(Equal(`add-generic-password -U -s caib -a "https://build-api.example.com" -X ` +
+				hex.EncodeToString([]byte(`to"ken`)) + "\n"))
+		})
+
+		It("deletes entries, missing ones too", func() {
+			helper("security", "exit 44")
+			Expect(keyringDelete("https://build-api.example.com")).To(Succeed())
+			helper("security", "echo 'security: write permissions error' >&2; exit 1")
+			Expect(keyringDelete("https://build-api.example.com")).To(MatchError(ContainSubstring("write permissions error")))
+		})
+	})
+
+	It("rejects platforms without a keyring", func() {
+		setFlag(&keyringOS, "plan9")
+		_, err := keyringGet("https://build-api.example.com")
+		Expect(err).To(MatchError(errKeyringUnsupported))
+		Expect(keyringSet("https://build-api.example.com", "t")).To(MatchError(errKeyringUnsupported))
+		Expect(keyringDelete("https://build-api.example.com")).To(MatchError(errKeyringUnsupported))
+	})
+})
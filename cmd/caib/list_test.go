@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	buildapitypes "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+	buildapiclient "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi/client"
+)
+
+var _ = Describe("list", func() {
+	var (
+		api         *buildapiclient.Client
+		apiVersions []string
+		mu          sync.Mutex
+		paths       []string
+	)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	created, completed := now.Add(-2*time.Hour), now.Add(-time.Hour)
+
+	BeforeEach(func() {
+		paths = nil
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			paths = append(paths, r.URL.Path)
+			mu.Unlock()
+			switch r.URL.Path {
+			case "/v1/info":
+				_ = json.NewEncoder(w).Encode(buildapitypes.InfoResponse{APIVersions: apiVersions})
+			case "/v1/builds":
+				w.Header().Set("X-Total-Count", "3")
+				w.Header().Set("X-Continue", "next")
+				_ = json.NewEncoder(w).Encode([]buildapitypes.BuildListItem{{
+					Name: "nightly", Phase: "Completed", CreatedAt: created.Format(time.RFC3339),
+					StartTime: created.Format(time.RFC3339), CompletionTime: completed.Format(time.RFC3339),
+					Labels: map[string]string{"branch": "main"}, ExpiresAt: "deleted",
+				}})
+			case "/v2/builds":
+				_ = json.NewEncoder(w).Encode(buildapitypes.BuildListV2{TotalCount: 3, Continue: "next", Items: []buildapitypes.BuildV2{{
+					Name: "nightly", Phase: buildapitypes.BuildPhaseCompleted, CreatedAt: created,
+					StartedAt: &created, CompletedAt: &completed, Labels: map[string]string{"branch": "main"},
+					Artifacts: []buildapitypes.BuildArtifact{
+						{Name: "nightly.sigstore.json", Kind: "signature"},
+						{Name: "nightly.raw.xz", Kind: "artifact"},
+					},
+				}}})
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		DeferCleanup(srv.Close)
+		var err error
+		api, err = buildapiclient.New(srv.URL)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	list := func() string {
+		page, err := api.ListBuildsV2(context.Background(), buildapiclient.ListBuildsOptions{Limit: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(page.TotalCount).To(Equal(3))
+		Expect(page.Continue).To(Equal("next"))
+		var out bytes.Buffer
+		writeBuildList(&out, page.Items, false, false, now)
+		return out.String()
+	}
+
+	It("lists with the v2 API when the server serves it", func() {
+		apiVersions = []string{"v1", "v2"}
+		out := list()
+		Expect(paths).To(Equal([]string{"/v1/info", "/v2/builds"}))
+		Expect(out).To(MatchRegexp(`nightly\s+Completed\s+1h\s+2h ago\s+nightly.raw.xz\s+-\s+branch=main`))
+	})
+
+	It("lists with the v1 API of a server that serves only v1", func() {
+		apiVersions = nil
+		out := list()
+		Expect(paths).To(Equal([]string{"/v1/info", "/v1/builds"}))
+		Expect(out).To(MatchRegexp(`nightly\s+Completed\s+1h\s+2h ago\s+deleted\s+branch=main`))
+	})
+})
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	buildapitypes "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+	"github.com/spf13/cobra"
+)
+
+var (
+	localRuntime string
+	localDryRun  bool
+)
+
+// newLocalCmd returns the "local" command group, which runs builds on this machine instead of the cluster
+func newLocalCmd() *cobra.Command {
+	localCmd := &cobra.Command{
+		Use:   "local",
+		Short: "Run builds on this machine with podman, docker or nerdctl instead of the cluster",
+	}
+	localBuildCmd := &cobra.Command{
+		Use:   "build",
+		Short: "Build an image locally by running automotive-image-builder in a container",
+		Long: `Build an image locally by running automotive-image-builder in a privileged container of
+podman, docker or nerdctl, whichever is installed first, or the runtime given with --runtime. It
+accepts the same manifest and build flags as "caib build", so a manifest iterated on locally can be
+submitted to the cluster unchanged.`,
+		Run: runLocalBuild,
+	}
+
+	localBuildCmd.Flags().StringVar(&manifest, "manifest", "", "path to manifest YAML file for the build")
+	localBuildCmd.Flags().StringVar(&buildName, "name", "", "base name of the output file (default: <distro>-<target>)")
+	localBuildCmd.Flags().StringVar(&distro, "distro", "autosd", "distribution to build")
+	localBuildCmd.Flags().StringVar(&target, "target", "qemu", "target platform (qemu, etc)")
+	localBuildCmd.Flags().StringVar(&architecture, "arch", "arm64", "architecture (amd64, arm64)")
+	localBuildCmd.Flags().StringVar(&exportFormat, "export", "image", "export format (image, qcow2, etc)")
+	localBuildCmd.Flags().StringVar(&mode, "mode", "image", "build mode")
+	localBuildCmd.Flags().StringVar(&automotiveImageBuilder, "automotive-image-builder", "quay.io/centos-sig-automotive/automotive-image-builder:1.0.0", "container image for automotive-image-builder")
+	localBuildCmd.Flags().StringArrayVar(&customDefs, "define", []string{}, "Custom definition in KEY=VALUE format (can be specified multiple times)")
+	localBuildCmd.Flags().StringArrayVar(&defineFiles, "define-file", nil, "YAML file of defines (KEY: VALUE) applied before --define (can be specified multiple times)")
+	localBuildCmd.Flags().StringSliceVar(&defineProfiles, "define-profile", nil, "profile sections of the --define-file files to apply, comma-separated or repeated")
+	localBuildCmd.Flags().StringVar(&aibExtraArgs, "aib-args", "", "extra arguments passed to automotive-image-builder (space-separated)")
+	localBuildCmd.Flags().StringVar(&aibOverrideArgs, "override", "", "override arguments passed as-is to automotive-image-builder")
+	localBuildCmd.Flags().StringVar(&outputDir, "output-dir", "./output", "directory for the image and the build cache")
+	localBuildCmd.Flags().StringVar(&localRuntime, "runtime", os.Getenv("CAIB_RUNTIME"), "container runtime command: podman, docker or nerdctl, optionally with a prefix such as \"sudo podman\" (default: the first installed)")
+	localBuildCmd.Flags().StringVar(&localRuntime, "podman", localRuntime, "podman command")
+	localBuildCmd.Flags().MarkDeprecated("podman", "use --runtime")
+	localBuildCmd.Flags().BoolVar(&localDryRun, "dry-run", false, "print the container runtime command instead of running it")
+	localBuildCmd.MarkFlagRequired("manifest")
+
+	localCmd.AddCommand(localBuildCmd)
+	return localCmd
+}
+
+func runLocalBuild(cmd *cobra.Command, args []string) {
+	manifestPath, err := filepath.Abs(manifest)
+	if err != nil {
+		handleError(err)
+	}
+	if _, err := os.Stat(manifestPath); err != nil {
+		handleError(fmt.Errorf("error reading manifest: %w", err))
+	}
+	outDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		handleError(err)
+	}
+
+	rt, err := detectRuntime(localRuntime)
+	if err != nil && localDryRun && localRuntime == "" {
+		// the printed command may be meant for another machine
+		rt, err = containerRuntime{kind: "podman", command: []string{"podman"}}, nil
+	}
+	if err != nil {
+		handleError(err)
+	}
+	runArgs, outFile, err := localBuildArgs(rt, manifestPath, outDir)
+	if err != nil {
+		handleError(err)
+	}
+	command := append(slices.Clone(rt.command), runArgs...)
+
+	if localDryRun {
+		fmt.Println(shellJoin(command))
+		return
+	}
+	if rt.rootless() {
+		fmt.Fprintf(os.Stderr, "Warning: %s runs rootless, but automotive-image-builder needs root; if the build fails, rerun with --runtime \"sudo %s\"\n", rt.kind, rt.kind)
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		handleError(fmt.Errorf("create output dir: %w", err))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	c := exec.CommandContext(ctx, command[0], command[1:]...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	fmt.Printf("Running %s\n", shellJoin(command))
+	if err := c.Run(); err != nil {
+		handleError(fmt.Errorf("local build failed: %w", err))
+	}
+	fmt.Printf("Image written to %s\n", outFile)
+}
+
+// localBuildArgs returns the "run" arguments of rt that build manifestPath into outDir the way the
+// cluster build step does, and the path of the resulting image on this machine. The manifest directory
+// is mounted at the same path so relative file references in the manifest resolve unchanged.
+func localBuildArgs(rt containerRuntime, manifestPath, outDir string) ([]string, string, error) {
+	arch := architecture
+	switch arch {
+	case "amd64":
+		arch = "x86_64"
+	case "arm64":
+		arch = "aarch64"
+	}
+
+	override := strings.Fields(aibOverrideArgs)
+	name, export := distro+"-"+target, exportFormat
+	if len(override) > 0 {
+		if v := flagValue(override, "--distro"); v != "" {
+			name = v + "-" + target
+		}
+		if v := flagValue(override, "--target"); v != "" {
+			name = strings.TrimSuffix(name, "-"+target) + "-" + v
+		}
+		if v := flagValue(override, "--export"); v != "" {
+			export = v
+		}
+	}
+	if strings.TrimSpace(buildName) != "" {
+		name = strings.TrimSpace(buildName)
+	}
+	ext := "." + export
+	switch export {
+	case "image":
+		ext = ".raw"
+	case "qcow2":
+		ext = ".qcow2"
+	}
+	fileName := name + ext
+
+	manifestDir := filepath.Dir(manifestPath)
+	args := append([]string{"run", "--rm", "--privileged"}, rt.runOptions()...)
+	args = append(args,
+		"-v", "/dev:/dev",
+		"-v", manifestDir+":"+manifestDir,
+		"-v", outDir+":/output",
+		"-w", manifestDir,
+		automotiveImageBuilder,
+		"automotive-image-builder", "--verbose", "build",
+	)
+	defines, err := resolveDefines()
+	if err != nil {
+		return nil, "", err
+	}
+	for _, def := range defines {
+		args = append(args, "--define", def)
+	}
+	args = append(args, "--build-dir=/output/_build")
+	if len(override) > 0 {
+		args = append(args, override...)
+	} else {
+		if _, err := buildapitypes.ParseArchitecture(architecture); err != nil {
+			return nil, "", err
+		}
+		args = append(args, "--distro", distro, "--target", target, "--arch="+arch, "--export", exportFormat)
+		if mode != "" {
+			args = append(args, "--mode", mode)
+		}
+		args = append(args, strings.Fields(aibExtraArgs)...)
+	}
+	args = append(args, manifestPath, "/output/"+fileName)
+	return args, filepath.Join(outDir, fileName), nil
+}
+
+// flagValue returns the value of --name=value or --name value in args
+func flagValue(args []string, name string) string {
+	for i, a := range args {
+		if v, ok := strings.CutPrefix(a, name+"="); ok {
+			return v
+		}
+		if a == name && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// shellJoin quotes args for display so the printed command can be pasted into a shell
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a != "" && !strings.ContainsAny(a, " \t\n'\"$`\\|&;<>()*?[]{}!#~") {
+			quoted[i] = a
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
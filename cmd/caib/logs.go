@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsOutput string
+	logsGzip   bool
+)
+
+// newLogsCmd returns the "logs" command
+func newLogsCmd() *cobra.Command {
+	logsCmd := &cobra.Command{
+		Use:   "logs <build-name>",
+		Short: "Download the complete logs of a finished build",
+		Long: `Download the logs of every step of a finished build. The operator archives the logs when the
+build pod finishes, so they remain available after the pod is gone, for as long as the build
+exists. Use caib build --follow or caib search-logs for builds that are still running.`,
+		Example: `  caib logs my-build
+  caib logs my-build -o my-build.log.gz`,
+		Args: cobra.ExactArgs(1),
+		Run:  runLogs,
+	}
+	logsCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	logsCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	logsCmd.Flags().StringVarP(&logsOutput, "output", "o", "", "file to write the logs to (default: standard output); gzip compressed when it ends in .gz")
+	logsCmd.Flags().BoolVar(&logsGzip, "gzip", false, "write the gzip archive as is, regardless of the output file name")
+	return logsCmd
+}
+
+func runLogs(_ *cobra.Command, args []string) {
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	compressed := logsGzip || strings.HasSuffix(logsOutput, ".gz")
+
+	var w io.Writer = os.Stdout
+	if logsOutput != "" {
+		f, err := os.Create(logsOutput)
+		if err != nil {
+			handleError(fmt.Errorf("create %s: %w", logsOutput, err))
+		}
+		defer f.Close()
+		w = f
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	if err := api.DownloadLogArchive(ctx, args[0], !compressed, w); err != nil {
+		if logsOutput != "" {
+			_ = os.Remove(logsOutput)
+		}
+		handleError(err)
+	}
+	if logsOutput != "" {
+		fmt.Fprintf(os.Stderr, "Logs of %s written to %s\n", args[0], logsOutput)
+	}
+}
@@ -4,6 +4,10 @@ import (
 	"archive/tar"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,15 +15,20 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"gopkg.in/yaml.v3"
 
 	buildapitypes "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
 	buildapiclient "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi/client"
-	progressbar "github.com/schollz/progressbar/v3"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/commitstatus"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/render"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -35,11 +44,21 @@ var (
 	mode                   string
 	automotiveImageBuilder string
 	storageClass           string
+	workspaceAccessMode    string
+	buildPriority          string
+	keepFor                string
+	keepLast               int32
+	keepPrefix             string
 	outputDir              string
+	downloadStdout         bool
+	outputName             string
+	acceptCompression      []string
 	timeout                int
 	waitForBuild           bool
 	download               bool
 	customDefs             []string
+	defineFiles            []string
+	defineProfiles         []string
 	followLogs             bool
 	version                string
 	aibExtraArgs           string
@@ -47,8 +66,78 @@ var (
 	compressArtifacts      bool
 	compressionAlgo        string
 	authToken              string
+	fromImageBuild         string
+	patchFile              string
+	catalogTarget          string
+	firstBootFile          string
+	firstBootFormat        string
+	firstBootMode          string
+	manifestOutput         string
+	sshKeyFile             string
+	testUser               string
+	loginTokenStdin        bool
+	loginFromKubeconfig    bool
+	hardeningProfiles      []string
+	networkExceptions      []string
+	remoteFilesSecret      string
+	gitURL                 string
+	gitRef                 string
+	gitPath                string
+	runtimeClass           string
+	capabilitiesAIBImage   string
+	complianceProfile      string
+	complianceDataStream   string
+	complianceEnforce      bool
+	complianceOutputDir    string
+	statsSince             string
+	statsFailures          bool
+	statsBucket            string
+	sizeBudget             string
+	sizeBudgetAction       string
+	buildLabels            []string
+	buildAnnotations       []string
+	listSelector           string
+	listPhases             []string
+	listArch               string
+	listCreatedAfter       string
+	listLimit              int
+	listContinue           string
+	listAllNamespaces      bool
+	listNamespaces         []string
+	listOutput             string
+	bootTest               bool
+	bootTimeout            int32
+	bootReadyMarker        string
+	bootMaxKernelToReady   string
+	bootEnforce            bool
+	debugHold              int32
+	requireEncryption      bool
+	scrubWorkspace         bool
+	signKeySecret          string
+	signKeyless            bool
+	buildCheck             bool
+	webhookURLs            []string
+	webhookSecret          string
+	commitStatus           bool
+	commitStatusContext    string
+	idempotencyKey         string
+	deduplicate            bool
+	impersonateUser        string
+	impersonateGroups      []string
+	buildNamespace         string
+	downloadList           bool
+	downloadFile           string
+	downloadHistory        bool
+	downloadNames          []string
+	downloadSelector       string
+	downloadConcurrency    int
+	downloadDecompress     bool
+	noColor                bool
 )
 
+// renderer formats phases, durations and timestamps on stdout; set once flags are parsed
+var renderer render.Renderer
+
 func main() {
 	rootCmd := &cobra.Command{
 		Use:     "caib",
@@ -58,6 +147,23 @@ func main() {
 
 	rootCmd.InitDefaultVersionFlag()
 	rootCmd.SetVersionTemplate("caib version: {{.Version}}\n")
+	rootCmd.PersistentFlags().StringVar(&progressMode, "progress", progressAuto,
+		"progress display: auto (bars on a terminal, periodic lines otherwise), plain or none")
+	rootCmd.PersistentFlags().StringVar(&impersonateUser, "as", "",
+		"user to act as, like kubectl --as; requires permission to impersonate the user in the cluster")
+	rootCmd.PersistentFlags().StringArrayVar(&impersonateGroups, "as-group", nil,
+		"group to act as, like kubectl --as-group (repeatable; requires --as)")
+	rootCmd.PersistentFlags().StringVarP(&buildNamespace, "namespace", "n", os.Getenv("CAIB_NAMESPACE"),
+		"namespace of the builds, when the build API serves several (default: the namespace of the build API)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false,
+		"disable colored output; also disabled by setting NO_COLOR or when stdout is not a terminal")
+	rootCmd.PersistentPreRunE = func(_ *cobra.Command, _ []string) error {
+		if len(impersonateGroups) > 0 && strings.TrimSpace(impersonateUser) == "" {
+			return fmt.Errorf("--as-group requires --as")
+		}
+		renderer = render.New(os.Stdout, noColor)
+		return validateProgressMode()
+	}
 
 	buildCmd := &cobra.Command{
 		Use:   "build",
@@ -77,10 +183,102 @@ func main() {
 		Run:   runList,
 	}
 
+	getManifestCmd := &cobra.Command{
+		Use:   "get-manifest <name>",
+		Short: "Print or save the manifest a build was created from",
+		Args:  cobra.ExactArgs(1),
+		Run:   runGetManifest,
+	}
+
+	complianceCmd := &cobra.Command{
+		Use:   "compliance <name>",
+		Short: "Show the OpenSCAP compliance scan result of a build",
+		Long: `Show the OpenSCAP compliance scan result of a build. With --output the ARF
+results and the HTML report are downloaded. Exits with status 1 unless the scan passed.`,
+		Args: cobra.ExactArgs(1),
+		Run:  runCompliance,
+	}
+
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show build counts, durations, artifact sizes and failure reasons",
+		Long: `Aggregate the builds created within --since (e.g. 7d, 24h): counts by phase, target and
+architecture, success rate, build duration percentiles per target/architecture, artifact sizes and
+the most frequent failure messages. With --failures, failed builds are instead grouped by
+classified reason and error signature, with failures per --bucket to show trends.`,
+		Run: runStats,
+	}
+
+	loginCmd := &cobra.Command{
+		Use:   "login",
+		Short: "Store a Build API token in the OS keyring",
+		Long: `Store a Build API token for --server in the OS keyring (macOS Keychain,
+Secret Service on Linux or Windows Credential Manager). Later commands use it when
+--token/CAIB_TOKEN is not set.
+The token is read from a prompt, from stdin with --token-stdin, or from the current
+kubeconfig context.`,
+		Run: runLogin,
+	}
+
+	logoutCmd := &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the stored Build API token from the OS keyring",
+		Run:   runLogout,
+	}
+
+	catalogCmd := &cobra.Command{
+		Use:   "catalog",
+		Short: "Inspect operator-managed build catalogs",
+	}
+
+	catalogDefinesCmd := &cobra.Command{
+		Use:   "defines",
+		Short: "Show the default defines builds inherit per target",
+		Run:   runCatalogDefines,
+	}
+	catalogHardeningCmd := &cobra.Command{
+		Use:   "hardening",
+		Short: "List the hardening profiles builds can select with --hardening",
+		Run:   runCatalogHardening,
+	}
+	catalogCmd.AddCommand(catalogDefinesCmd, catalogHardeningCmd)
+
+	distrosCmd := &cobra.Command{
+		Use:   "distros",
+		Short: "List the distros the server's automotive-image-builder supports",
+		Run: func(cmd *cobra.Command, args []string) {
+			runCapabilities(func(c *buildapitypes.CapabilitiesResponse) []string { return c.Distros })
+		},
+	}
+	targetsCmd := &cobra.Command{
+		Use:   "targets",
+		Short: "List the targets the server's automotive-image-builder supports",
+		Run: func(cmd *cobra.Command, args []string) {
+			runCapabilities(func(c *buildapitypes.CapabilitiesResponse) []string { return c.Targets })
+		},
+	}
+	formatsCmd := &cobra.Command{
+		Use:   "formats",
+		Short: "List the export formats the server's automotive-image-builder supports",
+		Run: func(cmd *cobra.Command, args []string) {
+			runCapabilities(func(c *buildapitypes.CapabilitiesResponse) []string { return c.ExportFormats })
+		},
+	}
+	compressionsCmd := &cobra.Command{
+		Use:   "compressions",
+		Short: "List the algorithms the server can compress artifacts with (--compression)",
+		Run: func(cmd *cobra.Command, args []string) {
+			runCapabilities(func(c *buildapitypes.CapabilitiesResponse) []string { return c.Compressions })
+		},
+	}
+
 	buildCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
 	buildCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
 	buildCmd.Flags().StringVar(&imageBuildCfg, "config", "", "path to ImageBuild YAML configuration file")
 	buildCmd.Flags().StringVar(&manifest, "manifest", "", "path to manifest YAML file for the build")
+	buildCmd.Flags().StringVar(&gitURL, "git-url", "", "build the manifest at --git-path of this git repository instead of --manifest; the cluster clones it")
+	buildCmd.Flags().StringVar(&gitRef, "git-ref", "", "branch, tag or commit SHA of --git-url to build (default: the default branch)")
+	buildCmd.Flags().StringVar(&gitPath, "git-path", "", "path of the manifest in --git-url")
 	buildCmd.Flags().StringVar(&buildName, "name", "", "name for the ImageBuild")
 	buildCmd.Flags().StringVar(&distro, "distro", "autosd", "distribution to build")
 	buildCmd.Flags().StringVar(&target, "target", "qemu", "target platform (qemu, etc)")
@@ -89,28 +287,128 @@ func main() {
 	buildCmd.Flags().StringVar(&mode, "mode", "image", "build mode")
 	buildCmd.Flags().StringVar(&automotiveImageBuilder, "automotive-image-builder", "quay.io/centos-sig-automotive/automotive-image-builder:1.0.0", "container image for automotive-image-builder")
 	buildCmd.Flags().StringVar(&storageClass, "storage-class", "", "storage class to use for build workspace PVC")
+	buildCmd.Flags().StringVar(&workspaceAccessMode, "workspace-access-mode", "", "access mode of the build workspace PVC (ReadWriteOnce, ReadWriteMany); defaults to ReadWriteMany when the storage class supports it")
+	buildCmd.Flags().StringVar(&buildPriority, "priority", "", "priority of the build when the server's build concurrency limit is reached (low, normal, high); higher-priority builds start first")
+	buildCmd.Flags().StringVar(&keepFor, "keep-for", "", "delete the artifacts this long after the build completed (e.g. 30d, 72h)")
+	buildCmd.Flags().Int32Var(&keepLast, "keep-last", 0, "keep only the artifacts of the newest N completed builds named --keep-prefix*")
+	buildCmd.Flags().StringVar(&keepPrefix, "keep-prefix", "", "name prefix grouping the builds --keep-last counts (e.g. nightly-)")
 	buildCmd.Flags().IntVar(&timeout, "timeout", 60, "timeout in minutes when waiting for build completion")
 	buildCmd.Flags().BoolVarP(&waitForBuild, "wait", "w", false, "wait for the build to complete")
 	buildCmd.Flags().BoolVarP(&download, "download", "d", false, "automatically download artifacts when build completes")
 	buildCmd.Flags().BoolVar(&compressArtifacts, "compress", true, "compress directory artifacts (tar.gz). For directories, server always compresses.")
 	buildCmd.Flags().BoolVarP(&followLogs, "follow", "f", false, "follow logs of the build")
 	buildCmd.Flags().StringArrayVar(&customDefs, "define", []string{}, "Custom definition in KEY=VALUE format (can be specified multiple times)")
+	buildCmd.Flags().StringArrayVar(&defineFiles, "define-file", nil, "YAML file of defines (KEY: VALUE) applied before --define (can be specified multiple times)")
+	buildCmd.Flags().StringSliceVar(&defineProfiles, "define-profile", nil, "profile sections of the --define-file files to apply, comma-separated or repeated")
 	buildCmd.Flags().StringVar(&aibExtraArgs, "aib-args", "", "extra arguments passed to automotive-image-builder (space-separated)")
 	buildCmd.Flags().StringVar(&aibOverrideArgs, "override", "", "override arguments passed as-is to automotive-image-builder")
-	buildCmd.Flags().StringVar(&compressionAlgo, "compression", "gzip", "artifact compression algorithm (lz4|gzip)")
-	_ = buildCmd.MarkFlagRequired("arch")
+	buildCmd.Flags().StringVar(&compressionAlgo, "compression", "gzip", "artifact compression algorithm (zstd|lz4|gzip)")
+	buildCmd.Flags().StringVar(&fromImageBuild, "from-imagebuild", "", "create the build from an existing ImageBuild's inputs instead of --manifest")
+	buildCmd.Flags().StringVar(&fromImageBuild, "from", "", "shorthand for --from-imagebuild; build flags given alongside (--arch, --define, --export, ...) override the source build")
+	buildCmd.Flags().StringVar(&sshKeyFile, "ssh-key", "", "SSH public key file authorized for --test-user (development/test images only)")
+	buildCmd.Flags().StringVar(&testUser, "test-user", "", "login user (wheel group) injected into the manifest for development/test images")
+	buildCmd.Flags().StringVar(&firstBootFile, "firstboot", "", "first-boot provisioning payload (ignition, cloud-init or combustion) to embed or attach")
+	buildCmd.Flags().StringVar(&firstBootFormat, "firstboot-format", "", "first-boot payload format (ignition|cloud-init|combustion); inferred from content when empty")
+	buildCmd.Flags().StringVar(&firstBootMode, "firstboot-mode", "attach", "attach the payload as a secondary artifact or embed it into the image (attach|embed)")
+	buildCmd.Flags().StringVar(&complianceProfile, "compliance-profile", "", "evaluate the built image against this OpenSCAP profile (e.g. cis)")
+	buildCmd.Flags().StringVar(&complianceDataStream, "compliance-datastream", "", "SCAP data stream path in the build image (default: SSG content for the image OS)")
+	buildCmd.Flags().BoolVar(&complianceEnforce, "compliance-enforce", false, "fail the build when the compliance scan does not pass")
+	buildCmd.Flags().StringArrayVarP(&buildLabels, "label", "l", nil, "label in KEY=VALUE format added to the ImageBuild, e.g. branch or commit (can be specified multiple times)")
+	buildCmd.Flags().StringArrayVar(&buildAnnotations, "annotation", nil, "annotation in KEY=VALUE format added to the ImageBuild (can be specified multiple times)")
+	buildCmd.Flags().BoolVar(&bootTest, "boot-test", false, "boot the built image in QEMU and record boot time KPIs")
+	buildCmd.Flags().Int32Var(&bootTimeout, "boot-timeout", 300, "seconds the image may take to boot during --boot-test")
+	buildCmd.Flags().StringVar(&bootReadyMarker, "boot-ready-marker", "", "regular expression on the serial console marking the end of boot (default: multi-user target or login prompt)")
+	buildCmd.Flags().StringVar(&bootMaxKernelToReady, "boot-max-kernel-to-ready", "", "boot time threshold from kernel start to ready (e.g. 15s)")
+	buildCmd.Flags().BoolVar(&bootEnforce, "boot-enforce", false, "fail the build when the boot test fails or exceeds --boot-max-kernel-to-ready")
+	buildCmd.Flags().StringVar(&outputName, "output-name", "", "template for the downloaded artifact file name, e.g. '{{.Name}}-{{.Arch}}-{{.Distro}}.{{.Ext}}'")
+	buildCmd.Flags().Int32Var(&debugHold, "debug-hold", 0, "keep the build pod this many minutes when the build step fails, to inspect it with caib exec")
+	buildCmd.Flags().BoolVar(&requireEncryption, "require-encryption", false, "fail the build unless the storage class encrypts the workspace at rest")
+	buildCmd.Flags().BoolVar(&scrubWorkspace, "scrub-workspace", false, "overwrite and delete the workspace once the build no longer needs it")
+	buildCmd.Flags().StringVar(&signKeySecret, "sign-key-secret", "", "Secret in the build namespace whose cosign.key the operator signs the artifact with")
+	buildCmd.Flags().BoolVar(&signKeyless, "sign-keyless", false, "have the operator sign the artifact keyless, with a Fulcio certificate for its service account")
+	buildCmd.Flags().StringArrayVar(&webhookURLs, "webhook", nil, "URL notified with a JSON payload on every phase change of the build (can be specified multiple times)")
+	buildCmd.Flags().StringVar(&webhookSecret, "webhook-secret", os.Getenv("CAIB_WEBHOOK_SECRET"), "secret signing the --webhook payloads with HMAC-SHA256")
+	buildCmd.Flags().BoolVar(&commitStatus, "commit-status", false, "report the build as a status of the commit of the GitHub Actions or GitLab CI job running caib, compared with the golden build of the target branch")
+	buildCmd.Flags().StringVar(&commitStatusContext, "commit-status-context", "", "name of the --commit-status status (default automotive/<target>-<arch>)")
+	buildCmd.Flags().StringVar(&idempotencyKey, "idempotency-key", os.Getenv("CAIB_IDEMPOTENCY_KEY"), "key identifying this build request, e.g. a CI job ID; repeating it returns the build the first request created (ignored with --from-imagebuild)")
+	buildCmd.Flags().BoolVar(&deduplicate, "deduplicate", false, "reuse the newest running or completed build of an identical request instead of starting a build (not for manifests with local files or unpinned git refs)")
+	buildCmd.Flags().StringVar(&sizeBudget, "size-budget", "", "largest allowed root filesystem size (e.g. 1536Mi); publishes a size breakdown report")
+	buildCmd.Flags().StringVar(&sizeBudgetAction, "size-budget-action", "fail", "what to do when --size-budget is exceeded (fail|warn)")
+	buildCmd.Flags().StringSliceVar(&hardeningProfiles, "hardening", nil, "hardening profiles to apply, comma-separated or repeated (see caib catalog hardening)")
+	buildCmd.Flags().StringSliceVar(&networkExceptions, "network-exception", nil, "exceptions of the operator's build network policy the build pod may reach, comma-separated or repeated")
+	buildCmd.Flags().StringVar(&runtimeClass, "runtime-class", "", "runtime class to run the build pod with, e.g. a gVisor or Kata Containers class the operator allows")
+	buildCmd.Flags().StringVar(&remoteFilesSecret, "remote-files-secret", "", "Secret with the credentials for add_files source paths that are http(s) or s3 URLs, which the cluster downloads")
+	buildCmd.Flags().BoolVar(&buildCheck, "check", false, "only ask the server whether the build would be accepted (validation, admission and quota), without creating it")
+	buildCmd.Flags().StringVar(&patchFile, "patch", "", "JSON merge patch file (YAML or JSON) applied to the --from-imagebuild inputs")
 
 	downloadCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
 	downloadCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
-	downloadCmd.Flags().StringVar(&buildName, "name", "", "name of the ImageBuild")
+	downloadCmd.Flags().StringSliceVar(&downloadNames, "name", nil, "name of the ImageBuild; repeat to download the artifacts of several builds concurrently")
+	downloadCmd.Flags().StringVarP(&downloadSelector, "selector", "l", "", "download the artifacts of every completed build matching this label selector (e.g. pipeline=1234)")
+	downloadCmd.Flags().IntVar(&downloadConcurrency, "concurrency", 4, "builds downloaded at the same time with several --name or --selector")
 	downloadCmd.Flags().StringVar(&outputDir, "output-dir", "./output", "directory to save artifacts")
-	downloadCmd.MarkFlagRequired("name")
+	downloadCmd.Flags().StringVar(&outputName, "output-name", "", "template for the artifact file name (default: the name the build was created with, or the server's)")
+	downloadCmd.Flags().StringSliceVar(&acceptCompression, "accept-compression", nil, "compressions to accept in order of preference, e.g. none or zstd,gzip (default: as stored)")
+	downloadCmd.Flags().BoolVar(&downloadDecompress, "decompress", false, "decompress gzip and zstd artifacts while downloading, after checking their digest as sent")
+	downloadCmd.Flags().BoolVar(&downloadStdout, "stdout", false, "write the artifact to stdout instead of --output-dir; progress goes to stderr")
+	downloadCmd.Flags().BoolVar(&downloadList, "list", false, "list the files of the build with their sizes and SHA-256 digests instead of downloading")
+	downloadCmd.Flags().StringVar(&downloadFile, "file", "", "download only this file of --list (e.g. a report) to --output-dir, verified against its digest")
+	downloadCmd.Flags().BoolVar(&downloadHistory, "history", false, "show who downloaded the artifacts of the build and when instead of downloading")
+	downloadCmd.Flags().BoolVar(&verifyOpts.enabled, "verify", false, "verify the artifact against its digest and cosign signature before saving it; unverifiable artifacts are not written")
+	downloadCmd.Flags().StringVar(&verifyOpts.key, "key", os.Getenv("CAIB_VERIFY_KEY"), "cosign public key for --verify: a file, KMS URI or k8s://namespace/secret")
+	downloadCmd.Flags().StringVar(&verifyOpts.identity, "certificate-identity", os.Getenv("CAIB_CERTIFICATE_IDENTITY"), "signer identity of keyless signatures for --verify (e.g. an email or workload URI)")
+	downloadCmd.Flags().StringVar(&verifyOpts.issuer, "certificate-oidc-issuer", os.Getenv("CAIB_CERTIFICATE_OIDC_ISSUER"), "OIDC issuer of keyless signatures for --verify")
+	downloadCmd.Flags().StringVar(&verifyOpts.bundle, "bundle", "", "local cosign bundle for --verify instead of the signature of the build")
+	downloadCmd.MarkFlagsOneRequired("name", "selector")
 	downloadCmd.Flags().BoolVar(&compressArtifacts, "compress", true, "compress directory artifacts (tar.gz). For directories, server always compresses.")
 
 	listCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
 	listCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	listCmd.Flags().StringVarP(&listSelector, "selector", "l", "", "label selector to filter builds (e.g. team=adas,branch=main)")
+	listCmd.Flags().StringSliceVar(&listPhases, "phase", nil, "only list builds in these phases (e.g. Failed,Building)")
+	listCmd.Flags().StringVar(&listArch, "arch", "", "only list builds for this architecture")
+	listCmd.Flags().StringVar(&listCreatedAfter, "created-after", "", "only list builds created after an RFC 3339 time or within a window (e.g. 7d, 24h)")
+	listCmd.Flags().IntVar(&listLimit, "limit", 0, "list at most this many builds, newest first (0 lists all)")
+	listCmd.Flags().StringVar(&listContinue, "continue", "", "continue token printed by a previous --limit listing")
+	listCmd.Flags().BoolVarP(&listAllNamespaces, "all-namespaces", "A", false, "list builds of every namespace (requires permission to list imagebuilds cluster-wide)")
+	listCmd.Flags().StringSliceVar(&listNamespaces, "namespaces", nil, "with --all-namespaces, only list builds in these namespaces")
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "", "output format: wide adds the artifact downloads of each build")
+
+	catalogDefinesCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	catalogDefinesCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	catalogDefinesCmd.Flags().StringVar(&catalogTarget, "target", "", "only show defines for this target (e.g. rpi4)")
+
+	catalogHardeningCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	catalogHardeningCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+
+	getManifestCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	getManifestCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	getManifestCmd.Flags().StringVarP(&manifestOutput, "output", "o", "", "write the manifest to this file instead of stdout")
+
+	for _, c := range []*cobra.Command{distrosCmd, targetsCmd, formatsCmd, compressionsCmd} {
+		c.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+		c.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+		c.Flags().StringVar(&capabilitiesAIBImage, "automotive-image-builder", "", "query this automotive-image-builder image instead of the server default")
+	}
 
-	rootCmd.AddCommand(buildCmd, downloadCmd, listCmd)
+	complianceCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	complianceCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	complianceCmd.Flags().StringVarP(&complianceOutputDir, "output", "o", "", "download the ARF results and HTML report to this directory")
+
+	statsCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	statsCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	statsCmd.Flags().StringVar(&statsSince, "since", "7d", "time window of builds to aggregate (e.g. 30d, 24h, 90m)")
+	statsCmd.Flags().BoolVar(&statsFailures, "failures", false, "report failures by reason and error signature")
+	statsCmd.Flags().StringVar(&statsBucket, "bucket", "1d", "width of the trend buckets of --failures (e.g. 1d, 6h)")
+
+	loginCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	loginCmd.Flags().BoolVar(&loginTokenStdin, "token-stdin", false, "read the token from stdin")
+	loginCmd.Flags().BoolVar(&loginFromKubeconfig, "from-kubeconfig", false, "store the token of the current kubeconfig context without prompting")
+
+	logoutCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+
+	rootCmd.AddCommand(buildCmd, downloadCmd, listCmd, catalogCmd, getManifestCmd, loginCmd, logoutCmd,
+		distrosCmd, targetsCmd, formatsCmd, compressionsCmd, complianceCmd, statsCmd, newLocalCmd(), newExecCmd(), newDebugCmd(), newCpCmd(), newWatchCmd(), newCancelCmd(), newDeleteCmd(), newSearchLogsCmd(), newRerunCmd(), newVersionCmd(), newRetentionCmd(), newConformanceCmd(), newValidateCmd(), newEventsCmd(), newBuildRecordsCmd(), newSBOMCmd(), newLogsCmd(), newSyncCmd(), newShareCmd(), newPipelineCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -121,595 +419,2069 @@ func main() {
 func runBuild(cmd *cobra.Command, args []string) {
 	ctx := context.Background()
 
+	// --arch is required for new builds; clones inherit it from the source build
+	if strings.TrimSpace(fromImageBuild) == "" && !cmd.Flags().Changed("arch") {
+		handleError(fmt.Errorf("required flag(s) \"arch\" not set"))
+	}
 	if err := validateBuildRequirements(); err != nil {
 		handleError(err)
 	}
 
-	if serverURL == "" {
-		handleError(fmt.Errorf("--server is required"))
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
 	}
 
-	if serverURL != "" {
-		if strings.TrimSpace(authToken) == "" {
-			if tok, err := loadTokenFromKubeconfig(); err == nil && strings.TrimSpace(tok) != "" {
-				authToken = tok
-			}
-		}
-		var opts []buildapiclient.Option
-		if strings.TrimSpace(authToken) != "" {
-			opts = append(opts, buildapiclient.WithAuthToken(strings.TrimSpace(authToken)))
-		}
-		api, err := buildapiclient.New(serverURL, opts...)
-		if err != nil {
-			handleError(err)
-		}
+	if buildCheck {
+		checkBuildPolicies(ctx, api, cmd)
+		return
+	}
 
-		manifestBytes, err := os.ReadFile(manifest)
-		if err != nil {
-			handleError(fmt.Errorf("error reading manifest: %w", err))
-		}
+	var resp *buildapitypes.BuildResponse
+	var manifestContent string
+	if strings.TrimSpace(fromImageBuild) != "" {
+		resp, manifestContent = cloneImageBuild(ctx, api, cmd)
+	} else {
+		resp, manifestContent = createImageBuild(ctx, api)
+	}
+	if resp.Replayed {
+		fmt.Printf("Reusing build %s of an identical request: %s - %s\n", resp.Name, renderer.Phase(resp.Phase), resp.Message)
+	} else {
+		fmt.Printf("Build %s accepted: %s - %s\n", resp.Name, renderer.Phase(resp.Phase), resp.Message)
+	}
+	if resp.RequestID != "" {
+		fmt.Printf("Request ID: %s\n", resp.RequestID)
+	}
 
-		parsedDistro, err := buildapitypes.ParseDistro(distro)
-		if err != nil {
-			handleError(err)
-		}
-		parsedTarget, err := buildapitypes.ParseTarget(target)
-		if err != nil {
-			handleError(err)
-		}
-		parsedArch, err := buildapitypes.ParseArchitecture(architecture)
+	// a repeated request uploads again only while the build it returned still waits for the files,
+	// e.g. when the first attempt failed during the upload
+	if !resp.Replayed || resp.Phase == "" || resp.Phase == "Pending" || resp.Phase == "Uploading" {
+		uploadLocalFiles(ctx, api, resp.Name, manifestContent)
+	}
+
+	if waitForBuild || followLogs || download {
+		waitForBuildCompletion(ctx, api, resp.Name)
+	}
+}
+
+// createImageBuild submits a new build from the local manifest and flags, returning the response and manifest content
+func createImageBuild(ctx context.Context, api *buildapiclient.Client) (*buildapitypes.BuildResponse, string) {
+	req := newBuildRequest()
+	if req.TestUser != "" {
+		fmt.Printf("Warning: injecting SSH access for user %s; this image is for development/testing only\n", req.TestUser)
+	}
+	validateBeforeBuild(ctx, api, req)
+	resp, err := api.CreateBuildWithKey(ctx, req, strings.TrimSpace(idempotencyKey))
+	if err != nil {
+		handleError(err)
+	}
+	return resp, req.Manifest
+}
+
+// checkBuildPolicies asks the server whether the build described by the flags would be accepted
+// and exits non-zero when it would be rejected. Nothing is created or uploaded.
+func checkBuildPolicies(ctx context.Context, api *buildapiclient.Client, cmd *cobra.Command) {
+	var req buildapitypes.BuildRequest
+	if strings.TrimSpace(fromImageBuild) != "" {
+		patch, err := buildClonePatch(ctx, api, cmd)
 		if err != nil {
 			handleError(err)
 		}
-		parsedExportFormat, err := buildapitypes.ParseExportFormat(exportFormat)
+		tpl, err := api.GetBuildTemplate(ctx, fromImageBuild)
 		if err != nil {
-			handleError(err)
+			handleError(fmt.Errorf("error fetching inputs of %s: %w", fromImageBuild, err))
 		}
-		parsedMode, err := buildapitypes.ParseMode(mode)
-		if err != nil {
+		if req, err = buildapitypes.ApplyBuildPatch(tpl.BuildRequest, patch); err != nil {
 			handleError(err)
 		}
+		req.Name = buildName
+	} else {
+		req = newBuildRequest()
+	}
 
-		var aibArgsArray []string
-		var aibOverrideArray []string
-		if strings.TrimSpace(aibExtraArgs) != "" {
-			aibArgsArray = strings.Fields(aibExtraArgs)
+	res, err := api.EvaluatePolicies(ctx, req)
+	if err != nil {
+		handleError(err)
+	}
+	for _, check := range res.Checks {
+		result := "pass"
+		switch {
+		case check.Skipped:
+			result = "skip"
+		case !check.Passed:
+			result = "FAIL"
 		}
-		if strings.TrimSpace(aibOverrideArgs) != "" {
-			aibOverrideArray = strings.Fields(aibOverrideArgs)
+		if check.Message != "" {
+			fmt.Printf("%-4s  %-9s  %s\n", result, check.Name, check.Message)
+		} else {
+			fmt.Printf("%-4s  %s\n", result, check.Name)
 		}
+	}
+	if !res.Allowed {
+		fmt.Printf("Build %s would be rejected\n", req.Name)
+		os.Exit(1)
+	}
+	fmt.Printf("Build %s would be accepted\n", req.Name)
+}
 
-		req := buildapitypes.BuildRequest{
-			Name:                   buildName,
-			Manifest:               string(manifestBytes),
-			ManifestFileName:       filepath.Base(manifest),
-			Distro:                 parsedDistro,
-			Target:                 parsedTarget,
-			Architecture:           parsedArch,
-			ExportFormat:           parsedExportFormat,
-			Mode:                   parsedMode,
-			AutomotiveImageBuilder: automotiveImageBuilder,
-			StorageClass:           storageClass,
-			CustomDefs:             customDefs,
-			AIBExtraArgs:           aibArgsArray,
-			AIBOverrideArgs:        aibOverrideArray,
-			ServeArtifact:          download,
-			Compression:            compressionAlgo,
+// newBuildRequest assembles a BuildRequest from the local manifest and the build flags
+func newBuildRequest() buildapitypes.BuildRequest {
+	var manifestBytes []byte
+	var manifestFileName string
+	var source *buildapitypes.BuildSource
+	if gitURL != "" {
+		source = &buildapitypes.BuildSource{Git: &buildapitypes.GitSource{
+			URL:  strings.TrimSpace(gitURL),
+			Ref:  strings.TrimSpace(gitRef),
+			Path: strings.TrimSpace(gitPath),
+		}}
+	} else {
+		var err error
+		if manifestBytes, err = os.ReadFile(manifest); err != nil {
+			handleError(fmt.Errorf("error reading manifest: %w", err))
 		}
+		manifestFileName = filepath.Base(manifest)
+	}
 
-		resp, err := api.CreateBuild(ctx, req)
-		if err != nil {
+	parsedDistro, err := buildapitypes.ParseDistro(distro)
+	if err != nil {
+		handleError(err)
+	}
+	parsedTarget, err := buildapitypes.ParseTarget(target)
+	if err != nil {
+		handleError(err)
+	}
+	parsedArch, err := buildapitypes.ParseArchitecture(architecture)
+	if err != nil {
+		handleError(err)
+	}
+	parsedExportFormat, err := buildapitypes.ParseExportFormat(exportFormat)
+	if err != nil {
+		handleError(err)
+	}
+	parsedMode, err := buildapitypes.ParseMode(mode)
+	if err != nil {
+		handleError(err)
+	}
+
+	defines, err := resolveDefines()
+	if err != nil {
+		handleError(err)
+	}
+
+	var aibArgsArray []string
+	var aibOverrideArray []string
+	if strings.TrimSpace(aibExtraArgs) != "" {
+		aibArgsArray = strings.Fields(aibExtraArgs)
+	}
+	if strings.TrimSpace(aibOverrideArgs) != "" {
+		aibOverrideArray = strings.Fields(aibOverrideArgs)
+	}
+
+	req := buildapitypes.BuildRequest{
+		Name:                   buildName,
+		Manifest:               string(manifestBytes),
+		ManifestFileName:       manifestFileName,
+		Source:                 source,
+		Distro:                 parsedDistro,
+		Target:                 parsedTarget,
+		Architecture:           parsedArch,
+		ExportFormat:           parsedExportFormat,
+		Mode:                   parsedMode,
+		AutomotiveImageBuilder: automotiveImageBuilder,
+		StorageClass:           storageClass,
+		WorkspaceAccessMode:    workspaceAccessMode,
+		Priority:               buildPriority,
+		Retention:              buildRetention(),
+		CustomDefs:             defines,
+		AIBExtraArgs:           aibArgsArray,
+		AIBOverrideArgs:        aibOverrideArray,
+		ServeArtifact:          download,
+		Compression:            compressionAlgo,
+		HardeningProfiles:      hardeningProfiles,
+		NetworkExceptions:      networkExceptions,
+		RemoteFilesSecretRef:   strings.TrimSpace(remoteFilesSecret),
+		RuntimeClassName:       strings.TrimSpace(runtimeClass),
+		Deduplicate:            deduplicate,
+		OutputName:             strings.TrimSpace(outputName),
+	}
+	if strings.TrimSpace(complianceProfile) != "" {
+		req.Compliance = &buildapitypes.ComplianceScan{
+			Profile:    strings.TrimSpace(complianceProfile),
+			DataStream: strings.TrimSpace(complianceDataStream),
+			Enforce:    complianceEnforce,
+		}
+	}
+	if strings.TrimSpace(sizeBudget) != "" {
+		req.SizeBudget = &buildapitypes.SizeBudget{MaxSize: strings.TrimSpace(sizeBudget), Action: sizeBudgetAction}
+	}
+	if bootTest {
+		req.BootTest = bootTestRequest()
+	}
+	if debugHold > 0 {
+		req.Debug = &buildapitypes.BuildDebug{HoldMinutes: debugHold}
+	}
+	if requireEncryption || scrubWorkspace {
+		req.WorkspaceProtection = &buildapitypes.WorkspaceProtection{RequireEncryption: requireEncryption, Scrub: scrubWorkspace}
+	}
+	if signKeySecret != "" || signKeyless {
+		req.Signing = &buildapitypes.ArtifactSigning{KeySecretRef: signKeySecret, Keyless: signKeyless}
+	}
+	req.Webhooks = webhookRequests()
+	if commitStatus {
+		if req.CommitStatus, err = commitStatusRequest(); err != nil {
 			handleError(err)
 		}
-		fmt.Printf("Build %s accepted: %s - %s\n", resp.Name, resp.Phase, resp.Message)
-		// If manifest references local files, upload them via the API
-		localRefs, err := findLocalFileReferences(string(manifestBytes))
+	}
+	if req.Labels, err = parseKeyValues("--label", buildLabels); err != nil {
+		handleError(err)
+	}
+	if req.Annotations, err = parseKeyValues("--annotation", buildAnnotations); err != nil {
+		handleError(err)
+	}
+
+	if strings.TrimSpace(sshKeyFile) != "" || strings.TrimSpace(testUser) != "" {
+		if strings.TrimSpace(sshKeyFile) == "" || strings.TrimSpace(testUser) == "" {
+			handleError(fmt.Errorf("--ssh-key and --test-user must be used together"))
+		}
+		keys, err := readSSHPublicKeys(sshKeyFile)
 		if err != nil {
-			handleError(fmt.Errorf("manifest file reference error: %w", err))
+			handleError(err)
 		}
-		if len(localRefs) > 0 {
-			for _, ref := range localRefs {
-				if _, err := os.Stat(ref["source_path"]); err != nil {
-					handleError(fmt.Errorf("referenced file %s does not exist: %w", ref["source_path"], err))
-				}
-			}
-
-			fmt.Println("Waiting for upload server to be ready...")
-			readyCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
-			defer cancel()
-			for {
-				if err := readyCtx.Err(); err != nil {
-					handleError(fmt.Errorf("timed out waiting for upload server to be ready"))
-				}
-				reqCtx, c := context.WithTimeout(ctx, 15*time.Second)
-				st, err := api.GetBuild(reqCtx, resp.Name)
-				c()
-				if err == nil {
-					if st.Phase == "Uploading" {
-						break
-					}
-					if st.Phase == "Failed" {
-						handleError(fmt.Errorf("build failed while waiting for upload server: %s", st.Message))
-					}
-				}
-				time.Sleep(3 * time.Second)
-			}
-
-			uploads := make([]buildapiclient.Upload, 0, len(localRefs))
-			for _, ref := range localRefs {
-				uploads = append(uploads, buildapiclient.Upload{SourcePath: ref["source_path"], DestPath: ref["source_path"]})
-			}
+		req.TestUser = strings.TrimSpace(testUser)
+		req.SSHKeys = keys
+	}
 
-			uploadDeadline := time.Now().Add(10 * time.Minute)
-			for {
-				if err := api.UploadFiles(ctx, resp.Name, uploads); err != nil {
-					lower := strings.ToLower(err.Error())
-					if time.Now().After(uploadDeadline) {
-						handleError(fmt.Errorf("upload files failed: %w", err))
-					}
-					if strings.Contains(lower, "503") || strings.Contains(lower, "service unavailable") || strings.Contains(lower, "upload pod not ready") {
-						fmt.Println("Upload server not ready yet. Retrying...")
-						time.Sleep(5 * time.Second)
-						continue
-					}
-					handleError(fmt.Errorf("upload files failed: %w", err))
-				}
-				break
-			}
-			fmt.Println("Local files uploaded. Build will proceed.")
-		}
-
-		if waitForBuild || followLogs || download {
-			fmt.Println("Waiting for build to complete...")
-			timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Minute)
-			defer cancel()
-			ticker := time.NewTicker(5 * time.Second)
-			defer ticker.Stop()
-			userFollowRequested := followLogs
-			var lastPhase, lastMessage string
-			logFollowWarned := false
-
-			logClient := &http.Client{
-				Timeout: 10 * time.Minute,
-				Transport: &http.Transport{
-					ResponseHeaderTimeout: 30 * time.Second,
-					IdleConnTimeout:       2 * time.Minute,
-				},
-			}
-
-			for {
-				select {
-				case <-timeoutCtx.Done():
-					handleError(fmt.Errorf("timed out waiting for build"))
-				case <-ticker.C:
-					if followLogs {
-						req, _ := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(serverURL, "/")+"/v1/builds/"+url.PathEscape(resp.Name)+"/logs?follow=1", nil)
-						if strings.TrimSpace(authToken) != "" {
-							req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(authToken))
-						}
-						resp2, err := logClient.Do(req)
-						if err == nil && resp2.StatusCode == http.StatusOK {
-							fmt.Println("Streaming logs...")
-							io.Copy(os.Stdout, resp2.Body)
-							resp2.Body.Close()
-							followLogs = userFollowRequested
-						} else if resp2 != nil {
-							body, _ := io.ReadAll(resp2.Body)
-							msg := strings.TrimSpace(string(body))
-							if resp2.StatusCode == http.StatusServiceUnavailable || resp2.StatusCode == http.StatusGatewayTimeout {
-								if !logFollowWarned {
-									fmt.Println("log stream not ready (HTTP", resp2.StatusCode, "). Retrying…")
-									logFollowWarned = true
-								}
-								// treat as transient; keep trying silently afterwards
-							} else {
-								if msg != "" {
-									fmt.Printf("log stream error (%d): %s\n", resp2.StatusCode, msg)
-								} else {
-									fmt.Printf("log stream error: HTTP %d\n", resp2.StatusCode)
-								}
-								followLogs = false
-							}
-							resp2.Body.Close()
-						}
-					}
-					reqCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
-					st, err := api.GetBuild(reqCtx, resp.Name)
-					cancel()
-					if err != nil {
-						fmt.Printf("status check failed: %v\n", err)
-						continue
-					}
-					if !userFollowRequested {
-						if st.Phase != lastPhase || st.Message != lastMessage {
-							fmt.Printf("status: %s - %s\n", st.Phase, st.Message)
-							lastPhase = st.Phase
-							lastMessage = st.Message
-						}
-					}
-					if st.Phase == "Completed" {
-						if download {
-							if err := downloadArtifactViaAPI(ctx, serverURL, resp.Name, outputDir); err != nil {
-								fmt.Printf("Download via API failed: %v\n", err)
-							}
-							return
-						}
-						return
-					}
-					if st.Phase == "Failed" {
-						handleError(fmt.Errorf("build failed: %s", st.Message))
-					}
-				}
-			}
+	if strings.TrimSpace(firstBootFile) != "" {
+		fb, err := loadFirstBoot(firstBootFile, firstBootFormat, firstBootMode)
+		if err != nil {
+			handleError(err)
 		}
-		return
+		req.FirstBoot = fb
 	}
-
+	return req
 }
 
-func validateBuildRequirements() error {
-	if manifest == "" {
-		return fmt.Errorf("--manifest is required")
+// readSSHPublicKeys returns the non-comment lines of a public key file, expanding a leading ~
+func readSSHPublicKeys(file string) ([]string, error) {
+	if strings.HasPrefix(file, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			file = filepath.Join(home, file[2:])
+		}
 	}
-
-	if buildName == "" {
-		return fmt.Errorf("name is required")
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading SSH key: %w", err)
 	}
-
-	if strings.TrimSpace(architecture) == "" {
-		return fmt.Errorf("--arch is required")
+	var keys []string
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, line)
 	}
-
-	return nil
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no SSH public keys found in %s", file)
+	}
+	return keys, nil
 }
 
-func handleError(err error) {
-	fmt.Printf("Error: %v\n", err)
-	os.Exit(1)
+// loadFirstBoot reads a first-boot payload, inferring its format from the content when not given
+func loadFirstBoot(file, format, mode string) (*buildapitypes.FirstBoot, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading first-boot payload: %w", err)
+	}
+	content := string(b)
+	if format == "" {
+		trimmed := strings.TrimSpace(content)
+		switch {
+		case strings.HasPrefix(trimmed, "{"):
+			format = "ignition"
+		case strings.HasPrefix(trimmed, "#cloud-config"):
+			format = "cloud-init"
+		default:
+			return nil, fmt.Errorf("cannot infer first-boot format of %s; set --firstboot-format", file)
+		}
+	}
+	return &buildapitypes.FirstBoot{Format: format, Mode: mode, Inline: content}, nil
 }
 
-func findLocalFileReferences(manifestContent string) ([]map[string]string, error) {
-	var manifestData map[string]any
-	var localFiles []map[string]string
+// cloneImageBuild creates a build from an existing ImageBuild's inputs with the --patch file applied server-side
+func cloneImageBuild(ctx context.Context, api *buildapiclient.Client, cmd *cobra.Command) (*buildapitypes.BuildResponse, string) {
+	patch, err := buildClonePatch(ctx, api, cmd)
+	if err != nil {
+		handleError(err)
+	}
 
-	if err := yaml.Unmarshal([]byte(manifestContent), &manifestData); err != nil {
-		return nil, fmt.Errorf("failed to parse manifest YAML: %w", err)
+	resp, err := api.CloneBuild(ctx, fromImageBuild, buildapitypes.BuildCloneRequest{Name: buildName, Patch: patch})
+	if err != nil {
+		handleError(err)
 	}
 
-	isPathSafe := func(path string) error {
-		if path == "" || path == "/" {
-			return fmt.Errorf("empty or root path is not allowed")
-		}
+	tpl, err := api.GetBuildTemplate(ctx, resp.Name)
+	if err != nil {
+		handleError(fmt.Errorf("error fetching cloned build inputs: %w", err))
+	}
+	return resp, tpl.Manifest
+}
 
-		if strings.Contains(path, "..") {
-			return fmt.Errorf("directory traversal detected in path: %s", path)
+// buildClonePatch combines the --patch file with the build flags given on the command line into
+// one merge patch. Flags win over the patch file; --define entries are merged into the source
+// build's defines by KEY instead of replacing the whole list.
+func buildClonePatch(ctx context.Context, api *buildapiclient.Client, cmd *cobra.Command) (string, error) {
+	flags := cmd.Flags()
+	patch := map[string]any{}
+	if strings.TrimSpace(patchFile) != "" {
+		b, err := os.ReadFile(patchFile)
+		if err != nil {
+			return "", fmt.Errorf("error reading patch: %w", err)
 		}
-
-		if filepath.IsAbs(path) {
-			// TODO add safe dirs flag
-			safeDirectories := []string{}
-			isInSafeDir := false
-			for _, dir := range safeDirectories {
-				if strings.HasPrefix(path, dir+"/") {
-					isInSafeDir = true
-					break
-				}
-			}
-			if !isInSafeDir {
-				return fmt.Errorf("absolute path outside safe directories: %s", path)
-			}
+		if err := yaml.Unmarshal(b, &patch); err != nil {
+			return "", fmt.Errorf("error parsing patch: %w", err)
+		}
+		if patch == nil {
+			patch = map[string]any{}
 		}
-
-		return nil
 	}
 
-	processAddFiles := func(addFiles []any) error {
-		for _, file := range addFiles {
-			if fileMap, ok := file.(map[string]any); ok {
-				path, hasPath := fileMap["path"].(string)
-				sourcePath, hasSourcePath := fileMap["source_path"].(string)
-				if hasPath && hasSourcePath {
-					if err := isPathSafe(sourcePath); err != nil {
-						return err
-					}
-					localFiles = append(localFiles, map[string]string{
-						"path":        path,
-						"source_path": sourcePath,
-					})
-				}
-			}
+	overrides := []struct {
+		flag  string
+		field string
+		value string
+	}{
+		{"arch", "architecture", architecture},
+		{"distro", "distro", distro},
+		{"target", "target", target},
+		{"export", "exportFormat", exportFormat},
+		{"mode", "mode", mode},
+		{"automotive-image-builder", "automotiveImageBuilder", automotiveImageBuilder},
+		{"storage-class", "storageClass", storageClass},
+		{"workspace-access-mode", "workspaceAccessMode", workspaceAccessMode},
+		{"priority", "priority", buildPriority},
+		{"compression", "compression", compressionAlgo},
+		{"runtime-class", "runtimeClassName", strings.TrimSpace(runtimeClass)},
+	}
+	for _, o := range overrides {
+		if flags.Changed(o.flag) {
+			patch[o.field] = o.value
 		}
-		return nil
 	}
-
-	if content, ok := manifestData["content"].(map[string]any); ok {
-		if addFiles, ok := content["add_files"].([]any); ok {
-			if err := processAddFiles(addFiles); err != nil {
-				return nil, err
+	if flags.Changed("hardening") {
+		patch["hardeningProfiles"] = hardeningProfiles
+	}
+	if flags.Changed("network-exception") {
+		patch["networkExceptions"] = networkExceptions
+	}
+	if flags.Changed("remote-files-secret") {
+		patch["remoteFilesSecretRef"] = strings.TrimSpace(remoteFilesSecret)
+	}
+	if flags.Changed("git-url") || flags.Changed("git-ref") || flags.Changed("git-path") {
+		source, _ := patch["source"].(map[string]any)
+		if source == nil {
+			source = map[string]any{}
+		}
+		git, _ := source["git"].(map[string]any)
+		if git == nil {
+			git = map[string]any{}
+		}
+		for _, o := range []struct{ flag, field, value string }{{"git-url", "url", gitURL}, {"git-ref", "ref", gitRef}, {"git-path", "path", gitPath}} {
+			if flags.Changed(o.flag) {
+				git[o.field] = strings.TrimSpace(o.value)
 			}
 		}
+		source["git"] = git
+		patch["source"] = source
 	}
-
-	if qm, ok := manifestData["qm"].(map[string]any); ok {
-		if qmContent, ok := qm["content"].(map[string]any); ok {
-			if addFiles, ok := qmContent["add_files"].([]any); ok {
-				if err := processAddFiles(addFiles); err != nil {
-					return nil, err
-				}
-			}
+	if flags.Changed("keep-for") || flags.Changed("keep-last") || flags.Changed("keep-prefix") {
+		patch["retention"] = buildRetention()
+	}
+	if flags.Changed("compliance-profile") {
+		patch["compliance"] = buildapitypes.ComplianceScan{
+			Profile:    strings.TrimSpace(complianceProfile),
+			DataStream: strings.TrimSpace(complianceDataStream),
+			Enforce:    complianceEnforce,
 		}
 	}
+	if flags.Changed("label") {
+		labels, err := parseKeyValues("--label", buildLabels)
+		if err != nil {
+			return "", err
+		}
+		patch["labels"] = labels
+	}
+	if flags.Changed("annotation") {
+		annotations, err := parseKeyValues("--annotation", buildAnnotations)
+		if err != nil {
+			return "", err
+		}
+		patch["annotations"] = annotations
+	}
+	if flags.Changed("boot-test") {
+		if bootTest {
+			patch["bootTest"] = bootTestRequest()
+		} else {
+			patch["bootTest"] = nil
+		}
+	}
+	if flags.Changed("debug-hold") {
+		if debugHold > 0 {
+			patch["debug"] = buildapitypes.BuildDebug{HoldMinutes: debugHold}
+		} else {
+			patch["debug"] = nil
+		}
+	}
+	if flags.Changed("output-name") {
+		patch["outputName"] = strings.TrimSpace(outputName)
+	}
+	if flags.Changed("webhook") {
+		patch["webhooks"] = webhookRequests()
+	}
+	if flags.Changed("size-budget") {
+		patch["sizeBudget"] = buildapitypes.SizeBudget{MaxSize: strings.TrimSpace(sizeBudget), Action: sizeBudgetAction}
+	}
+	if flags.Changed("aib-args") {
+		patch["aibExtraArgs"] = strings.Fields(aibExtraArgs)
+	}
+	if flags.Changed("override") {
+		patch["aibOverrideArgs"] = strings.Fields(aibOverrideArgs)
+	}
+	if download {
+		patch["serveArtifact"] = true
+	}
+	if flags.Changed("define") || flags.Changed("define-file") {
+		defines, err := resolveDefines()
+		if err != nil {
+			return "", err
+		}
+		tpl, err := api.GetBuildTemplate(ctx, fromImageBuild)
+		if err != nil {
+			return "", fmt.Errorf("error fetching source build inputs: %w", err)
+		}
+		patch["customDefs"] = mergeDefines(tpl.CustomDefs, defines)
+	}
 
-	return localFiles, nil
-}
-
-func downloadArtifactViaAPI(ctx context.Context, baseURL, name, outDir string) error {
-	if strings.TrimSpace(outDir) == "" {
-		outDir = "./output"
+	if len(patch) == 0 {
+		return "", nil
 	}
-	if err := os.MkdirAll(outDir, 0755); err != nil {
-		return fmt.Errorf("create output dir: %w", err)
+	b, err := json.Marshal(patch)
+	if err != nil {
+		return "", err
 	}
+	return string(b), nil
+}
 
-	base := strings.TrimRight(baseURL, "/")
-	urlStr := base + "/v1/builds/" + url.PathEscape(name) + "/artifact"
+// parseKeyValues parses repeated KEY=VALUE flag values into a map; nil when none were given
+func parseKeyValues(flag string, items []string) (map[string]string, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(items))
+	for _, item := range items {
+		k, v, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid %s %q: expected KEY=VALUE", flag, item)
+		}
+		out[strings.TrimSpace(k)] = v
+	}
+	return out, nil
+}
 
-	deadline := time.Now().Add(30 * time.Minute)
+// mergeDefines returns base with every KEY=VALUE in overrides replacing the same KEY or appended
+func mergeDefines(base, overrides []string) []string {
+	out := append([]string{}, base...)
+	for _, o := range overrides {
+		key, _, _ := strings.Cut(o, "=")
+		replaced := false
+		for i, b := range out {
+			if k, _, _ := strings.Cut(b, "="); k == key {
+				out[i] = o
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			out = append(out, o)
+		}
+	}
+	return out
+}
 
-	httpClient := &http.Client{
-		Timeout: 30 * time.Minute,
-		Transport: &http.Transport{
-			ResponseHeaderTimeout: 2 * time.Minute,
-			IdleConnTimeout:       5 * time.Minute,
-		},
+// defineFile is the format of --define-file: defines applied to every build and named profiles
+// applied on top with --define-profile. A file without defines and profiles keys is read as a
+// plain mapping of defines.
+type defineFile struct {
+	Defines  map[string]any            `yaml:"defines"`
+	Profiles map[string]map[string]any `yaml:"profiles"`
+}
+
+// resolveDefines flattens the --define-file files, in order and each followed by the selected
+// profiles, into KEY=VALUE defines. Explicit --define flags override defines from files.
+func resolveDefines() ([]string, error) {
+	var defines []string
+	found := map[string]bool{}
+	for _, file := range defineFiles {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading define file: %w", err)
+		}
+		var df defineFile
+		if err := yaml.Unmarshal(b, &df); err != nil {
+			return nil, fmt.Errorf("error parsing define file %s: %w", file, err)
+		}
+		if df.Defines == nil && df.Profiles == nil {
+			if err := yaml.Unmarshal(b, &df.Defines); err != nil {
+				return nil, fmt.Errorf("error parsing define file %s: %w", file, err)
+			}
+		}
+		sections := []map[string]any{df.Defines}
+		for _, profile := range defineProfiles {
+			if section, ok := df.Profiles[profile]; ok {
+				sections = append(sections, section)
+				found[profile] = true
+			}
+		}
+		for _, section := range sections {
+			flat, err := flattenDefines(section)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			defines = mergeDefines(defines, flat)
+		}
+	}
+	for _, profile := range defineProfiles {
+		if !found[profile] {
+			return nil, fmt.Errorf("define profile %q not found in --define-file files", profile)
+		}
+	}
+	for _, def := range customDefs {
+		if k, _, ok := strings.Cut(def, "="); !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid --define %q: expected KEY=VALUE", def)
+		}
+	}
+	return mergeDefines(defines, customDefs), nil
+}
+
+// flattenDefines formats a section of a define file as KEY=VALUE, sorted by key. Strings are used
+// as they are; lists, maps, numbers and booleans are written as JSON, e.g. extra_rpms=["gdb"].
+func flattenDefines(section map[string]any) ([]string, error) {
+	keys := make([]string, 0, len(section))
+	for k := range section {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if strings.TrimSpace(k) == "" || strings.Contains(k, "=") {
+			return nil, fmt.Errorf("invalid define key %q", k)
+		}
+		var value string
+		switch v := section[k].(type) {
+		case nil:
+		case string:
+			value = v
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("define %s: %w", k, err)
+			}
+			value = string(b)
+		}
+		out = append(out, k+"="+value)
 	}
+	return out, nil
+}
 
-	warned := false
+// uploadLocalFiles uploads files referenced by the manifest once the build's upload server is ready
+func uploadLocalFiles(ctx context.Context, api *buildapiclient.Client, name, manifestContent string) {
+	// If manifest references local files, upload them via the API
+	localRefs, err := findLocalFileReferences(manifestContent)
+	if err != nil {
+		handleError(fmt.Errorf("manifest file reference error: %w", err))
+	}
+	if len(localRefs) == 0 {
+		return
+	}
+	for _, ref := range localRefs {
+		if _, err := os.Stat(ref["source_path"]); err != nil {
+			handleError(fmt.Errorf("referenced file %s does not exist: %w", ref["source_path"], err))
+		}
+	}
+
+	fmt.Println("Waiting for upload server to be ready...")
+	// builds queued for storage capacity have not started; the timeout counts from when they do
+	deadline := time.Now().Add(10 * time.Minute)
+	wait := newWaitProgress(os.Stdout)
 	for {
-		if ctx.Err() != nil || time.Now().After(deadline) {
-			return fmt.Errorf("timed out waiting for artifact to become ready")
+		if time.Now().After(deadline) {
+			wait.Clear()
+			handleError(fmt.Errorf("timed out waiting for upload server to be ready"))
 		}
-		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
-		if strings.TrimSpace(authToken) != "" {
-			req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(authToken))
+		reqCtx, c := context.WithTimeout(ctx, 15*time.Second)
+		st, err := api.GetBuild(reqCtx, name)
+		c()
+		if err == nil {
+			if st.Phase == "Uploading" {
+				wait.Clear()
+				break
+			}
+			if st.Phase == "Failed" || st.Phase == "Cancelled" {
+				wait.Clear()
+				handleError(fmt.Errorf("build %s while waiting for upload server: %s", strings.ToLower(st.Phase), st.Message))
+			}
+			if st.Phase == "Queued" {
+				deadline = time.Now().Add(10 * time.Minute)
+			}
+			wait.Update(st.Phase)
 		}
-		resp, err := httpClient.Do(req)
-		if err != nil {
-			time.Sleep(3 * time.Second)
-			continue
+		time.Sleep(3 * time.Second)
+	}
+
+	uploads := make([]buildapiclient.Upload, 0, len(localRefs))
+	var uploadSize int64
+	for _, ref := range localRefs {
+		uploads = append(uploads, buildapiclient.Upload{SourcePath: ref["source_path"], DestPath: ref["source_path"]})
+		if fi, err := os.Stat(ref["source_path"]); err == nil {
+			uploadSize += fi.Size()
 		}
+	}
 
-		if resp.StatusCode == http.StatusOK {
-			filename := name + ".artifact"
-			contentType := resp.Header.Get("Content-Type")
-			if cd := resp.Header.Get("Content-Disposition"); cd != "" {
-				if i := strings.Index(cd, "filename="); i >= 0 {
-					f := strings.Trim(cd[i+9:], "\" ")
-					if f != "" {
-						filename = f
-					}
-				}
+	uploadDeadline := time.Now().Add(10 * time.Minute)
+	for {
+		bar := newTransferProgress(os.Stdout, "Uploading", uploadSize)
+		if err := api.UploadFilesResumable(ctx, name, uploads, bar); err != nil {
+			bar.Abort()
+			lower := strings.ToLower(err.Error())
+			if time.Now().After(uploadDeadline) {
+				handleError(fmt.Errorf("upload files failed: %w", err))
+			}
+			if strings.Contains(lower, "503") || strings.Contains(lower, "service unavailable") || strings.Contains(lower, "upload pod not ready") {
+				fmt.Println("Upload server not ready yet. Retrying...")
+				time.Sleep(5 * time.Second)
+				continue
 			}
-			if at := strings.TrimSpace(resp.Header.Get("X-AIB-Artifact-Type")); at != "" {
-				fmt.Printf("Artifact type: %s\n", at)
+			handleError(fmt.Errorf("upload files failed: %w", err))
+		}
+		bar.Finish()
+		break
+	}
+	fmt.Println("Local files uploaded. Build will proceed.")
+}
+
+// waitForBuildCompletion polls the build until it finishes, optionally following logs and downloading the artifact
+func waitForBuildCompletion(ctx context.Context, api *buildapiclient.Client, name string) {
+	fmt.Println("Waiting for build to complete...")
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Minute)
+	defer cancel()
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	userFollowRequested := followLogs
+	var lastPhase, lastMessage string
+	logFollowWarned := false
+	legacyLogStream := false
+	legacyWatch := false
+	wait := newWaitProgress(os.Stdout)
+
+	// handleStatus prints a status of the build, exiting when it failed or was cancelled, and reports
+	// whether it completed
+	handleStatus := func(st *buildapitypes.BuildResponse) bool {
+		if !userFollowRequested {
+			if st.Phase != lastPhase || st.Message != lastMessage {
+				fmt.Printf("status: %s - %s\n", renderer.Phase(st.Phase), st.Message)
+				lastPhase = st.Phase
+				lastMessage = st.Message
 			}
-			if comp := strings.TrimSpace(resp.Header.Get("X-AIB-Compression")); comp != "" {
-				fmt.Printf("Compression: %s\n", comp)
+		}
+		if st.Phase == "Completed" {
+			fmt.Printf("Build %s %s in %s\n", name, renderer.Phase(st.Phase), render.Elapsed(st.StartTime, st.CompletionTime, time.Now()))
+			printStageTimings(st.StageTimings)
+			if st.FirstBootFileName != "" {
+				fmt.Printf("first-boot payload attached as %s\n", st.FirstBootFileName)
 			}
-			if root := strings.TrimSpace(resp.Header.Get("X-AIB-Archive-Root")); root != "" {
-				fmt.Printf("Archive root: %s\n", root)
+			if st.HardeningReportFileName != "" {
+				fmt.Printf("hardening compliance report attached as %s\n", st.HardeningReportFileName)
 			}
-			outPath := filepath.Join(outDir, filename)
-			tmp := outPath + ".partial"
-			f, err := os.Create(tmp)
-			if err != nil {
-				resp.Body.Close()
-				return err
+			if st.ComplianceResult != "" {
+				fmt.Printf("compliance scan: %s (see caib compliance %s)\n", st.ComplianceResult, name)
 			}
-			if cl := strings.TrimSpace(resp.Header.Get("Content-Length")); cl != "" {
-				// Known size: nice progress bar
-				// Convert to int64
-				var total int64
-				fmt.Sscan(cl, &total)
-				bar := progressbar.NewOptions64(
-					total,
-					progressbar.OptionSetDescription("Downloading"),
-					progressbar.OptionShowBytes(true),
-					progressbar.OptionSetWidth(15),
-					progressbar.OptionThrottle(65*time.Millisecond),
-					progressbar.OptionShowCount(),
-					progressbar.OptionClearOnFinish(),
-				)
-				reader := io.TeeReader(resp.Body, bar)
-				if _, copyErr := io.Copy(f, reader); copyErr != nil {
-					f.Close()
-					os.Remove(tmp)
-					return copyErr
+			printSizeBudget(st)
+			printBootTest(st)
+			printPublications(st)
+			if download {
+				if st.SizeReportFileName != "" {
+					if err := downloadBuildFile(ctx, api, name, st.SizeReportFileName, outputDir); err != nil {
+						fmt.Printf("Size report download failed: %v\n", err)
+					}
 				}
-				_ = bar.Finish()
-				fmt.Println()
-			} else {
-				bar := progressbar.NewOptions(
-					-1,
-					progressbar.OptionSetDescription("Downloading"),
-					progressbar.OptionSpinnerType(14),
-					progressbar.OptionClearOnFinish(),
-				)
-				reader := io.TeeReader(resp.Body, bar)
-				if _, copyErr := io.Copy(f, reader); copyErr != nil {
-					f.Close()
-					os.Remove(tmp)
-					return copyErr
+				if st.HardeningReportFileName != "" {
+					if err := downloadBuildFile(ctx, api, name, st.HardeningReportFileName, outputDir); err != nil {
+						fmt.Printf("Hardening report download failed: %v\n", err)
+					}
+				}
+				if err := downloadArtifactViaAPI(ctx, serverURL, name, outputDir, nil); err != nil {
+					fmt.Printf("Download via API failed: %v\n", err)
 				}
-				_ = bar.Finish()
-				fmt.Println()
-			}
-			resp.Body.Close()
-			f.Close()
-			if err := os.Rename(tmp, outPath); err != nil {
-				return err
 			}
-			fmt.Printf("Artifact downloaded to %s\n", outPath)
-
-			// If the artifact is a tar archive (directory export), optionally extract it
-			if strings.HasPrefix(contentType, "application/x-tar") || strings.HasPrefix(contentType, "application/gzip") || strings.HasSuffix(strings.ToLower(outPath), ".tar") || strings.HasSuffix(strings.ToLower(outPath), ".tar.gz") {
-				if !compressArtifacts {
-					destDir := strings.TrimSuffix(outPath, ".tar")
-					destDir = strings.TrimSuffix(destDir, ".gz")
-					if err := os.MkdirAll(destDir, 0o755); err != nil {
-						return fmt.Errorf("create extract dir: %w", err)
+			return true
+		}
+		if st.Phase == "Failed" {
+			printSizeBudget(st)
+			printBootTest(st)
+			printBuildEnvironment(st)
+			if download {
+				for _, f := range []string{st.SizeReportFileName, st.BootConsoleLogFileName} {
+					if f == "" {
+						continue
 					}
-					if err := extractTar(outPath, destDir); err != nil {
-						return fmt.Errorf("extract tar: %w", err)
+					if err := downloadBuildFile(ctx, api, name, f, outputDir); err != nil {
+						fmt.Printf("Report download failed: %v\n", err)
 					}
-					fmt.Printf("Extracted to %s\n", destDir)
 				}
 			}
-			return nil
+			handleError(fmt.Errorf("build failed: %s", st.Message))
 		}
-
-		body, _ := io.ReadAll(resp.Body)
-		msg := strings.ToLower(strings.TrimSpace(string(body)))
-		resp.Body.Close()
-		if resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusConflict || strings.Contains(msg, "not ready") {
-			if !warned {
-				fmt.Println("Artifact not ready yet. Waiting...")
-				warned = true
-			}
-			time.Sleep(3 * time.Second)
-			continue
+		if st.Phase == "Cancelled" {
+			handleError(fmt.Errorf("build cancelled: %s", st.Message))
 		}
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
-	}
-}
-
-func extractTar(tarPath, destDir string) error {
-	f, err := os.Open(tarPath)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	var r io.Reader = f
-	if strings.HasSuffix(strings.ToLower(tarPath), ".gz") {
-		gr, gzErr := gzip.NewReader(f)
-		if gzErr == nil {
-			defer gr.Close()
-			r = gr
+		if !userFollowRequested {
+			wait.Update(st.Phase)
 		}
+		return false
 	}
-	tr := tar.NewReader(r)
+
 	for {
-		hdr, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-		targetPath := filepath.Join(destDir, hdr.Name)
-		switch hdr.Typeflag {
-		case tar.TypeDir:
-			if err := os.MkdirAll(targetPath, 0o755); err != nil {
-				return err
-			}
-		case tar.TypeReg:
-			if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
-				return err
+		select {
+		case <-timeoutCtx.Done():
+			handleError(fmt.Errorf("timed out waiting for build"))
+		case <-ticker.C:
+			wait.Clear()
+			if followLogs && !legacyLogStream {
+				fmt.Println("Streaming logs...")
+				err := api.FollowLogs(timeoutCtx, name, printLogEvent)
+				switch {
+				case errors.Is(err, buildapiclient.ErrLogStreamUnsupported):
+					// Servers without /logs/stream only offer the plain ?follow=1 stream
+					legacyLogStream = true
+				case err != nil && timeoutCtx.Err() == nil:
+					fmt.Printf("log stream error: %v\n", err)
+					followLogs = false
+				default:
+					followLogs = false
+				}
 			}
-			out, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode))
-			if err != nil {
-				return err
+			if followLogs && legacyLogStream {
+				if !logFollowWarned {
+					fmt.Println("Streaming logs...")
+				}
+				err := api.StreamLogs(timeoutCtx, name, os.Stdout)
+				switch {
+				case errors.Is(err, buildapiclient.ErrLogsNotReady):
+					if !logFollowWarned {
+						fmt.Println("log stream not ready. Retrying…")
+						logFollowWarned = true
+					}
+					// treat as transient; keep trying silently afterwards
+				case err != nil && timeoutCtx.Err() == nil:
+					fmt.Printf("log stream error: %v\n", err)
+					followLogs = false
+				default:
+					followLogs = false
+				}
 			}
-			if _, err := io.Copy(out, tr); err != nil {
-				out.Close()
-				return err
+			if !followLogs && !legacyWatch {
+				completed := false
+				// the status stream is quiet between changes, so the time waited is updated here
+				var mu sync.Mutex
+				stopProgress := make(chan struct{})
+				go func() {
+					t := time.NewTicker(5 * time.Second)
+					defer t.Stop()
+					for {
+						select {
+						case <-stopProgress:
+							return
+						case <-t.C:
+							mu.Lock()
+							if !userFollowRequested && !completed && lastPhase != "" {
+								wait.Update(lastPhase)
+							}
+							mu.Unlock()
+						}
+					}
+				}()
+				err := api.WatchBuild(timeoutCtx, name, func(ev buildapiclient.BuildStatusEvent) error {
+					mu.Lock()
+					defer mu.Unlock()
+					wait.Clear()
+					if ev.Type == "deleted" {
+						handleError(fmt.Errorf("build %s was deleted", name))
+					}
+					if handleStatus(&ev.Build) {
+						completed = true
+						return buildapiclient.ErrStop
+					}
+					return nil
+				})
+				close(stopProgress)
+				switch {
+				case completed:
+					return
+				case timeoutCtx.Err() != nil:
+					continue
+				case errors.Is(err, buildapiclient.ErrBuildWatchUnsupported):
+					// Servers without /watch are polled
+					legacyWatch = true
+				case err != nil:
+					fmt.Printf("status stream error: %v\n", err)
+					legacyWatch = true
+				}
 			}
-			out.Close()
-		case tar.TypeSymlink:
-			if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
-				return err
+			reqCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+			st, err := api.GetBuild(reqCtx, name)
+			cancel()
+			if err != nil {
+				fmt.Printf("status check failed: %v\n", err)
+				continue
 			}
-			if err := os.Symlink(hdr.Linkname, targetPath); err != nil && !os.IsExist(err) {
-				return err
+			if handleStatus(st) {
+				return
 			}
-		default:
-			// ignore other types
 		}
 	}
+}
+
+// printLogEvent prints an event of the build log stream in the format of the plain log stream
+func printLogEvent(ev buildapiclient.LogEvent) error {
+	switch ev.Type {
+	case "step":
+		fmt.Printf("\n%s\n\n", ev.Text)
+	case "log", "system":
+		fmt.Println(ev.Text)
+	case "error":
+		fmt.Printf("[log stream interrupted: %s; resuming]\n", ev.Text)
+	}
 	return nil
 }
 
-func runDownload(cmd *cobra.Command, args []string) {
-	ctx := context.Background()
+// webhookRequests builds the webhooks of a build from --webhook, all signed with --webhook-secret
+func webhookRequests() []buildapitypes.Webhook {
+	hooks := make([]buildapitypes.Webhook, 0, len(webhookURLs))
+	for _, u := range webhookURLs {
+		hooks = append(hooks, buildapitypes.Webhook{URL: strings.TrimSpace(u), Secret: webhookSecret})
+	}
+	return hooks
+}
 
-	if strings.TrimSpace(serverURL) == "" {
-		fmt.Println("Error: --server is required (or set CAIB_SERVER)")
-		os.Exit(1)
+// commitStatusRequest builds the CI metadata of a build from the environment of the CI job
+func commitStatusRequest() (*buildapitypes.CommitStatus, error) {
+	ci := commitstatus.FromCI(os.Getenv)
+	if ci == nil {
+		return nil, fmt.Errorf("--commit-status needs a GitHub Actions or GitLab CI job")
 	}
+	return &buildapitypes.CommitStatus{
+		Provider:     ci.Provider,
+		Repository:   ci.Repository,
+		SHA:          ci.SHA,
+		Branch:       ci.Branch,
+		TargetBranch: ci.TargetBranch,
+		Context:      strings.TrimSpace(commitStatusContext),
+	}, nil
+}
 
-	if strings.TrimSpace(authToken) == "" {
-		if tok, err := loadTokenFromKubeconfig(); err == nil && strings.TrimSpace(tok) != "" {
-			authToken = tok
-		}
+// bootTestRequest builds the boot test settings from the --boot-* flags
+func bootTestRequest() *buildapitypes.BootTest {
+	return &buildapitypes.BootTest{
+		TimeoutSeconds:   bootTimeout,
+		ReadyMarker:      strings.TrimSpace(bootReadyMarker),
+		MaxKernelToReady: strings.TrimSpace(bootMaxKernelToReady),
+		Enforce:          bootEnforce,
 	}
-	var opts []buildapiclient.Option
-	if strings.TrimSpace(authToken) != "" {
-		opts = append(opts, buildapiclient.WithAuthToken(strings.TrimSpace(authToken)))
+}
+
+// printBootTest prints the boot test outcome of builds run with --boot-test
+func printBootTest(st *buildapitypes.BuildResponse) {
+	if st.BootResult == "" || st.BootResult == "pending" {
+		return
 	}
-	api, err := buildapiclient.New(serverURL, opts...)
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+	fmt.Printf("boot test: %s", st.BootResult)
+	if st.BootAccelerator != "" {
+		fmt.Printf(" (%s)", st.BootAccelerator)
 	}
-
-	st, err := api.GetBuild(ctx, buildName)
-	if err != nil {
-		fmt.Printf("Error getting build %s: %v\n", buildName, err)
-		os.Exit(1)
+	fmt.Println()
+	for _, k := range []string{"firmware", "kernelToReady", "total"} {
+		if v, ok := st.BootTimings[k]; ok {
+			fmt.Printf("  %-14s %s\n", k, v)
+		}
 	}
-	if st.Phase != "Completed" {
-		fmt.Printf("Build %s is not completed (status: %s). Cannot download artifacts.\n", buildName, st.Phase)
-		os.Exit(1)
+	if st.BootResult != "booted" && st.BootConsoleLogFileName != "" {
+		fmt.Printf("  serial console log: %s\n", st.BootConsoleLogFileName)
 	}
+}
 
-	if err := downloadArtifactViaAPI(ctx, serverURL, buildName, outputDir); err != nil {
-		fmt.Printf("Download failed: %v\n", err)
-		os.Exit(1)
+// printPublications prints the publish targets of a completed build; the controller starts publishing
+// once the build completed, so they are usually still in progress
+func printPublications(st *buildapitypes.BuildResponse) {
+	for _, p := range st.Publications {
+		switch p.Phase {
+		case "Succeeded":
+			fmt.Printf("published to %s (%s): %s\n", p.Name, p.Type, p.Location)
+		case "Failed":
+			if p.Reason == "ImmutableTag" {
+				fmt.Printf("publishing to %s (%s) refused: %s\n", p.Name, p.Type, p.Message)
+				continue
+			}
+			fmt.Printf("publishing to %s (%s) failed after %d attempts: %s\n", p.Name, p.Type, p.Attempts, p.Message)
+		default:
+			fmt.Printf("publishing to %s (%s), attempt %d\n", p.Name, p.Type, p.Attempts)
+		}
 	}
 }
 
-func runList(cmd *cobra.Command, args []string) {
-	ctx := context.Background()
-	if strings.TrimSpace(serverURL) == "" {
-		fmt.Println("Error: --server is required (or set CAIB_SERVER)")
-		os.Exit(1)
+// printBuildEnvironment prints where and with what a build ran, for support requests about builds
+// that fail on one cluster but not on another
+func printBuildEnvironment(st *buildapitypes.BuildResponse) {
+	env := st.Environment
+	if env == nil {
+		return
 	}
-	if strings.TrimSpace(authToken) == "" {
-		if tok, err := loadTokenFromKubeconfig(); err == nil && strings.TrimSpace(tok) != "" {
-			authToken = tok
-		}
+	fmt.Printf("build environment: node %s, kernel %s, %s\n", valueOr(env.NodeName, "unknown"),
+		valueOr(env.KernelVersion, "unknown"), valueOr(env.ContainerRuntime, "unknown runtime"))
+	fmt.Printf("  builder image %s (osbuild %s, aib %s), SELinux %s\n", valueOr(env.BuilderImageDigest, env.BuilderImage),
+		valueOr(env.OSBuildVersion, "unknown"), valueOr(env.AIBVersion, "unknown"), valueOr(env.SELinux, "unknown"))
+}
+
+// printSizeBudget prints the measured root filesystem size of builds with a size budget
+func printSizeBudget(st *buildapitypes.BuildResponse) {
+	if st.SizeBudgetBytes == 0 || st.RootFSBytes == 0 {
+		return
 	}
-	var opts []buildapiclient.Option
-	if strings.TrimSpace(authToken) != "" {
-		opts = append(opts, buildapiclient.WithAuthToken(strings.TrimSpace(authToken)))
+	verdict := "within budget"
+	if st.RootFSBytes > st.SizeBudgetBytes {
+		verdict = "OVER BUDGET"
 	}
-	api, err := buildapiclient.New(serverURL, opts...)
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+	fmt.Printf("root filesystem: %s of %s budget (%s), breakdown in %s\n",
+		byteSize(st.RootFSBytes), byteSize(st.SizeBudgetBytes), verdict, st.SizeReportFileName)
+}
+
+// printStageTimings prints the build pod stage durations in a stable order
+// downloadBuildFile saves a secondary artifact of a build (e.g. a report) to dir as <name>-<file>
+func downloadBuildFile(ctx context.Context, api *buildapiclient.Client, name, file, dir string) error {
+	if strings.TrimSpace(dir) == "" {
+		dir = "./output"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create output dir: %w", err)
 	}
-	items, err := api.ListBuilds(ctx)
+	outPath := filepath.Join(dir, name+"-"+filepath.Base(file))
+	f, err := os.Create(outPath)
 	if err != nil {
-		fmt.Printf("Error listing ImageBuilds: %v\n", err)
-		os.Exit(1)
+		return err
 	}
-	if len(items) == 0 {
-		fmt.Println("No ImageBuilds found")
+	defer f.Close()
+	if err := api.DownloadArtifactFile(ctx, name, file, f); err != nil {
+		os.Remove(outPath)
+		return err
+	}
+	fmt.Printf("%s saved to %s\n", file, outPath)
+	return nil
+}
+
+func printStageTimings(timings map[string]string) {
+	if len(timings) == 0 {
 		return
 	}
-	fmt.Printf("%-20s %-12s %-20s %-20s %-20s\n", "NAME", "STATUS", "MESSAGE", "CREATED", "ARTIFACT")
-	for _, it := range items {
-		fmt.Printf("%-20s %-12s %-20s %-20s %-20s\n", it.Name, it.Phase, it.Message, it.CreatedAt, "")
+	stages := make([]string, 0, len(timings))
+	for stage := range timings {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+	parts := make([]string, 0, len(stages))
+	for _, stage := range stages {
+		parts = append(parts, fmt.Sprintf("%s=%s", stage, timings[stage]))
+	}
+	fmt.Printf("stage timings: %s\n", strings.Join(parts, " "))
+}
+
+func validateBuildRequirements() error {
+	if strings.TrimSpace(fromImageBuild) != "" {
+		if manifest != "" {
+			return fmt.Errorf("--manifest and --from-imagebuild are mutually exclusive")
+		}
+		if firstBootFile != "" {
+			return fmt.Errorf("--firstboot cannot be combined with --from-imagebuild; set firstBoot in the --patch file instead")
+		}
+		if sshKeyFile != "" || testUser != "" {
+			return fmt.Errorf("--ssh-key/--test-user cannot be combined with --from-imagebuild")
+		}
+		if deduplicate {
+			return fmt.Errorf("--deduplicate cannot be combined with --from-imagebuild")
+		}
+	} else {
+		switch {
+		case manifest != "" && gitURL != "":
+			return fmt.Errorf("--manifest and --git-url are mutually exclusive")
+		case manifest == "" && gitURL == "":
+			return fmt.Errorf("--manifest or --git-url is required")
+		case gitURL != "" && gitPath == "":
+			return fmt.Errorf("--git-path is required with --git-url")
+		}
+		if patchFile != "" {
+			return fmt.Errorf("--patch requires --from-imagebuild")
+		}
+	}
+
+	if buildName == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	if strings.TrimSpace(architecture) == "" {
+		return fmt.Errorf("--arch is required")
+	}
+
+	return nil
+}
+
+func handleError(err error) {
+	fmt.Printf("Error: %v\n", err)
+	if reqID := buildapiclient.RequestID(err); reqID != "" {
+		fmt.Printf("If the problem persists, contact your administrator with request ID %s\n", reqID)
+	}
+	os.Exit(1)
+}
+
+func findLocalFileReferences(manifestContent string) ([]map[string]string, error) {
+	var manifestData map[string]any
+	var localFiles []map[string]string
+
+	if err := yaml.Unmarshal([]byte(manifestContent), &manifestData); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest YAML: %w", err)
+	}
+
+	isPathSafe := func(path string) error {
+		if path == "" || path == "/" {
+			return fmt.Errorf("empty or root path is not allowed")
+		}
+
+		if strings.Contains(path, "..") {
+			return fmt.Errorf("directory traversal detected in path: %s", path)
+		}
+
+		if filepath.IsAbs(path) {
+			// TODO add safe dirs flag
+			safeDirectories := []string{}
+			isInSafeDir := false
+			for _, dir := range safeDirectories {
+				if strings.HasPrefix(path, dir+"/") {
+					isInSafeDir = true
+					break
+				}
+			}
+			if !isInSafeDir {
+				return fmt.Errorf("absolute path outside safe directories: %s", path)
+			}
+		}
+
+		return nil
+	}
+
+	processAddFiles := func(addFiles []any) error {
+		for _, file := range addFiles {
+			if fileMap, ok := file.(map[string]any); ok {
+				path, hasPath := fileMap["path"].(string)
+				sourcePath, hasSourcePath := fileMap["source_path"].(string)
+				if hasPath && hasSourcePath {
+					// URLs are downloaded by the cluster
+					if isRemoteSourcePath(sourcePath) {
+						continue
+					}
+					if err := isPathSafe(sourcePath); err != nil {
+						return err
+					}
+					localFiles = append(localFiles, map[string]string{
+						"path":        path,
+						"source_path": sourcePath,
+					})
+				}
+			}
+		}
+		return nil
+	}
+
+	if content, ok := manifestData["content"].(map[string]any); ok {
+		if addFiles, ok := content["add_files"].([]any); ok {
+			if err := processAddFiles(addFiles); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if qm, ok := manifestData["qm"].(map[string]any); ok {
+		if qmContent, ok := qm["content"].(map[string]any); ok {
+			if addFiles, ok := qmContent["add_files"].([]any); ok {
+				if err := processAddFiles(addFiles); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return localFiles, nil
+}
+
+// isRemoteSourcePath reports whether an add_files source_path is a URL the build's upload pod
+// downloads rather than a local file
+func isRemoteSourcePath(sourcePath string) bool {
+	scheme, _, ok := strings.Cut(sourcePath, "://")
+	switch strings.ToLower(scheme) {
+	case "http", "https", "s3":
+		return ok
+	}
+	return false
+}
+
+// downloadArtifactViaAPI saves the artifact of a build in outDir. With a verifier, the artifact is
+// only saved under its name once it passed verification.
+func downloadArtifactViaAPI(ctx context.Context, baseURL, name, outDir string, verifier *artifactVerifier) error {
+	_, err := saveArtifact(ctx, baseURL, name, outDir, verifier, os.Stdout, nil)
+	return err
+}
+
+// saveArtifact downloads the artifact of a build to outDir, extracting directory exports unless
+// --compress is set, and returns the path it was saved to. Messages and progress go to msgOut;
+// bytes received are also counted by combined when it is set.
+func saveArtifact(ctx context.Context, baseURL, name, outDir string, verifier *artifactVerifier, msgOut io.Writer, combined *combinedProgress) (string, error) {
+	if strings.TrimSpace(outDir) == "" {
+		outDir = "./output"
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return "", fmt.Errorf("create output dir: %w", err)
+	}
+
+	resp, err := openArtifact(ctx, baseURL, name, msgOut)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if combined != nil {
+		combined.track(resp)
+	}
+
+	filename := name + ".artifact"
+	contentType := resp.Header.Get("Content-Type")
+	if cd := resp.Header.Get("Content-Disposition"); cd != "" {
+		if i := strings.Index(cd, "filename="); i >= 0 {
+			f := strings.Trim(cd[i+9:], "\" ")
+			if f != "" {
+				filename = f
+			}
+		}
+	}
+	printArtifactHeaders(resp, msgOut)
+	compression := strings.TrimSpace(resp.Header.Get("X-AIB-Compression"))
+	decompress := downloadDecompress && compression != "" && compression != "none"
+	if decompress {
+		ext, ok := artifactExts[compression]
+		if !ok {
+			return "", fmt.Errorf("%s artifacts cannot be decompressed by caib", compression)
+		}
+		filename = strings.TrimSuffix(filename, ext)
+	}
+	outPath := filepath.Join(outDir, filename)
+	tmp := outPath + ".partial"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return "", err
+	}
+	var dst io.WriteCloser = f
+	if decompress {
+		dst = newDecompressingWriter(f, compression)
+	}
+	copyErr := copyArtifact(dst, resp, msgOut)
+	if decompress {
+		if err := dst.Close(); copyErr == nil && err != nil {
+			copyErr = fmt.Errorf("decompress %s: %w", compression, err)
+		}
+	}
+	if copyErr != nil {
+		f.Close()
+		os.Remove(tmp)
+		return "", copyErr
+	}
+	f.Close()
+	if verifier != nil {
+		if err := verifier.Verify(ctx, tmp); err != nil {
+			os.Remove(tmp)
+			return "", fmt.Errorf("artifact not saved, verification failed: %w", err)
+		}
+	}
+	if err := os.Rename(tmp, outPath); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(msgOut, "Artifact downloaded to %s\n", outPath)
+
+	// If the artifact is a tar archive (directory export), optionally extract it
+	lowerPath := strings.ToLower(outPath)
+	if strings.HasPrefix(contentType, "application/x-tar") || strings.HasPrefix(contentType, "application/gzip") || strings.HasSuffix(lowerPath, ".tar") || strings.HasSuffix(lowerPath, ".tar.gz") || strings.HasSuffix(lowerPath, ".tar.zst") {
+		if !compressArtifacts {
+			destDir := strings.TrimSuffix(strings.TrimSuffix(outPath, ".gz"), ".zst")
+			destDir = strings.TrimSuffix(destDir, ".tar")
+			if err := os.MkdirAll(destDir, 0o755); err != nil {
+				return "", fmt.Errorf("create extract dir: %w", err)
+			}
+			if err := extractTar(outPath, destDir); err != nil {
+				return "", fmt.Errorf("extract tar: %w", err)
+			}
+			fmt.Fprintf(msgOut, "Extracted to %s\n", destDir)
+			return destDir, nil
+		}
+	}
+	return outPath, nil
+}
+
+// streamArtifactToStdout writes the artifact of a build to stdout, e.g. to pipe it into
+// qemu-img or dd. Progress and messages go to stderr.
+func streamArtifactToStdout(ctx context.Context, baseURL, name string) error {
+	resp, err := openArtifact(ctx, baseURL, name, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	printArtifactHeaders(resp, os.Stderr)
+	compression := strings.TrimSpace(resp.Header.Get("X-AIB-Compression"))
+	if !downloadDecompress || compression == "" || compression == "none" {
+		return copyArtifact(os.Stdout, resp, os.Stderr)
+	}
+	if _, ok := artifactExts[compression]; !ok {
+		return fmt.Errorf("%s artifacts cannot be decompressed by caib", compression)
+	}
+	dst := newDecompressingWriter(os.Stdout, compression)
+	copyErr := copyArtifact(dst, resp, os.Stderr)
+	if err := dst.Close(); copyErr == nil && err != nil {
+		copyErr = fmt.Errorf("decompress %s: %w", compression, err)
+	}
+	return copyErr
+}
+
+// openArtifact requests the artifact of a build, waiting while the server reports it is not ready yet
+func openArtifact(ctx context.Context, baseURL, name string, msgOut io.Writer) (*http.Response, error) {
+	base := strings.TrimRight(baseURL, "/")
+	urlStr := base + "/v1/builds/" + url.PathEscape(name) + "/artifact"
+	if tmpl := strings.TrimSpace(outputName); tmpl != "" {
+		urlStr += "?outputName=" + url.QueryEscape(tmpl)
+	}
+
+	deadline := time.Now().Add(30 * time.Minute)
+
+	httpClient := &http.Client{
+		Timeout: 30 * time.Minute,
+		Transport: &http.Transport{
+			ResponseHeaderTimeout: 2 * time.Minute,
+			IdleConnTimeout:       5 * time.Minute,
+		},
+	}
+
+	warned := false
+	for {
+		if ctx.Err() != nil || time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for artifact to become ready")
+		}
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+		setAuthHeaders(req)
+		accept := acceptCompression
+		if len(accept) == 0 {
+			accept = defaultAcceptCompression
+		}
+		req.Header.Set("X-AIB-Accept-Compression", strings.Join(accept, ", "))
+		// uncompressed artifacts are sent with zstd rather than the transport's default gzip
+		req.Header.Set("Accept-Encoding", "zstd")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			time.Sleep(3 * time.Second)
+			continue
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			if err := decodeContentEncoding(resp); err != nil {
+				resp.Body.Close()
+				return nil, err
+			}
+			return resp, nil
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		msg := strings.ToLower(strings.TrimSpace(string(body)))
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusTooManyRequests {
+			wait := buildapiclient.RetryAfter(resp)
+			fmt.Fprintf(msgOut, "Rate limited by the build API, retrying in %s\n", wait)
+			time.Sleep(wait)
+			continue
+		}
+		if resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusConflict || strings.Contains(msg, "not ready") {
+			if !warned {
+				fmt.Fprintln(msgOut, "Artifact not ready yet. Waiting...")
+				warned = true
+			}
+			time.Sleep(3 * time.Second)
+			continue
+		}
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+}
+
+func printArtifactHeaders(resp *http.Response, msgOut io.Writer) {
+	if at := strings.TrimSpace(resp.Header.Get("X-AIB-Artifact-Type")); at != "" {
+		fmt.Fprintf(msgOut, "Artifact type: %s\n", at)
+	}
+	if comp := strings.TrimSpace(resp.Header.Get("X-AIB-Compression")); comp != "" {
+		fmt.Fprintf(msgOut, "Compression: %s\n", comp)
+	}
+	if root := strings.TrimSpace(resp.Header.Get("X-AIB-Archive-Root")); root != "" {
+		fmt.Fprintf(msgOut, "Archive root: %s\n", root)
+	}
+}
+
+// copyArtifact copies the artifact in resp to dst with progress on msgOut. When the server
+// sends the X-AIB-Checksum of the artifact, the copy is verified against it as it streams.
+func copyArtifact(dst io.Writer, resp *http.Response, msgOut io.Writer) error {
+	var total int64
+	if cl := strings.TrimSpace(resp.Header.Get("Content-Length")); cl != "" {
+		fmt.Sscan(cl, &total)
+	}
+	bar := newTransferProgress(msgOut, "Downloading", total)
+
+	algo, expected, _ := strings.Cut(strings.TrimSpace(resp.Header.Get("X-AIB-Checksum")), ":")
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(dst, h), io.TeeReader(resp.Body, bar)); err != nil {
+		return err
+	}
+	bar.Finish()
+
+	if algo != "sha256" || expected == "" {
+		return nil
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch: expected sha256 %s, got %s", expected, actual)
+	}
+	fmt.Fprintf(msgOut, "Checksum verified: sha256 %s\n", expected)
+	return nil
+}
+
+func extractTar(tarPath, destDir string) error {
+	f, err := os.Open(tarPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(strings.ToLower(tarPath), ".gz") {
+		gr, gzErr := gzip.NewReader(f)
+		if gzErr == nil {
+			defer gr.Close()
+			r = gr
+		}
+	} else if strings.HasSuffix(strings.ToLower(tarPath), ".zst") {
+		zr, err := newDecompressor(f, "zstd")
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		targetPath := filepath.Join(destDir, hdr.Name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(targetPath, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			out.Close()
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, targetPath); err != nil && !os.IsExist(err) {
+				return err
+			}
+		default:
+			// ignore other types
+		}
+	}
+	return nil
+}
+
+func runDownload(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+
+	api, err := newAPIClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(downloadNames) > 1 || downloadSelector != "" {
+		runMultiDownload(ctx, api)
+		return
+	}
+	buildName = downloadNames[0]
+
+	st, err := api.GetBuild(ctx, buildName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting build %s: %v\n", buildName, err)
+		os.Exit(1)
+	}
+	if downloadHistory {
+		printDownloadHistory(st.Downloads)
+		return
+	}
+	// Failed builds keep the reports of the checks that failed them
+	reportsOnly := downloadList || downloadFile != ""
+	if st.Phase != "Completed" && (st.Phase != "Failed" || !reportsOnly) {
+		fmt.Fprintf(os.Stderr, "Build %s is not completed (status: %s). Cannot download artifacts.\n", buildName, st.Phase)
+		os.Exit(1)
+	}
+
+	if downloadList {
+		manifest, err := api.GetArtifactManifest(ctx, buildName)
+		if err != nil {
+			handleError(err)
+		}
+		printArtifactManifest(manifest)
+		return
+	}
+	if downloadFile != "" {
+		if err := downloadVerifiedFile(ctx, api, buildName, downloadFile, outputDir); err != nil {
+			handleError(err)
+		}
+		return
+	}
+
+	var verifier *artifactVerifier
+	if verifyOpts.enabled {
+		if err := checkVerifyOptions(); err != nil {
+			handleError(err)
+		}
+		if verifier, err = newArtifactVerifier(ctx, api, buildName); err != nil {
+			handleError(err)
+		}
+		defer verifier.Close()
+	}
+
+	if downloadStdout {
+		if err := streamArtifactToStdout(ctx, serverURL, buildName); err != nil {
+			fmt.Fprintf(os.Stderr, "Download failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := downloadArtifactViaAPI(ctx, serverURL, buildName, outputDir, verifier); err != nil {
+		fmt.Printf("Download failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// printDownloadHistory prints the downloads of a build per user and the latest downloads
+func printDownloadHistory(d *buildapitypes.ArtifactDownloads) {
+	if d == nil || d.Count == 0 {
+		fmt.Println("Not downloaded yet")
+		return
+	}
+	now := time.Now()
+	fmt.Printf("Downloads: %d, last %s\n\n", d.Count, render.Timestamp(d.LastDownloadAt, now))
+	fmt.Printf("%-40s %8s  %s\n", "USER", "COUNT", "LAST DOWNLOAD")
+	for _, u := range d.Users {
+		fmt.Printf("%-40s %8d  %s\n", u.User, u.Count, render.Timestamp(u.LastDownloadAt, now))
+	}
+	fmt.Println("\nRecent downloads:")
+	fmt.Printf("%-20s %-40s %-10s %s\n", "TIME", "USER", "SIZE", "FILE")
+	for _, r := range d.Recent {
+		fmt.Printf("%-20s %-40s %-10s %s\n", r.Time, r.User, byteSize(r.Bytes), r.File)
+	}
+}
+
+func printArtifactManifest(manifest *buildapitypes.ArtifactManifestResponse) {
+	if len(manifest.Files) == 0 {
+		fmt.Println("No files found")
+		return
+	}
+	fmt.Printf("%-50s %-16s %-10s %-8s %s\n", "NAME", "KIND", "SIZE", "COMPR", "SHA256")
+	for _, f := range manifest.Files {
+		fmt.Printf("%-50s %-16s %-10s %-8s %s\n", f.Name, f.Kind, byteSize(f.SizeBytes), f.Compression, f.SHA256)
+	}
+}
+
+// downloadVerifiedFile downloads one file of the artifact manifest of a build to dir and checks
+// its size and SHA-256 digest; the file only appears under its name once it was verified
+func downloadVerifiedFile(ctx context.Context, api *buildapiclient.Client, name, file, dir string) error {
+	manifest, err := api.GetArtifactManifest(ctx, name)
+	if err != nil {
+		return err
+	}
+	var entry *buildapitypes.ArtifactFile
+	for i := range manifest.Files {
+		if manifest.Files[i].Name == file {
+			entry = &manifest.Files[i]
+		}
+	}
+	switch {
+	case entry == nil:
+		return fmt.Errorf("build %s has no file %s; see caib download --list", name, file)
+	case entry.Kind == "part":
+		return fmt.Errorf("%s is a part of the artifact; download the whole artifact instead", file)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+file+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	bar := newTransferProgress(os.Stderr, "Downloading", entry.SizeBytes)
+	if err := api.DownloadArtifactFile(ctx, name, file, io.MultiWriter(tmp, h, bar)); err != nil {
+		bar.Abort()
+		return err
+	}
+	bar.Finish()
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, entry.SHA256) {
+		return fmt.Errorf("checksum mismatch for %s: expected sha256 %s, got %s", file, entry.SHA256, actual)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	dest := filepath.Join(dir, file)
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return err
+	}
+	fmt.Printf("Downloaded %s (sha256 %s verified)\n", dest, entry.SHA256)
+	return nil
+}
+
+// newAPIClient builds a Build API client from --server/--token, falling back to the token
+// stored by `caib login` in the OS keyring and then to the kubeconfig token
+func newAPIClient() (*buildapiclient.Client, error) {
+	if strings.TrimSpace(serverURL) == "" {
+		return nil, fmt.Errorf("--server is required (or set CAIB_SERVER)")
+	}
+	if strings.TrimSpace(authToken) == "" {
+		tok, err := keyringGet(serverURL)
+		switch {
+		case err == nil:
+			authToken = tok
+		case !errors.Is(err, errKeyringUnsupported) && !errors.Is(err, exec.ErrNotFound):
+			// a keyring that is there but failed, e.g. locked, should not go unnoticed
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	if strings.TrimSpace(authToken) == "" {
+		if tok, err := loadTokenFromKubeconfig(); err == nil && strings.TrimSpace(tok) != "" {
+			authToken = tok
+		}
+	}
+	var opts []buildapiclient.Option
+	if strings.TrimSpace(authToken) != "" {
+		opts = append(opts, buildapiclient.WithAuthToken(strings.TrimSpace(authToken)))
+	}
+	if user := strings.TrimSpace(impersonateUser); user != "" {
+		opts = append(opts, buildapiclient.WithImpersonation(user, impersonateGroups))
+	}
+	if ns := strings.TrimSpace(buildNamespace); ns != "" {
+		opts = append(opts, buildapiclient.WithNamespace(ns))
+	}
+	api, err := buildapiclient.New(serverURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	printServerBanner(api)
+	return api, nil
+}
+
+// setAuthHeaders adds the token, the --as impersonation and the --namespace to requests made
+// without the API client
+func setAuthHeaders(req *http.Request) {
+	if ns := strings.TrimSpace(buildNamespace); ns != "" {
+		q := req.URL.Query()
+		q.Set("namespace", ns)
+		req.URL.RawQuery = q.Encode()
+	}
+	if strings.TrimSpace(authToken) != "" {
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(authToken))
+	}
+	if user := strings.TrimSpace(impersonateUser); user != "" {
+		req.Header.Set("Impersonate-User", user)
+		for _, g := range impersonateGroups {
+			req.Header.Add("Impersonate-Group", g)
+		}
+	}
+}
+
+// printServerBanner prints the maintenance banner of the server to stderr, so it does not mix with
+// command output. Servers without /v1/info and unreachable servers are ignored here.
+func printServerBanner(api *buildapiclient.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := api.GetInfo(ctx)
+	if err != nil {
+		return
+	}
+	if banner := strings.TrimSpace(info.Banner); banner != "" {
+		fmt.Fprintf(os.Stderr, "Notice from %s: %s\n", serverURL, banner)
+	}
+	if info.ReadOnly {
+		fmt.Fprintln(os.Stderr, "The build API is in read-only mode for maintenance; builds cannot be created or changed")
+	}
+}
+
+func runList(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+	api, err := newAPIClient()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(listNamespaces) > 0 && !listAllNamespaces {
+		handleError(fmt.Errorf("--namespaces requires --all-namespaces"))
+	}
+	wide := listOutput == "wide"
+	if listOutput != "" && !wide {
+		handleError(fmt.Errorf("unsupported output format %q, use wide", listOutput))
+	}
+	// ListBuildsV2 lists with the v2 API when the server serves it, which adds the artifact of
+	// every build, and with the v1 API otherwise
+	page, err := api.ListBuildsV2(ctx, buildapiclient.ListBuildsOptions{
+		LabelSelector: listSelector,
+		Phases:        listPhases,
+		Arch:          listArch,
+		CreatedAfter:  listCreatedAfter,
+		Limit:         listLimit,
+		Continue:      listContinue,
+		AllNamespaces: listAllNamespaces,
+		Namespaces:    listNamespaces,
+	})
+	if err != nil {
+		fmt.Printf("Error listing ImageBuilds: %v\n", err)
+		os.Exit(1)
+	}
+	if len(page.Items) == 0 {
+		fmt.Println("No ImageBuilds found")
+		return
+	}
+	writeBuildList(os.Stdout, page.Items, listAllNamespaces, wide, time.Now())
+	if page.Continue != "" {
+		next := fmt.Sprintf("--limit %d --continue %s", listLimit, page.Continue)
+		if listAllNamespaces {
+			next = "--all-namespaces " + next
+		}
+		fmt.Printf("\nShowing %d of %d builds. Next page: %s\n", len(page.Items), page.TotalCount, next)
+	}
+}
+
+// writeBuildList writes the table of caib list
+func writeBuildList(w io.Writer, builds []buildapitypes.BuildV2, allNamespaces, wide bool, now time.Time) {
+	if allNamespaces {
+		fmt.Fprintf(w, "%-20s ", "NAMESPACE")
+	}
+	fmt.Fprintf(w, "%-20s %-12s %-10s %-20s %-12s %-20s %-12s ", "NAME", "STATUS", "DURATION", "MESSAGE", "CREATED", "ARTIFACT", "EXPIRES")
+	if wide {
+		fmt.Fprintf(w, "%-10s %-14s ", "DOWNLOADS", "LAST DOWNLOAD")
+	}
+	fmt.Fprintln(w, "LABELS")
+	for _, it := range builds {
+		if allNamespaces {
+			fmt.Fprintf(w, "%-20s ", it.Namespace)
+		}
+		expires := render.Timestamp(rfc3339(it.ArtifactsExpireAt), now)
+		if it.ArtifactsDeletedAt != nil {
+			expires = "deleted"
+		}
+		artifact := ""
+		for _, a := range it.Artifacts {
+			if a.Kind == "artifact" {
+				artifact = a.Name
+				break
+			}
+		}
+		fmt.Fprintf(w, "%-20s %s %-10s %-20s %-12s %-20s %-12s ", it.Name, renderer.PaddedPhase(string(it.Phase), 12),
+			render.Elapsed(rfc3339(it.StartedAt), rfc3339(it.CompletedAt), now), it.Message, render.Timestamp(rfc3339(&it.CreatedAt), now),
+			artifact, expires)
+		if wide {
+			fmt.Fprintf(w, "%-10d %-14s ", it.Downloads, render.Timestamp(rfc3339(it.LastDownloadAt), now))
+		}
+		fmt.Fprintln(w, formatLabels(it.Labels))
+	}
+}
+
+// rfc3339 formats t for the render helpers, empty when t is not set
+func rfc3339(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// formatLabels renders labels as sorted key=value pairs
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// formatCounts renders counts as "name count" pairs, the largest first
+func formatCounts(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s %d", name, counts[name]))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func runGetManifest(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	content, fileName, err := api.GetBuildManifest(ctx, args[0])
+	if err != nil {
+		handleError(err)
+	}
+	if manifestOutput == "" {
+		fmt.Print(content)
+		return
+	}
+	if err := os.WriteFile(manifestOutput, []byte(content), 0644); err != nil {
+		handleError(fmt.Errorf("error writing manifest: %w", err))
+	}
+	fmt.Fprintf(os.Stderr, "Manifest %s of build %s written to %s\n", fileName, args[0], manifestOutput)
+}
+
+func runCompliance(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	name := args[0]
+	res, err := api.GetBuildCompliance(ctx, name)
+	if err != nil {
+		handleError(err)
+	}
+	fmt.Printf("Build:   %s\n", res.Name)
+	fmt.Printf("Profile: %s\n", res.Profile)
+	fmt.Printf("Result:  %s\n", res.Result)
+	if res.Message != "" {
+		fmt.Printf("Message: %s\n", res.Message)
+	}
+	if complianceOutputDir != "" {
+		for _, f := range []string{res.ARFFileName, res.ReportFileName} {
+			if f == "" {
+				continue
+			}
+			if err := downloadBuildFile(ctx, api, name, f, complianceOutputDir); err != nil {
+				handleError(err)
+			}
+		}
+	}
+	if res.Result != "pass" {
+		os.Exit(1)
+	}
+}
+
+func runStats(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	if statsFailures {
+		fa, err := api.GetFailureAnalytics(ctx, statsSince, statsBucket)
+		if err != nil {
+			handleError(err)
+		}
+		printFailureAnalytics(fa)
+		return
+	}
+	st, err := api.GetStats(ctx, statsSince)
+	if err != nil {
+		handleError(err)
+	}
+
+	fmt.Printf("Builds since %s: %d\n", st.Since, st.Total)
+	if st.Total == 0 {
+		return
+	}
+	phases := make([]string, 0, len(st.Phases))
+	for p := range st.Phases {
+		phases = append(phases, p)
+	}
+	sort.Strings(phases)
+	for _, p := range phases {
+		fmt.Printf("  %s %d\n", renderer.PaddedPhase(p, 12), st.Phases[p])
+	}
+	fmt.Printf("Success rate: %.1f%%\n", st.SuccessRate*100)
+	if len(st.Targets) > 0 {
+		fmt.Printf("By target: %s\n", formatCounts(st.Targets))
+		fmt.Printf("By architecture: %s\n", formatCounts(st.Architectures))
+	}
+
+	if len(st.Durations) > 0 {
+		fmt.Println("\nDurations of completed builds:")
+		fmt.Printf("%-16s %-8s %6s %10s %10s %10s %10s\n", "TARGET", "ARCH", "COUNT", "AVG", "P50", "P90", "P99")
+		for _, d := range st.Durations {
+			fmt.Printf("%-16s %-8s %6d %10s %10s %10s %10s\n", d.Target, d.Architecture, d.Count,
+				secondsString(d.AverageSeconds), secondsString(d.P50Seconds), secondsString(d.P90Seconds), secondsString(d.P99Seconds))
+		}
+	}
+
+	if st.ArtifactSize.Count > 0 {
+		fmt.Printf("\nArtifacts: %d, total %s, average %s, largest %s\n", st.ArtifactSize.Count,
+			byteSize(st.ArtifactSize.TotalBytes), byteSize(st.ArtifactSize.AverageBytes), byteSize(st.ArtifactSize.MaxBytes))
+	}
+
+	if dl := st.Downloads; dl.Count > 0 {
+		fmt.Printf("\nArtifact downloads: %d, of %d builds\n", dl.Count, dl.Builds)
+		fmt.Println("Most downloaded builds:")
+		for _, b := range dl.TopBuilds {
+			fmt.Printf("%6d  %s\n", b.Count, b.Name)
+		}
+		fmt.Println("Most active downloaders:")
+		for _, u := range dl.TopUsers {
+			fmt.Printf("%6d  %s\n", u.Count, u.Name)
+		}
+	}
+
+	if len(st.TopFailures) > 0 {
+		fmt.Println("\nMost frequent failures:")
+		for _, f := range st.TopFailures {
+			msg := f.Message
+			if msg == "" {
+				msg = "(no message)"
+			}
+			fmt.Printf("%6d  %s\n", f.Count, msg)
+		}
+	}
+}
+
+// printFailureAnalytics prints failures by reason, with a sparkline of failures per bucket and
+// the most frequent error signatures of each reason
+func printFailureAnalytics(fa *buildapitypes.FailureAnalyticsResponse) {
+	fmt.Printf("Builds since %s: %d, failed: %d (%.1f%%)\n", fa.Since, fa.Builds, fa.Failures, fa.FailureRate*100)
+	if fa.Failures == 0 {
+		return
+	}
+	perBucket := make([]int, len(fa.Buckets))
+	for i, b := range fa.Buckets {
+		perBucket[i] = b.Failures
+	}
+	fmt.Printf("Failures per %s: %s\n", fa.Bucket, sparkline(perBucket))
+	for _, r := range fa.Reasons {
+		fmt.Printf("\n%-12s %5d  %5.1f%%  %s\n", r.Reason, r.Count, r.Share*100, sparkline(r.Trend))
+		for _, sig := range r.Signatures {
+			fmt.Printf("  %5d  %s\n", sig.Count, sig.Signature)
+			fmt.Printf("         last seen %s in %s\n", sig.LastSeen, strings.Join(sig.Builds, ", "))
+		}
+	}
+}
+
+// sparkline draws counts as a line of block characters scaled to the largest count
+func sparkline(counts []int) string {
+	const ticks = "▁▂▃▄▅▆▇█"
+	levels := []rune(ticks)
+	top := slices.Max(append([]int{1}, counts...))
+	var b strings.Builder
+	for _, n := range counts {
+		if n == 0 {
+			b.WriteRune(' ')
+			continue
+		}
+		b.WriteRune(levels[n*(len(levels)-1)/top])
+	}
+	return b.String()
+}
+
+func secondsString(s float64) string {
+	return render.Duration(time.Duration(s * float64(time.Second)))
+}
+
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func runLogin(cmd *cobra.Command, args []string) {
+	if strings.TrimSpace(serverURL) == "" {
+		handleError(fmt.Errorf("--server is required (or set CAIB_SERVER)"))
+	}
+
+	var token string
+	switch {
+	case loginTokenStdin:
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			handleError(fmt.Errorf("reading token from stdin: %w", err))
+		}
+		token = strings.TrimSpace(string(b))
+	case term.IsTerminal(int(os.Stdin.Fd())) && !loginFromKubeconfig:
+		fmt.Print("Token: ")
+		b, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			handleError(fmt.Errorf("reading token: %w", err))
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	if token == "" {
+		tok, err := loadTokenFromKubeconfig()
+		if err != nil {
+			handleError(fmt.Errorf("no token given and none found in kubeconfig: %w", err))
+		}
+		token = strings.TrimSpace(tok)
+		fmt.Println("Using token from current kubeconfig context")
+	}
+	if token == "" {
+		handleError(fmt.Errorf("empty token"))
+	}
+
+	api, err := buildapiclient.New(serverURL, buildapiclient.WithAuthToken(token))
+	if err != nil {
+		handleError(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := api.ListBuilds(ctx, ""); err != nil {
+		handleError(fmt.Errorf("token rejected by %s: %w", serverURL, err))
+	}
+
+	if err := keyringSet(serverURL, token); err != nil {
+		handleError(err)
+	}
+	fmt.Printf("Login succeeded; token stored in OS keyring for %s\n", keyringAccount(serverURL))
+}
+
+func runLogout(cmd *cobra.Command, args []string) {
+	if strings.TrimSpace(serverURL) == "" {
+		handleError(fmt.Errorf("--server is required (or set CAIB_SERVER)"))
+	}
+	if err := keyringDelete(serverURL); err != nil {
+		handleError(err)
+	}
+	fmt.Printf("Removed stored token for %s\n", keyringAccount(serverURL))
+}
+
+func runCatalogDefines(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+	api, err := newAPIClient()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	catalog, err := api.GetDefinesCatalog(ctx, strings.TrimSpace(catalogTarget))
+	if err != nil {
+		fmt.Printf("Error reading defines catalog: %v\n", err)
+		os.Exit(1)
+	}
+	targets := make([]string, 0, len(catalog.Targets))
+	for t := range catalog.Targets {
+		targets = append(targets, t)
+	}
+	sort.Strings(targets)
+	if len(targets) == 0 {
+		fmt.Println("No default defines configured")
+		return
+	}
+	for _, t := range targets {
+		defs := catalog.Targets[t]
+		fmt.Printf("%s:\n", t)
+		if len(defs) == 0 {
+			fmt.Println("  (none)")
+			continue
+		}
+		for _, d := range defs {
+			fmt.Printf("  %s\n", d)
+		}
+	}
+}
+
+func runCatalogHardening(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+	api, err := newAPIClient()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	catalog, err := api.GetHardeningCatalog(ctx)
+	if err != nil {
+		fmt.Printf("Error reading hardening catalog: %v\n", err)
+		os.Exit(1)
+	}
+	for _, p := range catalog.Profiles {
+		fmt.Printf("%s: %s\n", p.Name, p.Description)
+		for _, ctl := range p.Controls {
+			fmt.Printf("  %s  %s\n", ctl.ID, ctl.Description)
+		}
+	}
+}
+
+// runCapabilities prints one value per line of the capability list selected by pick.
+// The first query of an image can take a minute while the server probes it.
+func runCapabilities(pick func(*buildapitypes.CapabilitiesResponse) []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	caps, err := api.GetCapabilities(ctx, strings.TrimSpace(capabilitiesAIBImage))
+	if err != nil {
+		handleError(fmt.Errorf("error reading capabilities: %w", err))
+	}
+	for _, v := range pick(caps) {
+		fmt.Println(v)
 	}
 }
 
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	buildapitypes "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+	buildapiclient "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi/client"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/render"
+)
+
+// combinedProgressInterval is how often the combined progress of several downloads is redrawn on
+// terminals
+const combinedProgressInterval = 250 * time.Millisecond
+
+// downloadResult is the outcome of downloading the artifact of one build
+type downloadResult struct {
+	name    string
+	path    string
+	bytes   int64
+	elapsed time.Duration
+	err     error
+}
+
+// runMultiDownload downloads the artifacts of the builds named with --name or matching --selector,
+// --concurrency at a time, each to its own directory under --output-dir. A combined progress line
+// replaces the progress of each download, and a table of results follows. Exits with status 1
+// when any download failed.
+func runMultiDownload(ctx context.Context, api *buildapiclient.Client) {
+	switch {
+	case downloadStdout || downloadList || downloadFile != "" || downloadHistory:
+		handleError(fmt.Errorf("--stdout, --list, --file and --history take a single --name"))
+	case verifyOpts.bundle != "":
+		handleError(fmt.Errorf("--bundle verifies a single build, it cannot be used with several --name or --selector"))
+	case downloadConcurrency < 1:
+		handleError(fmt.Errorf("--concurrency must be at least 1"))
+	}
+	if verifyOpts.enabled {
+		if err := checkVerifyOptions(); err != nil {
+			handleError(err)
+		}
+	}
+
+	names, err := downloadTargets(ctx, api)
+	if err != nil {
+		handleError(err)
+	}
+	if len(names) == 0 {
+		fmt.Println("No completed builds match")
+		return
+	}
+
+	progress := newCombinedProgress(os.Stderr, len(names))
+	results := downloadAll(names, downloadConcurrency, func(name string) downloadResult {
+		res := downloadBuild(ctx, api, name, progress)
+		progress.finished(res.err == nil)
+		return res
+	})
+	progress.Stop()
+
+	if err := printDownloadResults(os.Stdout, results); err != nil {
+		os.Exit(1)
+	}
+}
+
+// downloadAll calls download for each of names, at most concurrency at a time, and returns the
+// results in the order of names
+func downloadAll(names []string, concurrency int, download func(name string) downloadResult) []downloadResult {
+	results := make([]downloadResult, len(names))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = download(name)
+		}(i, name)
+	}
+	wg.Wait()
+	return results
+}
+
+// downloadTargets returns the builds to download: those named with --name, or the completed builds
+// matching --selector, newest first
+func downloadTargets(ctx context.Context, api *buildapiclient.Client) ([]string, error) {
+	if downloadSelector == "" {
+		seen := map[string]bool{}
+		var names []string
+		for _, n := range downloadNames {
+			if n = strings.TrimSpace(n); n != "" && !seen[n] {
+				seen[n] = true
+				names = append(names, n)
+			}
+		}
+		return names, nil
+	}
+	if len(downloadNames) > 0 {
+		return nil, fmt.Errorf("use either --name or --selector, not both")
+	}
+	var names []string
+	err := api.ForEachBuild(ctx, buildapiclient.ListBuildsOptions{
+		LabelSelector: downloadSelector,
+		Phases:        []string{"Completed"},
+	}, func(b buildapitypes.BuildListItem) error {
+		names = append(names, b.Name)
+		return nil
+	})
+	return names, err
+}
+
+// downloadBuild downloads the artifact of the build name to its own directory under --output-dir,
+// verifying it first with --verify
+func downloadBuild(ctx context.Context, api *buildapiclient.Client, name string, progress *combinedProgress) downloadResult {
+	res := downloadResult{name: name}
+	start := time.Now()
+	defer func() { res.elapsed = time.Since(start) }()
+
+	st, err := api.GetBuild(ctx, name)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	if st.Phase != "Completed" {
+		res.err = fmt.Errorf("build is %s, not completed", st.Phase)
+		return res
+	}
+	var verifier *artifactVerifier
+	if verifyOpts.enabled {
+		if verifier, err = newArtifactVerifier(ctx, api, name); err != nil {
+			res.err = err
+			return res
+		}
+		defer verifier.Close()
+	}
+	outDir := outputDir
+	if strings.TrimSpace(outDir) == "" {
+		outDir = "./output"
+	}
+	res.path, res.err = saveArtifact(ctx, serverURL, name, filepath.Join(outDir, name), verifier, io.Discard, progress)
+	if res.err == nil {
+		if fi, err := os.Stat(res.path); err == nil && !fi.IsDir() {
+			res.bytes = fi.Size()
+		}
+	}
+	return res
+}
+
+// printDownloadResults prints the outcome of each download, sorted by build name, and returns the
+// errors of the downloads that failed joined, each prefixed with its build, or nil
+func printDownloadResults(out io.Writer, results []downloadResult) error {
+	sort.Slice(results, func(i, j int) bool { return results[i].name < results[j].name })
+	var errs []error
+	fmt.Fprintf(out, "%-30s %-8s %-10s %-10s %s\n", "NAME", "RESULT", "SIZE", "TIME", "FILE")
+	for _, r := range results {
+		result, file, size := "ok", r.path, byteSize(r.bytes)
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.name, r.err))
+			result, file, size = "failed", r.err.Error(), ""
+		} else if r.bytes == 0 {
+			size = "-"
+		}
+		fmt.Fprintf(out, "%-30s %-8s %-10s %-10s %s\n", r.name, result, size, render.Duration(r.elapsed), file)
+	}
+	fmt.Fprintf(out, "\n%d of %d downloads succeeded\n", len(results)-len(errs), len(results))
+	return errors.Join(errs...)
+}
+
+// combinedProgress sums the transfers of several concurrent downloads into one progress line:
+// redrawn in place on terminals, printed every plainProgressInterval otherwise
+type combinedProgress struct {
+	mu       sync.Mutex
+	out      io.Writer
+	style    string
+	builds   int
+	done     int
+	failed   int
+	total    int64
+	received int64
+	start    time.Time
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+func newCombinedProgress(out io.Writer, builds int) *combinedProgress {
+	p := &combinedProgress{
+		out: out, style: progressStyle(out), builds: builds, start: time.Now(),
+		stop: make(chan struct{}), stopped: make(chan struct{}),
+	}
+	if p.style == progressNone {
+		close(p.stopped)
+		return p
+	}
+	interval := combinedProgressInterval
+	if p.style == progressPlain {
+		interval = plainProgressInterval
+	}
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.draw(false)
+			}
+		}
+	}()
+	return p
+}
+
+// track adds the size of the artifact in resp to the total and counts its bytes as they are read
+func (p *combinedProgress) track(resp *http.Response) {
+	p.mu.Lock()
+	if resp.ContentLength > 0 {
+		p.total += resp.ContentLength
+	}
+	p.mu.Unlock()
+	resp.Body = &countingBody{ReadCloser: resp.Body, p: p}
+}
+
+// finished records the end of the download of a build
+func (p *combinedProgress) finished(ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	if !ok {
+		p.failed++
+	}
+}
+
+// Stop ends the display with a final line
+func (p *combinedProgress) Stop() {
+	if p.style == progressNone {
+		return
+	}
+	close(p.stop)
+	<-p.stopped
+	p.draw(true)
+}
+
+func (p *combinedProgress) draw(final bool) {
+	p.mu.Lock()
+	line := fmt.Sprintf("Downloading %d builds: %d done", p.builds, p.done)
+	if p.failed > 0 {
+		line += fmt.Sprintf(", %d failed", p.failed)
+	}
+	if p.total > 0 {
+		line += fmt.Sprintf(", %s of %s", byteSize(p.received), byteSize(p.total))
+	} else {
+		line += ", " + byteSize(p.received)
+	}
+	if final {
+		line += " in " + render.Duration(time.Since(p.start))
+	}
+	p.mu.Unlock()
+
+	if p.style == progressPlain {
+		fmt.Fprintln(p.out, line)
+		return
+	}
+	fmt.Fprintf(p.out, "\r\033[K%s", line)
+	if final {
+		fmt.Fprintln(p.out)
+	}
+}
+
+// countingBody counts the bytes read from a response body into a combinedProgress
+type countingBody struct {
+	io.ReadCloser
+	p *combinedProgress
+}
+
+func (b *countingBody) Read(buf []byte) (int, error) {
+	n, err := b.ReadCloser.Read(buf)
+	if n > 0 {
+		b.p.mu.Lock()
+		b.p.received += int64(n)
+		b.p.mu.Unlock()
+	}
+	return n, err
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	buildapitypes "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+	buildapiclient "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi/client"
+)
+
+// setFlag sets a flag variable for the spec
+func setFlag[T any](flag *T, value T) {
+	previous := *flag
+	*flag = value
+	DeferCleanup(func() { *flag = previous })
+}
+
+var _ = Describe("downloadTargets", func() {
+	It("downloads every named build once, in order", func() {
+		setFlag(&downloadSelector, "")
+		setFlag(&downloadNames, []string{"b", " a ", "", "b", "c", "a"})
+		names, err := downloadTargets(context.Background(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names).To(Equal([]string{"b", "a", "c"}))
+	})
+
+	It("downloads the completed builds matching the selector", func() {
+		var query string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.RawQuery
+			_ = json.NewEncoder(w).Encode([]buildapitypes.BuildListItem{{Name: "new"}, {Name: "old"}})
+		}))
+		DeferCleanup(srv.Close)
+		api, err := buildapiclient.New(srv.URL)
+		Expect(err).NotTo(HaveOccurred())
+
+		setFlag(&downloadNames, nil)
+		setFlag(&downloadSelector, "pipeline=1234")
+		names, err := downloadTargets(context.Background(), api)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names).To(Equal([]string{"new", "old"}))
+		Expect(query).To(ContainSubstring("labelSelector=pipeline%3D1234"))
+		Expect(query).To(ContainSubstring("phase=Completed"))
+	})
+
+	It("rejects --name with --selector", func() {
+		setFlag(&downloadNames, []string{"a"})
+		setFlag(&downloadSelector, "pipeline=1234")
+		_, err := downloadTargets(context.Background(), nil)
+		Expect(err).To(MatchError("use either --name or --selector, not both"))
+	})
+})
+
+var _ = DescribeTable("downloadAll runs at most concurrency downloads at a time",
+	func(concurrency int) {
+		var running, most int32
+		names := []string{"a", "b", "c", "d", "e", "f", "g"}
+		results := downloadAll(names, concurrency, func(name string) downloadResult {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&most)
+				if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return downloadResult{name: name}
+		})
+		Expect(most).To(BeEquivalentTo(concurrency))
+		// the results keep the order of the builds
+		for i, r := range results {
+			Expect(r.name).To(Equal(names[i]))
+		}
+	},
+	Entry("one at a time", 1),
+	Entry("several", 3),
+)
+
+var _ = Describe("printDownloadResults", func() {
+	It("reports every download and returns the failures joined", func() {
+		notFound := errors.New("build not found")
+		notCompleted := errors.New("build is Failed, not completed")
+		var out bytes.Buffer
+		err := printDownloadResults(&out, []downloadResult{
+			{name: "c", err: notCompleted},
+			{name: "a", path: "output/a/disk.raw", bytes: 2048, elapsed: time.Second},
+			{name: "b", err: notFound},
+		})
+		Expect(err).To(MatchError(notFound))
+		Expect(err).To(MatchError(notCompleted))
+		Expect(err.Error()).To(Equal("b: build not found\nc: build is Failed, not completed"))
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		Expect(lines).To(HaveLen(6))
+		Expect(lines[1]).To(MatchRegexp(`^a\s+ok\s+\S+.*output/a/disk\.raw$`))
+		Expect(lines[2]).To(MatchRegexp(`^b\s+failed\s+.*build not found$`))
+		Expect(lines[3]).To(MatchRegexp(`^c\s+failed\s+.*build is Failed, not completed$`))
+		Expect(lines[5]).To(Equal("1 of 3 downloads succeeded"))
+	})
+
+	It("returns nil when every download succeeded", func() {
+		var out bytes.Buffer
+		Expect(printDownloadResults(&out, []downloadResult{{name: "a"}, {name: "b"}})).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("2 of 2 downloads succeeded"))
+	})
+})
+
+var _ = Describe("downloadBuild", func() {
+	var api *buildapiclient.Client
+
+	BeforeEach(func() {
+		// every build has an artifact named disk.raw
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name, artifact := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/v1/builds/"), "/artifact")
+			if !artifact {
+				phase := "Completed"
+				if name == "running" {
+					phase = "Building"
+				}
+				_ = json.NewEncoder(w).Encode(buildapitypes.BuildResponse{Name: name, Phase: phase})
+				return
+			}
+			w.Header().Set("Content-Disposition", `attachment; filename="disk.raw"`)
+			_, _ = fmt.Fprintf(w, "image of %s", name)
+		}))
+		DeferCleanup(srv.Close)
+		var err error
+		api, err = buildapiclient.New(srv.URL)
+		Expect(err).NotTo(HaveOccurred())
+		setFlag(&serverURL, srv.URL)
+		setFlag(&outputDir, GinkgoT().TempDir())
+		setFlag(&progressMode, progressNone)
+	})
+
+	It("saves artifacts with the same file name to the directory of each build", func() {
+		results := downloadAll([]string{"a", "b"}, 2, func(name string) downloadResult {
+			return downloadBuild(context.Background(), api, name, nil)
+		})
+		for _, r := range results {
+			Expect(r.err).NotTo(HaveOccurred())
+			Expect(r.path).To(Equal(filepath.Join(outputDir, r.name, "disk.raw")))
+			Expect(os.ReadFile(r.path)).To(BeEquivalentTo("image of " + r.name))
+			Expect(r.bytes).To(BeEquivalentTo(len("image of " + r.name)))
+		}
+	})
+
+	It("fails builds that are not completed without downloading them", func() {
+		r := downloadBuild(context.Background(), api, "running", nil)
+		Expect(r.err).To(MatchError("build is Building, not completed"))
+		Expect(filepath.Join(outputDir, "running")).NotTo(BeADirectory())
+	})
+})
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/pipelinegraph"
+)
+
+var (
+	pipelineGraphRun    string
+	pipelineGraphFormat string
+)
+
+// newPipelineCmd returns the "pipeline" command
+func newPipelineCmd() *cobra.Command {
+	pipelineCmd := &cobra.Command{
+		Use:   "pipeline",
+		Short: "Inspect the Tekton pipelines of the build API",
+	}
+
+	graphCmd := &cobra.Command{
+		Use:   "graph <pipeline-name>",
+		Short: "Show the tasks of a pipeline, what they run after and how far a run got",
+		Long: `Show the dependency graph of a Tekton pipeline, such as the automotive-build-pipeline the
+operator deploys, with the status of every task in its latest run or in --run. The text format
+lists the tasks stage by stage with their conditions and failures; --format dot prints Graphviz
+input, e.g. for "dot -Tsvg", and --format json the graph as the build API returns it.`,
+		Example: `  caib pipeline graph automotive-build-pipeline
+  caib pipeline graph automotive-build-pipeline --run automotive-build-pipeline-run-x7k2p
+  caib pipeline graph automotive-build-pipeline --format dot | dot -Tsvg > pipeline.svg`,
+		Args: cobra.ExactArgs(1),
+		Run:  runPipelineGraph,
+	}
+	graphCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	graphCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	graphCmd.Flags().StringVar(&pipelineGraphRun, "run", "", "PipelineRun to show the statuses of (default: the latest run)")
+	graphCmd.Flags().StringVar(&pipelineGraphFormat, "format", pipelinegraph.FormatText, "output format: text, dot or json")
+	pipelineCmd.AddCommand(graphCmd)
+	return pipelineCmd
+}
+
+func runPipelineGraph(_ *cobra.Command, args []string) {
+	switch pipelineGraphFormat {
+	case pipelinegraph.FormatText, pipelinegraph.FormatDOT, pipelinegraph.FormatJSON:
+	default:
+		handleError(fmt.Errorf("--format must be text, dot or json"))
+	}
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	graph, err := api.GetPipelineGraph(ctx, args[0], pipelineGraphRun)
+	if err != nil {
+		handleError(err)
+	}
+	switch pipelineGraphFormat {
+	case pipelinegraph.FormatDOT:
+		fmt.Print(graph.DOT())
+	case pipelinegraph.FormatJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(graph); err != nil {
+			handleError(err)
+		}
+	default:
+		fmt.Print(graph.Text())
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/render"
+	progressbar "github.com/schollz/progressbar/v3"
+	"golang.org/x/term"
+)
+
+// progressMode is the --progress flag: auto, plain or none
+var progressMode = progressAuto
+
+const (
+	// progressAuto draws progress bars on terminals and prints plain lines otherwise
+	progressAuto = "auto"
+	// progressPlain prints a progress line every plainProgressInterval, e.g. for CI logs
+	progressPlain = "plain"
+	// progressNone shows no progress
+	progressNone = "none"
+)
+
+const (
+	// plainProgressInterval is how often a transfer prints a plain progress line
+	plainProgressInterval = 10 * time.Second
+	// plainWaitInterval is how often waiting for a build prints a plain progress line
+	plainWaitInterval = time.Minute
+)
+
+func validateProgressMode() error {
+	switch progressMode {
+	case progressAuto, progressPlain, progressNone:
+		return nil
+	}
+	return fmt.Errorf("--progress must be auto, plain or none, got %q", progressMode)
+}
+
+// progressStyle resolves --progress for output w: auto becomes plain unless w is a terminal
+func progressStyle(w io.Writer) string {
+	if progressMode != progressAuto {
+		return progressMode
+	}
+	if f, ok := w.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		return progressAuto
+	}
+	return progressPlain
+}
+
+// transferProgress reports the bytes written to it as a bar, as periodic plain lines or not at all
+type transferProgress struct {
+	out         io.Writer
+	bar         *progressbar.ProgressBar
+	plain       bool
+	description string
+	total       int64
+	done        int64
+	start, last time.Time
+}
+
+// newTransferProgress returns a progress display for a transfer of total bytes, or of unknown
+// size when total is not positive, rendered on out
+func newTransferProgress(out io.Writer, description string, total int64) *transferProgress {
+	p := &transferProgress{out: out, description: description, total: total, start: time.Now()}
+	p.last = p.start
+	switch progressStyle(out) {
+	case progressNone:
+	case progressPlain:
+		p.plain = true
+	default:
+		if total > 0 {
+			p.bar = progressbar.NewOptions64(
+				total,
+				progressbar.OptionSetWriter(out),
+				progressbar.OptionSetDescription(description),
+				progressbar.OptionShowBytes(true),
+				progressbar.OptionSetWidth(15),
+				progressbar.OptionThrottle(65*time.Millisecond),
+				progressbar.OptionShowCount(),
+				progressbar.OptionClearOnFinish(),
+			)
+		} else {
+			p.bar = progressbar.NewOptions(
+				-1,
+				progressbar.OptionSetWriter(out),
+				progressbar.OptionSetDescription(description),
+				progressbar.OptionSpinnerType(14),
+				progressbar.OptionClearOnFinish(),
+			)
+		}
+	}
+	return p
+}
+
+func (p *transferProgress) Write(b []byte) (int, error) {
+	p.done += int64(len(b))
+	if p.bar != nil {
+		return p.bar.Write(b)
+	}
+	if p.plain {
+		if now := time.Now(); now.Sub(p.last) >= plainProgressInterval {
+			p.last = now
+			fmt.Fprintln(p.out, p.line())
+		}
+	}
+	return len(b), nil
+}
+
+// Finish completes the display; plain progress prints a final line with the time taken
+func (p *transferProgress) Finish() {
+	switch {
+	case p.bar != nil:
+		_ = p.bar.Finish()
+		fmt.Fprintln(p.out)
+	case p.plain:
+		fmt.Fprintf(p.out, "%s in %s\n", p.line(), render.Duration(time.Since(p.start)))
+	}
+}
+
+// Abort removes the display of a transfer that failed
+func (p *transferProgress) Abort() {
+	if p.bar != nil {
+		_ = p.bar.Clear()
+	}
+}
+
+func (p *transferProgress) line() string {
+	if p.total > 0 {
+		return fmt.Sprintf("%s: %d%% (%s of %s)", p.description, p.done*100/p.total, byteSize(p.done), byteSize(p.total))
+	}
+	return fmt.Sprintf("%s: %s", p.description, byteSize(p.done))
+}
+
+// waitProgress shows how long a build has been in its current phase: as a spinner on terminals
+// and as a line every plainWaitInterval otherwise
+type waitProgress struct {
+	out   io.Writer
+	bar   *progressbar.ProgressBar
+	plain bool
+	start time.Time
+	last  time.Time
+}
+
+func newWaitProgress(out io.Writer) *waitProgress {
+	p := &waitProgress{out: out, start: time.Now()}
+	p.last = p.start
+	switch progressStyle(out) {
+	case progressNone:
+	case progressPlain:
+		p.plain = true
+	default:
+		p.bar = progressbar.NewOptions(
+			-1,
+			progressbar.OptionSetWriter(out),
+			progressbar.OptionSpinnerType(14),
+			progressbar.OptionClearOnFinish(),
+		)
+	}
+	return p
+}
+
+// Update shows the current phase of the build and the time waited so far
+func (p *waitProgress) Update(phase string) {
+	elapsed := render.Duration(time.Since(p.start))
+	switch {
+	case p.bar != nil:
+		p.bar.Describe(fmt.Sprintf("%s (%s)", render.PhaseName(phase), elapsed))
+	case p.plain:
+		if now := time.Now(); now.Sub(p.last) >= plainWaitInterval {
+			p.last = now
+			fmt.Fprintf(p.out, "still waiting: %s after %s\n", render.PhaseName(phase), elapsed)
+		}
+	}
+}
+
+// Clear removes the spinner so other output can be printed
+func (p *waitProgress) Clear() {
+	if p.bar != nil {
+		_ = p.bar.Clear()
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	buildapitypes "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// rerunYes skips the confirmation of changed inputs
+var rerunYes bool
+
+// newRerunCmd returns the "rerun" command, which reruns a build with the same inputs or a new
+// manifest or defines
+func newRerunCmd() *cobra.Command {
+	rerunCmd := &cobra.Command{
+		Use:   "rerun <name>",
+		Short: "Rerun a build, optionally with a new manifest or defines",
+		Long: `Rerun a build under a new name with the inputs it was started with. With --manifest or
+--define the changes to the manifest and defines of the build are shown as a diff and have to be
+confirmed, or accepted up front with --yes, so release builds are not rerun with inputs that drifted
+by accident.`,
+		Args: cobra.ExactArgs(1),
+		Run:  runRerun,
+	}
+	rerunCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	rerunCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	rerunCmd.Flags().StringVar(&manifest, "manifest", "", "manifest YAML file replacing the manifest of the build")
+	rerunCmd.Flags().StringVar(&buildName, "name", "", "name of the rerun build (default: <name>-rerun-<timestamp>)")
+	rerunCmd.Flags().StringArrayVar(&customDefs, "define", nil, "define in KEY=VALUE format replacing the same KEY of the build (can be specified multiple times)")
+	rerunCmd.Flags().StringArrayVar(&defineFiles, "define-file", nil, "YAML file of defines (KEY: VALUE) applied before --define (can be specified multiple times)")
+	rerunCmd.Flags().StringSliceVar(&defineProfiles, "define-profile", nil, "profile sections of the --define-file files to apply, comma-separated or repeated")
+	rerunCmd.Flags().BoolVarP(&rerunYes, "yes", "y", false, "rerun with changed inputs without asking")
+	rerunCmd.Flags().BoolVarP(&waitForBuild, "wait", "w", false, "wait for the build to complete")
+	rerunCmd.Flags().BoolVarP(&followLogs, "follow", "f", false, "follow logs of the build")
+	rerunCmd.Flags().IntVar(&timeout, "timeout", 60, "timeout in minutes when waiting for build completion")
+	return rerunCmd
+}
+
+func runRerun(_ *cobra.Command, args []string) {
+	ctx := context.Background()
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	source := args[0]
+	tpl, err := api.GetBuildTemplate(ctx, source)
+	if err != nil {
+		handleError(fmt.Errorf("error fetching inputs of %s: %w", source, err))
+	}
+
+	patch := map[string]any{}
+	newManifest := tpl.Manifest
+	if strings.TrimSpace(manifest) != "" {
+		b, err := os.ReadFile(manifest)
+		if err != nil {
+			handleError(fmt.Errorf("error reading manifest: %w", err))
+		}
+		newManifest = string(b)
+		if newManifest != tpl.Manifest {
+			patch["manifest"] = newManifest
+			patch["manifestFileName"] = filepath.Base(manifest)
+			if tpl.Source != nil {
+				// the local manifest replaces the git source
+				patch["source"] = nil
+			}
+		}
+	}
+	newDefines := tpl.CustomDefs
+	if len(customDefs) > 0 || len(defineFiles) > 0 {
+		defines, err := resolveDefines()
+		if err != nil {
+			handleError(err)
+		}
+		newDefines = mergeDefines(tpl.CustomDefs, defines)
+		if !slices.Equal(newDefines, tpl.CustomDefs) {
+			patch["customDefs"] = newDefines
+		}
+	}
+
+	if len(patch) == 0 {
+		fmt.Printf("Rerunning %s with unchanged inputs\n", source)
+	} else {
+		fmt.Print(renderer.Diff(source+" manifest", "new manifest", tpl.Manifest, newManifest))
+		fmt.Print(renderer.Diff(source+" defines", "new defines", defineLines(tpl.CustomDefs), defineLines(newDefines)))
+		if !rerunYes && !confirm(fmt.Sprintf("Rerun %s with these changes?", source)) {
+			handleError(fmt.Errorf("rerun of %s aborted", source))
+		}
+	}
+
+	if buildName == "" {
+		buildName = fmt.Sprintf("%s-rerun-%s", source, time.Now().Format("20060102-150405"))
+	}
+	var patchJSON string
+	if len(patch) > 0 {
+		b, err := json.Marshal(patch)
+		if err != nil {
+			handleError(err)
+		}
+		patchJSON = string(b)
+	}
+	resp, err := api.CloneBuild(ctx, source, buildapitypes.BuildCloneRequest{Name: buildName, Patch: patchJSON})
+	if err != nil {
+		handleError(err)
+	}
+	fmt.Printf("Build %s accepted: %s - %s\n", resp.Name, renderer.Phase(resp.Phase), resp.Message)
+	if resp.RequestID != "" {
+		fmt.Printf("Request ID: %s\n", resp.RequestID)
+	}
+
+	uploadLocalFiles(ctx, api, resp.Name, newManifest)
+	if waitForBuild || followLogs {
+		waitForBuildCompletion(ctx, api, resp.Name)
+	}
+}
+
+// defineLines lists defines one per line, sorted so reordering them shows no change
+func defineLines(defines []string) string {
+	sorted := slices.Clone(defines)
+	slices.Sort(sorted)
+	if len(sorted) == 0 {
+		return ""
+	}
+	return strings.Join(sorted, "\n") + "\n"
+}
+
+// confirm asks a yes/no question on the terminal. Without a terminal there is nobody to answer
+// and the question counts as declined.
+func confirm(question string) bool {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintln(os.Stderr, "stdin is not a terminal; pass --yes to confirm")
+		return false
+	}
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
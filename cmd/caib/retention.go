@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	buildapitypes "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+	"github.com/spf13/cobra"
+)
+
+// retentionClear removes the retention of a build, so its artifacts are kept
+var retentionClear bool
+
+// buildRetention returns the retention the --keep-for, --keep-last and --keep-prefix flags ask
+// for, nil when none
+func buildRetention() *buildapitypes.Retention {
+	if keepFor == "" && keepLast == 0 && keepPrefix == "" {
+		return nil
+	}
+	return &buildapitypes.Retention{KeepFor: keepFor, KeepLast: keepLast, NamePrefix: keepPrefix}
+}
+
+// newRetentionCmd returns the "retention" command
+func newRetentionCmd() *cobra.Command {
+	retentionCmd := &cobra.Command{
+		Use:   "retention <build-name>",
+		Short: "Change how long the artifacts of a build are kept",
+		Long: `Change the retention of the artifacts of a build. --keep-for deletes them a while after
+the build completed; --keep-last keeps only those of the newest N completed builds whose name
+starts with --keep-prefix. The new retention replaces the previous one, and --clear keeps the
+artifacts until the build is deleted. Artifacts already deleted cannot be brought back.`,
+		Example: `  caib retention nightly-42 --keep-for 90d
+  caib retention nightly-42 --keep-last 5 --keep-prefix nightly-
+  caib retention nightly-42 --clear`,
+		Args: cobra.ExactArgs(1),
+		Run:  runRetention,
+	}
+	retentionCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	retentionCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	retentionCmd.Flags().StringVar(&keepFor, "keep-for", "", "delete the artifacts this long after the build completed (e.g. 30d, 72h)")
+	retentionCmd.Flags().Int32Var(&keepLast, "keep-last", 0, "keep only the artifacts of the newest N completed builds named --keep-prefix*")
+	retentionCmd.Flags().StringVar(&keepPrefix, "keep-prefix", "", "name prefix grouping the builds --keep-last counts (e.g. nightly-)")
+	retentionCmd.Flags().BoolVar(&retentionClear, "clear", false, "remove the retention, keeping the artifacts")
+	return retentionCmd
+}
+
+func runRetention(_ *cobra.Command, args []string) {
+	retention := buildRetention()
+	switch {
+	case retentionClear && retention != nil:
+		handleError(fmt.Errorf("--clear cannot be combined with --keep-for, --keep-last or --keep-prefix"))
+	case retentionClear:
+		retention = &buildapitypes.Retention{}
+	case retention == nil:
+		handleError(fmt.Errorf("pass --keep-for, --keep-last with --keep-prefix, or --clear"))
+	}
+
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := api.PatchBuild(ctx, args[0], buildapitypes.BuildPatchRequest{Retention: retention}); err != nil {
+		handleError(err)
+	}
+	switch {
+	case retentionClear:
+		fmt.Printf("The artifacts of %s are kept until the build is deleted\n", args[0])
+	case keepFor != "" && keepLast > 0:
+		fmt.Printf("The artifacts of %s are kept for %s while they are among the newest %d of %s*\n", args[0], keepFor, keepLast, keepPrefix)
+	case keepFor != "":
+		fmt.Printf("The artifacts of %s are kept for %s after it completed\n", args[0], keepFor)
+	default:
+		fmt.Printf("The artifacts of %s are kept while they are among the newest %d of %s*\n", args[0], keepLast, keepPrefix)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// knownRuntimes are the container runtimes local builds can use, in the order they are looked for
+var knownRuntimes = []string{"podman", "docker", "nerdctl"}
+
+// containerRuntime is the command local builds start containers with
+type containerRuntime struct {
+	// kind is podman, docker or nerdctl
+	kind string
+	// command is the command line of the runtime, e.g. ["sudo", "podman"]
+	command []string
+}
+
+// detectRuntime returns the runtime named by spec, e.g. "docker" or "sudo podman", or the first
+// of knownRuntimes installed when spec is empty
+func detectRuntime(spec string) (containerRuntime, error) {
+	command := strings.Fields(spec)
+	if len(command) == 0 {
+		for _, name := range knownRuntimes {
+			if _, err := exec.LookPath(name); err == nil {
+				command = []string{name}
+				break
+			}
+		}
+		if len(command) == 0 {
+			return containerRuntime{}, fmt.Errorf("no container runtime found; install one of %s or pass --runtime", strings.Join(knownRuntimes, ", "))
+		}
+	}
+	kind := filepath.Base(command[len(command)-1])
+	switch kind {
+	case "podman", "nerdctl":
+	case "docker":
+		// podman-docker installs podman as docker
+		if out, err := exec.Command(command[len(command)-1], "--version").Output(); err == nil && strings.Contains(strings.ToLower(string(out)), "podman") {
+			kind = "podman"
+		}
+	default:
+		return containerRuntime{}, fmt.Errorf("unsupported container runtime %q; use one of %s", kind, strings.Join(knownRuntimes, ", "))
+	}
+	return containerRuntime{kind: kind, command: command}, nil
+}
+
+// runOptions returns the "run" options that differ between runtimes for the privileged
+// automotive-image-builder container
+func (r containerRuntime) runOptions() []string {
+	switch r.kind {
+	case "podman":
+		return []string{"--pull=newer", "--security-opt", "label=type:unconfined_t"}
+	case "docker":
+		return []string{"--pull=always", "--security-opt", "label=type:unconfined_t"}
+	}
+	// nerdctl has no SELinux labels
+	return []string{"--pull=always"}
+}
+
+// rootless reports whether the runtime runs containers without root, which automotive-image-builder
+// cannot build in since it needs loop devices
+func (r containerRuntime) rootless() bool {
+	if r.command[0] == "sudo" || os.Geteuid() == 0 {
+		return false
+	}
+	switch r.kind {
+	case "podman":
+		out, err := r.output("info", "--format", "{{.Host.Security.Rootless}}")
+		return err == nil && strings.TrimSpace(out) == "true"
+	case "docker":
+		out, err := r.output("info", "--format", "{{.SecurityOptions}}")
+		return err == nil && strings.Contains(out, "rootless")
+	case "nerdctl":
+		// nerdctl run by a user talks to rootless containerd
+		return true
+	}
+	return false
+}
+
+func (r containerRuntime) output(args ...string) (string, error) {
+	out, err := exec.Command(r.command[0], append(r.command[1:], args...)...).Output()
+	return string(out), err
+}
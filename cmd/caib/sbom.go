@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	sbomFormat string
+	sbomOutput string
+)
+
+// newSBOMCmd returns the "sbom" command
+func newSBOMCmd() *cobra.Command {
+	sbomCmd := &cobra.Command{
+		Use:   "sbom <build-name>",
+		Short: "Download the software bill of materials of a build",
+		Long: `Download the SBOM of a completed build: the RPMs installed in its image, with their
+versions, package URLs and license tags, as SPDX 2.3 or CycloneDX 1.5 JSON. The build lists the
+packages of the image it produced, so the image does not need to be downloaded or mounted. Only
+image and qcow2 exports have an SBOM.`,
+		Example: `  caib sbom my-build
+  caib sbom my-build --format cyclonedx -o my-build.cdx.json`,
+		Args: cobra.ExactArgs(1),
+		Run:  runSBOM,
+	}
+	sbomCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	sbomCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	sbomCmd.Flags().StringVar(&sbomFormat, "format", "spdx", "SBOM format: spdx or cyclonedx")
+	sbomCmd.Flags().StringVarP(&sbomOutput, "output", "o", "", "file to write the SBOM to (default: standard output)")
+	return sbomCmd
+}
+
+func runSBOM(_ *cobra.Command, args []string) {
+	if sbomFormat != "spdx" && sbomFormat != "cyclonedx" {
+		handleError(fmt.Errorf("--format must be spdx or cyclonedx"))
+	}
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+	data, err := api.GetSBOM(ctx, args[0], sbomFormat)
+	if err != nil {
+		handleError(err)
+	}
+	if sbomOutput == "" {
+		_, _ = os.Stdout.Write(data)
+		fmt.Println()
+		return
+	}
+	if err := os.WriteFile(sbomOutput, data, 0o644); err != nil {
+		handleError(fmt.Errorf("write %s: %w", sbomOutput, err))
+	}
+	fmt.Fprintf(os.Stderr, "%s SBOM of %s written to %s\n", sbomFormat, args[0], sbomOutput)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	buildapiclient "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi/client"
+	"github.com/spf13/cobra"
+)
+
+var searchOpts buildapiclient.LogSearchOptions
+
+// newSearchLogsCmd returns the "search-logs" command, which greps build logs on the server
+func newSearchLogsCmd() *cobra.Command {
+	searchCmd := &cobra.Command{
+		Use:   "search-logs <name> <pattern>",
+		Short: "Search the logs of a build on the server",
+		Long: `Print the log lines of a build that contain pattern, like grep, without downloading the
+whole log. Lines are prefixed with the build step and line number. Logs can be searched as long
+as the build pod exists. Exits with 1 when nothing matched.`,
+		Args: cobra.ExactArgs(2),
+		Run:  runSearchLogs,
+	}
+	searchCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	searchCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	searchCmd.Flags().BoolVarP(&searchOpts.Regex, "regexp", "E", false, "treat pattern as a regular expression (RE2 syntax)")
+	searchCmd.Flags().BoolVarP(&searchOpts.IgnoreCase, "ignore-case", "i", false, "match case-insensitively")
+	searchCmd.Flags().IntVarP(&searchOpts.Context, "context", "C", 0, "lines of context to print around each match (max 20)")
+	searchCmd.Flags().IntVarP(&searchOpts.MaxMatches, "max-count", "m", 0, "stop after this many matches (default 100, max 1000)")
+	searchCmd.Flags().StringVar(&searchOpts.Step, "step", "", "only search the log of this build step")
+	return searchCmd
+}
+
+func runSearchLogs(_ *cobra.Command, args []string) {
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	opts := searchOpts
+	opts.Query = args[1]
+	resp, err := api.SearchLogs(context.Background(), args[0], opts)
+	if err != nil {
+		handleError(err)
+	}
+
+	for i, m := range resp.Matches {
+		if opts.Context > 0 && i > 0 {
+			fmt.Println("--")
+		}
+		for j, line := range m.Before {
+			fmt.Printf("%s-%d-%s\n", m.Step, m.Line-len(m.Before)+j, line)
+		}
+		fmt.Printf("%s:%d:%s\n", m.Step, m.Line, m.Text)
+		for j, line := range m.After {
+			fmt.Printf("%s-%d-%s\n", m.Step, m.Line+1+j, line)
+		}
+	}
+	if resp.Truncated {
+		fmt.Fprintf(os.Stderr, "Stopped after %d matches; use --max-count to see more\n", len(resp.Matches))
+	}
+	if len(resp.Matches) == 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+)
+
+var (
+	shareExpires time.Duration
+	shareFile    string
+	sharePart    bool
+)
+
+// newShareCmd returns the "share" command, which creates a time-limited download URL for the
+// artifact of a build
+func newShareCmd() *cobra.Command {
+	shareCmd := &cobra.Command{
+		Use:   "share <build-name>",
+		Short: "Print a time-limited URL that downloads the artifact of a build without a token",
+		Long: `Create a URL that downloads the artifact of a completed build, or a file of its artifact
+manifest, without cluster credentials, for handing results to test labs and partners. Anyone with
+the URL can download until it expires; downloads are recorded for you.
+
+The URL stops working when the build is deleted. Operators revoke every URL by deleting the
+ado-build-api-url-signing Secret of the build API.`,
+		Example: `  caib share my-build
+  caib share my-build --expires 24h
+  caib share my-build --file my-build-hardening-report.json`,
+		Args: cobra.ExactArgs(1),
+		Run:  runShare,
+	}
+	shareCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	shareCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	shareCmd.Flags().DurationVar(&shareExpires, "expires", time.Hour, "how long the URL is valid, at most 168h")
+	shareCmd.Flags().StringVar(&shareFile, "file", "", "file of the artifact manifest to share instead of the artifact")
+	shareCmd.Flags().BoolVar(&sharePart, "part", false, "the file is a part of the artifact, as listed by caib download --list")
+	return shareCmd
+}
+
+func runShare(_ *cobra.Command, args []string) {
+	if shareExpires < time.Minute {
+		handleError(fmt.Errorf("--expires must be at least 1m"))
+	}
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	resp, err := api.CreateSignedURL(context.Background(), args[0], buildapi.SignedURLRequest{
+		ExpiresInMinutes: int(shareExpires / time.Minute),
+		File:             shareFile,
+		Part:             sharePart,
+	})
+	if err != nil {
+		handleError(err)
+	}
+	fmt.Println(resp.URL)
+	fmt.Fprintf(os.Stderr, "Valid until %s\n", resp.ExpiresAt)
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/devsync"
+	aibmanifest "github.com/centos-automotive-suite/automotive-dev-operator/pkg/manifest"
+)
+
+var (
+	syncManifest   string
+	syncHost       string
+	syncPort       int
+	syncIdentity   string
+	syncSSHOptions []string
+	syncRestart    []string
+	syncSudo       bool
+	syncDryRun     bool
+)
+
+// newSyncCmd returns the "sync" command, which copies changed add_files content into a running
+// test VM or device
+func newSyncCmd() *cobra.Command {
+	syncCmd := &cobra.Command{
+		Use:   "sync <build-name> --manifest <file> --host <[user@]host>",
+		Short: "Copy changed add_files content of a manifest into a running test VM or device",
+		Long: `Copy the files the add_files entries of a manifest add to the image, and to its QM
+partition, into a running VM or device flashed with the build, over ssh with rsync. Only files
+whose content differs are transferred. Units whose unit files, drop-ins or Quadlet files changed
+are restarted after a daemon-reload, as are the units given with --restart.
+
+This skips rebuilding the image for userspace changes. The target no longer matches its build
+afterwards: caib records the build, its build record and every synced file in
+/var/lib/caib/dirty.json on the target and says so in the login message. Reflash the target to
+return to the build. Entries downloaded from a url are not synced.
+
+The target needs rsync; files are written as the ssh user, or as root with --sudo.`,
+		Example: `  caib sync my-build --manifest my.aib.yml --host root@192.168.122.10
+  caib sync my-build --manifest my.aib.yml --host dev@ecu --sudo --restart radio.service
+  caib sync my-build --manifest my.aib.yml --host root@localhost --port 2222 --dry-run`,
+		Args: cobra.ExactArgs(1),
+		Run:  runSync,
+	}
+	syncCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL, to record the build record of the build on the target (optional)")
+	syncCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	syncCmd.Flags().StringVar(&syncManifest, "manifest", "", "manifest whose add_files content is synced")
+	syncCmd.Flags().StringVar(&syncHost, "host", "", "ssh destination of the target, [user@]host")
+	syncCmd.Flags().IntVarP(&syncPort, "port", "p", 0, "ssh port of the target")
+	syncCmd.Flags().StringVarP(&syncIdentity, "identity", "i", "", "ssh private key to log in with")
+	syncCmd.Flags().StringArrayVarP(&syncSSHOptions, "ssh-option", "o", nil, "ssh option, e.g. StrictHostKeyChecking=no (repeatable)")
+	syncCmd.Flags().StringSliceVar(&syncRestart, "restart", nil, "systemd units to restart on the target when files changed (repeatable)")
+	syncCmd.Flags().BoolVar(&syncSudo, "sudo", false, "write files and restart units with sudo on the target")
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "list what would be synced and restarted without changing the target")
+	_ = syncCmd.MarkFlagRequired("manifest")
+	_ = syncCmd.MarkFlagRequired("host")
+	return syncCmd
+}
+
+func runSync(_ *cobra.Command, args []string) {
+	build := args[0]
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+	defer cancel()
+
+	data, err := os.ReadFile(syncManifest)
+	if err != nil {
+		handleError(fmt.Errorf("read manifest: %w", err))
+	}
+	m, err := aibmanifest.Parse(data)
+	if err != nil {
+		handleError(err)
+	}
+	files, skipped, err := devsync.Files(m, filepath.Dir(syncManifest))
+	if err != nil {
+		handleError(err)
+	}
+	for _, p := range skipped {
+		fmt.Fprintf(os.Stderr, "Skipping %s: downloaded from a url at build time\n", p)
+	}
+	if len(files) == 0 {
+		fmt.Println("The manifest adds no files to sync")
+		return
+	}
+
+	stage, err := os.MkdirTemp("", "caib-sync-")
+	if err != nil {
+		handleError(err)
+	}
+	defer os.RemoveAll(stage)
+	if err := devsync.Stage(files, stage); err != nil {
+		handleError(fmt.Errorf("stage files: %w", err))
+	}
+
+	out, err := syncRsync(ctx, stage)
+	if err != nil {
+		handleError(err)
+	}
+	changed := devsync.Changed(out)
+	host, qm := devsync.Restarts(changed)
+	if len(changed) > 0 {
+		for _, unit := range syncRestart {
+			if !slices.Contains(host.Units, unit) {
+				host.Units = append(host.Units, unit)
+			}
+		}
+	}
+
+	verb := "Synced"
+	if syncDryRun {
+		verb = "Would sync"
+	}
+	if len(changed) == 0 {
+		fmt.Printf("%s is up to date with %s\n", syncHost, syncManifest)
+		return
+	}
+	fmt.Printf("%s %d files to %s:\n", verb, len(changed), syncHost)
+	for _, p := range changed {
+		fmt.Printf("  %s\n", p)
+	}
+	printRestarts(syncDryRun, "", host)
+	printRestarts(syncDryRun, "QM partition ", qm)
+	if syncDryRun {
+		return
+	}
+
+	marker, err := syncMarker(ctx, build, files, changed)
+	if err != nil {
+		handleError(err)
+	}
+	if err := syncFinish(ctx, marker, host, qm); err != nil {
+		handleError(err)
+	}
+	fmt.Printf("%s now differs from build %s; see %s on the target\n", syncHost, build, devsync.MarkerPath)
+}
+
+func printRestarts(dryRun bool, scope string, r devsync.Restart) {
+	if len(r.Units) == 0 {
+		return
+	}
+	verb := "Restarted"
+	if dryRun {
+		verb = "Would restart"
+	}
+	fmt.Printf("%s %sunits: %s\n", verb, scope, strings.Join(r.Units, ", "))
+}
+
+// sshCommand is the ssh invocation reaching the target, without the destination
+func sshCommand() []string {
+	command := []string{"ssh"}
+	if syncPort > 0 {
+		command = append(command, "-p", strconv.Itoa(syncPort))
+	}
+	if syncIdentity != "" {
+		command = append(command, "-i", syncIdentity)
+	}
+	for _, opt := range syncSSHOptions {
+		command = append(command, "-o", opt)
+	}
+	return command
+}
+
+// sudo prefixes a command run on the target with sudo when --sudo is set
+func sudo(command string) string {
+	if syncSudo {
+		return "sudo " + command
+	}
+	return command
+}
+
+// syncRsync transfers the files of the staged image root that differ from the target, by content,
+// and returns what rsync reported as transferred. Existing files keep their owner and mode.
+func syncRsync(ctx context.Context, stage string) (string, error) {
+	quoted := make([]string, 0, len(sshCommand()))
+	for _, arg := range sshCommand() {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	args := []string{"--recursive", "--checksum", "--out-format=%n", "-e", strings.Join(quoted, " ")}
+	if syncSudo {
+		args = append(args, "--rsync-path=sudo rsync")
+	}
+	if syncDryRun {
+		args = append(args, "--dry-run")
+	}
+	args = append(args, "./", syncHost+":/")
+	cmd := exec.CommandContext(ctx, "rsync", args...)
+	cmd.Dir = stage
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("rsync to %s failed: %w", syncHost, err)
+	}
+	return stdout.String(), nil
+}
+
+// syncMarker returns the marker of the target after the changed files were synced, continuing
+// the one the target has
+func syncMarker(ctx context.Context, build string, files []devsync.File, changed []string) (*devsync.Marker, error) {
+	var existing bytes.Buffer
+	if err := runSSH(ctx, sudo("cat "+devsync.MarkerPath)+" 2>/dev/null || true", nil, &existing); err != nil {
+		return nil, fmt.Errorf("read %s on the target: %w", devsync.MarkerPath, err)
+	}
+	synced := slices.DeleteFunc(slices.Clone(files), func(f devsync.File) bool {
+		return !slices.Contains(changed, f.Path)
+	})
+	return devsync.Update(existing.Bytes(), build, syncBuildRecord(ctx, build), synced, time.Now())
+}
+
+// syncBuildRecord is the digest of the latest build record of build, empty when there is none or
+// no server is configured
+func syncBuildRecord(ctx context.Context, build string) string {
+	if strings.TrimSpace(serverURL) == "" {
+		return ""
+	}
+	api, err := newAPIClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: not recording the build record: %v\n", err)
+		return ""
+	}
+	resp, err := api.GetBuildRecords(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: not recording the build record: %v\n", err)
+		return ""
+	}
+	digest := ""
+	for _, r := range resp.Records {
+		if r.Build == build {
+			digest = r.Digest
+		}
+	}
+	return digest
+}
+
+// syncFinish writes the marker and login message to the target, then restarts units. The marker
+// comes first so the target is flagged even when a restart fails.
+func syncFinish(ctx context.Context, marker *devsync.Marker, host, qm devsync.Restart) error {
+	data, err := json.MarshalIndent(marker, "", "  ")
+	if err != nil {
+		return err
+	}
+	script := []string{
+		"set -e",
+		sudo("mkdir -p " + path.Dir(devsync.MarkerPath) + " " + path.Dir(devsync.MotdPath)),
+		sudo("tee "+devsync.MarkerPath) + " >/dev/null",
+		"printf '%s' " + shellQuote(marker.Motd()) + " | " + sudo("tee "+devsync.MotdPath) + " >/dev/null",
+	}
+	script = append(script, restartCommands(sudo("systemctl"), host)...)
+	script = append(script, restartCommands(sudo("podman exec qm systemctl"), qm)...)
+	return runSSH(ctx, strings.Join(script, "\n"), bytes.NewReader(append(data, '\n')), os.Stdout)
+}
+
+// restartCommands are the commands applying r with systemctl
+func restartCommands(systemctl string, r devsync.Restart) []string {
+	var commands []string
+	if r.DaemonReload {
+		commands = append(commands, systemctl+" daemon-reload")
+	}
+	if len(r.Units) > 0 {
+		units := make([]string, 0, len(r.Units))
+		for _, unit := range r.Units {
+			units = append(units, shellQuote(unit))
+		}
+		commands = append(commands, systemctl+" restart "+strings.Join(units, " "))
+	}
+	return commands
+}
+
+// runSSH runs a shell script on the target
+func runSSH(ctx context.Context, script string, stdin io.Reader, stdout io.Writer) error {
+	command := append(sshCommand(), syncHost, "sh -c "+shellQuote(script))
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ssh %s failed: %w", syncHost, err)
+	}
+	return nil
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%+=:,./_-") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	buildapitypes "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+	buildapiclient "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi/client"
+	"github.com/spf13/cobra"
+)
+
+// newValidateCmd returns the "validate" command
+func newValidateCmd() *cobra.Command {
+	validateCmd := &cobra.Command{
+		Use:   "validate <manifest>",
+		Short: "Check a manifest for problems a build of it would be rejected for",
+		Long: `Ask the server to check a manifest the way a build reads it: YAML syntax, fields of the
+wrong type, add_files entries with relative or duplicate paths, and the other problems
+automotive-image-builder rejects, along with the distro, target, architecture and export format
+it would be built with. Problems are printed as file:line:column: field: message, so editors and
+CI can point at them. Nothing is created. caib exits non-zero when the manifest is invalid.
+
+caib build runs the same checks before creating a build.`,
+		Example: `  caib validate gateway.aib.yml
+  caib validate gateway.aib.yml --target rpi4 --arch arm64 --export qcow2`,
+		Args: cobra.ExactArgs(1),
+		Run:  runValidate,
+	}
+	validateCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	validateCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	validateCmd.Flags().StringVar(&distro, "distro", "autosd", "distribution to build")
+	validateCmd.Flags().StringVar(&target, "target", "qemu", "target platform (qemu, etc)")
+	validateCmd.Flags().StringVar(&architecture, "arch", "arm64", "architecture (amd64, arm64)")
+	validateCmd.Flags().StringVar(&exportFormat, "export", "image", "export format (image, qcow2, etc)")
+	validateCmd.Flags().StringVar(&mode, "mode", "image", "build mode")
+	validateCmd.Flags().StringVar(&automotiveImageBuilder, "automotive-image-builder", "quay.io/centos-sig-automotive/automotive-image-builder:1.0.0", "container image for automotive-image-builder")
+	return validateCmd
+}
+
+func runValidate(_ *cobra.Command, args []string) {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		handleError(fmt.Errorf("error reading manifest: %w", err))
+	}
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	res, err := api.ValidateManifest(ctx, manifestValidationRequest(string(data)))
+	if err != nil {
+		handleError(err)
+	}
+	printManifestIssues(args[0], res)
+	if !res.Valid {
+		os.Exit(1)
+	}
+	fmt.Printf("%s is valid\n", args[0])
+}
+
+// manifestValidationRequest asks to validate manifest for the build settings of the flags
+func manifestValidationRequest(manifest string) buildapitypes.ManifestValidationRequest {
+	return buildapitypes.ManifestValidationRequest{
+		Manifest:               manifest,
+		Distro:                 buildapitypes.Distro(distro),
+		Target:                 buildapitypes.Target(target),
+		Architecture:           buildapitypes.Architecture(architecture),
+		ExportFormat:           buildapitypes.ExportFormat(exportFormat),
+		Mode:                   buildapitypes.Mode(mode),
+		AutomotiveImageBuilder: automotiveImageBuilder,
+	}
+}
+
+// validateBeforeBuild checks the manifest of req before the build is created and exits non-zero
+// when it is invalid. Servers that cannot validate manifests are not asked.
+func validateBeforeBuild(ctx context.Context, api *buildapiclient.Client, req buildapitypes.BuildRequest) {
+	// manifests of a git source are only read by the build
+	if req.Source != nil {
+		return
+	}
+	res, err := api.ValidateManifest(ctx, buildapitypes.ManifestValidationRequest{
+		Manifest:               req.Manifest,
+		Distro:                 req.Distro,
+		Target:                 req.Target,
+		Architecture:           req.Architecture,
+		ExportFormat:           req.ExportFormat,
+		Mode:                   req.Mode,
+		AutomotiveImageBuilder: req.AutomotiveImageBuilder,
+	})
+	if errors.Is(err, buildapiclient.ErrManifestValidationUnsupported) {
+		return
+	}
+	if err != nil {
+		handleError(err)
+	}
+	if !res.Valid {
+		printManifestIssues(manifest, res)
+		fmt.Printf("Build %s was not created: %s is invalid\n", req.Name, manifest)
+		os.Exit(1)
+	}
+}
+
+// printManifestIssues prints the errors and warnings of a validation of file
+func printManifestIssues(file string, res *buildapitypes.ManifestValidationResponse) {
+	for _, issue := range res.Errors {
+		fmt.Fprintln(os.Stderr, formatManifestIssue(file, issue, ""))
+	}
+	for _, issue := range res.Warnings {
+		fmt.Fprintln(os.Stderr, formatManifestIssue(file, issue, "warning: "))
+	}
+}
+
+// formatManifestIssue renders issue as file:line:column: field: message
+func formatManifestIssue(file string, issue buildapitypes.ManifestIssue, prefix string) string {
+	location := file
+	if issue.Line > 0 {
+		location = fmt.Sprintf("%s:%d", file, issue.Line)
+		if issue.Column > 0 {
+			location = fmt.Sprintf("%s:%d", location, issue.Column)
+		}
+	}
+	message := issue.Message
+	if issue.Field != "" {
+		message = issue.Field + ": " + message
+	}
+	return fmt.Sprintf("%s: %s%s", location, prefix, message)
+}
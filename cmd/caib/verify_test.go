@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	buildapitypes "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+	buildapiclient "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi/client"
+)
+
+var _ = DescribeTable("bundleLogged",
+	func(bundle string, logged bool) {
+		path := filepath.Join(GinkgoT().TempDir(), "artifact.bundle")
+		Expect(os.WriteFile(path, []byte(bundle), 0o600)).To(Succeed())
+		Expect(bundleLogged(path)).To(Equal(logged))
+	},
+	Entry("legacy bundle with a Rekor entry", `{"base64Signature":"c2ln","rekorBundle":{"SignedEntryTimestamp":"dA=="}}`, true),
+	Entry("legacy bundle without one", `{"base64Signature":"c2ln","rekorBundle":null}`, false),
+	Entry("protobuf bundle with tlog entries", `{"verificationMaterial":{"tlogEntries":[{"logIndex":"1"}]}}`, true),
+	Entry("protobuf bundle without", `{"verificationMaterial":{"publicKey":{"hint":"k"}}}`, false),
+	Entry("not JSON", `signature`, true),
+)
+
+var _ = Describe("download --verify", func() {
+	const artifact = "disk image"
+	var (
+		ctx            context.Context
+		api            *buildapiclient.Client
+		srvURL, outDir string
+		tmpDir, binDir string
+		digest         string
+		signed         bool
+	)
+
+	// cosign installs a fake cosign CLI running script
+	cosign := func(script string) {
+		Expect(os.WriteFile(filepath.Join(binDir, "cosign"), []byte("#!/bin/sh\n"+script+"\n"), 0o755)).To(Succeed())
+	}
+	// download verifies and saves the artifact of the build the way caib download --verify does
+	download := func() error {
+		verifier, err := newArtifactVerifier(ctx, api, "radio")
+		if err != nil {
+			return err
+		}
+		defer verifier.Close()
+		_, err = saveArtifact(ctx, srvURL, "radio", outDir, verifier, io.Discard, nil)
+		return err
+	}
+
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip("cosign is faked with a shell script")
+		}
+		ctx = context.Background()
+		sum := sha256.Sum256([]byte(artifact))
+		digest = hex.EncodeToString(sum[:])
+		signed = true
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v1/builds/radio/artifact/manifest":
+				files := []buildapitypes.ArtifactFile{{Name: "radio.raw", Kind: "artifact", SHA256: digest}}
+				if signed {
+					files = append(files, buildapitypes.ArtifactFile{Name: "radio.raw.sigstore.json", Kind: "signature"})
+				}
+				_ = json.NewEncoder(w).Encode(buildapitypes.ArtifactManifestResponse{Name: "radio", Files: files})
+			case "/v1/builds/radio/artifact/radio.raw.sigstore.json":
+				_, _ = io.WriteString(w, `{"rekorBundle":null}`)
+			case "/v1/builds/radio/artifact":
+				w.Header().Set("Content-Disposition", `attachment; filename="radio.raw"`)
+				w.Header().Set("X-AIB-Compression", "none")
+				_, _ = io.WriteString(w, artifact)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		DeferCleanup(srv.Close)
+		srvURL = srv.URL
+		var err error
+		api, err = buildapiclient.New(srv.URL)
+		Expect(err).NotTo(HaveOccurred())
+
+		outDir, tmpDir, binDir = GinkgoT().TempDir(), GinkgoT().TempDir(), GinkgoT().TempDir()
+		// the fetched bundles go to TMPDIR, and only the fake cosign can be found
+		GinkgoT().Setenv("TMPDIR", tmpDir)
+		GinkgoT().Setenv("PATH", binDir)
+		setFlag(&verifyOpts.key, "cosign.pub")
+		setFlag(&verifyOpts.bundle, "")
+		cosign("exit 0")
+	})
+
+	// nothingLeft expects no artifact, partial download or fetched bundle to be left behind
+	nothingLeft := func() {
+		Expect(os.ReadDir(outDir)).To(BeEmpty())
+		Expect(os.ReadDir(tmpDir)).To(BeEmpty())
+	}
+
+	It("saves an artifact that passed verification", func() {
+		cosign(`printf '%s\n' "$@" > '` + filepath.Join(binDir, "args") + `'`)
+		Expect(download()).To(Succeed())
+		Expect(os.ReadFile(filepath.Join(outDir, "radio.raw"))).To(BeEquivalentTo(artifact))
+		Expect(os.ReadFile(filepath.Join(binDir, "args"))).To(ContainSubstring("--insecure-ignore-tlog=true"))
+		Expect(os.ReadDir(tmpDir)).To(BeEmpty())
+	})
+
+	It("refuses an artifact whose checksum does not match", func() {
+		digest = hex.EncodeToString(make([]byte, sha256.Size))
+		Expect(download()).To(MatchError(ContainSubstring("checksum mismatch")))
+		nothingLeft()
+	})
+
+	It("refuses to download an artifact without a signature", func() {
+		signed = false
+		Expect(download()).To(MatchError(ContainSubstring("has no cosign signature")))
+		nothingLeft()
+	})
+
+	It("refuses an artifact cosign does not verify", func() {
+		cosign("echo 'Error: invalid signature when validating ASN.1 encoded signature' >&2; exit 1")
+		err := download()
+		Expect(err).To(MatchError(ContainSubstring("verification failed")))
+		Expect(err).To(MatchError(ContainSubstring("invalid signature")))
+		nothingLeft()
+	})
+})
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+	buildapitypes "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+	"github.com/spf13/cobra"
+)
+
+// versionRemote also reports the versions of the build API and the components behind it
+var versionRemote bool
+
+// newVersionCmd returns the "version" command
+func newVersionCmd() *cobra.Command {
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: "Show the version of caib and, with --remote, of the build API and operator",
+		Long: `Show the version of caib. With --remote the versions of the build API server, the
+operator, the ImageBuild API served by the cluster and the default automotive-image-builder image
+are shown as well, and combinations that do not work together are flagged. Include the output
+when reporting a problem.`,
+		Args: cobra.NoArgs,
+		Run:  runVersion,
+	}
+	versionCmd.Flags().BoolVar(&versionRemote, "remote", false, "also show the versions of the build API server and the components behind it")
+	versionCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	versionCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	return versionCmd
+}
+
+func runVersion(_ *cobra.Command, _ []string) {
+	if !versionRemote {
+		fmt.Printf("caib version: %s\n", cliVersion())
+		return
+	}
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	info, err := api.GetInfo(ctx)
+	if err != nil {
+		handleError(fmt.Errorf("error fetching versions from %s: %w", serverURL, err))
+	}
+
+	fmt.Printf("%-16s %s\n", "CLI:", cliVersion())
+	v := info.Versions
+	if v == nil {
+		fmt.Printf("%-16s %s\n", "API server:", "unknown")
+		fmt.Fprintf(os.Stderr, "Warning: the build API at %s does not report versions; it is older than caib %s\n", serverURL, cliVersion())
+		return
+	}
+	fmt.Printf("%-16s %s\n", "API server:", v.BuildAPI)
+	apiVersion, err := api.APIVersion(ctx)
+	if err != nil {
+		handleError(err)
+	}
+	fmt.Printf("%-16s %s (caib uses %s)\n", "REST API:", valueOr(strings.Join(info.APIVersions, ", "), "v1"), apiVersion)
+	fmt.Printf("%-16s %s\n", "Operator:", valueOr(v.Operator, "unknown"))
+	fmt.Printf("%-16s %s (served: %s)\n", "ImageBuild API:", v.APIVersion, valueOr(strings.Join(v.ServedVersions, ", "), "unknown"))
+	fmt.Printf("%-16s %s\n", "Builder image:", v.BuilderImage)
+
+	problems := versionProblems(cliVersion(), v)
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", p)
+	}
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+// cliVersion is the release caib was built from
+func cliVersion() string {
+	return valueOr(version, "dev")
+}
+
+func valueOr(s, fallback string) string {
+	if strings.TrimSpace(s) == "" {
+		return fallback
+	}
+	return s
+}
+
+// versionProblems lists the combinations of versions in v, and of caib itself, that are known not
+// to work together. Development builds have no release to compare and are not flagged.
+func versionProblems(cli string, v *buildapitypes.VersionInfo) []string {
+	var problems []string
+	if cliRelease, ok := minorRelease(cli); ok {
+		if apiRelease, ok := minorRelease(v.BuildAPI); ok && cliRelease != apiRelease {
+			problems = append(problems, fmt.Sprintf("caib %s and build API %s are different releases; install caib %s", cli, v.BuildAPI, v.BuildAPI))
+		}
+	}
+	if v.Operator != "" && v.Operator != v.BuildAPI && v.Operator != "dev" && v.BuildAPI != "dev" {
+		problems = append(problems, fmt.Sprintf("build API %s was not updated to operator %s; check the rollout of the ado-build-api deployment", v.BuildAPI, v.Operator))
+	}
+	if len(v.ServedVersions) > 0 {
+		if own := automotivev1alpha1.GroupVersion.Version; !slices.Contains(v.ServedVersions, own) {
+			problems = append(problems, fmt.Sprintf("the cluster does not serve ImageBuild %s, which caib uses", own))
+		}
+		if _, apiVersion, _ := strings.Cut(v.APIVersion, "/"); apiVersion != "" && !slices.Contains(v.ServedVersions, apiVersion) {
+			problems = append(problems, fmt.Sprintf("the build API uses ImageBuild %s, which the installed CRDs do not serve; update the CRDs", apiVersion))
+		}
+	}
+	return problems
+}
+
+// minorRelease returns the major.minor part of a release such as v1.4.2, which is what has to
+// match between caib and the build API
+func minorRelease(release string) (string, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(release, "v"), ".", 3)
+	if len(parts) < 2 {
+		return "", false
+	}
+	for _, p := range parts[:2] {
+		if _, err := strconv.Atoi(p); err != nil {
+			return "", false
+		}
+	}
+	return parts[0] + "." + parts[1], true
+}
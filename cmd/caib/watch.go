@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	buildapitypes "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/render"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var watchInterval time.Duration
+
+// watchTransitions is how many recent phase transitions are shown below the table
+const watchTransitions = 10
+
+const (
+	ansiClear   = "\033[H\033[2J"
+	ansiReverse = "\033[7m"
+	ansiReset   = "\033[0m"
+)
+
+// newWatchCmd returns the "watch" command, which keeps a live table of builds on screen
+func newWatchCmd() *cobra.Command {
+	watchCmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Show a live-updating table of builds",
+		Long: `Refresh the list of builds every --interval and highlight builds whose phase changed.
+When stdout is not a terminal the phase of every build and then each transition is printed, one
+per line, so the output can be piped or logged.`,
+		Args: cobra.NoArgs,
+		Run:  runWatch,
+	}
+	watchCmd.Flags().StringVar(&serverURL, "server", os.Getenv("CAIB_SERVER"), "REST API server base URL (e.g. https://api.example)")
+	watchCmd.Flags().StringVar(&authToken, "token", os.Getenv("CAIB_TOKEN"), "Bearer token for authentication (e.g., OpenShift access token)")
+	watchCmd.Flags().StringVarP(&listSelector, "selector", "l", "", "label selector to filter builds (e.g. team=adas,branch=main)")
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 5*time.Second, "refresh interval")
+	return watchCmd
+}
+
+// phaseTransition is a phase change seen between two refreshes
+type phaseTransition struct {
+	at       time.Time
+	name     string
+	from, to string
+	message  string
+}
+
+func runWatch(_ *cobra.Command, _ []string) {
+	if watchInterval < time.Second {
+		handleError(fmt.Errorf("--interval must be at least 1s"))
+	}
+	api, err := newAPIClient()
+	if err != nil {
+		handleError(err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	interactive := term.IsTerminal(int(os.Stdout.Fd()))
+	phases := map[string]string{}
+	var transitions []phaseTransition
+	first := true
+	for {
+		items, err := api.ListBuilds(ctx, listSelector)
+		if ctx.Err() != nil {
+			return
+		}
+		now := time.Now()
+		changed := map[string]bool{}
+		if err == nil {
+			for _, it := range items {
+				phase := render.PhaseName(it.Phase)
+				if prev, seen := phases[it.Name]; (seen && prev != phase) || (!seen && !first) {
+					changed[it.Name] = true
+					transitions = append(transitions, phaseTransition{at: now, name: it.Name, from: prev, to: phase, message: it.Message})
+					if !interactive {
+						fmt.Println(formatTransition(transitions[len(transitions)-1]))
+					}
+				}
+				if first && !interactive {
+					fmt.Println(formatTransition(phaseTransition{at: now, name: it.Name, to: phase}))
+				}
+				phases[it.Name] = phase
+			}
+			if len(transitions) > watchTransitions {
+				transitions = transitions[len(transitions)-watchTransitions:]
+			}
+		}
+		first = false
+
+		if interactive {
+			fmt.Print(ansiClear)
+			fmt.Printf("Every %s: caib list", watchInterval)
+			if listSelector != "" {
+				fmt.Printf(" -l %s", listSelector)
+			}
+			fmt.Printf("    %s\n\n", now.Format("15:04:05"))
+			if err != nil {
+				fmt.Printf("Error listing ImageBuilds: %v\n", err)
+			} else {
+				printWatchTable(items, changed, now)
+			}
+			if len(transitions) > 0 {
+				fmt.Println("\nRecent transitions:")
+				for _, t := range transitions {
+					fmt.Println("  " + formatTransition(t))
+				}
+			}
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing ImageBuilds: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchInterval):
+		}
+	}
+}
+
+// printWatchTable prints builds newest first, highlighting the ones in changed
+func printWatchTable(items []buildapitypes.BuildListItem, changed map[string]bool, now time.Time) {
+	if len(items) == 0 {
+		fmt.Println("No ImageBuilds found")
+		return
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].CreatedAt != items[j].CreatedAt {
+			return items[i].CreatedAt > items[j].CreatedAt
+		}
+		return items[i].Name < items[j].Name
+	})
+	fmt.Printf("%-30s %-12s %-10s %s\n", "NAME", "STATUS", "DURATION", "MESSAGE")
+	for _, it := range items {
+		row := fmt.Sprintf("%-30s %s %-10s %s", it.Name, renderer.PaddedPhase(it.Phase, 12), render.Elapsed(it.StartTime, it.CompletionTime, now), it.Message)
+		if changed[it.Name] {
+			row = ansiReverse + row + ansiReset
+		}
+		fmt.Println(row)
+	}
+}
+
+func formatTransition(t phaseTransition) string {
+	line := fmt.Sprintf("%s %s: %s", t.at.Format("15:04:05"), t.name, renderer.Phase(t.to))
+	if t.from != "" {
+		line = fmt.Sprintf("%s %s: %s -> %s", t.at.Format("15:04:05"), t.name, renderer.Phase(t.from), renderer.Phase(t.to))
+	}
+	if t.message != "" && (t.to == "Failed" || t.to == "Completed" || t.to == "Cancelled") {
+		line += " (" + t.message + ")"
+	}
+	return line
+}
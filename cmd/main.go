@@ -19,17 +19,21 @@ package main
 import (
 	"crypto/tls"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
 
 	routev1 "github.com/openshift/api/route/v1"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -38,9 +42,12 @@ import (
 	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 
 	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/version"
 	"github.com/centos-automotive-suite/automotive-dev-operator/internal/controller/image"
 	"github.com/centos-automotive-suite/automotive-dev-operator/internal/controller/imagebuild"
 	"github.com/centos-automotive-suite/automotive-dev-operator/internal/controller/operatorconfig"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/controller/sharding"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/plugin"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -66,6 +73,7 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var shard sharding.Shard
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -77,6 +85,23 @@ func main() {
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.IntVar(&shard.Count, "shards", 1,
+		"Number of shards the ImageBuilds are split into, each reconciled by the replicas of its --shard-id. "+
+			"Other controllers run on shard 0 only.")
+	flag.IntVar(&shard.ID, "shard-id", -1,
+		"Shard of this manager when --shards is above 1; by default the ordinal of the pod name, as in a StatefulSet.")
+	flag.Func("plugin", "A sidecar plugin as name=target, e.g. ota=unix:///var/run/plugins/ota.sock. "+
+		"Can be repeated.", func(value string) error {
+		name, target, ok := strings.Cut(value, "=")
+		if !ok || name == "" || target == "" {
+			return fmt.Errorf("expected name=target, got %q", value)
+		}
+		c, err := plugin.NewClient(name, target)
+		if err != nil {
+			return err
+		}
+		return plugin.DefaultRegistry.Register(c)
+	})
 	opts := zap.Options{
 		Development: true,
 	}
@@ -85,6 +110,25 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if shard.Count <= 1 {
+		shard = sharding.Shard{Count: 1}
+	} else if shard.ID < 0 {
+		podName := os.Getenv("POD_NAME")
+		if podName == "" {
+			podName, _ = os.Hostname()
+		}
+		id, err := sharding.IDFromPodName(podName)
+		if err != nil {
+			setupLog.Error(err, "unable to determine the shard")
+			os.Exit(1)
+		}
+		shard.ID = id
+	}
+	if err := shard.Validate(); err != nil {
+		setupLog.Error(err, "invalid sharding")
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -133,17 +177,28 @@ func main() {
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "930f6355.sdv.cloud.redhat.com",
+		LeaderElectionID:       shard.LeaderElectionID("930f6355.sdv.cloud.redhat.com"),
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create clientset")
+		os.Exit(1)
+	}
+
 	imageBuildReconciler := &imagebuild.ImageBuildReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 		Log:    ctrl.Log.WithName("controllers").WithName("ImageBuild"),
+		// Plugins compiled into the operator register themselves in plugin.DefaultRegistry from init functions
+		Plugins:   plugin.DefaultRegistry,
+		Recorder:  mgr.GetEventRecorderFor("imagebuild-controller"),
+		Shard:     shard,
+		Clientset: clientset,
 	}
 
 	if err = imageBuildReconciler.SetupWithManager(mgr); err != nil {
@@ -151,26 +206,34 @@ func main() {
 		os.Exit(1)
 	}
 
-	imageReconciler := &image.ImageReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Image"),
+	if shard.Sharded() {
+		metrics.Registry.MustRegister(&sharding.Collector{Shard: shard, Reader: mgr.GetCache()})
+		setupLog.Info("reconciling one shard of the ImageBuilds", "shard", shard.ID, "shards", shard.Count)
 	}
 
-	if err = imageReconciler.SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Image")
-		os.Exit(1)
-	}
-
-	operatorConfigReconciler := &operatorconfig.OperatorConfigReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Log:    ctrl.Log.WithName("controllers").WithName("OperatorConfig"),
-	}
-
-	if err = operatorConfigReconciler.SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "OperatorConfig")
-		os.Exit(1)
+	// the other controllers are not sharded and run on shard 0 only
+	if shard.ID == 0 {
+		imageReconciler := &image.ImageReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			Log:    ctrl.Log.WithName("controllers").WithName("Image"),
+		}
+
+		if err = imageReconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Image")
+			os.Exit(1)
+		}
+
+		operatorConfigReconciler := &operatorconfig.OperatorConfigReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			Log:    ctrl.Log.WithName("controllers").WithName("OperatorConfig"),
+		}
+
+		if err = operatorConfigReconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "OperatorConfig")
+			os.Exit(1)
+		}
 	}
 
 	// Health checks
@@ -183,7 +246,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	setupLog.Info("starting manager for controller")
+	setupLog.Info("starting manager for controller", "version", version.Version)
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
@@ -0,0 +1,91 @@
+package buildapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+const (
+	// rehydrateRequestedAnnotation tells the operator when a download last asked for the artifacts
+	// of an archived build, so it serves them from the archive PVC for a while
+	rehydrateRequestedAnnotation = "automotive.sdv.cloud.redhat.com/rehydrate-requested"
+	// rehydrateRefresh is how old the annotation may get before a download sets it again
+	rehydrateRefresh = time.Minute
+	// archivingRetryAfter is the Retry-After of downloads while the artifacts are being archived
+	archivingRetryAfter = 60 * time.Second
+)
+
+// artifactArchive reports the archive of the artifacts of build, nil when they were not archived
+func artifactArchive(build *automotivev1alpha1.ImageBuild) *ArtifactArchive {
+	st := build.Status.ArtifactArchive
+	if st == nil {
+		return nil
+	}
+	out := &ArtifactArchive{
+		Phase:        st.Phase,
+		StorageClass: st.StorageClass,
+		Bytes:        st.Bytes,
+		Message:      st.Message,
+	}
+	if st.CompletionTime != nil {
+		out.CompletionTime = st.CompletionTime.UTC().Format(time.RFC3339)
+	}
+	if st.RehydrationTime != nil {
+		out.RehydrationTime = st.RehydrationTime.UTC().Format(time.RFC3339)
+	}
+	return out
+}
+
+// artifactsArchiving answers 503 with a Retry-After for a build whose artifacts are being copied to
+// the archive storage class. For archived builds it asks the operator to serve the artifacts from
+// the archive PVC again, so the download waiting for the artifact pod gets it.
+func artifactsArchiving(c *gin.Context, build *automotivev1alpha1.ImageBuild) bool {
+	st := build.Status.ArtifactArchive
+	if st == nil {
+		return false
+	}
+	switch st.Phase {
+	case "Archiving":
+		c.Header("Retry-After", strconv.Itoa(int(archivingRetryAfter/time.Second)))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": fmt.Sprintf("the artifacts of build %s are being archived, retry shortly", build.Name)})
+		return true
+	case "Archived":
+		if err := requestRehydration(c.Request.Context(), build); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error rehydrating archived artifacts: %v", err)})
+			return true
+		}
+	}
+	return false
+}
+
+// requestRehydration sets the rehydrate-requested annotation of an archived build to now, unless a
+// download set it recently. It is written with the build API's service account, since readers of
+// the artifacts often may not update builds.
+func requestRehydration(ctx context.Context, build *automotivev1alpha1.ImageBuild) error {
+	if build.Status.ArtifactFileName == "" {
+		return nil
+	}
+	if v, ok := build.Annotations[rehydrateRequestedAnnotation]; ok {
+		if at, err := time.Parse(time.RFC3339, v); err == nil && time.Since(at) < rehydrateRefresh {
+			return nil
+		}
+	}
+	svc, err := serviceClient()
+	if err != nil {
+		return err
+	}
+	patch := client.MergeFrom(build.DeepCopy())
+	if build.Annotations == nil {
+		build.Annotations = map[string]string{}
+	}
+	build.Annotations[rehydrateRequestedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	return svc.Patch(ctx, build, patch)
+}
@@ -0,0 +1,317 @@
+package buildapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+const (
+	// auditSinkStdout writes to the log of the build API, which is JSON on stdout
+	auditSinkStdout = "stdout"
+	auditSinkFile   = "file"
+	auditSinkEvents = "events"
+
+	// defaultAuditFile is where the file sink writes when BUILD_API_AUDIT_FILE is not set
+	defaultAuditFile = "/var/log/build-api/audit.log"
+
+	// auditBuildKey is the context key of the build a request acts on, for requests that name it
+	// in their body rather than their path
+	auditBuildKey = "auditBuild"
+
+	// maxAuditErrorBytes bounds how much of an error response is kept to read its message
+	maxAuditErrorBytes = 4096
+)
+
+// auditExemptRoutes are POST routes that change nothing and so are not audited
+var auditExemptRoutes = map[string]bool{
+	"/v1/policies/evaluate":  true,
+	"/v1/manifests/validate": true,
+}
+
+// AuditEvent records a mutating call of the build API
+type AuditEvent struct {
+	Time      string `json:"time"`
+	RequestID string `json:"requestID"`
+	// User is the user the token of the request belongs to
+	User string `json:"user"`
+	// OnBehalfOf is the user an authorized caller impersonated, see Impersonate-User
+	OnBehalfOf string `json:"onBehalfOf,omitempty"`
+	SourceIP   string `json:"sourceIP"`
+	Method     string `json:"method"`
+	// Route is the matched route, e.g. /v1/builds/:name/cancel
+	Route     string `json:"route"`
+	Path      string `json:"path"`
+	Build     string `json:"build,omitempty"`
+	Namespace string `json:"namespace"`
+	Status    int    `json:"status"`
+	// Outcome is success, denied for 401 and 403 responses, or failure
+	Outcome    string `json:"outcome"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// auditSink writes audit events somewhere
+type auditSink interface {
+	write(e AuditEvent) error
+}
+
+// auditLog sends audit events to every configured sink
+type auditLog struct {
+	sinks []auditSink
+	log   logr.Logger
+}
+
+// auditLogFromEnv sets up the sinks the operator passes from the OperatorConfig in
+// BUILD_API_AUDIT_SINKS, a comma separated list of stdout, file and events. Without sinks nothing
+// is audited.
+func auditLogFromEnv(logger logr.Logger) *auditLog {
+	a := &auditLog{log: logger}
+	for _, name := range strings.Split(os.Getenv("BUILD_API_AUDIT_SINKS"), ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case auditSinkStdout:
+			a.sinks = append(a.sinks, logAuditSink{log: logger})
+		case auditSinkFile:
+			path := strings.TrimSpace(os.Getenv("BUILD_API_AUDIT_FILE"))
+			if path == "" {
+				path = defaultAuditFile
+			}
+			sink, err := newFileAuditSink(path)
+			if err != nil {
+				logger.Error(err, "audit file sink disabled", "path", path)
+				continue
+			}
+			a.sinks = append(a.sinks, sink)
+		case auditSinkEvents:
+			sink, err := newEventAuditSink()
+			if err != nil {
+				logger.Error(err, "audit events sink disabled")
+				continue
+			}
+			a.sinks = append(a.sinks, sink)
+		default:
+			logger.Info("ignoring unknown audit sink", "sink", name)
+		}
+	}
+	return a
+}
+
+// audited reports whether a request is recorded: every call that may change something
+func audited(method, route string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return (strings.HasPrefix(route, "/v1/") || strings.HasPrefix(route, "/v2/")) && !auditExemptRoutes[route]
+}
+
+// auditOutcome classifies the status of a response
+func auditOutcome(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return "denied"
+	case status >= 400:
+		return "failure"
+	}
+	return "success"
+}
+
+// middleware records the outcome of every audited request once it was handled
+func (l *auditLog) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(l.sinks) == 0 || !audited(c.Request.Method, c.FullPath()) {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		w := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		e := AuditEvent{
+			Time:       start.UTC().Format(time.RFC3339Nano),
+			RequestID:  c.GetString("reqID"),
+			User:       c.GetString(subjectKey),
+			SourceIP:   c.ClientIP(),
+			Method:     c.Request.Method,
+			Route:      c.FullPath(),
+			Path:       c.Request.URL.Path,
+			Build:      c.GetString(auditBuildKey),
+			Namespace:  requestNamespace(c),
+			Status:     w.Status(),
+			Outcome:    auditOutcome(w.Status()),
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if e.User == "" {
+			e.User = "anonymous"
+		}
+		if imp, ok := requestImpersonation(c); ok {
+			e.OnBehalfOf = imp.UserName
+		}
+		if e.Build == "" {
+			e.Build = c.Param("name")
+		}
+		if e.Outcome != "success" {
+			e.Error = responseError(w.body.Bytes())
+		}
+		l.record(e)
+	}
+}
+
+// record writes e to every sink; a failing sink is logged and does not fail the request
+func (l *auditLog) record(e AuditEvent) {
+	for _, sink := range l.sinks {
+		if err := sink.write(e); err != nil {
+			l.log.Error(err, "failed to write audit event", "sink", fmt.Sprintf("%T", sink), "reqID", e.RequestID)
+		}
+	}
+}
+
+// responseError returns the message of a JSON error response or the detail of a problem details
+// response, or the start of any other body
+func responseError(body []byte) string {
+	var resp struct {
+		Error  string `json:"error"`
+		Detail string `json:"detail"`
+	}
+	if json.Unmarshal(body, &resp) == nil && resp.Error != "" {
+		return resp.Error
+	}
+	if resp.Detail != "" {
+		return resp.Detail
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// auditResponseWriter keeps the start of error responses, whose message goes into the audit event
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	w.keep(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	w.keep([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *auditResponseWriter) keep(b []byte) {
+	if w.Status() < 400 {
+		return
+	}
+	if n := maxAuditErrorBytes - w.body.Len(); n > 0 {
+		w.body.Write(b[:min(n, len(b))])
+	}
+}
+
+// logAuditSink writes events to the JSON log of the build API on stdout, as "audit" messages
+type logAuditSink struct {
+	log logr.Logger
+}
+
+func (s logAuditSink) write(e AuditEvent) error {
+	s.log.Info("audit", "event", e)
+	return nil
+}
+
+// jsonLineSink writes each event as a line of JSON
+type jsonLineSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *jsonLineSink) write(e AuditEvent) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// newFileAuditSink appends events to the file at path as JSON lines
+func newFileAuditSink(path string) (*jsonLineSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonLineSink{w: f}, nil
+}
+
+// eventAuditSink records events on the ImageBuild they concern, so kubectl describe shows who
+// changed a build. Kubernetes aggregates and rate limits Events, so the stdout or file sink is the
+// complete record.
+type eventAuditSink struct {
+	recorder record.EventRecorder
+}
+
+func newEventAuditSink() (*eventAuditSink, error) {
+	cs, err := serviceClientset()
+	if err != nil {
+		return nil, err
+	}
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := automotivev1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: cs.CoreV1().Events("")})
+	return &eventAuditSink{recorder: broadcaster.NewRecorder(scheme, corev1.EventSource{Component: "build-api"})}, nil
+}
+
+func (s *eventAuditSink) write(e AuditEvent) error {
+	// events need an object; requests that name no build are only in the other sinks
+	if e.Build == "" {
+		return nil
+	}
+	build := &automotivev1alpha1.ImageBuild{ObjectMeta: metav1.ObjectMeta{Name: e.Build, Namespace: e.Namespace}}
+	eventType := corev1.EventTypeNormal
+	if e.Outcome != "success" {
+		eventType = corev1.EventTypeWarning
+	}
+	annotations := map[string]string{
+		"automotive.sdv.cloud.redhat.com/audit-user":       e.User,
+		"automotive.sdv.cloud.redhat.com/audit-source-ip":  e.SourceIP,
+		"automotive.sdv.cloud.redhat.com/audit-request-id": e.RequestID,
+	}
+	msg := fmt.Sprintf("%s %s by %s from %s: %d", e.Method, e.Path, e.User, e.SourceIP, e.Status)
+	if e.OnBehalfOf != "" {
+		msg = fmt.Sprintf("%s %s by %s on behalf of %s from %s: %d", e.Method, e.Path, e.User, e.OnBehalfOf, e.SourceIP, e.Status)
+	}
+	if e.Error != "" {
+		msg += ": " + e.Error
+	}
+	s.recorder.AnnotatedEventf(build, annotations, eventType, "APIAudit", "%s", msg)
+	return nil
+}
@@ -0,0 +1,268 @@
+package buildapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authnv1 "k8s.io/api/authentication/v1"
+	authzv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+const (
+	// userInfoKey is the context key of the authnv1.UserInfo of an authenticated request's token
+	userInfoKey = "userInfo"
+	// oidcUserKey is set for requests authenticated with a token of the OIDC issuer
+	oidcUserKey = "oidcUser"
+
+	// accessReviewTTL is how long an allowed SubjectAccessReview is reused. Denials are not cached,
+	// so a permission granted to a user takes effect with the next request.
+	accessReviewTTL = 10 * time.Second
+	// maxAccessReviews bounds the cache; it is emptied when full
+	maxAccessReviews = 4096
+)
+
+// routePermission is the permission on ImageBuilds a route requires
+type routePermission struct {
+	verb        string
+	subresource string
+	// named checks the permission on the build of the :name path parameter rather than all builds
+	named bool
+}
+
+var (
+	permGetBuild     = routePermission{verb: "get", named: true}
+	permUpdateBuild  = routePermission{verb: "update", named: true}
+	permGetArtifact  = routePermission{verb: "get", subresource: "artifact", named: true}
+	permCreateBuilds = routePermission{verb: "create"}
+	permListBuilds   = routePermission{verb: "list"}
+	permGetAnyBuild  = routePermission{verb: "get"}
+	permDeleteBuild  = routePermission{verb: "delete", named: true}
+	permGetLogs      = routePermission{verb: "get", subresource: "log", named: true}
+	permExec         = routePermission{verb: "create", subresource: "exec", named: true}
+)
+
+// routePermissions maps "METHOD route" of every authenticated route to the permission it requires.
+// Routes missing here are rejected, so a new route cannot be served without a decision on who may
+// call it.
+var routePermissions = map[string]routePermission{
+	"POST /v1/builds":                              permCreateBuilds,
+	"GET /v1/builds":                               permListBuilds,
+	"GET /v1/builds/:name":                         permGetBuild,
+	"DELETE /v1/builds/:name":                      permDeleteBuild,
+	"PATCH /v1/builds/:name":                       permUpdateBuild,
+	"GET /v1/builds/:name/logs":                    permGetLogs,
+	"GET /v1/builds/:name/logs/search":             permGetLogs,
+	"GET /v1/builds/:name/logs/stream":             permGetLogs,
+	"GET /v1/builds/:name/logs/archive":            permGetLogs,
+	"GET /v1/builds/:name/logs/sse":                permGetLogs,
+	"GET /v1/builds/:name/artifact":                permGetArtifact,
+	"HEAD /v1/builds/:name/artifact":               permGetArtifact,
+	"GET /v1/builds/:name/artifacts":               permGetBuild,
+	"GET /v1/builds/:name/artifacts/:file":         permGetArtifact,
+	"HEAD /v1/builds/:name/artifacts/:file":        permGetArtifact,
+	"GET /v1/builds/:name/artifact/manifest":       permGetBuild,
+	"POST /v1/builds/:name/artifact/url":           permGetArtifact,
+	"GET /v1/builds/:name/artifact/signature":      permGetArtifact,
+	"HEAD /v1/builds/:name/artifact/signature":     permGetArtifact,
+	"GET /v1/builds/:name/artifact/:filename":      permGetArtifact,
+	"HEAD /v1/builds/:name/artifact/:filename":     permGetArtifact,
+	"GET /v1/builds/:name/template":                permGetBuild,
+	"GET /v1/builds/:name/manifest":                permGetBuild,
+	"GET /v1/builds/:name/compliance":              permGetBuild,
+	"GET /v1/builds/:name/comparison":              permGetBuild,
+	"GET /v1/builds/:name/sbom":                    permGetArtifact,
+	"GET /v1/builds/:name/events":                  permGetBuild,
+	"GET /v1/builds/:name/watch":                   permGetBuild,
+	"POST /v1/builds/:name/clone":                  permCreateBuilds,
+	"POST /v1/builds/:name/cancel":                 permUpdateBuild,
+	"GET /v1/builds/:name/exec":                    permExec,
+	"POST /v1/builds/:name/exec":                   permExec,
+	"GET /v1/builds/:name/workspace":               permGetArtifact,
+	"POST /v1/builds/:name/uploads":                permUpdateBuild,
+	"POST /v1/builds/:name/uploads/sessions":       permUpdateBuild,
+	"GET /v1/builds/:name/uploads/sessions/:id":    permUpdateBuild,
+	"PATCH /v1/builds/:name/uploads/sessions/:id":  permUpdateBuild,
+	"DELETE /v1/builds/:name/uploads/sessions/:id": permUpdateBuild,
+	"POST /v1/builds/:name/uploads/complete":       permUpdateBuild,
+	"GET /v1/catalog/defines":                      permGetAnyBuild,
+	"GET /v1/catalog/hardening":                    permGetAnyBuild,
+	"GET /v1/capabilities":                         permGetAnyBuild,
+	"GET /v1/stats":                                permListBuilds,
+	"GET /v1/build-records":                        permListBuilds,
+	"GET /v1/stats/failures":                       permListBuilds,
+	"POST /v1/policies/evaluate":                   permCreateBuilds,
+	"POST /v1/manifests/validate":                  permCreateBuilds,
+	"GET /v1/pipelines/:name/graph":                permGetAnyBuild,
+	"POST /v2/builds":                              permCreateBuilds,
+	"GET /v2/builds":                               permListBuilds,
+	"GET /v2/builds/:name":                         permGetBuild,
+	"DELETE /v2/builds/:name":                      permDeleteBuild,
+	"POST /v2/builds/:name/cancel":                 permUpdateBuild,
+}
+
+// resourceAttributes returns what p requires of a request for the build name in namespace
+func (p routePermission) resourceAttributes(namespace, name string) authzv1.ResourceAttributes {
+	attrs := authzv1.ResourceAttributes{
+		Namespace:   namespace,
+		Verb:        p.verb,
+		Group:       automotivev1alpha1.GroupVersion.Group,
+		Resource:    "imagebuilds",
+		Subresource: p.subresource,
+	}
+	if p.named {
+		attrs.Name = name
+	}
+	return attrs
+}
+
+// missingPermission describes attrs for a 403 response
+func missingPermission(user string, attrs authzv1.ResourceAttributes) (string, Permission) {
+	resource := attrs.Resource
+	if attrs.Subresource != "" {
+		resource += "/" + attrs.Subresource
+	}
+	target := resource
+	if attrs.Name != "" {
+		target = fmt.Sprintf("%s %q", resource, attrs.Name)
+	}
+	msg := fmt.Sprintf("%s may not %s %s in namespace %s", user, attrs.Verb, target, attrs.Namespace)
+	return msg, Permission{
+		Verb:        attrs.Verb,
+		Group:       attrs.Group,
+		Resource:    attrs.Resource,
+		Subresource: attrs.Subresource,
+		Name:        attrs.Name,
+		Namespace:   attrs.Namespace,
+	}
+}
+
+// accessReviewCache remembers allowed SubjectAccessReviews for accessReviewTTL
+type accessReviewCache struct {
+	mu      sync.Mutex
+	allowed map[string]time.Time
+}
+
+func accessReviewKey(user authnv1.UserInfo, attrs authzv1.ResourceAttributes) string {
+	return strings.Join([]string{user.Username, user.UID, strings.Join(user.Groups, ","),
+		attrs.Namespace, attrs.Verb, attrs.Resource, attrs.Subresource, attrs.Name}, "\x00")
+}
+
+func (c *accessReviewCache) get(key string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiry, ok := c.allowed[key]
+	return ok && now.Before(expiry)
+}
+
+func (c *accessReviewCache) put(key string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.allowed == nil || len(c.allowed) >= maxAccessReviews {
+		c.allowed = map[string]time.Time{}
+	}
+	c.allowed[key] = now.Add(accessReviewTTL)
+}
+
+// authorizeRoute checks with a SubjectAccessReview that the caller, or the user it impersonates,
+// has the permission routePermissions lists for the route. The build API reads and writes
+// ImageBuilds with its own service account, so this is what keeps users to the builds Kubernetes
+// RBAC grants them. Partner namespaces are also denied partnerDeniedRoutes. It answers the request
+// and returns false when the permission is missing.
+func (a *APIServer) authorizeRoute(c *gin.Context) bool {
+	route := c.Request.Method + " " + c.FullPath()
+	perm, ok := routePermissions[route]
+	if !ok {
+		a.log.Info("no permission defined for route, rejecting", "route", route, "reqID", c.GetString("reqID"))
+		c.JSON(http.StatusForbidden, gin.H{"error": "no permission is defined for " + route})
+		return false
+	}
+	user, ok := requestUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return false
+	}
+	attrs := perm.resourceAttributes(requestNamespace(c), c.Param("name"))
+	allowed, err := a.accessAllowed(c.Request.Context(), user, attrs)
+	if err != nil {
+		a.log.Error(err, "SubjectAccessReview failed", "user", user.Username, "reqID", c.GetString("reqID"))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check authorization"})
+		return false
+	}
+	if !allowed {
+		msg, missing := missingPermission(user.Username, attrs)
+		c.JSON(http.StatusForbidden, ForbiddenResponse{Error: msg, MissingPermission: &missing})
+		return false
+	}
+	if slices.Contains(partnerDeniedRoutes, route) {
+		partner, err := loadPartnerIsolation(c.Request.Context(), attrs.Namespace)
+		if err != nil {
+			a.log.Error(err, "failed to read partner isolation profile", "reqID", c.GetString("reqID"))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check authorization"})
+			return false
+		}
+		if partner != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("builds in partner namespace %s cannot be accessed with %s", attrs.Namespace, route)})
+			return false
+		}
+	}
+	return true
+}
+
+// requestUser returns the user a request acts as: the impersonated user, or the user of its token
+func requestUser(c *gin.Context) (authnv1.UserInfo, bool) {
+	if imp, ok := requestImpersonation(c); ok {
+		return authnv1.UserInfo{Username: imp.UserName, Groups: imp.Groups}, true
+	}
+	v, ok := c.Get(userInfoKey)
+	if !ok {
+		return authnv1.UserInfo{}, false
+	}
+	user, ok := v.(authnv1.UserInfo)
+	return user, ok
+}
+
+// accessAllowed asks the Kubernetes API whether user may do attrs, reusing recent answers
+func (a *APIServer) accessAllowed(ctx context.Context, user authnv1.UserInfo, attrs authzv1.ResourceAttributes) (bool, error) {
+	key := accessReviewKey(user, attrs)
+	now := time.Now()
+	if a.accessReviews.get(key, now) {
+		return true, nil
+	}
+	cfg, err := serviceRESTConfig()
+	if err != nil {
+		return false, err
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return false, err
+	}
+	extra := make(map[string]authzv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authzv1.ExtraValue(v)
+	}
+	sar := &authzv1.SubjectAccessReview{Spec: authzv1.SubjectAccessReviewSpec{
+		User:               user.Username,
+		Groups:             user.Groups,
+		UID:                user.UID,
+		Extra:              extra,
+		ResourceAttributes: &attrs,
+	}}
+	res, err := clientset.AuthorizationV1().SubjectAccessReviews().Create(ctx, sar, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	if res.Status.Allowed {
+		a.accessReviews.put(key, now)
+	}
+	return res.Status.Allowed, nil
+}
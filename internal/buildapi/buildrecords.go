@@ -0,0 +1,145 @@
+package buildapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/buildrecord"
+)
+
+// buildRecordsPrefix names the head ConfigMap of the build record history of a namespace and,
+// suffixed with -<n>, its segments, as the controller writes them
+const (
+	buildRecordsPrefix = "build-records"
+	buildRecordHeadKey = "head.json"
+)
+
+func (a *APIServer) handleListBuildRecords(c *gin.Context) {
+	a.log.Info("list build records", "reqID", c.GetString("reqID"))
+	listBuildRecords(c)
+}
+
+// listBuildRecords returns the build record history of the request namespace and whether it is
+// unbroken. The ConfigMaps are read with the service account, as callers that may list builds
+// need not read ConfigMaps.
+func listBuildRecords(c *gin.Context) {
+	namespace := requestNamespace(c)
+	ctx := c.Request.Context()
+	svc, err := serviceClient()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	cms := &corev1.ConfigMapList{}
+	if err := svc.List(ctx, cms, client.InNamespace(namespace),
+		client.MatchingLabels{"app.kubernetes.io/managed-by": "automotive-dev-operator"}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error listing build records: %v", err)})
+		return
+	}
+	var segments []corev1.ConfigMap
+	for _, cm := range cms.Items {
+		if isBuildRecordSegment(cm.Name) {
+			segments = append(segments, cm)
+		}
+	}
+	head := &corev1.ConfigMap{}
+	if err := svc.Get(ctx, types.NamespacedName{Name: buildRecordsPrefix, Namespace: namespace}, head); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build record head: %v", err)})
+			return
+		}
+		head = nil
+	}
+	c.JSON(http.StatusOK, buildRecordsFromConfigMaps(namespace, head, segments))
+}
+
+// isBuildRecordSegment reports whether name is a segment of a build record history
+func isBuildRecordSegment(name string) bool {
+	n, ok := strings.CutPrefix(name, buildRecordsPrefix+"-")
+	if !ok {
+		return false
+	}
+	_, err := strconv.ParseUint(n, 10, 32)
+	return err == nil
+}
+
+// buildRecordsFromConfigMaps reads the records and Rekor entries of the segments of a history and
+// verifies the chain up to its head. The head may be one record ahead of the segments while the
+// controller stores it; any other difference breaks the history.
+func buildRecordsFromConfigMaps(namespace string, head *corev1.ConfigMap, segments []corev1.ConfigMap) BuildRecordsResponse {
+	resp := BuildRecordsResponse{Namespace: namespace, Records: []BuildRecord{}}
+	bySequence := map[int64]*BuildRecord{}
+	var problems []string
+	anchors := map[int64]*buildrecord.Anchor{}
+	for _, segment := range segments {
+		for key, data := range segment.Data {
+			seq, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSuffix(key, ".json"), ".rekor"), 10, 64)
+			if err != nil {
+				continue
+			}
+			if strings.HasSuffix(key, ".rekor.json") {
+				anchor := &buildrecord.Anchor{}
+				if err := json.Unmarshal([]byte(data), anchor); err != nil {
+					problems = append(problems, fmt.Sprintf("%s: %s: %v", segment.Name, key, err))
+					continue
+				}
+				anchors[seq] = anchor
+				continue
+			}
+			record := &BuildRecord{}
+			if err := json.Unmarshal([]byte(data), &record.Record); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s: %v", segment.Name, key, err))
+				continue
+			}
+			if record.Sequence != seq || segment.Name != buildrecord.SegmentName(buildRecordsPrefix, seq) {
+				problems = append(problems, fmt.Sprintf("%s: %s holds record %d", segment.Name, key, record.Sequence))
+				continue
+			}
+			bySequence[seq] = record
+		}
+	}
+
+	if head != nil && head.Data[buildRecordHeadKey] != "" {
+		var last buildrecord.Record
+		if err := json.Unmarshal([]byte(head.Data[buildRecordHeadKey]), &last); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s: %v", head.Name, buildRecordHeadKey, err))
+		} else if stored, ok := bySequence[last.Sequence]; ok && stored.Digest != last.Digest {
+			problems = append(problems, fmt.Sprintf("record %d differs from the head of the history", last.Sequence))
+		} else if !ok {
+			bySequence[last.Sequence] = &BuildRecord{Record: last}
+		}
+		for seq := range bySequence {
+			if seq > last.Sequence {
+				problems = append(problems, fmt.Sprintf("record %d is past the head of the history, record %d", seq, last.Sequence))
+				break
+			}
+		}
+	} else if len(bySequence) > 0 {
+		problems = append(problems, fmt.Sprintf("the head of the history, ConfigMap %s, is missing", buildRecordsPrefix))
+	}
+
+	records := make([]buildrecord.Record, 0, len(bySequence))
+	for seq, record := range bySequence {
+		record.Rekor = anchors[seq]
+		resp.Records = append(resp.Records, *record)
+		records = append(records, record.Record)
+	}
+	sort.Slice(resp.Records, func(i, j int) bool { return resp.Records[i].Sequence < resp.Records[j].Sequence })
+	sort.Strings(problems)
+	if err := buildrecord.Verify(records); err != nil {
+		problems = append([]string{err.Error()}, problems...)
+	}
+	resp.Verified = len(problems) == 0
+	resp.Problems = problems
+	return resp
+}
@@ -1,82 +1,1138 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	authnv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
 
 	"github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/pipelinegraph"
 )
 
 type Client struct {
-	baseURL    *url.URL
-	httpClient *http.Client
-	authToken  string
+	baseURL     *url.URL
+	httpClient  *http.Client
+	authToken   string
+	impersonate rest.ImpersonationConfig
+	namespace   string
+
+	// apiVersion is the REST API version negotiated by APIVersion
+	versionMu  sync.Mutex
+	apiVersion string
+}
+
+func New(base string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("base URL must include scheme and host (e.g., https://api.example.com)")
+	}
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{}, // No global timeout to avoid aborting large uploads
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	if c.impersonate.UserName != "" {
+		hc := *c.httpClient
+		hc.Transport = &impersonatingTransport{base: hc.Transport, impersonate: c.impersonate}
+		c.httpClient = &hc
+	}
+	if c.namespace != "" {
+		hc := *c.httpClient
+		hc.Transport = &namespaceTransport{base: hc.Transport, namespace: c.namespace}
+		c.httpClient = &hc
+	}
+	hc := *c.httpClient
+	hc.Transport = &requestIDTransport{base: hc.Transport}
+	c.httpClient = &hc
+	return c, nil
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(h *http.Client) Option { return func(c *Client) { c.httpClient = h } }
+func WithAuthToken(t string) Option        { return func(c *Client) { c.authToken = t } }
+
+// WithImpersonation makes every request act as user and groups, like kubectl --as and --as-group.
+// The server only accepts it from callers allowed to impersonate them in Kubernetes.
+func WithImpersonation(user string, groups []string) Option {
+	return func(c *Client) { c.impersonate = rest.ImpersonationConfig{UserName: user, Groups: groups} }
+}
+
+// WithNamespace makes every request work with the builds of namespace instead of those in the
+// namespace of the build API. The server only accepts namespaces it is configured to serve.
+func WithNamespace(namespace string) Option {
+	return func(c *Client) { c.namespace = namespace }
+}
+
+// namespaceTransport adds the namespace query parameter to every request that does not set it.
+// Requests listing builds of all namespaces are left alone, since there the parameter filters
+// the namespaces listed.
+type namespaceTransport struct {
+	base      http.RoundTripper
+	namespace string
+}
+
+func (t *namespaceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	q := req.URL.Query()
+	if !q.Has("namespace") && q.Get("allNamespaces") != "true" {
+		req = req.Clone(req.Context())
+		q.Set("namespace", t.namespace)
+		req.URL.RawQuery = q.Encode()
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// impersonatingTransport sets the Kubernetes impersonation headers on every request
+type impersonatingTransport struct {
+	base        http.RoundTripper
+	impersonate rest.ImpersonationConfig
+}
+
+func (t *impersonatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(authnv1.ImpersonateUserHeader, t.impersonate.UserName)
+	req.Header.Del(authnv1.ImpersonateGroupHeader)
+	for _, g := range t.impersonate.Groups {
+		req.Header.Add(authnv1.ImpersonateGroupHeader, g)
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+func (c *Client) CreateBuild(ctx context.Context, req buildapi.BuildRequest) (*buildapi.BuildResponse, error) {
+	return c.CreateBuildWithKey(ctx, req, "")
+}
+
+// CreateBuildWithKey creates a build with an Idempotency-Key, so retrying the request returns the
+// build the first attempt created; the response has Replayed set then
+func (c *Client) CreateBuildWithKey(ctx context.Context, req buildapi.BuildRequest, idempotencyKey string) (*buildapi.BuildResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := c.resolve("/v1/builds")
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if idempotencyKey != "" {
+		httpReq.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	if c.authToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return nil, apiError("create build", resp)
+	}
+	var out buildapi.BuildResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EvaluatePolicies asks the server whether req would be accepted, without creating the build
+func (c *Client) EvaluatePolicies(ctx context.Context, req buildapi.BuildRequest) (*buildapi.PolicyEvaluationResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := c.resolve("/v1/policies/evaluate")
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.authToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("evaluate policies", resp)
+	}
+	var out buildapi.PolicyEvaluationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ErrManifestValidationUnsupported is returned by ValidateManifest when the server cannot
+// validate manifests
+var ErrManifestValidationUnsupported = errors.New("server does not support manifest validation")
+
+// ValidateManifest asks the server what a build of req.Manifest with the settings of req would be
+// rejected for, without creating anything
+func (c *Client) ValidateManifest(ctx context.Context, req buildapi.ManifestValidationRequest) (*buildapi.ManifestValidationResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := c.resolve("/v1/manifests/validate")
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.authToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrManifestValidationUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("validate manifest", resp)
+	}
+	var out buildapi.ManifestValidationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) GetBuild(ctx context.Context, name string) (*buildapi.BuildResponse, error) {
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("get build", resp)
+	}
+	var out buildapi.BuildResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListBuilds lists builds, restricted to those matching labelSelector (e.g. "team=adas,branch=main") when set
+func (c *Client) ListBuilds(ctx context.Context, labelSelector string) ([]buildapi.BuildListItem, error) {
+	page, err := c.ListBuildsPage(ctx, ListBuildsOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// ListBuildsOptions filters and pages the build list; zero values do not filter
+type ListBuildsOptions struct {
+	LabelSelector string
+	// Phases match case-insensitively; Pending matches builds the controller has not picked up yet
+	Phases []string
+	Arch   string
+	// CreatedAfter is an RFC 3339 time or a window such as 7d or 24h
+	CreatedAfter string
+	// Limit is the page size; 0 returns all matching builds
+	Limit int
+	// Continue is the token of the page to return, from BuildListPage.Continue
+	Continue string
+	// AllNamespaces lists builds of every namespace; the caller needs permission to list
+	// ImageBuilds cluster-wide
+	AllNamespaces bool
+	// Namespaces restricts an AllNamespaces listing to these namespaces
+	Namespaces []string
+}
+
+// BuildListPage is a page of builds, newest first
+type BuildListPage struct {
+	Items []buildapi.BuildListItem
+	// Total is the number of builds matching the filters across all pages
+	Total int
+	// Continue fetches the next page; empty on the last page
+	Continue string
+	// ResourceVersion is the version of the list; a watch of the builds started from it receives
+	// the changes made after the list
+	ResourceVersion string
+}
+
+// ListBuildsPage returns one page of builds matching opts
+func (c *Client) ListBuildsPage(ctx context.Context, opts ListBuildsOptions) (*BuildListPage, error) {
+	endpoint := c.resolve("/v1/builds")
+	if q := listBuildsQuery(opts); len(q) > 0 {
+		endpoint += "?" + q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &retryError{err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, listBuildsError("list builds", resp)
+	}
+	page := &BuildListPage{Continue: resp.Header.Get("X-Continue"), ResourceVersion: resp.Header.Get("X-Resource-Version")}
+	if err := json.NewDecoder(resp.Body).Decode(&page.Items); err != nil {
+		return nil, err
+	}
+	page.Total = len(page.Items)
+	if n, err := strconv.Atoi(resp.Header.Get("X-Total-Count")); err == nil {
+		page.Total = n
+	}
+	return page, nil
+}
+
+// listBuildsError converts a failed response of GET /v1/builds to an error, a retryError for
+// transient failures
+func listBuildsError(op string, resp *http.Response) error {
+	err := apiError(op, resp)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return &retryError{err: err, after: RetryAfter(resp)}
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return &retryError{err: err}
+	}
+	return err
+}
+
+// listBuildsQuery encodes the filters and page of opts as parameters of GET /v1/builds
+func listBuildsQuery(opts ListBuildsOptions) url.Values {
+	q := url.Values{}
+	if opts.LabelSelector != "" {
+		q.Set("labelSelector", opts.LabelSelector)
+	}
+	if len(opts.Phases) > 0 {
+		q.Set("phase", strings.Join(opts.Phases, ","))
+	}
+	if opts.Arch != "" {
+		q.Set("arch", opts.Arch)
+	}
+	if opts.CreatedAfter != "" {
+		q.Set("created-after", opts.CreatedAfter)
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Continue != "" {
+		q.Set("continue", opts.Continue)
+	}
+	if opts.AllNamespaces {
+		q.Set("allNamespaces", "true")
+	}
+	if len(opts.Namespaces) > 0 {
+		q.Set("namespace", strings.Join(opts.Namespaces, ","))
+	}
+	return q
+}
+
+func (c *Client) GetBuildTemplate(ctx context.Context, name string) (*buildapi.BuildTemplateResponse, error) {
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "template"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("get build template", resp)
+	}
+	var out buildapi.BuildTemplateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetBuildManifest returns the manifest content a build was submitted with and its file name
+func (c *Client) GetBuildManifest(ctx context.Context, name string) (string, string, error) {
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "manifest"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", "", err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", apiError("get build manifest", resp)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", err
+	}
+	var fileName string
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		fileName = params["filename"]
+	}
+	return string(b), fileName, nil
+}
+
+// CloneBuild creates a new build from the stored inputs of source with req.Patch applied server-side
+func (c *Client) CloneBuild(ctx context.Context, source string, req buildapi.BuildCloneRequest) (*buildapi.BuildResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(source), "clone"))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.authToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return nil, apiError("clone build", resp)
+	}
+	var out buildapi.BuildResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatchBuild changes a build after it was created, e.g. the retention of its artifacts
+func (c *Client) PatchBuild(ctx context.Context, name string, req buildapi.BuildPatchRequest) (*buildapi.BuildResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name)))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPatch, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.authToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("patch build", resp)
+	}
+	var out buildapi.BuildResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// LogSearchOptions selects the log lines SearchLogs returns
+type LogSearchOptions struct {
+	// Query is matched as a substring, or as a regular expression when Regex is set
+	Query      string
+	Regex      bool
+	IgnoreCase bool
+	// Context is the number of lines returned before and after each match
+	Context int
+	// MaxMatches bounds the number of matches; 0 uses the server default
+	MaxMatches int
+	// Step restricts the search to the log of one build step
+	Step string
+}
+
+// SearchLogs greps the logs of a build on the server
+func (c *Client) SearchLogs(ctx context.Context, name string, opts LogSearchOptions) (*buildapi.LogSearchResponse, error) {
+	q := url.Values{}
+	q.Set("q", opts.Query)
+	if opts.Regex {
+		q.Set("regex", "true")
+	}
+	if opts.IgnoreCase {
+		q.Set("ignoreCase", "true")
+	}
+	if opts.Context > 0 {
+		q.Set("context", strconv.Itoa(opts.Context))
+	}
+	if opts.MaxMatches > 0 {
+		q.Set("maxMatches", strconv.Itoa(opts.MaxMatches))
+	}
+	if opts.Step != "" {
+		q.Set("step", opts.Step)
+	}
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "logs", "search")) + "?" + q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("log search", resp)
+	}
+	var out buildapi.LogSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ErrLogStreamUnsupported is returned by FollowLogs when the server has no log event stream
+var ErrLogStreamUnsupported = errors.New("server does not support log event streams")
+
+const (
+	// logStreamIdleTimeout is how long FollowLogs waits for data, including heartbeats, before
+	// it considers the connection dead and reconnects
+	logStreamIdleTimeout = 45 * time.Second
+)
+
+// logStreamRetryDelay is the wait before FollowLogs reconnects; tests shorten it
+var logStreamRetryDelay = 2 * time.Second
+
+// LogEvent is an event of the log stream of a build
+type LogEvent struct {
+	// Type is step, log, system, error or end
+	Type string
+	// ID is set for log events and identifies the line to resume after
+	ID string
+	buildapi.LogStreamEvent
+}
+
+// FollowLogs streams the logs of a build to fn until the server sends the end event. When the
+// connection drops or stays idle it reconnects and resumes after the last line received. Error
+// events are passed to fn and followed by a reconnect; an error returned by fn stops streaming.
+func (c *Client) FollowLogs(ctx context.Context, name string, fn func(LogEvent) error) error {
+	var lastID string
+	for {
+		ended, err := c.followLogsOnce(ctx, name, &lastID, fn)
+		if ended || ctx.Err() != nil {
+			return err
+		}
+		var retry *retryError
+		if err != nil && !errors.As(err, &retry) {
+			return err
+		}
+		delay := logStreamRetryDelay
+		if retry != nil && retry.after > delay {
+			delay = retry.after
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// RetryAfter returns the wait a rate limited response asks for in its Retry-After header, in
+// seconds; it defaults to one second
+func RetryAfter(resp *http.Response) time.Duration {
+	if secs, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return time.Second
+}
+
+// retryError is a transient failure of one request, e.g. a dropped connection or a 503, that is
+// recovered from by trying again, after the Retry-After of rate limited requests
+type retryError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryError) Error() string { return e.err.Error() }
+func (e *retryError) Unwrap() error { return e.err }
+
+// followLogsOnce reads the log stream over a single connection, updating lastID as log events
+// arrive. It reports whether the end event was received.
+func (c *Client) followLogsOnce(ctx context.Context, name string, lastID *string, fn func(LogEvent) error) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "logs", "stream"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if *lastID != "" {
+		req.Header.Set("Last-Event-ID", *lastID)
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, &retryError{err: err}
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusOK && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream"):
+	case resp.StatusCode == http.StatusOK:
+		// A server without the route, e.g. one that serves a web UI for unknown paths
+		return false, ErrLogStreamUnsupported
+	case resp.StatusCode == http.StatusNotFound && !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json"):
+		// Unknown routes are answered in plain text, missing builds in JSON
+		return false, ErrLogStreamUnsupported
+	case resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout:
+		return false, &retryError{err: fmt.Errorf("follow logs failed: %s", resp.Status)}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return false, &retryError{err: fmt.Errorf("follow logs failed: %s", resp.Status), after: RetryAfter(resp)}
+	default:
+		return false, apiError("follow logs", resp)
+	}
+
+	idle := time.AfterFunc(logStreamIdleTimeout, cancel)
+	defer idle.Stop()
+	err = readEvents(resp.Body, idle, func(eventType, id, data string) error {
+		ev := LogEvent{Type: eventType, ID: id}
+		if err := json.Unmarshal([]byte(data), &ev.LogStreamEvent); err != nil {
+			return fmt.Errorf("invalid log event: %w", err)
+		}
+		if ev.ID != "" {
+			*lastID = ev.ID
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+		switch ev.Type {
+		case "end":
+			return errStreamEnded
+		case "error":
+			return &retryError{err: errors.New(ev.Text)}
+		}
+		return nil
+	})
+	if errors.Is(err, errStreamEnded) {
+		return true, nil
+	}
+	return false, err
+}
+
+// errStreamEnded is returned by the function passed to readEvents after the last event of a stream
+var errStreamEnded = errors.New("stream ended")
+
+// readEvents calls fn with the type, id and data of every server-sent event read from r until fn
+// returns an error, which is returned, or r ends, which is a transient failure since streams end
+// with an event of their own. Events without a type are message events. idle is reset on every
+// line read.
+func readEvents(r io.Reader, idle *time.Timer, fn func(eventType, id, data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	var eventType, id string
+	var data strings.Builder
+	for scanner.Scan() {
+		idle.Reset(logStreamIdleTimeout)
+		line := scanner.Text()
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				eventType, id = "", ""
+				continue
+			}
+			if eventType == "" {
+				eventType = "message"
+			}
+			if err := fn(eventType, id, data.String()); err != nil {
+				return err
+			}
+			eventType, id = "", ""
+			data.Reset()
+		case field == "":
+			// Comment, e.g. a heartbeat
+		case field == "event":
+			eventType = value
+		case field == "id":
+			id = value
+		case field == "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return &retryError{err: err}
+	}
+	return &retryError{err: io.ErrUnexpectedEOF}
+}
+
+// DeleteBuildOptions controls what DeleteBuild removes
+type DeleteBuildOptions struct {
+	// KeepArtifact keeps the workspace PVC holding the artifact of a completed build
+	KeepArtifact bool
+	// Force cancels and deletes a build that is still running instead of failing with 409 Conflict
+	Force bool
+}
+
+// DeleteBuild deletes a build together with its workspace and artifact unless opts.KeepArtifact is set
+func (c *Client) DeleteBuild(ctx context.Context, name string, opts DeleteBuildOptions) (*buildapi.BuildDeleteResponse, error) {
+	q := url.Values{}
+	if opts.KeepArtifact {
+		q.Set("keep-artifact", "true")
+	}
+	if opts.Force {
+		q.Set("force", "true")
+	}
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name)))
+	if len(q) > 0 {
+		endpoint += "?" + q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("delete build", resp)
+	}
+	var out buildapi.BuildDeleteResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CancelBuild asks the server to stop a running build. The build moves to Cancelled once the
+// operator has stopped its pod; cancelling a finished build fails with 409 Conflict.
+func (c *Client) CancelBuild(ctx context.Context, name string) (*buildapi.BuildResponse, error) {
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "cancel"))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.authToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return nil, apiError("cancel build", resp)
+	}
+	var out buildapi.BuildResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateSignedURL returns a URL that downloads the artifact of a completed build, or the file of
+// its artifact manifest req names, without a token until it expires
+func (c *Client) CreateSignedURL(ctx context.Context, name string, req buildapi.SignedURLRequest) (*buildapi.SignedURLResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "artifact", "url"))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.authToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return nil, apiError("create signed URL", resp)
+	}
+	var out buildapi.SignedURLResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDefinesCatalog returns the default defines per target; an empty target returns all targets
+func (c *Client) GetDefinesCatalog(ctx context.Context, target string) (*buildapi.DefinesCatalogResponse, error) {
+	endpoint := c.resolve("/v1/catalog/defines")
+	if target != "" {
+		endpoint += "?target=" + url.QueryEscape(target)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("get defines catalog", resp)
+	}
+	var out buildapi.DefinesCatalogResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetBuildCompliance returns the OpenSCAP scan outcome of a build
+func (c *Client) GetBuildCompliance(ctx context.Context, name string) (*buildapi.ComplianceResponse, error) {
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "compliance"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("get compliance", resp)
+	}
+	var out buildapi.ComplianceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetBuildComparison compares a build with the golden build of branch, or of the target branch
+// of the build when branch is empty
+func (c *Client) GetBuildComparison(ctx context.Context, name, branch string) (*buildapi.BuildComparison, error) {
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "comparison"))
+	if branch != "" {
+		endpoint += "?branch=" + url.QueryEscape(branch)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("get comparison", resp)
+	}
+	var out buildapi.BuildComparison
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetBuildEvents returns what happened to a build, oldest first; eventType (Normal or Warning)
+// restricts the events to that type when set
+func (c *Client) GetBuildEvents(ctx context.Context, name, eventType string) (*buildapi.BuildEventsResponse, error) {
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "events"))
+	if eventType != "" {
+		endpoint += "?" + url.Values{"type": {eventType}}.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("get events", resp)
+	}
+	var out buildapi.BuildEventsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetBuildRecords returns the build record history of the namespace and whether it is unbroken
+func (c *Client) GetBuildRecords(ctx context.Context) (*buildapi.BuildRecordsResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.resolve("/v1/build-records"), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("get build records", resp)
+	}
+	var out buildapi.BuildRecordsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSBOM returns the software bill of materials of a build in format, spdx or cyclonedx
+func (c *Client) GetSBOM(ctx context.Context, name, format string) ([]byte, error) {
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "sbom"))
+	if format != "" {
+		endpoint += "?" + url.Values{"format": {format}}.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("get SBOM", resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// GetPipelineGraph returns the dependency graph of a Tekton pipeline with the status of every task
+// in run, or in the latest run of the pipeline when run is empty
+func (c *Client) GetPipelineGraph(ctx context.Context, name, run string) (*pipelinegraph.Graph, error) {
+	endpoint := c.resolve(path.Join("/v1/pipelines", url.PathEscape(name), "graph"))
+	if run != "" {
+		endpoint += "?" + url.Values{"run": {run}}.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("get pipeline graph", resp)
+	}
+	var graph pipelinegraph.Graph
+	if err := json.NewDecoder(resp.Body).Decode(&graph); err != nil {
+		return nil, err
+	}
+	return &graph, nil
 }
 
-func New(base string, opts ...Option) (*Client, error) {
-	u, err := url.Parse(base)
+// GetInfo returns the maintenance state, banner and component versions of the build API
+func (c *Client) GetInfo(ctx context.Context) (*buildapi.InfoResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.resolve("/v1/info"), nil)
 	if err != nil {
-		return nil, fmt.Errorf("invalid base URL: %w", err)
+		return nil, err
 	}
-	if u.Scheme == "" || u.Host == "" {
-		return nil, fmt.Errorf("base URL must include scheme and host (e.g., https://api.example.com)")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
 	}
-	c := &Client{
-		baseURL:    u,
-		httpClient: &http.Client{}, // No global timeout to avoid aborting large uploads
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("get info", resp)
 	}
-	for _, o := range opts {
-		o(c)
+	var out buildapi.InfoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
 	}
-	return c, nil
+	return &out, nil
 }
 
-type Option func(*Client)
-
-func WithHTTPClient(h *http.Client) Option { return func(c *Client) { c.httpClient = h } }
-func WithAuthToken(t string) Option        { return func(c *Client) { c.authToken = t } }
+// GetHardeningCatalog returns the hardening profiles builds can select
+func (c *Client) GetHardeningCatalog(ctx context.Context) (*buildapi.HardeningCatalogResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.resolve("/v1/catalog/hardening"), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("get hardening catalog", resp)
+	}
+	var out buildapi.HardeningCatalogResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
 
-func (c *Client) CreateBuild(ctx context.Context, req buildapi.BuildRequest) (*buildapi.BuildResponse, error) {
-	body, err := json.Marshal(req)
+// GetCapabilities returns the distros, targets, architectures and export formats supported by an
+// automotive-image-builder image; an empty image queries the operator's default
+func (c *Client) GetCapabilities(ctx context.Context, image string) (*buildapi.CapabilitiesResponse, error) {
+	endpoint := c.resolve("/v1/capabilities")
+	if image != "" {
+		endpoint += "?automotiveImageBuilder=" + url.QueryEscape(image)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
-	endpoint := c.resolve("/v1/builds")
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("get capabilities", resp)
+	}
+	var out buildapi.CapabilitiesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetStats returns aggregate statistics of the builds created within since (e.g. "7d", "24h");
+// an empty since uses the server default
+func (c *Client) GetStats(ctx context.Context, since string) (*buildapi.BuildStatsResponse, error) {
+	endpoint := c.resolve("/v1/stats")
+	if since != "" {
+		endpoint += "?since=" + url.QueryEscape(since)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
 	if c.authToken != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.authToken)
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
 	}
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return nil, fmt.Errorf("create build failed: %s: %s", resp.Status, string(b))
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("get stats", resp)
 	}
-	var out buildapi.BuildResponse
+	var out buildapi.BuildStatsResponse
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
 		return nil, err
 	}
 	return &out, nil
 }
 
-func (c *Client) GetBuild(ctx context.Context, name string) (*buildapi.BuildResponse, error) {
-	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name)))
+// GetFailureAnalytics groups the failed builds created within since by reason and error signature,
+// counting them per bucket (e.g. "1d"); empty values use the server defaults
+func (c *Client) GetFailureAnalytics(ctx context.Context, since, bucket string) (*buildapi.FailureAnalyticsResponse, error) {
+	q := url.Values{}
+	if since != "" {
+		q.Set("since", since)
+	}
+	if bucket != "" {
+		q.Set("bucket", bucket)
+	}
+	endpoint := c.resolve("/v1/stats/failures")
+	if len(q) > 0 {
+		endpoint += "?" + q.Encode()
+	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return nil, err
@@ -90,18 +1146,66 @@ func (c *Client) GetBuild(ctx context.Context, name string) (*buildapi.BuildResp
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return nil, fmt.Errorf("get build failed: %s: %s", resp.Status, string(b))
+		return nil, apiError("get failure analytics", resp)
 	}
-	var out buildapi.BuildResponse
+	var out buildapi.FailureAnalyticsResponse
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
 		return nil, err
 	}
 	return &out, nil
 }
 
-func (c *Client) ListBuilds(ctx context.Context) ([]buildapi.BuildListItem, error) {
-	endpoint := c.resolve("/v1/builds")
+// DownloadArtifactFile writes a named artifact of a completed build (e.g. a secondary artifact) to w
+func (c *Client) DownloadArtifactFile(ctx context.Context, name, file string, w io.Writer) error {
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "artifact", url.PathEscape(file)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apiError("download artifact", resp)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// DownloadLogArchive writes the complete logs of a finished build to w, as the gzip archive the
+// operator kept or, with text set, uncompressed
+func (c *Client) DownloadLogArchive(ctx context.Context, name string, text bool, w io.Writer) error {
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "logs", "archive"))
+	if text {
+		endpoint += "?format=text"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apiError("download log archive", resp)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// GetArtifactManifest lists the files of a finished build with their sizes and SHA-256 digests
+func (c *Client) GetArtifactManifest(ctx context.Context, name string) (*buildapi.ArtifactManifestResponse, error) {
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "artifact", "manifest"))
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return nil, err
@@ -115,14 +1219,99 @@ func (c *Client) ListBuilds(ctx context.Context) ([]buildapi.BuildListItem, erro
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return nil, fmt.Errorf("list builds failed: %s: %s", resp.Status, string(b))
+		return nil, apiError("get artifact manifest", resp)
 	}
-	var out []buildapi.BuildListItem
+	var out buildapi.ArtifactManifestResponse
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
 		return nil, err
 	}
-	return out, nil
+	return &out, nil
+}
+
+// CopyFromWorkspace writes a tar archive of a file or directory of a build to w. Relative paths
+// are relative to the build workspace.
+func (c *Client) CopyFromWorkspace(ctx context.Context, name, p string, w io.Writer) error {
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "workspace")) + "?path=" + url.QueryEscape(p)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apiError("copy", resp)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// ExecOptions describes a command run in a build pod
+type ExecOptions struct {
+	Command []string
+	// Container defaults to the debug hold of a failed build, otherwise the running step
+	Container string
+	Stdin     io.Reader
+	Stdout    io.Writer
+	Stderr    io.Writer
+	TTY       bool
+	// TerminalSizeQueue reports terminal resizes when TTY is set
+	TerminalSizeQueue remotecommand.TerminalSizeQueue
+}
+
+// Exec runs a command in the running pod of a build and streams its input and output until it exits
+func (c *Client) Exec(ctx context.Context, name string, opts ExecOptions) error {
+	endpoint, err := url.Parse(c.resolve(path.Join("/v1/builds", url.PathEscape(name), "exec")))
+	if err != nil {
+		return err
+	}
+	q := endpoint.Query()
+	for _, arg := range opts.Command {
+		q.Add("command", arg)
+	}
+	if opts.Container != "" {
+		q.Set("container", opts.Container)
+	}
+	q.Set("stdin", strconv.FormatBool(opts.Stdin != nil))
+	q.Set("stdout", strconv.FormatBool(opts.Stdout != nil))
+	// with a TTY stderr is merged into stdout
+	q.Set("stderr", strconv.FormatBool(opts.Stderr != nil && !opts.TTY))
+	q.Set("tty", strconv.FormatBool(opts.TTY))
+	endpoint.RawQuery = q.Encode()
+
+	cfg := &rest.Config{Host: c.baseURL.Scheme + "://" + c.baseURL.Host, BearerToken: c.authToken, Impersonate: c.impersonate}
+	ws, err := remotecommand.NewWebSocketExecutor(cfg, http.MethodGet, endpoint.String())
+	if err != nil {
+		return err
+	}
+	spdy, err := remotecommand.NewSPDYExecutor(cfg, http.MethodPost, endpoint)
+	if err != nil {
+		return err
+	}
+	exec, err := remotecommand.NewFallbackExecutor(ws, spdy, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	})
+	if err != nil {
+		return err
+	}
+	streamOpts := remotecommand.StreamOptions{
+		Stdin:             opts.Stdin,
+		Stdout:            opts.Stdout,
+		Tty:               opts.TTY,
+		TerminalSizeQueue: opts.TerminalSizeQueue,
+	}
+	if !opts.TTY {
+		streamOpts.Stderr = opts.Stderr
+	}
+	if err := exec.StreamWithContext(ctx, streamOpts); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	return nil
 }
 
 func (c *Client) resolve(p string) string {
@@ -142,6 +1331,11 @@ type Upload struct {
 }
 
 func (c *Client) UploadFiles(ctx context.Context, name string, files []Upload) error {
+	return c.UploadFilesWithProgress(ctx, name, files, nil)
+}
+
+// UploadFilesWithProgress is UploadFiles, writing the contents of the files to progress as they are sent
+func (c *Client) UploadFilesWithProgress(ctx context.Context, name string, files []Upload, progress io.Writer) error {
 	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "uploads"))
 	pr, pw := io.Pipe()
 	mw := multipart.NewWriter(pw)
@@ -165,7 +1359,11 @@ func (c *Client) UploadFiles(ctx context.Context, name string, files []Upload) e
 					pw.CloseWithError(err)
 					return
 				}
-				if _, err := io.Copy(part, file); err != nil {
+				var src io.Reader = file
+				if progress != nil {
+					src = io.TeeReader(file, progress)
+				}
+				if _, err := io.Copy(part, src); err != nil {
 					file.Close()
 					pw.CloseWithError(err)
 					return
@@ -195,8 +1393,195 @@ func (c *Client) UploadFiles(ctx context.Context, name string, files []Upload) e
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
+		return apiError("upload", resp)
+	}
+	return nil
+}
+
+const (
+	// uploadChunkBytes is the size of the chunks of resumable uploads, lowered to what the server accepts
+	uploadChunkBytes = 8 << 20
+	// maxUploadAttempts is how often a chunk is sent before the upload fails
+	maxUploadAttempts = 6
+)
+
+// UploadStatusError is an upload request the server rejected
+type UploadStatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+	RequestID  string
+}
+
+func (e *UploadStatusError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("upload failed: %s: %s (request ID %s)", e.Status, e.Body, e.RequestID)
+	}
+	return fmt.Sprintf("upload failed: %s: %s", e.Status, e.Body)
+}
+
+// retryable reports whether sending the chunk again may succeed: the server or the upload pod was
+// unavailable, the chunk was damaged in transit or the offset went out of sync
+func (e *UploadStatusError) retryable() bool {
+	switch e.StatusCode {
+	case http.StatusConflict, http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusBadGateway,
+		http.StatusGatewayTimeout, http.StatusInternalServerError, 460:
+		return true
+	}
+	return false
+}
+
+// uploadRequest sends a request of the resumable upload protocol and decodes the session it returns into out
+func (c *Client) uploadRequest(ctx context.Context, method, endpoint string, body io.Reader, header http.Header, want int, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != want {
 		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return fmt.Errorf("upload failed: %s: %s", resp.Status, string(b))
+		return &UploadStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(b), RequestID: responseRequestID(resp)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *Client) uploadSessionsPath(name string, elem ...string) string {
+	return c.resolve(path.Join(append([]string{"/v1/builds", url.PathEscape(name), "uploads", "sessions"}, elem...)...))
+}
+
+// CreateUploadSession starts a resumable upload of a file of build name
+func (c *Client) CreateUploadSession(ctx context.Context, name string, in buildapi.UploadSessionRequest) (*buildapi.UploadSession, error) {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	var out buildapi.UploadSession
+	header := http.Header{"Content-Type": {"application/json"}}
+	if err := c.uploadRequest(ctx, http.MethodPost, c.uploadSessionsPath(name), bytes.NewReader(body), header, http.StatusCreated, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetUploadSession returns the state of an upload session, in particular the offset to resume from
+func (c *Client) GetUploadSession(ctx context.Context, name, id string) (*buildapi.UploadSession, error) {
+	var out buildapi.UploadSession
+	if err := c.uploadRequest(ctx, http.MethodGet, c.uploadSessionsPath(name, url.PathEscape(id)), nil, nil, http.StatusOK, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UploadChunk sends the bytes of an upload session starting at offset, with their checksum
+func (c *Client) UploadChunk(ctx context.Context, name, id string, offset int64, chunk []byte) (*buildapi.UploadSession, error) {
+	sum := sha256.Sum256(chunk)
+	header := http.Header{
+		"Content-Type":    {"application/offset+octet-stream"},
+		"Upload-Offset":   {strconv.FormatInt(offset, 10)},
+		"Upload-Checksum": {"sha256 " + base64.StdEncoding.EncodeToString(sum[:])},
+	}
+	var out buildapi.UploadSession
+	if err := c.uploadRequest(ctx, http.MethodPatch, c.uploadSessionsPath(name, url.PathEscape(id)), bytes.NewReader(chunk), header, http.StatusOK, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteUploadSession aborts an upload session, discarding what was received
+func (c *Client) DeleteUploadSession(ctx context.Context, name, id string) error {
+	return c.uploadRequest(ctx, http.MethodDelete, c.uploadSessionsPath(name, url.PathEscape(id)), nil, nil, http.StatusNoContent, nil)
+}
+
+// CompleteUploads lets build name start once all its upload sessions finished
+func (c *Client) CompleteUploads(ctx context.Context, name string) error {
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "uploads", "complete"))
+	return c.uploadRequest(ctx, http.MethodPost, endpoint, nil, nil, http.StatusOK, nil)
+}
+
+// UploadFilesResumable uploads files with resumable upload sessions, then completes the uploads of
+// the build. The contents of the files are written to progress as the server acknowledges them.
+func (c *Client) UploadFilesResumable(ctx context.Context, name string, files []Upload, progress io.Writer) error {
+	for _, f := range files {
+		if err := c.UploadFileResumable(ctx, name, f, progress); err != nil {
+			return err
+		}
+	}
+	return c.CompleteUploads(ctx, name)
+}
+
+// UploadFileResumable uploads a file in chunks. A chunk that fails is sent again from the offset
+// the server reports, so network failures and restarts of the upload pod only cost that chunk.
+func (c *Client) UploadFileResumable(ctx context.Context, name string, f Upload, progress io.Writer) error {
+	file, err := os.Open(f.SourcePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return err
+	}
+
+	session, err := c.CreateUploadSession(ctx, name, buildapi.UploadSessionRequest{
+		Path:   f.DestPath,
+		Size:   size,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	})
+	if err != nil {
+		return err
+	}
+	chunkSize := int64(uploadChunkBytes)
+	if session.MaxChunkBytes > 0 {
+		chunkSize = min(chunkSize, session.MaxChunkBytes)
+	}
+	buf := make([]byte, chunkSize)
+
+	offset, attempts := session.Offset, 0
+	for !session.Complete {
+		n := min(chunkSize, size-offset)
+		if _, err := file.ReadAt(buf[:n], offset); err != nil {
+			return err
+		}
+		next, err := c.UploadChunk(ctx, name, session.ID, offset, buf[:n])
+		if err == nil {
+			if progress != nil {
+				_, _ = progress.Write(buf[:n])
+			}
+			session, offset, attempts = next, next.Offset, 0
+			continue
+		}
+
+		attempts++
+		var statusErr *UploadStatusError
+		if ctx.Err() != nil || attempts >= maxUploadAttempts || (errors.As(err, &statusErr) && !statusErr.retryable()) {
+			return fmt.Errorf("uploading %s: %w", f.SourcePath, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempts) * 2 * time.Second):
+		}
+		// the failed chunk may have arrived, so resume from where the server is
+		if current, err := c.GetUploadSession(ctx, name, session.ID); err == nil {
+			if current.Offset > offset && progress != nil {
+				_, _ = progress.Write(buf[:min(current.Offset-offset, n)])
+			}
+			session, offset = current, current.Offset
+		}
 	}
 	return nil
 }
@@ -0,0 +1,19 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestClient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Build API Client Suite")
+}
+
+var _ = BeforeSuite(func() {
+	// retries of transient failures need not wait in tests
+	logStreamRetryDelay = time.Millisecond
+})
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// requestIDHeader carries the ID of a request; the build API logs it with the request and returns
+// it in the response
+const requestIDHeader = "X-Request-ID"
+
+// requestIDTransport gives every request an X-Request-ID, so failures can be found in the logs of
+// the build API even when the error response did not reach the client intact
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get(requestIDHeader) == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(requestIDHeader, uuid.New().String())
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// APIError is an error response of the v1 API. Use errors.As to read its status or request ID.
+type APIError struct {
+	// Op is the operation that failed, e.g. "get build"
+	Op         string
+	StatusCode int
+	Status     string
+	// Message is the error of the response, or the start of bodies that are not JSON errors
+	Message string
+	// RequestID identifies the request in the logs of the build API
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s failed: %s: %s", e.Op, e.Status, e.Message)
+	if e.RequestID != "" {
+		msg += " (request ID " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// apiError reads the error response of a v1 request
+func apiError(op string, resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	e := &APIError{Op: op, StatusCode: resp.StatusCode, Status: resp.Status, Message: string(bytes.TrimSpace(b))}
+	var body struct {
+		Error     string `json:"error"`
+		RequestID string `json:"requestID"`
+	}
+	if json.Unmarshal(b, &body) == nil && body.Error != "" {
+		e.Message = body.Error
+		e.RequestID = body.RequestID
+	}
+	if e.RequestID == "" {
+		e.RequestID = responseRequestID(resp)
+	}
+	return e
+}
+
+// responseRequestID returns the request ID the build API answered resp with, or else the one the
+// request was sent with
+func responseRequestID(resp *http.Response) string {
+	if id := resp.Header.Get(requestIDHeader); id != "" {
+		return id
+	}
+	if resp.Request != nil {
+		return resp.Request.Header.Get(requestIDHeader)
+	}
+	return ""
+}
+
+// RequestID returns the request ID of a failed build API request, for reporting the failure to
+// the administrators of the build API; empty when err is not an error response of the build API
+func RequestID(err error) string {
+	var ae *APIError
+	if errors.As(err, &ae) {
+		return ae.RequestID
+	}
+	var pe *ProblemError
+	if errors.As(err, &pe) {
+		return pe.RequestID
+	}
+	var ue *UploadStatusError
+	if errors.As(err, &ue) {
+		return ue.RequestID
+	}
+	return ""
+}
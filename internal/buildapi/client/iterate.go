@@ -0,0 +1,317 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+)
+
+const (
+	// defaultPageSize is the page size of ForEachBuild when ListBuildsOptions.Limit is 0
+	defaultPageSize = 100
+	// maxRetries is how many times a transient failure of one request is retried
+	maxRetries = 5
+	// defaultWatchInterval is how often WatchBuilds lists the builds of a build API that cannot
+	// stream their changes when no interval is set
+	defaultWatchInterval = 5 * time.Second
+)
+
+// ErrStop can be returned by the function passed to ForEachBuild or WatchBuilds to stop early;
+// they return nil then
+var ErrStop = errors.New("stop")
+
+// withRetry calls fn until it succeeds or fails with an error that is not transient, waiting
+// between attempts. It gives up after maxRetries retries with the last error.
+func withRetry(ctx context.Context, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		var retry *retryError
+		if err == nil || !errors.As(err, &retry) || attempt == maxRetries || ctx.Err() != nil {
+			return err
+		}
+		delay := logStreamRetryDelay << attempt
+		if retry.after > delay {
+			delay = retry.after
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// ForEachBuild calls fn with every build matching opts, newest first, fetching them a page of
+// opts.Limit builds at a time (100 when 0) and following the continue token of each page from
+// opts.Continue on. Transient failures (dropped connections, 429, 502, 503 and 504) are retried.
+// An error returned by fn stops the iteration and is returned, except ErrStop.
+func (c *Client) ForEachBuild(ctx context.Context, opts ListBuildsOptions, fn func(buildapi.BuildListItem) error) error {
+	if opts.Limit <= 0 {
+		opts.Limit = defaultPageSize
+	}
+	for {
+		var page *BuildListPage
+		err := withRetry(ctx, func() error {
+			var err error
+			page, err = c.ListBuildsPage(ctx, opts)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for _, item := range page.Items {
+			if err := fn(item); err != nil {
+				if errors.Is(err, ErrStop) {
+					return nil
+				}
+				return err
+			}
+		}
+		if page.Continue == "" {
+			return nil
+		}
+		opts.Continue = page.Continue
+	}
+}
+
+// BuildEventType is how a build changed between two listings
+type BuildEventType string
+
+const (
+	BuildAdded    BuildEventType = "Added"
+	BuildModified BuildEventType = "Modified"
+	BuildDeleted  BuildEventType = "Deleted"
+)
+
+// BuildEvent is a change of a build seen by WatchBuilds. Build is the last listing of deleted builds.
+type BuildEvent struct {
+	Type  BuildEventType
+	Build buildapi.BuildListItem
+}
+
+// WatchBuildsOptions selects the builds WatchBuilds reports
+type WatchBuildsOptions struct {
+	// ListBuildsOptions filters the builds; Limit and Continue are ignored
+	ListBuildsOptions
+	// Interval is the time between two listings of a build API that cannot stream the changes of
+	// builds; 5 seconds when 0
+	Interval time.Duration
+	// Known are builds the caller already has, e.g. from an earlier watch it resumes, so they are
+	// only reported when they changed or were deleted since. Without them every build is first
+	// reported as Added.
+	Known []buildapi.BuildListItem
+}
+
+// WatchBuilds calls fn with each build matching opts that is added, modified or deleted, until
+// ctx is done or fn returns an error. It follows the changes the build API streams from
+// GET /v1/builds?watch=true and resumes the stream after the last change when the connection
+// drops; build APIs without the stream are listed every opts.Interval instead. Failures are
+// retried without losing track of the builds, so changes made meanwhile are reported once the
+// server answers again; only errors that are not transient are returned. fn returning ErrStop ends
+// the watch with nil. Builds deleted while the builds were not watched are reported in no
+// particular order.
+func (c *Client) WatchBuilds(ctx context.Context, opts WatchBuildsOptions, fn func(BuildEvent) error) error {
+	list := opts.ListBuildsOptions
+	list.Limit, list.Continue = 0, ""
+
+	known := map[string]buildapi.BuildListItem{}
+	for _, b := range opts.Known {
+		known[b.Namespace+"/"+b.Name] = b
+	}
+	err := c.streamBuilds(ctx, list, known, fn)
+	if errors.Is(err, errWatchUnsupported) {
+		err = c.pollBuilds(ctx, list, opts.Interval, known, fn)
+	}
+	if errors.Is(err, ErrStop) {
+		return nil
+	}
+	return err
+}
+
+// errWatchUnsupported is returned by streamBuilds when the build API answers a watch with the list
+var errWatchUnsupported = errors.New("the build API cannot stream the changes of builds")
+
+// report updates known with the change of a build and calls fn when it changed the build
+func report(known map[string]buildapi.BuildListItem, eventType BuildEventType, b buildapi.BuildListItem, fn func(BuildEvent) error) error {
+	key := b.Namespace + "/" + b.Name
+	prev, ok := known[key]
+	switch {
+	case eventType == BuildDeleted:
+		if !ok {
+			return nil
+		}
+		delete(known, key)
+	case !ok:
+		eventType = BuildAdded
+	case reflect.DeepEqual(prev, b):
+		return nil
+	default:
+		eventType = BuildModified
+	}
+	if eventType != BuildDeleted {
+		known[key] = b
+	}
+	return fn(BuildEvent{Type: eventType, Build: b})
+}
+
+// streamBuilds follows the build watch, reconnecting after the last event received and starting
+// over with the current builds when that is too old to resume from
+func (c *Client) streamBuilds(ctx context.Context, opts ListBuildsOptions, known map[string]buildapi.BuildListItem, fn func(BuildEvent) error) error {
+	var resourceVersion string
+	for {
+		err := c.streamBuildsOnce(ctx, opts, &resourceVersion, known, fn)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var retry *retryError
+		if !errors.As(err, &retry) {
+			return err
+		}
+		delay := logStreamRetryDelay
+		if retry.after > delay {
+			delay = retry.after
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// streamBuildsOnce reads the build watch over a single connection, updating resourceVersion as
+// events arrive. Started without a resource version, the stream begins with every matching build,
+// and known builds missing from them up to the first bookmark were deleted.
+func (c *Client) streamBuildsOnce(ctx context.Context, opts ListBuildsOptions, resourceVersion *string, known map[string]buildapi.BuildListItem, fn func(BuildEvent) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	q := listBuildsQuery(opts)
+	q.Set("watch", "true")
+	if *resourceVersion != "" {
+		q.Set("resourceVersion", *resourceVersion)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.resolve("/v1/builds")+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &retryError{err: err}
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusGone:
+		*resourceVersion = ""
+		return &retryError{err: errors.New("watch builds: the resource version expired")}
+	case resp.StatusCode != http.StatusOK:
+		return listBuildsError("watch builds", resp)
+	case !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json;stream=watch"):
+		return errWatchUnsupported
+	}
+
+	var initial map[string]bool
+	if *resourceVersion == "" {
+		initial = map[string]bool{}
+	}
+	// bookmarks arrive every 15 seconds; a connection silent for longer is gone
+	idle := time.AfterFunc(logStreamIdleTimeout, cancel)
+	defer idle.Stop()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		idle.Reset(logStreamIdleTimeout)
+		var ev buildapi.BuildWatchEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return fmt.Errorf("invalid build watch event: %w", err)
+		}
+		if ev.Type == "ERROR" {
+			return &retryError{err: fmt.Errorf("watch builds failed: %s", ev.Error)}
+		}
+		switch {
+		case ev.Type == "BOOKMARK" && initial != nil:
+			for key, b := range known {
+				if !initial[key] {
+					if err := report(known, BuildDeleted, b, fn); err != nil {
+						return err
+					}
+				}
+			}
+			initial = nil
+		case ev.Object == nil:
+		case ev.Type == "DELETED":
+			if err := report(known, BuildDeleted, *ev.Object, fn); err != nil {
+				return err
+			}
+		default:
+			if initial != nil {
+				initial[ev.Object.Namespace+"/"+ev.Object.Name] = true
+			}
+			if err := report(known, BuildModified, *ev.Object, fn); err != nil {
+				return err
+			}
+		}
+		// the initial builds are only complete with the bookmark after them
+		if initial == nil && ev.ResourceVersion != "" {
+			*resourceVersion = ev.ResourceVersion
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return &retryError{err: err}
+	}
+	return &retryError{err: io.ErrUnexpectedEOF}
+}
+
+// pollBuilds lists the builds every interval and reports the changes since the previous listing
+func (c *Client) pollBuilds(ctx context.Context, list ListBuildsOptions, interval time.Duration, known map[string]buildapi.BuildListItem, fn func(BuildEvent) error) error {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	for {
+		var items []buildapi.BuildListItem
+		err := c.ForEachBuild(ctx, list, func(b buildapi.BuildListItem) error {
+			items = append(items, b)
+			return nil
+		})
+		var retry *retryError
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil && !errors.As(err, &retry):
+			return err
+		case err == nil:
+			seen := make(map[string]bool, len(items))
+			// oldest first, so builds are reported in the order they were created
+			for i := len(items) - 1; i >= 0; i-- {
+				seen[items[i].Namespace+"/"+items[i].Name] = true
+				if err := report(known, BuildModified, items[i], fn); err != nil {
+					return err
+				}
+			}
+			for key, b := range known {
+				if !seen[key] {
+					if err := report(known, BuildDeleted, b, fn); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
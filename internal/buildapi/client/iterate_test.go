@@ -0,0 +1,370 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+)
+
+// fakeBuildAPI answers GET /v1/builds with handle and records the query of every request
+type fakeBuildAPI struct {
+	mu      sync.Mutex
+	queries []map[string]string
+	handle  func(w http.ResponseWriter, r *http.Request, n int)
+}
+
+func (f *fakeBuildAPI) start() *Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer GinkgoRecover()
+		Expect(r.URL.Path).To(Equal("/v1/builds"))
+		Expect(r.Header.Get("Authorization")).To(Equal("Bearer token"))
+		query := map[string]string{}
+		for k := range r.URL.Query() {
+			query[k] = r.URL.Query().Get(k)
+		}
+		f.mu.Lock()
+		f.queries = append(f.queries, query)
+		n := len(f.queries)
+		f.mu.Unlock()
+		f.handle(w, r, n)
+	}))
+	DeferCleanup(srv.Close)
+	c, err := New(srv.URL, WithAuthToken("token"))
+	Expect(err).NotTo(HaveOccurred())
+	return c
+}
+
+func (f *fakeBuildAPI) requests() []map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]map[string]string(nil), f.queries...)
+}
+
+func builds(names ...string) []buildapi.BuildListItem {
+	items := []buildapi.BuildListItem{}
+	for _, name := range names {
+		items = append(items, buildapi.BuildListItem{Name: name, Namespace: "ns", Phase: "Building"})
+	}
+	return items
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func names(items []buildapi.BuildListItem) []string {
+	var out []string
+	for _, b := range items {
+		out = append(out, b.Name)
+	}
+	return out
+}
+
+var _ = Describe("ForEachBuild", func() {
+	// pages are the builds of the pages, chained by the continue tokens page-1, page-2, ...
+	paged := func(pages ...[]string) *fakeBuildAPI {
+		f := &fakeBuildAPI{}
+		f.handle = func(w http.ResponseWriter, r *http.Request, _ int) {
+			i := 0
+			if token := r.URL.Query().Get("continue"); token != "" {
+				_, err := fmt.Sscanf(token, "page-%d", &i)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			if i+1 < len(pages) {
+				w.Header().Set("X-Continue", fmt.Sprintf("page-%d", i+1))
+			}
+			writeJSON(w, http.StatusOK, builds(pages[i]...))
+		}
+		return f
+	}
+
+	It("follows the continue token across pages", func() {
+		f := paged([]string{"e", "d"}, []string{"c", "b"}, []string{"a"})
+		c := f.start()
+
+		var seen []buildapi.BuildListItem
+		Expect(c.ForEachBuild(context.Background(), ListBuildsOptions{Limit: 2, Phases: []string{"Building"}}, func(b buildapi.BuildListItem) error {
+			seen = append(seen, b)
+			return nil
+		})).To(Succeed())
+		Expect(names(seen)).To(Equal([]string{"e", "d", "c", "b", "a"}))
+
+		requests := f.requests()
+		Expect(requests).To(HaveLen(3))
+		Expect(requests[0]).To(Equal(map[string]string{"limit": "2", "phase": "Building"}))
+		Expect(requests[1]).To(HaveKeyWithValue("continue", "page-1"))
+		Expect(requests[2]).To(HaveKeyWithValue("continue", "page-2"))
+		Expect(requests[2]).To(HaveKeyWithValue("phase", "Building"))
+	})
+
+	It("fetches pages of 100 builds from the given token", func() {
+		f := paged([]string{"c"}, []string{"b"}, []string{"a"})
+		c := f.start()
+		var seen []buildapi.BuildListItem
+		Expect(c.ForEachBuild(context.Background(), ListBuildsOptions{Continue: "page-1"}, func(b buildapi.BuildListItem) error {
+			seen = append(seen, b)
+			return nil
+		})).To(Succeed())
+		Expect(names(seen)).To(Equal([]string{"b", "a"}))
+		Expect(f.requests()[0]).To(Equal(map[string]string{"limit": "100", "continue": "page-1"}))
+	})
+
+	It("stops at ErrStop without fetching more pages and returns other errors of fn", func() {
+		f := paged([]string{"c", "b"}, []string{"a"})
+		c := f.start()
+		n := 0
+		Expect(c.ForEachBuild(context.Background(), ListBuildsOptions{}, func(buildapi.BuildListItem) error {
+			n++
+			return ErrStop
+		})).To(Succeed())
+		Expect(n).To(Equal(1))
+		Expect(f.requests()).To(HaveLen(1))
+
+		failed := errors.New("disk full")
+		Expect(c.ForEachBuild(context.Background(), ListBuildsOptions{}, func(buildapi.BuildListItem) error {
+			return failed
+		})).To(MatchError(failed))
+	})
+
+	DescribeTable("retries transient failures",
+		func(status int, retryAfter string) {
+			f := &fakeBuildAPI{}
+			f.handle = func(w http.ResponseWriter, _ *http.Request, n int) {
+				if n == 1 {
+					w.Header().Set("Retry-After", retryAfter)
+					writeJSON(w, status, map[string]string{"error": "try again"})
+					return
+				}
+				writeJSON(w, http.StatusOK, builds("a"))
+			}
+			c := f.start()
+			var seen []buildapi.BuildListItem
+			Expect(c.ForEachBuild(context.Background(), ListBuildsOptions{}, func(b buildapi.BuildListItem) error {
+				seen = append(seen, b)
+				return nil
+			})).To(Succeed())
+			Expect(names(seen)).To(Equal([]string{"a"}))
+			Expect(f.requests()).To(HaveLen(2))
+		},
+		Entry("rate limited", http.StatusTooManyRequests, "1"),
+		Entry("bad gateway", http.StatusBadGateway, ""),
+		Entry("unavailable", http.StatusServiceUnavailable, ""),
+		Entry("gateway timeout", http.StatusGatewayTimeout, ""),
+	)
+
+	It("waits for the Retry-After of rate limited requests", func() {
+		f := &fakeBuildAPI{}
+		f.handle = func(w http.ResponseWriter, _ *http.Request, n int) {
+			if n == 1 {
+				w.Header().Set("Retry-After", "1")
+				writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "slow down"})
+				return
+			}
+			writeJSON(w, http.StatusOK, builds())
+		}
+		c := f.start()
+		start := time.Now()
+		Expect(c.ForEachBuild(context.Background(), ListBuildsOptions{}, func(buildapi.BuildListItem) error { return nil })).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", time.Second))
+	})
+
+	DescribeTable("returns other failures at once",
+		func(status int) {
+			f := &fakeBuildAPI{}
+			f.handle = func(w http.ResponseWriter, _ *http.Request, _ int) {
+				w.Header().Set("X-Request-ID", "req-7")
+				writeJSON(w, status, map[string]string{"error": "no"})
+			}
+			c := f.start()
+			err := c.ForEachBuild(context.Background(), ListBuildsOptions{}, func(buildapi.BuildListItem) error { return nil })
+			var apiErr *APIError
+			Expect(errors.As(err, &apiErr)).To(BeTrue())
+			Expect(apiErr.StatusCode).To(Equal(status))
+			Expect(apiErr.Message).To(Equal("no"))
+			Expect(RequestID(err)).To(Equal("req-7"))
+			Expect(f.requests()).To(HaveLen(1))
+		},
+		Entry("bad request", http.StatusBadRequest),
+		Entry("unauthorized", http.StatusUnauthorized),
+		Entry("forbidden", http.StatusForbidden),
+		Entry("not found", http.StatusNotFound),
+		// failures of the build API itself are not transient
+		Entry("internal error", http.StatusInternalServerError),
+	)
+
+	It("gives up after maxRetries retries", func() {
+		f := &fakeBuildAPI{}
+		f.handle = func(w http.ResponseWriter, _ *http.Request, _ int) {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "draining"})
+		}
+		c := f.start()
+		err := c.ForEachBuild(context.Background(), ListBuildsOptions{}, func(buildapi.BuildListItem) error { return nil })
+		Expect(err).To(MatchError(ContainSubstring("draining")))
+		Expect(f.requests()).To(HaveLen(maxRetries + 1))
+	})
+
+	It("stops retrying when the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		f := &fakeBuildAPI{}
+		f.handle = func(w http.ResponseWriter, _ *http.Request, n int) {
+			if n == 2 {
+				cancel()
+			}
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "draining"})
+		}
+		c := f.start()
+		err := c.ForEachBuild(ctx, ListBuildsOptions{}, func(buildapi.BuildListItem) error { return nil })
+		Expect(err).To(HaveOccurred())
+		Expect(f.requests()).To(HaveLen(2))
+	})
+
+	It("stops between pages when the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		f := paged([]string{"b"}, []string{"a"})
+		c := f.start()
+		err := c.ForEachBuild(ctx, ListBuildsOptions{}, func(buildapi.BuildListItem) error {
+			cancel()
+			return nil
+		})
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue(), "%v", err)
+		Expect(f.requests()).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("WatchBuilds", func() {
+	// collect returns the events of a watch, which is stopped after n events
+	collect := func(c *Client, opts WatchBuildsOptions, n int) []string {
+		var events []string
+		Expect(c.WatchBuilds(context.Background(), opts, func(ev BuildEvent) error {
+			events = append(events, fmt.Sprintf("%s %s %s", ev.Type, ev.Build.Name, ev.Build.Phase))
+			if len(events) == n {
+				return ErrStop
+			}
+			return nil
+		})).To(Succeed())
+		return events
+	}
+	// stream writes watch events and closes the connection
+	stream := func(w http.ResponseWriter, events ...buildapi.BuildWatchEvent) {
+		w.Header().Set("Content-Type", "application/json;stream=watch")
+		w.WriteHeader(http.StatusOK)
+		for _, ev := range events {
+			Expect(json.NewEncoder(w).Encode(ev)).To(Succeed())
+		}
+	}
+	event := func(eventType, name, phase, resourceVersion string) buildapi.BuildWatchEvent {
+		ev := buildapi.BuildWatchEvent{Type: eventType, ResourceVersion: resourceVersion}
+		if name != "" {
+			ev.Object = &buildapi.BuildListItem{Name: name, Namespace: "ns", Phase: phase}
+		}
+		return ev
+	}
+
+	It("reports the streamed changes and resumes after the last one", func() {
+		f := &fakeBuildAPI{}
+		f.handle = func(w http.ResponseWriter, _ *http.Request, n int) {
+			switch n {
+			case 1:
+				stream(w, event("ADDED", "a", "Building", "5"), event("BOOKMARK", "", "", "6"),
+					event("MODIFIED", "a", "Completed", "7"))
+			default:
+				stream(w, event("DELETED", "a", "Completed", "8"))
+			}
+		}
+		c := f.start()
+		known := builds("gone")
+		Expect(collect(c, WatchBuildsOptions{Known: known}, 4)).To(Equal([]string{
+			"Added a Building", "Deleted gone Building", "Modified a Completed", "Deleted a Completed",
+		}))
+		requests := f.requests()
+		Expect(requests[0]).To(Equal(map[string]string{"watch": "true"}))
+		Expect(requests[1]).To(Equal(map[string]string{"watch": "true", "resourceVersion": "7"}))
+	})
+
+	It("starts over when the resource version expired", func() {
+		f := &fakeBuildAPI{}
+		f.handle = func(w http.ResponseWriter, _ *http.Request, n int) {
+			switch n {
+			case 1:
+				stream(w, event("BOOKMARK", "", "", "6"))
+			case 2:
+				writeJSON(w, http.StatusGone, map[string]string{"error": "too old"})
+			default:
+				stream(w, event("ADDED", "a", "Building", "9"), event("BOOKMARK", "", "", "9"))
+			}
+		}
+		c := f.start()
+		Expect(collect(c, WatchBuildsOptions{}, 1)).To(Equal([]string{"Added a Building"}))
+		requests := f.requests()
+		Expect(requests[1]).To(HaveKeyWithValue("resourceVersion", "6"))
+		Expect(requests[2]).NotTo(HaveKey("resourceVersion"))
+	})
+
+	It("retries transient failures and returns others", func() {
+		f := &fakeBuildAPI{}
+		f.handle = func(w http.ResponseWriter, _ *http.Request, n int) {
+			switch n {
+			case 1:
+				writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "draining"})
+			case 2:
+				stream(w, event("ERROR", "", "", ""))
+			default:
+				writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			}
+		}
+		c := f.start()
+		err := c.WatchBuilds(context.Background(), WatchBuildsOptions{}, func(BuildEvent) error { return nil })
+		Expect(err).To(MatchError(ContainSubstring("forbidden")))
+		Expect(f.requests()).To(HaveLen(3))
+	})
+
+	It("lists the builds of a build API that cannot stream them", func() {
+		f := &fakeBuildAPI{}
+		f.handle = func(w http.ResponseWriter, r *http.Request, n int) {
+			switch n {
+			case 1, 2:
+				// the watch is answered with the list
+				writeJSON(w, http.StatusOK, builds("b", "a"))
+			default:
+				items := builds("c", "a")
+				items[1].Phase = "Completed"
+				writeJSON(w, http.StatusOK, items)
+			}
+		}
+		c := f.start()
+		Expect(collect(c, WatchBuildsOptions{Interval: time.Millisecond}, 5)).To(ConsistOf(
+			"Added a Building", "Added b Building",
+			"Modified a Completed", "Added c Building", "Deleted b Building",
+		))
+		requests := f.requests()
+		Expect(requests[0]).To(HaveKeyWithValue("watch", "true"))
+		Expect(requests[1]).NotTo(HaveKey("watch"))
+	})
+
+	It("returns when the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		f := &fakeBuildAPI{}
+		f.handle = func(w http.ResponseWriter, r *http.Request, _ int) {
+			w.Header().Set("Content-Type", "application/json;stream=watch")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			cancel()
+			<-r.Context().Done()
+		}
+		c := f.start()
+		err := c.WatchBuilds(ctx, WatchBuildsOptions{}, func(BuildEvent) error { return nil })
+		Expect(err).To(MatchError(context.Canceled))
+	})
+})
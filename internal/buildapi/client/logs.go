@@ -0,0 +1,200 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrLogsNotReady is returned by StreamLogs when the build has no logs to stream yet
+var ErrLogsNotReady = errors.New("logs not available yet")
+
+// maxLogResumeAttempts is how often StreamLogs reconnects without receiving a line before it
+// gives up
+const maxLogResumeAttempts = 10
+
+// LogPosition is how far a plain log stream was read: the lines of Step received completely
+type LogPosition struct {
+	Step string
+	Line int
+}
+
+// String returns the position as the since parameter of the logs endpoint
+func (p LogPosition) String() string {
+	return fmt.Sprintf("%s:%d", p.Step, p.Line)
+}
+
+// StreamLogs copies the plain log stream of a build to w until the server reports that streaming
+// completed. When the connection drops it reconnects with ?since= and continues after the last
+// complete line received, so lines are neither written twice nor lost. It returns ErrLogsNotReady
+// when the build has no logs before the first line arrived, and gives up after
+// maxLogResumeAttempts reconnects that received nothing.
+func (c *Client) StreamLogs(ctx context.Context, name string, w io.Writer) error {
+	lc := &logCopier{w: w}
+	attempts := 0
+	for {
+		before := lc.pos
+		ended, err := c.streamLogsOnce(ctx, name, lc)
+		if ended || ctx.Err() != nil {
+			lc.flushHeld()
+			return err
+		}
+		var retry *retryError
+		if err != nil && !errors.As(err, &retry) {
+			lc.flushHeld()
+			return err
+		}
+		if lc.pos != before {
+			attempts = 0
+		}
+		if attempts++; attempts > maxLogResumeAttempts {
+			lc.flushHeld()
+			return fmt.Errorf("log stream lost after %d attempts to resume at %s: %w", maxLogResumeAttempts, lc.pos, err)
+		}
+		delay := logStreamRetryDelay
+		if retry != nil && retry.after > delay {
+			delay = retry.after
+		}
+		select {
+		case <-ctx.Done():
+			lc.flushHeld()
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// streamLogsOnce reads the plain log stream over a single connection, resuming at the position of
+// lc. It reports whether the server completed the stream.
+func (c *Client) streamLogsOnce(ctx context.Context, name string, lc *logCopier) (bool, error) {
+	q := url.Values{"follow": {"1"}}
+	if lc.pos.Step != "" {
+		q.Set("since", lc.pos.String())
+	}
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "logs")) + "?" + q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, &retryError{err: err}
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if lc.pos.Step == "" {
+			return false, fmt.Errorf("%w: %s: %s", ErrLogsNotReady, resp.Status, strings.TrimSpace(string(b)))
+		}
+		return false, &retryError{err: fmt.Errorf("stream logs failed: %s", resp.Status)}
+	case http.StatusTooManyRequests:
+		return false, &retryError{err: fmt.Errorf("stream logs failed: %s", resp.Status), after: RetryAfter(resp)}
+	default:
+		return false, apiError("stream logs", resp)
+	}
+
+	br := bufio.NewReader(resp.Body)
+	for {
+		text, err := br.ReadString('\n')
+		if err != nil {
+			// The rest of a line cut off by the drop is sent again after the resume
+			lc.partial(text)
+			if errors.Is(err, io.EOF) {
+				err = errors.New("log stream ended before it completed")
+			}
+			return false, &retryError{err: err}
+		}
+		if ended, err := lc.line(strings.TrimSuffix(text, "\n")); ended || err != nil {
+			return ended, err
+		}
+	}
+}
+
+// logCopier writes the lines of a plain log stream and keeps track of the position to resume at.
+// The newline of every line is held back until the next line arrives: the server ends a step it
+// failed to stream with "\n[Stream error: ...]", whose newline does not belong to the log and may
+// cut a line in two.
+type logCopier struct {
+	w   io.Writer
+	pos LogPosition
+	// held is set when the last line was written without its newline, counted in pos once written
+	held, heldCounts bool
+	// sent is what was written of the line the stream resumes in
+	sent string
+	// banner is set after a step banner, whose blank line is not part of the log
+	banner bool
+}
+
+// line writes a complete line of the stream and reports whether it ended the stream
+func (lc *logCopier) line(text string) (bool, error) {
+	switch {
+	case text == "[Log streaming completed]":
+		lc.flushHeld()
+		_, _ = io.WriteString(lc.w, text+"\n")
+		return true, nil
+	case strings.HasPrefix(text, "[Stream error: ") || strings.HasPrefix(text, "[Error: "):
+		// The held line was cut short by the server; it is sent again from its start
+		lc.held = false
+		return false, &retryError{err: errors.New(strings.Trim(text, "[]"))}
+	}
+	lc.flushHeld()
+	lc.write(text)
+	lc.held = true
+	switch step, ok := strings.CutPrefix(text, "===== Logs from "); {
+	case ok && strings.HasSuffix(step, " ====="):
+		lc.pos = LogPosition{Step: strings.TrimSuffix(step, " =====")}
+		lc.banner = true
+		lc.heldCounts = false
+	case lc.banner && text == "":
+		lc.banner = false
+		lc.heldCounts = false
+	default:
+		lc.banner = false
+		lc.heldCounts = lc.pos.Step != ""
+	}
+	return false, nil
+}
+
+// partial writes the start of a line the connection dropped in
+func (lc *logCopier) partial(text string) {
+	if text == "" {
+		return
+	}
+	lc.flushHeld()
+	lc.write(text)
+}
+
+// write writes the part of text not written before the stream resumed
+func (lc *logCopier) write(text string) {
+	if rest, ok := strings.CutPrefix(text, lc.sent); ok {
+		_, _ = io.WriteString(lc.w, rest)
+	} else {
+		_, _ = io.WriteString(lc.w, "\n"+text)
+	}
+	lc.sent = text
+}
+
+// flushHeld writes the newline of the held line
+func (lc *logCopier) flushHeld() {
+	if !lc.held {
+		return
+	}
+	_, _ = io.WriteString(lc.w, "\n")
+	if lc.heldCounts {
+		lc.pos.Line++
+	}
+	lc.held = false
+	lc.sent = ""
+}
@@ -0,0 +1,308 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+)
+
+// supportedAPIVersions are the REST API versions this client speaks, preferred first
+var supportedAPIVersions = []string{"v2", "v1"}
+
+// APIVersion negotiates the REST API version used by the V2 methods: the first of the versions
+// this client supports that the server lists in GET /v1/info, v1 for servers that list none. The
+// answer is kept for the life of the client.
+func (c *Client) APIVersion(ctx context.Context) (string, error) {
+	c.versionMu.Lock()
+	defer c.versionMu.Unlock()
+	if c.apiVersion != "" {
+		return c.apiVersion, nil
+	}
+	info, err := c.GetInfo(ctx)
+	if err != nil {
+		return "", fmt.Errorf("negotiating the API version: %w", err)
+	}
+	c.apiVersion = "v1"
+	for _, v := range supportedAPIVersions {
+		if slices.Contains(info.APIVersions, v) {
+			c.apiVersion = v
+			break
+		}
+	}
+	return c.apiVersion, nil
+}
+
+// ProblemError is an error answered by the v2 API with RFC 7807 problem details. Use errors.As
+// to tell problems apart by their Type, e.g. buildapi.ProblemTypeNotFound.
+type ProblemError struct {
+	buildapi.Problem
+	// Op is the operation that failed, e.g. "get build"
+	Op string
+}
+
+func (e *ProblemError) Error() string {
+	msg := e.Detail
+	if msg == "" {
+		msg = e.Title
+	}
+	msg = fmt.Sprintf("%s failed: %d %s: %s", e.Op, e.Status, http.StatusText(e.Status), msg)
+	if e.RequestID != "" {
+		msg += " (request ID " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// IsProblem reports whether err is a ProblemError of problemType
+func IsProblem(err error, problemType string) bool {
+	var pe *ProblemError
+	return errors.As(err, &pe) && pe.Type == problemType
+}
+
+// problemError reads the error response of a v2 request. Bodies that are not problem details,
+// e.g. from a proxy, become a ProblemError of type about:blank with the start of the body.
+func problemError(op string, resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	pe := &ProblemError{Op: op}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "application/problem+json" || json.Unmarshal(b, &pe.Problem) != nil {
+		detail := b
+		if len(detail) > 1024 {
+			detail = detail[:1024]
+		}
+		pe.Problem = buildapi.Problem{Type: "about:blank", Title: http.StatusText(resp.StatusCode), Detail: string(bytes.TrimSpace(detail))}
+	}
+	pe.Status = resp.StatusCode
+	if pe.RequestID == "" {
+		pe.RequestID = responseRequestID(resp)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return &retryError{err: pe, after: RetryAfter(resp)}
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return &retryError{err: pe}
+	}
+	return pe
+}
+
+// doV2 sends a request to the v2 API and decodes its response into out
+func (c *Client) doV2(ctx context.Context, op, method, endpoint string, body any, header http.Header, out any, statuses ...int) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if !slices.Contains(statuses, resp.StatusCode) {
+		return problemError(op, resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// GetBuildV2 returns a build as the v2 API describes it. Servers that only serve v1 are asked
+// with GET /v1/builds/{name}, whose response lacks the namespace, metadata, spec and creation
+// time of the build.
+func (c *Client) GetBuildV2(ctx context.Context, name string) (*buildapi.BuildV2, error) {
+	version, err := c.APIVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version == "v1" {
+		resp, err := c.GetBuild(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return buildV2FromV1(resp), nil
+	}
+	var out buildapi.BuildV2
+	endpoint := c.resolve(path.Join("/v2/builds", url.PathEscape(name)))
+	if err := c.doV2(ctx, "get build", http.MethodGet, endpoint, nil, nil, &out, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListBuildsV2 returns one page of builds matching opts as the v2 API describes them. Servers
+// that only serve v1 are asked with GET /v1/builds, whose builds lack their spec and artifacts.
+func (c *Client) ListBuildsV2(ctx context.Context, opts ListBuildsOptions) (*buildapi.BuildListV2, error) {
+	version, err := c.APIVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version == "v1" {
+		page, err := c.ListBuildsPage(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		out := &buildapi.BuildListV2{
+			Items:           make([]buildapi.BuildV2, 0, len(page.Items)),
+			TotalCount:      page.Total,
+			Continue:        page.Continue,
+			ResourceVersion: page.ResourceVersion,
+		}
+		for _, item := range page.Items {
+			out.Items = append(out.Items, buildListItemV2(item))
+		}
+		return out, nil
+	}
+	endpoint := c.resolve("/v2/builds")
+	if q := listBuildsQuery(opts); len(q) > 0 {
+		endpoint += "?" + q.Encode()
+	}
+	var out buildapi.BuildListV2
+	if err := c.doV2(ctx, "list builds", http.MethodGet, endpoint, nil, nil, &out, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateBuildV2 creates a build, or returns the build an earlier request with idempotencyKey
+// created, as the v2 API describes it. Servers that only serve v1 are asked with POST /v1/builds
+// followed by GetBuildV2.
+func (c *Client) CreateBuildV2(ctx context.Context, req buildapi.BuildRequest, idempotencyKey string) (*buildapi.BuildV2, error) {
+	version, err := c.APIVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version == "v1" {
+		resp, err := c.CreateBuildWithKey(ctx, req, idempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+		return c.GetBuildV2(ctx, resp.Name)
+	}
+	header := http.Header{}
+	if idempotencyKey != "" {
+		header.Set("Idempotency-Key", idempotencyKey)
+	}
+	var out buildapi.BuildV2
+	if err := c.doV2(ctx, "create build", http.MethodPost, c.resolve("/v2/builds"), req, header, &out, http.StatusCreated, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// buildV2FromV1 converts the v1 description of a build
+func buildV2FromV1(b *buildapi.BuildResponse) *buildapi.BuildV2 {
+	out := &buildapi.BuildV2{
+		Name:               b.Name,
+		Phase:              phaseV2(b.Phase),
+		Message:            b.Message,
+		RequestedBy:        b.RequestedBy,
+		RequestID:          b.RequestID,
+		Priority:           b.Priority,
+		Source:             b.Source,
+		GitCommit:          b.GitCommit,
+		QueuePosition:      b.QueuePosition,
+		StartedAt:          parseTimeV1(b.StartTime),
+		CompletedAt:        parseTimeV1(b.CompletionTime),
+		StageTimings:       b.StageTimings,
+		ComplianceResult:   b.ComplianceResult,
+		BootResult:         b.BootResult,
+		RootFSBytes:        b.RootFSBytes,
+		SizeBudgetBytes:    b.SizeBudgetBytes,
+		ArtifactsDeletedAt: parseTimeV1(b.ArtifactsDeletedAt),
+		ArtifactsExpireAt:  parseTimeV1(b.ArtifactsExpireAt),
+	}
+	if d := b.Downloads; d != nil {
+		out.Downloads = d.Count
+		out.LastDownloadAt = parseTimeV1(d.LastDownloadAt)
+	}
+	if out.ArtifactsDeletedAt != nil {
+		return out
+	}
+	for _, f := range []struct{ kind, name string }{
+		{"artifact", b.ArtifactFileName},
+		{"first-boot", b.FirstBootFileName},
+		{"hardening-report", b.HardeningReportFileName},
+		{"packages", b.PackagesFileName},
+		{"size-report", b.SizeReportFileName},
+		{"boot-log", b.BootConsoleLogFileName},
+	} {
+		if f.name == "" || (f.kind == "artifact" && out.Phase != buildapi.BuildPhaseCompleted) {
+			continue
+		}
+		out.Artifacts = append(out.Artifacts, buildapi.BuildArtifact{
+			Name:      f.name,
+			Kind:      f.kind,
+			Path:      path.Join("/v1/builds", b.Name, "artifact", url.PathEscape(f.name)),
+			MediaType: "application/octet-stream",
+		})
+	}
+	return out
+}
+
+// buildListItemV2 converts a build of the v1 build list. The v1 list says that the artifacts
+// were deleted but not when; ArtifactsDeletedAt is then the zero time.
+func buildListItemV2(item buildapi.BuildListItem) buildapi.BuildV2 {
+	out := buildapi.BuildV2{
+		Name:        item.Name,
+		Namespace:   item.Namespace,
+		Phase:       phaseV2(item.Phase),
+		Message:     item.Message,
+		RequestedBy: item.RequestedBy,
+		Labels:      item.Labels,
+		StartedAt:   parseTimeV1(item.StartTime),
+		CompletedAt: parseTimeV1(item.CompletionTime),
+		Downloads:   item.Downloads,
+	}
+	if t := parseTimeV1(item.CreatedAt); t != nil {
+		out.CreatedAt = *t
+	}
+	out.LastDownloadAt = parseTimeV1(item.LastDownloadAt)
+	if item.ExpiresAt == "deleted" {
+		out.ArtifactsDeletedAt = &time.Time{}
+	} else {
+		out.ArtifactsExpireAt = parseTimeV1(item.ExpiresAt)
+	}
+	return out
+}
+
+// phaseV2 converts a v1 phase, which is empty for builds the controller has not picked up yet
+func phaseV2(phase string) buildapi.BuildPhase {
+	if phase == "" {
+		return buildapi.BuildPhasePending
+	}
+	return buildapi.BuildPhase(phase)
+}
+
+// parseTimeV1 converts an RFC 3339 time of the v1 API, nil when it is empty or malformed
+func parseTimeV1(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+)
+
+// ErrBuildWatchUnsupported is returned by WatchBuild when the server has no build status stream
+var ErrBuildWatchUnsupported = errors.New("server does not support build status streams")
+
+// BuildStatusEvent is an event of the status stream of a build
+type BuildStatusEvent struct {
+	// Type is status, end once the build finished, or deleted
+	Type string
+	// Build is the build as GetBuild returns it; the last status sent for deleted builds
+	Build buildapi.BuildResponse
+}
+
+// WatchBuild streams the status of a build to fn: once when the stream starts and whenever its
+// phase or message change, until the build finished (an end event) or was deleted. When the
+// connection drops or stays idle it reconnects, and fn receives the current status again if it
+// changed meanwhile. Error events are followed by a reconnect; an error returned by fn stops
+// watching and is returned, except ErrStop.
+func (c *Client) WatchBuild(ctx context.Context, name string, fn func(BuildStatusEvent) error) error {
+	var last *buildapi.BuildResponse
+	for {
+		ended, err := c.watchBuildOnce(ctx, name, &last, fn)
+		if errors.Is(err, ErrStop) {
+			return nil
+		}
+		if ended || ctx.Err() != nil {
+			return err
+		}
+		var retry *retryError
+		if err != nil && !errors.As(err, &retry) {
+			return err
+		}
+		delay := logStreamRetryDelay
+		if retry != nil && retry.after > delay {
+			delay = retry.after
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// watchBuildOnce reads the status stream over a single connection, skipping the status fn already
+// received in last. It reports whether the stream ended with an end or deleted event.
+func (c *Client) watchBuildOnce(ctx context.Context, name string, last **buildapi.BuildResponse, fn func(BuildStatusEvent) error) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	endpoint := c.resolve(path.Join("/v1/builds", url.PathEscape(name), "watch"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, &retryError{err: err}
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusOK && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream"):
+	case resp.StatusCode == http.StatusOK:
+		return false, ErrBuildWatchUnsupported
+	case resp.StatusCode == http.StatusNotFound && !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json"):
+		// Unknown routes are answered in plain text, missing builds in JSON
+		return false, ErrBuildWatchUnsupported
+	case resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout:
+		return false, &retryError{err: fmt.Errorf("watch build failed: %s", resp.Status)}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return false, &retryError{err: fmt.Errorf("watch build failed: %s", resp.Status), after: RetryAfter(resp)}
+	default:
+		return false, apiError("watch build", resp)
+	}
+
+	idle := time.AfterFunc(logStreamIdleTimeout, cancel)
+	defer idle.Stop()
+	err = readEvents(resp.Body, idle, func(eventType, _, data string) error {
+		if eventType == "error" {
+			var e struct {
+				Error string `json:"error"`
+			}
+			_ = json.Unmarshal([]byte(data), &e)
+			return &retryError{err: fmt.Errorf("watch build failed: %s", e.Error)}
+		}
+		ev := BuildStatusEvent{Type: eventType}
+		if err := json.Unmarshal([]byte(data), &ev.Build); err != nil {
+			return fmt.Errorf("invalid build status event: %w", err)
+		}
+		if prev := *last; ev.Type == "status" && prev != nil && prev.Phase == ev.Build.Phase && prev.Message == ev.Build.Message {
+			// the status the stream starts with after a reconnect
+			return nil
+		}
+		*last = &ev.Build
+		if err := fn(ev); err != nil {
+			return err
+		}
+		if ev.Type == "end" || ev.Type == "deleted" {
+			return errStreamEnded
+		}
+		return nil
+	})
+	if errors.Is(err, errStreamEnded) {
+		return true, nil
+	}
+	return false, err
+}
@@ -0,0 +1,196 @@
+package buildapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/comparison"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/commitstatus"
+)
+
+// commitSHAPattern matches full and abbreviated commit hashes, SHA-1 or SHA-256
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-f]{7,64}$`)
+
+// commitStatusFromRequest validates the CI metadata of a request and converts it to its ImageBuild form
+func commitStatusFromRequest(req *CommitStatus) (*automotivev1alpha1.CommitStatus, error) {
+	if req == nil {
+		return nil, nil
+	}
+	cs := &automotivev1alpha1.CommitStatus{
+		Provider:     strings.ToLower(strings.TrimSpace(req.Provider)),
+		Repository:   strings.Trim(strings.TrimSpace(req.Repository), "/"),
+		SHA:          strings.ToLower(strings.TrimSpace(req.SHA)),
+		Branch:       strings.TrimSpace(req.Branch),
+		TargetBranch: strings.TrimSpace(req.TargetBranch),
+		Context:      strings.TrimSpace(req.Context),
+	}
+	switch cs.Provider {
+	case commitstatus.ProviderGitHub:
+		owner, name, ok := strings.Cut(cs.Repository, "/")
+		if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("commitStatus repository %q must be owner/name on GitHub", req.Repository)
+		}
+	case commitstatus.ProviderGitLab:
+		if cs.Repository == "" {
+			return nil, fmt.Errorf("commitStatus repository must be the path of the GitLab project")
+		}
+	default:
+		return nil, fmt.Errorf("commitStatus provider must be github or gitlab")
+	}
+	if !commitSHAPattern.MatchString(cs.SHA) {
+		return nil, fmt.Errorf("commitStatus sha %q must be a commit hash", req.SHA)
+	}
+	if cs.Branch != "" && cs.TargetBranch == cs.Branch {
+		return nil, fmt.Errorf("commitStatus targetBranch must differ from branch")
+	}
+	return cs, nil
+}
+
+// commitStatusGranted rejects cs when the OperatorConfig does not grant namespace statuses on its
+// repository; the controller checks again before posting
+func commitStatusGranted(ctx context.Context, namespace string, cs *automotivev1alpha1.CommitStatus) error {
+	if cs == nil {
+		return nil
+	}
+	k8sClient, err := serviceClient()
+	if err != nil {
+		return err
+	}
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: "config", Namespace: resolveNamespace()}, operatorConfig); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if !comparison.RepositoryGranted(operatorConfig.Spec.CommitStatus, namespace, cs.Repository) {
+		return &notGrantedError{reason: fmt.Sprintf("commitStatus repository %s is not granted to namespace %s by spec.commitStatus.repositories of the OperatorConfig", cs.Repository, namespace)}
+	}
+	return nil
+}
+
+// notGrantedError rejects a request asking for what the OperatorConfig does not grant, e.g.
+// statuses on a repository or downloads from a host
+type notGrantedError struct {
+	reason string
+}
+
+func (e *notGrantedError) Error() string {
+	return e.reason
+}
+
+// commitStatusToRequest converts the CI metadata of an ImageBuild back to its API form
+func commitStatusToRequest(spec *automotivev1alpha1.CommitStatus) *CommitStatus {
+	if spec == nil {
+		return nil
+	}
+	return &CommitStatus{
+		Provider:     spec.Provider,
+		Repository:   spec.Repository,
+		SHA:          spec.SHA,
+		Branch:       spec.Branch,
+		TargetBranch: spec.TargetBranch,
+		Context:      spec.Context,
+	}
+}
+
+// postedCommitStatus converts status.commitStatus of build for the API
+func postedCommitStatus(build *automotivev1alpha1.ImageBuild) *PostedCommitStatus {
+	st := build.Status.CommitStatus
+	if st == nil {
+		return nil
+	}
+	posted := &PostedCommitStatus{State: st.State, Description: st.Description, GoldenBuild: st.GoldenBuild, Error: st.Error}
+	if st.UpdateTime != nil {
+		posted.UpdatedAt = st.UpdateTime.UTC().Format(time.RFC3339)
+	}
+	return posted
+}
+
+func (a *APIServer) handleGetBuildComparison(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("build comparison requested", "build", name, "reqID", c.GetString("reqID"))
+	getBuildComparison(c, name)
+}
+
+// getBuildComparison compares a build with spec.commitStatus with the golden build of its target
+// branch, or of the branch parameter, as its commit status does once the build completed
+func getBuildComparison(c *gin.Context, name string) {
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	ctx := c.Request.Context()
+	namespace := requestNamespace(c)
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+	if build.Spec.CommitStatus == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "the build has no commitStatus to find the golden build of its repository with"})
+		return
+	}
+	branch := strings.TrimSpace(c.Query("branch"))
+	if branch == "" {
+		branch = build.Spec.CommitStatus.TargetBranch
+	}
+	if branch == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the build has no commitStatus targetBranch; name the branch to compare with"})
+		return
+	}
+	builds := &automotivev1alpha1.ImageBuildList{}
+	if err := k8sClient.List(ctx, builds, client.InNamespace(namespace)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error listing builds: %v", err)})
+		return
+	}
+	writeJSON(c, http.StatusOK, buildComparison(build, branch, comparison.GoldenBuild(build, branch, builds.Items)))
+}
+
+// buildComparison compares build with golden, the golden build of branch or nil
+func buildComparison(build *automotivev1alpha1.ImageBuild, branch string, golden *automotivev1alpha1.ImageBuild) BuildComparison {
+	measured := comparison.Measure(build)
+	out := BuildComparison{Build: build.Name, Branch: branch, Measurements: buildMeasurements(measured)}
+	outcome := build.Status.Phase
+	if outcome == "" {
+		outcome = "Pending"
+	}
+	if golden == nil {
+		out.Description = commitstatus.Description(outcome, measured, nil, branch)
+		return out
+	}
+	goldenMeasured := comparison.Measure(golden)
+	goldenOut := buildMeasurements(goldenMeasured)
+	out.GoldenBuild = golden.Name
+	out.Golden = &goldenOut
+	deltas := commitstatus.Compare(measured, goldenMeasured)
+	out.ArtifactBytesDelta = deltas.ArtifactBytes
+	out.RootFSBytesDelta = deltas.RootFSBytes
+	if deltas.Boot != nil {
+		seconds := deltas.Boot.Seconds()
+		out.BootSecondsDelta = &seconds
+	}
+	out.Description = commitstatus.Description(outcome, measured, &goldenMeasured, branch)
+	return out
+}
+
+func buildMeasurements(m commitstatus.Measurements) BuildMeasurements {
+	return BuildMeasurements{
+		ArtifactBytes:   m.ArtifactBytes,
+		RootFSBytes:     m.RootFSBytes,
+		BootSeconds:     m.Boot.Seconds(),
+		BootAccelerator: m.BootAccelerator,
+	}
+}
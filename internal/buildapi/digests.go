@@ -0,0 +1,146 @@
+package buildapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+// digestRecordTimeout bounds recording the digests of served files in the status of their build
+const digestRecordTimeout = 30 * time.Second
+
+// filesFinal reports whether the files of build no longer change, so their digests can be kept
+func filesFinal(build *automotivev1alpha1.ImageBuild) bool {
+	return build.Status.Phase == "Completed" || build.Status.Phase == "Failed"
+}
+
+// artifactStatCommand is the command that prints the size of the file at podPath in the artifact
+// pod, followed by its SHA-256 when hash is set, or MISSING. The path is an argument of the script
+// so it needs no quoting.
+func artifactStatCommand(podPath string, hash bool) []string {
+	script := `[ -f "$1" ] || { echo MISSING; exit 0; }; wc -c < "$1"`
+	if hash {
+		script += `; sha256sum "$1" | cut -d" " -f1`
+	}
+	return []string{"sh", "-c", script, "sh", podPath}
+}
+
+// parseArtifactStat reads the output of artifactStatCommand. ok is false for missing files.
+func parseArtifactStat(out string) (size int64, sum string, ok bool) {
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return 0, "", false
+	}
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	if len(fields) > 1 && len(fields[1]) == 64 {
+		sum = fields[1]
+	}
+	return size, sum, true
+}
+
+// needsDigest reports whether the digest of a file of build has to be computed while serving it
+func needsDigest(build *automotivev1alpha1.ImageBuild, fileName string) bool {
+	return filesFinal(build) && artifactDigest(build, fileName) == ""
+}
+
+// recordArtifactDigests keeps the digests of files of a build in its status, so their entity tags
+// stay content digests across restarts and replicas of the build API. Files with a known digest
+// are skipped; the response is not held up by it.
+func (a *APIServer) recordArtifactDigests(build *automotivev1alpha1.ImageBuild, digests map[string]string) {
+	if !filesFinal(build) {
+		return
+	}
+	missing := map[string]string{}
+	for file, sum := range digests {
+		if sum != "" && artifactDigest(build, file) != sum {
+			missing[file] = sum
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	key := types.NamespacedName{Name: build.Name, Namespace: build.Namespace}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), digestRecordTimeout)
+		defer cancel()
+		if err := saveArtifactDigests(ctx, key, missing); err != nil {
+			a.log.Error(err, "failed to record artifact digests", "build", key.Name, "files", len(missing))
+		}
+	}()
+}
+
+// learnArtifactDigest records the digest of a file computed while serving it and uses it for the
+// response
+func (a *APIServer) learnArtifactDigest(build *automotivev1alpha1.ImageBuild, fileName, sum string) {
+	if sum == "" {
+		return
+	}
+	a.recordArtifactDigests(build, map[string]string{fileName: sum})
+	if build.Status.ArtifactDigests == nil {
+		build.Status.ArtifactDigests = map[string]string{}
+	}
+	build.Status.ArtifactDigests[fileName] = sum
+}
+
+// saveArtifactDigests adds digests to status.artifactDigests of the build key
+func saveArtifactDigests(ctx context.Context, key types.NamespacedName, digests map[string]string) error {
+	k8sClient, err := serviceClient()
+	if err != nil {
+		return err
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		build := &automotivev1alpha1.ImageBuild{}
+		if err := k8sClient.Get(ctx, key, build); err != nil {
+			return err
+		}
+		if build.Status.ArtifactDigests == nil {
+			build.Status.ArtifactDigests = map[string]string{}
+		}
+		for file, sum := range digests {
+			build.Status.ArtifactDigests[file] = sum
+		}
+		return k8sClient.Status().Update(ctx, build)
+	})
+}
+
+// notModified reports whether a GET or HEAD request is conditional on the version of a file the
+// client holds already: If-None-Match lists etag, compared weakly, or, without If-None-Match,
+// If-Modified-Since is not before modified (RFC 9110, section 13.2.2)
+func notModified(c *gin.Context, etag string, modified time.Time) bool {
+	if m := c.Request.Method; m != http.MethodGet && m != http.MethodHead {
+		return false
+	}
+	if header := c.GetHeader("If-None-Match"); header != "" {
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || (etag != "" && strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/")) {
+				return true
+			}
+		}
+		return false
+	}
+	if header := c.GetHeader("If-Modified-Since"); header != "" && !modified.IsZero() {
+		if t, err := http.ParseTime(header); err == nil && !modified.After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeNotModified answers a conditional request with 304 and the validators already set
+func writeNotModified(c *gin.Context) {
+	c.Writer.Header().Del("Content-Length")
+	c.Status(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+}
@@ -0,0 +1,177 @@
+package buildapi
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+const (
+	// maxDownloadUsers and maxRecentDownloads bound the download records kept in the build status
+	maxDownloadUsers   = 50
+	maxRecentDownloads = 20
+	// maxTopDownloads is the length of the lists of most downloaded builds and most active users
+	maxTopDownloads = 10
+
+	downloadRecordTimeout = 30 * time.Second
+)
+
+// downloadUser is who a download is recorded for: the impersonated user, otherwise the user of the token
+func downloadUser(c *gin.Context) string {
+	if imp, ok := requestImpersonation(c); ok {
+		return imp.UserName
+	}
+	if subject := c.GetString(subjectKey); subject != "" {
+		return subject
+	}
+	return "unknown"
+}
+
+// addDownload counts d in status, keeping the users and recent downloads most recent first
+func addDownload(status *automotivev1alpha1.ArtifactDownloadStatus, d automotivev1alpha1.ArtifactDownload) {
+	status.Count++
+	last := d.Time
+	status.LastDownloadTime = &last
+
+	user := automotivev1alpha1.ArtifactDownloader{User: d.User, Count: 1, LastDownloadTime: d.Time}
+	if i := slices.IndexFunc(status.Users, func(u automotivev1alpha1.ArtifactDownloader) bool { return u.User == d.User }); i >= 0 {
+		user.Count += status.Users[i].Count
+		status.Users = slices.Delete(status.Users, i, i+1)
+	}
+	status.Users = append([]automotivev1alpha1.ArtifactDownloader{user}, status.Users...)
+	if len(status.Users) > maxDownloadUsers {
+		status.Users = status.Users[:maxDownloadUsers]
+	}
+
+	status.Recent = append([]automotivev1alpha1.ArtifactDownload{d}, status.Recent...)
+	if len(status.Recent) > maxRecentDownloads {
+		status.Recent = status.Recent[:maxRecentDownloads]
+	}
+}
+
+// countsAsDownload reports whether a request for rng of a file downloads it. Only GET requests
+// served from the first byte count, so resuming a download or fetching the other parts of a
+// parallel download does not count it again.
+func countsAsDownload(method string, rng *byteRange) bool {
+	return method == http.MethodGet && (rng == nil || rng.start == 0)
+}
+
+// recordArtifactDownload counts the download of file of build once its body was sent
+func (a *APIServer) recordArtifactDownload(c *gin.Context, build *automotivev1alpha1.ImageBuild, file string, rng *byteRange) {
+	if !countsAsDownload(c.Request.Method, rng) {
+		return
+	}
+	d := automotivev1alpha1.ArtifactDownload{
+		User:  downloadUser(c),
+		File:  file,
+		Time:  metav1.Now(),
+		Bytes: int64(max(c.Writer.Size(), 0)),
+	}
+	a.log.Info("artifact downloaded", "build", build.Name, "file", file, "user", d.User, "client", c.ClientIP(),
+		"bytes", d.Bytes, "reqID", c.GetString("reqID"))
+
+	// Users allowed to download need not be allowed to update builds, so the service account
+	// records the download; the response is not held up by it
+	key := types.NamespacedName{Name: build.Name, Namespace: build.Namespace}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), downloadRecordTimeout)
+		defer cancel()
+		if err := saveDownload(ctx, key, d); err != nil {
+			a.log.Error(err, "failed to record artifact download", "build", key.Name, "file", file)
+		}
+	}()
+}
+
+// saveDownload adds d to the status of the build key
+func saveDownload(ctx context.Context, key types.NamespacedName, d automotivev1alpha1.ArtifactDownload) error {
+	k8sClient, err := serviceClient()
+	if err != nil {
+		return err
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		build := &automotivev1alpha1.ImageBuild{}
+		if err := k8sClient.Get(ctx, key, build); err != nil {
+			return err
+		}
+		if build.Status.Downloads == nil {
+			build.Status.Downloads = &automotivev1alpha1.ArtifactDownloadStatus{}
+		}
+		addDownload(build.Status.Downloads, d)
+		return k8sClient.Status().Update(ctx, build)
+	})
+}
+
+// artifactDownloads converts status.downloads of build for the API
+func artifactDownloads(build *automotivev1alpha1.ImageBuild) *ArtifactDownloads {
+	st := build.Status.Downloads
+	if st == nil {
+		return nil
+	}
+	out := &ArtifactDownloads{Count: st.Count}
+	if st.LastDownloadTime != nil {
+		out.LastDownloadAt = st.LastDownloadTime.UTC().Format(time.RFC3339)
+	}
+	for _, u := range st.Users {
+		out.Users = append(out.Users, ArtifactDownloader{
+			User:           u.User,
+			Count:          u.Count,
+			LastDownloadAt: u.LastDownloadTime.UTC().Format(time.RFC3339),
+		})
+	}
+	for _, d := range st.Recent {
+		out.Recent = append(out.Recent, ArtifactDownload{
+			User:  d.User,
+			File:  d.File,
+			Time:  d.Time.UTC().Format(time.RFC3339),
+			Bytes: d.Bytes,
+		})
+	}
+	return out
+}
+
+// downloadStats sums the downloads of builds by build and by user
+func downloadStats(builds []automotivev1alpha1.ImageBuild) DownloadStats {
+	stats := DownloadStats{TopBuilds: []DownloadCount{}, TopUsers: []DownloadCount{}}
+	users := map[string]int64{}
+	for _, b := range builds {
+		st := b.Status.Downloads
+		if st == nil || st.Count == 0 {
+			continue
+		}
+		stats.Count += st.Count
+		stats.Builds++
+		stats.TopBuilds = append(stats.TopBuilds, DownloadCount{Name: b.Name, Count: st.Count})
+		for _, u := range st.Users {
+			users[u.User] += u.Count
+		}
+	}
+	for user, n := range users {
+		stats.TopUsers = append(stats.TopUsers, DownloadCount{Name: user, Count: n})
+	}
+	stats.TopBuilds = topDownloads(stats.TopBuilds)
+	stats.TopUsers = topDownloads(stats.TopUsers)
+	return stats
+}
+
+// topDownloads sorts counts by downloads, then name, and keeps the first maxTopDownloads
+func topDownloads(counts []DownloadCount) []DownloadCount {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	if len(counts) > maxTopDownloads {
+		counts = counts[:maxTopDownloads]
+	}
+	return counts
+}
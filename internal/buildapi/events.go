@@ -0,0 +1,175 @@
+package buildapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+// Sources of build events
+const (
+	// eventSourceOperator are milestones recorded in the status of the build
+	eventSourceOperator = "operator"
+	// eventSourceKubernetes are Kubernetes Events of the build and the objects it runs with
+	eventSourceKubernetes = "kubernetes"
+)
+
+func (a *APIServer) handleGetBuildEvents(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("build events", "build", name, "reqID", c.GetString("reqID"))
+	getBuildEvents(c, name)
+}
+
+// getBuildEvents returns what happened to a build, oldest first: the milestones of its status and
+// the Kubernetes Events of the build, its TaskRun, its pods and its workspace PVC. The caller only
+// needs to read the build; the Events are read with the build API's service account, since
+// users of the build API often may not list Events themselves.
+func getBuildEvents(c *gin.Context, name string) {
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	ctx := c.Request.Context()
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+
+	svc, err := serviceClient()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	related := map[string]bool{"ImageBuild/" + build.Name: true}
+	// the upload, debug and boot test pods carry the build name, the pod of the TaskRun its name
+	selectors := []client.MatchingLabels{{"automotive.sdv.cloud.redhat.com/imagebuild-name": build.Name}}
+	if tr := strings.TrimSpace(build.Status.TaskRunName); tr != "" {
+		related["TaskRun/"+tr] = true
+		selectors = append(selectors, client.MatchingLabels{"tekton.dev/taskRun": tr})
+	}
+	if build.Status.PVCName != "" {
+		related["PersistentVolumeClaim/"+build.Status.PVCName] = true
+	}
+	for _, selector := range selectors {
+		pods := &corev1.PodList{}
+		if err := svc.List(ctx, pods, client.InNamespace(namespace), selector); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error listing pods: %v", err)})
+			return
+		}
+		for _, pod := range pods.Items {
+			related["Pod/"+pod.Name] = true
+		}
+	}
+	events := &corev1.EventList{}
+	if err := svc.List(ctx, events, client.InNamespace(namespace)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error listing events: %v", err)})
+		return
+	}
+
+	resp := BuildEventsResponse{Name: build.Name, Events: buildEvents(build, events.Items, related)}
+	if t := strings.TrimSpace(c.Query("type")); t != "" {
+		filtered := []BuildEvent{}
+		for _, ev := range resp.Events {
+			if strings.EqualFold(ev.Type, t) {
+				filtered = append(filtered, ev)
+			}
+		}
+		resp.Events = filtered
+	}
+	writeJSON(c, http.StatusOK, resp)
+}
+
+// buildEvents merges the milestones of build with the events about the objects in related, keyed
+// Kind/name, oldest first
+func buildEvents(build *automotivev1alpha1.ImageBuild, events []corev1.Event, related map[string]bool) []BuildEvent {
+	type timed struct {
+		at time.Time
+		ev BuildEvent
+	}
+	var all []timed
+	milestone := func(at *metav1.Time, eventType, reason, message string) {
+		if at == nil || at.IsZero() {
+			return
+		}
+		all = append(all, timed{at.Time, BuildEvent{
+			Source:  eventSourceOperator,
+			Type:    eventType,
+			Reason:  reason,
+			Object:  "ImageBuild/" + build.Name,
+			Message: message,
+		}})
+	}
+
+	milestone(&build.CreationTimestamp, corev1.EventTypeNormal, "Created", "Build created")
+	milestone(build.Status.StartTime, corev1.EventTypeNormal, "Started", "Build started")
+	for _, cond := range build.Status.Conditions {
+		eventType := corev1.EventTypeNormal
+		if cond.Status == metav1.ConditionFalse {
+			eventType = corev1.EventTypeWarning
+		}
+		message := fmt.Sprintf("%s is %s", cond.Type, cond.Status)
+		if cond.Message != "" {
+			message += ": " + cond.Message
+		}
+		milestone(&cond.LastTransitionTime, eventType, cond.Reason, message)
+	}
+	if build.Status.CompletionTime != nil {
+		eventType := corev1.EventTypeNormal
+		if build.Status.Phase == "Failed" {
+			eventType = corev1.EventTypeWarning
+		}
+		message := "Build " + strings.ToLower(build.Status.Phase)
+		if build.Status.Message != "" {
+			message += ": " + build.Status.Message
+		}
+		milestone(build.Status.CompletionTime, eventType, build.Status.Phase, message)
+	}
+
+	for _, e := range events {
+		object := e.InvolvedObject.Kind + "/" + e.InvolvedObject.Name
+		if !related[object] {
+			continue
+		}
+		at := e.LastTimestamp.Time
+		if at.IsZero() {
+			at = e.EventTime.Time
+		}
+		if at.IsZero() {
+			at = e.CreationTimestamp.Time
+		}
+		all = append(all, timed{at, BuildEvent{
+			Source:  eventSourceKubernetes,
+			Type:    e.Type,
+			Reason:  e.Reason,
+			Object:  object,
+			Message: e.Message,
+			Count:   e.Count,
+		}})
+	}
+
+	sort.SliceStable(all, func(i, j int) bool { return all[i].at.Before(all[j].at) })
+	out := make([]BuildEvent, 0, len(all))
+	for _, t := range all {
+		t.ev.Time = t.at.UTC().Format(time.RFC3339)
+		out = append(out, t.ev)
+	}
+	return out
+}
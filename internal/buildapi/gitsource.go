@@ -0,0 +1,73 @@
+package buildapi
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+// gitRefPattern matches the branches, tags and commit SHAs a build accepts as git ref; refs
+// starting with a dash would be read as options by git
+var gitRefPattern = regexp.MustCompile(`^[A-Za-z0-9_.][A-Za-z0-9_./-]*$`)
+
+// sourceFromRequest validates the source of a build request, which replaces its manifest
+func sourceFromRequest(req *BuildRequest) (*automotivev1alpha1.BuildSource, error) {
+	if req.Source == nil {
+		return nil, nil
+	}
+	if req.Manifest != "" {
+		return nil, fmt.Errorf("manifest and source are mutually exclusive")
+	}
+	git := req.Source.Git
+	if git == nil {
+		return nil, fmt.Errorf("source.git is required when source is set")
+	}
+
+	u, err := url.Parse(git.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid source.git.url %q: must be an http(s) URL", git.URL)
+	}
+	if u.User != nil {
+		return nil, fmt.Errorf("invalid source.git.url: credentials are not allowed in the URL")
+	}
+	if git.Ref != "" && (!gitRefPattern.MatchString(git.Ref) || strings.Contains(git.Ref, "..")) {
+		return nil, fmt.Errorf("invalid source.git.ref %q", git.Ref)
+	}
+	p := strings.TrimSpace(git.Path)
+	if p == "" || path.IsAbs(p) || path.Clean(p) != p || strings.HasPrefix(p, "../") {
+		return nil, fmt.Errorf("invalid source.git.path %q: must be a relative path in the repository", git.Path)
+	}
+	if !strings.HasSuffix(p, ".aib.yml") && !strings.HasSuffix(p, ".mpp.yml") {
+		return nil, fmt.Errorf("invalid source.git.path %q: manifests end in .aib.yml or .mpp.yml", git.Path)
+	}
+
+	return &automotivev1alpha1.BuildSource{Git: &automotivev1alpha1.GitSource{
+		URL:  git.URL,
+		Ref:  git.Ref,
+		Path: p,
+	}}, nil
+}
+
+// sourceToRequest converts the source of an ImageBuild back to its API form
+func sourceToRequest(src *automotivev1alpha1.BuildSource) *BuildSource {
+	if src == nil || src.Git == nil {
+		return nil
+	}
+	return &BuildSource{Git: &GitSource{URL: src.Git.URL, Ref: src.Git.Ref, Path: src.Git.Path}}
+}
+
+// gitRevision names the revision the git source of a build is built from: the commit once the
+// build resolved it, else the ref it asked for
+func gitRevision(build *automotivev1alpha1.ImageBuild) string {
+	switch {
+	case build.Status.GitCommit != "":
+		return build.Status.GitCommit
+	case build.Spec.Source != nil && build.Spec.Source.Git != nil && build.Spec.Source.Git.Ref != "":
+		return build.Spec.Source.Git.Ref
+	}
+	return "the default branch"
+}
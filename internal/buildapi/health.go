@@ -0,0 +1,218 @@
+package buildapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authzv1 "k8s.io/api/authorization/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/storage"
+)
+
+const (
+	healthOK      = "ok"
+	healthWarning = "warning"
+	healthFailed  = "failed"
+	// healthDraining is the readiness of a replica that is shutting down
+	healthDraining = "draining"
+
+	// healthCheckTimeout bounds each check, so probes get an answer before their own timeout
+	healthCheckTimeout = 3 * time.Second
+	// healthCacheTTL is how long results are reused; the probes of every replica and load balancer
+	// would otherwise each reach the Kubernetes API
+	healthCacheTTL = 5 * time.Second
+)
+
+// healthCheckFunc checks one dependency. An error fails the check, a warning is reported without
+// failing it.
+type healthCheckFunc func(ctx context.Context) (warning string, err error)
+
+type namedHealthCheck struct {
+	name  string
+	check healthCheckFunc
+}
+
+// healthChecker runs the dependency checks of /healthz and /readyz concurrently and caches the results
+type healthChecker struct {
+	checks []namedHealthCheck
+	now    func() time.Time
+
+	mu        sync.Mutex
+	results   []HealthCheck
+	checkedAt time.Time
+}
+
+func newHealthChecker(checks ...namedHealthCheck) *healthChecker {
+	return &healthChecker{checks: checks, now: time.Now}
+}
+
+// defaultHealthChecks verify what every build request needs: the Kubernetes API, the permissions
+// of the service account on ImageBuilds and storage classes for build workspaces
+func defaultHealthChecks() []namedHealthCheck {
+	return []namedHealthCheck{
+		{name: "kubernetes", check: checkKubernetesAPI},
+		{name: "rbac", check: checkImageBuildAccess},
+		{name: "storage", check: checkWorkspaceStorage},
+	}
+}
+
+// evaluate returns the results of all checks, running them when the cached ones are too old
+func (h *healthChecker) evaluate(ctx context.Context) ([]HealthCheck, time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.results != nil && h.now().Sub(h.checkedAt) < healthCacheTTL {
+		return h.results, h.checkedAt
+	}
+
+	results := make([]HealthCheck, len(h.checks))
+	var wg sync.WaitGroup
+	for i, c := range h.checks {
+		wg.Add(1)
+		go func(i int, c namedHealthCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+			start := time.Now()
+			warning, err := c.check(checkCtx)
+			result := HealthCheck{Name: c.name, Status: healthOK, DurationMs: time.Since(start).Milliseconds()}
+			switch {
+			case err != nil:
+				result.Status, result.Message = healthFailed, err.Error()
+			case warning != "":
+				result.Status, result.Message = healthWarning, warning
+			}
+			results[i] = result
+		}(i, c)
+	}
+	wg.Wait()
+
+	h.results, h.checkedAt = results, h.now()
+	return results, h.checkedAt
+}
+
+// respond writes the check results. Readiness fails with 503 when a check failed; liveness reports
+// the failure as degraded but answers 200, since restarting the API does not fix its dependencies.
+func (h *healthChecker) respond(c *gin.Context, readiness bool) {
+	// results are shared with other callers, so a probe that gives up must not fail the checks
+	results, checkedAt := h.evaluate(context.WithoutCancel(c.Request.Context()))
+	resp := HealthResponse{Status: healthOK, Checks: results, CheckedAt: checkedAt.UTC().Format(time.RFC3339)}
+	status := http.StatusOK
+	for _, r := range results {
+		if r.Status != healthFailed {
+			continue
+		}
+		if readiness {
+			resp.Status, status = healthFailed, http.StatusServiceUnavailable
+		} else {
+			resp.Status = "degraded"
+		}
+	}
+	c.Header("Cache-Control", "no-store")
+	writeJSON(c, status, resp)
+}
+
+func (a *APIServer) handleHealthz(c *gin.Context) {
+	a.health.respond(c, false)
+}
+
+func (a *APIServer) handleReadyz(c *gin.Context) {
+	// a draining replica is taken out of the Service whatever the state of its dependencies
+	if a.draining.Load() {
+		c.Header("Cache-Control", "no-store")
+		writeJSON(c, http.StatusServiceUnavailable, HealthResponse{Status: healthDraining, Checks: []HealthCheck{}, CheckedAt: time.Now().UTC().Format(time.RFC3339)})
+		return
+	}
+	a.health.respond(c, true)
+}
+
+// checkKubernetesAPI reads the version of the API server with the service account of the build API
+func checkKubernetesAPI(ctx context.Context) (string, error) {
+	cs, err := serviceClientset()
+	if err != nil {
+		return "", err
+	}
+	if err := cs.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Error(); err != nil {
+		return "", fmt.Errorf("kubernetes API unreachable: %w", err)
+	}
+	return "", nil
+}
+
+// imageBuildVerbs are the verbs the build API uses on ImageBuilds on behalf of callers without
+// impersonation
+var imageBuildVerbs = []string{"get", "list", "watch", "create", "patch", "delete"}
+
+// checkImageBuildAccess asks the API server whether the service account of the build API may
+// manage ImageBuilds in its namespace
+func checkImageBuildAccess(ctx context.Context) (string, error) {
+	cs, err := serviceClientset()
+	if err != nil {
+		return "", err
+	}
+	namespace := resolveNamespace()
+	var missing []string
+	for _, verb := range imageBuildVerbs {
+		review := &authzv1.SelfSubjectAccessReview{Spec: authzv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authzv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      verb,
+				Group:     automotivev1alpha1.GroupVersion.Group,
+				Resource:  "imagebuilds",
+			},
+		}}
+		result, err := cs.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return "", fmt.Errorf("access review failed: %w", err)
+		}
+		if !result.Status.Allowed {
+			missing = append(missing, verb)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("service account may not %s imagebuilds in namespace %s", strings.Join(missing, ", "), namespace)
+	}
+	return "", nil
+}
+
+// checkWorkspaceStorage looks for the storage classes build workspaces are provisioned from. A
+// cluster without default class only warns, since builds can still name a class.
+func checkWorkspaceStorage(ctx context.Context) (string, error) {
+	c, err := serviceClient()
+	if err != nil {
+		return "", err
+	}
+	list := &storagev1.StorageClassList{}
+	if err := c.List(ctx, list); err != nil {
+		return "", fmt.Errorf("listing storage classes: %w", err)
+	}
+	if len(list.Items) == 0 {
+		return "", fmt.Errorf("the cluster has no storage classes to provision build workspaces from")
+	}
+	var names []string
+	for i := range list.Items {
+		if storage.IsDefault(&list.Items[i]) {
+			return "", nil
+		}
+		names = append(names, list.Items[i].Name)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("no default storage class; builds must set storageClass (one of %s)", strings.Join(names, ", ")), nil
+}
+
+// serviceClientset returns a clientset acting as the service account of the build API
+func serviceClientset() (*kubernetes.Clientset, error) {
+	cfg, err := serviceRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(cfg)
+}
@@ -0,0 +1,210 @@
+package buildapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+const (
+	// idempotencyKeyLabel holds the hash of the Idempotency-Key a build was created with, together
+	// with the user who sent it
+	idempotencyKeyLabel = "automotive.sdv.cloud.redhat.com/idempotency-key"
+	// requestHashLabel holds the hash of the request a build was created from, see requestHash
+	requestHashLabel = "automotive.sdv.cloud.redhat.com/request-hash"
+	// maxIdempotencyKey is the length limit of Idempotency-Key headers
+	maxIdempotencyKey = 255
+	// abandonedClaimAge is how long a claim may wait for its build before other requests with the
+	// key take it over, e.g. after the replica that claimed it crashed
+	abandonedClaimAge = time.Minute
+)
+
+// errKeyInUse answers a request whose Idempotency-Key is claimed by a request still creating its build
+var errKeyInUse = errors.New("a request with the same Idempotency-Key is still creating its build; retry shortly")
+
+// fullCommitSHA matches the unabbreviated commit hashes a git source can be pinned to
+var fullCommitSHA = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// labelHash returns a hash of s short enough for a label value
+func labelHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:24])
+}
+
+// idempotencyKeyHash validates the Idempotency-Key header of a create request and returns its hash;
+// keys are scoped to the user who sends them, so users cannot receive each other's builds by
+// guessing keys. Empty without a key.
+func idempotencyKeyHash(key, requestedBy string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", nil
+	}
+	if len(key) > maxIdempotencyKey {
+		return "", fmt.Errorf("Idempotency-Key must be at most %d characters", maxIdempotencyKey)
+	}
+	return labelHash(requestedBy + "\n" + key), nil
+}
+
+// requestHash identifies what a defaulted build request builds. The name, namespace, labels,
+// annotations and priority of the request do not change the image and are left out, so CI
+// retries under a new name or with the ID of another pipeline run still match.
+func requestHash(req BuildRequest) string {
+	req.Name, req.Namespace, req.Priority = "", "", ""
+	req.Labels, req.Annotations = nil, nil
+	req.Deduplicate = false
+	data, err := json.Marshal(req)
+	if err != nil {
+		return ""
+	}
+	return labelHash(string(data))
+}
+
+// deduplicatable reports whether identical requests build identical images: the request holds
+// everything the build reads, unlike builds of local or remote files and of git branches or tags,
+// whose content can change between requests
+func deduplicatable(req BuildRequest, inputs *buildInputs) bool {
+	if inputs.needsUpload {
+		return false
+	}
+	if req.Source != nil && (req.Source.Git == nil || !fullCommitSHA.MatchString(strings.ToLower(req.Source.Git.Ref))) {
+		return false
+	}
+	return true
+}
+
+// duplicateBuild finds the build a create request repeats: the build created with the same
+// Idempotency-Key, or with deduplicate the newest build of an identical request. Failed and
+// cancelled builds and builds whose artifacts were deleted are not repeated, so retrying them
+// starts a new build. conflict describes a key used before for a different request.
+func duplicateBuild(ctx context.Context, k8sClient client.Client, namespace, keyHash, reqHash string, deduplicate bool) (dup *automotivev1alpha1.ImageBuild, conflict string, err error) {
+	if keyHash != "" {
+		builds, err := listBuildsByLabel(ctx, k8sClient, namespace, idempotencyKeyLabel, keyHash)
+		if err != nil {
+			return nil, "", err
+		}
+		if dup, conflict = selectDuplicate(builds, reqHash); dup != nil || conflict != "" {
+			return dup, conflict, nil
+		}
+	}
+	if !deduplicate || reqHash == "" {
+		return nil, "", nil
+	}
+	builds, err := listBuildsByLabel(ctx, k8sClient, namespace, requestHashLabel, reqHash)
+	if err != nil {
+		return nil, "", err
+	}
+	dup, _ = selectDuplicate(builds, reqHash)
+	return dup, "", nil
+}
+
+func listBuildsByLabel(ctx context.Context, k8sClient client.Client, namespace, label, value string) ([]automotivev1alpha1.ImageBuild, error) {
+	list := &automotivev1alpha1.ImageBuildList{}
+	if err := k8sClient.List(ctx, list, client.InNamespace(namespace), client.MatchingLabels{label: value}); err != nil {
+		return nil, fmt.Errorf("error looking for duplicate builds: %w", err)
+	}
+	return list.Items, nil
+}
+
+// selectDuplicate picks the newest build of builds a request with hash reqHash can repeat
+func selectDuplicate(builds []automotivev1alpha1.ImageBuild, reqHash string) (*automotivev1alpha1.ImageBuild, string) {
+	var dup *automotivev1alpha1.ImageBuild
+	for i := range builds {
+		b := &builds[i]
+		switch b.Status.Phase {
+		case "Failed", "Cancelled":
+			continue
+		}
+		if b.DeletionTimestamp != nil {
+			continue
+		}
+		if _, deletedAt := artifactsExpiry(b); deletedAt != "" {
+			continue
+		}
+		if dup == nil || b.CreationTimestamp.After(dup.CreationTimestamp.Time) {
+			dup = b
+		}
+	}
+	if dup != nil && dup.Labels[requestHashLabel] != reqHash {
+		return nil, fmt.Sprintf("the Idempotency-Key was used for a different request, which created build %s", dup.Name)
+	}
+	return dup, ""
+}
+
+// idempotencyClaimName is the ConfigMap claiming an Idempotency-Key for the build created with it
+func idempotencyClaimName(keyHash string) string {
+	return "idempotency-" + keyHash
+}
+
+// claimIdempotencyKey makes the request the only one creating a build for keyHash. Creating the
+// claim ConfigMap named after the key fails for every other request with the key, which then gets
+// the build of the claim as dup, a conflict when it differs from the claiming request, or
+// errKeyInUse while that build is being created. Claims of builds that cannot be repeated (see
+// selectDuplicate) and claims whose build was not created within abandonedClaimAge are taken over.
+func claimIdempotencyKey(ctx context.Context, k8sClient client.Client, namespace, keyHash, reqHash, buildName string) (claim *corev1.ConfigMap, dup *automotivev1alpha1.ImageBuild, conflict string, err error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		claim = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      idempotencyClaimName(keyHash),
+				Namespace: namespace,
+				Labels:    map[string]string{idempotencyKeyLabel: keyHash},
+			},
+			Data: map[string]string{"build": buildName, "requestHash": reqHash},
+		}
+		err := k8sClient.Create(ctx, claim)
+		if err == nil {
+			return claim, nil, "", nil
+		}
+		if !k8serrors.IsAlreadyExists(err) {
+			return nil, nil, "", fmt.Errorf("error claiming the Idempotency-Key: %w", err)
+		}
+
+		existing := &corev1.ConfigMap{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: claim.Name, Namespace: namespace}, existing); err != nil {
+			if k8serrors.IsNotFound(err) {
+				// released meanwhile
+				continue
+			}
+			return nil, nil, "", fmt.Errorf("error reading the Idempotency-Key claim: %w", err)
+		}
+		build := &automotivev1alpha1.ImageBuild{}
+		err = k8sClient.Get(ctx, types.NamespacedName{Name: existing.Data["build"], Namespace: namespace}, build)
+		switch {
+		case err == nil && build.Labels[idempotencyKeyLabel] == keyHash:
+			if dup, conflict = selectDuplicate([]automotivev1alpha1.ImageBuild{*build}, reqHash); dup != nil || conflict != "" {
+				return nil, dup, conflict, nil
+			}
+		case err == nil || k8serrors.IsNotFound(err):
+			if time.Since(existing.CreationTimestamp.Time) < abandonedClaimAge {
+				return nil, nil, "", errKeyInUse
+			}
+		default:
+			return nil, nil, "", fmt.Errorf("error reading the build of the Idempotency-Key claim: %w", err)
+		}
+		// concurrent takeovers delete the claim once; the losers find the new claim on the next attempt
+		uid := existing.UID
+		if err := k8sClient.Delete(ctx, existing, client.Preconditions{UID: &uid}); err != nil && !k8serrors.IsNotFound(err) && !k8serrors.IsConflict(err) {
+			return nil, nil, "", fmt.Errorf("error taking over the Idempotency-Key claim: %w", err)
+		}
+	}
+	return nil, nil, "", errKeyInUse
+}
+
+// releaseIdempotencyKey deletes the claim of a request that failed to create its build
+func releaseIdempotencyKey(ctx context.Context, k8sClient client.Client, claim *corev1.ConfigMap) {
+	uid := claim.UID
+	_ = k8sClient.Delete(ctx, claim, client.Preconditions{UID: &uid})
+}
@@ -0,0 +1,137 @@
+package buildapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/logarchive"
+)
+
+// logArchive reports the log archive of build, nil until the controller archived its logs
+func logArchive(build *automotivev1alpha1.ImageBuild) *LogArchive {
+	archive := build.Status.LogArchive
+	if archive == nil {
+		return nil
+	}
+	return &LogArchive{
+		Size:      archive.Size,
+		LogSize:   archive.LogSize,
+		SHA256:    archive.SHA256,
+		Truncated: archive.Truncated,
+	}
+}
+
+// logArchiveFormat reads the format query parameter of the log archive endpoint: gzip, the
+// default, serves the archive as stored; text serves the log it holds
+func logArchiveFormat(query string) (string, error) {
+	switch format := strings.ToLower(strings.TrimSpace(query)); format {
+	case "", "gzip":
+		return "gzip", nil
+	case "text":
+		return format, nil
+	default:
+		return "", fmt.Errorf("format must be gzip or text")
+	}
+}
+
+func (a *APIServer) handleGetLogArchive(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("log archive requested", "build", name, "format", c.Query("format"), "reqID", c.GetString("reqID"))
+	getLogArchive(c, name)
+}
+
+// getLogArchive serves the complete logs of a finished build from the archive the controller
+// keeps in ConfigMaps, so they can be read after the build pod is gone. 409 while the build runs,
+// 404 for builds whose pod was gone before their logs were archived.
+func getLogArchive(c *gin.Context, name string) {
+	namespace := requestNamespace(c)
+	ctx := c.Request.Context()
+
+	format, err := logArchiveFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+	archive := build.Status.LogArchive
+	switch {
+	case archive == nil && filesFinal(build):
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("build %s has no log archive; its pod was gone before its logs were archived", name)})
+		return
+	case archive == nil:
+		c.JSON(http.StatusConflict, gin.H{"error": "log archive not available until the build finishes; stream the logs instead"})
+		return
+	}
+
+	etag := `"sha256-` + archive.SHA256 + `"`
+	if format == "text" {
+		etag = `W/"sha256-` + archive.SHA256 + `-text"`
+	}
+	c.Header("ETag", etag)
+	var completed time.Time
+	if build.Status.CompletionTime != nil {
+		completed = build.Status.CompletionTime.Time
+	}
+	if notModified(c, etag, completed) {
+		writeNotModified(c)
+		return
+	}
+
+	parts := make([][]byte, 0, archive.Parts)
+	for i := 0; i < int(archive.Parts); i++ {
+		cm := &corev1.ConfigMap{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: logarchive.PartName(name, i), Namespace: namespace}, cm); err != nil {
+			if k8serrors.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("log archive of build %s is no longer complete", name)})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching log archive: %v", err)})
+			return
+		}
+		parts = append(parts, cm.BinaryData[logarchive.DataKey])
+	}
+	data, err := logarchive.Join(parts, archive.SHA256)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if format == "text" {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("invalid log archive: %v", err)})
+			return
+		}
+		c.Header("Content-Type", "text/plain; charset=utf-8")
+		c.Status(http.StatusOK)
+		_, _ = io.Copy(c.Writer, zr)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".log.gz"))
+	c.Data(http.StatusOK, logarchive.MediaType, data)
+}
@@ -0,0 +1,125 @@
+package buildapi
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/tasks"
+	aibmanifest "github.com/centos-automotive-suite/automotive-dev-operator/pkg/manifest"
+)
+
+// targetArchitectures are the architectures of the hardware targets that only exist for one.
+// Targets not listed, e.g. qemu or aws, are built for any supported architecture.
+var targetArchitectures = map[string][]string{
+	"pc":            {"amd64"},
+	"rpi4":          {"arm64"},
+	"ridesx4":       {"arm64"},
+	"am62sk":        {"arm64"},
+	"am69sk":        {"arm64"},
+	"beagleplay":    {"arm64"},
+	"j784s4evm":     {"arm64"},
+	"tda4vm_sk":     {"arm64"},
+	"s32g_vnp_rdb3": {"arm64"},
+	"rcar_s4":       {"arm64"},
+}
+
+// validateManifest checks a manifest the way a build of it would: the manifest is linted and the
+// distro, target, architecture and export format are checked against what the
+// automotive-image-builder image supports. Problems are reported with 200 and valid false, so
+// clients can tell them apart from a request the server could not handle.
+func validateManifest(c *gin.Context) {
+	var req ManifestValidationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid JSON: %v", err)})
+		return
+	}
+	if strings.TrimSpace(req.Manifest) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "manifest is required"})
+		return
+	}
+	manifestValidationDefaults(&req)
+
+	resp := ManifestValidationResponse{Errors: []ManifestIssue{}}
+	for _, p := range aibmanifest.Lint([]byte(req.Manifest)) {
+		resp.Errors = append(resp.Errors, ManifestIssue{Field: p.Field, Line: p.Line, Column: p.Column, Message: p.Message})
+	}
+	resp.Errors = append(resp.Errors, settingIssues(&req)...)
+
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	caps, err := cachedCapabilities(c.Request.Context(), k8sClient, resolveNamespace(), req.AutomotiveImageBuilder)
+	switch {
+	case err != nil:
+		resp.Warnings = append(resp.Warnings, ManifestIssue{Message: fmt.Sprintf("distro, target and export format not checked: %v", err)})
+	case caps == nil:
+		resp.Warnings = append(resp.Warnings, ManifestIssue{Message: fmt.Sprintf(
+			"distro, target and export format not checked: the capabilities of %s were not probed yet, see GET /v1/capabilities", req.AutomotiveImageBuilder)})
+	default:
+		resp.Errors = append(resp.Errors, capabilityIssues(&req, caps)...)
+	}
+	resp.Valid = len(resp.Errors) == 0
+	writeJSON(c, http.StatusOK, resp)
+}
+
+// manifestValidationDefaults fills the settings a build request would default
+func manifestValidationDefaults(req *ManifestValidationRequest) {
+	if req.Distro == "" {
+		req.Distro = "cs9"
+	}
+	if req.Target == "" {
+		req.Target = "qemu"
+	}
+	if req.Architecture == "" {
+		req.Architecture = "arm64"
+	}
+	if req.ExportFormat == "" {
+		req.ExportFormat = "image"
+	}
+	if req.Mode == "" {
+		req.Mode = "image"
+	}
+	if req.AutomotiveImageBuilder == "" {
+		req.AutomotiveImageBuilder = tasks.AutomotiveImageBuilder
+	}
+}
+
+// settingIssues reports the build settings that are rejected whatever the
+// automotive-image-builder image
+func settingIssues(req *ManifestValidationRequest) []ManifestIssue {
+	var issues []ManifestIssue
+	arch := string(req.Architecture)
+	if !slices.Contains(supportedArchitectures, arch) {
+		issues = append(issues, ManifestIssue{Field: "architecture", Message: fmt.Sprintf(
+			"%q is not one of %s", arch, strings.Join(supportedArchitectures, ", "))})
+	} else if archs, ok := targetArchitectures[string(req.Target)]; ok && !slices.Contains(archs, arch) {
+		issues = append(issues, ManifestIssue{Field: "architecture", Message: fmt.Sprintf(
+			"target %s is only built for %s", req.Target, strings.Join(archs, ", "))})
+	}
+	return issues
+}
+
+// capabilityIssues reports the build settings the automotive-image-builder image does not support
+func capabilityIssues(req *ManifestValidationRequest, caps *CapabilitiesResponse) []ManifestIssue {
+	var issues []ManifestIssue
+	for _, setting := range []struct {
+		field, value string
+		supported    []string
+	}{
+		{"distro", string(req.Distro), caps.Distros},
+		{"target", string(req.Target), caps.Targets},
+		{"exportFormat", string(req.ExportFormat), caps.ExportFormats},
+	} {
+		if len(setting.supported) > 0 && !slices.Contains(setting.supported, setting.value) {
+			issues = append(issues, ManifestIssue{Field: setting.field, Message: fmt.Sprintf(
+				"%q is not supported by %s", setting.value, caps.AutomotiveImageBuilder)})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,98 @@
+package buildapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// namespaceKey is the context key of the namespace an authenticated request works in
+const namespaceKey = "namespace"
+
+// bodyNamespaceRoutes take the namespace from the namespace field of their BuildRequest body when
+// the query has none
+var bodyNamespaceRoutes = map[string]bool{
+	"POST /v1/builds":            true,
+	"POST /v2/builds":            true,
+	"POST /v1/policies/evaluate": true,
+}
+
+// namespacesFromEnv returns the namespaces BUILD_API_NAMESPACES lets clients select besides the
+// build API's own; "*" allows every namespace
+func namespacesFromEnv() []string {
+	var namespaces []string
+	for _, ns := range strings.Split(os.Getenv("BUILD_API_NAMESPACES"), ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// namespaceServed reports whether clients may select namespace
+func (a *APIServer) namespaceServed(namespace string) bool {
+	if namespace == resolveNamespace() {
+		return true
+	}
+	for _, ns := range a.namespaces {
+		if ns == "*" || ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveRequestNamespace picks the namespace a request works in: the namespace query parameter,
+// the namespace field of a build request, or the build API's own namespace. Only namespaces the
+// build API serves can be selected; whether the caller may use builds there is left to
+// authorizeRoute. It answers the request and returns false when the namespace is rejected.
+func (a *APIServer) resolveRequestNamespace(c *gin.Context) bool {
+	route := c.Request.Method + " " + c.FullPath()
+	namespace := strings.TrimSpace(c.Query("namespace"))
+	if (route == "GET /v1/builds" || route == "GET /v2/builds") && c.Query("allNamespaces") == "true" {
+		// there the namespace parameter filters the builds of every namespace
+		namespace = ""
+	}
+	if namespace == "" && bodyNamespaceRoutes[route] && c.Request.Body != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("error reading request body: %v", err)})
+			return false
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		var fields struct {
+			Namespace string `json:"namespace"`
+		}
+		// malformed bodies are rejected by the handler
+		_ = json.Unmarshal(body, &fields)
+		namespace = strings.TrimSpace(fields.Namespace)
+	}
+	if namespace == "" {
+		namespace = resolveNamespace()
+	}
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid namespace %q: %s", namespace, strings.Join(errs, "; "))})
+		return false
+	}
+	if !a.namespaceServed(namespace) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("namespace %q is not served by this build API", namespace)})
+		return false
+	}
+	c.Set(namespaceKey, namespace)
+	return true
+}
+
+// requestNamespace returns the namespace of a request, the build API's own when none was selected
+func requestNamespace(c *gin.Context) string {
+	if ns := c.GetString(namespaceKey); ns != "" {
+		return ns
+	}
+	return resolveNamespace()
+}
@@ -0,0 +1,495 @@
+package buildapi
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	authnv1 "k8s.io/api/authentication/v1"
+)
+
+const (
+	// oidcClockSkew is how far the clocks of the issuer and the build API may drift apart
+	oidcClockSkew = time.Minute
+	// oidcKeyRefreshInterval bounds how often the keys of the issuer are fetched again for a token
+	// signed with an unknown key, e.g. after a key rotation
+	oidcKeyRefreshInterval = time.Minute
+	// maxOIDCDocumentBytes bounds the discovery document and the key set
+	maxOIDCDocumentBytes = 1 << 20
+)
+
+// oidcConfig is the OIDC issuer whose tokens the build API accepts, see OIDCConfig of the OperatorConfig
+type oidcConfig struct {
+	issuerURL      string
+	audience       string
+	usernameClaim  string
+	usernamePrefix string
+	groupsClaim    string
+	groupsPrefix   string
+	// caFile verifies the TLS certificate of the issuer instead of the system roots
+	caFile string
+}
+
+// oidcVerifierFromEnv sets up the issuer the operator passes in BUILD_API_OIDC_*; without an issuer
+// URL only Kubernetes tokens are accepted and nil is returned
+func oidcVerifierFromEnv() (*oidcVerifier, error) {
+	cfg := oidcConfig{
+		issuerURL:      strings.TrimSpace(os.Getenv("BUILD_API_OIDC_ISSUER_URL")),
+		audience:       strings.TrimSpace(os.Getenv("BUILD_API_OIDC_AUDIENCE")),
+		usernameClaim:  strings.TrimSpace(os.Getenv("BUILD_API_OIDC_USERNAME_CLAIM")),
+		usernamePrefix: os.Getenv("BUILD_API_OIDC_USERNAME_PREFIX"),
+		groupsClaim:    strings.TrimSpace(os.Getenv("BUILD_API_OIDC_GROUPS_CLAIM")),
+		groupsPrefix:   os.Getenv("BUILD_API_OIDC_GROUPS_PREFIX"),
+		caFile:         strings.TrimSpace(os.Getenv("BUILD_API_OIDC_CA_FILE")),
+	}
+	if cfg.issuerURL == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(cfg.issuerURL, "https://") {
+		return nil, fmt.Errorf("OIDC issuer URL %q must be https", cfg.issuerURL)
+	}
+	if cfg.audience == "" {
+		return nil, errors.New("OIDC issuer URL is set without an audience")
+	}
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	if cfg.caFile != "" {
+		pem, err := os.ReadFile(cfg.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading OIDC CA: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in OIDC CA %s", cfg.caFile)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+		httpClient.Transport = transport
+	}
+	return newOIDCVerifier(cfg, httpClient), nil
+}
+
+// oidcVerifier validates JWTs of an OIDC issuer with the keys its discovery document names
+type oidcVerifier struct {
+	cfg    oidcConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu      sync.Mutex
+	jwksURI string
+	keys    []oidcKey
+	fetched time.Time
+}
+
+// oidcKey is a public key of the issuer's key set
+type oidcKey struct {
+	id  string
+	key crypto.PublicKey
+}
+
+func newOIDCVerifier(cfg oidcConfig, client *http.Client) *oidcVerifier {
+	if cfg.usernameClaim == "" {
+		cfg.usernameClaim = "sub"
+	}
+	// as in kube-apiserver, user names other than verified emails are prefixed with the issuer
+	// unless a prefix is set, and "-" turns the prefix off
+	switch {
+	case cfg.usernamePrefix == "-":
+		cfg.usernamePrefix = ""
+	case cfg.usernamePrefix == "" && cfg.usernameClaim != "email":
+		cfg.usernamePrefix = cfg.issuerURL + "#"
+	}
+	if cfg.groupsClaim == "" {
+		cfg.groupsClaim = "groups"
+	}
+	return &oidcVerifier{cfg: cfg, client: client, now: time.Now}
+}
+
+// jwtHeader is the JOSE header of a token
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// splitJWT decodes the header and claims of a compact JWT and its signature
+func splitJWT(token string) (header jwtHeader, claims map[string]any, signature []byte, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return header, nil, nil, errors.New("not a JWT")
+	}
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return header, nil, nil, fmt.Errorf("malformed JWT header: %w", err)
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return header, nil, nil, fmt.Errorf("malformed JWT header: %w", err)
+	}
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return header, nil, nil, fmt.Errorf("malformed JWT claims: %w", err)
+	}
+	dec := json.NewDecoder(strings.NewReader(string(rawClaims)))
+	dec.UseNumber()
+	if err := dec.Decode(&claims); err != nil {
+		return header, nil, nil, fmt.Errorf("malformed JWT claims: %w", err)
+	}
+	if signature, err = base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
+		return header, nil, nil, fmt.Errorf("malformed JWT signature: %w", err)
+	}
+	return header, claims, signature, nil
+}
+
+// issues reports whether token claims to come from the issuer. Kubernetes service account tokens
+// are JWTs as well; they name the cluster as issuer and are left to the TokenReview.
+func (v *oidcVerifier) issues(token string) bool {
+	_, claims, _, err := splitJWT(token)
+	if err != nil {
+		return false
+	}
+	iss, _ := claims["iss"].(string)
+	return iss == v.cfg.issuerURL
+}
+
+// verify checks the signature, issuer, audience and lifetime of token and returns its user
+func (v *oidcVerifier) verify(ctx context.Context, token string) (authnv1.UserInfo, error) {
+	header, claims, signature, err := splitJWT(token)
+	if err != nil {
+		return authnv1.UserInfo{}, err
+	}
+	signed := token[:strings.LastIndex(token, ".")]
+	if err := v.verifySignature(ctx, header, []byte(signed), signature); err != nil {
+		return authnv1.UserInfo{}, err
+	}
+
+	if iss, _ := claims["iss"].(string); iss != v.cfg.issuerURL {
+		return authnv1.UserInfo{}, fmt.Errorf("token issued by %q", iss)
+	}
+	if !audienceContains(claims["aud"], v.cfg.audience) {
+		return authnv1.UserInfo{}, fmt.Errorf("token not issued for %q", v.cfg.audience)
+	}
+	now := v.now()
+	exp, ok := numericDate(claims["exp"])
+	if !ok {
+		return authnv1.UserInfo{}, errors.New("token has no expiry")
+	}
+	if now.After(exp.Add(oidcClockSkew)) {
+		return authnv1.UserInfo{}, errors.New("token expired")
+	}
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Add(oidcClockSkew).Before(nbf) {
+		return authnv1.UserInfo{}, errors.New("token not valid yet")
+	}
+
+	name, ok := claimValue(claims, v.cfg.usernameClaim).(string)
+	if !ok || name == "" {
+		return authnv1.UserInfo{}, fmt.Errorf("token has no %s claim", v.cfg.usernameClaim)
+	}
+	if v.cfg.usernameClaim == "email" {
+		if verified, _ := claims["email_verified"].(bool); !verified {
+			return authnv1.UserInfo{}, errors.New("email of the token is not verified")
+		}
+	}
+	user := authnv1.UserInfo{Username: v.cfg.usernamePrefix + name}
+	if sub, ok := claims["sub"].(string); ok {
+		user.UID = sub
+	}
+	switch groups := claimValue(claims, v.cfg.groupsClaim).(type) {
+	case string:
+		user.Groups = []string{v.cfg.groupsPrefix + groups}
+	case []any:
+		for _, g := range groups {
+			if g, ok := g.(string); ok {
+				user.Groups = append(user.Groups, v.cfg.groupsPrefix+g)
+			}
+		}
+	}
+	// the issuer must not be able to name Kubernetes' own users and groups, e.g. system:masters
+	if strings.HasPrefix(user.Username, systemPrefix) {
+		return authnv1.UserInfo{}, fmt.Errorf("token user %q is reserved by Kubernetes", user.Username)
+	}
+	for _, g := range user.Groups {
+		if strings.HasPrefix(g, systemPrefix) {
+			return authnv1.UserInfo{}, fmt.Errorf("token group %q is reserved by Kubernetes", g)
+		}
+	}
+	return user, nil
+}
+
+// systemPrefix starts the names of the users and groups Kubernetes reserves for itself
+const systemPrefix = "system:"
+
+// claimValue looks up a claim; dots separate the names of nested claims
+func claimValue(claims map[string]any, path string) any {
+	if v, ok := claims[path]; ok {
+		return v
+	}
+	var v any = claims
+	for _, name := range strings.Split(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[name]
+	}
+	return v
+}
+
+func audienceContains(aud any, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []any:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// numericDate reads a JWT time claim, seconds since the epoch
+func numericDate(v any) (time.Time, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), true
+}
+
+// verifySignature checks the signature of a token with the key of the issuer it names, fetching
+// the keys again once when the key is not known
+func (v *oidcVerifier) verifySignature(ctx context.Context, header jwtHeader, signed, signature []byte) error {
+	if _, ok := jwsHashes[header.Alg]; !ok && header.Alg != "EdDSA" {
+		return fmt.Errorf("unsupported token signature algorithm %q", header.Alg)
+	}
+	keys, err := v.signingKeys(ctx, header.Kid, false)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		if keys, err = v.signingKeys(ctx, header.Kid, true); err != nil {
+			return err
+		}
+	}
+	for _, key := range keys {
+		if verifyJWS(header.Alg, key, signed, signature) == nil {
+			return nil
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("token signed with unknown key %q", header.Kid)
+	}
+	return errors.New("invalid token signature")
+}
+
+// signingKeys returns the keys with ID kid, or all keys for tokens without one
+func (v *oidcVerifier) signingKeys(ctx context.Context, kid string, refresh bool) ([]crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.fetched.IsZero() || (refresh && v.now().Sub(v.fetched) >= oidcKeyRefreshInterval) {
+		if err := v.fetchKeys(ctx); err != nil {
+			return nil, err
+		}
+	}
+	var keys []crypto.PublicKey
+	for _, k := range v.keys {
+		if kid == "" || k.id == kid {
+			keys = append(keys, k.key)
+		}
+	}
+	return keys, nil
+}
+
+// fetchKeys reads the key set named by the discovery document of the issuer
+func (v *oidcVerifier) fetchKeys(ctx context.Context) error {
+	if v.jwksURI == "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := v.getJSON(ctx, strings.TrimSuffix(v.cfg.issuerURL, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return fmt.Errorf("OIDC discovery failed: %w", err)
+		}
+		if discovery.Issuer != v.cfg.issuerURL {
+			return fmt.Errorf("OIDC discovery names issuer %q, not %q", discovery.Issuer, v.cfg.issuerURL)
+		}
+		if discovery.JWKSURI == "" {
+			return errors.New("OIDC discovery names no jwks_uri")
+		}
+		v.jwksURI = discovery.JWKSURI
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := v.getJSON(ctx, v.jwksURI, &set); err != nil {
+		return fmt.Errorf("error fetching OIDC keys: %w", err)
+	}
+	var keys []oidcKey
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// keys of unsupported types are skipped; tokens signed with them fail as unknown keys
+		if key, err := jwk.publicKey(); err == nil {
+			keys = append(keys, oidcKey{id: jwk.Kid, key: key})
+		}
+	}
+	v.keys = keys
+	v.fetched = v.now()
+	return nil
+}
+
+func (v *oidcVerifier) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxOIDCDocumentBytes)).Decode(out)
+}
+
+// jsonWebKey is a public key of a JWKS (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA exponent out of range")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("EC key is not on its curve")
+		}
+		return key, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// jwsHashes are the hashes of the supported JWS algorithms (RFC 7518) other than EdDSA
+var jwsHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// jwsCurves are the curves the ES algorithms sign with (RFC 7518 3.4)
+var jwsCurves = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(), "ES384": elliptic.P384(), "ES512": elliptic.P521(),
+}
+
+// verifyJWS checks a JWS signature made with alg by the private key of key
+func verifyJWS(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	if alg == "EdDSA" {
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(pub, signed, signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	hash := jwsHashes[alg]
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+	switch alg[:2] {
+	case "RS":
+		if pub, ok := key.(*rsa.PublicKey); ok {
+			return rsa.VerifyPKCS1v15(pub, hash, digest, signature)
+		}
+	case "PS":
+		if pub, ok := key.(*rsa.PublicKey); ok {
+			return rsa.VerifyPSS(pub, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != jwsCurves[alg] {
+			break
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if ecdsa.Verify(pub, digest, r, s) {
+			return nil
+		}
+		return errors.New("invalid signature")
+	}
+	return fmt.Errorf("key does not fit %s", alg)
+}
@@ -0,0 +1,339 @@
+package buildapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"sigs.k8s.io/yaml"
+)
+
+// openAPISchemaTypes are the request and response bodies whose schemas in /openapi.json are
+// generated from the Go types; types they refer to are generated as well
+var openAPISchemaTypes = []any{
+	BuildRequest{},
+	BuildResponse{},
+	BuildListItem{},
+	BuildWatchEvent{},
+	BuildV2{},
+	BuildListV2{},
+	Problem{},
+	BuildDeleteResponse{},
+	BuildCloneRequest{},
+	BuildPatchRequest{},
+	BuildTemplateResponse{},
+	ArtifactManifestResponse{},
+	ComplianceResponse{},
+	BuildEventsResponse{},
+	DefinesCatalogResponse{},
+	HardeningCatalogResponse{},
+	BuildStatsResponse{},
+	FailureAnalyticsResponse{},
+	CapabilitiesResponse{},
+	LogSearchResponse{},
+	LogStreamEvent{},
+	PolicyEvaluationResponse{},
+	ManifestValidationRequest{},
+	ManifestValidationResponse{},
+	ErrorResponse{},
+	ForbiddenResponse{},
+	InfoResponse{},
+	HealthResponse{},
+	UploadSessionRequest{},
+	UploadSession{},
+}
+
+// schemaKeys describe the shape of a schema; they always come from the Go type, while other keys
+// of the hand-written schema (descriptions, enums, defaults, limits) are kept
+var schemaKeys = map[string]bool{
+	"$ref":                 true,
+	"type":                 true,
+	"items":                true,
+	"properties":           true,
+	"additionalProperties": true,
+	"allOf":                true,
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIJSON []byte
+	openAPIErr  error
+)
+
+// openAPIDocument returns the OpenAPI document served at /openapi.json: the paths of the embedded
+// openapi.yaml with component schemas generated from the Go types, so they cannot drift apart
+func openAPIDocument() ([]byte, error) {
+	openAPIOnce.Do(func() {
+		openAPIJSON, openAPIErr = buildOpenAPIDocument(embeddedOpenAPI)
+	})
+	return openAPIJSON, openAPIErr
+}
+
+func buildOpenAPIDocument(spec []byte) ([]byte, error) {
+	raw, err := yaml.YAMLToJSON(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid openapi.yaml: %w", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	components, _ := doc["components"].(map[string]any)
+	if components == nil {
+		components = map[string]any{}
+		doc["components"] = components
+	}
+	written, _ := components["schemas"].(map[string]any)
+	if written == nil {
+		written = map[string]any{}
+	}
+
+	g := &schemaGenerator{schemas: map[string]map[string]any{}}
+	for _, v := range openAPISchemaTypes {
+		g.schema(reflect.TypeOf(v))
+	}
+	// nested objects written inline in openapi.yaml document the generated schemas they refer to
+	for found := true; found; {
+		found = false
+		for name, s := range g.schemas {
+			w, _ := written[name].(map[string]any)
+			genProps, _ := s["properties"].(map[string]any)
+			for prop, p := range writtenProperties(w) {
+				target, inline := inlineDoc(genProps[prop], p)
+				if _, ok := written[target]; target != "" && !ok && inline != nil {
+					written[target] = inline
+					found = true
+				}
+			}
+		}
+	}
+	schemas := make(map[string]any, len(written)+len(g.schemas))
+	for name, s := range written {
+		schemas[name] = s
+	}
+	for name, s := range g.schemas {
+		w, _ := written[name].(map[string]any)
+		schemas[name] = mergeSchema(s, w)
+	}
+	components["schemas"] = schemas
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// schemaGenerator derives JSON schemas from Go types the way encoding/json marshals them
+type schemaGenerator struct {
+	schemas map[string]map[string]any
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if _, ok := g.schemas[t.Name()]; !ok {
+			// registered before the fields are walked, so recursive types terminate
+			g.schemas[t.Name()] = map[string]any{}
+			g.schemas[t.Name()] = g.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return map[string]any{}
+}
+
+func (g *schemaGenerator) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	g.addFields(t, props)
+	return map[string]any{"type": "object", "properties": props}
+}
+
+func (g *schemaGenerator) addFields(t reflect.Type, props map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(ft, props)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+	}
+}
+
+// mergeSchema completes a generated schema with the documentation of the hand-written one.
+// Properties the Go type does not have are dropped.
+func mergeSchema(generated, written map[string]any) map[string]any {
+	if written == nil {
+		return generated
+	}
+	out := map[string]any{}
+	for k, v := range written {
+		if !schemaKeys[k] {
+			out[k] = v
+		}
+	}
+	for k, v := range generated {
+		out[k] = v
+	}
+
+	genProps, _ := generated["properties"].(map[string]any)
+	writtenProps := writtenProperties(written)
+	for name, p := range genProps {
+		gp, _ := p.(map[string]any)
+		wp, _ := writtenProps[name].(map[string]any)
+		genProps[name] = mergeProperty(gp, wp)
+	}
+	if req, ok := out["required"].([]any); ok {
+		var kept []any
+		for _, r := range req {
+			if name, _ := r.(string); genProps[name] != nil {
+				kept = append(kept, r)
+			}
+		}
+		if len(kept) == 0 {
+			delete(out, "required")
+		} else {
+			out["required"] = kept
+		}
+	}
+	return out
+}
+
+// inlineDoc returns the schema a generated property refers to, directly or as array items, and
+// the inline object the hand-written property describes it with
+func inlineDoc(generated, written any) (string, map[string]any) {
+	gp, _ := generated.(map[string]any)
+	wp, _ := written.(map[string]any)
+	if gp == nil || wp == nil {
+		return "", nil
+	}
+	if items, ok := gp["items"].(map[string]any); ok {
+		gp = items
+		wp, _ = wp["items"].(map[string]any)
+	}
+	ref, _ := gp["$ref"].(string)
+	if ref == "" || wp == nil || wp["properties"] == nil {
+		return "", nil
+	}
+	return strings.TrimPrefix(ref, "#/components/schemas/"), wp
+}
+
+// mergeProperty keeps the documentation of a hand-written property. A $ref cannot have siblings
+// in OpenAPI 3.0, so a documented reference is wrapped in allOf.
+func mergeProperty(generated, written map[string]any) map[string]any {
+	doc := map[string]any{}
+	for k, v := range written {
+		// required lists of inline objects belong to the schema the property refers to
+		if !schemaKeys[k] && k != "required" {
+			doc[k] = v
+		}
+	}
+	if len(doc) == 0 {
+		return generated
+	}
+	if _, ok := generated["$ref"]; ok {
+		doc["allOf"] = []any{generated}
+		return doc
+	}
+	for k, v := range generated {
+		doc[k] = v
+	}
+	return doc
+}
+
+// writtenProperties returns the properties of a hand-written schema, including the ones of
+// schemas combined with allOf
+func writtenProperties(written map[string]any) map[string]any {
+	props := map[string]any{}
+	if p, ok := written["properties"].(map[string]any); ok {
+		for k, v := range p {
+			props[k] = v
+		}
+	}
+	if all, ok := written["allOf"].([]any); ok {
+		for _, part := range all {
+			if m, ok := part.(map[string]any); ok {
+				for k, v := range writtenProperties(m) {
+					props[k] = v
+				}
+			}
+		}
+	}
+	return props
+}
+
+func getOpenAPIJSON(c *gin.Context) {
+	doc, err := openAPIDocument()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/json", doc)
+}
+
+// swaggerUIPage renders /openapi.json with Swagger UI. The assets are loaded from a CDN, so the
+// page needs internet access in the browser; the document itself is served by the build API.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Automotive Build API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+    };
+  </script>
+</body>
+</html>
+`
+
+func getDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
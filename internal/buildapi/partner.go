@@ -0,0 +1,131 @@
+package buildapi
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/tasks"
+)
+
+const (
+	// tenancyLabel marks the ImageBuilds of partner namespaces with partnerTenancy
+	tenancyLabel   = "automotive.sdv.cloud.redhat.com/tenancy"
+	partnerTenancy = "partner"
+
+	// partnerWatermarkPath is written into the images of partner builds
+	partnerWatermarkPath = "/etc/automotive-partner-build"
+
+	// defaultPartnerMaxActiveBuilds is how many unfinished builds a partner namespace may have when
+	// the profile does not say
+	defaultPartnerMaxActiveBuilds = 2
+)
+
+// partnerDeniedRoutes are the routes partner namespaces may not use whatever their RBAC grants:
+// they reach into the build pod or the workspace beyond the artifacts of the build
+var partnerDeniedRoutes = []string{
+	"GET /v1/builds/:name/exec",
+	"POST /v1/builds/:name/exec",
+	"GET /v1/builds/:name/workspace",
+}
+
+// loadPartnerIsolation returns the partner profile of the OperatorConfig when namespace is one of
+// its partner namespaces, else nil. The OperatorConfig is read with the service account: partners
+// may not read it, and the profile must not depend on what they may read.
+func loadPartnerIsolation(ctx context.Context, namespace string) (*automotivev1alpha1.PartnerIsolationConfig, error) {
+	k8sClient, err := serviceClient()
+	if err != nil {
+		return nil, err
+	}
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: "config", Namespace: resolveNamespace()}, operatorConfig); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if operatorConfig.Spec.OSBuilds == nil {
+		return nil, nil
+	}
+	profile := operatorConfig.Spec.OSBuilds.PartnerIsolation
+	if profile == nil || !slices.Contains(profile.Namespaces, namespace) {
+		return nil, nil
+	}
+	return profile, nil
+}
+
+// applyPartnerProfile rejects what a validated request of a partner namespace may not ask for,
+// pins it to the runtime and storage classes of the profile and watermarks its manifest
+func applyPartnerProfile(req *BuildRequest, inputs *buildInputs, profile *automotivev1alpha1.PartnerIsolationConfig, namespace, requestedBy string) error {
+	switch {
+	case inputs.testUser != "" || len(req.SSHKeys) > 0:
+		return fmt.Errorf("partner builds cannot inject testUser or sshKeys")
+	case inputs.source != nil:
+		return fmt.Errorf("partner builds need the manifest in the request, not a source")
+	case len(req.NetworkExceptions) > 0:
+		return fmt.Errorf("network exceptions are not granted to partner builds")
+	case inputs.debug != nil:
+		return fmt.Errorf("partner builds cannot keep the build pod for debugging")
+	case inputs.commitStatus != nil:
+		return fmt.Errorf("partner builds cannot post commit statuses with the credentials of the operator")
+	case len(inputs.webhooks) > 0:
+		// the controller would send requests to URLs of the partner's choosing from inside the cluster
+		return fmt.Errorf("partner builds cannot notify webhooks")
+	case inputs.remoteFiles != nil:
+		// so would the upload pod downloading add_files URLs
+		return fmt.Errorf("partner builds cannot have add_files downloaded from URLs; upload the files")
+	case len(req.AIBOverrideArgs) > 0:
+		return fmt.Errorf("partner builds cannot override the automotive-image-builder arguments")
+	case req.AutomotiveImageBuilder != tasks.AutomotiveImageBuilder:
+		return fmt.Errorf("partner builds run the default automotive-image-builder image")
+	case req.RuntimeClassName != "" && req.RuntimeClassName != profile.RuntimeClassName:
+		return fmt.Errorf("partner builds run with runtime class %s", profile.RuntimeClassName)
+	case req.StorageClass != "" && req.StorageClass != profile.StorageClass:
+		return fmt.Errorf("partner builds use storage class %s", profile.StorageClass)
+	}
+	req.RuntimeClassName = profile.RuntimeClassName
+	req.StorageClass = profile.StorageClass
+
+	watermark := fmt.Sprintf("Partner build %s/%s requested by %s\n", namespace, req.Name, requestedBy)
+	if notice := strings.TrimSpace(profile.Watermark); notice != "" {
+		watermark += notice + "\n"
+	}
+	manifest, err := addManifestFile(req.Manifest, partnerWatermarkPath, watermark)
+	if err != nil {
+		return err
+	}
+	req.Manifest = manifest
+	return nil
+}
+
+// markPartnerBuild labels the ImageBuild of a partner build and keeps its artifacts off a Route;
+// partners download them through the build API
+func markPartnerBuild(imageBuild *automotivev1alpha1.ImageBuild) {
+	imageBuild.Labels[tenancyLabel] = partnerTenancy
+	imageBuild.Spec.ExposeRoute = false
+}
+
+// partnerQuotaViolation describes why namespace may not start another build under the limit of
+// unfinished builds of profile; empty when it may
+func partnerQuotaViolation(ctx context.Context, k8sClient client.Client, namespace string, profile *automotivev1alpha1.PartnerIsolationConfig) (string, error) {
+	builds := &automotivev1alpha1.ImageBuildList{}
+	if err := k8sClient.List(ctx, builds, client.InNamespace(namespace)); err != nil {
+		return "", fmt.Errorf("error listing builds: %w", err)
+	}
+	limit := int(profile.MaxActiveBuilds)
+	if limit <= 0 {
+		limit = defaultPartnerMaxActiveBuilds
+	}
+	active := 0
+	for _, build := range builds.Items {
+		if !isFinishedPhase(build.Status.Phase) {
+			active++
+		}
+	}
+	if active >= limit {
+		return fmt.Sprintf("partner namespace %s has %d unfinished builds, the limit is %d", namespace, active, limit), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,119 @@
+package buildapi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/pipelinegraph"
+)
+
+// pipelineLabel is the label Tekton puts on the runs of a pipeline
+const pipelineLabel = "tekton.dev/pipeline"
+
+func (a *APIServer) handleGetPipelineGraph(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("pipeline graph requested", "pipeline", name, "run", c.Query("run"), "format", c.Query("format"), "reqID", c.GetString("reqID"))
+	a.getPipelineGraph(c, name)
+}
+
+// getPipelineGraph serves the dependency graph of a Pipeline, such as the automotive-build-pipeline
+// the operator deploys, with the status of every task in the run query parameter or else the latest
+// run. Pipelines and their runs are read as the build API's service account: callers need no access
+// to Tekton objects, only to builds of the namespace.
+func (a *APIServer) getPipelineGraph(c *gin.Context, name string) {
+	namespace := requestNamespace(c)
+	ctx := c.Request.Context()
+
+	format := strings.ToLower(strings.TrimSpace(c.Query("format")))
+	switch format {
+	case "":
+		format = pipelinegraph.FormatJSON
+	case pipelinegraph.FormatJSON, pipelinegraph.FormatDOT:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("format must be %s or %s", pipelinegraph.FormatJSON, pipelinegraph.FormatDOT)})
+		return
+	}
+
+	k8sClient, err := serviceClient()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	pipeline := &tektonv1.Pipeline{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, pipeline); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("pipeline %s not found in namespace %s", name, namespace)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching pipeline: %v", err)})
+		return
+	}
+	graph := pipelinegraph.New(pipeline)
+
+	run, status, err := pipelineRun(c, k8sClient, namespace, name, c.Query("run"))
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if run != nil {
+		var taskRuns []tektonv1.TaskRun
+		for _, child := range run.Status.ChildReferences {
+			tr := tektonv1.TaskRun{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: child.Name, Namespace: namespace}, &tr); err != nil {
+				if k8serrors.IsNotFound(err) {
+					continue
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching task run %s: %v", child.Name, err)})
+				return
+			}
+			taskRuns = append(taskRuns, tr)
+		}
+		graph.SetRun(run, taskRuns)
+	}
+
+	if format == pipelinegraph.FormatDOT {
+		c.Data(http.StatusOK, pipelinegraph.MediaTypeDOT+"; charset=utf-8", []byte(graph.DOT()))
+		return
+	}
+	c.JSON(http.StatusOK, graph)
+}
+
+// pipelineRun returns the run of pipeline named runName, or the latest run when runName is empty;
+// nil when the pipeline has not run. The status code goes with the error.
+func pipelineRun(c *gin.Context, k8sClient client.Client, namespace, pipeline, runName string) (*tektonv1.PipelineRun, int, error) {
+	ctx := c.Request.Context()
+	if runName = strings.TrimSpace(runName); runName != "" {
+		run := &tektonv1.PipelineRun{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: runName, Namespace: namespace}, run); err != nil {
+			if k8serrors.IsNotFound(err) {
+				return nil, http.StatusNotFound, fmt.Errorf("pipeline run %s not found in namespace %s", runName, namespace)
+			}
+			return nil, http.StatusInternalServerError, fmt.Errorf("error fetching pipeline run: %w", err)
+		}
+		if run.Labels[pipelineLabel] != pipeline {
+			return nil, http.StatusBadRequest, fmt.Errorf("%s is not a run of pipeline %s", runName, pipeline)
+		}
+		return run, http.StatusOK, nil
+	}
+
+	runs := &tektonv1.PipelineRunList{}
+	if err := k8sClient.List(ctx, runs, client.InNamespace(namespace), client.MatchingLabels{pipelineLabel: pipeline}); err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("error listing pipeline runs: %w", err)
+	}
+	var latest *tektonv1.PipelineRun
+	for i := range runs.Items {
+		run := &runs.Items[i]
+		if latest == nil || latest.CreationTimestamp.Before(&run.CreationTimestamp) ||
+			latest.CreationTimestamp.Equal(&run.CreationTimestamp) && latest.Name < run.Name {
+			latest = run
+		}
+	}
+	return latest, http.StatusOK, nil
+}
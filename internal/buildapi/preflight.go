@@ -0,0 +1,414 @@
+package buildapi
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	aibmanifest "github.com/centos-automotive-suite/automotive-dev-operator/pkg/manifest"
+)
+
+const (
+	// imageResolveTimeout bounds the resolution of one image, imagePreflightTimeout all of them
+	imageResolveTimeout   = 10 * time.Second
+	imagePreflightTimeout = 30 * time.Second
+	// imagePreflightWorkers is how many images are resolved at the same time
+	imagePreflightWorkers = 4
+)
+
+// registryHTTPClient is used to resolve the container images of manifests
+var registryHTTPClient = &http.Client{Timeout: imageResolveTimeout}
+
+var (
+	// errImageNotFound is a registry answering that the repository, tag or digest does not exist
+	errImageNotFound = errors.New("not found")
+	// errImageDenied is a registry refusing access. Some registries also answer anonymous requests
+	// for repositories that do not exist this way.
+	errImageDenied = errors.New("access denied")
+)
+
+// manifestAccept lists the manifest media types a registry may answer with
+var manifestAccept = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+// imageRef is a container image in a registry
+type imageRef struct {
+	// host is the registry, e.g. quay.io; Docker Hub is registry-1.docker.io
+	host string
+	// repo is the repository, e.g. centos-sig-automotive/autosd-sample or library/alpine
+	repo string
+	// reference is a tag or a digest
+	reference string
+	// source is the reference as written in the manifest
+	source string
+}
+
+// parseImageRef parses a reference such as quay.io/org/app:v1, alpine or registry:5000/app@sha256:...
+func parseImageRef(s string) (imageRef, error) {
+	ref := imageRef{source: s}
+	name := s
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.reference = name[:i], name[i+1:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.reference = name[:i], name[i+1:]
+	}
+	if ref.reference == "" {
+		ref.reference = "latest"
+	}
+
+	first, rest, found := strings.Cut(name, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.host, ref.repo = first, rest
+	} else {
+		ref.host, ref.repo = "docker.io", name
+	}
+	if ref.host == "docker.io" || ref.host == "index.docker.io" {
+		ref.host = "registry-1.docker.io"
+		if !strings.Contains(ref.repo, "/") {
+			ref.repo = "library/" + ref.repo
+		}
+	}
+	if ref.repo == "" || strings.ContainsAny(ref.repo, " \t") {
+		return imageRef{}, fmt.Errorf("invalid image reference %q", s)
+	}
+	return ref, nil
+}
+
+// manifestContainerImages returns the images of content.container_images and
+// qm.content.container_images in an automotive-image-builder manifest. Images from local
+// container storage are left out, since no registry can resolve them.
+func manifestContainerImages(manifest string) ([]string, error) {
+	m, err := aibmanifest.Parse([]byte(manifest))
+	if err != nil {
+		return nil, err
+	}
+	var images []aibmanifest.ContainerImage
+	if m.Content != nil {
+		images = append(images, m.Content.ContainerImages...)
+	}
+	if m.QM != nil && m.QM.Content != nil {
+		images = append(images, m.QM.Content.ContainerImages...)
+	}
+	var refs []string
+	seen := map[string]bool{}
+	for _, img := range images {
+		source := strings.TrimSpace(img.Source)
+		if source == "" || img.Transport == "containers-storage" || strings.HasPrefix(source, "localhost/") {
+			continue
+		}
+		// a variable of the manifest, e.g. $app_image, is only known to automotive-image-builder
+		if strings.Contains(source, "$") || strings.Contains(img.Tag, "$") || strings.Contains(img.Digest, "$") {
+			continue
+		}
+		ref := source
+		switch {
+		case img.Digest != "":
+			ref = source + "@" + img.Digest
+		case img.Tag != "":
+			ref = source + ":" + img.Tag
+		}
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// registryAuth is a credential for one registry host
+type registryAuth struct {
+	username, password string
+	// token is sent as bearer token instead of a username and password
+	token string
+}
+
+// registryAuths reads the credentials of a build request, keyed by registry host
+func registryAuths(creds *RegistryCredentials) map[string]registryAuth {
+	auths := map[string]registryAuth{}
+	if creds == nil || !creds.Enabled {
+		return auths
+	}
+	host := registryHost(creds.RegistryURL)
+	switch creds.AuthType {
+	case "username-password":
+		auths[host] = registryAuth{username: creds.Username, password: creds.Password}
+	case "token":
+		auths[host] = registryAuth{token: creds.Token}
+	case "docker-config":
+		var cfg struct {
+			Auths map[string]struct {
+				Auth string `json:"auth"`
+			} `json:"auths"`
+		}
+		if json.Unmarshal([]byte(creds.DockerConfig), &cfg) != nil {
+			return auths
+		}
+		for h, a := range cfg.Auths {
+			raw, err := base64.StdEncoding.DecodeString(a.Auth)
+			if err != nil {
+				continue
+			}
+			if user, pass, ok := strings.Cut(string(raw), ":"); ok {
+				auths[registryHost(h)] = registryAuth{username: user, password: pass}
+			}
+		}
+	}
+	return auths
+}
+
+// registryHost reduces a registry URL such as https://quay.io/v2/ to its host
+func registryHost(s string) string {
+	s = strings.TrimSpace(s)
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		s = u.Host
+	}
+	s, _, _ = strings.Cut(s, "/")
+	if s == "docker.io" || s == "index.docker.io" {
+		return "registry-1.docker.io"
+	}
+	return s
+}
+
+// imageResolver looks up image manifests with the registry HTTP API
+type imageResolver struct {
+	client *http.Client
+	auths  map[string]registryAuth
+}
+
+func (r *imageResolver) hasAuth(host string) bool {
+	_, ok := r.auths[host]
+	return ok
+}
+
+// resolve returns the digest of the manifest ref points to
+func (r *imageResolver) resolve(ctx context.Context, ref imageRef) (string, error) {
+	endpoint := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.host, ref.repo, ref.reference)
+	auth, hasAuth := r.auths[ref.host]
+
+	resp, err := r.manifestRequest(ctx, endpoint, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		authorization, err := r.authorize(ctx, challenge, ref, auth, hasAuth)
+		if err != nil {
+			return "", err
+		}
+		if resp, err = r.manifestRequest(ctx, endpoint, authorization); err != nil {
+			return "", err
+		}
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+			return digest, nil
+		}
+		return ref.reference, nil
+	case http.StatusNotFound:
+		return "", errImageNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", errImageDenied
+	}
+	return "", fmt.Errorf("registry %s answered %s", ref.host, resp.Status)
+}
+
+// manifestRequest asks for a manifest without its body, falling back to GET for registries that
+// do not allow HEAD
+func (r *imageResolver) manifestRequest(ctx context.Context, endpoint, authorization string) (*http.Response, error) {
+	send := func(method string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", manifestAccept)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		return r.client.Do(req)
+	}
+	resp, err := send(http.MethodHead)
+	if err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		return resp, err
+	}
+	resp.Body.Close()
+	return send(http.MethodGet)
+}
+
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authorize answers the WWW-Authenticate challenge of a registry with the Authorization header to
+// retry with: a token from the token service for Bearer challenges, the credentials for Basic ones
+func (r *imageResolver) authorize(ctx context.Context, challenge string, ref imageRef, auth registryAuth, hasAuth bool) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if !hasAuth || auth.token != "" {
+			return "", errImageDenied
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.username+":"+auth.password)), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("registry %s asks for unsupported authentication %q", ref.host, scheme)
+	}
+	if hasAuth && auth.token != "" {
+		return "Bearer " + auth.token, nil
+	}
+
+	values := map[string]string{}
+	for _, m := range challengeParam.FindAllStringSubmatch(params, -1) {
+		values[m[1]] = m[2]
+	}
+	realm, err := url.Parse(values["realm"])
+	if err != nil || realm.Scheme == "" {
+		return "", fmt.Errorf("registry %s sent an invalid token realm %q", ref.host, values["realm"])
+	}
+	q := realm.Query()
+	if values["service"] != "" {
+		q.Set("service", values["service"])
+	}
+	q.Set("scope", "repository:"+ref.repo+":pull")
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if hasAuth {
+		req.SetBasicAuth(auth.username, auth.password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return "", errImageDenied
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token service of %s answered %s", ref.host, resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return "", fmt.Errorf("token service of %s: %w", ref.host, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// imagePreflight is the outcome of resolving the container images of a manifest
+type imagePreflight struct {
+	// resolved maps references to the digests they resolved to
+	resolved map[string]string
+	// unresolved lists references registries answered do not exist, or may not be pulled with the
+	// credentials of the request, with the reason
+	unresolved []string
+	// skipped lists references that could not be checked, e.g. because the registry was unreachable
+	skipped []string
+}
+
+// preflightImages resolves every container image req embeds. Images that could not be checked do
+// not fail the preflight: the build pod may reach registries the build API cannot.
+func preflightImages(ctx context.Context, client *http.Client, req BuildRequest) (*imagePreflight, error) {
+	refs, err := manifestContainerImages(req.Manifest)
+	if err != nil {
+		return nil, err
+	}
+	result := &imagePreflight{resolved: map[string]string{}}
+	if len(refs) == 0 {
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, imagePreflightTimeout)
+	defer cancel()
+	resolver := &imageResolver{client: client, auths: registryAuths(req.RegistryCredentials)}
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, imagePreflightWorkers)
+	)
+	for _, s := range refs {
+		wg.Add(1)
+		go func(s string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			ref, err := parseImageRef(s)
+			var digest string
+			if err == nil {
+				resolveCtx, cancel := context.WithTimeout(ctx, imageResolveTimeout)
+				digest, err = resolver.resolve(resolveCtx, ref)
+				cancel()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				result.resolved[s] = digest
+			case errors.Is(err, errImageNotFound), ref.host == "":
+				result.unresolved = append(result.unresolved, fmt.Sprintf("%s (%v)", s, err))
+			case errors.Is(err, errImageDenied) && resolver.hasAuth(ref.host):
+				// denied without credentials is skipped: the build pod may be granted access by other means
+				result.unresolved = append(result.unresolved, fmt.Sprintf("%s (%v)", s, err))
+			default:
+				result.skipped = append(result.skipped, fmt.Sprintf("%s (%v)", s, err))
+			}
+		}(s)
+	}
+	wg.Wait()
+	sort.Strings(result.unresolved)
+	sort.Strings(result.skipped)
+	return result, nil
+}
+
+// check reports the preflight as the images policy check
+func (p *imagePreflight) check() PolicyCheck {
+	switch {
+	case len(p.unresolved) > 0:
+		return PolicyCheck{Name: "images", Message: "unresolvable container images: " + strings.Join(p.unresolved, ", ")}
+	case len(p.skipped) > 0:
+		return PolicyCheck{Name: "images", Passed: true, Message: "could not check: " + strings.Join(p.skipped, ", ")}
+	case len(p.resolved) > 0:
+		return PolicyCheck{Name: "images", Passed: true, Message: fmt.Sprintf("%d container images resolved", len(p.resolved))}
+	}
+	return PolicyCheck{Name: "images", Passed: true}
+}
+
+// containerImagesCheck resolves the container images req embeds before the build starts, so a
+// missing tag fails the request instead of the osbuild pipeline. The unresolvable references are
+// returned with the check. BUILD_API_IMAGE_PREFLIGHT=false turns the check off, e.g. for clusters
+// whose build API cannot reach any registry.
+func containerImagesCheck(ctx context.Context, req BuildRequest) (PolicyCheck, []string) {
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("BUILD_API_IMAGE_PREFLIGHT")), "false") {
+		return PolicyCheck{Name: "images", Skipped: true, Message: "image preflight is disabled"}, nil
+	}
+	result, err := preflightImages(ctx, registryHTTPClient, req)
+	if err != nil {
+		return PolicyCheck{Name: "images", Skipped: true, Message: err.Error()}, nil
+	}
+	return result.check(), result.unresolved
+}
@@ -0,0 +1,237 @@
+package buildapi
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-logr/logr"
+	"golang.org/x/time/rate"
+)
+
+// RateLimits bound how much of the build API each client may use, so a misbehaving CI job cannot
+// starve interactive users. Clients are identified by the user their token belongs to or, for
+// requests without a token, by their IP address.
+type RateLimits struct {
+	// RequestsPerSecond is the sustained request rate of each client; 0 disables the limit
+	RequestsPerSecond float64
+	// RequestsPerSecondPerIP is the sustained request rate of each IP address, counted before the
+	// token of a request is checked; 0 disables the limit
+	RequestsPerSecondPerIP float64
+	// Burst is how many requests a client may make at once; it defaults to RequestsPerSecond
+	Burst int
+	// MaxConcurrentDownloads is how many artifact downloads and log streams each client may have
+	// open at the same time; 0 disables the limit
+	MaxConcurrentDownloads int
+}
+
+// rateLimitsFromEnv reads the limits the operator passes from the OperatorConfig
+func rateLimitsFromEnv() RateLimits {
+	var limits RateLimits
+	if v, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv("BUILD_API_RATE_LIMIT")), 64); err == nil && v > 0 {
+		limits.RequestsPerSecond = v
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv("BUILD_API_RATE_LIMIT_BURST"))); err == nil && v > 0 {
+		limits.Burst = v
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv("BUILD_API_MAX_CONCURRENT_DOWNLOADS"))); err == nil && v > 0 {
+		limits.MaxConcurrentDownloads = v
+	}
+	if v, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv("BUILD_API_RATE_LIMIT_PER_IP")), 64); err == nil && v > 0 {
+		limits.RequestsPerSecondPerIP = v
+	} else {
+		// several users may share an address, behind NAT or a proxy that is not trusted
+		limits.RequestsPerSecondPerIP = addressRateFactor * limits.RequestsPerSecond
+	}
+	return limits
+}
+
+// addressRateFactor is how many times RequestsPerSecond an IP address may make by default
+const addressRateFactor = 10
+
+// trustedProxiesFromEnv returns the proxies whose X-Forwarded-For header identifies clients: the
+// loopback addresses, where the OAuth proxy of the pod connects from, and the addresses and CIDRs
+// of BUILD_API_TRUSTED_PROXIES. Entries that are neither are logged and skipped.
+func trustedProxiesFromEnv(log logr.Logger) []string {
+	proxies := []string{"127.0.0.1", "::1"}
+	for _, entry := range strings.Split(os.Getenv("BUILD_API_TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+			log.Info("ignoring trusted proxy that is neither an address nor a CIDR", "proxy", entry)
+			continue
+		}
+		proxies = append(proxies, entry)
+	}
+	return proxies
+}
+
+// clientIdleTimeout is how long the limiter remembers a client after its last request
+const clientIdleTimeout = 10 * time.Minute
+
+// downloadRetryAfter is the Retry-After of a rejected download; when a running download ends is unknown
+const downloadRetryAfter = 10 * time.Second
+
+// clientLimiter tracks the request rate and open downloads of every client
+type clientLimiter struct {
+	limits RateLimits
+	now    func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*clientUsage
+	lastPrune time.Time
+}
+
+type clientUsage struct {
+	requests  *rate.Limiter
+	downloads int
+	lastSeen  time.Time
+}
+
+func newClientLimiter(limits RateLimits) *clientLimiter {
+	if limits.RequestsPerSecond > 0 && limits.Burst <= 0 {
+		limits.Burst = int(math.Max(1, math.Ceil(limits.RequestsPerSecond)))
+	}
+	return &clientLimiter{limits: limits, now: time.Now, clients: map[string]*clientUsage{}}
+}
+
+// usage returns the usage of client; l.mu must be held
+func (l *clientLimiter) usage(client string, now time.Time) *clientUsage {
+	if now.Sub(l.lastPrune) > clientIdleTimeout {
+		for k, u := range l.clients {
+			if u.downloads == 0 && now.Sub(u.lastSeen) > clientIdleTimeout {
+				delete(l.clients, k)
+			}
+		}
+		l.lastPrune = now
+	}
+	u, ok := l.clients[client]
+	if !ok {
+		u = &clientUsage{requests: rate.NewLimiter(rate.Limit(l.limits.RequestsPerSecond), l.limits.Burst)}
+		l.clients[client] = u
+	}
+	u.lastSeen = now
+	return u
+}
+
+// allow takes a request of client from its rate; when the client is over it, it returns false and
+// how long until the next request is allowed
+func (l *clientLimiter) allow(client string) (bool, time.Duration) {
+	if l.limits.RequestsPerSecond <= 0 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	r := l.usage(client, now).requests.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// acquireDownload opens a download of client unless it has MaxConcurrentDownloads open already;
+// the returned function closes it
+func (l *clientLimiter) acquireDownload(client string) (func(), bool) {
+	if l.limits.MaxConcurrentDownloads <= 0 {
+		return func() {}, true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	u := l.usage(client, l.now())
+	if u.downloads >= l.limits.MaxConcurrentDownloads {
+		return nil, false
+	}
+	u.downloads++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			u.downloads--
+			u.lastSeen = l.now()
+		})
+	}, true
+}
+
+// subjectKey is the context key of the user an authenticated request's token belongs to
+const subjectKey = "subject"
+
+// rateLimitClient identifies the client of a request for rate limiting
+func rateLimitClient(c *gin.Context) string {
+	if subject := c.GetString(subjectKey); subject != "" {
+		return "user:" + subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// tooManyRequests answers a request over a rate limit with 429 and when to retry
+func tooManyRequests(c *gin.Context, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("%s; retry after %ds", message, seconds)})
+	c.Abort()
+}
+
+// addressRateLimit rejects requests of IP addresses over RateLimits.RequestsPerSecondPerIP. It runs
+// before authMiddleware, so requests with invalid tokens cannot cause unlimited token reviews.
+// Probes and /v1/healthz are not counted.
+func (a *APIServer) addressRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if probePath(c.Request.URL.Path) || c.Request.URL.Path == "/v1/healthz" {
+			c.Next()
+			return
+		}
+		client := "ip:" + c.ClientIP()
+		if ok, retryAfter := a.addressLimiter.allow(client); !ok {
+			a.log.Info("rate limited", "client", client, "method", c.Request.Method, "path", c.Request.URL.Path, "reqID", c.GetString("reqID"))
+			tooManyRequests(c, retryAfter, "rate limit exceeded")
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimit rejects requests of clients over RateLimits.RequestsPerSecond. It runs after
+// authMiddleware, so authenticated clients are limited by user rather than by IP address.
+func (a *APIServer) rateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := rateLimitClient(c)
+		if ok, retryAfter := a.limiter.allow(client); !ok {
+			a.log.Info("rate limited", "client", client, "method", c.Request.Method, "path", c.Request.URL.Path, "reqID", c.GetString("reqID"))
+			tooManyRequests(c, retryAfter, "rate limit exceeded")
+			return
+		}
+		c.Next()
+	}
+}
+
+// downloadLimit rejects artifact downloads and log streams of clients that have
+// RateLimits.MaxConcurrentDownloads open already
+func (a *APIServer) downloadLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := rateLimitClient(c)
+		release, ok := a.limiter.acquireDownload(client)
+		if !ok {
+			a.log.Info("too many concurrent downloads", "client", client, "method", c.Request.Method, "path", c.Request.URL.Path, "reqID", c.GetString("reqID"))
+			tooManyRequests(c, downloadRetryAfter,
+				fmt.Sprintf("at most %d concurrent downloads and log streams per client", a.limiter.limits.MaxConcurrentDownloads))
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
@@ -0,0 +1,153 @@
+package buildapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/remotefiles"
+)
+
+// uploadsCompleteAnnotation tells the controller that the client uploaded every local file of a build
+const uploadsCompleteAnnotation = "automotive.sdv.cloud.redhat.com/uploads-complete"
+
+// maxRemoteFiles limits the files a build has the upload pod download; each one is a container
+const maxRemoteFiles = 20
+
+// remoteFileSchemes are the URL schemes of add_files source paths the upload pod downloads
+var remoteFileSchemes = []string{"http", "https", "s3"}
+
+// isRemoteFile reports whether the source_path of an add_files entry is a URL rather than a file
+// the client uploads
+func isRemoteFile(sourcePath string) bool {
+	scheme, _, ok := strings.Cut(sourcePath, "://")
+	return ok && slices.Contains(remoteFileSchemes, strings.ToLower(scheme))
+}
+
+// manifestSourcePaths returns the source_path of every add_files entry of the image and of the QM
+// partition of a manifest
+func manifestSourcePaths(manifest string) ([]string, error) {
+	var data struct {
+		Content struct {
+			AddFiles []map[string]any `json:"add_files"`
+		} `json:"content"`
+		QM struct {
+			Content struct {
+				AddFiles []map[string]any `json:"add_files"`
+			} `json:"content"`
+		} `json:"qm"`
+	}
+	if err := yaml.Unmarshal([]byte(manifest), &data); err != nil {
+		return nil, fmt.Errorf("invalid manifest YAML: %w", err)
+	}
+	var paths []string
+	for _, entry := range append(data.Content.AddFiles, data.QM.Content.AddFiles...) {
+		if sourcePath, ok := entry["source_path"].(string); ok && sourcePath != "" {
+			paths = append(paths, sourcePath)
+		}
+	}
+	return paths, nil
+}
+
+// remoteFilesFromRequest returns the add_files entries of the manifest that the upload pod
+// downloads, with the Secret holding their credentials, and whether the manifest also refers to
+// local files the client uploads. It is nil when no source_path is a URL.
+func remoteFilesFromRequest(req BuildRequest) (*automotivev1alpha1.RemoteFiles, bool, error) {
+	if ref := req.RemoteFilesSecretRef; ref != "" {
+		if errs := validation.IsDNS1123Subdomain(ref); len(errs) > 0 {
+			return nil, false, fmt.Errorf("invalid remoteFilesSecretRef %q: %s", ref, strings.Join(errs, "; "))
+		}
+	}
+	if !strings.Contains(req.Manifest, "://") {
+		if req.RemoteFilesSecretRef != "" {
+			return nil, false, fmt.Errorf("remoteFilesSecretRef is set but no add_files source_path is a URL")
+		}
+		return nil, strings.Contains(req.Manifest, "source_path"), nil
+	}
+	paths, err := manifestSourcePaths(req.Manifest)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var urls []string
+	local := false
+	for _, sourcePath := range paths {
+		if !isRemoteFile(sourcePath) {
+			local = true
+			continue
+		}
+		if err := validateRemoteFile(sourcePath); err != nil {
+			return nil, false, err
+		}
+		if !slices.Contains(urls, sourcePath) {
+			urls = append(urls, sourcePath)
+		}
+	}
+	switch {
+	case len(urls) == 0 && req.RemoteFilesSecretRef != "":
+		return nil, false, fmt.Errorf("remoteFilesSecretRef is set but no add_files source_path is a URL")
+	case len(urls) == 0:
+		return nil, strings.Contains(req.Manifest, "source_path"), nil
+	case len(urls) > maxRemoteFiles:
+		return nil, false, fmt.Errorf("at most %d add_files entries can refer to URLs, got %d", maxRemoteFiles, len(urls))
+	}
+	return &automotivev1alpha1.RemoteFiles{URLs: urls, SecretRef: req.RemoteFilesSecretRef}, local, nil
+}
+
+// validateRemoteFile checks a source_path URL, which must name a file whose remotefiles.Path is a
+// path in the workspace
+func validateRemoteFile(sourcePath string) error {
+	u, err := url.Parse(sourcePath)
+	if err != nil {
+		return fmt.Errorf("invalid add_files source_path %q: %w", sourcePath, err)
+	}
+	if u.Host == "" || strings.Trim(u.Path, "/") == "" || u.User != nil {
+		return fmt.Errorf("invalid add_files source_path %q: URLs need a host and a file path, and no credentials", sourcePath)
+	}
+	if _, err := uploadDestination(remotefiles.Path(sourcePath)); err != nil || strings.Contains(sourcePath, "/../") {
+		return fmt.Errorf("invalid add_files source_path %q", sourcePath)
+	}
+	return nil
+}
+
+// remoteFilesGranted rejects files unless every URL is on a host of spec.osBuilds.remoteFileHosts of
+// the OperatorConfig; the controller checks again, with the s3 endpoint of their Secret
+func remoteFilesGranted(ctx context.Context, files *automotivev1alpha1.RemoteFiles) error {
+	if files == nil {
+		return nil
+	}
+	k8sClient, err := serviceClient()
+	if err != nil {
+		return err
+	}
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: "config", Namespace: resolveNamespace()}, operatorConfig); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	var hosts []string
+	if operatorConfig.Spec.OSBuilds != nil {
+		hosts = operatorConfig.Spec.OSBuilds.RemoteFileHosts
+	}
+	for _, u := range files.URLs {
+		if err := remotefiles.CheckURL(hosts, u); err != nil {
+			return &notGrantedError{reason: fmt.Sprintf("add_files source_path %s: %v", u, err)}
+		}
+	}
+	return nil
+}
+
+// remoteFilesSecretRef is the credentials Secret of the remote files of a build, for its template
+func remoteFilesSecretRef(files *automotivev1alpha1.RemoteFiles) string {
+	if files == nil {
+		return ""
+	}
+	return files.SecretRef
+}
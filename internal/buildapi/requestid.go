@@ -0,0 +1,56 @@
+package buildapi
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDKey is the member of error responses holding the X-Request-ID of the request
+const requestIDKey = "requestID"
+
+// errorResponses logs the error responses of the handlers after it with the request ID, and adds
+// the request ID to error bodies that are JSON objects, so users can quote it when reporting a
+// failure. Other responses, including streams, pass through unchanged.
+func (a *APIServer) errorResponses() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if probePath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		w := &problemWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		if w.body == nil {
+			return
+		}
+		reqID := c.GetString("reqID")
+		body := w.body.Bytes()
+		a.log.Info("request failed", "method", c.Request.Method, "path", c.Request.URL.Path,
+			"status", w.status, "error", responseError(body), "reqID", reqID)
+
+		body = withRequestID(body, reqID)
+		c.Writer.Header().Del("Content-Length")
+		c.Writer.WriteHeader(w.status)
+		_, _ = c.Writer.Write(body)
+	}
+}
+
+// withRequestID adds requestID to a JSON object body that does not hold one yet; other bodies are
+// returned unchanged
+func withRequestID(body []byte, requestID string) []byte {
+	members := map[string]json.RawMessage{}
+	if requestID == "" || json.Unmarshal(body, &members) != nil || members == nil {
+		return body
+	}
+	if _, ok := members[requestIDKey]; ok {
+		return body
+	}
+	members[requestIDKey], _ = json.Marshal(requestID)
+	out, err := json.Marshal(members)
+	if err != nil {
+		return body
+	}
+	return out
+}
@@ -0,0 +1,141 @@
+package buildapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+// retentionFromRequest converts the requested retention of the build name to its ImageBuild form;
+// an empty retention is none
+func retentionFromRequest(name string, req *Retention) (*automotivev1alpha1.Retention, error) {
+	if req == nil {
+		return nil, nil
+	}
+	req.KeepFor = strings.TrimSpace(req.KeepFor)
+	if req.KeepFor == "" && req.KeepLast == 0 {
+		return nil, nil
+	}
+	retention := &automotivev1alpha1.Retention{KeepLast: req.KeepLast, NamePrefix: req.NamePrefix}
+	if req.KeepFor != "" {
+		keepFor, err := parseStatsWindow(req.KeepFor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retention keepFor %q: use e.g. 30d, 72h or 90m", req.KeepFor)
+		}
+		retention.KeepFor = &metav1.Duration{Duration: keepFor}
+	}
+	if req.KeepLast < 0 {
+		return nil, fmt.Errorf("retention keepLast must not be negative")
+	}
+	if req.KeepLast > 0 {
+		if req.NamePrefix == "" {
+			return nil, fmt.Errorf("retention keepLast requires a namePrefix grouping the builds it counts")
+		}
+		if !strings.HasPrefix(name, req.NamePrefix) {
+			return nil, fmt.Errorf("retention namePrefix %q is not a prefix of the build name %s", req.NamePrefix, name)
+		}
+	}
+	return retention, nil
+}
+
+// retentionToRequest converts the ImageBuild retention back to its API form
+func retentionToRequest(spec *automotivev1alpha1.Retention) *Retention {
+	if spec == nil {
+		return nil
+	}
+	retention := &Retention{KeepLast: spec.KeepLast, NamePrefix: spec.NamePrefix}
+	if spec.KeepFor != nil {
+		retention.KeepFor = spec.KeepFor.Duration.String()
+	}
+	return retention
+}
+
+// artifactsExpiry returns when the retention policy deletes the artifacts of build, and when it
+// deleted them, as RFC 3339 times
+func artifactsExpiry(build *automotivev1alpha1.ImageBuild) (expireAt, deletedAt string) {
+	st := build.Status.Retention
+	if st == nil {
+		return "", ""
+	}
+	if st.ExpiryTime != nil {
+		expireAt = st.ExpiryTime.UTC().Format(time.RFC3339)
+	}
+	if st.DeletionTime != nil {
+		deletedAt = st.DeletionTime.UTC().Format(time.RFC3339)
+	}
+	return expireAt, deletedAt
+}
+
+// artifactsDeleted answers 410 for a build whose artifacts the retention policy deleted
+func artifactsDeleted(c *gin.Context, build *automotivev1alpha1.ImageBuild) bool {
+	if st := build.Status.Retention; st != nil && st.DeletionTime != nil {
+		c.JSON(http.StatusGone, gin.H{"error": fmt.Sprintf("the artifacts of build %s were deleted by its retention policy at %s",
+			build.Name, st.DeletionTime.UTC().Format(time.RFC3339))})
+		return true
+	}
+	return false
+}
+
+// patchBuild changes the retention of a build. Artifacts already deleted cannot be brought back.
+func patchBuild(c *gin.Context, name string) {
+	var req BuildPatchRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid JSON: %v", err)})
+		return
+	}
+	if req.Retention == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to change: only retention can be patched"})
+		return
+	}
+	retention, err := retentionFromRequest(name, req.Retention)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	ctx := c.Request.Context()
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+	if st := build.Status.Retention; st != nil && st.DeletionTime != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("the artifacts of build %s were already deleted", name)})
+		return
+	}
+
+	patched := build.DeepCopy()
+	patched.Spec.Retention = retention
+	if err := k8sClient.Patch(ctx, patched, client.MergeFrom(build)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error updating build: %v", err)})
+		return
+	}
+	writeJSON(c, http.StatusOK, BuildResponse{
+		Name:        patched.Name,
+		Phase:       patched.Status.Phase,
+		Message:     "Retention updated",
+		RequestedBy: patched.Annotations["automotive.sdv.cloud.redhat.com/requested-by"],
+		RequestID:   patched.Annotations[requestIDAnnotation],
+		Retention:   retentionToRequest(patched.Spec.Retention),
+	})
+}
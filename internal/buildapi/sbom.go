@@ -0,0 +1,204 @@
+package buildapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	kscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/sbom"
+)
+
+// packageInventories caches the package inventories of completed builds by UID; they are written
+// once by the build and read from the artifact pod otherwise
+var packageInventories sync.Map
+
+// sbomFormat picks the SBOM format of a request: the format query parameter, else the Accept
+// header, else SPDX
+func sbomFormat(query, accept string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(query)) {
+	case sbom.FormatSPDX:
+		return sbom.FormatSPDX, nil
+	case sbom.FormatCycloneDX:
+		return sbom.FormatCycloneDX, nil
+	case "":
+	default:
+		return "", fmt.Errorf("format must be %s or %s", sbom.FormatSPDX, sbom.FormatCycloneDX)
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case sbom.MediaTypeSPDX:
+			return sbom.FormatSPDX, nil
+		case sbom.MediaTypeCycloneDX:
+			return sbom.FormatCycloneDX, nil
+		}
+	}
+	return sbom.FormatSPDX, nil
+}
+
+// sbomSubject describes the artifact of build as the subject of its SBOM
+func sbomSubject(build *automotivev1alpha1.ImageBuild) sbom.Subject {
+	subject := sbom.Subject{
+		Name:           build.Name,
+		Namespace:      build.Namespace,
+		UID:            string(build.UID),
+		Artifact:       strings.TrimSpace(build.Status.ArtifactFileName),
+		ArtifactSHA256: build.Status.ArtifactSHA256,
+		Created:        build.CreationTimestamp.Time,
+	}
+	if build.Status.CompletionTime != nil {
+		subject.Created = build.Status.CompletionTime.Time
+	}
+	return subject
+}
+
+// getBuildSBOM serves the SBOM of a completed build, generated from the inventory of the RPMs the
+// build found installed in the image. 404 when the build has no inventory, e.g. for container or
+// directory exports.
+func (a *APIServer) getBuildSBOM(c *gin.Context, name string) {
+	namespace := requestNamespace(c)
+	ctx := c.Request.Context()
+
+	format, err := sbomFormat(c.Query("format"), c.GetHeader("Accept"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+	if build.Status.Phase != "Completed" {
+		c.JSON(http.StatusConflict, gin.H{"error": "SBOM not available until build completes"})
+		return
+	}
+	fileName := strings.TrimSpace(build.Status.PackagesFileName)
+	if fileName == "" || strings.Contains(fileName, "/") {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("build %s has no package inventory; SBOMs need an image or qcow2 export", name)})
+		return
+	}
+
+	var inventory sbom.Inventory
+	if cached, ok := packageInventories.Load(build.UID); ok {
+		inventory = cached.(sbom.Inventory)
+	} else {
+		if artifactsDeleted(c, build) || artifactsArchiving(c, build) {
+			return
+		}
+		data, ok := a.readArtifactPodFile(c, k8sClient, name, fileName)
+		if !ok {
+			return
+		}
+		if err := json.Unmarshal(data, &inventory); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("invalid package inventory: %v", err)})
+			return
+		}
+		packageInventories.Store(build.UID, inventory)
+	}
+
+	out, err := sbom.Generate(format, sbomSubject(build), inventory)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	a.log.Info("SBOM generated", "build", name, "format", format, "packages", len(inventory.Packages), "reqID", c.GetString("reqID"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s.%s.json", name, format)))
+	c.Data(http.StatusOK, sbom.MediaType(format), out)
+}
+
+// readArtifactPodFile reads a file of the shared workspace through the artifact pod of a build,
+// writing the error response itself when it fails
+func (a *APIServer) readArtifactPodFile(c *gin.Context, k8sClient client.Client, name, fileName string) ([]byte, bool) {
+	namespace := requestNamespace(c)
+	ctx := c.Request.Context()
+
+	var artifactPod *corev1.Pod
+	deadline := time.Now().Add(2 * time.Minute)
+	for artifactPod == nil {
+		podList := &corev1.PodList{}
+		if err := k8sClient.List(ctx, podList,
+			client.InNamespace(namespace),
+			client.MatchingLabels{
+				"app.kubernetes.io/name":                          "artifact-pod",
+				"automotive.sdv.cloud.redhat.com/imagebuild-name": name,
+			}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error listing artifact pods: %v", err)})
+			return nil, false
+		}
+		for i := range podList.Items {
+			p := &podList.Items[i]
+			for _, cs := range p.Status.ContainerStatuses {
+				if p.Status.Phase == corev1.PodRunning && cs.Name == "fileserver" && cs.Ready {
+					artifactPod = p
+				}
+			}
+		}
+		if artifactPod != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "artifact pod not ready"})
+			return nil, false
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	restCfg, err := getRESTConfigFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("rest config: %v", err)})
+		return nil, false
+	}
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("clientset: %v", err)})
+		return nil, false
+	}
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(artifactPod.Name).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: "fileserver",
+			Command:   []string{"cat", "/workspace/shared/" + fileName},
+			Stdout:    true,
+			Stderr:    true,
+		}, kscheme.ParameterCodec)
+	exec, err := remotecommand.NewSPDYExecutor(restCfg, http.MethodPost, req.URL())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("executor (%s): %v", fileName, err)})
+		return nil, false
+	}
+	var out bytes.Buffer
+	if err := exec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &out, Stderr: io.Discard}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("reading %s: %v", fileName, err)})
+		return nil, false
+	}
+	return out.Bytes(), true
+}
@@ -2,40 +2,96 @@ package buildapi
 
 import (
 	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
 	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
 	"os"
 	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/gin-gonic/gin"
 	"github.com/go-logr/logr"
 	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 	kscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	yamlv3 "gopkg.in/yaml.v3"
 
 	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/firstboot"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/hardening"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/storage"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/tasks"
 	authnv1 "k8s.io/api/authentication/v1"
+	authzv1 "k8s.io/api/authorization/v1"
 )
 
 type APIServer struct {
-	server *http.Server
-	router *gin.Engine
-	addr   string
-	log    logr.Logger
+	server  *http.Server
+	router  *gin.Engine
+	addr    string
+	log     logr.Logger
+	limiter *clientLimiter
+	health  *healthChecker
+	audit   *auditLog
+	// addressLimiter limits requests by IP address before they are authenticated
+	addressLimiter *clientLimiter
+	// trustedProxies are the proxies whose X-Forwarded-For header identifies clients
+	trustedProxies []string
+	// accessReviews caches the SubjectAccessReviews of authorizeRoute
+	accessReviews accessReviewCache
+	// signingKey caches the key of signed download URLs
+	signingKey signingKeyCache
+	// oidc validates tokens of an external OIDC issuer; nil when only Kubernetes tokens are accepted
+	oidc *oidcVerifier
+	// namespaces are the namespaces clients may select besides the build API's own; "*" allows all
+	namespaces []string
+	// drainPeriod is how long in-flight requests may finish once the server is stopped
+	drainPeriod time.Duration
+	// unreadyPeriod is how long the server keeps serving once draining, before it stops listening
+	unreadyPeriod time.Duration
+	// draining is set once the server is stopped; readiness fails and new builds are rejected
+	draining atomic.Bool
 }
 
 //go:embed openapi.yaml
@@ -49,7 +105,26 @@ func NewAPIServer(addr string, logger logr.Logger) *APIServer {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	a := &APIServer{addr: addr, log: logger}
+	limits := rateLimitsFromEnv()
+	a := &APIServer{
+		addr:    addr,
+		log:     logger,
+		limiter: newClientLimiter(limits),
+		health:  newHealthChecker(defaultHealthChecks()...),
+		audit:   auditLogFromEnv(logger),
+		// requests are counted by address before their token is checked
+		addressLimiter: newClientLimiter(RateLimits{RequestsPerSecond: limits.RequestsPerSecondPerIP}),
+		trustedProxies: trustedProxiesFromEnv(logger),
+		// builds live in the build API's own namespace unless BUILD_API_NAMESPACES serves more
+		namespaces:    namespacesFromEnv(),
+		drainPeriod:   drainPeriodFromEnv(),
+		unreadyPeriod: defaultUnreadyPeriod,
+	}
+	oidc, err := oidcVerifierFromEnv()
+	if err != nil {
+		logger.Error(err, "OIDC tokens are not accepted")
+	}
+	a.oidc = oidc
 	a.router = a.createRouter()
 	a.server = &http.Server{Addr: addr, Handler: a.router}
 	return a
@@ -68,10 +143,7 @@ func (a *APIServer) Start(ctx context.Context) error {
 	<-ctx.Done()
 	a.log.Info("shutting down build-api server...")
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	if err := a.server.Shutdown(shutdownCtx); err != nil {
+	if err := a.drain(); err != nil {
 		a.log.Error(err, "build-api server forced to shutdown")
 		return err
 	}
@@ -81,14 +153,35 @@ func (a *APIServer) Start(ctx context.Context) error {
 
 func (a *APIServer) createRouter() *gin.Engine {
 	router := gin.New()
+	// gin trusts the X-Forwarded-For header of every client unless told otherwise
+	if err := router.SetTrustedProxies(a.trustedProxies); err != nil {
+		a.log.Error(err, "invalid trusted proxies, trusting none")
+		_ = router.SetTrustedProxies(nil)
+	}
 	router.Use(gin.Recovery())
 
 	router.Use(func(c *gin.Context) {
-		reqID := uuid.New().String()
+		reqID := c.GetHeader("X-Request-ID")
+		if !validRequestID(reqID) {
+			reqID = uuid.New().String()
+		}
 		c.Set("reqID", reqID)
-		a.log.Info("http request", "method", c.Request.Method, "path", c.Request.URL.Path, "reqID", reqID)
+		c.Header("X-Request-ID", reqID)
+		// probes would otherwise fill the log
+		if !probePath(c.Request.URL.Path) {
+			a.log.Info("http request", "method", c.Request.Method, "path", c.Request.URL.Path, "reqID", reqID)
+		}
 		c.Next()
 	})
+	router.Use(a.errorResponses())
+	router.Use(a.audit.middleware())
+	router.Use(a.addressRateLimit())
+
+	router.GET("/openapi.json", getOpenAPIJSON)
+	router.GET("/docs", getDocs)
+	registerUI(router)
+	router.GET("/healthz", a.handleHealthz)
+	router.GET("/readyz", a.handleReadyz)
 
 	v1 := router.Group("/v1")
 	{
@@ -100,23 +193,65 @@ func (a *APIServer) createRouter() *gin.Engine {
 			c.Data(http.StatusOK, "application/yaml", embeddedOpenAPI)
 		})
 
-		v1.GET("/builds/:name/logs/sse", a.handleStreamLogsSSE)
+		v1.GET("/info", a.rateLimit(), getInfo)
+
+		v1.GET("/builds/:name/logs/sse", a.authMiddleware(), a.rateLimit(), a.downloadLimit(), a.handleStreamLogsSSE)
+		v1.Match([]string{http.MethodGet, http.MethodHead}, "/downloads/:token", a.signedDownloadAuth(), a.rateLimit(), a.downloadLimit(), a.handleSignedDownload)
 
 		buildsGroup := v1.Group("/builds")
-		buildsGroup.Use(a.authMiddleware())
+		buildsGroup.Use(a.authMiddleware(), a.rateLimit())
 		{
-			buildsGroup.POST("", a.handleCreateBuild)
+			buildsGroup.POST("", a.readOnlyGuard(), a.handleCreateBuild)
 			buildsGroup.GET("", a.handleListBuilds)
 			buildsGroup.GET("/:name", a.handleGetBuild)
-			buildsGroup.GET("/:name/logs", a.handleStreamLogs)
-			buildsGroup.GET("/:name/artifact", a.handleStreamDefaultArtifact)
+			buildsGroup.DELETE("/:name", a.readOnlyGuard(), a.handleDeleteBuild)
+			buildsGroup.PATCH("/:name", a.readOnlyGuard(), a.handlePatchBuild)
+			buildsGroup.GET("/:name/logs", a.downloadLimit(), a.handleStreamLogs)
+			buildsGroup.GET("/:name/logs/search", a.handleSearchLogs)
+			buildsGroup.GET("/:name/logs/stream", a.downloadLimit(), a.handleStreamLogEvents)
+			buildsGroup.GET("/:name/logs/archive", a.downloadLimit(), a.handleGetLogArchive)
+			buildsGroup.Match([]string{http.MethodGet, http.MethodHead}, "/:name/artifact", a.downloadLimit(), a.handleStreamDefaultArtifact)
 			buildsGroup.GET("/:name/artifacts", a.handleListArtifacts)
-			buildsGroup.GET("/:name/artifacts/:file", a.handleStreamArtifactPart)
-			buildsGroup.GET("/:name/artifact/:filename", a.handleStreamArtifactByFilename)
+			buildsGroup.Match([]string{http.MethodGet, http.MethodHead}, "/:name/artifacts/:file", a.downloadLimit(), a.handleStreamArtifactPart)
+			buildsGroup.GET("/:name/artifact/manifest", a.handleGetArtifactManifest)
+			buildsGroup.POST("/:name/artifact/url", a.handleCreateSignedURL)
+			buildsGroup.Match([]string{http.MethodGet, http.MethodHead}, "/:name/artifact/signature", a.downloadLimit(), a.handleStreamArtifactSignature)
+			buildsGroup.Match([]string{http.MethodGet, http.MethodHead}, "/:name/artifact/:filename", a.downloadLimit(), a.handleStreamArtifactByFilename)
 			buildsGroup.GET("/:name/template", a.handleGetBuildTemplate)
-			buildsGroup.POST("/:name/uploads", a.handleUploadFiles)
+			buildsGroup.GET("/:name/manifest", a.handleGetBuildManifest)
+			buildsGroup.GET("/:name/compliance", a.handleGetBuildCompliance)
+			buildsGroup.GET("/:name/comparison", a.handleGetBuildComparison)
+			buildsGroup.GET("/:name/sbom", a.handleGetBuildSBOM)
+			buildsGroup.GET("/:name/events", a.handleGetBuildEvents)
+			buildsGroup.GET("/:name/watch", a.handleWatchBuild)
+			buildsGroup.POST("/:name/clone", a.readOnlyGuard(), a.handleCloneBuild)
+			buildsGroup.POST("/:name/cancel", a.readOnlyGuard(), a.handleCancelBuild)
+			buildsGroup.Match([]string{http.MethodGet, http.MethodPost}, "/:name/exec", a.readOnlyGuard(), a.handleExecBuild)
+			buildsGroup.GET("/:name/workspace", a.downloadLimit(), a.handleCopyFromWorkspace)
+			buildsGroup.POST("/:name/uploads", a.readOnlyGuard(), a.handleUploadFiles)
+			buildsGroup.POST("/:name/uploads/sessions", a.readOnlyGuard(), a.handleCreateUploadSession)
+			buildsGroup.GET("/:name/uploads/sessions/:id", a.handleGetUploadSession)
+			buildsGroup.PATCH("/:name/uploads/sessions/:id", a.readOnlyGuard(), a.handleUploadChunk)
+			buildsGroup.DELETE("/:name/uploads/sessions/:id", a.readOnlyGuard(), a.handleDeleteUploadSession)
+			buildsGroup.POST("/:name/uploads/complete", a.readOnlyGuard(), a.handleCompleteUploads)
+		}
+
+		catalogGroup := v1.Group("/catalog")
+		catalogGroup.Use(a.authMiddleware(), a.rateLimit())
+		{
+			catalogGroup.GET("/defines", a.handleGetDefinesCatalog)
+			catalogGroup.GET("/hardening", a.handleGetHardeningCatalog)
 		}
+
+		v1.GET("/capabilities", a.authMiddleware(), a.rateLimit(), a.handleGetCapabilities)
+		v1.GET("/stats", a.authMiddleware(), a.rateLimit(), a.handleGetStats)
+		v1.GET("/build-records", a.authMiddleware(), a.rateLimit(), a.handleListBuildRecords)
+		v1.GET("/stats/failures", a.authMiddleware(), a.rateLimit(), a.handleGetFailureAnalytics)
+		v1.POST("/policies/evaluate", a.authMiddleware(), a.rateLimit(), a.handleEvaluatePolicies)
+		v1.POST("/manifests/validate", a.authMiddleware(), a.rateLimit(), a.handleValidateManifest)
+		v1.GET("/pipelines/:name/graph", a.authMiddleware(), a.rateLimit(), a.handleGetPipelineGraph)
 	}
+	a.registerV2(router)
 
 	return router
 }
@@ -133,7 +268,7 @@ func StartServer(addr string, logger logr.Logger) (*http.Server, error) {
 	return server, nil
 }
 
-// authMiddleware provides authentication middleware for Gin
+// authMiddleware authenticates the token of a request and authorizes its route, see authorizeRoute
 func (a *APIServer) authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if !a.isAuthenticated(c) {
@@ -141,6 +276,141 @@ func (a *APIServer) authMiddleware() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if !a.authorizeImpersonation(c) || !a.resolveRequestNamespace(c) || !a.authorizeRoute(c) {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// impersonationKey is the context key of the rest.ImpersonationConfig of impersonating requests
+const impersonationKey = "impersonate"
+
+// requestImpersonation returns the user and groups an authorized request impersonates
+func requestImpersonation(c *gin.Context) (rest.ImpersonationConfig, bool) {
+	if c == nil {
+		return rest.ImpersonationConfig{}, false
+	}
+	v, ok := c.Get(impersonationKey)
+	if !ok {
+		return rest.ImpersonationConfig{}, false
+	}
+	imp, ok := v.(rest.ImpersonationConfig)
+	return imp, ok
+}
+
+// impersonationFromHeaders reads the Impersonate-User and Impersonate-Group headers, as sent by
+// kubectl --as and --as-group
+func impersonationFromHeaders(h http.Header) (rest.ImpersonationConfig, error) {
+	imp := rest.ImpersonationConfig{UserName: strings.TrimSpace(h.Get(authnv1.ImpersonateUserHeader))}
+	for _, g := range h.Values(authnv1.ImpersonateGroupHeader) {
+		if g = strings.TrimSpace(g); g != "" {
+			imp.Groups = append(imp.Groups, g)
+		}
+	}
+	if imp.UserName == "" && len(imp.Groups) > 0 {
+		return imp, fmt.Errorf("%s requires %s", authnv1.ImpersonateGroupHeader, authnv1.ImpersonateUserHeader)
+	}
+	return imp, nil
+}
+
+// authorizeImpersonation lets callers allowed to impersonate users in Kubernetes act on behalf of
+// another user. It answers the request and returns false when the caller may not impersonate the
+// requested user or groups.
+func (a *APIServer) authorizeImpersonation(c *gin.Context) bool {
+	imp, err := impersonationFromHeaders(c.Request.Header)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if imp.UserName == "" {
+		return true
+	}
+	// impersonating calls reach Kubernetes with the caller's token, which it does not accept from
+	// an OIDC issuer it does not know
+	if c.GetBool(oidcUserKey) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "impersonation needs a Kubernetes token, not an OIDC token"})
+		return false
+	}
+	cfg, err := serviceRESTConfig()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return false
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return false
+	}
+	ctx := c.Request.Context()
+	v, ok := c.Get(userInfoKey)
+	caller, isUser := v.(authnv1.UserInfo)
+	if !ok || !isUser {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return false
+	}
+	extra := make(map[string]authzv1.ExtraValue, len(caller.Extra))
+	for k, v := range caller.Extra {
+		extra[k] = authzv1.ExtraValue(v)
+	}
+
+	targets := []authzv1.ResourceAttributes{{Verb: "impersonate", Resource: "users", Name: imp.UserName}}
+	if strings.HasPrefix(imp.UserName, "system:serviceaccount:") {
+		parts := strings.Split(imp.UserName, ":")
+		if len(parts) == 4 {
+			targets[0] = authzv1.ResourceAttributes{Verb: "impersonate", Resource: "serviceaccounts", Namespace: parts[2], Name: parts[3]}
+		}
+	}
+	for _, g := range imp.Groups {
+		targets = append(targets, authzv1.ResourceAttributes{Verb: "impersonate", Resource: "groups", Name: g})
+	}
+	for _, target := range targets {
+		sar := &authzv1.SubjectAccessReview{Spec: authzv1.SubjectAccessReviewSpec{
+			User:               caller.Username,
+			Groups:             caller.Groups,
+			UID:                caller.UID,
+			Extra:              extra,
+			ResourceAttributes: &target,
+		}}
+		res, err := clientset.AuthorizationV1().SubjectAccessReviews().Create(ctx, sar, metav1.CreateOptions{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to check impersonation: %v", err)})
+			return false
+		}
+		if !res.Status.Allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("%s may not impersonate %s %q", caller.Username, strings.TrimSuffix(target.Resource, "s"), target.Name)})
+			return false
+		}
+	}
+
+	a.log.Info("impersonating", "user", imp.UserName, "groups", imp.Groups, "by", caller.Username,
+		"method", c.Request.Method, "path", c.Request.URL.Path, "reqID", c.GetString("reqID"))
+	c.Set(impersonationKey, imp)
+	return true
+}
+
+// readOnlyGuard rejects requests that create or change builds while the OperatorConfig puts the API
+// into read-only mode or the server is draining
+func (a *APIServer) readOnlyGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if a.rejectWhileDraining(c) {
+			a.log.Info("rejected while draining", "method", c.Request.Method, "path", c.Request.URL.Path, "reqID", c.GetString("reqID"))
+			c.Abort()
+			return
+		}
+		k8sClient, err := getClientFromRequest(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+			c.Abort()
+			return
+		}
+		if m := loadMaintenance(c.Request.Context(), k8sClient, resolveNamespace()); m != nil && m.ReadOnly {
+			a.log.Info("rejected in read-only mode", "method", c.Request.Method, "path", c.Request.URL.Path, "reqID", c.GetString("reqID"))
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": maintenanceMessage(m)})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -161,12 +431,36 @@ func (a *APIServer) handleGetBuild(c *gin.Context) {
 	getBuild(c, name)
 }
 
+func (a *APIServer) handleDeleteBuild(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("delete build", "build", name, "keepArtifact", c.Query("keep-artifact"), "force", c.Query("force"), "reqID", c.GetString("reqID"))
+	deleteBuild(c, name)
+}
+
+func (a *APIServer) handlePatchBuild(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("patch build", "build", name, "reqID", c.GetString("reqID"))
+	patchBuild(c, name)
+}
+
 func (a *APIServer) handleStreamLogs(c *gin.Context) {
 	name := c.Param("name")
 	a.log.Info("logs requested", "build", name, "reqID", c.GetString("reqID"))
 	streamLogs(c, name)
 }
 
+func (a *APIServer) handleStreamLogEvents(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("log stream requested", "build", name, "lastEventID", c.GetHeader("Last-Event-ID"), "reqID", c.GetString("reqID"))
+	streamLogEvents(c, name)
+}
+
+func (a *APIServer) handleSearchLogs(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("log search", "build", name, "q", c.Query("q"), "reqID", c.GetString("reqID"))
+	searchLogs(c, name)
+}
+
 func (a *APIServer) handleStreamLogsSSE(c *gin.Context) {
 	name := c.Param("name")
 	a.log.Info("logs SSE requested", "build", name, "reqID", c.GetString("reqID"))
@@ -193,6 +487,24 @@ func (a *APIServer) handleStreamDefaultArtifact(c *gin.Context) {
 	a.streamDefaultArtifact(c, name)
 }
 
+func (a *APIServer) handleGetArtifactManifest(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("artifact manifest requested", "build", name, "reqID", c.GetString("reqID"))
+	a.getArtifactManifest(c, name)
+}
+
+func (a *APIServer) handleStreamArtifactSignature(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("artifact signature requested", "build", name, "reqID", c.GetString("reqID"))
+	a.streamArtifactSignature(c, name)
+}
+
+func (a *APIServer) handleGetBuildSBOM(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("SBOM requested", "build", name, "format", c.Query("format"), "reqID", c.GetString("reqID"))
+	a.getBuildSBOM(c, name)
+}
+
 func (a *APIServer) handleStreamArtifactByFilename(c *gin.Context) {
 	name := c.Param("name")
 	filename := c.Param("filename")
@@ -206,6 +518,77 @@ func (a *APIServer) handleGetBuildTemplate(c *gin.Context) {
 	getBuildTemplate(c, name)
 }
 
+func (a *APIServer) handleGetBuildManifest(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("manifest requested", "build", name, "reqID", c.GetString("reqID"))
+	getBuildManifest(c, name)
+}
+
+func (a *APIServer) handleGetBuildCompliance(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("compliance requested", "build", name, "reqID", c.GetString("reqID"))
+	getBuildCompliance(c, name)
+}
+
+func (a *APIServer) handleExecBuild(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("exec into build pod", "build", name, "command", c.QueryArray("command"), "reqID", c.GetString("reqID"))
+	execBuild(c, name)
+}
+
+func (a *APIServer) handleCopyFromWorkspace(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("workspace copy requested", "build", name, "path", c.Query("path"), "reqID", c.GetString("reqID"))
+	copyFromWorkspace(c, name)
+}
+
+func (a *APIServer) handleCloneBuild(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("clone build", "build", name, "reqID", c.GetString("reqID"))
+	cloneBuild(c, name)
+}
+
+func (a *APIServer) handleCancelBuild(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("cancel build", "build", name, "reqID", c.GetString("reqID"))
+	cancelBuild(c, name)
+}
+
+func (a *APIServer) handleGetDefinesCatalog(c *gin.Context) {
+	a.log.Info("defines catalog", "target", c.Query("target"), "reqID", c.GetString("reqID"))
+	getDefinesCatalog(c)
+}
+
+func (a *APIServer) handleGetHardeningCatalog(c *gin.Context) {
+	a.log.Info("hardening catalog", "reqID", c.GetString("reqID"))
+	writeJSON(c, http.StatusOK, HardeningCatalogResponse{Profiles: hardening.Profiles()})
+}
+
+func (a *APIServer) handleGetCapabilities(c *gin.Context) {
+	a.log.Info("capabilities", "automotiveImageBuilder", c.Query("automotiveImageBuilder"), "reqID", c.GetString("reqID"))
+	getCapabilities(c)
+}
+
+func (a *APIServer) handleGetStats(c *gin.Context) {
+	a.log.Info("build stats", "since", c.Query("since"), "reqID", c.GetString("reqID"))
+	getBuildStats(c)
+}
+
+func (a *APIServer) handleGetFailureAnalytics(c *gin.Context) {
+	a.log.Info("failure analytics", "since", c.Query("since"), "bucket", c.Query("bucket"), "reqID", c.GetString("reqID"))
+	getFailureAnalytics(c)
+}
+
+func (a *APIServer) handleEvaluatePolicies(c *gin.Context) {
+	a.log.Info("evaluate build policies", "reqID", c.GetString("reqID"))
+	evaluatePolicies(c)
+}
+
+func (a *APIServer) handleValidateManifest(c *gin.Context) {
+	a.log.Info("validate manifest", "reqID", c.GetString("reqID"))
+	validateManifest(c)
+}
+
 func (a *APIServer) handleUploadFiles(c *gin.Context) {
 	name := c.Param("name")
 	a.log.Info("uploads", "build", name, "reqID", c.GetString("reqID"))
@@ -213,7 +596,19 @@ func (a *APIServer) handleUploadFiles(c *gin.Context) {
 }
 
 func streamLogs(c *gin.Context, name string) {
-	namespace := resolveNamespace()
+	namespace := requestNamespace(c)
+
+	// A client that lost the stream resumes with ?since=, skipping the steps and lines it has
+	var since logOffset
+	resuming := false
+	if s := c.Query("since"); s != "" {
+		var ok bool
+		if since, ok = parseLogOffset(s); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be <step>:<line> or <step>:<bytes>b"})
+			return
+		}
+		resuming = true
+	}
 
 	k8sClient, err := getClientFromRequest(c)
 	if err != nil {
@@ -274,9 +669,20 @@ func streamLogs(c *gin.Context, name string) {
 	c.Writer.Header().Set("X-Accel-Buffering", "no")
 
 	c.Writer.WriteHeader(http.StatusOK)
-	_, _ = c.Writer.Write([]byte("Waiting for logs...\n"))
+	if !resuming {
+		_, _ = c.Writer.Write([]byte("Waiting for logs...\n"))
+	}
 	c.Writer.Flush()
 
+	// Prefix every log line with the ID of the request that created the build, so saved logs can
+	// be matched to API access logs
+	var out io.Writer = c.Writer
+	var prefixer *linePrefixWriter
+	if requestID := ib.Annotations[requestIDAnnotation]; requestID != "" {
+		prefixer = &linePrefixWriter{w: c.Writer, prefix: []byte("[" + requestID + "] "), atStart: true}
+		out = prefixer
+	}
+
 	var hadStream bool
 	streamed := make(map[string]bool)
 	var lastErrs []string
@@ -306,12 +712,27 @@ func streamLogs(c *gin.Context, name string) {
 			}
 		}
 
+		// An offset in a step this pod does not have, e.g. from a rerun build, restarts from the beginning
+		if resuming && !slices.Contains(stepNames, "step-"+since.step) && !slices.Contains(stepNames, since.step) {
+			resuming = false
+		}
+
 		var errs []string
 
 		for _, cName := range stepNames {
 			if streamed[cName] {
 				continue
 			}
+			step := strings.TrimPrefix(cName, "step-")
+			resumed := false
+			if resuming {
+				if step != since.step {
+					// Steps run in order, so steps before the resumed one were sent completely
+					streamed[cName] = true
+					continue
+				}
+				resumed = true
+			}
 
 			req := cs.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{Container: cName, Follow: true})
 			stream, err := req.Stream(ctx)
@@ -325,7 +746,22 @@ func streamLogs(c *gin.Context, name string) {
 			}
 			hadStream = true
 
-			_, _ = c.Writer.Write([]byte("\n===== Logs from " + strings.TrimPrefix(cName, "step-") + " =====\n\n"))
+			var logReader io.Reader = stream
+			if resumed {
+				resuming = false
+				var atStart bool
+				if logReader, atStart, err = skipLog(stream, since); err != nil {
+					stream.Close()
+					fmt.Fprintf(c.Writer, "\n[Stream error: %v]\n", err)
+					c.Writer.Flush()
+					return
+				}
+				if prefixer != nil {
+					prefixer.atStart = atStart
+				}
+			} else {
+				_, _ = c.Writer.Write([]byte("\n===== Logs from " + step + " =====\n\n"))
+			}
 			c.Writer.Flush()
 			// Stream with proper error handling and context cancellation
 			func() {
@@ -340,9 +776,9 @@ func streamLogs(c *gin.Context, name string) {
 					default:
 					}
 
-					n, err := stream.Read(buf)
+					n, err := logReader.Read(buf)
 					if n > 0 {
-						if _, writeErr := c.Writer.Write(buf[:n]); writeErr != nil {
+						if _, writeErr := out.Write(buf[:n]); writeErr != nil {
 							return
 						}
 						c.Writer.Flush()
@@ -395,6 +831,287 @@ func streamLogs(c *gin.Context, name string) {
 	}
 }
 
+// logOffset is a position in the log of a build step: after its first lines lines, or after its
+// first bytes bytes
+type logOffset struct {
+	step  string
+	lines int
+	bytes int64
+}
+
+// parseLogOffset reads a ?since= log offset, <step>:<line> or <step>:<bytes>b. Steps are named
+// without their step- prefix, as in the log stream.
+func parseLogOffset(s string) (logOffset, bool) {
+	i := strings.LastIndex(s, ":")
+	if i <= 0 {
+		return logOffset{}, false
+	}
+	o := logOffset{step: strings.TrimPrefix(s[:i], "step-")}
+	if n, ok := strings.CutSuffix(s[i+1:], "b"); ok {
+		size, err := strconv.ParseInt(n, 10, 64)
+		if err != nil || size < 0 {
+			return logOffset{}, false
+		}
+		o.bytes = size
+		return o, true
+	}
+	lines, err := strconv.Atoi(s[i+1:])
+	if err != nil || lines < 0 {
+		return logOffset{}, false
+	}
+	o.lines = lines
+	return o, true
+}
+
+// skipLog discards the part of a step log before o and reports whether the rest starts a line. A
+// log shorter than o is skipped entirely.
+func skipLog(r io.Reader, o logOffset) (io.Reader, bool, error) {
+	br := bufio.NewReader(r)
+	atStart := true
+	if o.bytes > 0 {
+		if _, err := io.CopyN(io.Discard, br, o.bytes-1); err != nil {
+			if errors.Is(err, io.EOF) {
+				return br, true, nil
+			}
+			return nil, false, err
+		}
+		last, err := br.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return br, true, nil
+			}
+			return nil, false, err
+		}
+		atStart = last == '\n'
+	}
+	for n := 0; n < o.lines; n++ {
+		if _, err := br.ReadString('\n'); err != nil {
+			if errors.Is(err, io.EOF) {
+				return br, true, nil
+			}
+			return nil, false, err
+		}
+	}
+	return br, atStart, nil
+}
+
+// requestIDAnnotation records the ID of the API request that created a build. The controller
+// passes it to the build pod as a label and the BUILD_REQUEST_ID environment variable.
+const requestIDAnnotation = "automotive.sdv.cloud.redhat.com/request-id"
+
+// validRequestID reports whether a client supplied X-Request-ID can be used as is. It must be a
+// valid label value because it is set as a label on the build pod.
+func validRequestID(id string) bool {
+	return id != "" && len(validation.IsValidLabelValue(id)) == 0
+}
+
+// probePath reports whether path is polled by the kubelet probes
+func probePath(path string) bool {
+	return path == "/healthz" || path == "/readyz"
+}
+
+// linePrefixWriter writes prefix at the start of every line
+type linePrefixWriter struct {
+	w       io.Writer
+	prefix  []byte
+	atStart bool
+}
+
+func (p *linePrefixWriter) Write(b []byte) (int, error) {
+	var buf bytes.Buffer
+	for _, ch := range b {
+		if p.atStart {
+			buf.Write(p.prefix)
+			p.atStart = false
+		}
+		buf.WriteByte(ch)
+		if ch == '\n' {
+			p.atStart = true
+		}
+	}
+	if _, err := p.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+const (
+	// maxLogSearchContext is the most context lines a log search returns around a match
+	maxLogSearchContext = 20
+	// defaultLogSearchMatches and maxLogSearchMatches bound the matches a log search returns
+	defaultLogSearchMatches = 100
+	maxLogSearchMatches     = 1000
+	// maxLogLineBytes is the longest log line a search reads; longer lines fail the search
+	maxLogLineBytes = 1 << 20
+)
+
+// searchLogs greps the step logs of a build's pod server-side. q is matched as a substring, or as
+// a regular expression with regex=true; ignoreCase=true matches case-insensitively.
+func searchLogs(c *gin.Context, name string) {
+	q := c.Query("q")
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	match, err := newLogMatcher(q, c.Query("regex") == "true", c.Query("ignoreCase") == "true")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	contextLines, err := parseBoundedInt(c.Query("context"), 0, maxLogSearchContext)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "context: " + err.Error()})
+		return
+	}
+	limit, err := parseBoundedInt(c.Query("maxMatches"), defaultLogSearchMatches, maxLogSearchMatches)
+	if err != nil || limit == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("maxMatches must be between 1 and %d", maxLogSearchMatches)})
+		return
+	}
+	step := strings.TrimPrefix(strings.TrimSpace(c.Query("step")), "step-")
+
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	ctx := c.Request.Context()
+	ib := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, ib); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tr := strings.TrimSpace(ib.Status.TaskRunName)
+	if tr == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "logs not available yet"})
+		return
+	}
+	restCfg, err := getRESTConfigFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	cs, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	pods, err := cs.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "tekton.dev/taskRun=" + tr})
+	if err != nil || len(pods.Items) == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "logs not available: the build pod no longer exists"})
+		return
+	}
+	pod := pods.Items[0]
+
+	resp := LogSearchResponse{Query: q, Matches: []LogMatch{}}
+	found := false
+	for _, ctr := range pod.Spec.Containers {
+		if !strings.HasPrefix(ctr.Name, "step-") {
+			continue
+		}
+		stepName := strings.TrimPrefix(ctr.Name, "step-")
+		if step != "" && stepName != step {
+			continue
+		}
+		found = true
+		stream, err := cs.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: ctr.Name}).Stream(ctx)
+		if err != nil {
+			// Steps that have not started yet have no logs
+			continue
+		}
+		matches, truncated, err := searchLog(stream, stepName, match, contextLines, limit-len(resp.Matches))
+		stream.Close()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error reading %s logs: %v", stepName, err)})
+			return
+		}
+		resp.Matches = append(resp.Matches, matches...)
+		if truncated {
+			resp.Truncated = true
+			break
+		}
+	}
+	if !found {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("build has no step %q", step)})
+		return
+	}
+	writeJSON(c, http.StatusOK, resp)
+}
+
+// newLogMatcher returns a function reporting whether a log line matches q
+func newLogMatcher(q string, regex, ignoreCase bool) (func(string) bool, error) {
+	if !regex {
+		q = regexp.QuoteMeta(q)
+	}
+	if ignoreCase {
+		q = "(?i)" + q
+	}
+	re, err := regexp.Compile(q)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return re.MatchString, nil
+}
+
+// searchLog returns up to limit lines of r that match, each with up to contextLines lines before
+// and after it. truncated is set when more lines matched than limit.
+func searchLog(r io.Reader, step string, match func(string) bool, contextLines, limit int) (matches []LogMatch, truncated bool, err error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxLogLineBytes)
+	var before []string
+	// open are the matches still collecting lines after them
+	var open []int
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		still := open[:0]
+		for _, i := range open {
+			matches[i].After = append(matches[i].After, line)
+			if len(matches[i].After) < contextLines {
+				still = append(still, i)
+			}
+		}
+		open = still
+
+		if match(line) {
+			if len(matches) == limit {
+				truncated = true
+			} else {
+				matches = append(matches, LogMatch{Step: step, Line: n, Text: line, Before: slices.Clone(before)})
+				if contextLines > 0 {
+					open = append(open, len(matches)-1)
+				}
+			}
+		}
+		if truncated && len(open) == 0 {
+			return matches, true, nil
+		}
+		if contextLines > 0 {
+			before = append(before, line)
+			if len(before) > contextLines {
+				before = before[1:]
+			}
+		}
+	}
+	return matches, truncated, sc.Err()
+}
+
+// parseBoundedInt parses an optional non-negative query parameter, returning def when it is empty
+func parseBoundedInt(v string, def, upper int) (int, error) {
+	if strings.TrimSpace(v) == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || n < 0 || n > upper {
+		return 0, fmt.Errorf("must be a number between 0 and %d", upper)
+	}
+	return n, nil
+}
+
 func streamLogsSSE(c *gin.Context, name string) {
 	c.Writer.Header().Set("Content-Type", "text/event-stream")
 	c.Writer.Header().Set("Cache-Control", "no-cache")
@@ -404,7 +1121,7 @@ func streamLogsSSE(c *gin.Context, name string) {
 	c.Writer.Header().Set("X-Accel-Buffering", "no")
 	c.Writer.WriteHeader(http.StatusOK)
 
-	namespace := resolveNamespace()
+	namespace := requestNamespace(c)
 
 	k8sClient, err := getClientFromRequest(c)
 	if err != nil {
@@ -627,6 +1344,280 @@ func sendSSEEvent(c *gin.Context, event, step, data string) {
 	c.Writer.WriteString("\n")
 }
 
+const (
+	// logStreamHeartbeat is how often GET /logs/stream sends a comment so proxies do not close
+	// the connection while a step is quiet
+	logStreamHeartbeat = 15 * time.Second
+	// logStreamPoll is how often GET /logs/stream checks for the build pod and the next step
+	logStreamPoll = 2 * time.Second
+)
+
+// sseWriter writes the events of one SSE response; the heartbeat writes from its own goroutine
+type sseWriter struct {
+	mu sync.Mutex
+	w  gin.ResponseWriter
+}
+
+// event sends data as JSON in an event of type event, with id when it is not empty
+func (s *sseWriter) event(event, id string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if id != "" {
+		buf.WriteString("id: " + id + "\n")
+	}
+	buf.WriteString("event: " + event + "\n")
+	buf.WriteString("data: ")
+	buf.Write(b)
+	buf.WriteString("\n\n")
+	return s.write(buf.Bytes())
+}
+
+// heartbeat sends a comment every logStreamHeartbeat until ctx is done or the returned function
+// is called, so proxies do not close the connection while no events are sent
+func (s *sseWriter) heartbeat(ctx context.Context) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(logStreamHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				_ = s.comment("heartbeat")
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// comment sends an SSE comment, which clients ignore
+func (s *sseWriter) comment(text string) error {
+	return s.write([]byte(": " + text + "\n\n"))
+}
+
+func (s *sseWriter) write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(b); err != nil {
+		return err
+	}
+	s.w.Flush()
+	return nil
+}
+
+// parseLogEventID splits the id of a log event, <step>:<line>, as sent back in Last-Event-ID
+func parseLogEventID(id string) (step string, line int, ok bool) {
+	i := strings.LastIndex(id, ":")
+	if i <= 0 {
+		return "", 0, false
+	}
+	line, err := strconv.Atoi(id[i+1:])
+	if err != nil || line < 0 {
+		return "", 0, false
+	}
+	return id[:i], line, true
+}
+
+// readLogLines calls emit for every line of r after the first skip lines, numbering lines from
+// 1. Empty lines are counted and sent so line numbers match the log; a last line without a
+// newline is sent too.
+func readLogLines(r io.Reader, skip int, emit func(line int, text string) error) error {
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		text, err := br.ReadString('\n')
+		if text != "" && n > skip {
+			if emitErr := emit(n, strings.TrimSuffix(text, "\n")); emitErr != nil {
+				return emitErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// streamLogEvents follows the logs of a build as server-sent events: a step event when a step
+// starts, a log event per line, system events while waiting, an error event when the stream
+// fails and an end event once every step finished. Heartbeat comments keep idle connections
+// open, and a client that reconnects with Last-Event-ID (or ?lastEventId=) continues after the
+// last line it received.
+func streamLogEvents(c *gin.Context, name string) {
+	resumeStep, resumeLine, resuming := parseLogEventID(c.GetHeader("Last-Event-ID"))
+	if !resuming {
+		resumeStep, resumeLine, resuming = parseLogEventID(c.Query("lastEventId"))
+	}
+
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	key := types.NamespacedName{Name: name, Namespace: namespace}
+	ib := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, key, ib); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	restCfg, err := getRESTConfigFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	cs, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
+	c.Writer.WriteHeader(http.StatusOK)
+
+	sse := &sseWriter{w: c.Writer}
+	if sse.comment("connected") != nil {
+		return
+	}
+	defer sse.heartbeat(ctx)()
+
+	system := func(event, text string) {
+		_ = sse.event(event, "", LogStreamEvent{Stream: "system", Text: text})
+	}
+	sleep := func() bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(logStreamPoll):
+			return true
+		}
+	}
+
+	var podName string
+	announced := false
+	for podName == "" {
+		if err := k8sClient.Get(ctx, key, ib); err != nil {
+			if ctx.Err() == nil {
+				system("error", fmt.Sprintf("build lookup failed: %v", err))
+			}
+			return
+		}
+		if tr := strings.TrimSpace(ib.Status.TaskRunName); tr != "" {
+			pods, err := cs.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "tekton.dev/taskRun=" + tr})
+			if err == nil && len(pods.Items) > 0 {
+				podName = pods.Items[0].Name
+				break
+			}
+		}
+		if isFinishedPhase(ib.Status.Phase) {
+			system("end", fmt.Sprintf("Build %s; its logs are no longer available", strings.ToLower(ib.Status.Phase)))
+			return
+		}
+		if !announced {
+			system("system", "Waiting for the build pod...")
+			announced = true
+		}
+		if !sleep() {
+			return
+		}
+	}
+
+	streamed := make(map[string]bool)
+	for {
+		pod, err := cs.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			if ctx.Err() == nil {
+				system("error", fmt.Sprintf("build pod lookup failed: %v", err))
+			}
+			return
+		}
+		stepNames := make([]string, 0, len(pod.Spec.Containers))
+		for _, container := range pod.Spec.Containers {
+			if strings.HasPrefix(container.Name, "step-") {
+				stepNames = append(stepNames, container.Name)
+			}
+		}
+		if len(stepNames) == 0 {
+			for _, container := range pod.Spec.Containers {
+				stepNames = append(stepNames, container.Name)
+			}
+		}
+		// An ID of a step this pod does not have, e.g. from a rerun build, restarts from the beginning
+		if resuming && !slices.Contains(stepNames, "step-"+resumeStep) && !slices.Contains(stepNames, resumeStep) {
+			resuming = false
+		}
+		podFinished := pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+
+		for _, cName := range stepNames {
+			if streamed[cName] {
+				continue
+			}
+			step := strings.TrimPrefix(cName, "step-")
+			skip := 0
+			if resuming {
+				if step != resumeStep {
+					// Steps run in order, so steps before the resumed one were sent completely
+					streamed[cName] = true
+					continue
+				}
+				skip = resumeLine
+				resuming = false
+			}
+
+			stream, err := cs.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{Container: cName, Follow: true}).Stream(ctx)
+			if err != nil {
+				if podFinished {
+					// The step never ran because an earlier one failed
+					streamed[cName] = true
+					continue
+				}
+				// The step has not started yet; later steps cannot have started either
+				break
+			}
+			if skip == 0 {
+				_ = sse.event("step", "", LogStreamEvent{Step: step, Stream: "system", Text: "===== Logs from " + step + " ====="})
+			}
+			err = readLogLines(stream, skip, func(line int, text string) error {
+				return sse.event("log", fmt.Sprintf("%s:%d", step, line), LogStreamEvent{Step: step, Line: line, Stream: "stdout", Text: text})
+			})
+			stream.Close()
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				// The client resumes from the last line it received
+				_ = sse.event("error", "", LogStreamEvent{Step: step, Stream: "system", Text: fmt.Sprintf("stream error: %v", err)})
+				return
+			}
+			streamed[cName] = true
+		}
+
+		if len(streamed) == len(stepNames) || podFinished {
+			break
+		}
+		if !sleep() {
+			return
+		}
+	}
+
+	system("end", "Log streaming completed")
+}
+
 func createRegistrySecret(ctx context.Context, k8sClient client.Client, namespace, buildName string, creds *RegistryCredentials) (string, error) {
 	if creds == nil || !creds.Enabled {
 		return "", nil
@@ -681,160 +1672,570 @@ func createRegistrySecret(ctx context.Context, k8sClient client.Client, namespac
 	return secretName, nil
 }
 
-func createBuild(c *gin.Context) {
-	var req BuildRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid JSON: %v", err)})
-		return
-	}
+// maxBuildWebhooks is the most webhooks a build may register
+const maxBuildWebhooks = 10
 
-	needsUpload := strings.Contains(req.Manifest, "source_path")
-
-	if req.Name == "" || req.Manifest == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "name and manifest are required"})
-		return
-	}
+func webhookSecretName(buildName string) string {
+	return buildName + "-webhooks"
+}
 
-	if req.Distro == "" {
-		req.Distro = "cs9"
-	}
-	if req.Target == "" {
-		req.Target = "qemu"
-	}
-	if req.Architecture == "" {
-		req.Architecture = "arm64"
-	}
-	if req.ExportFormat == "" {
-		req.ExportFormat = "image"
+// webhooksFromRequest validates the webhooks of a request. Webhooks with a secret refer to a key
+// of the build's webhook Secret, whose data is returned to be created with the build.
+func webhooksFromRequest(buildName string, hooks []Webhook) ([]automotivev1alpha1.Webhook, map[string][]byte, error) {
+	if len(hooks) == 0 {
+		return nil, nil, nil
+	}
+	if len(hooks) > maxBuildWebhooks {
+		return nil, nil, fmt.Errorf("at most %d webhooks can be registered for a build", maxBuildWebhooks)
+	}
+	specs := make([]automotivev1alpha1.Webhook, 0, len(hooks))
+	secrets := map[string][]byte{}
+	for i, hook := range hooks {
+		raw := strings.TrimSpace(hook.URL)
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, nil, fmt.Errorf("webhook url %q must be an absolute http or https URL", hook.URL)
+		}
+		spec := automotivev1alpha1.Webhook{URL: raw}
+		if hook.Secret != "" {
+			key := fmt.Sprintf("webhook-%d", i)
+			secrets[key] = []byte(hook.Secret)
+			spec.SecretRef = &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: webhookSecretName(buildName)},
+				Key:                  key,
+			}
+		}
+		specs = append(specs, spec)
 	}
-	if req.Mode == "" {
-		req.Mode = "image"
+	if len(secrets) == 0 {
+		secrets = nil
 	}
+	return specs, secrets, nil
+}
 
-	if strings.TrimSpace(req.Compression) == "" {
-		req.Compression = "gzip"
-	}
-	if req.Compression != "lz4" && req.Compression != "gzip" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid compression: must be lz4 or gzip"})
-		return
+// createWebhookSecret stores the signing secrets of the webhooks of a build
+func createWebhookSecret(ctx context.Context, k8sClient client.Client, namespace, buildName string, data map[string][]byte) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      webhookSecretName(buildName),
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by":                  "build-api",
+				"app.kubernetes.io/part-of":                     "automotive-dev",
+				"app.kubernetes.io/created-by":                  "automotive-dev-build-api",
+				"automotive.sdv.cloud.redhat.com/resource-type": "webhook-secret",
+				"automotive.sdv.cloud.redhat.com/build-name":    buildName,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
 	}
+	return k8sClient.Create(ctx, secret)
+}
 
-	if !req.Distro.IsValid() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "distro cannot be empty"})
-		return
-	}
-	if !req.Target.IsValid() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "target cannot be empty"})
-		return
-	}
-	if !req.Architecture.IsValid() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "architecture cannot be empty"})
-		return
-	}
-	if !req.ExportFormat.IsValid() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "exportFormat cannot be empty"})
-		return
-	}
-	if !req.Mode.IsValid() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "mode cannot be empty"})
+func createBuild(c *gin.Context) {
+	var req BuildRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid JSON: %v", err)})
 		return
 	}
-	if req.AutomotiveImageBuilder == "" {
-		req.AutomotiveImageBuilder = "quay.io/centos-sig-automotive/automotive-image-builder:1.0.0"
+	createBuildFromRequest(c, req)
+}
+
+// createBuildFromRequest validates and defaults req, then creates the manifest ConfigMap and ImageBuild
+func createBuildFromRequest(c *gin.Context, req BuildRequest) {
+	build, replayed := submitBuild(c, req)
+	switch {
+	case build == nil:
+	case replayed:
+		resp := buildResponse(build)
+		resp.Replayed = true
+		writeJSON(c, http.StatusOK, resp)
+	default:
+		writeJSON(c, http.StatusAccepted, BuildResponse{
+			Name:        build.Name,
+			Phase:       "Building",
+			Message:     "Build triggered",
+			RequestedBy: build.Annotations["automotive.sdv.cloud.redhat.com/requested-by"],
+			RequestID:   c.GetString("reqID"),
+		})
 	}
-	if req.ManifestFileName == "" {
-		req.ManifestFileName = "manifest.aib.yml"
+}
+
+// submitBuild validates and defaults req, then creates the manifest ConfigMap and ImageBuild. It
+// returns the created build, or the existing build the request repeats with replayed set; on
+// errors it answers the request and returns nil.
+func submitBuild(c *gin.Context, req BuildRequest) (build *automotivev1alpha1.ImageBuild, replayed bool) {
+	c.Set(auditBuildKey, req.Name)
+	inputs, err := validateBuildRequest(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
 	}
 
 	k8sClient, err := getClientFromRequest(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
-		return
+		return nil, false
 	}
 
 	ctx := c.Request.Context()
-	namespace := resolveNamespace()
+	namespace := requestNamespace(c)
+	if req.Namespace != "" && req.Namespace != namespace {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("namespace %q of the request body differs from the namespace %q of the query", req.Namespace, namespace)})
+		return nil, false
+	}
 
 	requestedBy := resolveRequester(c)
 
+	keyHash, err := idempotencyKeyHash(c.GetHeader("Idempotency-Key"), requestedBy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	reqHash := requestHash(req)
+	dup, conflict, err := duplicateBuild(ctx, k8sClient, namespace, keyHash, reqHash, req.Deduplicate && deduplicatable(req, inputs))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if conflict != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": conflict})
+		return nil, false
+	}
+	if dup != nil {
+		c.Set(auditBuildKey, dup.Name)
+		c.Header("Idempotent-Replayed", "true")
+		return dup, true
+	}
+
 	existing := &automotivev1alpha1.ImageBuild{}
 	if err := k8sClient.Get(ctx, types.NamespacedName{Name: req.Name, Namespace: namespace}, existing); err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("ImageBuild %s already exists", req.Name)})
-		return
+		return nil, false
 	} else if !k8serrors.IsNotFound(err) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error checking existing build: %v", err)})
-		return
+		return nil, false
 	}
 
-	cfgName := fmt.Sprintf("%s-manifest", req.Name)
-	cmData := map[string]string{req.ManifestFileName: req.Manifest}
-
-	if len(req.CustomDefs) > 0 {
-		cmData["custom-definitions.env"] = strings.Join(req.CustomDefs, "\n")
+	partner, err := loadPartnerIsolation(ctx, namespace)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error reading partner isolation profile: %v", err)})
+		return nil, false
 	}
-	if len(req.AIBOverrideArgs) > 0 {
-		// If override is provided, prefer it and ignore the regular extra args
-		cmData["aib-override-args.txt"] = strings.Join(req.AIBOverrideArgs, " ")
-	} else if len(req.AIBExtraArgs) > 0 {
-		cmData["aib-extra-args.txt"] = strings.Join(req.AIBExtraArgs, " ")
+	if partner != nil {
+		if err := applyPartnerProfile(&req, inputs, partner, namespace, requestedBy); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		violation, err := partnerQuotaViolation(ctx, k8sClient, namespace, partner)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		if violation != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": violation})
+			return nil, false
+		}
 	}
 
-	cm := &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      cfgName,
-			Namespace: namespace,
-			Labels: map[string]string{
-				"app.kubernetes.io/managed-by":                  "build-api",
-				"app.kubernetes.io/part-of":                     "automotive-dev",
-				"app.kubernetes.io/created-by":                  "automotive-dev-build-api",
-				"automotive.sdv.cloud.redhat.com/resource-type": "manifest-config",
-			},
-		},
-		Data: cmData,
+	err = commitStatusGranted(ctx, namespace, inputs.commitStatus)
+	if err == nil {
+		err = remoteFilesGranted(ctx, inputs.remoteFiles)
 	}
-	if err := k8sClient.Create(ctx, cm); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error creating manifest ConfigMap: %v", err)})
-		return
+	if err != nil {
+		var denied *notGrantedError
+		if errors.As(err, &denied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error reading the OperatorConfig: %v", err)})
+		}
+		return nil, false
 	}
 
-	labels := map[string]string{
-		"app.kubernetes.io/managed-by":                 "build-api",
-		"app.kubernetes.io/part-of":                    "automotive-dev",
-		"app.kubernetes.io/created-by":                 "automotive-dev-build-api",
-		"automotive.sdv.cloud.redhat.com/distro":       string(req.Distro),
-		"automotive.sdv.cloud.redhat.com/target":       string(req.Target),
-		"automotive.sdv.cloud.redhat.com/architecture": string(req.Architecture),
+	if check := workspaceStorageCheck(ctx, k8sClient, req); !check.Passed && !check.Skipped {
+		c.JSON(http.StatusBadRequest, gin.H{"error": check.Message})
+		return nil, false
+	}
+	if check, unresolved := containerImagesCheck(ctx, req); !check.Passed && !check.Skipped {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": check.Message, "unresolvedImages": unresolved})
+		return nil, false
+	}
+
+	plan, err := planBuild(ctx, k8sClient, namespace, req, inputs, requestedBy, c.GetString("reqID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if partner != nil {
+		markPartnerBuild(plan.imageBuild)
+	}
+	if plan.imageBuild.Labels == nil {
+		plan.imageBuild.Labels = map[string]string{}
+	}
+	if reqHash != "" {
+		plan.imageBuild.Labels[requestHashLabel] = reqHash
+	}
+	if keyHash != "" {
+		plan.imageBuild.Labels[idempotencyKeyLabel] = keyHash
+	}
+
+	// the check above misses requests with the key that run concurrently; only the request that
+	// claims the key creates a build, the others replay it
+	var claimClient client.Client
+	var claim *corev1.ConfigMap
+	if keyHash != "" {
+		// claims are bookkeeping of the build API, callers need not be allowed to delete ConfigMaps
+		if claimClient, err = serviceClient(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+			return nil, false
+		}
+		claim, dup, conflict, err = claimIdempotencyKey(ctx, claimClient, namespace, keyHash, reqHash, req.Name)
+		switch {
+		case errors.Is(err, errKeyInUse):
+			c.Header("Retry-After", "5")
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return nil, false
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return nil, false
+		case conflict != "":
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": conflict})
+			return nil, false
+		case dup != nil:
+			c.Set(auditBuildKey, dup.Name)
+			c.Header("Idempotent-Replayed", "true")
+			return dup, true
+		}
+		defer func() {
+			if build == nil {
+				releaseIdempotencyKey(ctx, claimClient, claim)
+			}
+		}()
+	}
+
+	if err := k8sClient.Create(ctx, plan.configMap); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error creating manifest ConfigMap: %v", err)})
+		return nil, false
+	}
+
+	imageBuild := plan.imageBuild
+	if req.RegistryCredentials != nil && req.RegistryCredentials.Enabled {
+		secretName, err := createRegistrySecret(ctx, k8sClient, namespace, req.Name, req.RegistryCredentials)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error creating registry secret: %v", err)})
+			return nil, false
+		}
+		imageBuild.Spec.EnvSecretRef = secretName
+	}
+	if len(inputs.webhookSecrets) > 0 {
+		if err := createWebhookSecret(ctx, k8sClient, namespace, req.Name, inputs.webhookSecrets); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error creating webhook secret: %v", err)})
+			return nil, false
+		}
+	}
+
+	if err := k8sClient.Create(ctx, imageBuild); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error creating ImageBuild: %v", err)})
+		return nil, false
+	}
+
+	if err := setOwnerRef(ctx, k8sClient, namespace, plan.configMap.Name, imageBuild); err != nil {
+		// best-effort
+	}
+	if claim != nil {
+		// the claim goes with the build; a claim left behind is taken over once it is abandoned
+		_ = setOwnerRef(ctx, claimClient, namespace, claim.Name, imageBuild)
+	}
+
+	if envSecretRef := imageBuild.Spec.EnvSecretRef; envSecretRef != "" {
+		if err := setOwnerRef(ctx, k8sClient, namespace, envSecretRef, imageBuild); err != nil {
+			// best-effort
+		}
+	}
+	if len(inputs.webhookSecrets) > 0 {
+		if err := setSecretOwnerRef(ctx, k8sClient, namespace, webhookSecretName(req.Name), imageBuild); err != nil {
+			// best-effort
+		}
+	}
+
+	return imageBuild, false
+}
+
+// buildInputs holds the parts of the ImageBuild spec derived from a validated BuildRequest
+type buildInputs struct {
+	// needsUpload is set when the build waits in the upload pod for local files the client uploads
+	// or remote files the pod downloads; clientUploads when there are local files
+	needsUpload   bool
+	clientUploads bool
+	remoteFiles   *automotivev1alpha1.RemoteFiles
+	source        *automotivev1alpha1.BuildSource
+	testUser      string
+	firstBoot     *automotivev1alpha1.FirstBoot
+	compliance    *automotivev1alpha1.ComplianceScan
+	sizeBudget    *automotivev1alpha1.SizeBudget
+	bootTest      *automotivev1alpha1.BootTest
+	debug         *automotivev1alpha1.BuildDebug
+	webhooks      []automotivev1alpha1.Webhook
+	commitStatus  *automotivev1alpha1.CommitStatus
+	retention     *automotivev1alpha1.Retention
+	signing       *automotivev1alpha1.ArtifactSigning
+	// webhookSecrets holds the signing secrets of webhooks, stored in the Secret they refer to
+	webhookSecrets map[string][]byte
+}
+
+// validateBuildRequest defaults req in place and validates everything that does not need the cluster
+func validateBuildRequest(req *BuildRequest) (*buildInputs, error) {
+	inputs := &buildInputs{}
+
+	if req.Name == "" || (req.Manifest == "" && req.Source == nil) {
+		return nil, fmt.Errorf("name and manifest are required")
+	}
+	var err error
+	if inputs.source, err = sourceFromRequest(req); err != nil {
+		return nil, err
+	}
+
+	if req.Distro == "" {
+		req.Distro = "cs9"
+	}
+	if req.Target == "" {
+		req.Target = "qemu"
+	}
+	if req.Architecture == "" {
+		req.Architecture = "arm64"
+	}
+	if req.ExportFormat == "" {
+		req.ExportFormat = "image"
+	}
+	if req.Mode == "" {
+		req.Mode = "image"
+	}
+
+	if strings.TrimSpace(req.Compression) == "" {
+		req.Compression = "gzip"
+	}
+	if !slices.Contains(supportedCompressions, req.Compression) {
+		return nil, fmt.Errorf("invalid compression: must be gzip, lz4 or zstd")
+	}
+
+	switch corev1.PersistentVolumeAccessMode(req.WorkspaceAccessMode) {
+	case "", corev1.ReadWriteOnce, corev1.ReadWriteMany:
+	default:
+		return nil, fmt.Errorf("invalid workspaceAccessMode: must be ReadWriteOnce or ReadWriteMany")
+	}
+
+	switch req.Priority {
+	case "", "low", "normal", "high":
+	default:
+		return nil, fmt.Errorf("invalid priority: must be low, normal or high")
+	}
+
+	for _, name := range req.NetworkExceptions {
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid networkExceptions entry %q: %s", name, strings.Join(errs, "; "))
+		}
+	}
+	if req.RuntimeClassName != "" {
+		if errs := validation.IsDNS1123Subdomain(req.RuntimeClassName); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid runtimeClassName %q: %s", req.RuntimeClassName, strings.Join(errs, "; "))
+		}
+	}
+
+	if !req.Distro.IsValid() {
+		return nil, fmt.Errorf("distro cannot be empty")
+	}
+	if !req.Target.IsValid() {
+		return nil, fmt.Errorf("target cannot be empty")
+	}
+	if !req.Architecture.IsValid() {
+		return nil, fmt.Errorf("architecture cannot be empty")
+	}
+	if !req.ExportFormat.IsValid() {
+		return nil, fmt.Errorf("exportFormat cannot be empty")
+	}
+	if !req.Mode.IsValid() {
+		return nil, fmt.Errorf("mode cannot be empty")
+	}
+	if req.AutomotiveImageBuilder == "" {
+		req.AutomotiveImageBuilder = tasks.AutomotiveImageBuilder
+	}
+	if req.ManifestFileName == "" {
+		req.ManifestFileName = "manifest.aib.yml"
+	}
+
+	inputs.testUser = strings.TrimSpace(req.TestUser)
+	if inputs.testUser != "" || len(req.SSHKeys) > 0 {
+		if inputs.testUser == "" {
+			return nil, fmt.Errorf("testUser is required with sshKeys")
+		}
+		if inputs.source != nil {
+			return nil, fmt.Errorf("testUser and sshKeys need the manifest in the request, not a source")
+		}
+		injected, err := injectTestAccess(req.Manifest, inputs.testUser, req.SSHKeys)
+		if err != nil {
+			return nil, err
+		}
+		req.Manifest = injected
+	}
+
+	if inputs.remoteFiles, inputs.clientUploads, err = remoteFilesFromRequest(*req); err != nil {
+		return nil, err
+	}
+	if inputs.firstBoot, err = firstBootFromRequest(string(req.Distro), req.FirstBoot); err != nil {
+		return nil, err
+	}
+	if inputs.firstBoot != nil && inputs.firstBoot.FileName != "" {
+		inputs.clientUploads = true
+	}
+	inputs.needsUpload = inputs.clientUploads || inputs.remoteFiles != nil
+
+	if _, err := hardening.Resolve(req.HardeningProfiles); err != nil {
+		return nil, err
+	}
+
+	if req.Compliance != nil {
+		profile := strings.TrimSpace(req.Compliance.Profile)
+		dataStream := strings.TrimSpace(req.Compliance.DataStream)
+		if profile == "" {
+			return nil, fmt.Errorf("compliance profile is required")
+		}
+		if dataStream != "" && !strings.HasPrefix(dataStream, "/") {
+			return nil, fmt.Errorf("compliance dataStream must be an absolute path in the build image")
+		}
+		inputs.compliance = &automotivev1alpha1.ComplianceScan{Profile: profile, DataStream: dataStream, Enforce: req.Compliance.Enforce}
+	}
+
+	if inputs.sizeBudget, err = sizeBudgetFromRequest(req.SizeBudget); err != nil {
+		return nil, err
+	}
+	if inputs.bootTest, err = bootTestFromRequest(req.BootTest); err != nil {
+		return nil, err
+	}
+	if inputs.debug, err = debugFromRequest(req.Debug); err != nil {
+		return nil, err
+	}
+	if inputs.webhooks, inputs.webhookSecrets, err = webhooksFromRequest(req.Name, req.Webhooks); err != nil {
+		return nil, err
+	}
+	if inputs.commitStatus, err = commitStatusFromRequest(req.CommitStatus); err != nil {
+		return nil, err
+	}
+	if inputs.retention, err = retentionFromRequest(req.Name, req.Retention); err != nil {
+		return nil, err
 	}
+	if inputs.signing, err = signingFromRequest(req.Signing); err != nil {
+		return nil, err
+	}
+	req.OutputName = strings.TrimSpace(req.OutputName)
+	if req.OutputName != "" {
+		sample := outputNameData{Name: req.Name, Distro: string(req.Distro), Target: string(req.Target),
+			Arch: string(req.Architecture), Mode: string(req.Mode), Export: string(req.ExportFormat), Ext: "raw.gz"}
+		if _, err := renderOutputName(req.OutputName, sample); err != nil {
+			return nil, err
+		}
+	}
+	return inputs, nil
+}
+
+// buildPlan holds the objects a BuildRequest creates, before anything is sent to the cluster
+type buildPlan struct {
+	configMap  *corev1.ConfigMap
+	imageBuild *automotivev1alpha1.ImageBuild
+	// workspaceSize is the size of the workspace PVC the controller will request for the build
+	workspaceSize resource.Quantity
+}
 
+// planBuild applies the OperatorConfig defaults to a validated request and returns the manifest
+// ConfigMap and ImageBuild to create. Only invalid user labels or annotations are reported as errors.
+func planBuild(ctx context.Context, k8sClient client.Client, namespace string, req BuildRequest, inputs *buildInputs, requestedBy, requestID string) (*buildPlan, error) {
 	serveExpiryHours := int32(24)
+	workspaceSize := resource.MustParse("8Gi")
 	{
+		// the OperatorConfig lives next to the build API, whichever namespace the build is created in
 		operatorConfig := &automotivev1alpha1.OperatorConfig{}
-		if err := k8sClient.Get(ctx, types.NamespacedName{Name: "config", Namespace: namespace}, operatorConfig); err == nil {
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: "config", Namespace: resolveNamespace()}, operatorConfig); err == nil {
 			if operatorConfig.Spec.OSBuilds != nil && operatorConfig.Spec.OSBuilds.ServeExpiryHours > 0 {
 				serveExpiryHours = operatorConfig.Spec.OSBuilds.ServeExpiryHours
 			}
+			if operatorConfig.Spec.OSBuilds != nil {
+				req.CustomDefs = mergeTargetDefines(operatorConfig.Spec.OSBuilds.TargetDefines[string(req.Target)], req.CustomDefs)
+				if size, err := resource.ParseQuantity(operatorConfig.Spec.OSBuilds.PVCSize); err == nil {
+					workspaceSize = size
+				}
+			}
 		}
 	}
 
-	var envSecretRef string
-	if req.RegistryCredentials != nil && req.RegistryCredentials.Enabled {
-		secretName, err := createRegistrySecret(ctx, k8sClient, namespace, req.Name, req.RegistryCredentials)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error creating registry secret: %v", err)})
-			return
-		}
-		envSecretRef = secretName
+	cfgName := fmt.Sprintf("%s-manifest", req.Name)
+	cmData := map[string]string{}
+	if inputs.source == nil {
+		cmData[req.ManifestFileName] = req.Manifest
 	}
 
-	imageBuild := &automotivev1alpha1.ImageBuild{
+	if len(req.CustomDefs) > 0 {
+		cmData["custom-definitions.env"] = strings.Join(req.CustomDefs, "\n")
+	}
+	if len(req.AIBOverrideArgs) > 0 {
+		// If override is provided, prefer it and ignore the regular extra args
+		cmData["aib-override-args.txt"] = strings.Join(req.AIBOverrideArgs, " ")
+	} else if len(req.AIBExtraArgs) > 0 {
+		cmData["aib-extra-args.txt"] = strings.Join(req.AIBExtraArgs, " ")
+	}
+
+	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      req.Name,
+			Name:      cfgName,
 			Namespace: namespace,
-			Labels:    labels,
-			Annotations: map[string]string{
-				"automotive.sdv.cloud.redhat.com/requested-by": requestedBy,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by":                  "build-api",
+				"app.kubernetes.io/part-of":                     "automotive-dev",
+				"app.kubernetes.io/created-by":                  "automotive-dev-build-api",
+				"automotive.sdv.cloud.redhat.com/resource-type": "manifest-config",
 			},
 		},
+		Data: cmData,
+	}
+
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by":                 "build-api",
+		"app.kubernetes.io/part-of":                    "automotive-dev",
+		"app.kubernetes.io/created-by":                 "automotive-dev-build-api",
+		"automotive.sdv.cloud.redhat.com/distro":       string(req.Distro),
+		"automotive.sdv.cloud.redhat.com/target":       string(req.Target),
+		"automotive.sdv.cloud.redhat.com/architecture": string(req.Architecture),
+	}
+	annotations := map[string]string{
+		"automotive.sdv.cloud.redhat.com/requested-by": requestedBy,
+	}
+	if requestID != "" {
+		annotations[requestIDAnnotation] = requestID
+	}
+	if req.OutputName != "" {
+		annotations[outputNameAnnotation] = req.OutputName
+	}
+	if inputs.needsUpload && !inputs.clientUploads {
+		// only remote files: the build starts once the upload pod downloaded them
+		annotations[uploadsCompleteAnnotation] = "true"
+	}
+	if inputs.testUser != "" {
+		labels["automotive.sdv.cloud.redhat.com/non-production"] = "true"
+		annotations["automotive.sdv.cloud.redhat.com/test-user"] = inputs.testUser
+	}
+	if err := mergeUserMetadata(labels, req.Labels, true); err != nil {
+		return nil, err
+	}
+	if err := mergeUserMetadata(annotations, req.Annotations, false); err != nil {
+		return nil, err
+	}
+
+	imageBuild := &automotivev1alpha1.ImageBuild{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        req.Name,
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
 		Spec: automotivev1alpha1.ImageBuildSpec{
 			Distro:                 string(req.Distro),
 			Target:                 string(req.Target),
@@ -843,204 +2244,2522 @@ func createBuild(c *gin.Context) {
 			Mode:                   string(req.Mode),
 			AutomotiveImageBuilder: req.AutomotiveImageBuilder,
 			StorageClass:           req.StorageClass,
+			WorkspaceAccessMode:    req.WorkspaceAccessMode,
 			ServeArtifact:          req.ServeArtifact,
 			ExposeRoute:            req.ServeArtifact,
 			ServeExpiryHours:       serveExpiryHours,
 			ManifestConfigMap:      cfgName,
-			InputFilesServer:       needsUpload,
-			EnvSecretRef:           envSecretRef,
+			Source:                 inputs.source,
+			InputFilesServer:       inputs.needsUpload,
 			Compression:            req.Compression,
+			FirstBoot:              inputs.firstBoot,
+			HardeningProfiles:      req.HardeningProfiles,
+			Compliance:             inputs.compliance,
+			SizeBudget:             inputs.sizeBudget,
+			BootTest:               inputs.bootTest,
+			Debug:                  inputs.debug,
+			WorkspaceProtection:    workspaceProtectionFromRequest(req.WorkspaceProtection),
+			Signing:                inputs.signing,
+			Webhooks:               inputs.webhooks,
+			CommitStatus:           inputs.commitStatus,
+			Priority:               req.Priority,
+			Retention:              inputs.retention,
+			RemoteFiles:            inputs.remoteFiles,
+			NetworkExceptions:      req.NetworkExceptions,
+			RuntimeClassName:       req.RuntimeClassName,
 		},
 	}
-	if err := k8sClient.Create(ctx, imageBuild); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error creating ImageBuild: %v", err)})
+	return &buildPlan{configMap: cm, imageBuild: imageBuild, workspaceSize: workspaceSize}, nil
+}
+
+// getInfo reports the maintenance state of the API. It is not authenticated so the CLI can show
+// the banner before logging in.
+func getInfo(c *gin.Context) {
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
 		return
 	}
+	resp := InfoResponse{Versions: componentVersions(c.Request.Context()), APIVersions: apiVersions}
+	if m := loadMaintenance(c.Request.Context(), k8sClient, resolveNamespace()); m != nil {
+		resp.ReadOnly = m.ReadOnly
+		resp.Banner = m.Banner
+	}
+	writeJSON(c, http.StatusOK, resp)
+}
 
-	if err := setOwnerRef(ctx, k8sClient, namespace, cfgName, imageBuild); err != nil {
-		// best-effort
+// loadMaintenance returns the maintenance settings of the OperatorConfig, nil when there are none
+// or the OperatorConfig cannot be read
+func loadMaintenance(ctx context.Context, k8sClient client.Client, namespace string) *automotivev1alpha1.MaintenanceConfig {
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: "config", Namespace: namespace}, operatorConfig); err != nil {
+		return nil
 	}
+	return operatorConfig.Spec.Maintenance
+}
 
-	if envSecretRef != "" {
-		if err := setOwnerRef(ctx, k8sClient, namespace, envSecretRef, imageBuild); err != nil {
-			// best-effort
-		}
+// workspaceStorageClass is the storage class the controller provisions the workspace of a build
+// asking for storageClass from: storageClass itself, else spec.osBuilds.workspaceStorageClass of the
+// OperatorConfig; empty for the default storage class
+func workspaceStorageClass(ctx context.Context, k8sClient client.Client, storageClass string) string {
+	if storageClass != "" {
+		return storageClass
+	}
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: "config", Namespace: resolveNamespace()}, operatorConfig); err != nil || operatorConfig.Spec.OSBuilds == nil {
+		return ""
 	}
+	return operatorConfig.Spec.OSBuilds.WorkspaceStorageClass
+}
 
-	writeJSON(c, http.StatusAccepted, BuildResponse{
-		Name:        req.Name,
-		Phase:       "Building",
-		Message:     "Build triggered",
-		RequestedBy: requestedBy,
-	})
+// maintenanceMessage is the error returned for requests rejected in read-only mode
+func maintenanceMessage(m *automotivev1alpha1.MaintenanceConfig) string {
+	msg := "the build API is in read-only mode for maintenance; builds cannot be created or changed"
+	if banner := strings.TrimSpace(m.Banner); banner != "" {
+		msg += ": " + banner
+	}
+	return msg
 }
 
-func listBuilds(c *gin.Context) {
-	namespace := resolveNamespace()
+// evaluatePolicies runs every check createBuild applies to a BuildRequest, plus a server-side dry run
+// of the objects it would create and the namespace storage quota, without creating anything
+func evaluatePolicies(c *gin.Context) {
+	var req BuildRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid JSON: %v", err)})
+		return
+	}
 
 	k8sClient, err := getClientFromRequest(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
 		return
 	}
-
 	ctx := c.Request.Context()
-	list := &automotivev1alpha1.ImageBuildList{}
-	if err := k8sClient.List(ctx, list, client.InNamespace(namespace)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error listing builds: %v", err)})
-		return
-	}
+	namespace := requestNamespace(c)
 
-	resp := make([]BuildListItem, 0, len(list.Items))
-	for _, b := range list.Items {
-		var startStr, compStr string
-		if b.Status.StartTime != nil {
-			startStr = b.Status.StartTime.Time.Format(time.RFC3339)
+	var checks []PolicyCheck
+	if m := loadMaintenance(ctx, k8sClient, resolveNamespace()); m != nil && m.ReadOnly {
+		checks = append(checks, PolicyCheck{Name: "maintenance", Message: maintenanceMessage(m)})
+	} else {
+		checks = append(checks, PolicyCheck{Name: "maintenance", Passed: true})
+	}
+	respond := func() {
+		allowed := true
+		for _, check := range checks {
+			if !check.Passed && !check.Skipped {
+				allowed = false
+			}
 		}
-		if b.Status.CompletionTime != nil {
-			compStr = b.Status.CompletionTime.Time.Format(time.RFC3339)
+		writeJSON(c, http.StatusOK, PolicyEvaluationResponse{Allowed: allowed, Checks: checks})
+	}
+	skipRest := func(names ...string) {
+		for _, name := range names {
+			checks = append(checks, PolicyCheck{Name: name, Skipped: true, Message: "not evaluated because an earlier check failed"})
 		}
-		resp = append(resp, BuildListItem{
-			Name:           b.Name,
-			Phase:          b.Status.Phase,
-			Message:        b.Status.Message,
-			RequestedBy:    b.Annotations["automotive.sdv.cloud.redhat.com/requested-by"],
-			CreatedAt:      b.CreationTimestamp.Time.Format(time.RFC3339),
-			StartTime:      startStr,
-			CompletionTime: compStr,
-		})
 	}
-	writeJSON(c, http.StatusOK, resp)
-}
 
-func getBuild(c *gin.Context, name string) {
-	namespace := resolveNamespace()
-	k8sClient, err := getClientFromRequest(c)
+	requestedBy := resolveRequester(c)
+	inputs, err := validateBuildRequest(&req)
+	var partner *automotivev1alpha1.PartnerIsolationConfig
+	if err == nil {
+		partner, err = loadPartnerIsolation(ctx, namespace)
+	}
+	if err == nil && partner != nil {
+		err = applyPartnerProfile(&req, inputs, partner, namespace, requestedBy)
+	}
+	if err == nil {
+		err = commitStatusGranted(ctx, namespace, inputs.commitStatus)
+	}
+	if err == nil {
+		err = remoteFilesGranted(ctx, inputs.remoteFiles)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		checks = append(checks, PolicyCheck{Name: "request", Message: err.Error()})
+		skipRest("metadata", "name", "admission", "quota", "storage", "images")
+		respond()
 		return
 	}
+	checks = append(checks, PolicyCheck{Name: "request", Passed: true})
 
-	ctx := c.Request.Context()
-	build := &automotivev1alpha1.ImageBuild{}
-	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
-		if k8serrors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-			return
+	plan, err := planBuild(ctx, k8sClient, namespace, req, inputs, requestedBy, c.GetString("reqID"))
+	if err != nil {
+		checks = append(checks, PolicyCheck{Name: "metadata", Message: err.Error()})
+		skipRest("name", "admission", "quota", "storage", "images")
+		respond()
+		return
+	}
+	if partner != nil {
+		markPartnerBuild(plan.imageBuild)
+	}
+	checks = append(checks, PolicyCheck{Name: "metadata", Passed: true})
+
+	nameCheck := PolicyCheck{Name: "name", Passed: true}
+	existing := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: req.Name, Namespace: namespace}, existing); err == nil {
+		nameCheck = PolicyCheck{Name: "name", Message: fmt.Sprintf("ImageBuild %s already exists", req.Name)}
+	} else if !k8serrors.IsNotFound(err) {
+		nameCheck = PolicyCheck{Name: "name", Skipped: true, Message: fmt.Sprintf("error checking existing build: %v", err)}
+	}
+	checks = append(checks, nameCheck)
+
+	// A server-side dry run goes through schema validation, admission webhooks and object count quotas.
+	// AlreadyExists is reported by the name check.
+	admission := PolicyCheck{Name: "admission", Passed: true}
+	for _, obj := range []client.Object{plan.configMap, plan.imageBuild} {
+		if err := k8sClient.Create(ctx, obj, client.DryRunAll); err != nil && !k8serrors.IsAlreadyExists(err) {
+			admission = PolicyCheck{Name: "admission", Message: err.Error()}
+			break
+		}
+	}
+	checks = append(checks, admission)
+
+	quotas := &corev1.ResourceQuotaList{}
+	if err := k8sClient.List(ctx, quotas, client.InNamespace(namespace)); err != nil {
+		checks = append(checks, PolicyCheck{Name: "quota", Skipped: true, Message: fmt.Sprintf("error listing resource quotas: %v", err)})
+	} else if violations := workspaceQuotaViolations(quotas.Items, workspaceStorageClass(ctx, k8sClient, req.StorageClass), plan.workspaceSize); len(violations) > 0 {
+		checks = append(checks, PolicyCheck{Name: "quota", Message: strings.Join(violations, "; ")})
+	} else if partner == nil {
+		checks = append(checks, PolicyCheck{Name: "quota", Passed: true})
+	} else if violation, err := partnerQuotaViolation(ctx, k8sClient, namespace, partner); err != nil {
+		checks = append(checks, PolicyCheck{Name: "quota", Skipped: true, Message: err.Error()})
+	} else if violation != "" {
+		checks = append(checks, PolicyCheck{Name: "quota", Message: violation})
+	} else {
+		checks = append(checks, PolicyCheck{Name: "quota", Passed: true})
+	}
+	checks = append(checks, workspaceStorageCheck(ctx, k8sClient, req))
+	imagesCheck, _ := containerImagesCheck(ctx, req)
+	checks = append(checks, imagesCheck)
+	respond()
+}
+
+// workspaceStorageCheck checks that the storage class of req can provide the requested workspace
+// access mode. It is skipped for users who may not read storage classes; the controller checks
+// again before it creates the workspace PVC.
+func workspaceStorageCheck(ctx context.Context, k8sClient client.Client, req BuildRequest) PolicyCheck {
+	sc, err := storage.Class(ctx, k8sClient, workspaceStorageClass(ctx, k8sClient, req.StorageClass))
+	mode := corev1.PersistentVolumeAccessMode(req.WorkspaceAccessMode)
+	if err == nil {
+		mode, err = storage.WorkspaceAccessMode(sc, req.WorkspaceAccessMode)
+	}
+	encrypted := req.WorkspaceProtection != nil && req.WorkspaceProtection.RequireEncryption
+	if err == nil && encrypted {
+		err = storage.RequireEncryption(sc)
+	}
+	var unsupported *storage.UnsupportedError
+	switch {
+	case errors.As(err, &unsupported):
+		return PolicyCheck{Name: "storage", Message: unsupported.Message}
+	case err != nil:
+		return PolicyCheck{Name: "storage", Skipped: true, Message: fmt.Sprintf("error reading storage classes: %v", err)}
+	case sc == nil:
+		return PolicyCheck{Name: "storage", Passed: true, Message: fmt.Sprintf("no default storage class; the %s workspace needs a matching volume", mode)}
+	case encrypted:
+		return PolicyCheck{Name: "storage", Passed: true, Message: fmt.Sprintf("storage class %s provides encrypted %s workspaces", sc.Name, mode)}
+	}
+	return PolicyCheck{Name: "storage", Passed: true, Message: fmt.Sprintf("storage class %s provides %s workspaces", sc.Name, mode)}
+}
+
+// workspaceQuotaViolations describes the ResourceQuotas the workspace PVC of a build would exceed.
+// The PVC is created by the controller, so it is not covered by the admission dry run.
+func workspaceQuotaViolations(quotas []corev1.ResourceQuota, storageClass string, size resource.Quantity) []string {
+	requests := corev1.ResourceList{
+		corev1.ResourcePersistentVolumeClaims: resource.MustParse("1"),
+		corev1.ResourceRequestsStorage:        size,
+	}
+	if storageClass != "" {
+		prefix := storageClass + ".storageclass.storage.k8s.io/"
+		requests[corev1.ResourceName(prefix+"persistentvolumeclaims")] = resource.MustParse("1")
+		requests[corev1.ResourceName(prefix+"requests.storage")] = size
+	}
+
+	var violations []string
+	for _, quota := range quotas {
+		for name, request := range requests {
+			hard, ok := quota.Status.Hard[name]
+			if !ok {
+				continue
+			}
+			used := quota.Status.Used[name]
+			total := used.DeepCopy()
+			total.Add(request)
+			if total.Cmp(hard) > 0 {
+				violations = append(violations, fmt.Sprintf("ResourceQuota %s: %s would be %s, limit %s", quota.Name, name, total.String(), hard.String()))
+			}
+		}
+	}
+	sort.Strings(violations)
+	return violations
+}
+
+// firstBootFromRequest validates the requested first-boot payload and converts it to the ImageBuild spec.
+// Inline payloads are fully validated here; ConfigMap and uploaded payloads are checked by the controller and build step.
+func firstBootFromRequest(distro string, fb *FirstBoot) (*automotivev1alpha1.FirstBoot, error) {
+	if fb == nil {
+		return nil, nil
+	}
+	if fb.Mode == "" {
+		fb.Mode = firstboot.ModeAttach
+	}
+
+	spec := &automotivev1alpha1.FirstBoot{Format: fb.Format, Mode: fb.Mode}
+	sources := 0
+	if fb.Inline != "" {
+		sources++
+		spec.Inline = fb.Inline
+	}
+	if fb.ConfigMap != "" || fb.Key != "" {
+		sources++
+		if fb.ConfigMap == "" || fb.Key == "" {
+			return nil, fmt.Errorf("firstBoot configMap and key must be set together")
+		}
+		spec.ConfigMapRef = &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: fb.ConfigMap},
+			Key:                  fb.Key,
+		}
+	}
+	if fb.FileName != "" {
+		sources++
+		if strings.Contains(fb.FileName, "/") || strings.Contains(fb.FileName, "..") {
+			return nil, fmt.Errorf("firstBoot fileName must be a plain file name")
+		}
+		spec.FileName = fb.FileName
+	}
+	if sources != 1 {
+		return nil, fmt.Errorf("firstBoot requires exactly one of inline, configMap+key or fileName")
+	}
+
+	if spec.Inline != "" {
+		if err := firstboot.Validate(distro, fb.Format, fb.Mode, spec.Inline); err != nil {
+			return nil, err
+		}
+	} else if err := firstboot.ValidateSpec(distro, fb.Format, fb.Mode); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// mergeTargetDefines returns the catalog defaults followed by the requested defines.
+// A default is dropped when the request sets the same KEY, so user values always win.
+func mergeTargetDefines(defaults, requested []string) []string {
+	if len(defaults) == 0 {
+		return requested
+	}
+	overridden := make(map[string]bool, len(requested))
+	for _, def := range requested {
+		key, _, _ := strings.Cut(def, "=")
+		overridden[strings.TrimSpace(key)] = true
+	}
+	merged := make([]string, 0, len(defaults)+len(requested))
+	for _, def := range defaults {
+		key, _, _ := strings.Cut(def, "=")
+		if overridden[strings.TrimSpace(key)] {
+			continue
+		}
+		merged = append(merged, def)
+	}
+	return append(merged, requested...)
+}
+
+// getDefinesCatalog returns the operator-managed default defines, optionally filtered by ?target=
+func getDefinesCatalog(c *gin.Context) {
+	namespace := resolveNamespace()
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+
+	catalog := map[string][]string{}
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	if err := k8sClient.Get(c.Request.Context(), types.NamespacedName{Name: "config", Namespace: namespace}, operatorConfig); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error reading operator config: %v", err)})
+			return
+		}
+	} else if operatorConfig.Spec.OSBuilds != nil {
+		catalog = operatorConfig.Spec.OSBuilds.TargetDefines
+	}
+
+	resp := DefinesCatalogResponse{Targets: map[string][]string{}}
+	if target := strings.TrimSpace(c.Query("target")); target != "" {
+		resp.Targets[target] = append([]string{}, catalog[target]...)
+	} else {
+		for target, defs := range catalog {
+			resp.Targets[target] = defs
+		}
+	}
+	writeJSON(c, http.StatusOK, resp)
+}
+
+const (
+	capabilitiesDataKey      = "capabilities.json"
+	capabilitiesProbeTimeout = 2 * time.Minute
+)
+
+// capabilitiesProbeScript prints each automotive-image-builder listing under a section marker
+const capabilitiesProbeScript = `set -e
+echo "## distros"; automotive-image-builder list-dist
+echo "## targets"; automotive-image-builder list-targets
+echo "## exports"; automotive-image-builder list-exports`
+
+// supportedArchitectures are the architectures builds accept, independent of the AIB version
+var supportedArchitectures = []string{"amd64", "arm64"}
+
+// supportedCompressions are the algorithms artifacts can be compressed with, independent of the
+// AIB version
+var supportedCompressions = []string{"gzip", "lz4", "zstd"}
+
+// getCapabilities returns the distros, targets, architectures and export formats supported by an
+// automotive-image-builder image (?automotiveImageBuilder=, default the operator's) and the
+// compression algorithms of the build API. The image is probed once with a short-lived pod and the
+// result is cached in a ConfigMap per image.
+func getCapabilities(c *gin.Context) {
+	namespace := resolveNamespace()
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+
+	image := strings.TrimSpace(c.Query("automotiveImageBuilder"))
+	if image == "" {
+		image = tasks.AutomotiveImageBuilder
+	}
+	ctx := c.Request.Context()
+	name := capabilitiesResourceName(image)
+
+	cached, err := cachedCapabilities(ctx, k8sClient, namespace, image)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if cached != nil {
+		cached.Compressions = append([]string{}, supportedCompressions...)
+		writeJSON(c, http.StatusOK, cached)
+		return
+	}
+
+	output, err := probeCapabilities(c, k8sClient, namespace, name, image)
+	if err != nil {
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": fmt.Sprintf("probing %s: %v", image, err)})
+		return
+	}
+	resp := parseCapabilitiesOutput(output)
+	resp.AutomotiveImageBuilder = image
+	resp.ProbedAt = time.Now().UTC().Format(time.RFC3339)
+
+	if data, err := json.Marshal(resp); err == nil {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/managed-by":                  "build-api",
+					"app.kubernetes.io/part-of":                     "automotive-dev",
+					"automotive.sdv.cloud.redhat.com/resource-type": "aib-capabilities",
+				},
+			},
+			Data: map[string]string{capabilitiesDataKey: string(data)},
+		}
+		// best-effort: a concurrent probe may have cached it already, and on failure the next
+		// request probes again
+		_ = k8sClient.Create(ctx, cm)
+	}
+	resp.Compressions = append([]string{}, supportedCompressions...)
+	writeJSON(c, http.StatusOK, resp)
+}
+
+// cachedCapabilities returns the capabilities of image probed earlier, nil when it was not probed yet
+func cachedCapabilities(ctx context.Context, k8sClient client.Client, namespace, image string) (*CapabilitiesResponse, error) {
+	cached := &corev1.ConfigMap{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: capabilitiesResourceName(image), Namespace: namespace}, cached); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading capabilities cache: %w", err)
+	}
+	var resp CapabilitiesResponse
+	if err := json.Unmarshal([]byte(cached.Data[capabilitiesDataKey]), &resp); err != nil || resp.AutomotiveImageBuilder != image {
+		return nil, nil
+	}
+	return &resp, nil
+}
+
+// capabilitiesResourceName derives the cache ConfigMap name, and the prefix of probe pod names,
+// from the image reference
+func capabilitiesResourceName(image string) string {
+	sum := sha256.Sum256([]byte(image))
+	return "aib-capabilities-" + hex.EncodeToString(sum[:])[:12]
+}
+
+// probeCapabilities runs the listing commands in a pod using image and returns their output.
+// Every request probes with a pod of its own named after name, so concurrent requests on a cold
+// cache never delete each other's pod.
+func probeCapabilities(c *gin.Context, k8sClient client.Client, namespace, name, image string) (string, error) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), capabilitiesProbeTimeout)
+	defer cancel()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: name + "-",
+			Namespace:    namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by":                  "build-api",
+				"app.kubernetes.io/part-of":                     "automotive-dev",
+				"automotive.sdv.cloud.redhat.com/resource-type": "aib-capabilities",
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:         corev1.RestartPolicyNever,
+			ActiveDeadlineSeconds: ptr.To(int64(300)),
+			Containers: []corev1.Container{{
+				Name:    "probe",
+				Image:   image,
+				Command: []string{"/bin/sh", "-c", capabilitiesProbeScript},
+			}},
+		},
+	}
+	if err := k8sClient.Create(ctx, pod); err != nil {
+		return "", fmt.Errorf("creating probe pod: %w", err)
+	}
+	defer func() {
+		_ = k8sClient.Delete(context.Background(), &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: namespace}})
+	}()
+
+	for {
+		current := &corev1.Pod{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: pod.Name, Namespace: namespace}, current); err != nil {
+			return "", fmt.Errorf("waiting for probe pod: %w", err)
+		}
+		if current.Status.Phase == corev1.PodFailed {
+			return "", fmt.Errorf("probe pod failed: %s", current.Status.Message)
+		}
+		if current.Status.Phase == corev1.PodSucceeded {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("probe pod did not finish in %s", capabilitiesProbeTimeout)
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	restCfg, err := getRESTConfigFromRequest(c)
+	if err != nil {
+		return "", err
+	}
+	cs, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return "", err
+	}
+	out, err := cs.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: "probe"}).DoRaw(ctx)
+	if err != nil {
+		return "", fmt.Errorf("reading probe output: %w", err)
+	}
+	return string(out), nil
+}
+
+// parseCapabilitiesOutput splits the probe output into its sections, keeping the first word of each
+// line so listings with descriptions ("qemu  QEMU virtual machine") reduce to the value to pass
+func parseCapabilitiesOutput(output string) CapabilitiesResponse {
+	resp := CapabilitiesResponse{
+		Distros:       []string{},
+		Targets:       []string{},
+		Architectures: append([]string{}, supportedArchitectures...),
+		ExportFormats: []string{},
+	}
+	var section *[]string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch line {
+		case "## distros":
+			section = &resp.Distros
+			continue
+		case "## targets":
+			section = &resp.Targets
+			continue
+		case "## exports":
+			section = &resp.ExportFormats
+			continue
+		}
+		if section == nil || line == "" || strings.HasSuffix(line, ":") {
+			continue
+		}
+		value := strings.Fields(line)[0]
+		if !slices.Contains(*section, value) {
+			*section = append(*section, value)
+		}
+	}
+	return resp
+}
+
+func listBuilds(c *gin.Context) {
+	opts, query, ok := buildListRequest(c)
+	if !ok {
+		return
+	}
+	if watchRequested(c.Query("watch")) {
+		watchBuilds(c, opts, query)
+		return
+	}
+	page, ok := listBuildPage(c, opts, query)
+	if !ok {
+		return
+	}
+
+	resp := make([]BuildListItem, 0, len(page.builds))
+	for i := range page.builds {
+		resp = append(resp, buildListItem(&page.builds[i]))
+	}
+	// The body stays a plain array for existing clients; paging information is sent in headers
+	c.Header("X-Total-Count", strconv.Itoa(page.total))
+	if page.next != "" {
+		c.Header("X-Continue", page.next)
+	}
+	// a watch started from this version receives the changes made after the list
+	c.Header("X-Resource-Version", page.resourceVersion)
+	writeJSON(c, http.StatusOK, resp)
+}
+
+// buildListRequest reads the list options and filters of a build list request. It answers the
+// request and returns false when they are invalid or the caller may not list all namespaces.
+func buildListRequest(c *gin.Context) ([]client.ListOption, *buildListQuery, bool) {
+	namespace := requestNamespace(c)
+	allNamespaces := c.Query("allNamespaces") == "true"
+	var opts []client.ListOption
+	if allNamespaces {
+		allowed, err := canListAllNamespaces(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("authorization check failed: %v", err)})
+			return nil, nil, false
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "listing builds in all namespaces requires permission to list imagebuilds cluster-wide"})
+			return nil, nil, false
+		}
+	} else {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	selector := c.Query("labelSelector")
+	if selector == "" {
+		selector = c.Query("label-selector")
+	}
+	if s := strings.TrimSpace(selector); s != "" {
+		sel, err := k8slabels.Parse(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid labelSelector: %v", err)})
+			return nil, nil, false
+		}
+		opts = append(opts, client.MatchingLabelsSelector{Selector: sel})
+	}
+	query, err := parseBuildListQuery(c.Query("phase"), c.Query("arch"), c.Query("created-after"), c.Query("limit"), c.Query("continue"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+	// without allNamespaces the namespace parameter selects the namespace to list instead
+	if allNamespaces {
+		for _, ns := range strings.Split(c.Query("namespace"), ",") {
+			if ns = strings.TrimSpace(ns); ns != "" {
+				query.namespaces = append(query.namespaces, ns)
+			}
+		}
+	}
+	return opts, query, true
+}
+
+// buildPage is a page of the build list
+type buildPage struct {
+	builds []automotivev1alpha1.ImageBuild
+	// total is the number of builds matching the filters, next the continue token of the next page
+	total           int
+	next            string
+	resourceVersion string
+}
+
+// listBuildPage lists the page of builds opts and query select. It answers the request and
+// returns false when the builds cannot be listed.
+func listBuildPage(c *gin.Context, opts []client.ListOption, query *buildListQuery) (buildPage, bool) {
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return buildPage{}, false
+	}
+	list := &automotivev1alpha1.ImageBuildList{}
+	if err := k8sClient.List(c.Request.Context(), list, opts...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error listing builds: %v", err)})
+		return buildPage{}, false
+	}
+	builds, total, next := selectBuilds(list.Items, query)
+	return buildPage{builds: builds, total: total, next: next, resourceVersion: list.ResourceVersion}, true
+}
+
+// buildListItem converts b to its representation in the build list
+func buildListItem(b *automotivev1alpha1.ImageBuild) BuildListItem {
+	var startStr, compStr string
+	if b.Status.StartTime != nil {
+		startStr = b.Status.StartTime.Time.Format(time.RFC3339)
+	}
+	if b.Status.CompletionTime != nil {
+		compStr = b.Status.CompletionTime.Time.Format(time.RFC3339)
+	}
+	item := BuildListItem{
+		Name:           b.Name,
+		Namespace:      b.Namespace,
+		Phase:          b.Status.Phase,
+		Message:        b.Status.Message,
+		RequestedBy:    b.Annotations["automotive.sdv.cloud.redhat.com/requested-by"],
+		CreatedAt:      b.CreationTimestamp.Time.Format(time.RFC3339),
+		StartTime:      startStr,
+		CompletionTime: compStr,
+		Labels:         userMetadata(b.Labels),
+	}
+	if d := b.Status.Downloads; d != nil {
+		item.Downloads = d.Count
+		if d.LastDownloadTime != nil {
+			item.LastDownloadAt = d.LastDownloadTime.UTC().Format(time.RFC3339)
+		}
+	}
+	if expireAt, deletedAt := artifactsExpiry(b); deletedAt != "" {
+		item.ExpiresAt = "deleted"
+	} else {
+		item.ExpiresAt = expireAt
+	}
+	return item
+}
+
+// buildListQuery holds the filters and the page requested from the build list
+type buildListQuery struct {
+	// phases match case-insensitively; builds the controller has not picked up yet are Pending
+	phases       []string
+	arch         string
+	createdAfter time.Time
+	// namespaces restricts an all-namespaces listing to these namespaces
+	namespaces []string
+	limit      int
+	after      *listCursor
+}
+
+// listCursor is the position after the last build of a page, encoded into the continue token.
+// Builds are listed newest first, so a cursor stays valid while new builds are created.
+type listCursor struct {
+	CreatedAt time.Time `json:"c"`
+	Namespace string    `json:"ns,omitempty"`
+	Name      string    `json:"n"`
+}
+
+func parseBuildListQuery(phase, arch, createdAfter, limit, continueToken string, now time.Time) (*buildListQuery, error) {
+	q := &buildListQuery{arch: strings.TrimSpace(arch)}
+	for _, p := range strings.Split(phase, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			q.phases = append(q.phases, p)
+		}
+	}
+	if s := strings.TrimSpace(createdAfter); s != "" {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			q.createdAfter = t
+		} else if window, err := parseStatsWindow(s); err == nil {
+			q.createdAfter = now.Add(-window)
+		} else {
+			return nil, fmt.Errorf("invalid created-after %q: use an RFC 3339 time or a window such as 7d or 24h", s)
+		}
+	}
+	if s := strings.TrimSpace(limit); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid limit %q: must be a positive number", s)
+		}
+		q.limit = n
+	}
+	if s := strings.TrimSpace(continueToken); s != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(s)
+		cursor := &listCursor{}
+		if err == nil {
+			err = json.Unmarshal(raw, cursor)
+		}
+		if err != nil || cursor.Name == "" {
+			return nil, fmt.Errorf("invalid continue token")
+		}
+		q.after = cursor
+	}
+	return q, nil
+}
+
+func (q *buildListQuery) matches(b *automotivev1alpha1.ImageBuild) bool {
+	if q.arch != "" && b.Spec.Architecture != q.arch {
+		return false
+	}
+	if len(q.namespaces) > 0 && !slices.Contains(q.namespaces, b.Namespace) {
+		return false
+	}
+	if !q.createdAfter.IsZero() && b.CreationTimestamp.Time.Before(q.createdAfter) {
+		return false
+	}
+	if len(q.phases) == 0 {
+		return true
+	}
+	phase := b.Status.Phase
+	if phase == "" {
+		phase = "Pending"
+	}
+	for _, p := range q.phases {
+		if strings.EqualFold(p, phase) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectBuilds filters builds, sorts them newest first and cuts the requested page. It returns the
+// number of builds matching the filters and the continue token of the next page, empty on the last.
+func selectBuilds(builds []automotivev1alpha1.ImageBuild, q *buildListQuery) ([]automotivev1alpha1.ImageBuild, int, string) {
+	matched := make([]automotivev1alpha1.ImageBuild, 0, len(builds))
+	for i := range builds {
+		if q.matches(&builds[i]) {
+			matched = append(matched, builds[i])
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		ti, tj := matched[i].CreationTimestamp.Time, matched[j].CreationTimestamp.Time
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		if matched[i].Namespace != matched[j].Namespace {
+			return matched[i].Namespace < matched[j].Namespace
+		}
+		return matched[i].Name < matched[j].Name
+	})
+
+	page := matched
+	if q.after != nil {
+		start := sort.Search(len(matched), func(i int) bool {
+			t := matched[i].CreationTimestamp.Time
+			if !t.Equal(q.after.CreatedAt) {
+				return t.Before(q.after.CreatedAt)
+			}
+			if matched[i].Namespace != q.after.Namespace {
+				return matched[i].Namespace > q.after.Namespace
+			}
+			return matched[i].Name > q.after.Name
+		})
+		page = matched[start:]
+	}
+	var next string
+	if q.limit > 0 && len(page) > q.limit {
+		page = page[:q.limit]
+		last := page[len(page)-1]
+		raw, _ := json.Marshal(listCursor{CreatedAt: last.CreationTimestamp.Time, Namespace: last.Namespace, Name: last.Name})
+		next = base64.RawURLEncoding.EncodeToString(raw)
+	}
+	return page, len(matched), next
+}
+
+// systemMetadataPrefixes are label and annotation key prefixes managed by the Build API and the operator
+var systemMetadataPrefixes = []string{"automotive.sdv.cloud.redhat.com/", "app.kubernetes.io/"}
+
+func isSystemMetadataKey(key string) bool {
+	for _, p := range systemMetadataPrefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeUserMetadata validates user-supplied labels (or annotations) and adds them to dst; keys under
+// the prefixes the Build API manages are rejected
+func mergeUserMetadata(dst, user map[string]string, isLabel bool) error {
+	kind := "annotation"
+	if isLabel {
+		kind = "label"
+	}
+	for k, v := range user {
+		if isSystemMetadataKey(k) {
+			return fmt.Errorf("%s %q uses a reserved prefix", kind, k)
+		}
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return fmt.Errorf("invalid %s key %q: %s", kind, k, strings.Join(errs, "; "))
+		}
+		if isLabel {
+			if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+				return fmt.Errorf("invalid value for label %q: %s", k, strings.Join(errs, "; "))
+			}
+		}
+		dst[k] = v
+	}
+	return nil
+}
+
+// userMetadata returns the labels (or annotations) of a build that were supplied by the user
+func userMetadata(all map[string]string) map[string]string {
+	var out map[string]string
+	for k, v := range all {
+		if isSystemMetadataKey(k) {
+			continue
+		}
+		if out == nil {
+			out = map[string]string{}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+const (
+	defaultStatsWindow = 7 * 24 * time.Hour
+	topFailuresLimit   = 5
+	maxFailureMessage  = 200
+)
+
+// parseStatsWindow parses a window like "7d", "12h" or "90m"; empty means the default window
+func parseStatsWindow(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return defaultStatsWindow, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid window %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q: use e.g. 7d, 24h or 90m", s)
+	}
+	return d, nil
+}
+
+// getBuildStats aggregates the builds created within ?since= (default 7d)
+func getBuildStats(c *gin.Context) {
+	window, err := parseStatsWindow(c.Query("since"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	list := &automotivev1alpha1.ImageBuildList{}
+	if err := k8sClient.List(c.Request.Context(), list, client.InNamespace(namespace)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error listing builds: %v", err)})
+		return
+	}
+	writeJSON(c, http.StatusOK, computeBuildStats(list.Items, time.Now().Add(-window)))
+}
+
+// computeBuildStats aggregates builds created at or after since
+func computeBuildStats(builds []automotivev1alpha1.ImageBuild, since time.Time) BuildStatsResponse {
+	resp := BuildStatsResponse{
+		Since:         since.UTC().Format(time.RFC3339),
+		Phases:        map[string]int{},
+		Targets:       map[string]int{},
+		Architectures: map[string]int{},
+		Durations:     []DurationStats{},
+		TopFailures:   []FailureCount{},
+	}
+
+	type groupKey struct{ target, arch string }
+	durations := map[groupKey][]float64{}
+	failures := map[string]int{}
+	var downloaded []automotivev1alpha1.ImageBuild
+	for _, b := range builds {
+		if b.CreationTimestamp.Time.Before(since) {
+			continue
+		}
+		resp.Total++
+		if b.Status.Downloads != nil {
+			downloaded = append(downloaded, b)
+		}
+		phase := b.Status.Phase
+		if phase == "" {
+			phase = "Pending"
+		}
+		resp.Phases[phase]++
+		resp.Targets[b.Spec.Target]++
+		resp.Architectures[b.Spec.Architecture]++
+
+		switch phase {
+		case "Completed":
+			if b.Status.StartTime != nil && b.Status.CompletionTime != nil {
+				k := groupKey{b.Spec.Target, b.Spec.Architecture}
+				durations[k] = append(durations[k], b.Status.CompletionTime.Sub(b.Status.StartTime.Time).Seconds())
+			}
+			if size := b.Status.ArtifactSizeBytes; size > 0 {
+				resp.ArtifactSize.Count++
+				resp.ArtifactSize.TotalBytes += size
+				resp.ArtifactSize.MaxBytes = max(resp.ArtifactSize.MaxBytes, size)
+			}
+		case "Failed":
+			msg := strings.TrimSpace(b.Status.Message)
+			if len(msg) > maxFailureMessage {
+				msg = msg[:maxFailureMessage] + "..."
+			}
+			failures[msg]++
+		}
+	}
+
+	if finished := resp.Phases["Completed"] + resp.Phases["Failed"]; finished > 0 {
+		resp.SuccessRate = float64(resp.Phases["Completed"]) / float64(finished)
+	}
+	if resp.ArtifactSize.Count > 0 {
+		resp.ArtifactSize.AverageBytes = resp.ArtifactSize.TotalBytes / int64(resp.ArtifactSize.Count)
+	}
+
+	for k, d := range durations {
+		sort.Float64s(d)
+		var sum float64
+		for _, v := range d {
+			sum += v
+		}
+		resp.Durations = append(resp.Durations, DurationStats{
+			Target:         k.target,
+			Architecture:   k.arch,
+			Count:          len(d),
+			AverageSeconds: sum / float64(len(d)),
+			P50Seconds:     percentile(d, 50),
+			P90Seconds:     percentile(d, 90),
+			P99Seconds:     percentile(d, 99),
+		})
+	}
+	sort.Slice(resp.Durations, func(i, j int) bool {
+		if resp.Durations[i].Target != resp.Durations[j].Target {
+			return resp.Durations[i].Target < resp.Durations[j].Target
+		}
+		return resp.Durations[i].Architecture < resp.Durations[j].Architecture
+	})
+
+	for msg, n := range failures {
+		resp.TopFailures = append(resp.TopFailures, FailureCount{Message: msg, Count: n})
+	}
+	sort.Slice(resp.TopFailures, func(i, j int) bool {
+		if resp.TopFailures[i].Count != resp.TopFailures[j].Count {
+			return resp.TopFailures[i].Count > resp.TopFailures[j].Count
+		}
+		return resp.TopFailures[i].Message < resp.TopFailures[j].Message
+	})
+	if len(resp.TopFailures) > topFailuresLimit {
+		resp.TopFailures = resp.TopFailures[:topFailuresLimit]
+	}
+	resp.Downloads = downloadStats(downloaded)
+	return resp
+}
+
+// percentile returns the nearest-rank percentile p of sorted values
+func percentile(sorted []float64, p int) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+const (
+	// maxFailureBuckets bounds the number of trend buckets of the failure analytics
+	maxFailureBuckets = 366
+	// topSignaturesLimit is the number of error signatures reported per failure reason
+	topSignaturesLimit = 5
+)
+
+// Failure reasons, from the most to the least specific
+const (
+	failureReasonCompliance = "compliance"
+	failureReasonSizeBudget = "size-budget"
+	failureReasonBootTest   = "boot-test"
+	failureReasonTimeout    = "timeout"
+	failureReasonDependency = "dependency"
+	failureReasonUpload     = "upload"
+	failureReasonBuild      = "build"
+	failureReasonUnknown    = "unknown"
+)
+
+// failureClassifiers map lower-cased failure message fragments to reasons; the first match wins
+var failureClassifiers = []struct {
+	reason    string
+	fragments []string
+}{
+	{failureReasonCompliance, []string{"compliance scan"}},
+	{failureReasonSizeBudget, []string{"size budget"}},
+	{failureReasonBootTest, []string{"boot test"}},
+	{failureReasonTimeout, []string{"timeout", "timed out", "deadline exceeded", "failed to finish within"}},
+	{failureReasonDependency, []string{"no match for argument", "nothing provides", "depsolve", "dnf", "package not found"}},
+	{failureReasonUpload, []string{"upload"}},
+	{failureReasonBuild, []string{"build failed", "exited with code"}},
+}
+
+// classifyFailure returns the reason a build failed, derived from its status message
+func classifyFailure(msg string) string {
+	lower := strings.ToLower(msg)
+	for _, cl := range failureClassifiers {
+		for _, f := range cl.fragments {
+			if strings.Contains(lower, f) {
+				return cl.reason
+			}
+		}
+	}
+	return failureReasonUnknown
+}
+
+var (
+	signatureUUID   = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	signatureDigest = regexp.MustCompile(`\b(sha256:)?[0-9a-fA-F]{12,}\b`)
+	signatureNumber = regexp.MustCompile(`\d+`)
+)
+
+// errorSignature normalizes a failure message of build so the same error in different builds
+// yields the same signature
+func errorSignature(msg, build string) string {
+	msg = strings.TrimSpace(msg)
+	// Tekton appends how to fetch the logs of the failed pod
+	if i := strings.Index(msg, "; for logs run"); i >= 0 {
+		msg = msg[:i]
+	}
+	if build != "" {
+		msg = strings.ReplaceAll(msg, build, "<build>")
+	}
+	msg = signatureUUID.ReplaceAllString(msg, "<id>")
+	msg = signatureDigest.ReplaceAllString(msg, "<digest>")
+	msg = signatureNumber.ReplaceAllString(msg, "<n>")
+	if len(msg) > maxFailureMessage {
+		msg = msg[:maxFailureMessage] + "..."
+	}
+	if msg == "" {
+		msg = "(no message)"
+	}
+	return msg
+}
+
+// getFailureAnalytics clusters the failed builds created within ?since= (default 7d) by reason and
+// error signature, counting them per ?bucket= (default 1d)
+func getFailureAnalytics(c *gin.Context) {
+	window, err := parseStatsWindow(c.Query("since"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	bucket := 24 * time.Hour
+	if v := c.Query("bucket"); v != "" {
+		if bucket, err = parseStatsWindow(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bucket: " + err.Error()})
+			return
+		}
+	}
+	if bucket > window || window/bucket > maxFailureBuckets {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("bucket must be at most the window and give at most %d buckets", maxFailureBuckets)})
+		return
+	}
+
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	list := &automotivev1alpha1.ImageBuildList{}
+	if err := k8sClient.List(c.Request.Context(), list, client.InNamespace(namespace)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error listing builds: %v", err)})
+		return
+	}
+	writeJSON(c, http.StatusOK, computeFailureAnalytics(list.Items, time.Now(), window, bucket))
+}
+
+// computeFailureAnalytics aggregates the builds created within window before now
+func computeFailureAnalytics(builds []automotivev1alpha1.ImageBuild, now time.Time, window, bucket time.Duration) FailureAnalyticsResponse {
+	since := now.Add(-window)
+	n := int((window + bucket - 1) / bucket)
+	resp := FailureAnalyticsResponse{
+		Since:   since.UTC().Format(time.RFC3339),
+		Bucket:  bucket.String(),
+		Buckets: make([]FailureBucket, n),
+		Reasons: []FailureReasonStats{},
+	}
+	for i := range resp.Buckets {
+		resp.Buckets[i].Start = since.Add(time.Duration(i) * bucket).UTC().Format(time.RFC3339)
+	}
+
+	type signatureKey struct{ reason, signature string }
+	reasons := map[string]*FailureReasonStats{}
+	signatures := map[signatureKey]*FailureSignature{}
+
+	// Newest first, so the example builds of a signature are the most recent ones
+	sorted := slices.Clone(builds)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreationTimestamp.After(sorted[j].CreationTimestamp.Time)
+	})
+	for _, b := range sorted {
+		created := b.CreationTimestamp.Time
+		if created.Before(since) || created.After(now) {
+			continue
+		}
+		i := min(int(created.Sub(since)/bucket), n-1)
+		resp.Builds++
+		resp.Buckets[i].Builds++
+		if b.Status.Phase != "Failed" {
+			continue
+		}
+		resp.Failures++
+		resp.Buckets[i].Failures++
+
+		reason := classifyFailure(b.Status.Message)
+		rs := reasons[reason]
+		if rs == nil {
+			rs = &FailureReasonStats{Reason: reason, Trend: make([]int, n)}
+			reasons[reason] = rs
+		}
+		rs.Count++
+		rs.Trend[i]++
+
+		key := signatureKey{reason, errorSignature(b.Status.Message, b.Name)}
+		sig := signatures[key]
+		if sig == nil {
+			sig = &FailureSignature{Signature: key.signature, LastSeen: created.UTC().Format(time.RFC3339)}
+			signatures[key] = sig
+		}
+		sig.Count++
+		if len(sig.Builds) < 3 {
+			sig.Builds = append(sig.Builds, b.Name)
+		}
+	}
+	if resp.Builds > 0 {
+		resp.FailureRate = float64(resp.Failures) / float64(resp.Builds)
+	}
+
+	for key, sig := range signatures {
+		rs := reasons[key.reason]
+		rs.Signatures = append(rs.Signatures, *sig)
+	}
+	for _, rs := range reasons {
+		rs.Share = float64(rs.Count) / float64(resp.Failures)
+		sort.Slice(rs.Signatures, func(i, j int) bool {
+			if rs.Signatures[i].Count != rs.Signatures[j].Count {
+				return rs.Signatures[i].Count > rs.Signatures[j].Count
+			}
+			return rs.Signatures[i].Signature < rs.Signatures[j].Signature
+		})
+		if len(rs.Signatures) > topSignaturesLimit {
+			rs.Signatures = rs.Signatures[:topSignaturesLimit]
+		}
+		resp.Reasons = append(resp.Reasons, *rs)
+	}
+	sort.Slice(resp.Reasons, func(i, j int) bool {
+		if resp.Reasons[i].Count != resp.Reasons[j].Count {
+			return resp.Reasons[i].Count > resp.Reasons[j].Count
+		}
+		return resp.Reasons[i].Reason < resp.Reasons[j].Reason
+	})
+	return resp
+}
+
+func getBuild(c *gin.Context, name string) {
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+	writeJSON(c, http.StatusOK, buildResponse(build))
+}
+
+// buildResponse converts build for the API
+func buildResponse(build *automotivev1alpha1.ImageBuild) BuildResponse {
+	size := sizeStatus(build)
+	var boot automotivev1alpha1.BootTestStatus
+	if build.Status.Boot != nil {
+		boot = *build.Status.Boot
+	}
+	var debugPod, debugHeldUntil string
+	if d := build.Status.Debug; d != nil {
+		debugPod = d.PodName
+		debugHeldUntil = d.HeldUntil.Format(time.RFC3339)
+	}
+	expireAt, deletedAt := artifactsExpiry(build)
+	return BuildResponse{
+		Name:             build.Name,
+		Phase:            build.Status.Phase,
+		Message:          build.Status.Message,
+		RequestedBy:      build.Annotations["automotive.sdv.cloud.redhat.com/requested-by"],
+		RequestID:        build.Annotations[requestIDAnnotation],
+		ArtifactURL:      build.Status.ArtifactURL,
+		ArtifactFileName: strings.TrimSpace(build.Status.ArtifactFileName),
+		StartTime: func() string {
+			if build.Status.StartTime != nil {
+				return build.Status.StartTime.Time.Format(time.RFC3339)
+			}
+			return ""
+		}(),
+		CompletionTime: func() string {
+			if build.Status.CompletionTime != nil {
+				return build.Status.CompletionTime.Time.Format(time.RFC3339)
+			}
+			return ""
+		}(),
+		StageTimings:            build.Status.StageTimings,
+		FirstBootFileName:       build.Status.FirstBootFileName,
+		HardeningReportFileName: build.Status.HardeningReportFileName,
+		PackagesFileName:        build.Status.PackagesFileName,
+		ComplianceResult:        complianceResult(build),
+		RootFSBytes:             size.RootFSBytes,
+		SizeBudgetBytes:         size.BudgetBytes,
+		SizeReportFileName:      size.ReportFileName,
+		BootResult:              bootResult(build),
+		BootTimings:             boot.Timings,
+		BootAccelerator:         boot.Accelerator,
+		BootConsoleLogFileName:  boot.ConsoleLogFileName,
+		DebugPod:                debugPod,
+		DebugHeldUntil:          debugHeldUntil,
+		Environment:             buildEnvironment(build),
+		LogArchive:              logArchive(build),
+		Publications:            publications(build),
+		WorkspaceAccessMode:     build.Status.WorkspaceAccessMode,
+		WorkspaceScrub:          workspaceScrub(build),
+		Signature:               artifactSignature(build),
+		ArtifactArchive:         artifactArchive(build),
+		Downloads:               artifactDownloads(build),
+		Priority:                build.Spec.Priority,
+		NetworkExceptions:       build.Spec.NetworkExceptions,
+		RuntimeClassName:        build.Spec.RuntimeClassName,
+		Source:                  sourceToRequest(build.Spec.Source),
+		GitCommit:               build.Status.GitCommit,
+		QueuePosition:           build.Status.QueuePosition,
+		Retention:               retentionToRequest(build.Spec.Retention),
+		ArtifactsExpireAt:       expireAt,
+		ArtifactsDeletedAt:      deletedAt,
+		CommitStatus:            commitStatusToRequest(build.Spec.CommitStatus),
+		PostedCommitStatus:      postedCommitStatus(build),
+	}
+}
+
+// publications converts status.publications of build for the API
+func publications(build *automotivev1alpha1.ImageBuild) []Publication {
+	var out []Publication
+	for _, p := range build.Status.Publications {
+		out = append(out, Publication{
+			Name:     p.Name,
+			Type:     p.Type,
+			Phase:    p.Phase,
+			Attempts: p.Attempts,
+			Location: p.Location,
+			Message:  p.Message,
+			Reason:   p.Reason,
+		})
+	}
+	return out
+}
+
+// cancelRequestedAnnotation asks the controller to stop a build; its value is the user who asked
+const cancelRequestedAnnotation = "automotive.sdv.cloud.redhat.com/cancel-requested-by"
+
+// isFinishedPhase reports whether a build in phase will not change phase anymore
+func isFinishedPhase(phase string) bool {
+	return phase == "Completed" || phase == "Failed" || phase == "Cancelled"
+}
+
+// cancelBuild marks a build for cancellation. The controller stops the build pod, releases the
+// workspace PVC and moves the build to Cancelled; repeating the request while it does so is a no-op.
+func cancelBuild(c *gin.Context, name string) {
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+	if isFinishedPhase(build.Status.Phase) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("build %s already finished with phase %s", name, build.Status.Phase)})
+		return
+	}
+
+	if _, requested := build.Annotations[cancelRequestedAnnotation]; !requested {
+		patched := build.DeepCopy()
+		if patched.Annotations == nil {
+			patched.Annotations = map[string]string{}
+		}
+		patched.Annotations[cancelRequestedAnnotation] = resolveRequester(c)
+		if err := k8sClient.Patch(ctx, patched, client.MergeFrom(build)); err != nil {
+			if k8serrors.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error requesting cancellation: %v", err)})
+			return
+		}
+		build = patched
+	}
+
+	writeJSON(c, http.StatusAccepted, BuildResponse{
+		Name:        build.Name,
+		Phase:       build.Status.Phase,
+		Message:     "Cancellation requested",
+		RequestedBy: build.Annotations["automotive.sdv.cloud.redhat.com/requested-by"],
+		RequestID:   build.Annotations[requestIDAnnotation],
+	})
+}
+
+// keptArtifactAnnotation is set on a workspace PVC kept after its build was deleted, to the name of the build
+const keptArtifactAnnotation = "automotive.sdv.cloud.redhat.com/kept-artifact-of"
+
+// deleteBuild deletes a build and, through owner references, its workspace, pods and artifact
+// serving resources. keep-artifact=true detaches the workspace PVC of a completed build first so
+// the artifact survives. Running builds are rejected with 409 unless force=true, which requests
+// cancellation and deletes the build pod before the build.
+func deleteBuild(c *gin.Context, name string) {
+	keepArtifact := c.Query("keep-artifact") == "true"
+	force := c.Query("force") == "true"
+
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+
+	running := !isFinishedPhase(build.Status.Phase)
+	if running && !force {
+		phase := build.Status.Phase
+		if phase == "" {
+			phase = "Pending"
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("build %s is still running (%s); cancel it first or pass force=true", name, phase)})
+		return
+	}
+
+	resp := BuildDeleteResponse{Name: name, Message: "Build deleted"}
+	if keepArtifact {
+		if build.Status.Phase != "Completed" || build.Status.PVCName == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "keep-artifact requires a completed build whose workspace still exists"})
+			return
+		}
+		if err := detachWorkspacePVC(ctx, k8sClient, build); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error keeping artifact: %v", err)})
+			return
+		}
+		resp.ArtifactPVC = build.Status.PVCName
+		resp.ArtifactPath = build.Status.ArtifactPath
+		resp.Message = "Build deleted; artifact kept on PVC " + build.Status.PVCName
+	}
+
+	// Cancel first so the controller stops the build instead of picking it up again, then delete in
+	// the foreground so the TaskRun and its pod are gone before the build is
+	propagation := metav1.DeletePropagationBackground
+	if running {
+		if _, requested := build.Annotations[cancelRequestedAnnotation]; !requested {
+			patched := build.DeepCopy()
+			if patched.Annotations == nil {
+				patched.Annotations = map[string]string{}
+			}
+			patched.Annotations[cancelRequestedAnnotation] = resolveRequester(c)
+			if err := k8sClient.Patch(ctx, patched, client.MergeFrom(build)); err != nil && !k8serrors.IsNotFound(err) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error requesting cancellation: %v", err)})
+				return
+			}
+		}
+		propagation = metav1.DeletePropagationForeground
+		resp.Message = "Build cancelled and deleted"
+	}
+	if err := k8sClient.Delete(ctx, build, client.PropagationPolicy(propagation)); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error deleting build: %v", err)})
+		return
+	}
+	writeJSON(c, http.StatusOK, resp)
+}
+
+// detachWorkspacePVC removes the owner reference of build from its workspace PVC so it is not
+// garbage collected with the build, and records which build it belonged to
+func detachWorkspacePVC(ctx context.Context, k8sClient client.Client, build *automotivev1alpha1.ImageBuild) error {
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: build.Status.PVCName, Namespace: build.Namespace}, pvc); err != nil {
+		return err
+	}
+	patched := pvc.DeepCopy()
+	patched.OwnerReferences = slices.DeleteFunc(patched.OwnerReferences, func(ref metav1.OwnerReference) bool {
+		return ref.UID == build.UID
+	})
+	if patched.Annotations == nil {
+		patched.Annotations = map[string]string{}
+	}
+	patched.Annotations[keptArtifactAnnotation] = build.Name
+	return k8sClient.Patch(ctx, patched, client.MergeFrom(pvc))
+}
+
+// getBuildTemplate returns a BuildRequest-like struct representing the inputs that produced a given build
+func getBuildTemplate(c *gin.Context, name string) {
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+
+	tpl, err := loadBuildTemplate(ctx, k8sClient, build)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	writeJSON(c, http.StatusOK, tpl)
+}
+
+// loadBuildTemplate rehydrates the BuildRequest inputs of build from its spec and manifest ConfigMap
+func loadBuildTemplate(ctx context.Context, k8sClient client.Client, build *automotivev1alpha1.ImageBuild) (*BuildTemplateResponse, error) {
+	cm := &corev1.ConfigMap{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: build.Spec.ManifestConfigMap, Namespace: build.Namespace}, cm); err != nil {
+		return nil, fmt.Errorf("error fetching manifest config: %w", err)
+	}
+
+	// Rehydrate advanced args
+	var aibExtra []string
+	var aibOverride []string
+	var customDefs []string
+	if v, ok := cm.Data["aib-extra-args.txt"]; ok {
+		fields := strings.Fields(strings.TrimSpace(v))
+		aibExtra = append(aibExtra, fields...)
+	}
+	if v, ok := cm.Data["aib-override-args.txt"]; ok {
+		fields := strings.Fields(strings.TrimSpace(v))
+		aibOverride = append(aibOverride, fields...)
+	}
+	if v, ok := cm.Data["custom-definitions.env"]; ok {
+		for _, line := range strings.Split(v, "\n") {
+			if s := strings.TrimSpace(line); s != "" {
+				customDefs = append(customDefs, s)
+			}
+		}
+	}
+
+	manifestFileName := "manifest.aib.yml"
+	var manifest string
+	for k, v := range cm.Data {
+		if k == "custom-definitions.env" || k == "aib-extra-args.txt" || k == "aib-override-args.txt" ||
+			k == firstboot.ConfigMapKey || k == hardening.FragmentKey || k == hardening.ReportKey {
+			continue
+		}
+		manifestFileName = k
+		manifest = v
+		break
+	}
+
+	var sourceFiles []string
+	for _, line := range strings.Split(manifest, "\n") {
+		s := strings.TrimSpace(line)
+		if strings.HasPrefix(s, "source:") || strings.HasPrefix(s, "source_path:") {
+			parts := strings.SplitN(s, ":", 2)
+			if len(parts) == 2 {
+				p := strings.TrimSpace(parts[1])
+				p = strings.Trim(p, "'\"")
+				if p != "" && !strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "http") && !isRemoteFile(p) {
+					sourceFiles = append(sourceFiles, p)
+				}
+			}
+		}
+	}
+
+	return &BuildTemplateResponse{
+		BuildRequest: BuildRequest{
+			Name:                   build.Name,
+			Manifest:               manifest,
+			ManifestFileName:       manifestFileName,
+			Distro:                 Distro(build.Spec.Distro),
+			Target:                 Target(build.Spec.Target),
+			Architecture:           Architecture(build.Spec.Architecture),
+			ExportFormat:           ExportFormat(build.Spec.ExportFormat),
+			Mode:                   Mode(build.Spec.Mode),
+			AutomotiveImageBuilder: build.Spec.AutomotiveImageBuilder,
+			StorageClass:           build.Spec.StorageClass,
+			WorkspaceAccessMode:    build.Spec.WorkspaceAccessMode,
+			CustomDefs:             customDefs,
+			AIBExtraArgs:           aibExtra,
+			AIBOverrideArgs:        aibOverride,
+			ServeArtifact:          build.Spec.ServeArtifact,
+			Compression:            build.Spec.Compression,
+			FirstBoot:              firstBootToRequest(build.Spec.FirstBoot),
+			HardeningProfiles:      build.Spec.HardeningProfiles,
+			Compliance:             complianceToRequest(build.Spec.Compliance),
+			SizeBudget:             sizeBudgetToRequest(build.Spec.SizeBudget),
+			BootTest:               bootTestToRequest(build.Spec.BootTest),
+			Debug:                  debugToRequest(build.Spec.Debug),
+			WorkspaceProtection:    workspaceProtectionToRequest(build.Spec.WorkspaceProtection),
+			Signing:                signingToRequest(build.Spec.Signing),
+			OutputName:             build.Annotations[outputNameAnnotation],
+			Labels:                 userMetadata(build.Labels),
+			Annotations:            userMetadata(build.Annotations),
+			Priority:               build.Spec.Priority,
+			Retention:              retentionToRequest(build.Spec.Retention),
+			RemoteFilesSecretRef:   remoteFilesSecretRef(build.Spec.RemoteFiles),
+			NetworkExceptions:      build.Spec.NetworkExceptions,
+			RuntimeClassName:       build.Spec.RuntimeClassName,
+			Source:                 sourceToRequest(build.Spec.Source),
+		},
+		SourceFiles: sourceFiles,
+	}, nil
+}
+
+// getBuildManifest returns the manifest a build was submitted with, as the original YAML
+func getBuildManifest(c *gin.Context, name string) {
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+
+	tpl, err := loadBuildTemplate(ctx, k8sClient, build)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "manifest no longer available"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if git := tpl.Source; git != nil && git.Git != nil && tpl.Manifest == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("build %s was built from %s in %s at %s", name, git.Git.Path, git.Git.URL, gitRevision(build))})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", tpl.ManifestFileName))
+	c.Data(http.StatusOK, "application/yaml", []byte(tpl.Manifest))
+}
+
+// firstBootToRequest converts the ImageBuild firstBoot spec back to its API form
+// complianceResult summarizes the scan state of a build for API responses; empty when no scan was requested
+func complianceResult(build *automotivev1alpha1.ImageBuild) string {
+	switch {
+	case build.Spec.Compliance == nil:
+		return ""
+	case build.Status.Compliance == nil:
+		return "pending"
+	default:
+		return build.Status.Compliance.Result
+	}
+}
+
+// isComplianceResultFile reports whether base is one of the build's OpenSCAP result files
+func isComplianceResultFile(build *automotivev1alpha1.ImageBuild, base string) bool {
+	st := build.Status.Compliance
+	if st == nil || base == "" {
+		return false
+	}
+	return base == st.ARFFileName || base == st.ReportFileName
+}
+
+// getBuildCompliance returns the OpenSCAP scan outcome of a build
+func getBuildCompliance(c *gin.Context, name string) {
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(c.Request.Context(), types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+	if build.Spec.Compliance == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no compliance scan was requested for this build"})
+		return
+	}
+
+	resp := ComplianceResponse{
+		Name:    build.Name,
+		Profile: build.Spec.Compliance.Profile,
+		Result:  complianceResult(build),
+	}
+	if st := build.Status.Compliance; st != nil {
+		resp.ARFFileName = st.ARFFileName
+		resp.ReportFileName = st.ReportFileName
+	}
+	for _, cond := range build.Status.Conditions {
+		if cond.Type == "ComplianceScanPassed" {
+			resp.Message = cond.Message
+		}
+	}
+	if resp.Result == "pending" && build.Status.Phase == "Failed" {
+		resp.Message = "build failed before the scan ran"
+	}
+	writeJSON(c, http.StatusOK, resp)
+}
+
+// execParams are the pods/exec query parameters a client may set; the container is chosen by the server
+var execParams = []string{"command", "stdin", "stdout", "stderr", "tty"}
+
+// execBuild proxies a pods/exec request into the running pod of a build so users can inspect the
+// osbuild workspace without cluster access. The client speaks the Kubernetes exec protocol (SPDY or
+// WebSocket); the upgraded connection is passed through to the cluster API unchanged.
+func execBuild(c *gin.Context, name string) {
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+	tr := strings.TrimSpace(build.Status.TaskRunName)
+	if tr == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "build has no pod yet"})
+		return
+	}
+
+	restCfg, err := getRESTConfigFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	cs, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	pods, err := cs.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "tekton.dev/taskRun=" + tr})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(pods.Items) == 0 || pods.Items[0].Status.Phase != corev1.PodRunning {
+		c.JSON(http.StatusConflict, gin.H{"error": "build pod is not running; failed build pods are only kept when the build requests debug"})
+		return
+	}
+	pod := &pods.Items[0]
+
+	container := c.Query("container")
+	if container == "" {
+		container = execContainer(pod)
+	} else if !slices.ContainsFunc(pod.Spec.Containers, func(ct corev1.Container) bool { return ct.Name == container }) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("pod %s has no container %q", pod.Name, container)})
+		return
+	}
+	if container == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "no build step is running"})
+		return
+	}
+
+	execURL := cs.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(pod.Name).
+		Namespace(namespace).
+		SubResource("exec").
+		URL()
+	query := execURL.Query()
+	for _, key := range execParams {
+		for _, v := range c.QueryArray(key) {
+			query.Add(key, v)
+		}
+	}
+	query.Set("container", container)
+	execURL.RawQuery = query.Encode()
+
+	tlsCfg, err := rest.TLSConfigFor(restCfg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Protocol upgrades need HTTP/1.1, so HTTP/2 is not negotiated
+	base := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsCfg,
+		TLSNextProto:    map[string]func(string, *tls.Conn) http.RoundTripper{},
+	}
+	transport, err := rest.HTTPWrappersForConfig(restCfg, base)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.Out.URL = execURL
+			r.Out.Host = execURL.Host
+			// The cluster API is called with the credentials of restCfg, not the ones of the request
+			r.Out.Header.Del("Authorization")
+			r.Out.Header.Del("X-Forwarded-Access-Token")
+			// impersonation is applied by the transport once it was authorized
+			r.Out.Header.Del(authnv1.ImpersonateUserHeader)
+			r.Out.Header.Del(authnv1.ImpersonateGroupHeader)
+		},
+		Transport: transport,
+		ErrorHandler: func(w http.ResponseWriter, _ *http.Request, err error) {
+			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("exec failed: %v", err)})
+		},
+	}
+	proxy.ServeHTTP(c.Writer, c.Request)
+}
+
+// execContainer picks the step to exec into: the debug hold of a failed build, otherwise the running step
+func execContainer(pod *corev1.Pod) string {
+	var running string
+	for _, st := range pod.Status.ContainerStatuses {
+		if st.State.Running == nil || !strings.HasPrefix(st.Name, "step-") {
+			continue
+		}
+		if st.Name == "step-debug-hold" {
+			return st.Name
+		}
+		if running == "" {
+			running = st.Name
+		}
+	}
+	return running
+}
+
+// workspaceRoot is where the workspace PVC is mounted in build, artifact and workspace reader pods
+const workspaceRoot = "/workspace/shared"
+
+// buildPodRoots are directories of the build pod that are not on the workspace PVC, so they can
+// only be copied while the build pod runs (or is held for debugging)
+var buildPodRoots = []string{"/output", "/_build", "/manifest-work"}
+
+// resolveWorkspacePath cleans a path given to caib cp. Relative paths are relative to the workspace.
+// It returns the absolute path in the pod and whether it is on the workspace PVC.
+func resolveWorkspacePath(p string) (string, bool, error) {
+	p = strings.TrimSpace(p)
+	if p == "" {
+		return "", false, fmt.Errorf("path is required")
+	}
+	if !strings.HasPrefix(p, "/") {
+		p = workspaceRoot + "/" + p
+	}
+	p = path.Clean(p)
+	if p == workspaceRoot || strings.HasPrefix(p, workspaceRoot+"/") {
+		return p, true, nil
+	}
+	for _, root := range buildPodRoots {
+		if p == root || strings.HasPrefix(p, root+"/") {
+			return p, false, nil
+		}
+	}
+	return "", false, fmt.Errorf("path must be in %s or, while the build pod runs, in %s", workspaceRoot, strings.Join(buildPodRoots, ", "))
+}
+
+// copyFromWorkspace streams a file or directory of a build as a tar archive. It is read in the running
+// build pod, in the artifact pod, or in a short-lived reader pod mounting the workspace PVC.
+func copyFromWorkspace(c *gin.Context, name string) {
+	podPath, inWorkspace, err := resolveWorkspacePath(c.Query("path"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
 		return
 	}
 
-	writeJSON(c, http.StatusOK, BuildResponse{
-		Name:             build.Name,
-		Phase:            build.Status.Phase,
-		Message:          build.Status.Message,
-		RequestedBy:      build.Annotations["automotive.sdv.cloud.redhat.com/requested-by"],
-		ArtifactURL:      build.Status.ArtifactURL,
-		ArtifactFileName: strings.TrimSpace(build.Status.ArtifactFileName),
-		StartTime: func() string {
-			if build.Status.StartTime != nil {
-				return build.Status.StartTime.Time.Format(time.RFC3339)
-			}
-			return ""
-		}(),
-		CompletionTime: func() string {
-			if build.Status.CompletionTime != nil {
-				return build.Status.CompletionTime.Time.Format(time.RFC3339)
+	restCfg, err := getRESTConfigFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("rest config: %v", err)})
+		return
+	}
+	cs, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("clientset: %v", err)})
+		return
+	}
+
+	var podName, container string
+	if tr := strings.TrimSpace(build.Status.TaskRunName); tr != "" {
+		pods, err := cs.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "tekton.dev/taskRun=" + tr})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error listing build pods: %v", err)})
+			return
+		}
+		if len(pods.Items) > 0 && pods.Items[0].Status.Phase == corev1.PodRunning {
+			podName, container = pods.Items[0].Name, execContainer(&pods.Items[0])
+		}
+	}
+	if container == "" && !inWorkspace {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%s only exists while the build pod runs; start the build with debug to keep it after a failure", podPath)})
+		return
+	}
+	if container == "" {
+		podList := &corev1.PodList{}
+		if err := k8sClient.List(ctx, podList, client.InNamespace(namespace), client.MatchingLabels{
+			"app.kubernetes.io/name":                          "artifact-pod",
+			"automotive.sdv.cloud.redhat.com/imagebuild-name": name,
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error listing artifact pods: %v", err)})
+			return
+		}
+		for i := range podList.Items {
+			if podList.Items[i].Status.Phase == corev1.PodRunning {
+				podName, container = podList.Items[i].Name, "fileserver"
+				break
 			}
-			return ""
-		}(),
-	})
+		}
+	}
+	if container == "" {
+		if build.Status.WorkspaceScrub != nil {
+			c.JSON(http.StatusGone, gin.H{"error": fmt.Sprintf("the workspace of the build is no longer readable, its scrub is %s", build.Status.WorkspaceScrub.Phase)})
+			return
+		}
+		if build.Status.PVCName == "" {
+			c.JSON(http.StatusConflict, gin.H{"error": "build has no workspace yet"})
+			return
+		}
+		// every copy gets its own reader, so concurrent copies do not delete each other's pod
+		podName, err = startWorkspaceReader(ctx, k8sClient, build)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		container = "reader"
+		defer func() {
+			_ = k8sClient.Delete(context.Background(), &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: namespace}})
+		}()
+	}
+
+	execReq := cs.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			// Exit code 3 distinguishes a missing path from tar failures
+			Command: []string{"sh", "-c", `cd "$1" && [ -e "$2" ] || exit 3; exec tar -cf - "$2"`, "sh", path.Dir(podPath), path.Base(podPath)},
+			Stdout:  true,
+			Stderr:  true,
+		}, kscheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(restCfg, http.MethodPost, execReq.URL())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("executor: %v", err)})
+		return
+	}
+
+	c.Writer.Header().Set("Content-Type", "application/x-tar")
+	c.Writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.tar\"", path.Base(podPath)))
+	var stderr strings.Builder
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: c.Writer, Stderr: &stderr})
+	if err == nil || c.Writer.Written() {
+		return
+	}
+	c.Writer.Header().Del("Content-Type")
+	c.Writer.Header().Del("Content-Disposition")
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitStatus() == 3 {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s not found", podPath)})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("copy failed: %v: %s", err, strings.TrimSpace(stderr.String()))})
+}
+
+// startWorkspaceReader creates a reader pod for build and waits until it runs, returning its name.
+// The pod is owned by the build and deletes itself after ten minutes if it is not cleaned up; when it
+// does not start it is deleted right away.
+func startWorkspaceReader(ctx context.Context, k8sClient client.Client, build *automotivev1alpha1.ImageBuild) (string, error) {
+	pod := workspaceReaderPod(build)
+	if err := k8sClient.Create(ctx, pod); err != nil {
+		return "", fmt.Errorf("creating workspace reader pod: %w", err)
+	}
+	if err := waitForWorkspaceReader(ctx, k8sClient, pod); err != nil {
+		_ = k8sClient.Delete(context.Background(), pod)
+		return "", err
+	}
+	return pod.Name, nil
+}
+
+// workspaceReaderPod is a pod mounting the workspace PVC of build read-only, named after the build
+// with a random suffix
+func workspaceReaderPod(build *automotivev1alpha1.ImageBuild) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-workspace-reader-", build.Name),
+			Namespace:    build.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by":                    "build-api",
+				"app.kubernetes.io/part-of":                       "automotive-dev",
+				"automotive.sdv.cloud.redhat.com/resource-type":   "workspace-reader",
+				"automotive.sdv.cloud.redhat.com/imagebuild-name": build.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(build, automotivev1alpha1.GroupVersion.WithKind("ImageBuild"))},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:         corev1.RestartPolicyNever,
+			ActiveDeadlineSeconds: ptr.To(int64(600)),
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsUser:    ptr.To[int64](1000),
+				RunAsGroup:   ptr.To[int64](1000),
+				FSGroup:      ptr.To[int64](1000),
+				RunAsNonRoot: ptr.To(true),
+			},
+			Containers: []corev1.Container{{
+				Name:    "reader",
+				Image:   "quay.io/nginx/nginx-unprivileged:latest",
+				Command: []string{"sleep", "600"},
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "workspace",
+					MountPath: workspaceRoot,
+					ReadOnly:  true,
+				}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "workspace",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: build.Status.PVCName, ReadOnly: true},
+				},
+			}},
+		},
+	}
+	if build.Status.WorkspaceAccessMode != string(corev1.ReadWriteMany) {
+		// a ReadWriteOnce workspace can only be mounted on the node of the pods already using it.
+		// The reader carries the label it selects, so with no other pod of the build it may run
+		// on any node, and concurrent readers land on the same one.
+		pod.Spec.Affinity = &corev1.Affinity{
+			PodAffinity: &corev1.PodAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+					LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+						"automotive.sdv.cloud.redhat.com/imagebuild-name": build.Name,
+					}},
+					TopologyKey: corev1.LabelHostname,
+				}},
+			},
+		}
+	}
+	return pod
+}
+
+// waitForWorkspaceReader waits up to two minutes for pod to run
+func waitForWorkspaceReader(ctx context.Context, k8sClient client.Client, pod *corev1.Pod) error {
+	deadline := time.Now().Add(2 * time.Minute)
+	for {
+		current := &corev1.Pod{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, current); err != nil {
+			return fmt.Errorf("waiting for workspace reader pod: %w", err)
+		}
+		switch current.Status.Phase {
+		case corev1.PodRunning:
+			return nil
+		case corev1.PodSucceeded, corev1.PodFailed:
+			return fmt.Errorf("workspace reader pod exited, retry the copy")
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("workspace reader pod not ready")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// complianceToRequest converts the ImageBuild compliance spec back to its API form
+func complianceToRequest(spec *automotivev1alpha1.ComplianceScan) *ComplianceScan {
+	if spec == nil {
+		return nil
+	}
+	return &ComplianceScan{Profile: spec.Profile, DataStream: spec.DataStream, Enforce: spec.Enforce}
+}
+
+// sizeBudgetFromRequest validates the requested size budget and converts it to its ImageBuild form
+func sizeBudgetFromRequest(req *SizeBudget) (*automotivev1alpha1.SizeBudget, error) {
+	if req == nil {
+		return nil, nil
+	}
+	maxSize := strings.TrimSpace(req.MaxSize)
+	q, err := resource.ParseQuantity(maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sizeBudget maxSize %q: use a quantity such as 1536Mi or 2G", req.MaxSize)
+	}
+	if q.Sign() <= 0 {
+		return nil, fmt.Errorf("sizeBudget maxSize must be positive")
+	}
+	action := "Fail"
+	switch strings.ToLower(strings.TrimSpace(req.Action)) {
+	case "", "fail":
+	case "warn":
+		action = "Warn"
+	default:
+		return nil, fmt.Errorf("invalid sizeBudget action %q: must be Fail or Warn", req.Action)
+	}
+	return &automotivev1alpha1.SizeBudget{MaxSize: maxSize, Action: action}, nil
+}
+
+const maxBootTestTimeoutSeconds = 3600
+
+// bootTestFromRequest validates the requested boot test and converts it to its ImageBuild form
+func bootTestFromRequest(req *BootTest) (*automotivev1alpha1.BootTest, error) {
+	if req == nil {
+		return nil, nil
+	}
+	if req.TimeoutSeconds < 0 || req.TimeoutSeconds > maxBootTestTimeoutSeconds {
+		return nil, fmt.Errorf("bootTest timeoutSeconds must be between 1 and %d", maxBootTestTimeoutSeconds)
+	}
+	marker := strings.TrimSpace(req.ReadyMarker)
+	if marker != "" {
+		if strings.Contains(marker, "'") {
+			return nil, fmt.Errorf("bootTest readyMarker must not contain single quotes")
+		}
+		if _, err := regexp.Compile(marker); err != nil {
+			return nil, fmt.Errorf("invalid bootTest readyMarker: %v", err)
+		}
+	}
+	threshold := strings.TrimSpace(req.MaxKernelToReady)
+	if threshold != "" {
+		if d, err := time.ParseDuration(threshold); err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid bootTest maxKernelToReady %q: use a duration such as 15s", req.MaxKernelToReady)
+		}
+	}
+	timeout := req.TimeoutSeconds
+	if timeout == 0 {
+		timeout = 300
+	}
+	return &automotivev1alpha1.BootTest{
+		TimeoutSeconds:   timeout,
+		ReadyMarker:      marker,
+		MaxKernelToReady: threshold,
+		Enforce:          req.Enforce,
+	}, nil
+}
+
+// bootTestToRequest converts the ImageBuild boot test back to its API form
+func bootTestToRequest(spec *automotivev1alpha1.BootTest) *BootTest {
+	if spec == nil {
+		return nil
+	}
+	return &BootTest{
+		TimeoutSeconds:   spec.TimeoutSeconds,
+		ReadyMarker:      spec.ReadyMarker,
+		MaxKernelToReady: spec.MaxKernelToReady,
+		Enforce:          spec.Enforce,
+	}
+}
+
+const maxDebugHoldMinutes = 720
+
+// debugFromRequest validates the requested debug hold and converts it to its ImageBuild form
+func debugFromRequest(req *BuildDebug) (*automotivev1alpha1.BuildDebug, error) {
+	if req == nil {
+		return nil, nil
+	}
+	if req.HoldMinutes < 0 || req.HoldMinutes > maxDebugHoldMinutes {
+		return nil, fmt.Errorf("debug holdMinutes must be between 1 and %d", maxDebugHoldMinutes)
+	}
+	hold := req.HoldMinutes
+	if hold == 0 {
+		hold = 60
+	}
+	return &automotivev1alpha1.BuildDebug{HoldMinutes: hold}, nil
+}
+
+// debugToRequest converts the ImageBuild debug settings back to their API form
+func debugToRequest(spec *automotivev1alpha1.BuildDebug) *BuildDebug {
+	if spec == nil {
+		return nil
+	}
+	return &BuildDebug{HoldMinutes: spec.HoldMinutes}
+}
+
+// workspaceProtectionFromRequest converts the requested workspace protection to its ImageBuild form
+func workspaceProtectionFromRequest(req *WorkspaceProtection) *automotivev1alpha1.WorkspaceProtection {
+	if req == nil || (!req.RequireEncryption && !req.Scrub) {
+		return nil
+	}
+	return &automotivev1alpha1.WorkspaceProtection{RequireEncryption: req.RequireEncryption, Scrub: req.Scrub}
+}
+
+// workspaceProtectionToRequest converts the ImageBuild workspace protection back to its API form
+func workspaceProtectionToRequest(spec *automotivev1alpha1.WorkspaceProtection) *WorkspaceProtection {
+	if spec == nil {
+		return nil
+	}
+	return &WorkspaceProtection{RequireEncryption: spec.RequireEncryption, Scrub: spec.Scrub}
+}
+
+// buildEnvironment reports the environment recorded for build, nil before its TaskRun finished
+func buildEnvironment(build *automotivev1alpha1.ImageBuild) *BuildEnvironment {
+	env := build.Status.Environment
+	if env == nil {
+		return nil
+	}
+	return &BuildEnvironment{
+		NodeName:           env.NodeName,
+		KernelVersion:      env.KernelVersion,
+		OSImage:            env.OSImage,
+		ContainerRuntime:   env.ContainerRuntime,
+		BuilderImage:       env.BuilderImage,
+		BuilderImageDigest: env.BuilderImageDigest,
+		OSBuildVersion:     env.OSBuildVersion,
+		AIBVersion:         env.AIBVersion,
+		SELinux:            env.SELinux,
+		Sysctls:            env.Sysctls,
+	}
+}
+
+// workspaceScrub reports the scrub of the workspace of build, nil when none started
+func workspaceScrub(build *automotivev1alpha1.ImageBuild) *WorkspaceScrub {
+	st := build.Status.WorkspaceScrub
+	if st == nil {
+		return nil
+	}
+	out := &WorkspaceScrub{
+		Phase:         st.Phase,
+		Method:        st.Method,
+		FilesScrubbed: st.FilesScrubbed,
+		BytesScrubbed: st.BytesScrubbed,
+		Message:       st.Message,
+	}
+	if st.CompletionTime != nil {
+		out.CompletionTime = st.CompletionTime.UTC().Format(time.RFC3339)
+	}
+	return out
+}
+
+// bootResult summarizes the boot test state of a build for API responses; empty when none was requested
+func bootResult(build *automotivev1alpha1.ImageBuild) string {
+	switch {
+	case build.Spec.BootTest == nil:
+		return ""
+	case build.Status.Boot == nil:
+		return "pending"
+	default:
+		return build.Status.Boot.Result
+	}
+}
+
+// sizeBudgetToRequest converts the ImageBuild size budget back to its API form
+func sizeBudgetToRequest(spec *automotivev1alpha1.SizeBudget) *SizeBudget {
+	if spec == nil {
+		return nil
+	}
+	return &SizeBudget{MaxSize: spec.MaxSize, Action: spec.Action}
+}
+
+// sizeStatus returns the measured size of a build, zero when none was recorded
+func sizeStatus(build *automotivev1alpha1.ImageBuild) automotivev1alpha1.SizeStatus {
+	if build.Status.Size == nil {
+		return automotivev1alpha1.SizeStatus{}
+	}
+	return *build.Status.Size
+}
+
+// isSizeReportFile reports whether base is the build's size breakdown
+func isSizeReportFile(build *automotivev1alpha1.ImageBuild, base string) bool {
+	report := sizeStatus(build).ReportFileName
+	return report != "" && base == report
+}
+
+func firstBootToRequest(spec *automotivev1alpha1.FirstBoot) *FirstBoot {
+	if spec == nil {
+		return nil
+	}
+	fb := &FirstBoot{Format: spec.Format, Mode: spec.Mode, Inline: spec.Inline, FileName: spec.FileName}
+	if spec.ConfigMapRef != nil {
+		fb.ConfigMap = spec.ConfigMapRef.Name
+		fb.Key = spec.ConfigMapRef.Key
+	}
+	return fb
+}
+
+// cloneBuild creates a new build from the stored inputs of an existing one, with an optional JSON merge patch applied
+func cloneBuild(c *gin.Context, name string) {
+	var req BuildCloneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid JSON: %v", err)})
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	source := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, source); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+
+	tpl, err := loadBuildTemplate(ctx, k8sClient, source)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	buildReq, err := ApplyBuildPatch(tpl.BuildRequest, req.Patch)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	buildReq.Name = req.Name
+
+	createBuildFromRequest(c, buildReq)
+}
+
+// ApplyBuildPatch applies a JSON merge patch (RFC 7386), given as JSON or YAML, to a BuildRequest.
+// It is what the clone endpoint applies to the inputs of the source build.
+func ApplyBuildPatch(base BuildRequest, patch string) (BuildRequest, error) {
+	if strings.TrimSpace(patch) == "" {
+		return base, nil
+	}
+	patchJSON, err := yaml.YAMLToJSON([]byte(patch))
+	if err != nil {
+		return BuildRequest{}, fmt.Errorf("invalid patch: %w", err)
+	}
+	original, err := json.Marshal(base)
+	if err != nil {
+		return BuildRequest{}, err
+	}
+	merged, err := jsonpatch.MergePatch(original, patchJSON)
+	if err != nil {
+		return BuildRequest{}, fmt.Errorf("error applying patch: %w", err)
+	}
+	var out BuildRequest
+	if err := json.Unmarshal(merged, &out); err != nil {
+		return BuildRequest{}, fmt.Errorf("patched build request is invalid: %w", err)
+	}
+	return out, nil
 }
 
-// getBuildTemplate returns a BuildRequest-like struct representing the inputs that produced a given build
-func getBuildTemplate(c *gin.Context, name string) {
-	namespace := resolveNamespace()
-	k8sClient, err := getClientFromRequest(c)
+var testUserNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+
+// testImageMarkerPath is written into images that carry an injected test user
+const testImageMarkerPath = "/etc/motd.d/99-caib-test-image"
+
+// injectTestAccess adds a login user with the given SSH public keys to the manifest's auth.users
+// and a motd marker flagging the image as non-production. Comments and ordering are preserved.
+func injectTestAccess(manifest, user string, keys []string) (string, error) {
+	if !testUserNamePattern.MatchString(user) || user == "root" {
+		return "", fmt.Errorf("invalid testUser %q", user)
+	}
+	if len(keys) == 0 {
+		return "", fmt.Errorf("at least one SSH public key is required for testUser")
+	}
+	for _, k := range keys {
+		k = strings.TrimSpace(k)
+		if strings.Contains(k, "PRIVATE KEY") {
+			return "", fmt.Errorf("sshKeys must contain public keys, got a private key")
+		}
+		if !strings.HasPrefix(k, "ssh-") && !strings.HasPrefix(k, "ecdsa-") && !strings.HasPrefix(k, "sk-") {
+			return "", fmt.Errorf("unrecognized SSH public key: %.20s...", k)
+		}
+	}
+
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal([]byte(manifest), &doc); err != nil {
+		return "", fmt.Errorf("invalid manifest YAML: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yamlv3.MappingNode {
+		return "", fmt.Errorf("manifest must be a YAML mapping")
+	}
+	root := doc.Content[0]
+
+	auth, err := yamlMapping(root, "auth")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
-		return
+		return "", err
+	}
+	users, err := yamlMapping(auth, "users")
+	if err != nil {
+		return "", err
 	}
+	if yamlLookup(users, user) != nil {
+		return "", fmt.Errorf("manifest already defines user %s", user)
+	}
+	entry := &yamlv3.Node{Kind: yamlv3.MappingNode}
+	entry.Content = append(entry.Content,
+		yamlScalar("groups"), &yamlv3.Node{Kind: yamlv3.SequenceNode, Style: yamlv3.FlowStyle, Content: []*yamlv3.Node{yamlScalar("wheel")}},
+		yamlScalar("key"), yamlScalar(strings.Join(keys, "\n")),
+	)
+	users.Content = append(users.Content, yamlScalar(user), entry)
 
-	ctx := c.Request.Context()
-	build := &automotivev1alpha1.ImageBuild{}
-	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
-		if k8serrors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
-		return
+	marker := fmt.Sprintf("NON-PRODUCTION TEST IMAGE: SSH access for user %s was injected by caib\n", user)
+	if err := yamlAddFile(root, testImageMarkerPath, marker); err != nil {
+		return "", err
 	}
 
-	cm := &corev1.ConfigMap{}
-	if err := k8sClient.Get(ctx, types.NamespacedName{Name: build.Spec.ManifestConfigMap, Namespace: namespace}, cm); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching manifest config: %v", err)})
-		return
+	out, err := yamlv3.Marshal(&doc)
+	if err != nil {
+		return "", err
 	}
+	return string(out), nil
+}
 
-	// Rehydrate advanced args
-	var aibExtra []string
-	var aibOverride []string
-	if v, ok := cm.Data["aib-extra-args.txt"]; ok {
-		fields := strings.Fields(strings.TrimSpace(v))
-		aibExtra = append(aibExtra, fields...)
+// addManifestFile adds a file with the given text at path of the image to the manifest's
+// content.add_files. Comments and ordering are preserved.
+func addManifestFile(manifest, path, text string) (string, error) {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal([]byte(manifest), &doc); err != nil {
+		return "", fmt.Errorf("invalid manifest YAML: %w", err)
 	}
-	if v, ok := cm.Data["aib-override-args.txt"]; ok {
-		fields := strings.Fields(strings.TrimSpace(v))
-		aibOverride = append(aibOverride, fields...)
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yamlv3.MappingNode {
+		return "", fmt.Errorf("manifest must be a YAML mapping")
+	}
+	if err := yamlAddFile(doc.Content[0], path, text); err != nil {
+		return "", err
 	}
+	out, err := yamlv3.Marshal(&doc)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
 
-	manifestFileName := "manifest.aib.yml"
-	var manifest string
-	for k, v := range cm.Data {
-		if k == "custom-definitions.env" || k == "aib-extra-args.txt" || k == "aib-override-args.txt" {
-			continue
+// yamlAddFile appends an add_files entry writing text to path to content.add_files of the
+// manifest root
+func yamlAddFile(root *yamlv3.Node, path, text string) error {
+	content, err := yamlMapping(root, "content")
+	if err != nil {
+		return err
+	}
+	addFiles := yamlLookup(content, "add_files")
+	if addFiles == nil || (addFiles.Kind == yamlv3.ScalarNode && addFiles.Tag == "!!null") {
+		if addFiles == nil {
+			addFiles = &yamlv3.Node{}
+			content.Content = append(content.Content, yamlScalar("add_files"), addFiles)
 		}
-		manifestFileName = k
-		manifest = v
-		break
+		addFiles.Kind, addFiles.Tag, addFiles.Value = yamlv3.SequenceNode, "!!seq", ""
+	}
+	if addFiles.Kind != yamlv3.SequenceNode {
+		return fmt.Errorf("content.add_files must be a list")
 	}
+	entry := &yamlv3.Node{Kind: yamlv3.MappingNode}
+	entry.Content = append(entry.Content,
+		yamlScalar("path"), yamlScalar(path),
+		yamlScalar("text"), yamlScalar(text),
+	)
+	addFiles.Content = append(addFiles.Content, entry)
+	return nil
+}
 
-	var sourceFiles []string
-	for _, line := range strings.Split(manifest, "\n") {
-		s := strings.TrimSpace(line)
-		if strings.HasPrefix(s, "source:") || strings.HasPrefix(s, "source_path:") {
-			parts := strings.SplitN(s, ":", 2)
-			if len(parts) == 2 {
-				p := strings.TrimSpace(parts[1])
-				p = strings.Trim(p, "'\"")
-				if p != "" && !strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "http") {
-					sourceFiles = append(sourceFiles, p)
-				}
-			}
+// yamlLookup returns the value node for key in a mapping node, or nil
+func yamlLookup(m *yamlv3.Node, key string) *yamlv3.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
 		}
 	}
+	return nil
+}
+
+// yamlMapping returns the mapping stored under key, creating it when missing or null
+func yamlMapping(m *yamlv3.Node, key string) (*yamlv3.Node, error) {
+	v := yamlLookup(m, key)
+	if v == nil {
+		v = &yamlv3.Node{Kind: yamlv3.MappingNode}
+		m.Content = append(m.Content, yamlScalar(key), v)
+		return v, nil
+	}
+	if v.Kind == yamlv3.ScalarNode && v.Tag == "!!null" {
+		v.Kind, v.Tag, v.Value = yamlv3.MappingNode, "!!map", ""
+	}
+	if v.Kind != yamlv3.MappingNode {
+		return nil, fmt.Errorf("manifest key %s must be a mapping", key)
+	}
+	return v, nil
+}
 
-	writeJSON(c, http.StatusOK, BuildTemplateResponse{
-		BuildRequest: BuildRequest{
-			Name:                   build.Name,
-			Manifest:               manifest,
-			ManifestFileName:       manifestFileName,
-			Distro:                 Distro(build.Spec.Distro),
-			Target:                 Target(build.Spec.Target),
-			Architecture:           Architecture(build.Spec.Architecture),
-			ExportFormat:           ExportFormat(build.Spec.ExportFormat),
-			Mode:                   Mode(build.Spec.Mode),
-			AutomotiveImageBuilder: build.Spec.AutomotiveImageBuilder,
-			CustomDefs:             nil,
-			AIBExtraArgs:           aibExtra,
-			AIBOverrideArgs:        aibOverride,
-			ServeArtifact:          build.Spec.ServeArtifact,
-			Compression:            build.Spec.Compression,
-		},
-		SourceFiles: sourceFiles,
-	})
+func yamlScalar(v string) *yamlv3.Node {
+	return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: v}
 }
 
 func uploadFiles(c *gin.Context, name string) {
-	namespace := resolveNamespace()
+	namespace := requestNamespace(c)
 
 	k8sClient, err := getClientFromRequest(c)
 	if err != nil {
@@ -1057,26 +4776,11 @@ func uploadFiles(c *gin.Context, name string) {
 		return
 	}
 
-	// Find upload pod
-	podList := &corev1.PodList{}
-	if err := k8sClient.List(c.Request.Context(), podList,
-		client.InNamespace(namespace),
-		client.MatchingLabels{
-			"automotive.sdv.cloud.redhat.com/imagebuild-name": name,
-			"app.kubernetes.io/name":                          "upload-pod",
-		},
-	); err != nil {
+	uploadPod, err := findUploadPod(c.Request.Context(), k8sClient, namespace, name)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error listing upload pods: %v", err)})
 		return
 	}
-	var uploadPod *corev1.Pod
-	for i := range podList.Items {
-		p := &podList.Items[i]
-		if p.Status.Phase == corev1.PodRunning {
-			uploadPod = p
-			break
-		}
-	}
 	if uploadPod == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "upload pod not ready"})
 		return
@@ -1112,9 +4816,9 @@ func uploadFiles(c *gin.Context, name string) {
 			return
 		}
 
-		cleanDest := path.Clean(dest)
-		if strings.HasPrefix(cleanDest, "..") || strings.HasPrefix(cleanDest, "/") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid destination path: %s", dest)})
+		cleanDest, err := uploadDestination(dest)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
@@ -1141,21 +4845,45 @@ func uploadFiles(c *gin.Context, name string) {
 		}
 	}
 
-	original := build
-	patched := original.DeepCopy()
-	if patched.Annotations == nil {
-		patched.Annotations = map[string]string{}
-	}
-	patched.Annotations["automotive.sdv.cloud.redhat.com/uploads-complete"] = "true"
-	if err := k8sClient.Patch(c.Request.Context(), patched, client.MergeFrom(original)); err != nil {
+	if err := markUploadsComplete(c.Request.Context(), k8sClient, build); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("mark complete failed: %v", err)})
 		return
 	}
 	writeJSON(c, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// findUploadPod returns the running upload pod of a build, or nil while there is none
+func findUploadPod(ctx context.Context, k8sClient client.Client, namespace, name string) (*corev1.Pod, error) {
+	podList := &corev1.PodList{}
+	if err := k8sClient.List(ctx, podList,
+		client.InNamespace(namespace),
+		client.MatchingLabels{
+			"automotive.sdv.cloud.redhat.com/imagebuild-name": name,
+			"app.kubernetes.io/name":                          "upload-pod",
+		},
+	); err != nil {
+		return nil, err
+	}
+	for i := range podList.Items {
+		if podList.Items[i].Status.Phase == corev1.PodRunning {
+			return &podList.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// markUploadsComplete lets the controller start a build waiting for its local files
+func markUploadsComplete(ctx context.Context, k8sClient client.Client, build *automotivev1alpha1.ImageBuild) error {
+	patched := build.DeepCopy()
+	if patched.Annotations == nil {
+		patched.Annotations = map[string]string{}
+	}
+	patched.Annotations[uploadsCompleteAnnotation] = "true"
+	return k8sClient.Patch(ctx, patched, client.MergeFrom(build))
+}
+
 func (a *APIServer) listArtifacts(c *gin.Context, name string) {
-	namespace := resolveNamespace()
+	namespace := requestNamespace(c)
 	ctx := c.Request.Context()
 
 	k8sClient, err := getClientFromRequest(c)
@@ -1178,6 +4906,9 @@ func (a *APIServer) listArtifacts(c *gin.Context, name string) {
 		c.JSON(http.StatusConflict, gin.H{"error": "artifact not available until build completes"})
 		return
 	}
+	if artifactsDeleted(c, build) {
+		return
+	}
 
 	artifactFileName := strings.TrimSpace(build.Status.ArtifactFileName)
 	if artifactFileName == "" {
@@ -1193,6 +4924,10 @@ func (a *APIServer) listArtifacts(c *gin.Context, name string) {
 		artifactFileName = fmt.Sprintf("%s-%s%s", build.Spec.Distro, build.Spec.Target, ext)
 	}
 
+	if artifactsArchiving(c, build) {
+		return
+	}
+
 	var artifactPod *corev1.Pod
 	deadline := time.Now().Add(2 * time.Minute)
 	for {
@@ -1285,8 +5020,273 @@ func (a *APIServer) listArtifacts(c *gin.Context, name string) {
 	writeJSON(c, http.StatusOK, map[string]any{"items": items})
 }
 
+// Kinds of the files in an artifact manifest
+const (
+	artifactKindImage            = "artifact"
+	artifactKindPart             = "part"
+	artifactKindFirstBoot        = "first-boot"
+	artifactKindHardeningReport  = "hardening-report"
+	artifactKindPackages         = "packages"
+	artifactKindComplianceResult = "compliance"
+	artifactKindSizeReport       = "size-report"
+	artifactKindBootLog          = "boot-log"
+	artifactKindSignature        = "signature"
+	artifactKindAttestation      = "attestation"
+)
+
+// Cosign bundles of the artifact, next to it in the workspace; caib download --verify checks the
+// artifact against them before saving it
+const (
+	signatureBundleSuffix   = ".sigstore.json"
+	attestationBundleSuffix = ".att.sigstore.json"
+)
+
+// artifactManifests caches the manifests of completed builds by UID; their files no longer change
+// and hashing them again for every request would read the whole artifact
+var artifactManifests sync.Map
+
+// artifactManifestScript prints kind, name, size and SHA-256 of the files given as triplets of
+// kind, path and known digest. Directories list their files; missing paths are skipped. Paths are
+// arguments rather than part of the script so file names need no quoting.
+const artifactManifestScript = `emit() {
+  [ -f "$2" ] || return 0
+  sum=$3
+  [ -n "$sum" ] || sum=$(sha256sum "$2" | cut -d" " -f1)
+  printf "%s\t%s\t%s\t%s\n" "$1" "${2##*/}" "$(wc -c < "$2" | tr -d " ")" "$sum"
+}
+while [ $# -ge 3 ]; do
+  if [ -d "$2" ]; then
+    for f in "$2"/*; do emit "$1" "$f" ""; done
+  else
+    emit "$1" "$2" "$3"
+  fi
+  shift 3
+done`
+
+// defaultArtifactFileName is the name of the main artifact of a build as stored in the workspace
+func defaultArtifactFileName(build *automotivev1alpha1.ImageBuild) string {
+	fileName := strings.TrimSpace(build.Status.ArtifactFileName)
+	if fileName == "" {
+		ext := "." + build.Spec.ExportFormat
+		switch build.Spec.ExportFormat {
+		case "image":
+			ext = ".raw"
+		case "qcow2":
+			ext = ".qcow2"
+		}
+		fileName = fmt.Sprintf("%s-%s%s", build.Spec.Distro, build.Spec.Target, ext)
+	}
+	if codec, ok := artifactCodecs[build.Spec.Compression]; ok && codec.ext != "" && !strings.HasSuffix(fileName, codec.ext) {
+		fileName += codec.ext
+	}
+	return fileName
+}
+
+// artifactManifestArgs returns the arguments of artifactManifestScript for the files of a build.
+// Failed builds only have the reports of the checks that failed them.
+func artifactManifestArgs(build *automotivev1alpha1.ImageBuild) []string {
+	var args []string
+	add := func(kind, fileName, sha256 string) {
+		if fileName = strings.TrimSpace(fileName); fileName != "" && !strings.Contains(fileName, "/") {
+			args = append(args, kind, "/workspace/shared/"+fileName, sha256)
+		}
+	}
+	if build.Status.Phase == "Completed" {
+		artifact := defaultArtifactFileName(build)
+		sum := ""
+		if artifact == strings.TrimSpace(build.Status.ArtifactFileName) {
+			sum = build.Status.ArtifactSHA256
+		}
+		add(artifactKindImage, artifact, sum)
+		if fileName := strings.TrimSpace(build.Status.ArtifactFileName); fileName != "" {
+			add(artifactKindPart, fileName+"-parts", "")
+		}
+		add(artifactKindSignature, artifact+signatureBundleSuffix, "")
+		add(artifactKindAttestation, artifact+attestationBundleSuffix, "")
+		add(artifactKindFirstBoot, build.Status.FirstBootFileName, "")
+		add(artifactKindHardeningReport, build.Status.HardeningReportFileName, "")
+		add(artifactKindPackages, build.Status.PackagesFileName, "")
+	}
+	if st := build.Status.Compliance; st != nil {
+		add(artifactKindComplianceResult, st.ARFFileName, "")
+		add(artifactKindComplianceResult, st.ReportFileName, "")
+	}
+	add(artifactKindSizeReport, sizeStatus(build).ReportFileName, "")
+	if build.Status.Boot != nil {
+		add(artifactKindBootLog, build.Status.Boot.ConsoleLogFileName, "")
+	}
+	return args
+}
+
+// parseArtifactManifest reads the output of artifactManifestScript
+func parseArtifactManifest(out string) []ArtifactFile {
+	files := []ArtifactFile{}
+	for _, ln := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(strings.TrimSpace(ln), "\t")
+		if len(fields) != 4 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		mediaType, compression := artifactMediaType(fields[1])
+		files = append(files, ArtifactFile{
+			Name:        fields[1],
+			Kind:        fields[0],
+			SizeBytes:   size,
+			SHA256:      fields[3],
+			MediaType:   mediaType,
+			Compression: compression,
+		})
+	}
+	return files
+}
+
+// artifactMediaType derives the media type and compression of a build file from its name
+func artifactMediaType(fileName string) (string, string) {
+	lower := strings.ToLower(fileName)
+	for name, codec := range artifactCodecs {
+		if codec.ext != "" && strings.HasSuffix(lower, codec.ext) {
+			return codec.contentType, name
+		}
+	}
+	switch path.Ext(lower) {
+	case ".qcow2":
+		return "application/x-qemu-disk", "none"
+	case ".tar":
+		return "application/x-tar", "none"
+	case ".json":
+		return "application/json", "none"
+	case ".xml":
+		return "application/xml", "none"
+	case ".html":
+		return "text/html", "none"
+	case ".log", ".txt":
+		return "text/plain", "none"
+	}
+	return "application/octet-stream", "none"
+}
+
+// getArtifactManifest lists the files of a build with their sizes and digests, before any download starts
+func (a *APIServer) getArtifactManifest(c *gin.Context, name string) {
+	namespace := requestNamespace(c)
+	ctx := c.Request.Context()
+
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+	if build.Status.Phase != "Completed" && build.Status.Phase != "Failed" {
+		c.JSON(http.StatusConflict, gin.H{"error": "artifact not available until build completes"})
+		return
+	}
+	if artifactsDeleted(c, build) {
+		return
+	}
+	if cached, ok := artifactManifests.Load(build.UID); ok {
+		writeJSON(c, http.StatusOK, cached)
+		return
+	}
+	args := artifactManifestArgs(build)
+	if len(args) == 0 {
+		writeJSON(c, http.StatusOK, ArtifactManifestResponse{Name: name, Files: []ArtifactFile{}})
+		return
+	}
+
+	if artifactsArchiving(c, build) {
+		return
+	}
+
+	var artifactPod *corev1.Pod
+	deadline := time.Now().Add(2 * time.Minute)
+	for artifactPod == nil {
+		podList := &corev1.PodList{}
+		if err := k8sClient.List(ctx, podList,
+			client.InNamespace(namespace),
+			client.MatchingLabels{
+				"app.kubernetes.io/name":                          "artifact-pod",
+				"automotive.sdv.cloud.redhat.com/imagebuild-name": name,
+			}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error listing artifact pods: %v", err)})
+			return
+		}
+		for i := range podList.Items {
+			p := &podList.Items[i]
+			for _, cs := range p.Status.ContainerStatuses {
+				if p.Status.Phase == corev1.PodRunning && cs.Name == "fileserver" && cs.Ready {
+					artifactPod = p
+				}
+			}
+		}
+		if artifactPod != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "artifact pod not ready"})
+			return
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	restCfg, err := getRESTConfigFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("rest config: %v", err)})
+		return
+	}
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("clientset: %v", err)})
+		return
+	}
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(artifactPod.Name).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: "fileserver",
+			Command:   append([]string{"sh", "-c", artifactManifestScript, "sh"}, args...),
+			Stdout:    true,
+			Stderr:    true,
+		}, kscheme.ParameterCodec)
+	exec, err := remotecommand.NewSPDYExecutor(restCfg, http.MethodPost, req.URL())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("executor (manifest): %v", err)})
+		return
+	}
+	var out strings.Builder
+	if err := exec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &out, Stderr: io.Discard}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("manifest stream: %v", err)})
+		return
+	}
+
+	resp := ArtifactManifestResponse{Name: name, Files: parseArtifactManifest(out.String())}
+	if build.Status.Phase == "Completed" && !signaturePending(build) {
+		artifactManifests.Store(build.UID, resp)
+	}
+	digests := make(map[string]string, len(resp.Files))
+	for _, f := range resp.Files {
+		digests[f.Name] = f.SHA256
+	}
+	a.recordArtifactDigests(build, digests)
+	a.log.Info("artifact manifest computed", "build", name, "files", len(resp.Files), "reqID", c.GetString("reqID"))
+	writeJSON(c, http.StatusOK, resp)
+}
+
 func (a *APIServer) streamArtifactPart(c *gin.Context, name, file string) {
-	namespace := resolveNamespace()
+	namespace := requestNamespace(c)
 	ctx := c.Request.Context()
 
 	if strings.Contains(file, "/") || strings.Contains(file, "..") || strings.TrimSpace(file) == "" {
@@ -1314,6 +5314,9 @@ func (a *APIServer) streamArtifactPart(c *gin.Context, name, file string) {
 		c.JSON(http.StatusConflict, gin.H{"error": "artifact not available until build completes"})
 		return
 	}
+	if artifactsDeleted(c, build) {
+		return
+	}
 
 	artifactFileName := strings.TrimSpace(build.Status.ArtifactFileName)
 	if artifactFileName == "" {
@@ -1329,6 +5332,10 @@ func (a *APIServer) streamArtifactPart(c *gin.Context, name, file string) {
 		artifactFileName = fmt.Sprintf("%s-%s%s", build.Spec.Distro, build.Spec.Target, ext)
 	}
 
+	if artifactsArchiving(c, build) {
+		return
+	}
+
 	var artifactPod *corev1.Pod
 	deadline := time.Now().Add(2 * time.Minute)
 	for {
@@ -1386,7 +5393,7 @@ func (a *APIServer) streamArtifactPart(c *gin.Context, name, file string) {
 		SubResource("exec").
 		VersionedParams(&corev1.PodExecOptions{
 			Container: "fileserver",
-			Command:   []string{"sh", "-c", "if [ -f \"" + gzPath + "\" ]; then wc -c < \"" + gzPath + "\"; else echo MISSING; fi"},
+			Command:   artifactStatCommand(gzPath, needsDigest(build, file)),
 			Stdout:    true,
 			Stderr:    true,
 		}, kscheme.ParameterCodec)
@@ -1400,11 +5407,22 @@ func (a *APIServer) streamArtifactPart(c *gin.Context, name, file string) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("size stream: %v", err)})
 		return
 	}
-	sz := strings.TrimSpace(sizeStdout.String())
-	if sz == "" || sz == "MISSING" {
+	size, sum, ok := parseArtifactStat(sizeStdout.String())
+	if !ok {
 		c.JSON(http.StatusNotFound, gin.H{"error": "artifact item not found"})
 		return
 	}
+	a.learnArtifactDigest(build, file, sum)
+
+	c.Writer.Header().Set("Content-Type", "application/gzip")
+	c.Writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", file))
+	c.Writer.Header().Set("X-AIB-Artifact-Type", "file")
+	c.Writer.Header().Set("X-AIB-Compression", "gzip")
+	setArtifactDigest(c, artifactDigest(build, file))
+	rng, ok := artifactRange(c, build, file, size)
+	if !ok || !writeArtifactHeaders(c, size, rng) {
+		return
+	}
 
 	streamReq := clientset.CoreV1().RESTClient().Post().
 		Resource("pods").
@@ -1413,30 +5431,22 @@ func (a *APIServer) streamArtifactPart(c *gin.Context, name, file string) {
 		SubResource("exec").
 		VersionedParams(&corev1.PodExecOptions{
 			Container: "fileserver",
-			Command:   []string{"cat", gzPath},
+			Command:   artifactReadCommand(gzPath, rng),
 			Stdout:    true,
 			Stderr:    true,
 		}, kscheme.ParameterCodec)
 	streamExec, err := remotecommand.NewSPDYExecutor(restCfg, http.MethodPost, streamReq.URL())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("executor (stream): %v", err)})
+		a.log.Error(err, "failed to create executor", "build", name, "file", file)
 		return
 	}
 
-	c.Writer.Header().Set("Content-Type", "application/gzip")
-	c.Writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", file))
-	c.Writer.Header().Set("Content-Length", sz)
-	c.Writer.Header().Set("X-AIB-Artifact-Type", "file")
-	c.Writer.Header().Set("X-AIB-Compression", "gzip")
-	if f, ok := c.Writer.(http.Flusher); ok {
-		f.Flush()
-	}
-
 	_ = streamExec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: c.Writer, Stderr: io.Discard})
+	a.recordArtifactDownload(c, build, file, rng)
 }
 
 func (a *APIServer) streamDefaultArtifact(c *gin.Context, name string) {
-	namespace := resolveNamespace()
+	namespace := requestNamespace(c)
 	ctx := c.Request.Context()
 
 	k8sClient, err := getClientFromRequest(c)
@@ -1459,6 +5469,9 @@ func (a *APIServer) streamDefaultArtifact(c *gin.Context, name string) {
 		c.JSON(http.StatusConflict, gin.H{"error": "artifact not available until build completes"})
 		return
 	}
+	if artifactsDeleted(c, build) {
+		return
+	}
 
 	artifactFileName := strings.TrimSpace(build.Status.ArtifactFileName)
 	if artifactFileName == "" {
@@ -1481,6 +5494,8 @@ func (a *APIServer) streamDefaultArtifact(c *gin.Context, name string) {
 			compressionExt = ".gz"
 		case "lz4":
 			compressionExt = ".lz4"
+		case "zstd":
+			compressionExt = ".zst"
 		}
 	}
 
@@ -1488,6 +5503,14 @@ func (a *APIServer) streamDefaultArtifact(c *gin.Context, name string) {
 		artifactFileName = artifactFileName + compressionExt
 	}
 
+	stored := storedCompression(build.Spec.Compression, artifactFileName)
+	delivered, contentEncoding, err := artifactDelivery(c.Request.Header, stored)
+	if err != nil {
+		c.JSON(http.StatusNotAcceptable, gin.H{"error": err.Error()})
+		return
+	}
+	deliveredName := strings.TrimSuffix(artifactFileName, artifactCodecs[stored].ext) + artifactCodecs[delivered].ext
+
 	restCfg, err := getRESTConfigFromRequest(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("rest config: %v", err)})
@@ -1499,6 +5522,10 @@ func (a *APIServer) streamDefaultArtifact(c *gin.Context, name string) {
 		return
 	}
 
+	if artifactsArchiving(c, build) {
+		return
+	}
+
 	var artifactPod *corev1.Pod
 	deadline := time.Now().Add(2 * time.Minute)
 	for {
@@ -1549,7 +5576,7 @@ func (a *APIServer) streamDefaultArtifact(c *gin.Context, name string) {
 		SubResource("exec").
 		VersionedParams(&corev1.PodExecOptions{
 			Container: "fileserver",
-			Command:   []string{"sh", "-c", "if [ -f '" + podPath + "' ]; then wc -c < '" + podPath + "'; else echo MISSING; fi"},
+			Command:   artifactStatCommand(podPath, needsDigest(build, artifactFileName)),
 			Stdout:    true,
 			Stderr:    true,
 		}, kscheme.ParameterCodec)
@@ -1570,11 +5597,13 @@ func (a *APIServer) streamDefaultArtifact(c *gin.Context, name string) {
 
 	sz := strings.TrimSpace(sizeStdout.String())
 	a.log.Info("file size check result", "build", name, "result", sz, "artifactFileName", artifactFileName)
-	if sz == "" || sz == "MISSING" {
+	size, sum, ok := parseArtifactStat(sz)
+	if !ok {
 		a.log.Info("file not found in artifact pod", "build", name, "artifactFileName", artifactFileName, "podPath", podPath)
 		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
 		return
 	}
+	a.learnArtifactDigest(build, artifactFileName, sum)
 
 	// Determine artifact type from filename
 	artifactType := "file"
@@ -1583,32 +5612,78 @@ func (a *APIServer) streamDefaultArtifact(c *gin.Context, name string) {
 	}
 
 	// Set appropriate content type based on file extension
-	var contentType string
-	if strings.HasSuffix(strings.ToLower(artifactFileName), ".lz4") {
-		contentType = "application/x-lz4"
-	} else if strings.Contains(strings.ToLower(artifactFileName), ".tar.") {
-		if strings.HasSuffix(strings.ToLower(artifactFileName), ".gz") {
-			contentType = "application/gzip"
-		} else {
-			contentType = "application/x-lz4"
+	contentType, _ := artifactMediaType(artifactFileName)
+
+	downloadName := deliveredName
+	if tmpl := strings.TrimSpace(c.Query("outputName")); tmpl != "" {
+		if downloadName, err = renderOutputName(tmpl, newOutputNameData(build, deliveredName)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else if tmpl := build.Annotations[outputNameAnnotation]; tmpl != "" {
+		if name, err := renderOutputName(tmpl, newOutputNameData(build, deliveredName)); err == nil {
+			downloadName = name
 		}
-	} else if strings.HasSuffix(strings.ToLower(artifactFileName), ".gz") {
-		contentType = "application/gzip"
-	} else {
-		contentType = "application/octet-stream"
 	}
 
 	// Set response headers
+	transcode := delivered != stored
+	if transcode {
+		contentType = artifactCodecs[delivered].contentType
+		if delivered == "none" && strings.HasSuffix(deliveredName, ".tar") {
+			contentType = "application/x-tar"
+		}
+	}
 	c.Writer.Header().Set("Content-Type", contentType)
-	c.Writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", artifactFileName))
-	c.Writer.Header().Set("Content-Length", sz)
+	c.Writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", downloadName))
 	c.Writer.Header().Set("X-AIB-Artifact-Type", artifactType)
-	if build.Spec.Compression != "" {
-		c.Writer.Header().Set("X-AIB-Compression", build.Spec.Compression)
+	c.Writer.Header().Set("X-AIB-Compression", delivered)
+	c.Writer.Header().Set("Vary", "Accept-Encoding, X-AIB-Accept-Compression")
+
+	// Transcoded artifacts are produced as they stream, so their length and digest are unknown
+	// and parts of them cannot be requested. Content-Encoding leaves the artifact as it is stored,
+	// so its digest still applies once the client decoded it.
+	var rng *byteRange
+	if contentEncoding != "" {
+		c.Writer.Header().Set("Content-Encoding", contentEncoding)
+		if sum := artifactDigest(build, artifactFileName); sum != "" {
+			c.Writer.Header().Set("X-AIB-Checksum", "sha256:"+sum)
+			setArtifactDigest(c, sum)
+		}
 	}
-
-	if f, ok := c.Writer.(http.Flusher); ok {
-		f.Flush()
+	if transcode || contentEncoding != "" {
+		c.Writer.Header().Set("Accept-Ranges", "none")
+		// Compressing for the transfer need not give the same bytes twice, so the tag is weak
+		if sum := artifactDigest(build, artifactFileName); sum != "" {
+			encoding := contentEncoding
+			if transcode {
+				encoding = delivered
+			}
+			etag := `W/"sha256-` + sum + "-" + encoding + `"`
+			c.Writer.Header().Set("ETag", etag)
+			if notModified(c, etag, time.Time{}) {
+				writeNotModified(c)
+				return
+			}
+		}
+		c.Status(http.StatusOK)
+		c.Writer.WriteHeaderNow()
+		if c.Request.Method == http.MethodHead {
+			return
+		}
+		if f, ok := c.Writer.(http.Flusher); ok {
+			f.Flush()
+		}
+	} else {
+		sum := artifactDigest(build, artifactFileName)
+		if sum != "" {
+			c.Writer.Header().Set("X-AIB-Checksum", "sha256:"+sum)
+		}
+		setArtifactDigest(c, sum)
+		var ok bool
+		if rng, ok = artifactRange(c, build, artifactFileName, size); !ok || !writeArtifactHeaders(c, size, rng) {
+			return
+		}
 	}
 
 	// Stream the file content
@@ -1619,23 +5694,440 @@ func (a *APIServer) streamDefaultArtifact(c *gin.Context, name string) {
 		SubResource("exec").
 		VersionedParams(&corev1.PodExecOptions{
 			Container: "fileserver",
-			Command:   []string{"cat", podPath},
+			Command:   artifactReadCommand(podPath, rng),
 			Stdout:    true,
 			Stderr:    true,
 		}, kscheme.ParameterCodec)
 
 	streamExec, err := remotecommand.NewSPDYExecutor(restCfg, http.MethodPost, streamReq.URL())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("executor (stream): %v", err)})
+		a.log.Error(err, "failed to create executor", "build", name)
 		return
 	}
 
-	_ = streamExec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: c.Writer, Stderr: io.Discard})
+	if !transcode && contentEncoding == "" {
+		_ = streamExec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: c.Writer, Stderr: io.Discard})
+		a.recordArtifactDownload(c, build, deliveredName, rng)
+		return
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(streamExec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: pw, Stderr: io.Discard}))
+	}()
+	defer pr.Close()
+	to := delivered
+	if contentEncoding != "" {
+		to = contentEncoding
+	}
+	if err := transcodeArtifact(c.Writer, pr, stored, to); err != nil {
+		a.log.Error(err, "artifact transcoding failed", "build", name, "from", stored, "to", to)
+	}
+	a.recordArtifactDownload(c, build, deliveredName, nil)
+}
+
+// byteRange is the part of a file a Range request asks for
+type byteRange struct {
+	start, length int64
+}
+
+// errUnsatisfiableRange is returned by parseByteRange when a range starts past the end of the file
+var errUnsatisfiableRange = errors.New("range not satisfiable")
+
+// parseByteRange interprets a Range header for a file of size bytes, e.g. "bytes=0-1023",
+// "bytes=1024-" or "bytes=-512". It returns nil, meaning the whole file, without a header and for
+// headers servers may ignore: malformed ones, other units and several ranges.
+func parseByteRange(header string, size int64) (*byteRange, error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return nil, nil
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return nil, nil
+	}
+	if first == "" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return nil, nil
+		}
+		if n == 0 || size == 0 {
+			return nil, errUnsatisfiableRange
+		}
+		n = min(n, size)
+		return &byteRange{start: size - n, length: n}, nil
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return nil, nil
+	}
+	end := size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return nil, nil
+		}
+		end = min(end, size-1)
+	}
+	if start >= size {
+		return nil, errUnsatisfiableRange
+	}
+	return &byteRange{start: start, length: end - start + 1}, nil
+}
+
+// artifactETag is the entity tag of a build file: its digest when known, otherwise derived from
+// the build UID, the name and the size, as the files of a build do not change once it completed
+func artifactETag(build *automotivev1alpha1.ImageBuild, fileName string, size int64) string {
+	if sum := artifactDigest(build, fileName); sum != "" {
+		return `"sha256-` + sum + `"`
+	}
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d", build.UID, fileName, size)))
+	return `"` + hex.EncodeToString(h[:16]) + `"`
+}
+
+// artifactDigest is the SHA-256 of a build file when it is known without reading the file: from
+// the status, otherwise from a manifest cached by getArtifactManifest
+func artifactDigest(build *automotivev1alpha1.ImageBuild, fileName string) string {
+	if fileName == strings.TrimSpace(build.Status.ArtifactFileName) && build.Status.ArtifactSHA256 != "" {
+		return build.Status.ArtifactSHA256
+	}
+	if sum := build.Status.ArtifactDigests[fileName]; sum != "" {
+		return sum
+	}
+	if cached, ok := artifactManifests.Load(build.UID); ok {
+		for _, f := range cached.(ArtifactManifestResponse).Files {
+			if f.Name == fileName {
+				return f.SHA256
+			}
+		}
+	}
+	return ""
+}
+
+// setArtifactDigest sends the digest of the whole file as Repr-Digest (RFC 9530), which stays
+// valid for partial responses
+func setArtifactDigest(c *gin.Context, sum string) {
+	raw, err := hex.DecodeString(sum)
+	if sum == "" || err != nil {
+		return
+	}
+	c.Writer.Header().Set("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(raw)+":")
+}
+
+// artifactRange sets the validators of a build file of size bytes and returns the part of it a
+// Range request asks for, or nil for all of it. A Range is only honored when If-Range, if sent,
+// still matches the file. ok is false when the response was written: 304 for a file the client
+// holds already (If-None-Match, If-Modified-Since) and 416 for unsatisfiable ranges.
+func artifactRange(c *gin.Context, build *automotivev1alpha1.ImageBuild, fileName string, size int64) (*byteRange, bool) {
+	etag := artifactETag(build, fileName, size)
+	var modified time.Time
+	if build.Status.CompletionTime != nil {
+		modified = build.Status.CompletionTime.UTC().Truncate(time.Second)
+		c.Writer.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+	}
+	c.Writer.Header().Set("ETag", etag)
+	c.Writer.Header().Set("Accept-Ranges", "bytes")
+	if notModified(c, etag, modified) {
+		writeNotModified(c)
+		return nil, false
+	}
+
+	header := c.GetHeader("Range")
+	if header == "" {
+		return nil, true
+	}
+	if ifRange := strings.TrimSpace(c.GetHeader("If-Range")); ifRange != "" {
+		if strings.HasPrefix(ifRange, `"`) {
+			// weak tags never match, so a W/ prefix falls through to the date comparison and fails
+			if ifRange != etag {
+				return nil, true
+			}
+		} else if t, err := http.ParseTime(ifRange); err != nil || modified.IsZero() || !t.Equal(modified) {
+			return nil, true
+		}
+	}
+	rng, err := parseByteRange(header, size)
+	if err != nil {
+		c.Writer.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		c.JSON(http.StatusRequestedRangeNotSatisfiable, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return rng, true
+}
+
+// writeArtifactHeaders sends the status and headers of a download of a size bytes long file, of
+// which rng is sent or all when rng is nil. It reports whether the body should follow, which is
+// not the case for HEAD requests.
+func writeArtifactHeaders(c *gin.Context, size int64, rng *byteRange) bool {
+	status, length := http.StatusOK, size
+	if rng != nil {
+		status, length = http.StatusPartialContent, rng.length
+		c.Writer.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", rng.start, rng.start+rng.length-1, size))
+	}
+	c.Writer.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	c.Status(status)
+	c.Writer.WriteHeaderNow()
+	if c.Request.Method == http.MethodHead {
+		return false
+	}
+	if f, ok := c.Writer.(http.Flusher); ok {
+		f.Flush()
+	}
+	return true
+}
+
+// artifactReadCommand is the command that prints the file at podPath, or the part rng of it, in
+// the artifact pod. The path is an argument of the script so it needs no quoting.
+func artifactReadCommand(podPath string, rng *byteRange) []string {
+	if rng == nil {
+		return []string{"cat", podPath}
+	}
+	return []string{"sh", "-c", `tail -c +"$1" "$3" | head -c "$2"`, "sh",
+		strconv.FormatInt(rng.start+1, 10), strconv.FormatInt(rng.length, 10), podPath}
+}
+
+// artifactCodec is a compression algorithm artifacts are stored or delivered in. Algorithms the
+// API server cannot decode are only delivered as stored; ones it cannot encode only when stored so.
+type artifactCodec struct {
+	ext         string
+	contentType string
+	newReader   func(io.Reader) (io.ReadCloser, error)
+	newWriter   func(io.Writer) io.WriteCloser
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// artifactCodecs are the compressions known to the negotiation, by X-AIB-Compression name
+var artifactCodecs = map[string]artifactCodec{
+	"none": {
+		contentType: "application/octet-stream",
+		newReader:   func(r io.Reader) (io.ReadCloser, error) { return io.NopCloser(r), nil },
+		newWriter:   func(w io.Writer) io.WriteCloser { return nopWriteCloser{w} },
+	},
+	"gzip": {
+		ext:         ".gz",
+		contentType: "application/gzip",
+		newReader:   func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+		newWriter:   func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+	},
+	"lz4": {ext: ".lz4", contentType: "application/x-lz4"},
+	"zstd": {
+		ext:         ".zst",
+		contentType: "application/zstd",
+		newReader: func(r io.Reader) (io.ReadCloser, error) {
+			dec, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return dec.IOReadCloser(), nil
+		},
+		newWriter: newZstdWriter,
+	},
+}
+
+// newZstdWriter compresses to w with zstd at its default level, on all CPUs
+func newZstdWriter(w io.Writer) io.WriteCloser {
+	// NewWriter only fails for invalid options
+	enc, _ := zstd.NewWriter(w)
+	return enc
+}
+
+// storedCompression is the compression of an artifact, from the build spec or else its file name
+func storedCompression(spec, fileName string) string {
+	if _, ok := artifactCodecs[spec]; ok && spec != "" {
+		return spec
+	}
+	for name, codec := range artifactCodecs {
+		if codec.ext != "" && strings.HasSuffix(fileName, codec.ext) {
+			return name
+		}
+	}
+	return "none"
+}
+
+// artifactDelivery decides how an artifact stored with the stored compression is sent. Clients
+// that advertise the compressions they accept get the best one the server can deliver; others get
+// the artifact as it is stored. Artifacts sent uncompressed as they are stored are compressed for
+// the transfer when Accept-Encoding allows it, except for parts of them.
+func artifactDelivery(header http.Header, stored string) (delivered, contentEncoding string, err error) {
+	delivered = stored
+	if accept := header.Get("X-AIB-Accept-Compression"); strings.TrimSpace(accept) != "" {
+		if delivered, err = negotiateCompression(accept, stored); err != nil {
+			return "", "", err
+		}
+	}
+	if stored == "none" && delivered == "none" && header.Get("Range") == "" {
+		contentEncoding = negotiateContentEncoding(header.Get("Accept-Encoding"))
+	}
+	return delivered, contentEncoding, nil
+}
+
+// canDeliver reports whether an artifact stored with one compression can be sent with another
+func canDeliver(stored, delivered string) bool {
+	if stored == delivered {
+		return true
+	}
+	from, to := artifactCodecs[stored], artifactCodecs[delivered]
+	return from.newReader != nil && to.newWriter != nil
+}
+
+// negotiateCompression picks the compression to deliver an artifact in from an
+// X-AIB-Accept-Compression header such as "zstd, lz4;q=0.8, gzip;q=0.5, none;q=0.1". Higher
+// q values win; among equals the stored compression is preferred, then the listed order.
+// Unknown algorithms are ignored, so clients can list algorithms this server does not know yet.
+func negotiateCompression(accept, stored string) (string, error) {
+	type candidate struct {
+		name string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				return "", fmt.Errorf("invalid q value in %q", part)
+			}
+			q = parsed
+		}
+		if name == "identity" {
+			name = "none"
+		}
+		if name == "*" {
+			name = stored
+		}
+		candidates = append(candidates, candidate{name: name, q: q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].q != candidates[j].q {
+			return candidates[i].q > candidates[j].q
+		}
+		return candidates[i].name == stored && candidates[j].name != stored
+	})
+	for _, cand := range candidates {
+		if _, known := artifactCodecs[cand.name]; known && cand.q > 0 && canDeliver(stored, cand.name) {
+			return cand.name, nil
+		}
+	}
+	var available []string
+	for name := range artifactCodecs {
+		if canDeliver(stored, name) {
+			available = append(available, name)
+		}
+	}
+	sort.Strings(available)
+	return "", fmt.Errorf("none of the accepted compressions can be delivered; the artifact is available as %s", strings.Join(available, ", "))
+}
+
+// contentEncodings are the Content-Encodings artifacts stored uncompressed can be sent with, in
+// order of preference among equal q values: zstd compresses faster and smaller than gzip
+var contentEncodings = []string{"zstd", "gzip"}
+
+// negotiateContentEncoding picks the Content-Encoding of an artifact stored uncompressed from an
+// Accept-Encoding header such as "zstd, gzip;q=0.5", or returns "" to send it as it is. Higher q
+// values win; "*" stands for zstd.
+func negotiateContentEncoding(accept string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "*" {
+			name = contentEncodings[0]
+		}
+		rank := slices.Index(contentEncodings, name)
+		if rank < 0 {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ || (q == bestQ && q > 0 && rank < slices.Index(contentEncodings, best)) {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// transcodeArtifact copies an artifact from one compression to another
+func transcodeArtifact(w io.Writer, r io.Reader, from, to string) error {
+	dec, err := artifactCodecs[from].newReader(r)
+	if err != nil {
+		return err
+	}
+	defer dec.Close()
+	enc := artifactCodecs[to].newWriter(w)
+	if _, err := io.Copy(enc, dec); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// outputNameAnnotation holds the template the artifact download of a build is named with
+const outputNameAnnotation = "automotive.sdv.cloud.redhat.com/output-name"
+
+// outputNameData is what an output name template can refer to, e.g. {{.Name}}-{{.Arch}}.{{.Ext}}
+type outputNameData struct {
+	Name   string
+	Distro string
+	Target string
+	Arch   string
+	Mode   string
+	Export string
+	// Ext is the extension of the artifact including its compression, e.g. raw.gz or tar.lz4
+	Ext string
+}
+
+func newOutputNameData(build *automotivev1alpha1.ImageBuild, fileName string) outputNameData {
+	// Artifacts are named <distro>-<target>.<ext>; distros and targets may contain dots themselves
+	ext := strings.TrimPrefix(fileName, build.Spec.Distro+"-"+build.Spec.Target+".")
+	if ext == fileName {
+		if i := strings.Index(fileName, "."); i >= 0 {
+			ext = fileName[i+1:]
+		} else {
+			ext = ""
+		}
+	}
+	return outputNameData{
+		Name:   build.Name,
+		Distro: build.Spec.Distro,
+		Target: build.Spec.Target,
+		Arch:   build.Spec.Architecture,
+		Mode:   build.Spec.Mode,
+		Export: build.Spec.ExportFormat,
+		Ext:    ext,
+	}
+}
+
+// renderOutputName executes an output name template. The result must be a plain file name.
+func renderOutputName(tmpl string, data outputNameData) (string, error) {
+	t, err := template.New("outputName").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid output name template: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid output name template: %w", err)
+	}
+	name := strings.TrimSpace(b.String())
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\\"") {
+		return "", fmt.Errorf("output name template must produce a file name, got %q", name)
+	}
+	return name, nil
 }
 
 // streamArtifactByFilename streams the specified artifact file from the artifact pod to the client over HTTP
 func (a *APIServer) streamArtifactByFilename(c *gin.Context, name, filename string) {
-	namespace := resolveNamespace()
+	namespace := requestNamespace(c)
 	ctx := c.Request.Context()
 
 	if strings.Contains(filename, "/") || strings.Contains(filename, "..") || strings.TrimSpace(filename) == "" {
@@ -1659,19 +6151,28 @@ func (a *APIServer) streamArtifactByFilename(c *gin.Context, name, filename stri
 		return
 	}
 
-	if build.Status.Phase != "Completed" {
+	// Compliance results, size reports and boot logs stay downloadable when the check they record failed the build
+	base := path.Base(filename)
+	reportFile := isComplianceResultFile(build, base) || isSizeReportFile(build, base) ||
+		(build.Status.Boot != nil && base == build.Status.Boot.ConsoleLogFileName)
+	if build.Status.Phase != "Completed" && (build.Status.Phase != "Failed" || !reportFile) {
 		c.JSON(http.StatusConflict, gin.H{"error": "artifact not available until build completes"})
 		return
 	}
+	if artifactsDeleted(c, build) {
+		return
+	}
 
 	// Only allow the exact final artifact file name or files from the -parts directory
 	expected := strings.TrimSpace(build.Status.ArtifactFileName)
-	base := path.Base(filename)
-	allowed := base == expected
+	allowed := base == expected || reportFile || isSignatureFile(build, base) ||
+		(build.Status.FirstBootFileName != "" && base == build.Status.FirstBootFileName) ||
+		(build.Status.HardeningReportFileName != "" && base == build.Status.HardeningReportFileName) ||
+		(build.Status.PackagesFileName != "" && base == build.Status.PackagesFileName)
 
 	if !allowed {
 		// Check if it's a part file (from -parts directory)
-		if strings.HasSuffix(base, ".gz") || strings.HasSuffix(base, ".lz4") {
+		if strings.HasSuffix(base, ".gz") || strings.HasSuffix(base, ".lz4") || strings.HasSuffix(base, ".zst") {
 			// Allow parts that follow the pattern: <expected>-parts/<filename>
 			if strings.Contains(base, ".tar.") || strings.HasPrefix(base, strings.TrimSuffix(expected, path.Ext(expected))) {
 				allowed = true
@@ -1696,6 +6197,10 @@ func (a *APIServer) streamArtifactByFilename(c *gin.Context, name, filename stri
 		return
 	}
 
+	if artifactsArchiving(c, build) {
+		return
+	}
+
 	var artifactPod *corev1.Pod
 	deadline := time.Now().Add(2 * time.Minute)
 	for {
@@ -1745,7 +6250,7 @@ func (a *APIServer) streamArtifactByFilename(c *gin.Context, name, filename stri
 		SubResource("exec").
 		VersionedParams(&corev1.PodExecOptions{
 			Container: "fileserver",
-			Command:   []string{"sh", "-c", "if [ -f '" + podPath + "' ]; then wc -c < '" + podPath + "'; else echo MISSING; fi"},
+			Command:   artifactStatCommand(podPath, needsDigest(build, base)),
 			Stdout:    true,
 			Stderr:    true,
 		}, kscheme.ParameterCodec)
@@ -1762,31 +6267,21 @@ func (a *APIServer) streamArtifactByFilename(c *gin.Context, name, filename stri
 		return
 	}
 
-	sz := strings.TrimSpace(sizeStdout.String())
-	if sz == "" || sz == "MISSING" {
+	size, sum, ok := parseArtifactStat(sizeStdout.String())
+	if !ok {
 		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
 		return
 	}
+	a.learnArtifactDigest(build, base, sum)
 
-	if strings.HasSuffix(strings.ToLower(base), ".lz4") {
-		c.Writer.Header().Set("Content-Type", "application/x-lz4")
-	} else if strings.Contains(strings.ToLower(base), ".tar.") {
-		if strings.HasSuffix(strings.ToLower(base), ".gz") {
-			c.Writer.Header().Set("Content-Type", "application/gzip")
-		} else {
-			c.Writer.Header().Set("Content-Type", "application/x-lz4")
-		}
-	} else if strings.HasSuffix(strings.ToLower(base), ".gz") {
-		c.Writer.Header().Set("Content-Type", "application/gzip")
-	} else {
-		c.Writer.Header().Set("Content-Type", "application/octet-stream")
-	}
+	contentType, _ := artifactMediaType(base)
+	c.Writer.Header().Set("Content-Type", contentType)
 
 	c.Writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", base))
-	c.Writer.Header().Set("Content-Length", sz)
-
-	if f, ok := c.Writer.(http.Flusher); ok {
-		f.Flush()
+	setArtifactDigest(c, artifactDigest(build, base))
+	rng, ok := artifactRange(c, build, base, size)
+	if !ok || !writeArtifactHeaders(c, size, rng) {
+		return
 	}
 
 	// Stream the file content
@@ -1797,18 +6292,19 @@ func (a *APIServer) streamArtifactByFilename(c *gin.Context, name, filename stri
 		SubResource("exec").
 		VersionedParams(&corev1.PodExecOptions{
 			Container: "fileserver",
-			Command:   []string{"cat", podPath},
+			Command:   artifactReadCommand(podPath, rng),
 			Stdout:    true,
 			Stderr:    true,
 		}, kscheme.ParameterCodec)
 
 	streamExec, err := remotecommand.NewSPDYExecutor(restCfg, http.MethodPost, streamReq.URL())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("executor (stream): %v", err)})
+		a.log.Error(err, "failed to create executor", "build", name, "file", base)
 		return
 	}
 
 	_ = streamExec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: c.Writer, Stderr: io.Discard})
+	a.recordArtifactDownload(c, build, base, rng)
 }
 
 func copyFileToPod(config *rest.Config, namespace, podName, containerName, localPath, podPath string) error {
@@ -1871,6 +6367,18 @@ func setOwnerRef(ctx context.Context, c client.Client, namespace, configMapName
 	return c.Update(ctx, cm)
 }
 
+// setSecretOwnerRef makes a Secret created for a build owned by it, so it is deleted with the build
+func setSecretOwnerRef(ctx context.Context, c client.Client, namespace, secretName string, owner *automotivev1alpha1.ImageBuild) error {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: secretName, Namespace: namespace}, secret); err != nil {
+		return err
+	}
+	secret.OwnerReferences = []metav1.OwnerReference{
+		*metav1.NewControllerRef(owner, automotivev1alpha1.GroupVersion.WithKind("ImageBuild")),
+	}
+	return c.Update(ctx, secret)
+}
+
 func writeJSON(c *gin.Context, status int, v any) {
 	c.Header("Cache-Control", "no-store")
 	c.IndentedJSON(status, v)
@@ -1889,7 +6397,8 @@ func resolveNamespace() string {
 	return "default"
 }
 
-func getRESTConfigFromRequest(_ *gin.Context) (*rest.Config, error) {
+// serviceRESTConfig is the configuration of the build API's own service account
+func serviceRESTConfig() (*rest.Config, error) {
 	var cfg *rest.Config
 	var err error
 	cfg, err = rest.InClusterConfig()
@@ -1905,12 +6414,72 @@ func getRESTConfigFromRequest(_ *gin.Context) (*rest.Config, error) {
 	return cfgCopy, nil
 }
 
-func getClientFromRequest(c *gin.Context) (client.Client, error) {
+// getRESTConfigFromRequest returns the configuration for the Kubernetes requests made on behalf of
+// a request: the build API's service account, or for impersonating requests the caller's token
+// with the same impersonation, so Kubernetes authorizes them as the impersonated user
+func getRESTConfigFromRequest(c *gin.Context) (*rest.Config, error) {
+	cfg, err := serviceRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	imp, ok := requestImpersonation(c)
+	if !ok {
+		return cfg, nil
+	}
+	userCfg := rest.AnonymousClientConfig(cfg)
+	userCfg.BearerToken = requestToken(c)
+	userCfg.Impersonate = imp
+	return userCfg, nil
+}
+
+// getClientFromRequest returns a client acting for the caller of a request; a variable so tests
+// can serve requests from an in-memory client
+var getClientFromRequest = func(c *gin.Context) (client.Client, error) {
 	cfg, err := getRESTConfigFromRequest(c)
 	if err != nil {
 		return nil, err
 	}
+	return newK8sClient(cfg)
+}
+
+// serviceClient returns a client acting as the build API's own service account, for what the
+// build API does regardless of the caller, e.g. recording downloads; a variable like
+// getClientFromRequest
+var serviceClient = func() (client.Client, error) {
+	cfg, err := serviceRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	return newK8sClient(cfg)
+}
+
+func newK8sClient(cfg *rest.Config) (client.Client, error) {
+	scheme, err := k8sScheme()
+	if err != nil {
+		return nil, err
+	}
+	k8sClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	return k8sClient, nil
+}
+
+// newK8sWatchClient is newK8sClient for requests that also watch objects
+func newK8sWatchClient(cfg *rest.Config) (client.WithWatch, error) {
+	scheme, err := k8sScheme()
+	if err != nil {
+		return nil, err
+	}
+	k8sClient, err := client.NewWithWatch(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	return k8sClient, nil
+}
 
+// k8sScheme holds the types the build API reads and writes
+func k8sScheme() (*runtime.Scheme, error) {
 	scheme := runtime.NewScheme()
 	if err := automotivev1alpha1.AddToScheme(scheme); err != nil {
 		return nil, fmt.Errorf("failed to add automotive scheme: %w", err)
@@ -1918,12 +6487,22 @@ func getClientFromRequest(c *gin.Context) (client.Client, error) {
 	if err := corev1.AddToScheme(scheme); err != nil {
 		return nil, fmt.Errorf("failed to add core scheme: %w", err)
 	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add storage scheme: %w", err)
+	}
+	if err := tektonv1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add tekton scheme: %w", err)
+	}
+	return scheme, nil
+}
 
-	k8sClient, err := client.New(cfg, client.Options{Scheme: scheme})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create k8s client: %w", err)
+// requestToken returns the bearer token of the caller
+func requestToken(c *gin.Context) string {
+	token, _ := strings.CutPrefix(c.Request.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = c.Request.Header.Get("X-Forwarded-Access-Token")
 	}
-	return k8sClient, nil
+	return strings.TrimSpace(token)
 }
 
 func (a *APIServer) isAuthenticated(c *gin.Context) bool {
@@ -1936,7 +6515,18 @@ func (a *APIServer) isAuthenticated(c *gin.Context) bool {
 	if strings.TrimSpace(token) == "" {
 		return false
 	}
-	cfg, err := getRESTConfigFromRequest(c)
+	if a.oidc != nil && a.oidc.issues(token) {
+		user, err := a.oidc.verify(c.Request.Context(), token)
+		if err != nil {
+			a.log.Info("OIDC token rejected", "reason", err.Error(), "reqID", c.GetString("reqID"))
+			return false
+		}
+		c.Set(subjectKey, user.Username)
+		c.Set(userInfoKey, user)
+		c.Set(oidcUserKey, true)
+		return true
+	}
+	cfg, err := serviceRESTConfig()
 	if err != nil {
 		return false
 	}
@@ -1949,10 +6539,58 @@ func (a *APIServer) isAuthenticated(c *gin.Context) bool {
 	if err != nil {
 		return false
 	}
+	if res.Status.Authenticated {
+		c.Set(subjectKey, res.Status.User.Username)
+		c.Set(userInfoKey, res.Status.User)
+	}
 	return res.Status.Authenticated
 }
 
+// canListAllNamespaces asks the Kubernetes API whether the caller may list ImageBuilds in every
+// namespace. The Build API lists them with its own service account, so the caller's permission
+// is checked with a SubjectAccessReview for the user behind the token.
+func canListAllNamespaces(c *gin.Context) (bool, error) {
+	// impersonating callers list with the permissions of the impersonated user
+	user, ok := requestUser(c)
+	if !ok {
+		return false, nil
+	}
+	cfg, err := serviceRESTConfig()
+	if err != nil {
+		return false, err
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return false, err
+	}
+	ctx := c.Request.Context()
+	extra := make(map[string]authzv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authzv1.ExtraValue(v)
+	}
+	sar := &authzv1.SubjectAccessReview{Spec: authzv1.SubjectAccessReviewSpec{
+		User:   user.Username,
+		Groups: user.Groups,
+		UID:    user.UID,
+		Extra:  extra,
+		ResourceAttributes: &authzv1.ResourceAttributes{
+			Group:    automotivev1alpha1.GroupVersion.Group,
+			Resource: "imagebuilds",
+			Verb:     "list",
+		},
+	}}
+	res, err := clientset.AuthorizationV1().SubjectAccessReviews().Create(ctx, sar, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return res.Status.Allowed, nil
+}
+
 func resolveRequester(c *gin.Context) string {
+	if user, ok := requestUser(c); ok && user.Username != "" {
+		return user.Username
+	}
+
 	authHeader := c.Request.Header.Get("Authorization")
 	token := ""
 	token, _ = strings.CutPrefix(authHeader, "Bearer ")
@@ -1961,7 +6599,7 @@ func resolveRequester(c *gin.Context) string {
 	}
 
 	if strings.TrimSpace(token) != "" {
-		cfg, err := getRESTConfigFromRequest(c)
+		cfg, err := serviceRESTConfig()
 		if err == nil {
 			clientset, err := kubernetes.NewForConfig(cfg)
 			if err == nil {
@@ -0,0 +1,73 @@
+package buildapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultDrainPeriod is how long in-flight requests may finish after a shutdown signal when
+	// BUILD_API_DRAIN_PERIOD is not set
+	defaultDrainPeriod = 30 * time.Second
+	// drainingRetryAfter is the Retry-After of builds rejected while draining, about the time a
+	// rolling update needs to take the replica out of the Service
+	drainingRetryAfter = 5 * time.Second
+	// defaultUnreadyPeriod is how long a draining replica keeps accepting connections with readiness
+	// failing and builds rejected, so requests the Service and routers still send it get the 503
+	// and Retry-After instead of a refused connection
+	defaultUnreadyPeriod = 5 * time.Second
+)
+
+// drainPeriodFromEnv returns the drain period of BUILD_API_DRAIN_PERIOD, a duration such as 45s or
+// a number of seconds
+func drainPeriodFromEnv() time.Duration {
+	v := strings.TrimSpace(os.Getenv("BUILD_API_DRAIN_PERIOD"))
+	if v == "" {
+		return defaultDrainPeriod
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return d
+	}
+	return defaultDrainPeriod
+}
+
+// drain stops the server gracefully: readiness fails and new builds are rejected at once, for the
+// unready period the server keeps serving, then the listener closes, and in-flight requests such as
+// uploads, downloads and log streams get the drain period to finish before their connections are
+// closed. Resumable upload sessions are kept in the upload pod of their build, so clients continue
+// them on another replica.
+func (a *APIServer) drain() error {
+	a.draining.Store(true)
+	a.log.Info("draining build-api server", "unreadyPeriod", a.unreadyPeriod.String(), "drainPeriod", a.drainPeriod.String())
+	time.Sleep(a.unreadyPeriod)
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.drainPeriod)
+	defer cancel()
+	err := a.server.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		a.log.Info("drain period over, closing remaining connections")
+		err = a.server.Close()
+	}
+	return err
+}
+
+// rejectWhileDraining answers requests creating builds during shutdown with 503 and a Retry-After,
+// so clients retry on a replica that keeps running. It reports whether the request was rejected.
+func (a *APIServer) rejectWhileDraining(c *gin.Context) bool {
+	if !a.draining.Load() {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(drainingRetryAfter/time.Second)))
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": "build API is shutting down, retry shortly"})
+	return true
+}
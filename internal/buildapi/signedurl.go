@@ -0,0 +1,326 @@
+package buildapi
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+const (
+	// urlSigningSecretName holds the key download URLs are signed with, shared by every replica of
+	// the build API; deleting it revokes every signed URL
+	urlSigningSecretName = "ado-build-api-url-signing"
+	urlSigningKeyField   = "key"
+	// urlSigningKeyTTL is how long a replica uses the key it read before reading it again
+	urlSigningKeyTTL = 5 * time.Minute
+
+	// defaultSignedURLMinutes and maxSignedURLMinutes bound how long a signed URL is valid
+	defaultSignedURLMinutes = 60
+	maxSignedURLMinutes     = 7 * 24 * 60
+
+	// signedDownloadPath is the route serving signed download URLs, without a bearer token
+	signedDownloadPath = "/v1/downloads/"
+)
+
+// signedDownload is what a signed download URL grants: one file of one build until Expires
+type signedDownload struct {
+	Namespace string `json:"ns"`
+	Build     string `json:"b"`
+	// UID keeps the URL from serving a build created again under the same name
+	UID string `json:"uid"`
+	// File is a file of the artifact manifest; empty for the artifact
+	File string `json:"f,omitempty"`
+	// Part is set when File is a part of the artifact
+	Part bool `json:"p,omitempty"`
+	// IssuedBy is the user who created the URL; downloads are recorded for them
+	IssuedBy string `json:"by"`
+	// Expires is a Unix time
+	Expires int64 `json:"exp"`
+}
+
+// signDownload returns the token of a signed download URL: the grant and its HMAC-SHA256, each
+// base64url encoded
+func signDownload(key []byte, d signedDownload) (string, error) {
+	payload, err := json.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// errSignedURLExpired is returned by verifyDownload for URLs past their expiry
+var errSignedURLExpired = errors.New("signed URL expired")
+
+// verifyDownload checks the signature and expiry of the token of a signed download URL and
+// returns what it grants
+func verifyDownload(key []byte, token string, now time.Time) (*signedDownload, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errors.New("malformed signed URL")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("malformed signed URL")
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, errors.New("malformed signed URL")
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return nil, errors.New("invalid signature")
+	}
+	d := &signedDownload{}
+	if err := json.Unmarshal(payload, d); err != nil {
+		return nil, errors.New("malformed signed URL")
+	}
+	if !now.Before(time.Unix(d.Expires, 0)) {
+		return nil, errSignedURLExpired
+	}
+	return d, nil
+}
+
+// signingKeyCache holds the URL signing key a replica read last
+type signingKeyCache struct {
+	mu     sync.Mutex
+	key    []byte
+	readAt time.Time
+}
+
+// urlSigningKey returns the key of urlSigningSecretName, creating the Secret with a random key
+// when it does not exist
+func (a *APIServer) urlSigningKey(ctx context.Context) ([]byte, error) {
+	cache := &a.signingKey
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.key != nil && time.Since(cache.readAt) < urlSigningKeyTTL {
+		return cache.key, nil
+	}
+	k8sClient, err := serviceClient()
+	if err != nil {
+		return nil, err
+	}
+	key := types.NamespacedName{Name: urlSigningSecretName, Namespace: resolveNamespace()}
+	secret := &corev1.Secret{}
+	err = k8sClient.Get(ctx, key, secret)
+	if k8serrors.IsNotFound(err) {
+		generated := make([]byte, 32)
+		if _, err := rand.Read(generated); err != nil {
+			return nil, err
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/managed-by": "build-api",
+					"app.kubernetes.io/part-of":    "automotive-dev",
+					"app.kubernetes.io/component":  "build-api",
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{urlSigningKeyField: generated},
+		}
+		err = k8sClient.Create(ctx, secret)
+		if k8serrors.IsAlreadyExists(err) {
+			// another replica created it first
+			err = k8sClient.Get(ctx, key, secret)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading URL signing key: %w", err)
+	}
+	if len(secret.Data[urlSigningKeyField]) < 32 {
+		return nil, fmt.Errorf("secret %s holds no %s of at least 32 bytes", urlSigningSecretName, urlSigningKeyField)
+	}
+	cache.key, cache.readAt = secret.Data[urlSigningKeyField], time.Now()
+	return cache.key, nil
+}
+
+// requestBaseURL is the scheme and host clients reach the build API at, as seen through the proxy
+// or Route in front of it
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto, _, _ := strings.Cut(c.GetHeader("X-Forwarded-Proto"), ","); strings.TrimSpace(proto) != "" {
+		scheme = strings.TrimSpace(proto)
+	}
+	host := c.Request.Host
+	if fwd, _, _ := strings.Cut(c.GetHeader("X-Forwarded-Host"), ","); strings.TrimSpace(fwd) != "" {
+		host = strings.TrimSpace(fwd)
+	}
+	return scheme + "://" + host
+}
+
+func (a *APIServer) handleCreateSignedURL(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("signed artifact URL requested", "build", name, "reqID", c.GetString("reqID"))
+	a.createSignedURL(c, name)
+}
+
+// createSignedURL answers with a URL that downloads the artifact, or a file of the artifact
+// manifest, of a completed build without a bearer token until it expires
+func (a *APIServer) createSignedURL(c *gin.Context, name string) {
+	var req SignedURLRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid JSON: %v", err)})
+			return
+		}
+	}
+	minutes := req.ExpiresInMinutes
+	if minutes == 0 {
+		minutes = defaultSignedURLMinutes
+	}
+	if minutes < 0 || minutes > maxSignedURLMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("expiresInMinutes must be between 1 and %d", maxSignedURLMinutes)})
+		return
+	}
+	file := strings.TrimSpace(req.File)
+	if strings.ContainsAny(file, "/\\") || file == "." || file == ".." {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid file %q", file)})
+		return
+	}
+
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	ctx := c.Request.Context()
+	namespace := requestNamespace(c)
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+	if build.Status.Phase != "Completed" {
+		c.JSON(http.StatusConflict, gin.H{"error": "artifact not available until build completes"})
+		return
+	}
+	if artifactsDeleted(c, build) {
+		return
+	}
+
+	key, err := a.urlSigningKey(ctx)
+	if err != nil {
+		a.log.Error(err, "failed to read URL signing key", "reqID", c.GetString("reqID"))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to sign URL"})
+		return
+	}
+	issuedBy := resolveRequester(c)
+	expires := time.Now().Add(time.Duration(minutes) * time.Minute).Truncate(time.Second)
+	token, err := signDownload(key, signedDownload{
+		Namespace: namespace,
+		Build:     name,
+		UID:       string(build.UID),
+		File:      file,
+		Part:      file != "" && req.Part,
+		IssuedBy:  issuedBy,
+		Expires:   expires.Unix(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to sign URL: %v", err)})
+		return
+	}
+	path := signedDownloadPath + token
+	a.log.Info("signed artifact URL issued", "build", name, "namespace", namespace, "file", file,
+		"user", issuedBy, "expiresAt", expires.UTC().Format(time.RFC3339), "reqID", c.GetString("reqID"))
+	writeJSON(c, http.StatusCreated, SignedURLResponse{
+		URL:       requestBaseURL(c) + path,
+		Path:      path,
+		File:      file,
+		ExpiresAt: expires.UTC().Format(time.RFC3339),
+	})
+}
+
+// signedDownloadAuth admits requests with a valid signed download URL in place of authMiddleware.
+// The download then runs with the service account of the build API, in the namespace of the URL,
+// and is recorded for the user who created the URL.
+func (a *APIServer) signedDownloadAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, err := a.urlSigningKey(c.Request.Context())
+		if err != nil {
+			a.log.Error(err, "failed to read URL signing key", "reqID", c.GetString("reqID"))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check signed URL"})
+			c.Abort()
+			return
+		}
+		d, err := verifyDownload(key, c.Param("token"), time.Now())
+		if errors.Is(err, errSignedURLExpired) {
+			c.JSON(http.StatusGone, gin.H{"error": "signed URL expired; ask for a new one"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		k8sClient, err := serviceClient()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+			c.Abort()
+			return
+		}
+		build := &automotivev1alpha1.ImageBuild{}
+		err = k8sClient.Get(c.Request.Context(), types.NamespacedName{Name: d.Build, Namespace: d.Namespace}, build)
+		if k8serrors.IsNotFound(err) || (err == nil && string(build.UID) != d.UID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "the build of the signed URL no longer exists"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+			c.Abort()
+			return
+		}
+		c.Set(namespaceKey, d.Namespace)
+		c.Set(subjectKey, d.IssuedBy+" (signed URL)")
+		c.Set(signedDownloadKey, d)
+		c.Next()
+	}
+}
+
+// signedDownloadKey is the context key of the signedDownload of a request to a signed URL
+const signedDownloadKey = "signedDownload"
+
+func (a *APIServer) handleSignedDownload(c *gin.Context) {
+	d := c.MustGet(signedDownloadKey).(*signedDownload)
+	a.log.Info("signed artifact download requested", "build", d.Build, "namespace", d.Namespace, "file", d.File,
+		"issuedBy", d.IssuedBy, "reqID", c.GetString("reqID"))
+	switch {
+	case d.File == "":
+		a.streamDefaultArtifact(c, d.Build)
+	case d.Part:
+		a.streamArtifactPart(c, d.Build, d.File)
+	default:
+		a.streamArtifactByFilename(c, d.Build, d.File)
+	}
+}
@@ -0,0 +1,138 @@
+package buildapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+// signingFromRequest validates the requested signing and converts it to its ImageBuild form
+func signingFromRequest(req *ArtifactSigning) (*automotivev1alpha1.ArtifactSigning, error) {
+	if req == nil {
+		return nil, nil
+	}
+	spec := &automotivev1alpha1.ArtifactSigning{
+		KeySecretRef:       strings.TrimSpace(req.KeySecretRef),
+		Keyless:            req.Keyless,
+		ServiceAccountName: strings.TrimSpace(req.ServiceAccountName),
+		FulcioURL:          strings.TrimSpace(req.FulcioURL),
+		RekorURL:           strings.TrimSpace(req.RekorURL),
+	}
+	switch {
+	case spec.KeySecretRef == "" && !spec.Keyless:
+		return nil, fmt.Errorf("signing needs keySecretRef or keyless")
+	case spec.KeySecretRef != "" && spec.Keyless:
+		return nil, fmt.Errorf("signing takes either keySecretRef or keyless, not both")
+	case spec.KeySecretRef != "" && len(validation.IsDNS1123Subdomain(spec.KeySecretRef)) > 0:
+		return nil, fmt.Errorf("signing keySecretRef %q is not a valid Secret name", spec.KeySecretRef)
+	case spec.ServiceAccountName != "" && !spec.Keyless:
+		return nil, fmt.Errorf("signing serviceAccountName only applies to keyless signatures")
+	case spec.ServiceAccountName != "" && len(validation.IsDNS1123Subdomain(spec.ServiceAccountName)) > 0:
+		return nil, fmt.Errorf("signing serviceAccountName %q is not a valid service account name", spec.ServiceAccountName)
+	case spec.FulcioURL != "" && !spec.Keyless:
+		return nil, fmt.Errorf("signing fulcioURL only applies to keyless signatures")
+	case spec.RekorURL != "" && !spec.Keyless:
+		return nil, fmt.Errorf("signing rekorURL only applies to keyless signatures; key-based signatures are recorded in the Rekor of the OperatorConfig")
+	}
+	for field, v := range map[string]string{"fulcioURL": spec.FulcioURL, "rekorURL": spec.RekorURL} {
+		if v == "" {
+			continue
+		}
+		if u, err := url.Parse(v); err != nil || u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("signing %s must be an https URL", field)
+		}
+	}
+	return spec, nil
+}
+
+// signingToRequest converts the ImageBuild signing back to its API form
+func signingToRequest(spec *automotivev1alpha1.ArtifactSigning) *ArtifactSigning {
+	if spec == nil {
+		return nil
+	}
+	return &ArtifactSigning{
+		KeySecretRef:       spec.KeySecretRef,
+		Keyless:            spec.Keyless,
+		ServiceAccountName: spec.ServiceAccountName,
+		FulcioURL:          spec.FulcioURL,
+		RekorURL:           spec.RekorURL,
+	}
+}
+
+// artifactSignature reports the signature of the artifact of build, nil before signing started
+func artifactSignature(build *automotivev1alpha1.ImageBuild) *ArtifactSignature {
+	st := build.Status.Signature
+	if st == nil {
+		return nil
+	}
+	out := &ArtifactSignature{
+		Phase:    st.Phase,
+		Method:   st.Method,
+		Identity: st.Identity,
+		Issuer:   st.Issuer,
+		Message:  st.Message,
+	}
+	if st.CompletionTime != nil {
+		out.CompletionTime = st.CompletionTime.UTC().Format(time.RFC3339)
+	}
+	return out
+}
+
+// signaturePending reports whether the cosign bundle of a build that requested signing may still
+// appear in its workspace
+func signaturePending(build *automotivev1alpha1.ImageBuild) bool {
+	st := build.Status.Signature
+	return build.Spec.Signing != nil && (st == nil || st.Phase == "Signing")
+}
+
+// isSignatureFile reports whether base is a cosign bundle of the artifact of build
+func isSignatureFile(build *automotivev1alpha1.ImageBuild, base string) bool {
+	if st := build.Status.Signature; st != nil && st.BundleFileName != "" && base == st.BundleFileName {
+		return true
+	}
+	artifact := defaultArtifactFileName(build)
+	return base == artifact+signatureBundleSuffix || base == artifact+attestationBundleSuffix
+}
+
+// streamArtifactSignature streams the cosign bundle the operator signed the artifact of a build
+// with, as requested by its signing. 404 when the build does not sign its artifact or signing
+// failed, 409 while the artifact is being signed.
+func (a *APIServer) streamArtifactSignature(c *gin.Context, name string) {
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(c.Request.Context(), types.NamespacedName{Name: name, Namespace: requestNamespace(c)}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+
+	st := build.Status.Signature
+	switch {
+	case build.Spec.Signing == nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("build %s does not sign its artifact", name)})
+	case build.Status.Phase != "Completed":
+		c.JSON(http.StatusConflict, gin.H{"error": "signature not available until build completes"})
+	case st == nil || st.Phase == "Signing":
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("the artifact of build %s is being signed, retry shortly", name)})
+	case st.Phase != "Signed" || st.BundleFileName == "":
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("the artifact of build %s was not signed: %s", name, st.Message)})
+	default:
+		a.streamArtifactByFilename(c, name, st.BundleFileName)
+	}
+}
@@ -3,6 +3,10 @@ package buildapi
 import (
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/hardening"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/buildrecord"
 )
 
 type Distro string
@@ -77,9 +81,11 @@ func ParseMode(s string) (Mode, error) {
 
 // BuildRequest is the payload to create a build via the REST API
 type BuildRequest struct {
-	Name                   string               `json:"name"`
+	Name string `json:"name"`
+	// Manifest is the manifest content; Source is set instead for manifests the build fetches
 	Manifest               string               `json:"manifest"`
 	ManifestFileName       string               `json:"manifestFileName"`
+	Source                 *BuildSource         `json:"source,omitempty"`
 	Distro                 Distro               `json:"distro"`
 	Target                 Target               `json:"target"`
 	Architecture           Architecture         `json:"architecture"`
@@ -87,12 +93,256 @@ type BuildRequest struct {
 	Mode                   Mode                 `json:"mode"`
 	AutomotiveImageBuilder string               `json:"automotiveImageBuilder"`
 	StorageClass           string               `json:"storageClass"`
+	WorkspaceAccessMode    string               `json:"workspaceAccessMode,omitempty"`
 	CustomDefs             []string             `json:"customDefs"`
 	AIBExtraArgs           []string             `json:"aibExtraArgs"`
 	AIBOverrideArgs        []string             `json:"aibOverrideArgs"`
 	ServeArtifact          bool                 `json:"serveArtifact"`
 	Compression            string               `json:"compression,omitempty"`
 	RegistryCredentials    *RegistryCredentials `json:"registryCredentials,omitempty"`
+	FirstBoot              *FirstBoot           `json:"firstBoot,omitempty"`
+	// TestUser and SSHKeys inject a login user for developer/test images; such builds are labeled non-production
+	TestUser string   `json:"testUser,omitempty"`
+	SSHKeys  []string `json:"sshKeys,omitempty"`
+	// HardeningProfiles are applied at build time; see GET /v1/catalog/hardening
+	HardeningProfiles []string `json:"hardeningProfiles,omitempty"`
+	// Compliance requests an OpenSCAP scan of the built image
+	Compliance *ComplianceScan `json:"compliance,omitempty"`
+	// SizeBudget limits the built root filesystem size and publishes a size breakdown
+	SizeBudget *SizeBudget `json:"sizeBudget,omitempty"`
+	// BootTest boots the built image in QEMU and records boot time KPIs
+	BootTest *BootTest `json:"bootTest,omitempty"`
+	// Debug keeps the build pod running after the build step fails so it can be inspected with the exec endpoint
+	Debug *BuildDebug `json:"debug,omitempty"`
+	// WorkspaceProtection requires an encrypted workspace and scrubs it once the build no longer needs it
+	WorkspaceProtection *WorkspaceProtection `json:"workspaceProtection,omitempty"`
+	// Signing signs the artifact with cosign once the build completed
+	Signing *ArtifactSigning `json:"signing,omitempty"`
+	// OutputName is a text/template for the file name the artifact is downloaded as, e.g.
+	// {{.Name}}-{{.Arch}}-{{.Distro}}.{{.Ext}}; Name, Distro, Target, Arch, Mode, Export and Ext are available
+	OutputName string `json:"outputName,omitempty"`
+	// Labels and Annotations are added to the ImageBuild, e.g. branch, commit SHA or CI pipeline ID;
+	// builds can be listed by label with GET /v1/builds?labelSelector=
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Webhooks receive a JSON payload on every phase change of the build, so CI systems do not
+	// have to poll; they are not copied when the build is cloned
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+	// CommitStatus posts the outcome of the build as a status of a commit on GitHub or GitLab, with
+	// the size and boot time compared with the golden build of the target branch; it is not copied
+	// when the build is cloned
+	CommitStatus *CommitStatus `json:"commitStatus,omitempty"`
+	// Namespace to create the build in, like the namespace query parameter; the build API's own
+	// namespace when empty
+	Namespace string `json:"namespace,omitempty"`
+	// Priority is low, normal or high; when the operator's build concurrency limit is reached,
+	// higher-priority builds start first
+	Priority string `json:"priority,omitempty"`
+	// Retention deletes the artifacts of the completed build after a while or once newer builds
+	// replaced them; it can be changed later with PATCH /v1/builds/{name}
+	Retention *Retention `json:"retention,omitempty"`
+	// NetworkExceptions names exceptions of the operator's build network policy the build pod may
+	// reach besides its egress allowlist, e.g. an internal mirror only some namespaces may use
+	NetworkExceptions []string `json:"networkExceptions,omitempty"`
+	// RemoteFilesSecretRef names a Secret in the build namespace with the credentials for add_files
+	// entries whose source_path is an http(s) or s3 URL. The server downloads those files itself:
+	// token holds a bearer token for http(s), AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY (and
+	// optionally AWS_DEFAULT_REGION and AWS_ENDPOINT_URL) the S3 credentials.
+	RemoteFilesSecretRef string `json:"remoteFilesSecretRef,omitempty"`
+	// RuntimeClassName runs the build pod with a sandboxed runtime such as gVisor or Kata Containers,
+	// e.g. for untrusted or partner builds; the operator may restrict the classes builds can choose
+	RuntimeClassName string `json:"runtimeClassName,omitempty"`
+	// Deduplicate returns the newest running or completed build of an identical request instead of
+	// creating a build; only requests without local or remote files whose git source, if any, is
+	// pinned to a full commit SHA are deduplicated
+	Deduplicate bool `json:"deduplicate,omitempty"`
+}
+
+// BuildSource is where the manifest of a build comes from instead of BuildRequest.Manifest
+type BuildSource struct {
+	Git *GitSource `json:"git,omitempty"`
+}
+
+// GitSource is a manifest in a git repository, which the build clones into its workspace
+type GitSource struct {
+	// URL is the http(s) URL of the repository
+	URL string `json:"url"`
+	// Ref is the branch, tag or commit SHA to build; the default branch when empty
+	Ref string `json:"ref,omitempty"`
+	// Path is the path of the manifest in the repository; relative add_files source paths are
+	// resolved against its directory
+	Path string `json:"path"`
+}
+
+// Retention limits how long the artifacts of a completed build are kept
+type Retention struct {
+	// KeepFor is how long after completion the artifacts are kept, e.g. 72h or 30d
+	KeepFor string `json:"keepFor,omitempty"`
+	// KeepLast keeps the artifacts of the newest KeepLast completed builds whose name starts with
+	// NamePrefix and whose retention names the same prefix
+	KeepLast   int32  `json:"keepLast,omitempty"`
+	NamePrefix string `json:"namePrefix,omitempty"`
+}
+
+// BuildPatchRequest changes a build after it was created
+type BuildPatchRequest struct {
+	// Retention replaces the retention of the build; an empty retention keeps the artifacts
+	Retention *Retention `json:"retention,omitempty"`
+}
+
+// Webhook is an HTTP endpoint notified of the phase changes of a build
+type Webhook struct {
+	URL string `json:"url"`
+	// Secret signs the payloads with HMAC-SHA256; the signature is sent in the X-ADO-Signature-256 header
+	Secret string `json:"secret,omitempty"`
+}
+
+// CommitStatus is the CI metadata of a build made for a commit of a GitHub or GitLab repository.
+// The operator posts the status with the credentials its OperatorConfig configures.
+type CommitStatus struct {
+	// Provider is github or gitlab
+	Provider string `json:"provider"`
+	// Repository is owner/name on GitHub or the path of the project on GitLab
+	Repository string `json:"repository"`
+	// SHA is the full or abbreviated commit hash
+	SHA string `json:"sha"`
+	// Branch is set for builds of a branch; the latest completed build of a branch is its golden build
+	Branch string `json:"branch,omitempty"`
+	// TargetBranch is set for builds of pull or merge requests, which are compared with the golden
+	// build of the branch they target
+	TargetBranch string `json:"targetBranch,omitempty"`
+	// Context names the status; defaults to automotive/<target>-<architecture>
+	Context string `json:"context,omitempty"`
+}
+
+// BuildDebug configures how long a failed build pod is kept for debugging
+type BuildDebug struct {
+	// HoldMinutes defaults to 60, at most 720
+	HoldMinutes int32 `json:"holdMinutes,omitempty"`
+}
+
+// WorkspaceProtection keeps the image content of sensitive programs off shared storage in recoverable form
+type WorkspaceProtection struct {
+	// RequireEncryption rejects the build unless the storage class of the workspace encrypts volumes at rest
+	RequireEncryption bool `json:"requireEncryption,omitempty"`
+	// Scrub overwrites every file of the workspace and deletes it once the build no longer needs it
+	Scrub bool `json:"scrub,omitempty"`
+}
+
+// ArtifactSigning selects how the artifact of a build is signed with cosign: with the key of a
+// Secret, or keyless with a Fulcio certificate issued for a service account of the cluster
+type ArtifactSigning struct {
+	// KeySecretRef names a Secret in the namespace of the build holding cosign.key and, if the key
+	// has one, cosign.password
+	KeySecretRef string `json:"keySecretRef,omitempty"`
+	Keyless      bool   `json:"keyless,omitempty"`
+	// ServiceAccountName is the service account keyless signatures are issued for
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// FulcioURL and RekorURL of keyless signatures default to the public sigstore instances, or the
+	// Rekor of the operator. Key-based signatures are only recorded in the Rekor of the operator.
+	FulcioURL string `json:"fulcioURL,omitempty"`
+	RekorURL  string `json:"rekorURL,omitempty"`
+}
+
+// ArtifactSignature records the cosign signature of the artifact of a build
+type ArtifactSignature struct {
+	// Phase is Signing, Signed or Failed
+	Phase string `json:"phase"`
+	// Method is key or keyless
+	Method string `json:"method"`
+	// Identity is the fingerprint of the signing key, or the subject of the certificate of a
+	// keyless signature; Issuer is the OIDC issuer of that subject
+	Identity string `json:"identity,omitempty"`
+	Issuer   string `json:"issuer,omitempty"`
+	// CompletionTime is when the artifact was signed, or signing failed
+	CompletionTime string `json:"completionTime,omitempty"`
+	Message        string `json:"message,omitempty"`
+}
+
+// BuildEnvironment is where and with what a build ran, for comparing builds that behave
+// differently on two clusters or nodes
+type BuildEnvironment struct {
+	NodeName         string `json:"nodeName,omitempty"`
+	KernelVersion    string `json:"kernelVersion,omitempty"`
+	OSImage          string `json:"osImage,omitempty"`
+	ContainerRuntime string `json:"containerRuntime,omitempty"`
+	// BuilderImage is the automotive-image-builder image and BuilderImageDigest the image the node ran for it
+	BuilderImage       string `json:"builderImage,omitempty"`
+	BuilderImageDigest string `json:"builderImageDigest,omitempty"`
+	OSBuildVersion     string `json:"osbuildVersion,omitempty"`
+	AIBVersion         string `json:"aibVersion,omitempty"`
+	// SELinux is Enforcing, Permissive or Disabled
+	SELinux string `json:"selinux,omitempty"`
+	// Sysctls are the kernel settings osbuild depends on
+	Sysctls map[string]string `json:"sysctls,omitempty"`
+}
+
+// WorkspaceScrub attests to the scrub of the workspace of a build
+type WorkspaceScrub struct {
+	// Phase is Scrubbing, Scrubbed or Failed
+	Phase         string `json:"phase"`
+	Method        string `json:"method,omitempty"`
+	FilesScrubbed int64  `json:"filesScrubbed,omitempty"`
+	BytesScrubbed int64  `json:"bytesScrubbed,omitempty"`
+	// CompletionTime is when the PVC was deleted, or the scrub failed
+	CompletionTime string `json:"completionTime,omitempty"`
+	Message        string `json:"message,omitempty"`
+}
+
+// ArtifactArchive reports the move of the artifacts of a build to the archive storage class
+type ArtifactArchive struct {
+	// Phase is Archiving, Archived or Failed
+	Phase        string `json:"phase"`
+	StorageClass string `json:"storageClass,omitempty"`
+	Bytes        int64  `json:"bytes,omitempty"`
+	// CompletionTime is when the artifacts were archived, or the copy failed
+	CompletionTime string `json:"completionTime,omitempty"`
+	// RehydrationTime is when a download last had the artifacts served from the archive
+	RehydrationTime string `json:"rehydrationTime,omitempty"`
+	Message         string `json:"message,omitempty"`
+}
+
+// BootTest configures the QEMU boot test of a built image
+type BootTest struct {
+	// TimeoutSeconds bounds the boot (default 300)
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+	// ReadyMarker is an extended regular expression matched on the serial console; defaults to the
+	// multi-user target or a login prompt
+	ReadyMarker string `json:"readyMarker,omitempty"`
+	// MaxKernelToReady is the boot time threshold, e.g. 15s
+	MaxKernelToReady string `json:"maxKernelToReady,omitempty"`
+	// Enforce fails the build when the image does not boot or exceeds the threshold
+	Enforce bool `json:"enforce,omitempty"`
+}
+
+// SizeBudget is the largest root filesystem a build may produce
+type SizeBudget struct {
+	// MaxSize is a Kubernetes quantity such as 1536Mi or 2G
+	MaxSize string `json:"maxSize"`
+	// Action is Fail (default) or Warn when the budget is exceeded
+	Action string `json:"action,omitempty"`
+}
+
+// ComplianceScan selects the OpenSCAP profile a build is evaluated against
+type ComplianceScan struct {
+	Profile string `json:"profile"`
+	// DataStream is a SCAP data stream path in the build image; defaults to the SSG content for the image OS
+	DataStream string `json:"dataStream,omitempty"`
+	// Enforce fails the build when the scan does not pass
+	Enforce bool `json:"enforce,omitempty"`
+}
+
+// FirstBoot is a first-boot provisioning payload; set exactly one of Inline, ConfigMap+Key or FileName
+type FirstBoot struct {
+	// Format is ignition, cloud-init or combustion
+	Format string `json:"format"`
+	// Mode is attach (default, secondary artifact) or embed (written into the image)
+	Mode      string `json:"mode,omitempty"`
+	Inline    string `json:"inline,omitempty"`
+	ConfigMap string `json:"configMap,omitempty"`
+	Key       string `json:"key,omitempty"`
+	// FileName refers to a file uploaded to the build workspace
+	FileName string `json:"fileName,omitempty"`
 }
 
 type RegistryCredentials struct {
@@ -107,25 +357,583 @@ type RegistryCredentials struct {
 
 // BuildResponse is returned by POST and GET build operations
 type BuildResponse struct {
-	Name             string `json:"name"`
-	Phase            string `json:"phase"`
-	Message          string `json:"message"`
-	RequestedBy      string `json:"requestedBy,omitempty"`
+	Name        string `json:"name"`
+	Phase       string `json:"phase"`
+	Message     string `json:"message"`
+	RequestedBy string `json:"requestedBy,omitempty"`
+	// RequestID is the X-Request-ID of the request that created the build; build logs are prefixed with it
+	RequestID        string `json:"requestID,omitempty"`
 	ArtifactURL      string `json:"artifactURL,omitempty"`
 	ArtifactFileName string `json:"artifactFileName,omitempty"`
 	StartTime        string `json:"startTime,omitempty"`
 	CompletionTime   string `json:"completionTime,omitempty"`
+	// StageTimings maps build pod stages (build, package) to their durations
+	StageTimings map[string]string `json:"stageTimings,omitempty"`
+	// FirstBootFileName is the attached first-boot payload, downloadable via the artifact endpoint
+	FirstBootFileName string `json:"firstBootFileName,omitempty"`
+	// HardeningReportFileName is the compliance report of the applied hardening profiles, downloadable via the artifact endpoint
+	HardeningReportFileName string `json:"hardeningReportFileName,omitempty"`
+	// PackagesFileName is the inventory of the installed RPMs; set when GET /v1/builds/{name}/sbom can serve an SBOM
+	PackagesFileName string `json:"packagesFileName,omitempty"`
+	// ComplianceResult is pending, pass, fail or error when a compliance scan was requested
+	ComplianceResult string `json:"complianceResult,omitempty"`
+	// RootFSBytes and SizeBudgetBytes are set once a build with a size budget was measured
+	RootFSBytes     int64 `json:"rootfsBytes,omitempty"`
+	SizeBudgetBytes int64 `json:"sizeBudgetBytes,omitempty"`
+	// SizeReportFileName is the per-package and per-directory size breakdown, downloadable via the artifact endpoint
+	SizeReportFileName string `json:"sizeReportFileName,omitempty"`
+	// BootResult is pending, booted, timeout or error when a boot test was requested
+	BootResult string `json:"bootResult,omitempty"`
+	// BootTimings maps firmware, kernelToReady and total to their durations
+	BootTimings     map[string]string `json:"bootTimings,omitempty"`
+	BootAccelerator string            `json:"bootAccelerator,omitempty"`
+	// BootConsoleLogFileName is the serial console output of the boot test, downloadable via the artifact endpoint
+	BootConsoleLogFileName string `json:"bootConsoleLogFileName,omitempty"`
+	// DebugPod and DebugHeldUntil are set while a failed build pod is kept for debugging
+	DebugPod       string `json:"debugPod,omitempty"`
+	DebugHeldUntil string `json:"debugHeldUntil,omitempty"`
+	// Environment is the node and tools the build ran with, set once its TaskRun finished
+	Environment *BuildEnvironment `json:"environment,omitempty"`
+	// LogArchive is set once the logs of the finished build pod were archived for GET /v1/builds/{name}/logs/archive
+	LogArchive *LogArchive `json:"logArchive,omitempty"`
+	// Publications report the publication of the artifact to the targets of the ImageBuild's spec.publishers
+	Publications []Publication `json:"publications,omitempty"`
+	// WorkspaceAccessMode is ReadWriteOnce or ReadWriteMany once the controller chose the access mode of the build PVC
+	WorkspaceAccessMode string `json:"workspaceAccessMode,omitempty"`
+	// WorkspaceScrub is set once the scrub requested by workspaceProtection started
+	WorkspaceScrub *WorkspaceScrub `json:"workspaceScrub,omitempty"`
+	// Signature is set once signing the artifact as requested by signing started
+	Signature *ArtifactSignature `json:"signature,omitempty"`
+	// ArtifactArchive is set once the artifacts are moved to the archive storage class
+	ArtifactArchive *ArtifactArchive `json:"artifactArchive,omitempty"`
+	// Downloads is set once an artifact of the build was downloaded
+	Downloads *ArtifactDownloads `json:"downloads,omitempty"`
+	// Priority is the priority the build waits for a build slot with
+	Priority string `json:"priority,omitempty"`
+	// NetworkExceptions are the exceptions of the build network policy the build asked for
+	NetworkExceptions []string `json:"networkExceptions,omitempty"`
+	// RuntimeClassName is the runtime class the build asked to run with
+	RuntimeClassName string `json:"runtimeClassName,omitempty"`
+	// Source is the git repository the manifest of the build comes from; GitCommit is the commit
+	// its ref resolved to, set once the build completed
+	Source    *BuildSource `json:"source,omitempty"`
+	GitCommit string       `json:"gitCommit,omitempty"`
+	// QueuePosition is the position of a Queued build among the builds waiting for a build slot,
+	// starting at 1
+	QueuePosition int32 `json:"queuePosition,omitempty"`
+	// Retention is the retention of the artifacts; ArtifactsExpireAt is when keepFor deletes them
+	// and ArtifactsDeletedAt when they were deleted
+	Retention          *Retention `json:"retention,omitempty"`
+	ArtifactsExpireAt  string     `json:"artifactsExpireAt,omitempty"`
+	ArtifactsDeletedAt string     `json:"artifactsDeletedAt,omitempty"`
+	// CommitStatus is the CI metadata of the build and PostedCommitStatus the last status the
+	// operator posted for it
+	CommitStatus       *CommitStatus       `json:"commitStatus,omitempty"`
+	PostedCommitStatus *PostedCommitStatus `json:"postedCommitStatus,omitempty"`
+	// Replayed is set when a create request returned an existing build, for its Idempotency-Key
+	// or deduplicate, instead of creating one
+	Replayed bool `json:"replayed,omitempty"`
+}
+
+// PostedCommitStatus is the last commit status posted for a build
+type PostedCommitStatus struct {
+	// State is pending, running, success, failure, error or cancelled
+	State       string `json:"state"`
+	Description string `json:"description,omitempty"`
+	// GoldenBuild is the golden build of the target branch the build was compared with
+	GoldenBuild string `json:"goldenBuild,omitempty"`
+	// Error is why the status could not be posted
+	Error     string `json:"error,omitempty"`
+	UpdatedAt string `json:"updatedAt,omitempty"`
+}
+
+// BuildComparison compares what a build measured about its image with the golden build of a branch
+type BuildComparison struct {
+	Build string `json:"build"`
+	// Branch is the target branch of the build unless the branch parameter names another
+	Branch string `json:"branch"`
+	// GoldenBuild is empty when the branch has no golden build
+	GoldenBuild  string             `json:"goldenBuild,omitempty"`
+	Measurements BuildMeasurements  `json:"measurements"`
+	Golden       *BuildMeasurements `json:"golden,omitempty"`
+	// The deltas are the build minus the golden build, unset where either did not measure;
+	// boot times measured with different accelerators are not compared
+	ArtifactBytesDelta *int64   `json:"artifactBytesDelta,omitempty"`
+	RootFSBytesDelta   *int64   `json:"rootfsBytesDelta,omitempty"`
+	BootSecondsDelta   *float64 `json:"bootSecondsDelta,omitempty"`
+	// Description is the summary a commit status of the build shows
+	Description string `json:"description"`
+}
+
+// BuildMeasurements are what a build measured about its image; zero values were not measured
+type BuildMeasurements struct {
+	ArtifactBytes   int64   `json:"artifactBytes,omitempty"`
+	RootFSBytes     int64   `json:"rootfsBytes,omitempty"`
+	BootSeconds     float64 `json:"bootSeconds,omitempty"`
+	BootAccelerator string  `json:"bootAccelerator,omitempty"`
+}
+
+// LogArchive describes the gzip archive of the step logs of a finished build
+type LogArchive struct {
+	// Size is the size of the archive and LogSize the size of the logs it holds, in bytes
+	Size    int64  `json:"size"`
+	LogSize int64  `json:"logSize"`
+	SHA256  string `json:"sha256"`
+	// Truncated is set when the end of the logs did not fit in the archive
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// SignedURLRequest asks for a URL that downloads a file of a build without a bearer token
+type SignedURLRequest struct {
+	// ExpiresInMinutes is how long the URL is valid, 60 by default and at most 10080 (7 days)
+	ExpiresInMinutes int `json:"expiresInMinutes,omitempty"`
+	// File is a file of the artifact manifest, e.g. a report; the artifact when empty
+	File string `json:"file,omitempty"`
+	// Part is set when File is a part of the artifact, of kind part in the artifact manifest
+	Part bool `json:"part,omitempty"`
+}
+
+// SignedURLResponse is a signed download URL and when it expires
+type SignedURLResponse struct {
+	URL string `json:"url"`
+	// Path is URL without the scheme and host, for clients that reach the build API at another address
+	Path      string `json:"path"`
+	File      string `json:"file,omitempty"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+// ArtifactDownloads reports how often and by whom the artifacts of a build were downloaded
+type ArtifactDownloads struct {
+	Count          int64  `json:"count"`
+	LastDownloadAt string `json:"lastDownloadAt,omitempty"`
+	// Users are the downloaders, the most recent first
+	Users []ArtifactDownloader `json:"users,omitempty"`
+	// Recent are the latest downloads, the most recent first
+	Recent []ArtifactDownload `json:"recent,omitempty"`
+}
+
+// ArtifactDownloader is how often one user downloaded the artifacts of a build
+type ArtifactDownloader struct {
+	User           string `json:"user"`
+	Count          int64  `json:"count"`
+	LastDownloadAt string `json:"lastDownloadAt"`
+}
+
+// ArtifactDownload is one download of a file of a build
+type ArtifactDownload struct {
+	User string `json:"user"`
+	File string `json:"file"`
+	Time string `json:"time"`
+	// Bytes is how much of the file was sent
+	Bytes int64 `json:"bytes,omitempty"`
+}
+
+// Publication is the progress of publishing the artifact of a completed build to one target
+type Publication struct {
+	Name string `json:"name"`
+	// Type is registry, s3 or pxe
+	Type string `json:"type"`
+	// Phase is Publishing, Succeeded or Failed
+	Phase    string `json:"phase"`
+	Attempts int32  `json:"attempts,omitempty"`
+	// Location is where the artifact was published, e.g. an image reference or object URL
+	Location string `json:"location,omitempty"`
+	Message  string `json:"message,omitempty"`
+	// Reason is ImmutableTag when the target refused to overwrite an immutable tag
+	Reason string `json:"reason,omitempty"`
+}
+
+// ComplianceResponse is the outcome of a build's OpenSCAP scan
+type ComplianceResponse struct {
+	Name    string `json:"name"`
+	Profile string `json:"profile"`
+	// Result is pending, pass, fail or error
+	Result  string `json:"result"`
+	Message string `json:"message,omitempty"`
+	// ARFFileName and ReportFileName are downloadable via the artifact endpoint
+	ARFFileName    string `json:"arfFileName,omitempty"`
+	ReportFileName string `json:"reportFileName,omitempty"`
+}
+
+// DefinesCatalogResponse lists the operator-managed default defines keyed by build target
+type DefinesCatalogResponse struct {
+	Targets map[string][]string `json:"targets"`
+}
+
+// HardeningCatalogResponse lists the hardening profiles builds can select
+type HardeningCatalogResponse struct {
+	Profiles []hardening.Profile `json:"profiles"`
+}
+
+// BuildStatsResponse aggregates the builds created within a time window
+type BuildStatsResponse struct {
+	// Since is the start of the window (RFC3339)
+	Since string `json:"since"`
+	Total int    `json:"total"`
+	// Phases counts builds by phase; builds without a phase yet are counted as Pending
+	Phases map[string]int `json:"phases"`
+	// Targets and Architectures count builds by target and by architecture
+	Targets       map[string]int `json:"targets"`
+	Architectures map[string]int `json:"architectures"`
+	// SuccessRate is Completed / (Completed + Failed), 0 when no build finished
+	SuccessRate float64 `json:"successRate"`
+	// Durations are computed from completed builds, grouped by target and architecture
+	Durations    []DurationStats   `json:"durations"`
+	ArtifactSize ArtifactSizeStats `json:"artifactSize"`
+	// TopFailures are the most frequent failure messages, most frequent first
+	TopFailures []FailureCount `json:"topFailures"`
+	// Downloads counts the artifact downloads of the builds in the window
+	Downloads DownloadStats `json:"downloads"`
+}
+
+// DownloadStats summarizes artifact downloads, by build and by user
+type DownloadStats struct {
+	Count int64 `json:"count"`
+	// Builds is the number of builds downloaded at least once
+	Builds int `json:"builds"`
+	// TopBuilds and TopUsers are the most downloaded builds and the most active downloaders, most
+	// downloads first
+	TopBuilds []DownloadCount `json:"topBuilds"`
+	TopUsers  []DownloadCount `json:"topUsers"`
+}
+
+// DownloadCount is the number of downloads of a build or by a user
+type DownloadCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// DurationStats summarizes build durations for one target/architecture pair
+type DurationStats struct {
+	Target         string  `json:"target"`
+	Architecture   string  `json:"architecture"`
+	Count          int     `json:"count"`
+	AverageSeconds float64 `json:"averageSeconds"`
+	P50Seconds     float64 `json:"p50Seconds"`
+	P90Seconds     float64 `json:"p90Seconds"`
+	P99Seconds     float64 `json:"p99Seconds"`
+}
+
+// ArtifactSizeStats summarizes the size of artifacts of completed builds that recorded one
+type ArtifactSizeStats struct {
+	Count        int   `json:"count"`
+	TotalBytes   int64 `json:"totalBytes"`
+	AverageBytes int64 `json:"averageBytes"`
+	MaxBytes     int64 `json:"maxBytes"`
+}
+
+// FailureCount is a failure message and how many builds failed with it
+type FailureCount struct {
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// FailureAnalyticsResponse groups the failed builds of a time window by classified reason and
+// error signature, with failures per time bucket to show trends
+type FailureAnalyticsResponse struct {
+	// Since is the start of the window (RFC3339)
+	Since string `json:"since"`
+	// Bucket is the width of the trend buckets, e.g. 24h0m0s
+	Bucket   string `json:"bucket"`
+	Builds   int    `json:"builds"`
+	Failures int    `json:"failures"`
+	// FailureRate is Failures / Builds, 0 when there were no builds
+	FailureRate float64 `json:"failureRate"`
+	// Buckets count builds and failures per bucket, oldest first
+	Buckets []FailureBucket `json:"buckets"`
+	// Reasons are the failure reasons, most frequent first
+	Reasons []FailureReasonStats `json:"reasons"`
+}
+
+// FailureBucket counts the builds created within one trend bucket
+type FailureBucket struct {
+	Start    string `json:"start"`
+	Builds   int    `json:"builds"`
+	Failures int    `json:"failures"`
+}
+
+// FailureReasonStats summarizes the failures classified with one reason
+type FailureReasonStats struct {
+	// Reason is one of build, dependency, timeout, upload, compliance, size-budget, boot-test or unknown
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+	// Share is the fraction of all failures with this reason
+	Share float64 `json:"share"`
+	// Trend counts the failures with this reason per bucket, aligned with Buckets
+	Trend []int `json:"trend"`
+	// Signatures are the most frequent normalized error messages, most frequent first
+	Signatures []FailureSignature `json:"signatures"`
+}
+
+// FailureSignature is a failure message with build specific parts such as names, numbers and
+// digests replaced by placeholders, so the same error in different builds is counted together
+type FailureSignature struct {
+	Signature string `json:"signature"`
+	Count     int    `json:"count"`
+	// Builds are up to three of the most recent builds that failed with the signature
+	Builds []string `json:"builds"`
+	// LastSeen is when the most recent of them was created (RFC3339)
+	LastSeen string `json:"lastSeen"`
+}
+
+// CapabilitiesResponse lists the values an automotive-image-builder image and the build API accept for builds
+type CapabilitiesResponse struct {
+	AutomotiveImageBuilder string   `json:"automotiveImageBuilder"`
+	Distros                []string `json:"distros"`
+	Targets                []string `json:"targets"`
+	Architectures          []string `json:"architectures"`
+	ExportFormats          []string `json:"exportFormats"`
+	// Compressions are the algorithms artifacts can be compressed with; they do not depend on the image
+	Compressions []string `json:"compressions"`
+	// ProbedAt is when the image was queried; results are cached per image
+	ProbedAt string `json:"probedAt,omitempty"`
 }
 
 // BuildListItem represents a build in the list API
 type BuildListItem struct {
 	Name           string `json:"name"`
+	Namespace      string `json:"namespace,omitempty"`
 	Phase          string `json:"phase"`
 	Message        string `json:"message"`
 	RequestedBy    string `json:"requestedBy,omitempty"`
 	CreatedAt      string `json:"createdAt"`
 	StartTime      string `json:"startTime,omitempty"`
 	CompletionTime string `json:"completionTime,omitempty"`
+	// Labels are the user-supplied labels of the build
+	Labels map[string]string `json:"labels,omitempty"`
+	// Downloads is the number of artifact downloads
+	Downloads      int64  `json:"downloads,omitempty"`
+	LastDownloadAt string `json:"lastDownloadAt,omitempty"`
+	// ExpiresAt is when the retention policy deletes the artifacts; "deleted" once it did
+	ExpiresAt string `json:"expiresAt,omitempty"`
+}
+
+// BuildWatchEvent is a change of the build list, streamed one per line by GET /v1/builds?watch=true
+type BuildWatchEvent struct {
+	// Type is ADDED, MODIFIED or DELETED for a build, BOOKMARK or ERROR
+	Type string `json:"type"`
+	// Object is the build as GET /v1/builds lists it; builds that stop matching the filters are DELETED
+	Object *BuildListItem `json:"object,omitempty"`
+	// ResourceVersion resumes the watch after this event
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	// Code and Error describe an ERROR event, which ends the stream
+	Code  int    `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// BuildPhase is the phase of a build in the /v2 API
+type BuildPhase string
+
+// Phases of a build; builds the controller has not picked up yet are Pending
+const (
+	BuildPhasePending   BuildPhase = "Pending"
+	BuildPhaseQueued    BuildPhase = "Queued"
+	BuildPhaseUploading BuildPhase = "Uploading"
+	BuildPhaseBuilding  BuildPhase = "Building"
+	BuildPhaseCompleted BuildPhase = "Completed"
+	BuildPhaseFailed    BuildPhase = "Failed"
+	BuildPhaseCancelled BuildPhase = "Cancelled"
+)
+
+// Finished reports whether a build in phase p will not change phase anymore
+func (p BuildPhase) Finished() bool {
+	return p == BuildPhaseCompleted || p == BuildPhaseFailed || p == BuildPhaseCancelled
+}
+
+// BuildV2 is a build in the /v2 API. Times are RFC 3339 in UTC, and the files of the build are
+// listed with it.
+type BuildV2 struct {
+	Name        string     `json:"name"`
+	Namespace   string     `json:"namespace"`
+	Phase       BuildPhase `json:"phase"`
+	Message     string     `json:"message,omitempty"`
+	RequestedBy string     `json:"requestedBy,omitempty"`
+	// RequestID is the X-Request-ID of the request that created the build
+	RequestID string `json:"requestID,omitempty"`
+	// Labels and Annotations are the user-supplied metadata of the build
+	Labels       map[string]string `json:"labels,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	Distro       string            `json:"distro"`
+	Target       string            `json:"target"`
+	Architecture string            `json:"architecture"`
+	ExportFormat string            `json:"exportFormat,omitempty"`
+	Mode         string            `json:"mode,omitempty"`
+	Priority     string            `json:"priority,omitempty"`
+	// Source is the git repository the manifest comes from; GitCommit the commit its ref resolved to
+	Source    *BuildSource `json:"source,omitempty"`
+	GitCommit string       `json:"gitCommit,omitempty"`
+	// QueuePosition is the position of a Queued build among the builds waiting for a build slot
+	QueuePosition int32      `json:"queuePosition,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	StartedAt     *time.Time `json:"startedAt,omitempty"`
+	CompletedAt   *time.Time `json:"completedAt,omitempty"`
+	// StageTimings maps build pod stages (build, package) to their durations
+	StageTimings map[string]string `json:"stageTimings,omitempty"`
+	// ComplianceResult and BootResult are set when a compliance scan or boot test was requested
+	ComplianceResult string `json:"complianceResult,omitempty"`
+	BootResult       string `json:"bootResult,omitempty"`
+	// RootFSBytes and SizeBudgetBytes are set once a build with a size budget was measured
+	RootFSBytes     int64 `json:"rootfsBytes,omitempty"`
+	SizeBudgetBytes int64 `json:"sizeBudgetBytes,omitempty"`
+	// Artifacts are the files of the build that can be downloaded
+	Artifacts []BuildArtifact `json:"artifacts,omitempty"`
+	// ArtifactsExpireAt is when the retention policy deletes the artifacts, ArtifactsDeletedAt when it did
+	ArtifactsExpireAt  *time.Time `json:"artifactsExpireAt,omitempty"`
+	ArtifactsDeletedAt *time.Time `json:"artifactsDeletedAt,omitempty"`
+	// Downloads is the number of artifact downloads, LastDownloadAt the time of the latest one
+	Downloads      int64      `json:"downloads,omitempty"`
+	LastDownloadAt *time.Time `json:"lastDownloadAt,omitempty"`
+}
+
+// BuildArtifact is a file of a build in the /v2 API
+type BuildArtifact struct {
+	Name string `json:"name"`
+	// Kind is artifact, part, signature, attestation, first-boot, hardening-report, packages, compliance,
+	// size-report or boot-log
+	Kind string `json:"kind"`
+	// Path downloads the file from the build API
+	Path      string `json:"path"`
+	MediaType string `json:"mediaType"`
+	// SizeBytes and SHA256 are set once known, i.e. once the artifact manifest was read
+	SizeBytes int64  `json:"sizeBytes,omitempty"`
+	SHA256    string `json:"sha256,omitempty"`
+}
+
+// BuildListV2 is a page of the build list in the /v2 API
+type BuildListV2 struct {
+	Items []BuildV2 `json:"items"`
+	// TotalCount is the number of builds matching the filters
+	TotalCount int `json:"totalCount"`
+	// Continue is the token of the next page, empty on the last page
+	Continue string `json:"continue,omitempty"`
+	// ResourceVersion is the version of the list, for GET /v1/builds?watch=true
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// Problem is an error response of the /v2 API, an RFC 7807 problem details object served as
+// application/problem+json. Members of the error besides these, e.g. the missingPermission of a
+// 403, are kept as extension members.
+type Problem struct {
+	// Type identifies the problem, one of the ProblemType constants or about:blank
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Instance is the path of the request
+	Instance string `json:"instance,omitempty"`
+	// RequestID is the X-Request-ID of the request, for finding it in the logs of the build API
+	RequestID string `json:"requestID,omitempty"`
+}
+
+// Problem types of the /v2 API
+const (
+	ProblemTypeInvalidRequest     = "urn:automotive-dev-operator:problem:invalid-request"
+	ProblemTypeUnauthorized       = "urn:automotive-dev-operator:problem:unauthorized"
+	ProblemTypeForbidden          = "urn:automotive-dev-operator:problem:forbidden"
+	ProblemTypeNotFound           = "urn:automotive-dev-operator:problem:not-found"
+	ProblemTypeConflict           = "urn:automotive-dev-operator:problem:conflict"
+	ProblemTypeGone               = "urn:automotive-dev-operator:problem:gone"
+	ProblemTypeUnprocessable      = "urn:automotive-dev-operator:problem:unprocessable"
+	ProblemTypeRateLimited        = "urn:automotive-dev-operator:problem:rate-limited"
+	ProblemTypeInternal           = "urn:automotive-dev-operator:problem:internal"
+	ProblemTypeServiceUnavailable = "urn:automotive-dev-operator:problem:service-unavailable"
+)
+
+// BuildDeleteResponse is returned when a build is deleted
+type BuildDeleteResponse struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	// ArtifactPVC is the workspace PVC kept with keep-artifact=true; it is no longer owned by the build
+	ArtifactPVC string `json:"artifactPVC,omitempty"`
+	// ArtifactPath is the path of the artifact on ArtifactPVC
+	ArtifactPath string `json:"artifactPath,omitempty"`
+}
+
+// ArtifactManifestResponse lists the files a build produced, so clients can choose what to
+// download and verify it
+type ArtifactManifestResponse struct {
+	Name  string         `json:"name"`
+	Files []ArtifactFile `json:"files"`
+}
+
+// ArtifactFile is a downloadable file of a build. Parts are downloaded from
+// GET /v1/builds/{name}/artifacts/{file}, all other kinds from GET /v1/builds/{name}/artifact/{filename}.
+type ArtifactFile struct {
+	Name string `json:"name"`
+	// Kind is artifact, part, signature, attestation, first-boot, hardening-report, packages, compliance,
+	// size-report or boot-log
+	Kind      string `json:"kind"`
+	SizeBytes int64  `json:"sizeBytes"`
+	// SHA256 is the hex encoded digest of the file as stored, i.e. compressed when Compression is set
+	SHA256    string `json:"sha256"`
+	MediaType string `json:"mediaType"`
+	// Compression is gzip, lz4, zstd or none
+	Compression string `json:"compression"`
+}
+
+// UploadSessionRequest starts a resumable upload of a local file of a build
+type UploadSessionRequest struct {
+	// Path is where the file is stored, relative to the workspace the manifest refers to
+	Path string `json:"path"`
+	// Size is the length of the file in bytes
+	Size int64 `json:"size"`
+	// SHA256 is the hex encoded digest of the file; the received file is discarded when it differs
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// UploadSession is the state of a resumable upload. Chunks are sent with
+// PATCH /v1/builds/{name}/uploads/sessions/{id} starting at Offset.
+type UploadSession struct {
+	ID     string `json:"id"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256,omitempty"`
+	// Offset is the number of bytes received so far
+	Offset int64 `json:"offset"`
+	// Complete is set once the whole file was received, verified and stored at Path
+	Complete bool `json:"complete,omitempty"`
+	// MaxChunkBytes is the largest chunk the server accepts
+	MaxChunkBytes int64 `json:"maxChunkBytes"`
+}
+
+// LogSearchResponse lists the log lines of a build that matched a search
+type LogSearchResponse struct {
+	Query   string     `json:"query"`
+	Matches []LogMatch `json:"matches"`
+	// Truncated is set when the search stopped at maxMatches
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// LogMatch is a matching log line with the lines around it
+type LogMatch struct {
+	// Step is the build step whose log contains the line, e.g. build or push
+	Step string `json:"step"`
+	// Line is the 1-based line number within the log of the step
+	Line   int      `json:"line"`
+	Text   string   `json:"text"`
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+}
+
+// LogStreamEvent is the data of an event of GET /v1/builds/{name}/logs/stream. Log events carry
+// the id <step>:<line>, which a client sends back as Last-Event-ID to resume after that line.
+type LogStreamEvent struct {
+	// Step is the build step the event is about; empty for events about the whole build
+	Step string `json:"step,omitempty"`
+	// Line is the 1-based line number within the log of the step, set for log events
+	Line int `json:"line,omitempty"`
+	// Stream is stdout for output of the step containers, which Kubernetes stores merged with
+	// stderr, and system for messages of the API server
+	Stream string `json:"stream"`
+	Text   string `json:"text"`
+}
+
+// BuildCloneRequest creates a new build from the inputs of an existing one
+type BuildCloneRequest struct {
+	// Name of the new build
+	Name string `json:"name"`
+	// Patch is a JSON merge patch (JSON or YAML) applied to the source build's BuildRequest
+	Patch string `json:"patch,omitempty"`
 }
 
 type (
@@ -138,3 +946,157 @@ type BuildTemplateResponse struct {
 	BuildRequest `json:",inline"`
 	SourceFiles  []string `json:"sourceFiles,omitempty"`
 }
+
+// PolicyCheck is the outcome of one check a build request has to pass to be accepted
+type PolicyCheck struct {
+	// Name is one of maintenance, request, metadata, name, admission, quota, storage or images
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	// Skipped is set when the check could not be evaluated; it does not reject the request
+	Skipped bool   `json:"skipped,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// PolicyEvaluationResponse reports whether a build request would be accepted without creating anything
+type PolicyEvaluationResponse struct {
+	Allowed bool          `json:"allowed"`
+	Checks  []PolicyCheck `json:"checks"`
+}
+
+// BuildEvent is something that happened to a build or to an object it runs with
+type BuildEvent struct {
+	// Time is when it happened (RFC3339); for repeated Kubernetes Events the last time
+	Time string `json:"time"`
+	// Source is operator for milestones recorded in the build status and kubernetes for Events
+	Source string `json:"source"`
+	// Type is Normal or Warning
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+	// Object is the object it happened to, e.g. Pod/my-build-abc12-pod
+	Object  string `json:"object"`
+	Message string `json:"message"`
+	// Count is how many times a Kubernetes Event was seen
+	Count int32 `json:"count,omitempty"`
+}
+
+// BuildEventsResponse lists what happened to a build, oldest first
+type BuildEventsResponse struct {
+	Name   string       `json:"name"`
+	Events []BuildEvent `json:"events"`
+}
+
+// BuildRecord is a record of the build history of a namespace and the Rekor entry of its digest
+type BuildRecord struct {
+	buildrecord.Record
+	Rekor *buildrecord.Anchor `json:"rekor,omitempty"`
+}
+
+// BuildRecordsResponse is the build record history of a namespace, oldest first
+type BuildRecordsResponse struct {
+	Namespace string        `json:"namespace"`
+	Records   []BuildRecord `json:"records"`
+	// Verified is set when the records form an unbroken chain up to the head of the history
+	Verified bool `json:"verified"`
+	// Problems are the records that were changed, removed or could not be read
+	Problems []string `json:"problems,omitempty"`
+}
+
+// ManifestValidationRequest is a manifest to validate for the build settings it would be used with.
+// Empty settings take the defaults of a build request.
+type ManifestValidationRequest struct {
+	Manifest               string       `json:"manifest"`
+	Distro                 Distro       `json:"distro,omitempty"`
+	Target                 Target       `json:"target,omitempty"`
+	Architecture           Architecture `json:"architecture,omitempty"`
+	ExportFormat           ExportFormat `json:"exportFormat,omitempty"`
+	Mode                   Mode         `json:"mode,omitempty"`
+	AutomotiveImageBuilder string       `json:"automotiveImageBuilder,omitempty"`
+}
+
+// ManifestIssue is a problem found in a manifest or in the settings it is built with
+type ManifestIssue struct {
+	// Field is the path of the offending field, e.g. content.add_files[0], or the build setting,
+	// e.g. target; empty for the manifest as a whole
+	Field string `json:"field,omitempty"`
+	// Line and Column locate the problem in the manifest, counting from 1; omitted for build settings
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// ManifestValidationResponse reports whether a build of the manifest would be rejected
+type ManifestValidationResponse struct {
+	// Valid is set when there are no errors; warnings do not make a manifest invalid
+	Valid    bool            `json:"valid"`
+	Errors   []ManifestIssue `json:"errors"`
+	Warnings []ManifestIssue `json:"warnings,omitempty"`
+}
+
+// Permission is a Kubernetes RBAC permission on ImageBuilds
+type Permission struct {
+	Verb        string `json:"verb"`
+	Group       string `json:"group"`
+	Resource    string `json:"resource"`
+	Subresource string `json:"subresource,omitempty"`
+	// Name is the build the permission is needed on; empty for all builds of the namespace
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace"`
+}
+
+// ErrorResponse is the body of the error responses of the /v1 API
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// RequestID is the X-Request-ID of the request, for finding it in the logs of the build API
+	RequestID string `json:"requestID,omitempty"`
+}
+
+// ForbiddenResponse is returned with 403 when the caller lacks a permission
+type ForbiddenResponse struct {
+	Error             string      `json:"error"`
+	MissingPermission *Permission `json:"missingPermission,omitempty"`
+	RequestID         string      `json:"requestID,omitempty"`
+}
+
+// InfoResponse describes the state of the build API
+type InfoResponse struct {
+	// ReadOnly is set during maintenance; requests that create builds are rejected
+	ReadOnly bool `json:"readOnly"`
+	// Banner is a message from the operator administrators, e.g. a maintenance schedule
+	Banner string `json:"banner,omitempty"`
+	// Versions are the versions of the build API and the components it works with
+	Versions *VersionInfo `json:"versions,omitempty"`
+	// APIVersions are the versions of the REST API the server serves, e.g. v1 and v2
+	APIVersions []string `json:"apiVersions,omitempty"`
+}
+
+// VersionInfo reports the versions of the build API and the components it works with
+type VersionInfo struct {
+	// BuildAPI is the release of the build API server
+	BuildAPI string `json:"buildAPI"`
+	// Operator is the release of the operator that deployed the build API
+	Operator string `json:"operator,omitempty"`
+	// APIVersion is the ImageBuild API version the build API reads and writes
+	APIVersion string `json:"apiVersion"`
+	// ServedVersions are the ImageBuild API versions the installed CRDs serve, preferred first
+	ServedVersions []string `json:"servedVersions,omitempty"`
+	// BuilderImage is the automotive-image-builder image of builds that do not name one
+	BuilderImage string `json:"builderImage"`
+}
+
+// HealthResponse reports the dependencies of the build API checked by /healthz and /readyz
+type HealthResponse struct {
+	// Status is ok when every check passed, degraded on /healthz and failed on /readyz otherwise
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+	// CheckedAt is when the checks ran; results are reused for a few seconds
+	CheckedAt string `json:"checkedAt"`
+}
+
+// HealthCheck is the outcome of checking one dependency
+type HealthCheck struct {
+	Name string `json:"name"`
+	// Status is ok, warning or failed; warnings do not fail readiness
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
@@ -0,0 +1,39 @@
+package buildapi
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// uiAssets is the web UI served at /ui/: a page that browses builds, follows their logs, downloads
+// their artifacts and submits manifests with the same endpoints caib uses, for users without caib
+//
+//go:embed ui
+var uiAssets embed.FS
+
+// uiContentSecurityPolicy keeps the web UI to its own assets and the build API; downloads leave
+// through signed URLs of the build API as well
+const uiContentSecurityPolicy = "default-src 'self'; img-src 'self' data:; object-src 'none'; frame-ancestors 'none'; base-uri 'none'; form-action 'self'"
+
+// registerUI serves the web UI at /ui/ and redirects / to it. The assets need no authentication;
+// the page asks for a token when the build API answers 401, unless a proxy in front of the build
+// API forwards the access token of the browser session.
+func registerUI(router *gin.Engine) {
+	assets, err := fs.Sub(uiAssets, "ui")
+	if err != nil {
+		panic(err)
+	}
+	ui := router.Group("/ui", func(c *gin.Context) {
+		c.Header("Content-Security-Policy", uiContentSecurityPolicy)
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Referrer-Policy", "no-referrer")
+		c.Header("Cache-Control", "no-cache")
+	})
+	ui.StaticFS("/", http.FS(assets))
+	router.GET("/", func(c *gin.Context) {
+		c.Redirect(http.StatusFound, "/ui/")
+	})
+}
@@ -0,0 +1,504 @@
+// Web UI of the build API. It uses the same endpoints as caib; see /docs for their reference.
+"use strict";
+
+const tokenKey = "automotive-build-api-token";
+const terminalPhases = ["Completed", "Failed", "Cancelled"];
+const pageSize = 50;
+
+const $ = (id) => document.getElementById(id);
+
+let builds = [];
+let nextPage = "";
+let current = "";
+let logStream = null;
+let pollTimer = 0;
+let logTimer = 0;
+let listWatch = null;
+
+// api calls the build API in the selected namespace with the token of the tab, if any. Without
+// one, a proxy in front of the build API may authenticate the browser session.
+async function api(method, path, body, signal) {
+  const url = new URL(path, location.origin);
+  const namespace = $("namespace").value.trim();
+  if (namespace) {
+    url.searchParams.set("namespace", namespace);
+  }
+  const headers = {};
+  const token = sessionStorage.getItem(tokenKey);
+  if (token) {
+    headers.Authorization = "Bearer " + token;
+  }
+  const init = {method, headers, signal};
+  if (body !== undefined) {
+    headers["Content-Type"] = "application/json";
+    init.body = JSON.stringify(body);
+  }
+  const resp = await fetch(url, init);
+  if (resp.status === 401) {
+    showLogin();
+    throw new Error("the build API rejected the token");
+  }
+  if (!resp.ok) {
+    let message = resp.status + " " + resp.statusText;
+    try {
+      const problem = await resp.json();
+      message = problem.error || problem.detail || message;
+    } catch (e) {
+      // not a JSON error
+    }
+    const err = new Error(message);
+    err.status = resp.status;
+    throw err;
+  }
+  return resp;
+}
+
+function showError(err) {
+  if (!err) {
+    $("error").hidden = true;
+    return;
+  }
+  if (err.name === "AbortError") {
+    return;
+  }
+  $("error").textContent = err.message || String(err);
+  $("error").hidden = false;
+}
+
+function showLogin() {
+  $("app").hidden = true;
+  $("login").hidden = false;
+  $("sign-out").hidden = true;
+  $("token").focus();
+}
+
+function showApp() {
+  $("login").hidden = true;
+  $("app").hidden = false;
+  $("sign-out").hidden = !sessionStorage.getItem(tokenKey);
+}
+
+function formatTime(value) {
+  return value ? new Date(value).toLocaleString() : "";
+}
+
+function formatSize(bytes) {
+  const units = ["B", "KiB", "MiB", "GiB", "TiB"];
+  let i = 0;
+  for (; bytes >= 1024 && i < units.length - 1; i++) {
+    bytes /= 1024;
+  }
+  return (i === 0 ? bytes : bytes.toFixed(1)) + " " + units[i];
+}
+
+function cell(row, text, className) {
+  const td = row.insertCell();
+  td.textContent = text || "";
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+async function loadInfo() {
+  try {
+    const info = await (await api("GET", "/v1/info")).json();
+    $("banner").textContent = info.banner || "";
+    $("banner").hidden = !info.banner;
+    $("new-build").disabled = !!info.readOnly;
+    $("new-build").title = info.readOnly ? "The build API is read-only during maintenance" : "";
+  } catch (err) {
+    // the banner is optional
+  }
+}
+
+async function loadBuilds(more) {
+  const params = new URLSearchParams({limit: String(pageSize)});
+  if ($("phase").value) {
+    params.set("phase", $("phase").value);
+  }
+  if (more && nextPage) {
+    params.set("continue", nextPage);
+  }
+  const resp = await api("GET", "/v1/builds?" + params);
+  const page = await resp.json();
+  builds = more ? builds.concat(page) : page;
+  nextPage = resp.headers.get("X-Continue") || "";
+  showApp();
+  renderBuilds();
+  if (!more) {
+    watchBuilds(resp.headers.get("X-Resource-Version") || "");
+  }
+}
+
+// watchBuilds applies the changes of the listed builds made after resourceVersion as the build
+// API streams them, and lists the builds again when the stream ends
+async function watchBuilds(resourceVersion) {
+  if (listWatch) {
+    listWatch.abort();
+  }
+  const watch = new AbortController();
+  listWatch = watch;
+  const params = new URLSearchParams({watch: "true"});
+  if (resourceVersion) {
+    params.set("resourceVersion", resourceVersion);
+  }
+  if ($("phase").value) {
+    params.set("phase", $("phase").value);
+  }
+  try {
+    const resp = await api("GET", "/v1/builds?" + params, undefined, watch.signal);
+    const reader = resp.body.getReader();
+    const decoder = new TextDecoder();
+    let pending = "";
+    for (;;) {
+      const {done, value} = await reader.read();
+      if (done) {
+        break;
+      }
+      const lines = (pending + decoder.decode(value, {stream: true})).split("\n");
+      pending = lines.pop();
+      for (const line of lines) {
+        if (line.trim()) {
+          applyBuildEvent(JSON.parse(line));
+        }
+      }
+    }
+  } catch (err) {
+    // the builds are listed again below
+  }
+  if (!watch.signal.aborted && $("login").hidden) {
+    setTimeout(() => !watch.signal.aborted && loadBuilds(false).catch(showError), 5000);
+  }
+}
+
+function applyBuildEvent(ev) {
+  if (!ev.object) {
+    return;
+  }
+  const i = builds.findIndex((b) => b.name === ev.object.name && b.namespace === ev.object.namespace);
+  if (ev.type === "DELETED") {
+    if (i >= 0) {
+      builds.splice(i, 1);
+    }
+  } else if (i >= 0) {
+    builds[i] = ev.object;
+  } else if (ev.type === "ADDED") {
+    builds.unshift(ev.object);
+  }
+  renderBuilds();
+}
+
+function renderBuilds() {
+  const search = $("search").value.trim().toLowerCase();
+  const tbody = $("builds");
+  tbody.replaceChildren();
+  for (const b of builds) {
+    const labels = Object.entries(b.labels || {}).map(([k, v]) => k + "=" + v).join(" ");
+    const text = [b.name, b.requestedBy, labels, b.message].join(" ").toLowerCase();
+    if (search && !text.includes(search)) {
+      continue;
+    }
+    const row = tbody.insertRow();
+    const link = document.createElement("a");
+    link.href = "#/builds/" + encodeURIComponent(b.name);
+    link.textContent = b.name;
+    row.insertCell().append(link);
+    cell(row, b.phase, "phase " + b.phase.toLowerCase()).title = b.message || "";
+    cell(row, b.requestedBy);
+    cell(row, formatTime(b.createdAt));
+    cell(row, formatTime(b.completionTime));
+  }
+  $("more").hidden = !nextPage;
+}
+
+function stopFollowing() {
+  clearTimeout(pollTimer);
+  clearTimeout(logTimer);
+  if (logStream) {
+    logStream.abort();
+  }
+  logStream = new AbortController();
+}
+
+async function showBuild(name) {
+  stopFollowing();
+  current = name;
+  $("submit").hidden = true;
+  $("detail").hidden = false;
+  $("detail-name").textContent = name;
+  $("logs").textContent = "";
+  $("files").replaceChildren();
+  const build = await refreshBuild(name);
+  if (!build) {
+    return;
+  }
+  if (build.phase === "Completed") {
+    loadFiles(name);
+  }
+  followLogs(name, build.phase);
+}
+
+// refreshBuild shows the state of a build, polling until it finished
+async function refreshBuild(name) {
+  let build;
+  try {
+    build = await (await api("GET", "/v1/builds/" + encodeURIComponent(name))).json();
+  } catch (err) {
+    showError(err);
+    return null;
+  }
+  if (name !== current) {
+    return null;
+  }
+  const fields = [
+    ["Phase", build.phase],
+    ["Message", build.message],
+    ["Requested by", build.requestedBy],
+    ["Started", formatTime(build.startTime)],
+    ["Finished", formatTime(build.completionTime)],
+    ["Artifact", build.artifactFileName],
+    ["Artifacts expire", build.artifactsExpireAt ? formatTime(build.artifactsExpireAt) : ""],
+  ];
+  const dl = $("detail-fields");
+  dl.replaceChildren();
+  for (const [label, value] of fields) {
+    if (!value) {
+      continue;
+    }
+    const dt = document.createElement("dt");
+    dt.textContent = label;
+    const dd = document.createElement("dd");
+    dd.textContent = value;
+    dl.append(dt, dd);
+  }
+  const finished = terminalPhases.includes(build.phase);
+  $("download").hidden = build.phase !== "Completed" || !!build.artifactsDeletedAt;
+  $("cancel").hidden = finished;
+  if (!finished) {
+    pollTimer = setTimeout(async () => {
+      const updated = await refreshBuild(name);
+      if (updated && updated.phase === "Completed") {
+        loadFiles(name);
+      }
+    }, 5000);
+  }
+  return build;
+}
+
+async function loadFiles(name) {
+  let manifest;
+  try {
+    manifest = await (await api("GET", "/v1/builds/" + encodeURIComponent(name) + "/artifact/manifest")).json();
+  } catch (err) {
+    showError(err);
+    return;
+  }
+  const list = $("files");
+  list.replaceChildren();
+  for (const f of manifest.files || []) {
+    const item = document.createElement("li");
+    const button = document.createElement("button");
+    button.type = "button";
+    button.className = "link";
+    button.textContent = f.name;
+    button.addEventListener("click", () => download(name, f));
+    item.append(button, " " + f.kind + ", " + formatSize(f.sizeBytes));
+    list.append(item);
+  }
+}
+
+// download fetches a signed URL of a file and lets the browser download it, so the token never
+// becomes part of a URL
+async function download(name, file) {
+  const req = {expiresInMinutes: 15};
+  if (file && file.kind !== "artifact") {
+    req.file = file.name;
+    req.part = file.kind === "part";
+  }
+  try {
+    const signed = await (await api("POST", "/v1/builds/" + encodeURIComponent(name) + "/artifact/url", req)).json();
+    location.assign(signed.path || signed.url);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+// followLogs streams the logs of a running build, or shows the archived logs of a finished one
+async function followLogs(name, phase) {
+  const base = "/v1/builds/" + encodeURIComponent(name);
+  const out = $("logs");
+  let resp;
+  try {
+    if (terminalPhases.includes(phase)) {
+      try {
+        resp = await api("GET", base + "/logs/archive", undefined, logStream.signal);
+      } catch (err) {
+        resp = await api("GET", base + "/logs", undefined, logStream.signal);
+      }
+    } else {
+      resp = await api("GET", base + "/logs", undefined, logStream.signal);
+    }
+    const reader = resp.body.getReader();
+    const decoder = new TextDecoder();
+    for (;;) {
+      const {done, value} = await reader.read();
+      if (done || name !== current) {
+        break;
+      }
+      const atEnd = out.scrollTop + out.clientHeight >= out.scrollHeight - 4;
+      out.append(decoder.decode(value, {stream: true}));
+      if (atEnd) {
+        out.scrollTop = out.scrollHeight;
+      }
+    }
+  } catch (err) {
+    if (err.name === "AbortError" || name !== current) {
+      return;
+    }
+    if (err.status === 503 && !terminalPhases.includes(phase)) {
+      // the build pod has not started yet
+      out.textContent = "Waiting for the build to start…";
+      logTimer = setTimeout(() => followLogs(name, phase), 5000);
+      return;
+    }
+    out.append("\n" + err.message + "\n");
+  }
+}
+
+async function cancelBuild() {
+  if (!confirm("Cancel build " + current + "?")) {
+    return;
+  }
+  try {
+    await api("POST", "/v1/builds/" + encodeURIComponent(current) + "/cancel");
+    showBuild(current);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+function closeBuild() {
+  stopFollowing();
+  current = "";
+  $("detail").hidden = true;
+  if (location.hash) {
+    history.pushState("", "", location.pathname + location.search);
+  }
+}
+
+async function showSubmit() {
+  closeBuild();
+  $("submit").hidden = false;
+  let caps = {};
+  try {
+    caps = await (await api("GET", "/v1/capabilities")).json();
+  } catch (err) {
+    // fall back to the defaults of the build API
+  }
+  const form = $("build-form");
+  const choices = {
+    distro: [caps.distros, "cs9"],
+    target: [caps.targets, "qemu"],
+    architecture: [caps.architectures, "arm64"],
+    exportFormat: [caps.exportFormats, "image"],
+  };
+  for (const [field, [values, fallback]] of Object.entries(choices)) {
+    const select = form.elements[field];
+    const selected = select.value || fallback;
+    select.replaceChildren();
+    for (const v of values && values.length ? values : [fallback]) {
+      select.add(new Option(v, v, false, v === selected));
+    }
+  }
+}
+
+async function submitBuild(event) {
+  event.preventDefault();
+  const form = event.target;
+  const req = {};
+  for (const field of ["name", "manifest", "distro", "target", "architecture", "exportFormat", "mode"]) {
+    req[field] = form.elements[field].value;
+  }
+  const file = $("manifest-file").files[0];
+  if (file) {
+    req.manifestFileName = file.name;
+  }
+  try {
+    const build = await (await api("POST", "/v1/builds", req)).json();
+    showError(null);
+    form.reset();
+    $("submit").hidden = true;
+    location.hash = "#/builds/" + encodeURIComponent(build.name);
+    loadBuilds(false);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+function route() {
+  const m = location.hash.match(/^#\/builds\/(.+)$/);
+  if (m) {
+    showBuild(decodeURIComponent(m[1]));
+  }
+}
+
+function refresh() {
+  showError(null);
+  loadInfo();
+  loadBuilds(false).then(route, showError);
+}
+
+document.addEventListener("DOMContentLoaded", () => {
+  logStream = new AbortController();
+  $("namespace").value = new URLSearchParams(location.search).get("namespace") || "";
+  $("namespace").addEventListener("change", () => {
+    const url = new URL(location.href);
+    const namespace = $("namespace").value.trim();
+    if (namespace) {
+      url.searchParams.set("namespace", namespace);
+    } else {
+      url.searchParams.delete("namespace");
+    }
+    history.replaceState("", "", url);
+    closeBuild();
+    refresh();
+  });
+  $("login-form").addEventListener("submit", (event) => {
+    event.preventDefault();
+    sessionStorage.setItem(tokenKey, $("token").value.trim());
+    $("token").value = "";
+    refresh();
+  });
+  $("sign-out").addEventListener("click", () => {
+    sessionStorage.removeItem(tokenKey);
+    if (listWatch) {
+      listWatch.abort();
+    }
+    closeBuild();
+    showLogin();
+  });
+  $("filters").addEventListener("submit", (event) => {
+    event.preventDefault();
+    loadBuilds(false).catch(showError);
+  });
+  $("phase").addEventListener("change", () => loadBuilds(false).catch(showError));
+  $("search").addEventListener("input", renderBuilds);
+  $("more").addEventListener("click", () => loadBuilds(true).catch(showError));
+  $("new-build").addEventListener("click", showSubmit);
+  $("download").addEventListener("click", () => download(current, null));
+  $("cancel").addEventListener("click", cancelBuild);
+  $("close").addEventListener("click", closeBuild);
+  $("submit-cancel").addEventListener("click", () => {
+    $("submit").hidden = true;
+  });
+  $("manifest-file").addEventListener("change", async (event) => {
+    const file = event.target.files[0];
+    if (file) {
+      $("build-form").elements.manifest.value = await file.text();
+    }
+  });
+  $("build-form").addEventListener("submit", submitBuild);
+  window.addEventListener("hashchange", route);
+  refresh();
+});
@@ -0,0 +1,445 @@
+package buildapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	kscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+const (
+	// maxUploadChunkBytes bounds the body of one chunk, which is held in memory to verify its checksum
+	maxUploadChunkBytes = 32 << 20
+
+	// statusChecksumMismatch is the tus status for a chunk whose Upload-Checksum does not match
+	statusChecksumMismatch = 460
+
+	uploadOffsetHeader   = "Upload-Offset"
+	uploadChecksumHeader = "Upload-Checksum"
+)
+
+// uploadSessionScript keeps the state of resumable uploads in the upload pod: for each session a
+// .info file with the destination, size and sha256, and a .part file with the bytes received so
+// far, whose size is the offset. Outcomes the API answers for are printed rather than exit codes.
+const uploadSessionScript = `dir=/workspace/shared/.uploads
+op=$1; id=$2
+info="$dir/$id.info"; part="$dir/$id.part"
+case "$op" in
+pending) ls "$dir" 2>/dev/null | grep -c '\.info$' || true; exit 0 ;;
+cleanup) rm -rf "$dir"; exit 0 ;;
+create) mkdir -p "$dir" && printf '%s\n%s\n%s\n' "$3" "$4" "$5" > "$info" && : > "$part" && echo 0; exit ;;
+esac
+[ -f "$info" ] || { echo missing; exit 0; }
+case "$op" in
+status) cat "$info"; wc -c < "$part" | tr -d ' ' ;;
+append)
+  cur=$(wc -c < "$part" | tr -d ' ')
+  [ "$cur" -eq "$3" ] || { echo "conflict $cur"; exit 0; }
+  cat >> "$part" && wc -c < "$part" | tr -d ' ' ;;
+finish)
+  if [ -n "$4" ] && [ "$(sha256sum "$part" | cut -d' ' -f1)" != "$4" ]; then
+    rm -f "$info" "$part"; echo mismatch; exit 0
+  fi
+  dest="/workspace/shared/$3"
+  mkdir -p "$(dirname "$dest")" && mv "$part" "$dest" && rm -f "$info" && echo done ;;
+abort) rm -f "$info" "$part"; echo aborted ;;
+esac
+`
+
+// uploadTarget is the upload pod of a build that sessions are kept in
+type uploadTarget struct {
+	build     *automotivev1alpha1.ImageBuild
+	k8sClient client.Client
+	restCfg   *rest.Config
+	pod       *corev1.Pod
+}
+
+// uploadTargetFromRequest finds the build and upload pod of a request, writing the error response
+// when there is none
+func uploadTargetFromRequest(c *gin.Context, name string) (*uploadTarget, bool) {
+	namespace := requestNamespace(c)
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return nil, false
+	}
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(c.Request.Context(), types.NamespacedName{Name: name, Namespace: namespace}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return nil, false
+	}
+	pod, err := findUploadPod(c.Request.Context(), k8sClient, namespace, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error listing upload pods: %v", err)})
+		return nil, false
+	}
+	if pod == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "upload pod not ready"})
+		return nil, false
+	}
+	restCfg, err := getRESTConfigFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("rest config: %v", err)})
+		return nil, false
+	}
+	return &uploadTarget{build: build, k8sClient: k8sClient, restCfg: restCfg, pod: pod}, true
+}
+
+// session runs an operation of uploadSessionScript in the upload pod and returns its output
+func (t *uploadTarget) session(ctx context.Context, stdin io.Reader, args ...string) (string, error) {
+	cmd := append([]string{"/bin/sh", "-c", uploadSessionScript, "sh"}, args...)
+	return podExec(ctx, t.restCfg, t.pod.Namespace, t.pod.Name, t.pod.Spec.Containers[0].Name, cmd, stdin)
+}
+
+// podExec runs cmd in a container and returns its standard output
+func podExec(ctx context.Context, restCfg *rest.Config, namespace, pod, container string, cmd []string, stdin io.Reader) (string, error) {
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return "", err
+	}
+	req := clientset.CoreV1().RESTClient().Post().Resource("pods").Name(pod).Namespace(namespace).SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   cmd,
+			Stdin:     stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+		}, kscheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(restCfg, http.MethodPost, req.URL())
+	if err != nil {
+		return "", err
+	}
+	var stdout, stderr bytes.Buffer
+	if err := executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdin: stdin, Stdout: &stdout, Stderr: &stderr}); err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// uploadDestination cleans the destination of an uploaded file, which must stay in the workspace
+func uploadDestination(dest string) (string, error) {
+	clean := path.Clean(strings.TrimSpace(dest))
+	if clean == "." || strings.ContainsAny(clean, "\r\n") || strings.HasPrefix(clean, "..") || strings.HasPrefix(clean, "/") || strings.HasPrefix(clean, ".uploads") {
+		return "", fmt.Errorf("invalid destination path: %s", dest)
+	}
+	return clean, nil
+}
+
+// parseUploadChecksum parses an Upload-Checksum header of the form "sha256 <base64 digest>"
+func parseUploadChecksum(header string) ([]byte, error) {
+	algorithm, value, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok {
+		return nil, fmt.Errorf("%s must be \"<algorithm> <base64 digest>\"", uploadChecksumHeader)
+	}
+	if algorithm != "sha256" {
+		return nil, fmt.Errorf("unsupported checksum algorithm %q, only sha256 is supported", algorithm)
+	}
+	sum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("invalid sha256 digest in %s", uploadChecksumHeader)
+	}
+	return sum, nil
+}
+
+// parseUploadSession reads the output of the status operation: the destination, size and sha256
+// of the session, then its offset
+func parseUploadSession(id, out string) (*UploadSession, error) {
+	lines := strings.Split(out, "\n")
+	if len(lines) != 4 {
+		return nil, fmt.Errorf("unexpected upload session state %q", out)
+	}
+	size, err := strconv.ParseInt(lines[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upload size %q", lines[1])
+	}
+	offset, err := strconv.ParseInt(lines[3], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upload offset %q", lines[3])
+	}
+	return &UploadSession{
+		ID:            id,
+		Path:          lines[0],
+		Size:          size,
+		SHA256:        lines[2],
+		Offset:        offset,
+		MaxChunkBytes: maxUploadChunkBytes,
+	}, nil
+}
+
+// sessionID returns the id parameter of a request, which names files in the upload pod and so must
+// be one the API generated
+func sessionID(c *gin.Context) (string, bool) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "upload session not found"})
+		return "", false
+	}
+	return id, true
+}
+
+func (a *APIServer) handleCreateUploadSession(c *gin.Context) {
+	name := c.Param("name")
+	var req UploadSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	dest, err := uploadDestination(req.Path)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Size < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must not be negative"})
+		return
+	}
+	req.SHA256 = strings.ToLower(req.SHA256)
+	if sum, err := hex.DecodeString(req.SHA256); req.SHA256 != "" && (err != nil || len(sum) != sha256.Size) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sha256 must be a hex encoded SHA-256 digest"})
+		return
+	}
+
+	t, ok := uploadTargetFromRequest(c, name)
+	if !ok {
+		return
+	}
+	session := &UploadSession{
+		ID:            uuid.New().String(),
+		Path:          dest,
+		Size:          req.Size,
+		SHA256:        req.SHA256,
+		MaxChunkBytes: maxUploadChunkBytes,
+	}
+	ctx := c.Request.Context()
+	if _, err := t.session(ctx, nil, "create", session.ID, dest, strconv.FormatInt(req.Size, 10), req.SHA256); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("creating upload session: %v", err)})
+		return
+	}
+	a.log.Info("upload session created", "build", name, "session", session.ID, "path", dest, "size", req.Size,
+		"reqID", c.GetString("reqID"))
+
+	// an empty file has no chunk to complete it
+	if req.Size == 0 {
+		if !finishUpload(c, t, session) {
+			return
+		}
+	}
+	c.Header(uploadOffsetHeader, strconv.FormatInt(session.Offset, 10))
+	c.Header("Location", c.Request.URL.Path+"/"+session.ID)
+	writeJSON(c, http.StatusCreated, session)
+}
+
+func (a *APIServer) handleGetUploadSession(c *gin.Context) {
+	id, ok := sessionID(c)
+	if !ok {
+		return
+	}
+	t, ok := uploadTargetFromRequest(c, c.Param("name"))
+	if !ok {
+		return
+	}
+	session, ok := loadUploadSession(c, t, id)
+	if !ok {
+		return
+	}
+	c.Header(uploadOffsetHeader, strconv.FormatInt(session.Offset, 10))
+	c.Header("Cache-Control", "no-store")
+	writeJSON(c, http.StatusOK, session)
+}
+
+// loadUploadSession reads the state of a session, writing the error response when it fails
+func loadUploadSession(c *gin.Context, t *uploadTarget, id string) (*UploadSession, bool) {
+	out, err := t.session(c.Request.Context(), nil, "status", id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("reading upload session: %v", err)})
+		return nil, false
+	}
+	if out == "missing" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "upload session not found"})
+		return nil, false
+	}
+	session, err := parseUploadSession(id, out)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return session, true
+}
+
+// handleUploadChunk appends a chunk to a session. The chunk must start at the offset of the
+// session; when it completes the file, the file is checked against its sha256 and moved to its
+// destination.
+func (a *APIServer) handleUploadChunk(c *gin.Context) {
+	name := c.Param("name")
+	id, ok := sessionID(c)
+	if !ok {
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader(uploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("missing or invalid %s header", uploadOffsetHeader)})
+		return
+	}
+	var checksum []byte
+	if h := c.GetHeader(uploadChecksumHeader); h != "" {
+		if checksum, err = parseUploadChecksum(h); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	chunk, err := io.ReadAll(io.LimitReader(c.Request.Body, maxUploadChunkBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("reading chunk: %v", err)})
+		return
+	}
+	if len(chunk) > maxUploadChunkBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("chunks must not exceed %d bytes", maxUploadChunkBytes)})
+		return
+	}
+	if checksum != nil {
+		if sum := sha256.Sum256(chunk); !bytes.Equal(sum[:], checksum) {
+			c.JSON(statusChecksumMismatch, gin.H{"error": "chunk does not match its checksum"})
+			return
+		}
+	}
+
+	t, ok := uploadTargetFromRequest(c, name)
+	if !ok {
+		return
+	}
+	session, ok := loadUploadSession(c, t, id)
+	if !ok {
+		return
+	}
+	if offset+int64(len(chunk)) > session.Size {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("chunk ends past the size of the upload (%d bytes)", session.Size)})
+		return
+	}
+
+	out, err := t.session(c.Request.Context(), bytes.NewReader(chunk), "append", id, strconv.FormatInt(offset, 10))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("writing chunk: %v", err)})
+		return
+	}
+	switch {
+	case out == "missing":
+		c.JSON(http.StatusNotFound, gin.H{"error": "upload session not found"})
+		return
+	case strings.HasPrefix(out, "conflict "):
+		current := strings.TrimPrefix(out, "conflict ")
+		c.Header(uploadOffsetHeader, current)
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%s %d does not match the offset of the upload, %s", uploadOffsetHeader, offset, current)})
+		return
+	}
+	if session.Offset, err = strconv.ParseInt(out, 10, 64); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unexpected upload offset %q", out)})
+		return
+	}
+
+	if session.Offset == session.Size {
+		if !finishUpload(c, t, session) {
+			return
+		}
+		a.log.Info("upload session finished", "build", name, "session", id, "path", session.Path, "size", session.Size,
+			"reqID", c.GetString("reqID"))
+	}
+	c.Header(uploadOffsetHeader, strconv.FormatInt(session.Offset, 10))
+	writeJSON(c, http.StatusOK, session)
+}
+
+// finishUpload checks a fully received file against its sha256 and moves it to its destination.
+// A file that does not match is discarded, since resending chunks cannot repair it.
+func finishUpload(c *gin.Context, t *uploadTarget, session *UploadSession) bool {
+	out, err := t.session(c.Request.Context(), nil, "finish", session.ID, session.Path, session.SHA256)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("finishing upload: %v", err)})
+		return false
+	}
+	switch out {
+	case "done":
+		session.Complete = true
+		return true
+	case "mismatch":
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("%s does not match its sha256; start a new upload", session.Path)})
+	case "missing":
+		c.JSON(http.StatusNotFound, gin.H{"error": "upload session not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unexpected upload state %q", out)})
+	}
+	return false
+}
+
+func (a *APIServer) handleDeleteUploadSession(c *gin.Context) {
+	id, ok := sessionID(c)
+	if !ok {
+		return
+	}
+	t, ok := uploadTargetFromRequest(c, c.Param("name"))
+	if !ok {
+		return
+	}
+	out, err := t.session(c.Request.Context(), nil, "abort", id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("aborting upload: %v", err)})
+		return
+	}
+	if out == "missing" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "upload session not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// handleCompleteUploads starts the build once every upload session finished
+func (a *APIServer) handleCompleteUploads(c *gin.Context) {
+	name := c.Param("name")
+	t, ok := uploadTargetFromRequest(c, name)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	out, err := t.session(ctx, nil, "pending")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("listing upload sessions: %v", err)})
+		return
+	}
+	if pending, _ := strconv.Atoi(out); pending > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%d uploads are not finished", pending)})
+		return
+	}
+	if _, err := t.session(ctx, nil, "cleanup"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("removing upload sessions: %v", err)})
+		return
+	}
+	if err := markUploadsComplete(ctx, t.k8sClient, t.build); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("mark complete failed: %v", err)})
+		return
+	}
+	a.log.Info("uploads complete", "build", name, "reqID", c.GetString("reqID"))
+	writeJSON(c, http.StatusOK, map[string]string{"status": "ok"})
+}
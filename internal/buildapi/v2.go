@@ -0,0 +1,379 @@
+package buildapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+// apiVersions are the versions of the REST API this server serves, oldest first
+var apiVersions = []string{"v1", "v2"}
+
+// problemContentType is the media type of the error responses of the /v2 API
+const problemContentType = "application/problem+json"
+
+// problemTypes are the problem types of the statuses /v2 errors are answered with; other statuses
+// are about:blank, which RFC 7807 defines as the meaning of the status itself
+var problemTypes = map[int]string{
+	http.StatusBadRequest:          ProblemTypeInvalidRequest,
+	http.StatusUnauthorized:        ProblemTypeUnauthorized,
+	http.StatusForbidden:           ProblemTypeForbidden,
+	http.StatusNotFound:            ProblemTypeNotFound,
+	http.StatusConflict:            ProblemTypeConflict,
+	http.StatusGone:                ProblemTypeGone,
+	http.StatusUnprocessableEntity: ProblemTypeUnprocessable,
+	http.StatusTooManyRequests:     ProblemTypeRateLimited,
+	http.StatusInternalServerError: ProblemTypeInternal,
+	http.StatusServiceUnavailable:  ProblemTypeServiceUnavailable,
+}
+
+// registerV2 serves the /v2 API. Its routes share the handlers of /v1 where the bodies are the
+// same; problemDetails answers their errors as problem details. Builds are returned as BuildV2.
+func (a *APIServer) registerV2(router *gin.Engine) {
+	v2 := router.Group("/v2", problemDetails())
+	{
+		v2.GET("/info", a.rateLimit(), getInfo)
+
+		buildsGroup := v2.Group("/builds")
+		buildsGroup.Use(a.authMiddleware(), a.rateLimit())
+		{
+			buildsGroup.POST("", a.readOnlyGuard(), a.handleCreateBuildV2)
+			buildsGroup.GET("", a.handleListBuildsV2)
+			buildsGroup.GET("/:name", a.handleGetBuildV2)
+			buildsGroup.DELETE("/:name", a.readOnlyGuard(), a.handleDeleteBuild)
+			buildsGroup.POST("/:name/cancel", a.readOnlyGuard(), a.handleCancelBuild)
+		}
+	}
+}
+
+func (a *APIServer) handleCreateBuildV2(c *gin.Context) {
+	a.log.Info("create build", "apiVersion", "v2", "reqID", c.GetString("reqID"))
+	var req BuildRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid JSON: %v", err)})
+		return
+	}
+	build, replayed := submitBuild(c, req)
+	if build == nil {
+		return
+	}
+	if replayed {
+		writeJSON(c, http.StatusOK, buildV2(build))
+		return
+	}
+	c.Header("Location", path.Join("/v2/builds", build.Name))
+	writeJSON(c, http.StatusCreated, buildV2(build))
+}
+
+func (a *APIServer) handleListBuildsV2(c *gin.Context) {
+	a.log.Info("list builds", "apiVersion", "v2", "reqID", c.GetString("reqID"))
+	if watchRequested(c.Query("watch")) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "watch is served by GET /v1/builds?watch=true"})
+		return
+	}
+	opts, query, ok := buildListRequest(c)
+	if !ok {
+		return
+	}
+	page, ok := listBuildPage(c, opts, query)
+	if !ok {
+		return
+	}
+	resp := BuildListV2{
+		Items:           make([]BuildV2, 0, len(page.builds)),
+		TotalCount:      page.total,
+		Continue:        page.next,
+		ResourceVersion: page.resourceVersion,
+	}
+	for i := range page.builds {
+		resp.Items = append(resp.Items, buildV2(&page.builds[i]))
+	}
+	writeJSON(c, http.StatusOK, resp)
+}
+
+func (a *APIServer) handleGetBuildV2(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("get build", "apiVersion", "v2", "build", name, "reqID", c.GetString("reqID"))
+	k8sClient, err := getClientFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(c.Request.Context(), types.NamespacedName{Name: name, Namespace: requestNamespace(c)}, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("build %s not found", name)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+	writeJSON(c, http.StatusOK, buildV2(build))
+}
+
+// buildV2 converts build for the /v2 API
+func buildV2(build *automotivev1alpha1.ImageBuild) BuildV2 {
+	size := sizeStatus(build)
+	out := BuildV2{
+		Name:             build.Name,
+		Namespace:        build.Namespace,
+		Phase:            buildPhase(build.Status.Phase),
+		Message:          build.Status.Message,
+		RequestedBy:      build.Annotations["automotive.sdv.cloud.redhat.com/requested-by"],
+		RequestID:        build.Annotations[requestIDAnnotation],
+		Labels:           userMetadata(build.Labels),
+		Annotations:      userMetadata(build.Annotations),
+		Distro:           build.Spec.Distro,
+		Target:           build.Spec.Target,
+		Architecture:     build.Spec.Architecture,
+		ExportFormat:     build.Spec.ExportFormat,
+		Mode:             build.Spec.Mode,
+		Priority:         build.Spec.Priority,
+		Source:           sourceToRequest(build.Spec.Source),
+		GitCommit:        build.Status.GitCommit,
+		QueuePosition:    build.Status.QueuePosition,
+		CreatedAt:        build.CreationTimestamp.UTC().Truncate(time.Second),
+		StartedAt:        timestamp(build.Status.StartTime),
+		CompletedAt:      timestamp(build.Status.CompletionTime),
+		StageTimings:     build.Status.StageTimings,
+		ComplianceResult: complianceResult(build),
+		BootResult:       bootResult(build),
+		RootFSBytes:      size.RootFSBytes,
+		SizeBudgetBytes:  size.BudgetBytes,
+		Artifacts:        buildArtifacts(build),
+	}
+	if st := build.Status.Retention; st != nil {
+		out.ArtifactsExpireAt = timestamp(st.ExpiryTime)
+		out.ArtifactsDeletedAt = timestamp(st.DeletionTime)
+	}
+	if d := build.Status.Downloads; d != nil {
+		out.Downloads = d.Count
+		out.LastDownloadAt = timestamp(d.LastDownloadTime)
+	}
+	return out
+}
+
+// buildPhase returns the phase of the /v2 API of an ImageBuild in phase
+func buildPhase(phase string) BuildPhase {
+	if phase == "" {
+		return BuildPhasePending
+	}
+	return BuildPhase(phase)
+}
+
+// timestamp converts t for the /v2 API, nil when it is not set
+func timestamp(t *metav1.Time) *time.Time {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	ts := t.UTC().Truncate(time.Second)
+	return &ts
+}
+
+// buildArtifacts lists the files of build that can be downloaded: those of its artifact manifest
+// with their sizes and digests once GET /v1/builds/{name}/artifact/manifest read them, otherwise
+// the files its status names
+func buildArtifacts(build *automotivev1alpha1.ImageBuild) []BuildArtifact {
+	if st := build.Status.Retention; st != nil && st.DeletionTime != nil {
+		return nil
+	}
+	var files []ArtifactFile
+	if cached, ok := artifactManifests.Load(build.UID); ok {
+		files = cached.(ArtifactManifestResponse).Files
+	} else {
+		files = statusArtifactFiles(build)
+	}
+	var out []BuildArtifact
+	for _, f := range files {
+		download := "artifact"
+		if f.Kind == artifactKindPart {
+			download = "artifacts"
+		}
+		out = append(out, BuildArtifact{
+			Name:      f.Name,
+			Kind:      f.Kind,
+			Path:      path.Join("/v1/builds", build.Name, download, url.PathEscape(f.Name)),
+			MediaType: f.MediaType,
+			SizeBytes: f.SizeBytes,
+			SHA256:    f.SHA256,
+		})
+	}
+	return out
+}
+
+// statusArtifactFiles returns the files the status of build names; only the size and digest of
+// the artifact itself are known from it
+func statusArtifactFiles(build *automotivev1alpha1.ImageBuild) []ArtifactFile {
+	var files []ArtifactFile
+	add := func(kind, fileName string, size int64, sha256 string) {
+		if fileName = strings.TrimSpace(fileName); fileName == "" || strings.Contains(fileName, "/") {
+			return
+		}
+		mediaType, compression := artifactMediaType(fileName)
+		files = append(files, ArtifactFile{Name: fileName, Kind: kind, SizeBytes: size, SHA256: sha256, MediaType: mediaType, Compression: compression})
+	}
+	if build.Status.Phase == "Completed" {
+		artifact := defaultArtifactFileName(build)
+		if artifact == strings.TrimSpace(build.Status.ArtifactFileName) {
+			add(artifactKindImage, artifact, build.Status.ArtifactSizeBytes, build.Status.ArtifactSHA256)
+		} else {
+			add(artifactKindImage, artifact, 0, "")
+		}
+		if st := build.Status.Signature; st != nil && st.Phase == "Signed" {
+			add(artifactKindSignature, artifact+signatureBundleSuffix, 0, "")
+		}
+		add(artifactKindFirstBoot, build.Status.FirstBootFileName, 0, "")
+		add(artifactKindHardeningReport, build.Status.HardeningReportFileName, 0, "")
+		add(artifactKindPackages, build.Status.PackagesFileName, 0, "")
+	}
+	if st := build.Status.Compliance; st != nil {
+		add(artifactKindComplianceResult, st.ARFFileName, 0, "")
+		add(artifactKindComplianceResult, st.ReportFileName, 0, "")
+	}
+	add(artifactKindSizeReport, sizeStatus(build).ReportFileName, 0, "")
+	if build.Status.Boot != nil {
+		add(artifactKindBootLog, build.Status.Boot.ConsoleLogFileName, 0, "")
+	}
+	return files
+}
+
+// problemDetails answers the errors of the handlers after it as RFC 7807 problem details. The
+// handlers are shared with /v1 and answer errors with {"error": message}: error responses are
+// held back and rewritten once the handler returned, while other responses, including streams,
+// pass through unchanged.
+func problemDetails() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &problemWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		if w.body == nil {
+			return
+		}
+		body := problemBody(w.status, w.body.Bytes(), c.Request.URL.Path, c.GetString("reqID"))
+		header := c.Writer.Header()
+		header.Set("Content-Type", problemContentType)
+		header.Del("Content-Length")
+		c.Writer.WriteHeader(w.status)
+		_, _ = c.Writer.Write(body)
+	}
+}
+
+// problemWriter holds back the body of error responses for problemDetails and errorResponses
+type problemWriter struct {
+	gin.ResponseWriter
+	status int
+	// body is set once the status of an error response was written
+	body    *bytes.Buffer
+	written bool
+}
+
+func (w *problemWriter) WriteHeader(code int) {
+	if code >= http.StatusBadRequest && !w.ResponseWriter.Written() {
+		w.status = code
+		if w.body == nil {
+			w.body = &bytes.Buffer{}
+		}
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *problemWriter) WriteHeaderNow() {
+	if w.body != nil {
+		w.written = true
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *problemWriter) Write(b []byte) (int, error) {
+	if w.body != nil {
+		w.written = true
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *problemWriter) WriteString(s string) (int, error) {
+	if w.body != nil {
+		w.written = true
+		return w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *problemWriter) Status() int {
+	if w.body != nil {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *problemWriter) Size() int {
+	if w.body != nil {
+		return w.body.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *problemWriter) Written() bool {
+	if w.body != nil {
+		return w.written
+	}
+	return w.ResponseWriter.Written()
+}
+
+func (w *problemWriter) Flush() {
+	if w.body != nil {
+		return
+	}
+	w.ResponseWriter.Flush()
+}
+
+// problemBody converts the error response body of a /v1 handler to a problem details object.
+// The message of {"error": message} becomes the detail, other members of the body are kept as
+// extension members; bodies that are not JSON objects become the detail as text.
+func problemBody(status int, body []byte, instance, requestID string) []byte {
+	members := map[string]any{}
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		members = map[string]any{}
+		if text := strings.TrimSpace(string(body)); text != "" {
+			members["error"] = text
+		}
+	}
+	detail, _ := members["error"].(string)
+	delete(members, "error")
+
+	problemType, ok := problemTypes[status]
+	if !ok {
+		problemType = "about:blank"
+	}
+	members["type"] = problemType
+	members["title"] = http.StatusText(status)
+	members["status"] = status
+	members["instance"] = instance
+	if detail != "" {
+		members["detail"] = detail
+	}
+	if requestID != "" {
+		members["requestID"] = requestID
+	}
+	out, err := json.MarshalIndent(members, "", "    ")
+	if err != nil {
+		return []byte(fmt.Sprintf(`{"type":"about:blank","title":%q,"status":%d}`, http.StatusText(status), status))
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package buildapi
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/tasks"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/version"
+)
+
+// servedVersionsTTL is how long the versions of the ImageBuild API found by discovery are reused;
+// /v1/info is requested by every caib command
+const servedVersionsTTL = time.Minute
+
+var servedVersionsCache struct {
+	mu       sync.Mutex
+	versions []string
+	expiry   time.Time
+}
+
+// componentVersions returns the versions /v1/info reports. The versions the cluster serves are
+// left out when discovery fails, since /v1/info has to answer without the Kubernetes API.
+func componentVersions(ctx context.Context) *VersionInfo {
+	info := &VersionInfo{
+		BuildAPI:     version.Version,
+		Operator:     os.Getenv("BUILD_API_OPERATOR_VERSION"),
+		APIVersion:   automotivev1alpha1.GroupVersion.String(),
+		BuilderImage: tasks.AutomotiveImageBuilder,
+	}
+	servedVersionsCache.mu.Lock()
+	defer servedVersionsCache.mu.Unlock()
+	if time.Now().After(servedVersionsCache.expiry) {
+		versions, err := discoverServedVersions(ctx)
+		if err != nil {
+			return info
+		}
+		servedVersionsCache.versions = versions
+		servedVersionsCache.expiry = time.Now().Add(servedVersionsTTL)
+	}
+	info.ServedVersions = servedVersionsCache.versions
+	return info
+}
+
+// discoverServedVersions asks the Kubernetes API which versions of the ImageBuild API group the
+// installed CRDs serve
+func discoverServedVersions(ctx context.Context) ([]string, error) {
+	cfg, err := serviceRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Timeout = 5 * time.Second
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := clientset.Discovery().RESTClient().Get().
+		AbsPath("/apis", automotivev1alpha1.GroupVersion.Group).Do(ctx).Raw()
+	if err != nil {
+		return nil, err
+	}
+	var group metav1.APIGroup
+	if err := json.Unmarshal(raw, &group); err != nil {
+		return nil, err
+	}
+	return servedVersions(group), nil
+}
+
+// servedVersions lists the versions of group, the preferred version first
+func servedVersions(group metav1.APIGroup) []string {
+	versions := []string{}
+	if group.PreferredVersion.Version != "" {
+		versions = append(versions, group.PreferredVersion.Version)
+	}
+	for _, v := range group.Versions {
+		if v.Version != group.PreferredVersion.Version {
+			versions = append(versions, v.Version)
+		}
+	}
+	return versions
+}
@@ -0,0 +1,489 @@
+package buildapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+const (
+	// buildWatchPoll is how often GET /watch reads the build for callers that may not watch builds
+	buildWatchPoll = 5 * time.Second
+	// buildWatchRetry is the wait before a watch the API server ended is started again
+	buildWatchRetry = time.Second
+)
+
+func (a *APIServer) handleWatchBuild(c *gin.Context) {
+	name := c.Param("name")
+	a.log.Info("watch build", "build", name, "reqID", c.GetString("reqID"))
+	watchBuild(c, name)
+}
+
+// buildStatusChanged reports whether a status event is due for next after prev was sent
+func buildStatusChanged(prev *BuildResponse, next BuildResponse) bool {
+	return prev == nil || prev.Phase != next.Phase || prev.Message != next.Message
+}
+
+// watchBuild streams the status of a build as server-sent events: a status event, holding the
+// build as GET /v1/builds/{name} returns it, when the stream starts and whenever its phase or
+// message change, then an end event with the final status once the build finished, or a deleted
+// event. Heartbeat comments keep idle connections open. The build is watched as the build API's
+// service account, the route having checked that the caller may get it, or as the user an
+// impersonating request names; users that may get but not watch builds are served by reading
+// the build every few seconds instead.
+func watchBuild(c *gin.Context, name string) {
+	namespace := requestNamespace(c)
+	cfg, err := getRESTConfigFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	k8sClient, err := newK8sWatchClient(cfg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	ctx := c.Request.Context()
+	key := types.NamespacedName{Name: name, Namespace: namespace}
+	build := &automotivev1alpha1.ImageBuild{}
+	if err := k8sClient.Get(ctx, key, build); err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+		return
+	}
+
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
+	c.Writer.WriteHeader(http.StatusOK)
+
+	sse := &sseWriter{w: c.Writer}
+	if sse.comment("connected") != nil {
+		return
+	}
+	defer sse.heartbeat(ctx)()
+
+	var last *BuildResponse
+	// send sends the status of b when it changed and reports whether the stream is over
+	send := func(b *automotivev1alpha1.ImageBuild) bool {
+		resp := buildResponse(b)
+		finished := isFinishedPhase(resp.Phase)
+		if !buildStatusChanged(last, resp) && !finished {
+			return false
+		}
+		event := "status"
+		if finished {
+			event = "end"
+		}
+		last = &resp
+		return sse.event(event, b.ResourceVersion, resp) != nil || finished
+	}
+	// refresh reads the build again, for changes made while it was not watched, and reports
+	// whether the stream is over
+	refresh := func() bool {
+		if err := k8sClient.Get(ctx, key, build); err != nil {
+			if ctx.Err() != nil {
+				return true
+			}
+			if k8serrors.IsNotFound(err) {
+				_ = sse.event("deleted", "", last)
+			} else {
+				_ = sse.event("error", "", gin.H{"error": fmt.Sprintf("error fetching build: %v", err)})
+			}
+			return true
+		}
+		return send(build)
+	}
+	sleep := func(d time.Duration) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(d):
+			return true
+		}
+	}
+
+	if send(build) {
+		return
+	}
+	resourceVersion := build.ResourceVersion
+	for {
+		w, err := k8sClient.Watch(ctx, &automotivev1alpha1.ImageBuildList{},
+			&client.ListOptions{Raw: &metav1.ListOptions{ResourceVersion: resourceVersion}},
+			client.InNamespace(namespace), client.MatchingFields{"metadata.name": name})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if !k8serrors.IsForbidden(err) {
+				_ = sse.event("error", "", gin.H{"error": fmt.Sprintf("error watching build: %v", err)})
+				return
+			}
+			for sleep(buildWatchPoll) {
+				if refresh() {
+					return
+				}
+			}
+			return
+		}
+		for ev := range w.ResultChan() {
+			switch ev.Type {
+			case watch.Added, watch.Modified:
+				b, ok := ev.Object.(*automotivev1alpha1.ImageBuild)
+				if !ok {
+					continue
+				}
+				resourceVersion = b.ResourceVersion
+				if send(b) {
+					w.Stop()
+					return
+				}
+			case watch.Deleted:
+				_ = sse.event("deleted", "", last)
+				w.Stop()
+				return
+			case watch.Error:
+				// e.g. the resource version expired; the build is read again below
+				resourceVersion = ""
+			}
+		}
+		w.Stop()
+		if ctx.Err() != nil || refresh() || !sleep(buildWatchRetry) {
+			return
+		}
+		resourceVersion = build.ResourceVersion
+	}
+}
+
+// Types of the events of a build list watch, as Kubernetes names its watch events
+const (
+	buildWatchAdded    = "ADDED"
+	buildWatchModified = "MODIFIED"
+	buildWatchDeleted  = "DELETED"
+	buildWatchBookmark = "BOOKMARK"
+	buildWatchError    = "ERROR"
+)
+
+// buildListWatchBookmark is how often a list watch sends a BOOKMARK event, so proxies do not
+// close idle connections and clients always hold a recent resource version to resume from
+const buildListWatchBookmark = 15 * time.Second
+
+// watchRequested reports whether the watch parameter of GET /v1/builds asks for a watch
+func watchRequested(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "true":
+		return true
+	}
+	return false
+}
+
+// parseWatchTimeout reads the timeoutSeconds parameter of a list watch; zero watches until the
+// client disconnects
+func parseWatchTimeout(s string) (time.Duration, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid timeoutSeconds %q: must be a positive number", s)
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+// knownBuild is a build a list watch told its client about
+type knownBuild struct {
+	resourceVersion string
+	item            BuildListItem
+}
+
+// buildWatch translates the changes of builds into the events of a list watch, keeping track of
+// the builds the client knows so builds that stop matching the filters, e.g. a phase, leave the
+// list with a DELETED event and changes that do not touch a build send nothing
+type buildWatch struct {
+	query *buildListQuery
+	// known holds the builds the client knows by namespace/name
+	known map[string]knownBuild
+}
+
+func newBuildWatch(query *buildListQuery) *buildWatch {
+	return &buildWatch{query: query, known: map[string]knownBuild{}}
+}
+
+// seed records the matching builds as known without events, for a client that listed them
+func (w *buildWatch) seed(builds []automotivev1alpha1.ImageBuild) {
+	for i := range builds {
+		if b := &builds[i]; w.query.matches(b) {
+			w.known[b.Namespace+"/"+b.Name] = knownBuild{resourceVersion: b.ResourceVersion, item: buildListItem(b)}
+		}
+	}
+}
+
+// update returns the event for the current state of b, nil when the client need not know
+func (w *buildWatch) update(b *automotivev1alpha1.ImageBuild) *BuildWatchEvent {
+	key := b.Namespace + "/" + b.Name
+	prev, known := w.known[key]
+	if known && prev.resourceVersion == b.ResourceVersion {
+		return nil
+	}
+	matches := w.query.matches(b)
+	if !matches && !known {
+		return nil
+	}
+	item := buildListItem(b)
+	if !matches {
+		delete(w.known, key)
+		return &BuildWatchEvent{Type: buildWatchDeleted, Object: &item, ResourceVersion: b.ResourceVersion}
+	}
+	w.known[key] = knownBuild{resourceVersion: b.ResourceVersion, item: item}
+	eventType := buildWatchAdded
+	if known {
+		eventType = buildWatchModified
+	}
+	return &BuildWatchEvent{Type: eventType, Object: &item, ResourceVersion: b.ResourceVersion}
+}
+
+// remove returns the event for the deletion of b, nil when the client did not know it
+func (w *buildWatch) remove(b *automotivev1alpha1.ImageBuild) *BuildWatchEvent {
+	key := b.Namespace + "/" + b.Name
+	if _, known := w.known[key]; !known {
+		return nil
+	}
+	delete(w.known, key)
+	item := buildListItem(b)
+	return &BuildWatchEvent{Type: buildWatchDeleted, Object: &item, ResourceVersion: b.ResourceVersion}
+}
+
+// sync returns the events that bring the client from the builds it knows to builds, a complete
+// list: the changed builds oldest first, then the deletions
+func (w *buildWatch) sync(builds []automotivev1alpha1.ImageBuild, resourceVersion string) []BuildWatchEvent {
+	sorted := make([]*automotivev1alpha1.ImageBuild, 0, len(builds))
+	present := make(map[string]bool, len(builds))
+	for i := range builds {
+		sorted = append(sorted, &builds[i])
+		present[builds[i].Namespace+"/"+builds[i].Name] = true
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ti, tj := sorted[i].CreationTimestamp.Time, sorted[j].CreationTimestamp.Time
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return sorted[i].Namespace+"/"+sorted[i].Name < sorted[j].Namespace+"/"+sorted[j].Name
+	})
+	var events []BuildWatchEvent
+	for _, b := range sorted {
+		if ev := w.update(b); ev != nil {
+			events = append(events, *ev)
+		}
+	}
+	var gone []string
+	for key := range w.known {
+		if !present[key] {
+			gone = append(gone, key)
+		}
+	}
+	sort.Strings(gone)
+	for _, key := range gone {
+		item := w.known[key].item
+		delete(w.known, key)
+		events = append(events, BuildWatchEvent{Type: buildWatchDeleted, Object: &item, ResourceVersion: resourceVersion})
+	}
+	return events
+}
+
+// watchBuilds streams the changes of the builds GET /v1/builds lists as BuildWatchEvents, one
+// JSON object per line, the way Kubernetes watches do. Without resourceVersion the stream starts
+// with an ADDED event for every build matching the filters; with the resource version of a list
+// or event it starts with the changes made after it, and answers 410 Gone when that version is
+// too old to resume from, so the client lists again. A BOOKMARK event follows the initial events
+// and is repeated while nothing changes. timeoutSeconds ends the stream, for long-polling
+// clients. The builds are watched as the build API's service account, the route having checked
+// that the caller may list them, or as the user an impersonating request names; users that may list
+// but not watch builds are served by listing every few seconds instead.
+func watchBuilds(c *gin.Context, opts []client.ListOption, query *buildListQuery) {
+	if query.after != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "continue cannot be combined with watch"})
+		return
+	}
+	timeout, err := parseWatchTimeout(c.Query("timeoutSeconds"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resourceVersion := strings.TrimSpace(c.Query("resourceVersion"))
+	cfg, err := getRESTConfigFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	k8sClient, err := newK8sWatchClient(cfg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("k8s client error: %v", err)})
+		return
+	}
+	ctx := c.Request.Context()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	withVersion := func(raw *metav1.ListOptions) []client.ListOption {
+		return append(slices.Clone(opts), &client.ListOptions{Raw: raw})
+	}
+
+	bw := newBuildWatch(query)
+	list := &automotivev1alpha1.ImageBuildList{}
+	listOpts := opts
+	if resourceVersion != "" {
+		// the client knows the builds as they were at resourceVersion
+		listOpts = withVersion(&metav1.ListOptions{ResourceVersion: resourceVersion, ResourceVersionMatch: metav1.ResourceVersionMatchExact})
+	}
+	if err := k8sClient.List(ctx, list, listOpts...); err != nil {
+		switch {
+		case k8serrors.IsResourceExpired(err) || k8serrors.IsGone(err):
+			c.JSON(http.StatusGone, gin.H{"error": fmt.Sprintf("resourceVersion %s is too old to resume from; list the builds again", resourceVersion)})
+		case k8serrors.IsBadRequest(err):
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid resourceVersion %q", resourceVersion)})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("error listing builds: %v", err)})
+		}
+		return
+	}
+
+	c.Writer.Header().Set("Content-Type", "application/json;stream=watch")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
+	c.Writer.WriteHeader(http.StatusOK)
+
+	send := func(events ...BuildWatchEvent) bool {
+		for _, ev := range events {
+			b, err := json.Marshal(ev)
+			if err != nil {
+				return false
+			}
+			if _, err := c.Writer.Write(append(b, '\n')); err != nil {
+				return false
+			}
+		}
+		c.Writer.Flush()
+		return true
+	}
+	bookmark := func() BuildWatchEvent {
+		return BuildWatchEvent{Type: buildWatchBookmark, ResourceVersion: resourceVersion}
+	}
+	// relist sends the changes missed while the builds were not watched and reports whether the
+	// stream goes on
+	relist := func() bool {
+		list := &automotivev1alpha1.ImageBuildList{}
+		if err := k8sClient.List(ctx, list, opts...); err != nil {
+			if ctx.Err() == nil {
+				send(BuildWatchEvent{Type: buildWatchError, Code: http.StatusInternalServerError, Error: fmt.Sprintf("error listing builds: %v", err)})
+			}
+			return false
+		}
+		resourceVersion = list.ResourceVersion
+		return send(append(bw.sync(list.Items, resourceVersion), bookmark())...)
+	}
+	sleep := func(d time.Duration) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(d):
+			return true
+		}
+	}
+
+	var initial []BuildWatchEvent
+	if resourceVersion == "" {
+		resourceVersion = list.ResourceVersion
+		initial = bw.sync(list.Items, resourceVersion)
+	} else {
+		bw.seed(list.Items)
+	}
+	if !send(append(initial, bookmark())...) {
+		return
+	}
+	ticker := time.NewTicker(buildListWatchBookmark)
+	defer ticker.Stop()
+	for {
+		w, err := k8sClient.Watch(ctx, &automotivev1alpha1.ImageBuildList{},
+			withVersion(&metav1.ListOptions{ResourceVersion: resourceVersion, AllowWatchBookmarks: true})...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if !k8serrors.IsForbidden(err) {
+				send(BuildWatchEvent{Type: buildWatchError, Code: http.StatusInternalServerError, Error: fmt.Sprintf("error watching builds: %v", err)})
+				return
+			}
+			for sleep(buildWatchPoll) {
+				if !relist() {
+					return
+				}
+			}
+			return
+		}
+		expired := false
+	events:
+		for {
+			select {
+			case <-ctx.Done():
+				w.Stop()
+				return
+			case <-ticker.C:
+				if !send(bookmark()) {
+					w.Stop()
+					return
+				}
+			case ev, ok := <-w.ResultChan():
+				if !ok {
+					break events
+				}
+				b, isBuild := ev.Object.(*automotivev1alpha1.ImageBuild)
+				var out *BuildWatchEvent
+				switch {
+				case ev.Type == watch.Error:
+					// e.g. the resource version expired; the changes are found by listing again
+					expired = true
+					break events
+				case !isBuild:
+					continue
+				case ev.Type == watch.Deleted:
+					out = bw.remove(b)
+				case ev.Type == watch.Added || ev.Type == watch.Modified:
+					out = bw.update(b)
+				}
+				resourceVersion = b.ResourceVersion
+				if out != nil && !send(*out) {
+					w.Stop()
+					return
+				}
+			}
+		}
+		w.Stop()
+		if expired && !relist() {
+			return
+		}
+		if !sleep(buildWatchRetry) {
+			return
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Package comparison finds the golden build of a branch, the latest completed build made for a
+// commit of that branch, and reads what builds measured about their image, so builds of pull and
+// merge requests can be compared with the image of the branch they target.
+package comparison
+
+import (
+	"strings"
+	"time"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/commitstatus"
+)
+
+// Context is the name of the commit status of build, automotive/<target>-<architecture> unless
+// spec.commitStatus.context names it
+func Context(build *automotivev1alpha1.ImageBuild) string {
+	if cs := build.Spec.CommitStatus; cs != nil && cs.Context != "" {
+		return cs.Context
+	}
+	return "automotive/" + build.Spec.Target + "-" + build.Spec.Architecture
+}
+
+// DefaultContextPrefix starts the contexts of commit statuses when the OperatorConfig sets none
+const DefaultContextPrefix = "automotive/"
+
+// StatusContext is the context the status of build is posted under: Context after the contextPrefix
+// of cfg, unless it starts with the prefix already, so builds cannot post under the contexts of
+// other CI systems
+func StatusContext(cfg *automotivev1alpha1.CommitStatusConfig, build *automotivev1alpha1.ImageBuild) string {
+	prefix := DefaultContextPrefix
+	if cfg != nil && cfg.ContextPrefix != "" {
+		prefix = cfg.ContextPrefix
+	}
+	context := Context(build)
+	if strings.HasPrefix(context, prefix) {
+		return context
+	}
+	return prefix + context
+}
+
+// RepositoryGranted reports whether cfg grants the builds of namespace commit statuses on
+// repository. Repositories compare case-insensitively, as GitHub and GitLab paths do.
+func RepositoryGranted(cfg *automotivev1alpha1.CommitStatusConfig, namespace, repository string) bool {
+	if cfg == nil {
+		return false
+	}
+	repository = strings.ToLower(repository)
+	for _, grant := range cfg.Repositories {
+		if grant.Namespace != "*" && grant.Namespace != namespace {
+			continue
+		}
+		for _, pattern := range grant.Repositories {
+			pattern = strings.ToLower(strings.Trim(pattern, "/"))
+			if owner, ok := strings.CutSuffix(pattern, "/*"); ok {
+				if strings.HasPrefix(repository, owner+"/") {
+					return true
+				}
+			} else if pattern == repository {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GoldenBuild returns the golden build of branch for build among builds: the latest completed
+// build of branch of the same repository and commit status context, for the same distro, target,
+// architecture and export format. Nil when there is none.
+func GoldenBuild(build *automotivev1alpha1.ImageBuild, branch string, builds []automotivev1alpha1.ImageBuild) *automotivev1alpha1.ImageBuild {
+	cs := build.Spec.CommitStatus
+	if cs == nil || branch == "" {
+		return nil
+	}
+	var golden *automotivev1alpha1.ImageBuild
+	for i := range builds {
+		candidate := &builds[i]
+		other := candidate.Spec.CommitStatus
+		switch {
+		case candidate.UID == build.UID && candidate.Name == build.Name,
+			other == nil || other.Branch != branch,
+			other.Provider != cs.Provider || other.Repository != cs.Repository,
+			Context(candidate) != Context(build),
+			candidate.Spec.Distro != build.Spec.Distro,
+			candidate.Spec.Target != build.Spec.Target,
+			candidate.Spec.Architecture != build.Spec.Architecture,
+			candidate.Spec.ExportFormat != build.Spec.ExportFormat,
+			candidate.Status.Phase != "Completed",
+			candidate.Status.CompletionTime == nil:
+			continue
+		}
+		if golden == nil || candidate.Status.CompletionTime.After(golden.Status.CompletionTime.Time) {
+			golden = candidate
+		}
+	}
+	return golden
+}
+
+// Measure returns what build measured about its image
+func Measure(build *automotivev1alpha1.ImageBuild) commitstatus.Measurements {
+	m := commitstatus.Measurements{ArtifactBytes: build.Status.ArtifactSizeBytes}
+	if size := build.Status.Size; size != nil {
+		m.RootFSBytes = size.RootFSBytes
+	}
+	if boot := build.Status.Boot; boot != nil && boot.Result == "booted" {
+		if total, err := time.ParseDuration(boot.Timings["total"]); err == nil {
+			m.Boot = total
+			m.BootAccelerator = boot.Accelerator
+		}
+	}
+	return m
+}
@@ -0,0 +1,126 @@
+// Package firstboot validates first-boot provisioning payloads (ignition, cloud-init, combustion)
+// and describes how the build step embeds or attaches them.
+package firstboot
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	FormatIgnition   = "ignition"
+	FormatCloudInit  = "cloud-init"
+	FormatCombustion = "combustion"
+
+	// ModeAttach publishes the payload next to the image as a secondary artifact
+	ModeAttach = "attach"
+	// ModeEmbed writes the payload into the image filesystem
+	ModeEmbed = "embed"
+
+	// ConfigMapKey is the manifest ConfigMap key the payload is copied to for the build step
+	ConfigMapKey = "firstboot-payload"
+
+	// MaxPayloadSize keeps payloads well below the ConfigMap size limit
+	MaxPayloadSize = 512 * 1024
+)
+
+// embedPaths is where each format is placed inside the image when embedded
+var embedPaths = map[string]string{
+	FormatIgnition:  "/usr/lib/ignition/user.ign",
+	FormatCloudInit: "/etc/cloud/cloud.cfg.d/99-caib-firstboot.cfg",
+}
+
+// artifactNames is the file name of the payload when attached as a secondary artifact
+var artifactNames = map[string]string{
+	FormatIgnition:   "firstboot.ign",
+	FormatCloudInit:  "firstboot-user-data",
+	FormatCombustion: "firstboot-combustion",
+}
+
+// EmbedPath returns the in-image path for an embedded payload of format
+func EmbedPath(format string) string {
+	return embedPaths[format]
+}
+
+// ArtifactName returns the secondary artifact file name for an attached payload of format
+func ArtifactName(format string) string {
+	return artifactNames[format]
+}
+
+// supportsCombustion reports whether distro is SUSE-based; combustion is not shipped elsewhere
+func supportsCombustion(distro string) bool {
+	d := strings.ToLower(distro)
+	return strings.HasPrefix(d, "opensuse") || strings.HasPrefix(d, "sle") || strings.HasPrefix(d, "suse")
+}
+
+// ValidateSpec checks that format and mode are known and usable for distro
+func ValidateSpec(distro, format, mode string) error {
+	if _, ok := artifactNames[format]; !ok {
+		return fmt.Errorf("unsupported firstBoot format %q: must be ignition, cloud-init or combustion", format)
+	}
+	switch mode {
+	case "", ModeAttach:
+	case ModeEmbed:
+		if _, ok := embedPaths[format]; !ok {
+			return fmt.Errorf("firstBoot format %s can only be attached, not embedded", format)
+		}
+	default:
+		return fmt.Errorf("unsupported firstBoot mode %q: must be attach or embed", mode)
+	}
+	if format == FormatCombustion && !supportsCombustion(distro) {
+		return fmt.Errorf("combustion is not available for distro %s", distro)
+	}
+	return nil
+}
+
+// Validate checks the spec with ValidateSpec and then parses payload according to format
+func Validate(distro, format, mode, payload string) error {
+	if err := ValidateSpec(distro, format, mode); err != nil {
+		return err
+	}
+	if strings.TrimSpace(payload) == "" {
+		return fmt.Errorf("firstBoot payload is empty")
+	}
+	if len(payload) > MaxPayloadSize {
+		return fmt.Errorf("firstBoot payload exceeds %d bytes", MaxPayloadSize)
+	}
+
+	switch format {
+	case FormatIgnition:
+		var cfg struct {
+			Ignition struct {
+				Version string `json:"version"`
+			} `json:"ignition"`
+		}
+		if err := json.Unmarshal([]byte(payload), &cfg); err != nil {
+			return fmt.Errorf("invalid ignition config: %w", err)
+		}
+		if cfg.Ignition.Version == "" {
+			return fmt.Errorf("invalid ignition config: ignition.version is required")
+		}
+	case FormatCloudInit:
+		first, _, _ := strings.Cut(strings.TrimLeft(payload, " \t\r\n"), "\n")
+		first = strings.TrimSpace(first)
+		switch {
+		case first == "#cloud-config":
+			var doc map[string]any
+			if err := yaml.Unmarshal([]byte(payload), &doc); err != nil {
+				return fmt.Errorf("invalid cloud-config: %w", err)
+			}
+		case strings.HasPrefix(first, "#!"):
+			if mode == ModeEmbed {
+				return fmt.Errorf("cloud-init scripts can only be attached; embed requires #cloud-config")
+			}
+		default:
+			return fmt.Errorf("cloud-init user-data must start with #cloud-config or #!")
+		}
+	case FormatCombustion:
+		if !strings.HasPrefix(strings.TrimLeft(payload, " \t\r\n"), "#!") {
+			return fmt.Errorf("combustion script must start with #!")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+// Package hardening defines the image hardening profiles a build can select and renders the
+// manifest fragment and compliance report that record their application.
+package hardening
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// FragmentKey is the manifest ConfigMap key holding the merged manifest fragment of all selected profiles.
+	// It must not end in .aib.yml/.mpp.yml so find-manifest does not pick it up as the manifest.
+	FragmentKey = "hardening-fragment.yaml"
+	// ReportKey is the manifest ConfigMap key holding the compliance report template
+	ReportKey = "hardening-report.json"
+	// ReportArtifactName is the file name of the compliance report published next to the image
+	ReportArtifactName = "hardening-report.json"
+)
+
+// Control is a single hardening measure applied by a profile
+type Control struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+}
+
+// Profile is a named set of controls and the manifest fragment implementing them
+type Profile struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Controls    []Control `json:"controls"`
+	// fragment is deep-merged into the build manifest; lists are appended
+	fragment map[string]any
+}
+
+var profiles = map[string]Profile{
+	"minimal-services": {
+		Name:        "minimal-services",
+		Description: "Disable network-facing and convenience services not needed on a vehicle ECU",
+		Controls: []Control{
+			{ID: "MS-1", Description: "avahi-daemon, cups, bluetooth and rpcbind are disabled"},
+			{ID: "MS-2", Description: "debug-shell and serial getty are masked"},
+		},
+		fragment: map[string]any{
+			"content": map[string]any{
+				"systemd": map[string]any{
+					"disabled_services": []any{"avahi-daemon.service", "cups.service", "bluetooth.service", "rpcbind.service", "debug-shell.service", "serial-getty@.service"},
+				},
+			},
+		},
+	},
+	"readonly-rootfs": {
+		Name:        "readonly-rootfs",
+		Description: "Mount the root filesystem read-only and keep mutable state in /var",
+		Controls: []Control{
+			{ID: "RO-1", Description: "root filesystem is mounted read-only (kernel argument ro)"},
+			{ID: "RO-2", Description: "SELinux runs in enforcing mode"},
+		},
+		fragment: map[string]any{
+			"image": map[string]any{
+				"selinux_mode": "enforcing",
+			},
+			"kernel": map[string]any{
+				"cmdline": []any{"ro"},
+			},
+		},
+	},
+	"audit": {
+		Name:        "audit",
+		Description: "Install auditd with rules for identity, privilege and module changes",
+		Controls: []Control{
+			{ID: "AU-1", Description: "audit package is installed and auditd is enabled"},
+			{ID: "AU-2", Description: "audit rules watch identity files, sudoers and kernel module loading"},
+		},
+		fragment: map[string]any{
+			"content": map[string]any{
+				"rpms": []any{"audit"},
+				"systemd": map[string]any{
+					"enabled_services": []any{"auditd.service"},
+				},
+				"add_files": []any{
+					map[string]any{
+						"path": "/etc/audit/rules.d/90-caib-hardening.rules",
+						"text": "-w /etc/passwd -p wa -k identity\n" +
+							"-w /etc/group -p wa -k identity\n" +
+							"-w /etc/shadow -p wa -k identity\n" +
+							"-w /etc/sudoers -p wa -k privilege\n" +
+							"-w /etc/sudoers.d/ -p wa -k privilege\n" +
+							"-a always,exit -F arch=b64 -S init_module,finit_module,delete_module -k modules\n",
+					},
+				},
+			},
+		},
+	},
+	"kernel-sysctl": {
+		Name:        "kernel-sysctl",
+		Description: "Restrict kernel information leaks and unprivileged interfaces via sysctl",
+		Controls: []Control{
+			{ID: "KS-1", Description: "kernel pointers and dmesg are hidden from unprivileged users"},
+			{ID: "KS-2", Description: "unprivileged BPF and user namespaces are disabled"},
+		},
+		fragment: map[string]any{
+			"content": map[string]any{
+				"add_files": []any{
+					map[string]any{
+						"path": "/etc/sysctl.d/90-caib-hardening.conf",
+						"text": "kernel.kptr_restrict = 2\n" +
+							"kernel.dmesg_restrict = 1\n" +
+							"kernel.unprivileged_bpf_disabled = 1\n" +
+							"user.max_user_namespaces = 0\n",
+					},
+				},
+			},
+		},
+	},
+}
+
+// Profiles returns all known profiles sorted by name
+func Profiles() []Profile {
+	out := make([]Profile, 0, len(profiles))
+	for _, p := range profiles {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Resolve returns the profiles for names in order, rejecting unknown and duplicate names
+func Resolve(names []string) ([]Profile, error) {
+	seen := map[string]bool{}
+	out := make([]Profile, 0, len(names))
+	for _, name := range names {
+		p, ok := profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown hardening profile %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("hardening profile %q selected more than once", name)
+		}
+		seen[name] = true
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// Fragment merges the manifest fragments of selected into one YAML document
+func Fragment(selected []Profile) (string, error) {
+	merged := map[string]any{}
+	for _, p := range selected {
+		mergeInto(merged, p.fragment)
+	}
+	b, err := yaml.Marshal(merged)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// mergeInto deep-merges src into dst, appending lists the same way find-manifest applies the fragment
+func mergeInto(dst, src map[string]any) {
+	for k, v := range src {
+		switch sv := v.(type) {
+		case map[string]any:
+			dv, ok := dst[k].(map[string]any)
+			if !ok {
+				dv = map[string]any{}
+				dst[k] = dv
+			}
+			mergeInto(dv, sv)
+		case []any:
+			dv, _ := dst[k].([]any)
+			dst[k] = append(append([]any{}, dv...), sv...)
+		default:
+			dst[k] = v
+		}
+	}
+}
+
+// Report is the compliance report published with the image. The build step fills in
+// ManifestSHA256, Artifact and CompletedAt once the image is built.
+type Report struct {
+	Build          string    `json:"build"`
+	Namespace      string    `json:"namespace"`
+	Distro         string    `json:"distro"`
+	Target         string    `json:"target"`
+	Architecture   string    `json:"architecture"`
+	Profiles       []Profile `json:"profiles"`
+	ManifestSHA256 string    `json:"manifestSHA256,omitempty"`
+	Artifact       string    `json:"artifact,omitempty"`
+	CompletedAt    string    `json:"completedAt,omitempty"`
+}
+
+// NewReport renders the report template for a build applying selected
+func NewReport(build, namespace, distro, target, arch string, selected []Profile) (string, error) {
+	b, err := json.MarshalIndent(Report{
+		Build:        build,
+		Namespace:    namespace,
+		Distro:       distro,
+		Target:       target,
+		Architecture: arch,
+		Profiles:     selected,
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,58 @@
+// Package remotefiles decides which add_files URLs builds may have the upload pod download, and
+// where in the workspace the downloads are stored for the build to find them.
+package remotefiles
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Dir is the directory of the workspace the upload pod stores downloads in; find_manifest.sh points
+// the source_path of URLs there
+const Dir = "remote-files"
+
+// Path is where the download of rawURL is stored, relative to the workspace: its host and path
+// under Dir, without query or fragment, so https://host/a.bin is remote-files/host/a.bin
+func Path(rawURL string) string {
+	_, rest, _ := strings.Cut(rawURL, "://")
+	if i := strings.IndexAny(rest, "?#"); i >= 0 {
+		rest = rest[:i]
+	}
+	return path.Join(Dir, rest)
+}
+
+// CheckURL rejects rawURL unless its host, the bucket of s3 URLs, is one of hosts, see CheckHost
+func CheckURL(hosts []string, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	return CheckHost(hosts, u.Hostname())
+}
+
+// CheckHost rejects host unless it is one of hosts, where "*.example.com" stands for the subdomains
+// of example.com. Loopback, link-local and unspecified addresses, which reach the pod itself, its
+// node or the metadata service of the cloud, are rejected whatever hosts says.
+func CheckHost(hosts []string, host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return fmt.Errorf("URL has no host")
+	}
+	if ip := net.ParseIP(host); host == "localhost" || ip != nil && (ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()) {
+		return fmt.Errorf("downloads from %s are not allowed", host)
+	}
+	for _, allowed := range hosts {
+		allowed = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(allowed), "."))
+		if domain, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return nil
+			}
+		} else if host == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %s is not in spec.osBuilds.remoteFileHosts of the OperatorConfig", host)
+}
@@ -0,0 +1,217 @@
+// Package storage works out which access modes a storage class offers the workspace PVC of a
+// build. Kubernetes does not publish the access modes of a provisioner, so they are taken from
+// an annotation on the StorageClass when present and otherwise from the provisioners known to
+// support ReadWriteMany. It also reads the capacity CSI drivers with storage capacity tracking
+// publish for a class.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// AccessModesAnnotation lists the access modes of a StorageClass, comma separated, e.g.
+	// "ReadWriteOnce,ReadWriteMany". It overrides the detection from the provisioner.
+	AccessModesAnnotation = "automotive.sdv.cloud.redhat.com/access-modes"
+
+	// EncryptedAnnotation is "true" on a StorageClass whose volumes are encrypted at rest and "false"
+	// on one that is not. It overrides the detection from the class parameters.
+	EncryptedAnnotation = "automotive.sdv.cloud.redhat.com/encrypted"
+
+	defaultClassAnnotation     = "storageclass.kubernetes.io/is-default-class"
+	betaDefaultClassAnnotation = "storageclass.beta.kubernetes.io/is-default-class"
+
+	// ReasonClassNotFound and ReasonAccessModeUnsupported are the reasons of an UnsupportedError
+	ReasonClassNotFound         = "StorageClassNotFound"
+	ReasonAccessModeUnsupported = "AccessModeUnsupported"
+	ReasonNotEncrypted          = "EncryptionUnsupported"
+)
+
+// UnsupportedError reports a workspace the storage class can never provide
+type UnsupportedError struct {
+	Reason  string
+	Message string
+}
+
+func (e *UnsupportedError) Error() string {
+	return e.Message
+}
+
+// readWriteManyProvisioners are the provisioners of shared file systems, whose volumes can be
+// mounted on several nodes at once
+var readWriteManyProvisioners = map[string]bool{
+	"cephfs.csi.ceph.com":                         true,
+	"openshift-storage.cephfs.csi.ceph.com":       true,
+	"nfs.csi.k8s.io":                              true,
+	"k8s-sigs.io/nfs-subdir-external-provisioner": true,
+	"efs.csi.aws.com":                             true,
+	"file.csi.azure.com":                          true,
+	"kubernetes.io/azure-file":                    true,
+	"filestore.csi.storage.gke.io":                true,
+	"kubernetes.io/glusterfs":                     true,
+	"spectrumscale.csi.ibm.com":                   true,
+	"vpc.file.csi.ibm.io":                         true,
+}
+
+// encryptionKeyParameters are StorageClass parameters selecting the key provisioners encrypt
+// volumes with, e.g. on GKE and Azure; encryption is on whenever they are set
+var encryptionKeyParameters = []string{"disk-encryption-kms-key", "diskEncryptionSetID"}
+
+// Class returns the StorageClass named name, or the default class when name is empty. It returns
+// nil without error when name is empty and the cluster has no default class, and an
+// UnsupportedError when the named class does not exist.
+func Class(ctx context.Context, c client.Reader, name string) (*storagev1.StorageClass, error) {
+	if name != "" {
+		sc := &storagev1.StorageClass{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name}, sc); err != nil {
+			if k8serrors.IsNotFound(err) {
+				return nil, &UnsupportedError{ReasonClassNotFound, fmt.Sprintf("storage class %s does not exist", name)}
+			}
+			return nil, fmt.Errorf("reading storage class %s: %w", name, err)
+		}
+		return sc, nil
+	}
+	list := &storagev1.StorageClassList{}
+	if err := c.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("listing storage classes: %w", err)
+	}
+	for i := range list.Items {
+		if IsDefault(&list.Items[i]) {
+			return &list.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// IsDefault reports whether sc is the class of PVCs that do not name one
+func IsDefault(sc *storagev1.StorageClass) bool {
+	return sc.Annotations[defaultClassAnnotation] == "true" || sc.Annotations[betaDefaultClassAnnotation] == "true"
+}
+
+// AccessModes returns the access modes volumes of sc can be mounted with
+func AccessModes(sc *storagev1.StorageClass) []corev1.PersistentVolumeAccessMode {
+	if value, ok := sc.Annotations[AccessModesAnnotation]; ok {
+		var modes []corev1.PersistentVolumeAccessMode
+		for _, mode := range strings.Split(value, ",") {
+			if mode = strings.TrimSpace(mode); mode != "" {
+				modes = append(modes, corev1.PersistentVolumeAccessMode(mode))
+			}
+		}
+		return modes
+	}
+	if readWriteManyProvisioners[sc.Provisioner] {
+		return []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce, corev1.ReadWriteMany}
+	}
+	return []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+}
+
+// Supports reports whether volumes of sc can be mounted with mode
+func Supports(sc *storagev1.StorageClass, mode corev1.PersistentVolumeAccessMode) bool {
+	for _, m := range AccessModes(sc) {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkspaceAccessMode chooses the access mode of a workspace PVC of class sc. An empty requested
+// mode picks ReadWriteMany when the class offers it and ReadWriteOnce otherwise. A nil sc, a PVC
+// without class on a cluster without default class, can only bind to volumes created by hand,
+// so the requested mode is used as it is. A mode the class does not offer is an UnsupportedError.
+func WorkspaceAccessMode(sc *storagev1.StorageClass, requested string) (corev1.PersistentVolumeAccessMode, error) {
+	mode := corev1.PersistentVolumeAccessMode(requested)
+	switch mode {
+	case "", corev1.ReadWriteOnce, corev1.ReadWriteMany:
+	default:
+		return "", &UnsupportedError{ReasonAccessModeUnsupported,
+			fmt.Sprintf("unsupported workspace access mode %s, use %s or %s", requested, corev1.ReadWriteOnce, corev1.ReadWriteMany)}
+	}
+	if sc == nil {
+		if mode == "" {
+			return corev1.ReadWriteOnce, nil
+		}
+		return mode, nil
+	}
+	if mode == "" {
+		if Supports(sc, corev1.ReadWriteMany) {
+			return corev1.ReadWriteMany, nil
+		}
+		return corev1.ReadWriteOnce, nil
+	}
+	if !Supports(sc, mode) {
+		return "", &UnsupportedError{ReasonAccessModeUnsupported, fmt.Sprintf(
+			"storage class %s (provisioner %s) does not support %s; choose a class of a shared file system, "+
+				"or annotate the class with %s if its provisioner supports it", sc.Name, sc.Provisioner, mode, AccessModesAnnotation)}
+	}
+	return mode, nil
+}
+
+// Encrypted reports whether volumes of sc are encrypted at rest, either through the encrypted
+// parameter of Ceph RBD and AWS EBS or an encryption key of the cloud provider
+func Encrypted(sc *storagev1.StorageClass) bool {
+	if value, ok := sc.Annotations[EncryptedAnnotation]; ok {
+		return value == "true"
+	}
+	if sc.Parameters["encrypted"] == "true" {
+		return true
+	}
+	for _, key := range encryptionKeyParameters {
+		if sc.Parameters[key] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireEncryption returns an UnsupportedError unless volumes of sc are encrypted at rest. A nil
+// sc, a PVC without class on a cluster without default class, cannot be relied upon.
+func RequireEncryption(sc *storagev1.StorageClass) error {
+	if sc == nil {
+		return &UnsupportedError{ReasonNotEncrypted, "an encrypted workspace needs a storage class, the cluster has no default class"}
+	}
+	if !Encrypted(sc) {
+		return &UnsupportedError{ReasonNotEncrypted, fmt.Sprintf(
+			"storage class %s (provisioner %s) does not encrypt volumes; choose an encrypted class, "+
+				"or annotate the class with %s=true if its volumes are encrypted", sc.Name, sc.Provisioner, EncryptedAnnotation)}
+	}
+	return nil
+}
+
+// Capacity returns the size of the largest volume of class sc that can still be provisioned,
+// from the CSIStorageCapacity objects CSI drivers with storage capacity tracking publish per
+// topology segment. A volume is provisioned in a single segment, so it is the largest capacity
+// of a segment, bounded by the maximum volume size the driver reports for it. tracked is false
+// when no driver publishes the capacity of sc and nothing is known about it.
+func Capacity(ctx context.Context, c client.Reader, sc *storagev1.StorageClass) (available resource.Quantity, tracked bool, err error) {
+	if sc == nil {
+		return available, false, nil
+	}
+	list := &storagev1.CSIStorageCapacityList{}
+	if err := c.List(ctx, list); err != nil {
+		return available, false, fmt.Errorf("listing storage capacities: %w", err)
+	}
+	for _, capacity := range list.Items {
+		if capacity.StorageClassName != sc.Name || capacity.Capacity == nil {
+			continue
+		}
+		segment := capacity.Capacity.DeepCopy()
+		if limit := capacity.MaximumVolumeSize; limit != nil && limit.Cmp(segment) < 0 {
+			segment = limit.DeepCopy()
+		}
+		if !tracked || segment.Cmp(available) > 0 {
+			available = segment
+		}
+		tracked = true
+	}
+	return available, tracked, nil
+}
@@ -4,11 +4,50 @@ import (
 	_ "embed"
 )
 
+//go:embed scripts/clone_git_source.sh
+var CloneGitSourceScript string
+
 //go:embed scripts/find_manifest.sh
 var FindManifestScript string
 
 //go:embed scripts/build_image.sh
 var BuildImageScript string
 
+//go:embed scripts/debug_hold.sh
+var DebugHoldScript string
+
+//go:embed scripts/compliance_scan.sh
+var ComplianceScanScript string
+
+//go:embed scripts/size_report.sh
+var SizeReportScript string
+
+//go:embed scripts/boot_test.sh
+var BootTestScript string
+
 //go:embed scripts/push_artifact.sh
 var PushArtifactScript string
+
+//go:embed scripts/publish_registry.sh
+var PublishRegistryScript string
+
+//go:embed scripts/publish_s3.sh
+var PublishS3Script string
+
+//go:embed scripts/publish_pxe.sh
+var PublishPXEScript string
+
+//go:embed scripts/scrub_workspace.sh
+var ScrubWorkspaceScript string
+
+//go:embed scripts/archive_artifacts.sh
+var ArchiveArtifactsScript string
+
+//go:embed scripts/sign_artifact.sh
+var SignArtifactScript string
+
+//go:embed scripts/sbom_packages.sh
+var SBOMPackagesScript string
+
+//go:embed scripts/fetch_remote_file.sh
+var FetchRemoteFileScript string
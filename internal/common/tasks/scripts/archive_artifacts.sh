@@ -0,0 +1,22 @@
+#!/bin/bash
+set -euo pipefail
+
+WORKSPACE=/workspace/shared
+ARCHIVE=/workspace/archive
+
+# A retried copy starts over on an empty archive
+find "$ARCHIVE" -xdev -mindepth 1 -maxdepth 1 ! -name lost+found -exec rm -rf {} +
+
+# Upload sessions are build inputs and lost+found belongs to the file system
+tar -C "$WORKSPACE" --exclude=./.uploads --exclude=./lost+found --sparse -cf - . | tar -C "$ARCHIVE" -xpf -
+
+# The workspace is deleted once the copy succeeded, so every file is checked against its original
+(cd "$WORKSPACE" && find . -xdev \( -path ./.uploads -o -path ./lost+found \) -prune -o -type f -print0 |
+  xargs -0 -r sha256sum) > /tmp/workspace.sha256
+(cd "$ARCHIVE" && sha256sum --quiet -c /tmp/workspace.sha256)
+sync
+
+files=$(wc -l < /tmp/workspace.sha256)
+bytes=$(cd "$ARCHIVE" && find . -xdev -path ./lost+found -prune -o -type f -printf '%s\n' | awk '{ s += $1 } END { print s + 0 }')
+echo -n "$bytes" > /tekton/results/bytes
+echo "Archived ${files} files (${bytes} bytes)"
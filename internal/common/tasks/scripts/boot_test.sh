@@ -0,0 +1,143 @@
+#!/bin/sh
+set -e
+
+TIMEOUT="$(params.boot-test-timeout)"
+READY_MARKER='$(params.boot-ready-marker)'
+
+if [ -z "$TIMEOUT" ]; then
+  echo "No boot test requested, skipping"
+  exit 0
+fi
+
+write_result() {
+  echo -n "$1" > /tekton/results/boot-test-result || echo "Failed to write boot test result"
+}
+
+if [ ! -e /output/disk.img ] || [ -d "$(readlink -f /output/disk.img)" ]; then
+  echo "error: boot test needs an image or qcow2 export, /output/disk.img is missing or a directory"
+  write_result "error"
+  exit 0
+fi
+image=$(readlink -f /output/disk.img)
+image_format=raw
+if [ "$(params.export-format)" = "qcow2" ]; then
+  image_format=qcow2
+fi
+
+case "$(params.target-architecture)" in
+  arm64|aarch64)
+    qemu_arch=aarch64
+    machine=virt
+    firmware_pkg=edk2-aarch64
+    firmware=/usr/share/edk2/aarch64/QEMU_EFI-silent-pflash.raw
+    [ -f "$firmware" ] || firmware=/usr/share/edk2/aarch64/QEMU_EFI.fd
+    ;;
+  *)
+    qemu_arch=x86_64
+    machine=q35
+    firmware_pkg=edk2-ovmf
+    firmware=/usr/share/edk2/ovmf/OVMF_CODE.fd
+    ;;
+esac
+
+install_packages() {
+  for mgr in dnf microdnf yum; do
+    if command -v $mgr >/dev/null 2>&1; then
+      $mgr -y install "$@" && return 0
+    fi
+  done
+  return 1
+}
+
+qemu="qemu-system-$qemu_arch"
+if ! command -v "$qemu" >/dev/null 2>&1 && [ -x /usr/libexec/qemu-kvm ] && [ "$qemu_arch" = "$(uname -m)" ]; then
+  qemu=/usr/libexec/qemu-kvm
+fi
+if ! command -v "$qemu" >/dev/null 2>&1; then
+  echo "$qemu not found. Attempting to install it..."
+  install_packages "qemu-system-$qemu_arch-core" "$firmware_pkg" || install_packages qemu-kvm "$firmware_pkg" || true
+  [ -x /usr/libexec/qemu-kvm ] && ! command -v "$qemu" >/dev/null 2>&1 && qemu=/usr/libexec/qemu-kvm
+fi
+if ! command -v "$qemu" >/dev/null 2>&1; then
+  echo "error: no QEMU available for $qemu_arch in $(params.automotive-image-builder)"
+  write_result "error"
+  exit 0
+fi
+if [ ! -f "$firmware" ]; then
+  install_packages "$firmware_pkg" || true
+fi
+if [ ! -f "$firmware" ]; then
+  echo "error: UEFI firmware $firmware not found"
+  write_result "error"
+  exit 0
+fi
+
+# Timings are only comparable between builds when the guest runs on KVM
+accel="tcg"
+cpu="max"
+if [ "$qemu_arch" = "$(uname -m)" ] && [ -w /dev/kvm ]; then
+  accel="kvm"
+  cpu="host"
+fi
+echo -n "$accel" > /tekton/results/boot-accelerator || true
+
+work_dir=/output/_boot
+mkdir -p "$work_dir"
+console="$work_dir/console.log"
+: > "$console"
+
+now_ms() {
+  date +%s%3N
+}
+
+echo "Booting $image ($image_format, $qemu_arch, $accel) with a ${TIMEOUT}s timeout"
+started=$(now_ms)
+# -snapshot keeps the published image untouched
+$qemu -machine "$machine,accel=$accel" -cpu "$cpu" -m 2048 -smp 2 -snapshot \
+  -bios "$firmware" \
+  -drive file="$image",format="$image_format",if=virtio \
+  -netdev user,id=net0 -device virtio-net-pci,netdev=net0 \
+  -serial file:"$console" -monitor none -display none &
+qemu_pid=$!
+cleanup() {
+  kill "$qemu_pid" 2>/dev/null || true
+  wait "$qemu_pid" 2>/dev/null || true
+}
+trap cleanup EXIT
+
+kernel_at=""
+ready_at=""
+deadline=$((started + TIMEOUT * 1000))
+while [ "$(now_ms)" -lt "$deadline" ]; do
+  if [ -z "$kernel_at" ] && grep -q "Linux version" "$console" 2>/dev/null; then
+    kernel_at=$(now_ms)
+    echo "Kernel started after $((kernel_at - started))ms"
+  fi
+  if [ -n "$kernel_at" ] && grep -Eq "$READY_MARKER" "$console" 2>/dev/null; then
+    ready_at=$(now_ms)
+    break
+  fi
+  if ! kill -0 "$qemu_pid" 2>/dev/null; then
+    echo "QEMU exited before the image finished booting"
+    break
+  fi
+  sleep 0.2
+done
+
+cp "$console" "$(workspaces.shared-workspace.path)/boot-console.log" || true
+
+if [ -z "$ready_at" ]; then
+  if kill -0 "$qemu_pid" 2>/dev/null; then
+    echo "Boot did not reach '$READY_MARKER' within ${TIMEOUT}s"
+    write_result "timeout"
+  else
+    write_result "error"
+  fi
+  tail -n 50 "$console" || true
+  exit 0
+fi
+
+timings="firmware=$((kernel_at - started)),kernelToReady=$((ready_at - kernel_at)),total=$((ready_at - started))"
+echo "Boot timings (milliseconds): $timings"
+echo -n "$timings" > /tekton/results/boot-timings || echo "Failed to write boot timings result"
+write_result "booted"
@@ -78,6 +78,32 @@ runTmp="/run/osbuild/"
 mkdir -p "$storePath"
 mkdir -p "$runTmp"
 
+# Record what the build runs with, so builds that behave differently on two clusters can be compared:
+# the tool versions, the SELinux mode of the node and the sysctls osbuild relies on (user namespaces
+# for its bubblewrap sandbox, inotify for its store). Values must not contain the separators.
+env_value() {
+    printf '%s' "$1" | head -n1 | tr -d ',=' | cut -c1-100
+}
+selinux_mode=Disabled
+if [ -r /sys/fs/selinux/enforce ]; then
+    if [ "$(cat /sys/fs/selinux/enforce)" = "1" ]; then
+        selinux_mode=Enforcing
+    else
+        selinux_mode=Permissive
+    fi
+fi
+build_environment="osbuild=$(env_value "$($osbuildPath --version 2>/dev/null | sed 's/^osbuild //')")"
+build_environment="$build_environment,aib=$(env_value "$(automotive-image-builder --version 2>/dev/null | sed 's/^automotive-image-builder //')")"
+build_environment="$build_environment,selinux=$selinux_mode"
+for sysctl in user.max_user_namespaces fs.inotify.max_user_instances fs.inotify.max_user_watches; do
+    sysctl_file="/proc/sys/$(echo "$sysctl" | tr '.' '/')"
+    if [ -r "$sysctl_file" ]; then
+        build_environment="$build_environment,sysctl.$sysctl=$(env_value "$(cat "$sysctl_file")")"
+    fi
+done
+echo "Build environment: $build_environment"
+echo -n "$build_environment" > /tekton/results/build-environment || echo "Failed to write build environment result"
+
 MANIFEST_FILE=$(cat /tekton/results/manifest-file-path)
 if [ -z "$MANIFEST_FILE" ]; then
     echo "Error: No manifest file path provided"
@@ -227,7 +253,9 @@ cat "$MANIFEST_FILE"
 
 
 echo "Running the build command: $build_command"
+build_started=$(date +%s)
 eval "$build_command"
+build_finished=$(date +%s)
 
 pushd /output
 ln -sf ./${exportFile} ./disk.img
@@ -236,25 +264,23 @@ echo "copying build artifacts to shared workspace..."
 
 mkdir -p $(workspaces.shared-workspace.path)
 
+# Regular files are copied in the background while the compressed artifact is
+# produced from the same source below, so export and compression overlap.
+export_pid=""
 if [ -d "/output/${exportFile}" ]; then
     echo "${exportFile} is a directory, copying recursively..."
     cp -rv "/output/${exportFile}" $(workspaces.shared-workspace.path)/ || echo "Failed to copy ${exportFile}"
-else
-    echo "${exportFile} is a regular file, copying..."
-    cp -v "/output/${exportFile}" $(workspaces.shared-workspace.path)/ || echo "Failed to copy ${exportFile}"
-fi
-
-pushd $(workspaces.shared-workspace.path)
-if [ -d "${exportFile}" ]; then
+    pushd $(workspaces.shared-workspace.path)
     echo "Creating symlink to directory ${exportFile}"
     ln -sf ${exportFile} disk.img
-elif [ -f "${exportFile}" ]; then
-    echo "Creating symlink to file ${exportFile}"
-    ln -sf ${exportFile} disk.img
+    popd
+elif [ -f "/output/${exportFile}" ]; then
+    echo "${exportFile} is a regular file, copying in the background..."
+    ( cp -v --sparse=always "/output/${exportFile}" $(workspaces.shared-workspace.path)/ || echo "Failed to copy ${exportFile}" ) &
+    export_pid=$!
 else
-    echo "Warning: ${exportFile} not found in workspace, cannot create symlink"
+    echo "Warning: ${exportFile} not found in /output, nothing to copy"
 fi
-popd
 
 cp -v /output/image.json $(workspaces.shared-workspace.path)/image.json || echo "Failed to copy image.json"
 
@@ -263,6 +289,13 @@ ls -la $(workspaces.shared-workspace.path)/
 
 COMPRESSION="$(params.compression)"
 echo "Requested compression: $COMPRESSION"
+case "$COMPRESSION" in
+  gzip|lz4|zstd) ;;
+  *)
+    echo "Unsupported compression '$COMPRESSION'; use gzip, lz4 or zstd" >&2
+    exit 1
+    ;;
+esac
 
 ensure_lz4() {
   if ! command -v lz4 >/dev/null 2>&1; then
@@ -283,9 +316,31 @@ ensure_lz4() {
   fi
 }
 
+ensure_zstd() {
+  if ! command -v zstd >/dev/null 2>&1; then
+    echo "zstd not found. Attempting to install..."
+    if command -v dnf >/dev/null 2>&1; then
+      dnf -y install zstd || true
+    fi
+    if command -v microdnf >/dev/null 2>&1; then
+      microdnf install -y zstd || true
+    fi
+    if command -v yum >/dev/null 2>&1; then
+      yum -y install zstd || true
+    fi
+    if ! command -v zstd >/dev/null 2>&1; then
+      echo "zstd still not available; falling back to gzip"
+      COMPRESSION="gzip"
+    fi
+  fi
+}
+
 if [ "$COMPRESSION" = "lz4" ]; then
   ensure_lz4
 fi
+if [ "$COMPRESSION" = "zstd" ]; then
+  ensure_zstd
+fi
 
 compress_file_gzip() {
   src="$1"; dest="$2"
@@ -297,6 +352,12 @@ compress_file_lz4() {
   lz4 -z -f -q "$src" "$dest"
 }
 
+# zstd compresses on all cores, which matters for images of 10 GB and more
+compress_file_zstd() {
+  src="$1"; dest="$2"
+  zstd -T0 -q -f "$src" -o "$dest"
+}
+
 tar_dir_gzip() {
   dir="$1"; out="$2"
   tar -C $(workspaces.shared-workspace.path) -czf "$out" "$dir"
@@ -307,11 +368,17 @@ tar_dir_lz4() {
   tar -C $(workspaces.shared-workspace.path) -cf - "$dir" | lz4 -z -f -q > "$out"
 }
 
+tar_dir_zstd() {
+  dir="$1"; out="$2"
+  tar -C $(workspaces.shared-workspace.path) -cf - "$dir" | zstd -T0 -q > "$out"
+}
+
 compress_file() {
   src="$1"; dest="$2"
   case "$COMPRESSION" in
     lz4) compress_file_lz4 "$src" "$dest" ;;
-    gzip|*) compress_file_gzip "$src" "$dest" ;;
+    zstd) compress_file_zstd "$src" "$dest" ;;
+    gzip) compress_file_gzip "$src" "$dest" ;;
   esac
 }
 
@@ -319,7 +386,8 @@ tar_dir() {
   dir="$1"; out="$2"
   case "$COMPRESSION" in
     lz4) tar_dir_lz4 "$dir" "$out" ;;
-    gzip|*) tar_dir_gzip "$dir" "$out" ;;
+    zstd) tar_dir_zstd "$dir" "$out" ;;
+    gzip) tar_dir_gzip "$dir" "$out" ;;
   esac
 }
 
@@ -328,18 +396,24 @@ case "$COMPRESSION" in
     EXT_FILE=".lz4"
     EXT_DIR=".tar.lz4"
     ;;
-  gzip|*)
+  zstd)
+    EXT_FILE=".zst"
+    EXT_DIR=".tar.zst"
+    ;;
+  gzip)
     EXT_FILE=".gz"
     EXT_DIR=".tar.gz"
     ;;
 esac
 
+package_started=$(date +%s)
 final_name=""
 if [ -d "$(workspaces.shared-workspace.path)/${exportFile}" ]; then
   echo "Preparing compressed parts for directory ${exportFile}..."
   final_compressed_name="${exportFile}${EXT_DIR}"
   parts_dir="$(workspaces.shared-workspace.path)/${final_compressed_name}-parts"
   mkdir -p "$parts_dir"
+  # Parts and the full archive are independent, so compress them concurrently
   (
     cd "$(workspaces.shared-workspace.path)"
     for item in "${exportFile}"/*; do
@@ -347,15 +421,18 @@ if [ -d "$(workspaces.shared-workspace.path)/${exportFile}" ]; then
       base=$(basename "$item")
       if [ -f "$item" ]; then
         echo "Creating $parts_dir/${base}${EXT_FILE}"
-        compress_file "$item" "$parts_dir/${base}${EXT_FILE}" || echo "Failed to create $parts_dir/${base}${EXT_FILE}"
+        compress_file "$item" "$parts_dir/${base}${EXT_FILE}" || echo "Failed to create $parts_dir/${base}${EXT_FILE}" &
       elif [ -d "$item" ]; then
         echo "Creating $parts_dir/${base}${EXT_DIR}"
-        tar_dir "${exportFile}/$base" "$parts_dir/${base}${EXT_DIR}" || echo "Failed to create $parts_dir/${base}${EXT_DIR}"
+        tar_dir "${exportFile}/$base" "$parts_dir/${base}${EXT_DIR}" || echo "Failed to create $parts_dir/${base}${EXT_DIR}" &
       fi
     done
-  )
+    wait
+  ) &
+  parts_pid=$!
   echo "Creating compressed archive ${final_compressed_name} in shared workspace..."
   tar_dir "${exportFile}" "$(workspaces.shared-workspace.path)/${final_compressed_name}" || echo "Failed to create ${final_compressed_name}"
+  wait $parts_pid
   echo "Compressed archive size:" && ls -lah $(workspaces.shared-workspace.path)/${final_compressed_name} || true
   if [ -f "$(workspaces.shared-workspace.path)/${final_compressed_name}" ]; then
     echo "Removing uncompressed directory ${exportFile} (keeping parts directory)"
@@ -371,9 +448,13 @@ if [ -d "$(workspaces.shared-workspace.path)/${exportFile}" ]; then
       ls -la "$(workspaces.shared-workspace.path)/${final_compressed_name}-parts/" || true
     fi
   fi
-elif [ -f "$(workspaces.shared-workspace.path)/${exportFile}" ]; then
+elif [ -f "/output/${exportFile}" ]; then
   echo "Creating compressed file ${exportFile}${EXT_FILE} in shared workspace..."
-  compress_file "$(workspaces.shared-workspace.path)/${exportFile}" "$(workspaces.shared-workspace.path)/${exportFile}${EXT_FILE}" || echo "Failed to create ${exportFile}${EXT_FILE}"
+  compress_file "/output/${exportFile}" "$(workspaces.shared-workspace.path)/${exportFile}${EXT_FILE}" || echo "Failed to create ${exportFile}${EXT_FILE}"
+  if [ -n "$export_pid" ]; then
+    echo "Waiting for export of ${exportFile} to finish..."
+    wait $export_pid
+  fi
   echo "Compressed file size:" && ls -lah $(workspaces.shared-workspace.path)/${exportFile}${EXT_FILE} || true
   if [ -f "$(workspaces.shared-workspace.path)/${exportFile}${EXT_FILE}" ]; then
     pushd $(workspaces.shared-workspace.path)
@@ -383,6 +464,25 @@ elif [ -f "$(workspaces.shared-workspace.path)/${exportFile}" ]; then
   fi
 fi
 
+package_finished=$(date +%s)
+
+FIRSTBOOT_FILE="$(params.firstboot-file)"
+FIRSTBOOT_ARTIFACT="$(params.firstboot-artifact)"
+if [ -n "$FIRSTBOOT_FILE" ] && [ -n "$FIRSTBOOT_ARTIFACT" ]; then
+  if [ -s "$FIRSTBOOT_FILE" ]; then
+    echo "Attaching first-boot payload as ${FIRSTBOOT_ARTIFACT}"
+    cp -v -L "$FIRSTBOOT_FILE" "$(workspaces.shared-workspace.path)/${FIRSTBOOT_ARTIFACT}"
+    echo -n "$FIRSTBOOT_ARTIFACT" > /tekton/results/firstboot-filename || echo "Failed to write first-boot result"
+  else
+    echo "error: first-boot payload not found at $FIRSTBOOT_FILE"
+    exit 1
+  fi
+fi
+
+if [ -n "$export_pid" ]; then
+  wait $export_pid 2>/dev/null || true
+fi
+
 if [ -z "$final_name" ]; then
   guess=$(ls -1 $(workspaces.shared-workspace.path)/${cleanName}* 2>/dev/null | head -n1)
   if [ -n "$guess" ]; then
@@ -394,10 +494,41 @@ if [ -n "$final_name" ]; then
   echo "$final_name" > /tekton/results/artifact-filename || echo "Failed to write Tekton result"
   echo "Verifying Tekton result file:"
   cat /tekton/results/artifact-filename || echo "Failed to read Tekton result"
+  artifact_size=$(stat -L -c %s "$(workspaces.shared-workspace.path)/${final_name}" 2>/dev/null || echo "")
+  if [ -n "$artifact_size" ]; then
+    echo -n "$artifact_size" > /tekton/results/artifact-size || echo "Failed to write artifact size result"
+  fi
+  artifact_sha256=$(sha256sum "$(workspaces.shared-workspace.path)/${final_name}" 2>/dev/null | cut -d' ' -f1)
+  if [ -n "$artifact_sha256" ]; then
+    echo "Artifact SHA-256: $artifact_sha256"
+    echo -n "$artifact_sha256" > /tekton/results/artifact-sha256 || echo "Failed to write artifact checksum result"
+  fi
 else
   echo "Warning: final_name is empty, no artifact filename will be recorded"
 fi
 
+HARDENING_REPORT="$(params.hardening-report)"
+if [ -n "$HARDENING_REPORT" ]; then
+  if [ ! -s "$HARDENING_REPORT" ]; then
+    echo "error: hardening report template not found at $HARDENING_REPORT"
+    exit 1
+  fi
+  report_name="hardening-report.json"
+  manifest_sha=$(sha256sum "$MANIFEST_FILE" | cut -d' ' -f1)
+  echo "Writing hardening compliance report as ${report_name}"
+  # The template is indented JSON ending in a lone "}"; replace it to append the build evidence
+  {
+    sed '$d' "$HARDENING_REPORT"
+    printf ',\n  "manifestSHA256": "%s",\n  "artifact": "%s",\n  "completedAt": "%s"\n}\n' \
+      "$manifest_sha" "$final_name" "$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+  } > "$(workspaces.shared-workspace.path)/${report_name}"
+  echo -n "$report_name" > /tekton/results/hardening-report-filename || echo "Failed to write hardening report result"
+fi
+
+stage_timings="build=$((build_finished - build_started)),package=$((package_finished - package_started))"
+echo "Stage timings (seconds): $stage_timings"
+echo -n "$stage_timings" > /tekton/results/stage-timings || echo "Failed to write stage timings result"
+
 # Ensure all filesystem writes are flushed to disk before task completes
 echo "Syncing filesystem to ensure all artifacts are written..."
 sync
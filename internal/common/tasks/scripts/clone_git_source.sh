@@ -0,0 +1,42 @@
+#!/bin/sh
+# Clones the git source of a build into its workspace and records the commit the ref resolved to
+set -e
+
+# GIT_URL, GIT_REF and GIT_PATH come from the environment rather than the script, so no quoting
+# in them can change it
+if [ -z "$GIT_URL" ]; then
+  printf '' > "$(results.git-commit.path)"
+  exit 0
+fi
+
+SOURCE_DIR="$(workspaces.shared-workspace.path)/.git-source"
+rm -rf "$SOURCE_DIR"
+mkdir -p "$SOURCE_DIR"
+cd "$SOURCE_DIR"
+
+export HOME=/tekton/home
+git config --global --add safe.directory "$SOURCE_DIR"
+git init --quiet
+git remote add origin "$GIT_URL"
+
+echo "fetching ${GIT_REF:-the default branch} of $GIT_URL"
+# Fetch only the ref; servers that do not serve commits by SHA get a full fetch instead
+if git fetch --quiet --depth 1 origin "${GIT_REF:-HEAD}"; then
+  COMMIT=$(git rev-parse FETCH_HEAD)
+else
+  git fetch --quiet --tags origin '+refs/heads/*:refs/remotes/origin/*'
+  COMMIT=$(git rev-parse --verify --quiet "${GIT_REF}^{commit}" || git rev-parse --verify --quiet "origin/${GIT_REF}^{commit}" || true)
+  if [ -z "$COMMIT" ]; then
+    echo "error: ref $GIT_REF not found in $GIT_URL"
+    exit 1
+  fi
+fi
+git checkout --quiet --detach "$COMMIT"
+
+if [ ! -f "$SOURCE_DIR/$GIT_PATH" ]; then
+  echo "error: manifest $GIT_PATH not found in $GIT_URL at $COMMIT"
+  exit 1
+fi
+
+echo "building $GIT_PATH at commit $COMMIT"
+printf '%s' "$COMMIT" > "$(results.git-commit.path)"
@@ -0,0 +1,135 @@
+#!/bin/sh
+set -e
+
+PROFILE="$(params.compliance-profile)"
+DATASTREAM="$(params.compliance-datastream)"
+
+if [ -z "$PROFILE" ]; then
+  echo "No compliance profile requested, skipping scan"
+  exit 0
+fi
+
+write_result() {
+  echo -n "$1" > /tekton/results/compliance-result || echo "Failed to write compliance result"
+}
+
+if [ ! -e /output/disk.img ]; then
+  echo "error: built image not found at /output/disk.img"
+  write_result "error"
+  exit 0
+fi
+if [ -d "$(readlink -f /output/disk.img)" ]; then
+  echo "error: export format $(params.export-format) produces a directory; compliance scans need image or qcow2"
+  write_result "error"
+  exit 0
+fi
+
+install_packages() {
+  for mgr in dnf microdnf yum; do
+    if command -v $mgr >/dev/null 2>&1; then
+      $mgr -y install "$@" && return 0
+    fi
+  done
+  return 1
+}
+
+if ! command -v oscap-chroot >/dev/null 2>&1; then
+  echo "oscap not found. Attempting to install openscap-scanner and scap-security-guide..."
+  install_packages openscap-scanner openscap-utils scap-security-guide || true
+fi
+if ! command -v oscap-chroot >/dev/null 2>&1; then
+  echo "error: oscap-chroot is not available in $(params.automotive-image-builder)"
+  write_result "error"
+  exit 0
+fi
+
+scan_dir=/output/_compliance
+mkdir -p "$scan_dir" "$scan_dir/mnt"
+
+raw_image=$(readlink -f /output/disk.img)
+if [ "$(params.export-format)" = "qcow2" ]; then
+  if ! command -v qemu-img >/dev/null 2>&1; then
+    install_packages qemu-img || true
+  fi
+  echo "Converting qcow2 image to raw for scanning..."
+  qemu-img convert -O raw "$raw_image" "$scan_dir/disk.raw"
+  raw_image="$scan_dir/disk.raw"
+fi
+
+loop_dev=$(losetup -f -P -r --show "$raw_image")
+cleanup() {
+  umount "$scan_dir/mnt" 2>/dev/null || true
+  losetup -d "$loop_dev" 2>/dev/null || true
+}
+trap cleanup EXIT
+
+# Find the partition holding the root filesystem; for ostree images the deployment is the root
+rootfs=""
+for part in "${loop_dev}"p*; do
+  [ -b "$part" ] || continue
+  if ! mount -o ro "$part" "$scan_dir/mnt" 2>/dev/null; then
+    continue
+  fi
+  deploy=$(ls -d "$scan_dir"/mnt/ostree/deploy/*/deploy/*/ 2>/dev/null | head -n1)
+  if [ -n "$deploy" ]; then
+    rootfs="${deploy%/}"
+    break
+  fi
+  if [ -f "$scan_dir/mnt/etc/os-release" ] || [ -f "$scan_dir/mnt/usr/lib/os-release" ]; then
+    rootfs="$scan_dir/mnt"
+    break
+  fi
+  umount "$scan_dir/mnt"
+done
+
+if [ -z "$rootfs" ]; then
+  echo "error: no root filesystem found in $raw_image"
+  write_result "error"
+  exit 0
+fi
+echo "Scanning root filesystem at $rootfs"
+
+if [ -z "$DATASTREAM" ]; then
+  os_release="$rootfs/etc/os-release"
+  [ -f "$os_release" ] || os_release="$rootfs/usr/lib/os-release"
+  os_id=$(. "$os_release" && echo "$ID")
+  os_version=$(. "$os_release" && echo "${VERSION_ID%%.*}")
+  case "$os_id" in
+    centos|autosd) content="cs${os_version}" ;;
+    rhel) content="rhel${os_version}" ;;
+    fedora) content="fedora" ;;
+    *) content="${os_id}${os_version}" ;;
+  esac
+  DATASTREAM="/usr/share/xml/scap/ssg/content/ssg-${content}-ds.xml"
+fi
+if [ ! -f "$DATASTREAM" ]; then
+  echo "error: SCAP data stream not found at $DATASTREAM"
+  write_result "error"
+  exit 0
+fi
+
+arf_name="compliance-arf.xml"
+report_name="compliance-report.html"
+echo "Evaluating profile $PROFILE from $DATASTREAM"
+rc=0
+oscap-chroot "$rootfs" xccdf eval \
+  --profile "$PROFILE" \
+  --results-arf "$scan_dir/$arf_name" \
+  --report "$scan_dir/$report_name" \
+  "$DATASTREAM" || rc=$?
+
+# oscap exits 0 when all rules pass, 2 when at least one rule fails and 1 on errors
+case "$rc" in
+  0) result="pass" ;;
+  2) result="fail" ;;
+  *) result="error" ;;
+esac
+echo "Compliance scan result: $result (oscap exit code $rc)"
+
+for f in "$arf_name" "$report_name"; do
+  if [ -f "$scan_dir/$f" ]; then
+    cp -v "$scan_dir/$f" "$(workspaces.shared-workspace.path)/$f"
+  fi
+done
+write_result "$result"
+sync
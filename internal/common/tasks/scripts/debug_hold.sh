@@ -0,0 +1,26 @@
+#!/bin/sh
+
+HOLD_MINUTES="$(params.debug-hold-minutes)"
+exit_code=$(cat "$(steps.step-build-image.exitCode.path)" 2>/dev/null || echo 1)
+
+if [ "$exit_code" = "0" ]; then
+  exit 0
+fi
+if [ -z "$HOLD_MINUTES" ]; then
+  exit "$exit_code"
+fi
+
+release=/tmp/release-debug-hold
+held_until=$(( $(date +%s) + HOLD_MINUTES * 60 ))
+
+echo "Build step failed with exit code $exit_code"
+echo "Keeping the build pod for debugging until $(date -u -d "@$held_until")"
+echo "The osbuild workspace is in /_build, outputs in /output and the manifest in /manifest-work"
+echo "Run 'touch $release' in the debug shell to release the pod early"
+
+while [ "$(date +%s)" -lt "$held_until" ] && [ ! -e "$release" ]; do
+  sleep 5
+done
+
+echo "Releasing the build pod"
+exit "$exit_code"
@@ -0,0 +1,42 @@
+#!/bin/sh
+# Downloads a remote file of a build into the workspace of its upload pod:
+# fetch_remote_file.sh <url> <path in the workspace>
+set -e
+
+URL="$1"
+DEST="/workspace/shared/$2"
+CREDENTIALS=/workspace/remote-credentials
+mkdir -p "$(dirname "${DEST}")"
+
+case "${URL}" in
+s3://*)
+  set --
+  if [ -f "${CREDENTIALS}/AWS_ACCESS_KEY_ID" ]; then
+    # read from the mounted secret rather than the environment, so the keys do not show up in the pod spec
+    AWS_ACCESS_KEY_ID="$(cat "${CREDENTIALS}/AWS_ACCESS_KEY_ID")"
+    AWS_SECRET_ACCESS_KEY="$(cat "${CREDENTIALS}/AWS_SECRET_ACCESS_KEY")"
+    export AWS_ACCESS_KEY_ID AWS_SECRET_ACCESS_KEY
+  else
+    set -- --no-sign-request
+  fi
+  if [ -f "${CREDENTIALS}/AWS_DEFAULT_REGION" ]; then
+    AWS_DEFAULT_REGION="$(cat "${CREDENTIALS}/AWS_DEFAULT_REGION")"
+    export AWS_DEFAULT_REGION
+  fi
+  if [ -f "${CREDENTIALS}/AWS_ENDPOINT_URL" ]; then
+    set -- "$@" --endpoint-url "$(cat "${CREDENTIALS}/AWS_ENDPOINT_URL")"
+  fi
+  aws "$@" s3 cp --no-progress "${URL}" "${DEST}.part"
+  ;;
+*)
+  # redirects are not followed: they could lead anywhere, not only to the remoteFileHosts
+  set -- --fail --show-error --silent --proto =http,https --retry 3 --retry-connrefused
+  if [ -f "${CREDENTIALS}/token" ]; then
+    set -- "$@" --header "Authorization: Bearer $(cat "${CREDENTIALS}/token")"
+  fi
+  curl "$@" --output "${DEST}.part" "${URL}"
+  ;;
+esac
+
+mv "${DEST}.part" "${DEST}"
+echo "Downloaded ${URL} ($(wc -c < "${DEST}") bytes)"
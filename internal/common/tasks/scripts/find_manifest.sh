@@ -6,7 +6,19 @@ echo "looking for manifest file..."
 echo "listing contents of manifest config workspace:"
 ls -la $(workspaces.manifest-config-workspace.path)
 
-MANIFEST_FILE=$(find $(workspaces.manifest-config-workspace.path) -name '*.mpp.yml' -o -name '*.aib.yml' -type f | head -n 1)
+# Relative paths of add_files entries are resolved against the directory of a git manifest, else
+# against the workspace the files were uploaded to
+if [ -n "$GIT_URL" ]; then
+  MANIFEST_FILE="$(workspaces.shared-workspace.path)/.git-source/$GIT_PATH"
+  SOURCE_BASE=$(dirname "$MANIFEST_FILE")
+else
+  MANIFEST_FILE=$(find $(workspaces.manifest-config-workspace.path) -name '*.mpp.yml' -o -name '*.aib.yml' -type f | head -n 1)
+  SOURCE_BASE="$(workspaces.shared-workspace.path)"
+fi
+
+# The upload pod stores add_files sources that are URLs under remote-files/<host>/<path> of the
+# workspace, see internal/common/remotefiles
+REMOTE_FILES_DIR="$(workspaces.shared-workspace.path)/remote-files"
 
 if [ -z "$MANIFEST_FILE" ]; then
   echo "No manifest file found in the ConfigMap"
@@ -27,12 +39,14 @@ if yq eval '.content.add_files' "$workspace_manifest.tmp" | grep -q '^[^#]'; the
   indices=$(yq eval '.content.add_files | to_entries | .[] | select(.value.source != null and .value.text == null) | .key' "$workspace_manifest.tmp")
 
   for idx in $indices; do
-    yq eval -i ".content.add_files[$idx].source_path = \"$(workspaces.shared-workspace.path)/\" + (.content.add_files[$idx].source // \"\")" "$workspace_manifest.tmp"
+    yq eval -i ".content.add_files[$idx].source_path = \"$SOURCE_BASE/\" + (.content.add_files[$idx].source // \"\")" "$workspace_manifest.tmp"
   done
 
+  yq eval -i "(.content.add_files[] | select(.source_path != null and (.source_path | test(\"(?i)^(https?|s3)://\"))) | .source_path) |= sub(\"^[^:]+://([^/?#]*)([^?#]*).*\$\", \"$REMOTE_FILES_DIR/\${1}\${2}\")" "$workspace_manifest.tmp"
+
   sp_indices=$(yq eval '.content.add_files | to_entries | .[] | select(.value.source_path != null and (.value.source_path | test("^/") | not) and .value.text == null) | .key' "$workspace_manifest.tmp")
   for idx in $sp_indices; do
-    yq eval -i ".content.add_files[$idx].source_path = \"$(workspaces.shared-workspace.path)/\" + (.content.add_files[$idx].source_path // \"\")" "$workspace_manifest.tmp"
+    yq eval -i ".content.add_files[$idx].source_path = \"$SOURCE_BASE/\" + (.content.add_files[$idx].source_path // \"\")" "$workspace_manifest.tmp"
   done
 fi
 
@@ -40,15 +54,40 @@ if yq eval '.qm.content.add_files' "$workspace_manifest.tmp" | grep -q '^[^#]';
   indices=$(yq eval '.qm.content.add_files | to_entries | .[] | select(.value.source != null and .value.text == null) | .key' "$workspace_manifest.tmp")
 
   for idx in $indices; do
-    yq eval -i ".qm.content.add_files[$idx].source_path = \"$(workspaces.shared-workspace.path)/\" + (.qm.content.add_files[$idx].source // \"\")" "$workspace_manifest.tmp"
+    yq eval -i ".qm.content.add_files[$idx].source_path = \"$SOURCE_BASE/\" + (.qm.content.add_files[$idx].source // \"\")" "$workspace_manifest.tmp"
   done
 
+  yq eval -i "(.qm.content.add_files[] | select(.source_path != null and (.source_path | test(\"(?i)^(https?|s3)://\"))) | .source_path) |= sub(\"^[^:]+://([^/?#]*)([^?#]*).*\$\", \"$REMOTE_FILES_DIR/\${1}\${2}\")" "$workspace_manifest.tmp"
+
   sp_indices=$(yq eval '.qm.content.add_files | to_entries | .[] | select(.value.source_path != null and (.value.source_path | test("^/") | not) and .value.text == null) | .key' "$workspace_manifest.tmp")
   for idx in $sp_indices; do
-    yq eval -i ".qm.content.add_files[$idx].source_path = \"$(workspaces.shared-workspace.path)/\" + (.qm.content.add_files[$idx].source_path // \"\")" "$workspace_manifest.tmp"
+    yq eval -i ".qm.content.add_files[$idx].source_path = \"$SOURCE_BASE/\" + (.qm.content.add_files[$idx].source_path // \"\")" "$workspace_manifest.tmp"
   done
 fi
 
+FIRSTBOOT_FILE="$(params.firstboot-file)"
+FIRSTBOOT_EMBED_PATH="$(params.firstboot-embed-path)"
+if [ -n "$FIRSTBOOT_FILE" ] && [ -n "$FIRSTBOOT_EMBED_PATH" ]; then
+  if [ ! -s "$FIRSTBOOT_FILE" ]; then
+    echo "error: first-boot payload not found at $FIRSTBOOT_FILE"
+    exit 1
+  fi
+  echo "embedding first-boot payload at $FIRSTBOOT_EMBED_PATH"
+  yq eval -i ".content.add_files += [{\"path\": \"$FIRSTBOOT_EMBED_PATH\", \"source_path\": \"$FIRSTBOOT_FILE\"}]" "$workspace_manifest.tmp"
+fi
+
+HARDENING_FRAGMENT="$(params.hardening-fragment)"
+if [ -n "$HARDENING_FRAGMENT" ]; then
+  if [ ! -s "$HARDENING_FRAGMENT" ]; then
+    echo "error: hardening fragment not found at $HARDENING_FRAGMENT"
+    exit 1
+  fi
+  echo "applying hardening profiles from $HARDENING_FRAGMENT"
+  # Deep-merge the fragment into the manifest, appending to existing lists
+  yq eval-all 'select(fileIndex == 0) *+ select(fileIndex == 1)' "$workspace_manifest.tmp" "$HARDENING_FRAGMENT" > "$workspace_manifest.hardened"
+  mv "$workspace_manifest.hardened" "$workspace_manifest.tmp"
+fi
+
 # Replace original with processed file
 mv "$workspace_manifest.tmp" "$workspace_manifest"
 
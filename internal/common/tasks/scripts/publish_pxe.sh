@@ -0,0 +1,18 @@
+#!/bin/sh
+set -e
+
+ARTIFACT="$(params.artifact-filename)"
+URL="$(params.url)"
+URL="${URL%/}/${ARTIFACT}"
+TOKEN_FILE=/workspace/publish-credentials/token
+
+set -- --fail --show-error --silent --retry 3 --upload-file "${ARTIFACT}"
+if [ -f "${TOKEN_FILE}" ]; then
+  set -- "$@" --header "Authorization: Bearer $(cat "${TOKEN_FILE}")"
+fi
+
+echo "Uploading ${ARTIFACT} to ${URL}"
+curl "$@" "${URL}"
+
+echo -n "${URL}" > /tekton/results/location
+echo "Artifact uploaded to ${URL}"
@@ -0,0 +1,35 @@
+#!/bin/sh
+set -e
+
+ARTIFACT="$(params.artifact-filename)"
+REFERENCE="$(params.repository-url)"
+
+# the tag of the reference, unless it is pinned by digest; oras pushes untagged references as latest
+TAG=latest
+case "${REFERENCE##*/}" in
+  *@*) TAG="" ;;
+  *:*) TAG="${REFERENCE##*:}" ;;
+esac
+
+# release tags matching an immutable pattern are never overwritten; the patterns must not expand to files
+set -f
+for PATTERN in $(params.immutable-tags); do
+  case "${TAG}" in
+    ${PATTERN})
+      if oras manifest fetch --descriptor "${REFERENCE}" > /dev/null 2>&1; then
+        echo -n "Tag ${TAG} of ${REFERENCE} already exists and is immutable (${PATTERN}); publish a new version" > /tekton/results/rejected
+        cat /tekton/results/rejected
+        echo
+        exit 0
+      fi
+      ;;
+  esac
+done
+set +f
+
+echo "Pushing ${ARTIFACT} to ${REFERENCE}"
+oras push --disable-path-validation "${REFERENCE}" \
+  "${ARTIFACT}:application/vnd.oci.image.layer.v1.tar"
+
+echo -n "${REFERENCE}" > /tekton/results/location
+echo "Artifact pushed to ${REFERENCE}"
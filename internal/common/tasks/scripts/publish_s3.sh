@@ -0,0 +1,27 @@
+#!/bin/sh
+set -e
+
+ARTIFACT="$(params.artifact-filename)"
+BUCKET="$(params.bucket)"
+PREFIX="$(params.prefix)"
+KEY="${PREFIX:+${PREFIX%/}/}${ARTIFACT}"
+CREDENTIALS=/workspace/publish-credentials
+
+# read from the mounted secret rather than the environment, so the keys do not show up in the pod spec
+AWS_ACCESS_KEY_ID="$(cat "${CREDENTIALS}/AWS_ACCESS_KEY_ID")"
+AWS_SECRET_ACCESS_KEY="$(cat "${CREDENTIALS}/AWS_SECRET_ACCESS_KEY")"
+export AWS_ACCESS_KEY_ID AWS_SECRET_ACCESS_KEY
+if [ -n "$(params.region)" ]; then
+  export AWS_DEFAULT_REGION="$(params.region)"
+fi
+
+set --
+if [ -n "$(params.endpoint)" ]; then
+  set -- --endpoint-url "$(params.endpoint)"
+fi
+
+echo "Uploading ${ARTIFACT} to s3://${BUCKET}/${KEY}"
+aws "$@" s3 cp --no-progress "${ARTIFACT}" "s3://${BUCKET}/${KEY}"
+
+echo -n "s3://${BUCKET}/${KEY}" > /tekton/results/location
+echo "Artifact uploaded to s3://${BUCKET}/${KEY}"
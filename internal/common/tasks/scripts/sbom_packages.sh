@@ -0,0 +1,101 @@
+#!/bin/sh
+set -e
+
+write_result() {
+  echo -n "$1" > /tekton/results/packages-filename || echo "Failed to write packages filename"
+}
+
+if [ ! -e /output/disk.img ] || [ -d "$(readlink -f /output/disk.img)" ]; then
+  echo "Package inventory needs an image or qcow2 export, skipping SBOM"
+  exit 0
+fi
+
+work_dir=/output/_sbom
+mkdir -p "$work_dir" "$work_dir/mnt"
+
+raw_image=$(readlink -f /output/disk.img)
+if [ "$(params.export-format)" = "qcow2" ]; then
+  if ! command -v qemu-img >/dev/null 2>&1; then
+    dnf -y install qemu-img || true
+  fi
+  echo "Converting qcow2 image to raw for reading the package database..."
+  qemu-img convert -O raw "$raw_image" "$work_dir/disk.raw" || { echo "error: qcow2 conversion failed"; exit 0; }
+  raw_image="$work_dir/disk.raw"
+fi
+
+loop_dev=$(losetup -f -P -r --show "$raw_image") || { echo "error: could not attach $raw_image"; exit 0; }
+cleanup() {
+  umount "$work_dir/mnt" 2>/dev/null || true
+  losetup -d "$loop_dev" 2>/dev/null || true
+  rm -f "$work_dir/disk.raw"
+}
+trap cleanup EXIT
+
+# Find the partition holding the root filesystem; for ostree images the deployment is the root
+rootfs=""
+for part in "${loop_dev}"p*; do
+  [ -b "$part" ] || continue
+  if ! mount -o ro "$part" "$work_dir/mnt" 2>/dev/null; then
+    continue
+  fi
+  deploy=$(ls -d "$work_dir"/mnt/ostree/deploy/*/deploy/*/ 2>/dev/null | head -n1)
+  if [ -n "$deploy" ]; then
+    rootfs="${deploy%/}"
+    break
+  fi
+  if [ -f "$work_dir/mnt/etc/os-release" ] || [ -f "$work_dir/mnt/usr/lib/os-release" ]; then
+    rootfs="$work_dir/mnt"
+    break
+  fi
+  umount "$work_dir/mnt"
+done
+
+if [ -z "$rootfs" ]; then
+  echo "error: no root filesystem found in $raw_image"
+  exit 0
+fi
+
+json_escape() {
+  sed -e 's/\\/\\\\/g' -e 's/"/\\"/g'
+}
+
+os_release="$rootfs/etc/os-release"
+[ -f "$os_release" ] || os_release="$rootfs/usr/lib/os-release"
+os_field() {
+  sed -n "s/^$1=//p" "$os_release" 2>/dev/null | head -n1 | sed -e 's/^"//' -e 's/"$//' | json_escape
+}
+
+# Every installed package but the gpg-pubkey pseudo packages, which have no architecture
+packages=""
+for db in usr/lib/sysimage/rpm var/lib/rpm usr/share/rpm; do
+  if [ -d "$rootfs/$db" ] && [ -n "$(ls -A "$rootfs/$db" 2>/dev/null)" ]; then
+    packages=$(rpm --root "$rootfs" --dbpath "/$db" -qa \
+      --queryformat '%{NAME}\t%{EPOCHNUM}\t%{VERSION}\t%{RELEASE}\t%{ARCH}\t%{LICENSE}\t%{VENDOR}\t%{SOURCERPM}\n' 2>/dev/null \
+      | sort \
+      | json_escape \
+      | awk -F'\t' '$5 != "(none)" {
+          for (i = 6; i <= 8; i++) if ($i == "(none)") $i = ""
+          printf "%s    {\"name\": \"%s\", \"epoch\": \"%s\", \"version\": \"%s\", \"release\": \"%s\", \"arch\": \"%s\", \"license\": \"%s\", \"vendor\": \"%s\", \"sourceRpm\": \"%s\"}", (n++ ? ",\n" : ""), $1, $2, $3, $4, $5, $6, $7, $8
+        }' OFS='\t')
+    break
+  fi
+done
+
+if [ -z "$packages" ]; then
+  echo "error: no RPM database found in the root filesystem"
+  exit 0
+fi
+
+inventory_name="installed-rpms.json"
+cat > "$(workspaces.shared-workspace.path)/$inventory_name" <<EOF
+{
+  "os": {"id": "$(os_field ID)", "versionId": "$(os_field VERSION_ID)", "name": "$(os_field PRETTY_NAME)"},
+  "packages": [
+$packages
+  ]
+}
+EOF
+echo "Package inventory of $(echo "$packages" | wc -l) packages written to $inventory_name"
+
+write_result "$inventory_name"
+sync
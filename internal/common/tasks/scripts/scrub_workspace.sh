@@ -0,0 +1,28 @@
+#!/bin/bash
+set -euo pipefail
+
+WORKSPACE=/workspace/shared
+files=0
+bytes=0
+
+# Every regular file is overwritten with random data and then zeros before it is removed, so the
+# blocks released to the storage backend do not hold recoverable image content
+while IFS= read -r -d '' file; do
+  size=$(stat -c %s "$file")
+  shred --iterations=1 --zero --remove=wipesync "$file"
+  files=$((files + 1))
+  bytes=$((bytes + size))
+done < <(find "$WORKSPACE" -xdev -type f -print0)
+
+find "$WORKSPACE" -xdev -mindepth 1 -depth -delete
+sync
+
+remaining=$(find "$WORKSPACE" -mindepth 1 | wc -l)
+if [ "$remaining" -ne 0 ]; then
+  echo "error: ${remaining} entries are left in the workspace"
+  exit 1
+fi
+
+echo -n "$files" > /tekton/results/files
+echo -n "$bytes" > /tekton/results/bytes
+echo "Scrubbed ${files} files (${bytes} bytes)"
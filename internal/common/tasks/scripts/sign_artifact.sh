@@ -0,0 +1,45 @@
+#!/bin/sh
+set -eu
+
+ARTIFACT="$(params.artifact-filename)"
+BUNDLE="${ARTIFACT}.sigstore.json"
+KEY=/workspace/signing-key/cosign.key
+
+if [ ! -f "${ARTIFACT}" ]; then
+  echo "Artifact ${ARTIFACT} not found in the workspace"
+  exit 1
+fi
+rm -f "${BUNDLE}"
+
+case "$(params.method)" in
+  keyless)
+    cosign sign-blob --yes \
+      --fulcio-url "$(params.fulcio-url)" \
+      --rekor-url "$(params.rekor-url)" \
+      --identity-token /var/run/sigstore/token \
+      --output-certificate /tekton/home/signing.crt \
+      --bundle "${BUNDLE}" "${ARTIFACT}"
+    # the controller reads the identity and issuer of the signer from the certificate
+    cat /tekton/home/signing.crt > /tekton/results/certificate
+    ;;
+  *)
+    # keys without a password have no cosign.password
+    export COSIGN_PASSWORD="${COSIGN_PASSWORD:-}"
+    # the signature is only uploaded to the Rekor the operator configured, never to the public one
+    REKOR_URL="$(params.rekor-url)"
+    if [ -n "${REKOR_URL}" ]; then
+      set -- --rekor-url "${REKOR_URL}"
+    else
+      set -- --tlog-upload=false
+    fi
+    cosign sign-blob --yes \
+      --key "${KEY}" \
+      "$@" \
+      --bundle "${BUNDLE}" "${ARTIFACT}"
+    # the fingerprint is the SHA-256 of the DER encoded public key
+    FINGERPRINT=$(cosign public-key --key "${KEY}" | sed '1d;$d' | base64 -d | sha256sum | cut -d' ' -f1)
+    echo -n "SHA256:${FINGERPRINT}" > /tekton/results/identity
+    ;;
+esac
+
+echo "Signed ${ARTIFACT}, the cosign bundle is ${BUNDLE}"
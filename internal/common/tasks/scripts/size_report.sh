@@ -0,0 +1,112 @@
+#!/bin/sh
+set -e
+
+BUDGET="$(params.size-budget)"
+
+if [ -z "$BUDGET" ]; then
+  echo "No size budget requested, skipping size report"
+  exit 0
+fi
+
+write_result() {
+  echo -n "$1" > /tekton/results/rootfs-size || echo "Failed to write rootfs size"
+}
+
+if [ ! -e /output/disk.img ] || [ -d "$(readlink -f /output/disk.img)" ]; then
+  echo "error: size breakdown needs an image or qcow2 export, /output/disk.img is missing or a directory"
+  exit 0
+fi
+
+work_dir=/output/_size
+mkdir -p "$work_dir" "$work_dir/mnt"
+
+raw_image=$(readlink -f /output/disk.img)
+image_bytes=$(stat -L -c %s "$raw_image")
+if [ "$(params.export-format)" = "qcow2" ]; then
+  if ! command -v qemu-img >/dev/null 2>&1; then
+    dnf -y install qemu-img || true
+  fi
+  echo "Converting qcow2 image to raw for measuring..."
+  qemu-img convert -O raw "$raw_image" "$work_dir/disk.raw"
+  raw_image="$work_dir/disk.raw"
+fi
+
+loop_dev=$(losetup -f -P -r --show "$raw_image")
+cleanup() {
+  umount "$work_dir/mnt" 2>/dev/null || true
+  losetup -d "$loop_dev" 2>/dev/null || true
+  rm -f "$work_dir/disk.raw"
+}
+trap cleanup EXIT
+
+# Find the partition holding the root filesystem; for ostree images the deployment is the root
+rootfs=""
+for part in "${loop_dev}"p*; do
+  [ -b "$part" ] || continue
+  if ! mount -o ro "$part" "$work_dir/mnt" 2>/dev/null; then
+    continue
+  fi
+  deploy=$(ls -d "$work_dir"/mnt/ostree/deploy/*/deploy/*/ 2>/dev/null | head -n1)
+  if [ -n "$deploy" ]; then
+    rootfs="${deploy%/}"
+    break
+  fi
+  if [ -f "$work_dir/mnt/etc/os-release" ] || [ -f "$work_dir/mnt/usr/lib/os-release" ]; then
+    rootfs="$work_dir/mnt"
+    break
+  fi
+  umount "$work_dir/mnt"
+done
+
+if [ -z "$rootfs" ]; then
+  echo "error: no root filesystem found in $raw_image"
+  exit 0
+fi
+
+rootfs_bytes=$(du -sx --block-size=1 "$rootfs" | cut -f1)
+echo "Root filesystem uses $rootfs_bytes bytes (budget $BUDGET bytes)"
+
+json_escape() {
+  sed -e 's/\\/\\\\/g' -e 's/"/\\"/g'
+}
+
+# Installed size of every package, largest first
+packages=""
+for db in usr/lib/sysimage/rpm var/lib/rpm usr/share/rpm; do
+  if [ -d "$rootfs/$db" ] && [ -n "$(ls -A "$rootfs/$db" 2>/dev/null)" ]; then
+    packages=$(rpm --root "$rootfs" --dbpath "/$db" -qa --queryformat '%{NAME}\t%{SIZE}\n' 2>/dev/null \
+      | sort -t "$(printf '\t')" -k2 -rn \
+      | json_escape \
+      | awk -F'\t' '{ printf "%s    {\"name\": \"%s\", \"bytes\": %s}", (NR > 1 ? ",\n" : ""), $1, $2 }')
+    break
+  fi
+done
+
+# Largest directories up to three levels below the root
+directories=$(du -x --block-size=1 --max-depth=3 "$rootfs" 2>/dev/null \
+  | sort -rn \
+  | head -n 100 \
+  | json_escape \
+  | awk -F'\t' -v root="$rootfs" '{
+      p = substr($2, length(root) + 1); if (p == "") p = "/"
+      printf "%s    {\"path\": \"%s\", \"bytes\": %s}", (NR > 1 ? ",\n" : ""), p, $1
+    }')
+
+report_name="size-report.json"
+cat > "$(workspaces.shared-workspace.path)/$report_name" <<EOF
+{
+  "rootfsBytes": $rootfs_bytes,
+  "imageBytes": $image_bytes,
+  "budgetBytes": $BUDGET,
+  "packages": [
+$packages
+  ],
+  "directories": [
+$directories
+  ]
+}
+EOF
+echo "Size breakdown written to $report_name"
+
+write_result "$rootfs_bytes"
+sync
@@ -23,6 +23,9 @@ type BuildConfig struct {
 
 const AutomotiveImageBuilder = "quay.io/centos-sig-automotive/automotive-image-builder:1.0.0"
 
+// DefaultBootReadyMarker matches the serial console line that ends a boot: the multi-user target or a login prompt
+const DefaultBootReadyMarker = "Reached target .*[Mm]ulti-[Uu]ser|login:"
+
 // GeneratePushArtifactRegistryTask creates a Tekton Task for pushing artifacts to a registry
 func GeneratePushArtifactRegistryTask(namespace string) *tektonv1.Task {
 	return &tektonv1.Task{
@@ -108,6 +111,355 @@ func GeneratePushArtifactRegistryTask(namespace string) *tektonv1.Task {
 	}
 }
 
+// Kinds of publish targets supported by GeneratePublishArtifactTask
+const (
+	PublishKindRegistry = "registry"
+	PublishKindS3       = "s3"
+	PublishKindPXE      = "pxe"
+)
+
+// publishParams are the params of the publish task of each kind, besides artifact-filename
+var publishParams = map[string][]string{
+	PublishKindRegistry: {"repository-url", "immutable-tags"},
+	PublishKindS3:       {"bucket", "prefix", "endpoint", "region"},
+	PublishKindPXE:      {"url"},
+}
+
+// GeneratePublishArtifactTask creates a Tekton Task that publishes the artifact in the shared workspace
+// to a target of the given kind and reports where it went in the location result, or why the target
+// refused it in the rejected result. secretRef names the secret with the credentials of the target;
+// it is mounted at /workspace/publish-credentials, or as docker config for registries, and may be
+// empty for PXE servers that accept anonymous uploads.
+func GeneratePublishArtifactTask(namespace, kind, secretRef string) *tektonv1.Task {
+	params := []tektonv1.ParamSpec{
+		{
+			Name:        "artifact-filename",
+			Type:        tektonv1.ParamTypeString,
+			Description: "Name of the artifact file in the shared workspace",
+		},
+	}
+	for _, name := range publishParams[kind] {
+		params = append(params, tektonv1.ParamSpec{
+			Name:    name,
+			Type:    tektonv1.ParamTypeString,
+			Default: &tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: ""},
+		})
+	}
+
+	step := tektonv1.Step{
+		Name:       "publish-" + kind,
+		WorkingDir: "/workspace/shared",
+	}
+	mount := corev1.VolumeMount{
+		Name:      "publish-credentials",
+		MountPath: "/workspace/publish-credentials",
+		ReadOnly:  true,
+	}
+	switch kind {
+	case PublishKindRegistry:
+		step.Image = "ghcr.io/oras-project/oras:v1.2.0"
+		step.Script = PublishRegistryScript
+		step.Env = []corev1.EnvVar{{Name: "DOCKER_CONFIG", Value: "/tekton/home/.docker"}}
+		mount.MountPath = "/tekton/home/.docker/config.json"
+		mount.SubPath = ".dockerconfigjson"
+	case PublishKindS3:
+		step.Image = "docker.io/amazon/aws-cli:2.17.0"
+		step.Script = PublishS3Script
+	case PublishKindPXE:
+		step.Image = "docker.io/curlimages/curl:8.8.0"
+		step.Script = PublishPXEScript
+	}
+
+	var volumes []corev1.Volume
+	if secretRef != "" {
+		step.VolumeMounts = []corev1.VolumeMount{mount}
+		volumes = []corev1.Volume{
+			{
+				Name: "publish-credentials",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{SecretName: secretRef},
+				},
+			},
+		}
+	}
+
+	return &tektonv1.Task{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "tekton.dev/v1",
+			Kind:       "Task",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "publish-artifact-" + kind,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "automotive-dev-operator",
+				"app.kubernetes.io/part-of":    "automotive-dev",
+			},
+		},
+		Spec: tektonv1.TaskSpec{
+			Params: params,
+			Workspaces: []tektonv1.WorkspaceDeclaration{
+				{
+					Name:        "shared-workspace",
+					Description: "Workspace containing the build artifacts",
+					MountPath:   "/workspace/shared",
+				},
+			},
+			Results: []tektonv1.TaskResult{
+				{
+					Name:        "location",
+					Description: "where the artifact was published, e.g. an image reference or object URL",
+				},
+				{
+					Name:        "rejected",
+					Description: "why the target refused the artifact without publishing it, e.g. an immutable tag that already exists",
+				},
+			},
+			Steps:   []tektonv1.Step{step},
+			Volumes: volumes,
+		},
+	}
+}
+
+// ScrubMethod describes how GenerateScrubWorkspaceTask overwrites the files of a workspace
+const ScrubMethod = "shred: one pass of random data, one of zeros, then unlink"
+
+// GenerateScrubWorkspaceTask creates a Tekton Task that overwrites and removes every file of the
+// shared workspace. The step runs privileged like the build step, so it can remove files the build
+// created as root; the image param selects a builder image, which ships shred.
+func GenerateScrubWorkspaceTask(namespace string) *tektonv1.Task {
+	return &tektonv1.Task{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "tekton.dev/v1",
+			Kind:       "Task",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "scrub-workspace",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "automotive-dev-operator",
+				"app.kubernetes.io/part-of":    "automotive-dev",
+			},
+		},
+		Spec: tektonv1.TaskSpec{
+			Params: []tektonv1.ParamSpec{
+				{
+					Name:        "automotive-image-builder",
+					Type:        tektonv1.ParamTypeString,
+					Description: "Image the scrub step runs in",
+					Default:     &tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: AutomotiveImageBuilder},
+				},
+			},
+			Workspaces: []tektonv1.WorkspaceDeclaration{
+				{
+					Name:        "shared-workspace",
+					Description: "Workspace to scrub",
+					MountPath:   "/workspace/shared",
+				},
+			},
+			Results: []tektonv1.TaskResult{
+				{Name: "files", Description: "number of files overwritten"},
+				{Name: "bytes", Description: "total size of the overwritten files"},
+			},
+			Steps: []tektonv1.Step{
+				{
+					Name:  "scrub",
+					Image: "$(params.automotive-image-builder)",
+					SecurityContext: &corev1.SecurityContext{
+						Privileged: ptr.To(true),
+						SELinuxOptions: &corev1.SELinuxOptions{
+							Type: "unconfined_t",
+						},
+					},
+					Script: ScrubWorkspaceScript,
+				},
+			},
+		},
+	}
+}
+
+// GenerateArchiveArtifactsTask creates a Tekton Task that copies the artifacts of a completed build
+// from its workspace to an archive PVC and verifies every file of the copy
+func GenerateArchiveArtifactsTask(namespace string) *tektonv1.Task {
+	return &tektonv1.Task{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "tekton.dev/v1",
+			Kind:       "Task",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "archive-artifacts",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "automotive-dev-operator",
+				"app.kubernetes.io/part-of":    "automotive-dev",
+			},
+		},
+		Spec: tektonv1.TaskSpec{
+			Params: []tektonv1.ParamSpec{
+				{
+					Name:        "automotive-image-builder",
+					Type:        tektonv1.ParamTypeString,
+					Description: "Image the copy step runs in",
+					Default:     &tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: AutomotiveImageBuilder},
+				},
+			},
+			Workspaces: []tektonv1.WorkspaceDeclaration{
+				{
+					Name:        "shared-workspace",
+					Description: "Workspace holding the artifacts",
+					MountPath:   "/workspace/shared",
+					ReadOnly:    true,
+				},
+				{
+					Name:        "archive",
+					Description: "Archive PVC the artifacts are copied to",
+					MountPath:   "/workspace/archive",
+				},
+			},
+			Results: []tektonv1.TaskResult{
+				{Name: "bytes", Description: "total size of the archived files"},
+			},
+			Steps: []tektonv1.Step{
+				{
+					Name:  "archive",
+					Image: "$(params.automotive-image-builder)",
+					// the files were written by the privileged build step
+					SecurityContext: &corev1.SecurityContext{
+						Privileged: ptr.To(true),
+						SELinuxOptions: &corev1.SELinuxOptions{
+							Type: "unconfined_t",
+						},
+					},
+					Script: ArchiveArtifactsScript,
+				},
+			},
+		},
+	}
+}
+
+// CosignImage is the image the signing step runs cosign in; the -dev variant has a shell
+const CosignImage = "ghcr.io/sigstore/cosign/cosign:v2.4.1-dev"
+
+// Signing methods of GenerateSignArtifactTask
+const (
+	SigningMethodKey     = "key"
+	SigningMethodKeyless = "keyless"
+)
+
+// PublicRekorURL is the public Rekor instance keyless signatures are recorded in by default
+const PublicRekorURL = "https://rekor.sigstore.dev"
+
+// GenerateSignArtifactTask creates a Tekton Task that signs the artifact in the shared workspace
+// with cosign and writes the bundle next to it. With a keySecretRef the key in the Secret signs;
+// without one the signature is keyless, with a certificate Fulcio issues for a service account
+// token projected for the sigstore audience.
+func GenerateSignArtifactTask(namespace, keySecretRef string) *tektonv1.Task {
+	method := SigningMethodKeyless
+	step := tektonv1.Step{
+		Name:       "sign",
+		Image:      CosignImage,
+		WorkingDir: "/workspace/shared",
+		// the workspace was written by the privileged build step
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: ptr.To(true),
+			RunAsUser:  ptr.To(int64(0)),
+			SELinuxOptions: &corev1.SELinuxOptions{
+				Type: "unconfined_t",
+			},
+		},
+		Script: SignArtifactScript,
+	}
+	var volumes []corev1.Volume
+	if keySecretRef != "" {
+		method = SigningMethodKey
+		step.Env = []corev1.EnvVar{{
+			Name: "COSIGN_PASSWORD",
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: keySecretRef},
+				Key:                  "cosign.password",
+				Optional:             ptr.To(true),
+			}},
+		}}
+		step.VolumeMounts = []corev1.VolumeMount{{Name: "signing-key", MountPath: "/workspace/signing-key", ReadOnly: true}}
+		volumes = []corev1.Volume{{
+			Name: "signing-key",
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+				SecretName: keySecretRef,
+				Items:      []corev1.KeyToPath{{Key: "cosign.key", Path: "cosign.key"}},
+			}},
+		}}
+	} else {
+		step.VolumeMounts = []corev1.VolumeMount{{Name: "sigstore-token", MountPath: "/var/run/sigstore", ReadOnly: true}}
+		volumes = []corev1.Volume{{
+			Name: "sigstore-token",
+			VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{{
+					ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+						Audience:          "sigstore",
+						ExpirationSeconds: ptr.To(int64(600)),
+						Path:              "token",
+					},
+				}},
+			}},
+		}}
+	}
+
+	return &tektonv1.Task{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "tekton.dev/v1",
+			Kind:       "Task",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sign-artifact-" + method,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "automotive-dev-operator",
+				"app.kubernetes.io/part-of":    "automotive-dev",
+			},
+		},
+		Spec: tektonv1.TaskSpec{
+			Params: []tektonv1.ParamSpec{
+				{
+					Name:        "artifact-filename",
+					Type:        tektonv1.ParamTypeString,
+					Description: "Name of the artifact file in the shared workspace",
+				},
+				{
+					Name:        "method",
+					Type:        tektonv1.ParamTypeString,
+					Description: "key or keyless",
+					Default:     &tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: method},
+				},
+				{
+					Name:        "fulcio-url",
+					Type:        tektonv1.ParamTypeString,
+					Description: "Fulcio instance issuing keyless certificates",
+					Default:     &tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: "https://fulcio.sigstore.dev"},
+				},
+				{
+					Name:        "rekor-url",
+					Type:        tektonv1.ParamTypeString,
+					Description: "Transparency log the signature is recorded in; empty records key-based signatures nowhere",
+					Default:     &tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: PublicRekorURL},
+				},
+			},
+			Workspaces: []tektonv1.WorkspaceDeclaration{
+				{
+					Name:        "shared-workspace",
+					Description: "Workspace containing the build artifacts",
+					MountPath:   "/workspace/shared",
+				},
+			},
+			Results: []tektonv1.TaskResult{
+				{Name: "identity", Description: "SHA-256 fingerprint of the public key of a key signature"},
+				{Name: "certificate", Description: "PEM certificate of a keyless signature"},
+			},
+			Steps:   []tektonv1.Step{step},
+			Volumes: volumes,
+		},
+	}
+}
+
 // GenerateBuildAutomotiveImageTask creates a Tekton Task for building automotive images
 func GenerateBuildAutomotiveImageTask(namespace string, buildConfig *BuildConfig, envSecretRef string) *tektonv1.Task {
 	task := &tektonv1.Task{
@@ -153,12 +505,147 @@ func GenerateBuildAutomotiveImageTask(namespace string, buildConfig *BuildConfig
 				{
 					Name:        "compression",
 					Type:        tektonv1.ParamTypeString,
-					Description: "Compression algorithm for artifacts (lz4, gzip)",
+					Description: "Compression algorithm for artifacts (gzip, lz4, zstd)",
 					Default: &tektonv1.ParamValue{
 						Type:      tektonv1.ParamTypeString,
 						StringVal: "gzip",
 					},
 				},
+				{
+					Name:        "firstboot-file",
+					Type:        tektonv1.ParamTypeString,
+					Description: "Path of the first-boot provisioning payload, empty if none",
+					Default: &tektonv1.ParamValue{
+						Type:      tektonv1.ParamTypeString,
+						StringVal: "",
+					},
+				},
+				{
+					Name:        "firstboot-embed-path",
+					Type:        tektonv1.ParamTypeString,
+					Description: "In-image path the first-boot payload is embedded at, empty to not embed",
+					Default: &tektonv1.ParamValue{
+						Type:      tektonv1.ParamTypeString,
+						StringVal: "",
+					},
+				},
+				{
+					Name:        "firstboot-artifact",
+					Type:        tektonv1.ParamTypeString,
+					Description: "File name the first-boot payload is attached as, empty to not attach",
+					Default: &tektonv1.ParamValue{
+						Type:      tektonv1.ParamTypeString,
+						StringVal: "",
+					},
+				},
+				{
+					Name:        "hardening-fragment",
+					Type:        tektonv1.ParamTypeString,
+					Description: "Path of the hardening manifest fragment merged into the manifest, empty if none",
+					Default: &tektonv1.ParamValue{
+						Type:      tektonv1.ParamTypeString,
+						StringVal: "",
+					},
+				},
+				{
+					Name:        "hardening-report",
+					Type:        tektonv1.ParamTypeString,
+					Description: "Path of the hardening compliance report template, empty if none",
+					Default: &tektonv1.ParamValue{
+						Type:      tektonv1.ParamTypeString,
+						StringVal: "",
+					},
+				},
+				{
+					Name:        "compliance-profile",
+					Type:        tektonv1.ParamTypeString,
+					Description: "OpenSCAP profile to evaluate the built root filesystem against, empty to skip the scan",
+					Default: &tektonv1.ParamValue{
+						Type:      tektonv1.ParamTypeString,
+						StringVal: "",
+					},
+				},
+				{
+					Name:        "compliance-datastream",
+					Type:        tektonv1.ParamTypeString,
+					Description: "SCAP source data stream path, empty to pick the SSG content matching the image OS",
+					Default: &tektonv1.ParamValue{
+						Type:      tektonv1.ParamTypeString,
+						StringVal: "",
+					},
+				},
+				{
+					Name:        "size-budget",
+					Type:        tektonv1.ParamTypeString,
+					Description: "Root filesystem size budget in bytes, empty to skip the size breakdown",
+					Default: &tektonv1.ParamValue{
+						Type:      tektonv1.ParamTypeString,
+						StringVal: "",
+					},
+				},
+				{
+					Name:        "boot-test-timeout",
+					Type:        tektonv1.ParamTypeString,
+					Description: "Seconds the built image may take to boot in QEMU, empty to skip the boot test",
+					Default: &tektonv1.ParamValue{
+						Type:      tektonv1.ParamTypeString,
+						StringVal: "",
+					},
+				},
+				{
+					Name:        "git-url",
+					Type:        tektonv1.ParamTypeString,
+					Description: "URL of the git repository holding the manifest, empty to take the manifest from the ConfigMap",
+					Default: &tektonv1.ParamValue{
+						Type:      tektonv1.ParamTypeString,
+						StringVal: "",
+					},
+				},
+				{
+					Name:        "git-ref",
+					Type:        tektonv1.ParamTypeString,
+					Description: "Branch, tag or commit SHA of the git repository to build, empty for the default branch",
+					Default: &tektonv1.ParamValue{
+						Type:      tektonv1.ParamTypeString,
+						StringVal: "",
+					},
+				},
+				{
+					Name:        "git-path",
+					Type:        tektonv1.ParamTypeString,
+					Description: "Path of the manifest in the git repository",
+					Default: &tektonv1.ParamValue{
+						Type:      tektonv1.ParamTypeString,
+						StringVal: "",
+					},
+				},
+				{
+					Name:        "debug-hold-minutes",
+					Type:        tektonv1.ParamTypeString,
+					Description: "Minutes to keep the pod after the build step fails, empty to fail immediately",
+					Default: &tektonv1.ParamValue{
+						Type:      tektonv1.ParamTypeString,
+						StringVal: "",
+					},
+				},
+				{
+					Name:        "request-id",
+					Type:        tektonv1.ParamTypeString,
+					Description: "ID of the API request that created the build, exported to the steps as BUILD_REQUEST_ID",
+					Default: &tektonv1.ParamValue{
+						Type:      tektonv1.ParamTypeString,
+						StringVal: "",
+					},
+				},
+				{
+					Name:        "boot-ready-marker",
+					Type:        tektonv1.ParamTypeString,
+					Description: "Extended regular expression matched on the serial console that marks the end of boot",
+					Default: &tektonv1.ParamValue{
+						Type:      tektonv1.ParamTypeString,
+						StringVal: DefaultBootReadyMarker,
+					},
+				},
 				{
 					Name:        "automotive-image-builder",
 					Type:        tektonv1.ParamTypeString,
@@ -174,10 +661,62 @@ func GenerateBuildAutomotiveImageTask(namespace string, buildConfig *BuildConfig
 					Name:        "manifest-file-path",
 					Description: "Path to the manifest file used for building",
 				},
+				{
+					Name:        "git-commit",
+					Description: "commit the git ref resolved to, empty when the manifest came from the ConfigMap",
+				},
 				{
 					Name:        "artifact-filename",
 					Description: "artifact filename placed in the shared workspace",
 				},
+				{
+					Name:        "artifact-size",
+					Description: "size in bytes of the final artifact",
+				},
+				{
+					Name:        "artifact-sha256",
+					Description: "hex encoded SHA-256 digest of the final artifact",
+				},
+				{
+					Name:        "stage-timings",
+					Description: "comma-separated stage=seconds durations measured in the build step",
+				},
+				{
+					Name:        "build-environment",
+					Description: "comma-separated key=value tool versions, SELinux mode and sysctls seen by the build step",
+				},
+				{
+					Name:        "firstboot-filename",
+					Description: "first-boot payload attached as a secondary artifact in the shared workspace",
+				},
+				{
+					Name:        "hardening-report-filename",
+					Description: "hardening compliance report published as a secondary artifact in the shared workspace",
+				},
+				{
+					Name:        "compliance-result",
+					Description: "OpenSCAP scan outcome (pass, fail or error), empty when no scan was requested",
+				},
+				{
+					Name:        "rootfs-size",
+					Description: "bytes used by the built root filesystem, empty when no size budget was requested",
+				},
+				{
+					Name:        "boot-test-result",
+					Description: "QEMU boot test outcome (booted, timeout or error), empty when no boot test was requested",
+				},
+				{
+					Name:        "boot-timings",
+					Description: "comma-separated firmware, kernelToReady and total boot durations in milliseconds",
+				},
+				{
+					Name:        "boot-accelerator",
+					Description: "QEMU accelerator used for the boot test (kvm or tcg)",
+				},
+				{
+					Name:        "packages-filename",
+					Description: "inventory of the installed RPMs in the shared workspace, the source of the SBOM of the image",
+				},
 			},
 			Workspaces: []tektonv1.WorkspaceDeclaration{
 				{
@@ -191,11 +730,23 @@ func GenerateBuildAutomotiveImageTask(namespace string, buildConfig *BuildConfig
 					MountPath:   "/workspace/manifest-config",
 				},
 			},
+			StepTemplate: &tektonv1.StepTemplate{
+				Env: []corev1.EnvVar{
+					{Name: "BUILD_REQUEST_ID", Value: "$(params.request-id)"},
+				},
+			},
 			Steps: []tektonv1.Step{
+				{
+					Name:   "clone-git-source",
+					Image:  "docker.io/alpine/git:2.45.2",
+					Script: CloneGitSourceScript,
+					Env:    gitSourceEnv(),
+				},
 				{
 					Name:   "find-manifest-file",
 					Image:  "quay.io/konflux-ci/yq:latest",
 					Script: FindManifestScript,
+					Env:    gitSourceEnv(),
 					VolumeMounts: []corev1.VolumeMount{
 						{
 							Name:      "manifest-work",
@@ -217,6 +768,8 @@ func GenerateBuildAutomotiveImageTask(namespace string, buildConfig *BuildConfig
 					},
 					Script:  BuildImageScript,
 					EnvFrom: buildEnvFrom(envSecretRef),
+					// debug-hold fails the TaskRun with the build exit code, optionally after keeping the pod
+					OnError: tektonv1.Continue,
 					VolumeMounts: []corev1.VolumeMount{
 						{
 							Name:      "build-dir",
@@ -240,6 +793,123 @@ func GenerateBuildAutomotiveImageTask(namespace string, buildConfig *BuildConfig
 						},
 					},
 				},
+				{
+					Name:  "debug-hold",
+					Image: "$(params.automotive-image-builder)",
+					SecurityContext: &corev1.SecurityContext{
+						Privileged: ptr.To(true),
+						SELinuxOptions: &corev1.SELinuxOptions{
+							Type: "unconfined_t",
+						},
+					},
+					Script: DebugHoldScript,
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      "build-dir",
+							MountPath: "/_build",
+						},
+						{
+							Name:      "output-dir",
+							MountPath: "/output",
+						},
+						{
+							Name:      "run-dir",
+							MountPath: "/run/osbuild",
+						},
+						{
+							Name:      "dev",
+							MountPath: "/dev",
+						},
+						{
+							Name:      "manifest-work",
+							MountPath: "/manifest-work",
+						},
+					},
+				},
+				{
+					Name:  "size-report",
+					Image: "$(params.automotive-image-builder)",
+					SecurityContext: &corev1.SecurityContext{
+						Privileged: ptr.To(true),
+						SELinuxOptions: &corev1.SELinuxOptions{
+							Type: "unconfined_t",
+						},
+					},
+					Script: SizeReportScript,
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      "output-dir",
+							MountPath: "/output",
+						},
+						{
+							Name:      "dev",
+							MountPath: "/dev",
+						},
+					},
+				},
+				{
+					Name:  "sbom-packages",
+					Image: "$(params.automotive-image-builder)",
+					SecurityContext: &corev1.SecurityContext{
+						Privileged: ptr.To(true),
+						SELinuxOptions: &corev1.SELinuxOptions{
+							Type: "unconfined_t",
+						},
+					},
+					Script: SBOMPackagesScript,
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      "output-dir",
+							MountPath: "/output",
+						},
+						{
+							Name:      "dev",
+							MountPath: "/dev",
+						},
+					},
+				},
+				{
+					Name:  "boot-test",
+					Image: "$(params.automotive-image-builder)",
+					SecurityContext: &corev1.SecurityContext{
+						Privileged: ptr.To(true),
+						SELinuxOptions: &corev1.SELinuxOptions{
+							Type: "unconfined_t",
+						},
+					},
+					Script: BootTestScript,
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      "output-dir",
+							MountPath: "/output",
+						},
+						{
+							Name:      "dev",
+							MountPath: "/dev",
+						},
+					},
+				},
+				{
+					Name:  "compliance-scan",
+					Image: "$(params.automotive-image-builder)",
+					SecurityContext: &corev1.SecurityContext{
+						Privileged: ptr.To(true),
+						SELinuxOptions: &corev1.SELinuxOptions{
+							Type: "unconfined_t",
+						},
+					},
+					Script: ComplianceScanScript,
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      "output-dir",
+							MountPath: "/output",
+						},
+						{
+							Name:      "dev",
+							MountPath: "/dev",
+						},
+					},
+				},
 			},
 			Volumes: []corev1.Volume{
 				{
@@ -366,7 +1036,7 @@ func GenerateTektonPipeline(name, namespace string) *tektonv1.Pipeline {
 						Type:      tektonv1.ParamTypeString,
 						StringVal: "lz4",
 					},
-					Description: "Compression algorithm for artifacts (lz4, gzip)",
+					Description: "Compression algorithm for artifacts (gzip, lz4, zstd)",
 				},
 				{
 					Name:        "storage-class",
@@ -588,6 +1258,15 @@ func GenerateTektonPipeline(name, namespace string) *tektonv1.Pipeline {
 	return pipeline
 }
 
+// gitSourceEnv passes the git source params to the steps that read them
+func gitSourceEnv() []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{Name: "GIT_URL", Value: "$(params.git-url)"},
+		{Name: "GIT_REF", Value: "$(params.git-ref)"},
+		{Name: "GIT_PATH", Value: "$(params.git-path)"},
+	}
+}
+
 func buildEnvFrom(envSecretRef string) []corev1.EnvFromSource {
 	if envSecretRef == "" {
 		return nil
@@ -0,0 +1,6 @@
+// Package version holds the release the operator binaries were built from. It is set at build
+// time with -ldflags "-X github.com/centos-automotive-suite/automotive-dev-operator/internal/common/version.Version=<version>".
+package version
+
+// Version is the release of the operator and build API binaries; dev for builds without one
+var Version = "dev"
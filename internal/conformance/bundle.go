@@ -0,0 +1,305 @@
+package conformance
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Files of a results bundle. SHA256SUMS lists the digest of every other file and is what the
+// signature covers.
+const (
+	bundleReport    = "report.json"
+	bundleJUnit     = "junit.xml"
+	bundleSums      = "SHA256SUMS"
+	bundleSignature = "SHA256SUMS.sig"
+	bundlePublicKey = "signer.pub"
+	bundleBuildsDir = "builds/"
+)
+
+// LoadSigner parses a PEM encoded Ed25519, ECDSA or RSA private key
+func LoadSigner(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key found")
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// GenerateSigner returns a new Ed25519 key, for bundles that only need to be checked for
+// tampering rather than attributed to a known signer
+func GenerateSigner() (crypto.Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	return key, err
+}
+
+// LoadPublicKey parses a PEM encoded public key
+func LoadPublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded public key found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing public key: %w", err)
+	}
+	return key, nil
+}
+
+// KeyFingerprint is the SHA-256 digest of the DER encoding of a public key
+func KeyFingerprint(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+func sign(signer crypto.Signer, data []byte) ([]byte, error) {
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		return signer.Sign(rand.Reader, data, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(data)
+	return signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+func verify(pub crypto.PublicKey, data, sig []byte) bool {
+	digest := sha256.Sum256(data)
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, data, sig)
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, digest[:], sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil
+	}
+	return false
+}
+
+// WriteBundle writes report as a gzipped tar holding report.json, a JUnit report, the last status
+// of every build of the run and SHA256SUMS, signed by signer. The public key of signer is included,
+// so the bundle can be checked for tampering without it; who signed it is established by comparing
+// the key, or its fingerprint, with the one the platform team signs with.
+func WriteBundle(w io.Writer, report *Report, signer crypto.Signer) error {
+	files := map[string][]byte{}
+	var err error
+	if files[bundleReport], err = json.MarshalIndent(report, "", "  "); err != nil {
+		return err
+	}
+	if files[bundleJUnit], err = junitReport(report); err != nil {
+		return err
+	}
+	for name, st := range report.BuildStatus {
+		if files[bundleBuildsDir+name+".json"], err = json.MarshalIndent(st, "", "  "); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sums bytes.Buffer
+	for _, name := range names {
+		sum := sha256.Sum256(files[name])
+		fmt.Fprintf(&sums, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+	}
+	sig, err := sign(signer, sums.Bytes())
+	if err != nil {
+		return fmt.Errorf("error signing the bundle: %w", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	modTime := report.CompletionTime
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+	add := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: modTime}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	for _, name := range names {
+		if err := add(name, files[name]); err != nil {
+			return err
+		}
+	}
+	if err := add(bundleSums, sums.Bytes()); err != nil {
+		return err
+	}
+	if err := add(bundleSignature, []byte(base64.StdEncoding.EncodeToString(sig)+"\n")); err != nil {
+		return err
+	}
+	if err := add(bundlePublicKey, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// VerifyBundle checks the signature of a bundle and the digest of every file it holds, and
+// returns its report and the key that signed it. With pub the bundle must be signed by that key.
+func VerifyBundle(r io.Reader, pub crypto.PublicKey) (*Report, crypto.PublicKey, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a results bundle: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	files := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading bundle: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || path.Clean(hdr.Name) != hdr.Name {
+			return nil, nil, fmt.Errorf("unexpected entry %q in bundle", hdr.Name)
+		}
+		if files[hdr.Name], err = io.ReadAll(tr); err != nil {
+			return nil, nil, fmt.Errorf("error reading %s: %w", hdr.Name, err)
+		}
+	}
+
+	signer, err := LoadPublicKey(files[bundlePublicKey])
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", bundlePublicKey, err)
+	}
+	if pub != nil {
+		want, err := KeyFingerprint(pub)
+		if err != nil {
+			return nil, nil, err
+		}
+		if got, _ := KeyFingerprint(signer); got != want {
+			return nil, nil, fmt.Errorf("bundle is signed by key %s, not %s", got, want)
+		}
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(files[bundleSignature])))
+	if err != nil || !verify(signer, files[bundleSums], sig) {
+		return nil, nil, errors.New("the signature of the bundle is invalid")
+	}
+
+	listed := map[string]bool{bundleSums: true, bundleSignature: true, bundlePublicKey: true}
+	for _, line := range strings.Split(strings.TrimSpace(string(files[bundleSums])), "\n") {
+		sum, name, ok := strings.Cut(line, "  ")
+		if !ok {
+			return nil, nil, fmt.Errorf("malformed %s line %q", bundleSums, line)
+		}
+		data, found := files[name]
+		if !found {
+			return nil, nil, fmt.Errorf("%s is missing from the bundle", name)
+		}
+		if got := sha256.Sum256(data); hex.EncodeToString(got[:]) != sum {
+			return nil, nil, fmt.Errorf("%s was modified after the bundle was signed", name)
+		}
+		listed[name] = true
+	}
+	for name := range files {
+		if !listed[name] {
+			return nil, nil, fmt.Errorf("%s was added after the bundle was signed", name)
+		}
+	}
+
+	report := &Report{}
+	if err := json.Unmarshal(files[bundleReport], report); err != nil {
+		return nil, nil, fmt.Errorf("error parsing %s: %w", bundleReport, err)
+	}
+	return report, signer, nil
+}
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     float64     `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// junitReport renders report for CI systems that show JUnit results
+func junitReport(report *Report) ([]byte, error) {
+	suite := junitSuite{
+		Name:     "caib conformance",
+		Tests:    len(report.Results),
+		Failures: report.Failed,
+		Skipped:  report.Skipped,
+		Time:     report.CompletionTime.Sub(report.StartTime).Seconds(),
+	}
+	for _, res := range report.Results {
+		class, name, _ := strings.Cut(res.Name, "/")
+		tc := junitCase{ClassName: class, Name: name, Time: res.Seconds}
+		switch res.Status {
+		case StatusFailed:
+			tc.Failure = &junitMessage{Message: res.Message}
+		case StatusSkipped:
+			tc.Skipped = &junitMessage{Message: res.Message}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	out, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
@@ -0,0 +1,527 @@
+// Package conformance certifies a deployment of the build API end to end. It checks health and
+// authentication, runs small real builds for the requested targets and export formats, uploads a
+// local file into one of them, downloads and verifies the artifacts, and reports the results as a
+// signed bundle platform teams keep as evidence for their installation, e.g. after an upgrade.
+package conformance
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+	buildapiclient "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi/client"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/manifest"
+)
+
+// Result statuses
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// RunLabel is the label of the builds of a run, set to its run ID
+const RunLabel = "caib-conformance-run"
+
+// BuildCase is a combination of target, export format and architecture the suite builds
+type BuildCase struct {
+	Target       string `json:"target"`
+	ExportFormat string `json:"exportFormat"`
+	Architecture string `json:"architecture"`
+}
+
+func (bc BuildCase) String() string {
+	return bc.Target + "/" + bc.ExportFormat + "/" + bc.Architecture
+}
+
+// Options configure a run
+type Options struct {
+	// Server is the base URL of the build API
+	Server string
+	// Client talks to the build API as the user certifying the installation
+	Client *buildapiclient.Client
+	// HTTPClient sends the requests that must be rejected, without credentials; http.DefaultClient
+	// when nil
+	HTTPClient *http.Client
+	// Builds are built and downloaded; the first one also gets an uploaded file. None skips the
+	// build checks.
+	Builds []BuildCase
+	// Distro and AutomotiveImageBuilder of the builds; the server defaults are used when empty
+	Distro                 string
+	AutomotiveImageBuilder string
+	StorageClass           string
+	// BuildTimeout bounds each build, from its creation until it finished
+	BuildTimeout time.Duration
+	// PollInterval is how often builds are checked; 10 seconds when zero
+	PollInterval time.Duration
+	// KeepBuilds keeps the builds of the run for inspection instead of deleting them
+	KeepBuilds bool
+	// ClientVersion is recorded in the report
+	ClientVersion string
+	// Namespace is recorded in the report
+	Namespace string
+	// Progress receives a line per finished check
+	Progress io.Writer
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name    string  `json:"name"`
+	Status  string  `json:"status"`
+	Message string  `json:"message,omitempty"`
+	Seconds float64 `json:"seconds"`
+}
+
+// Report is the outcome of a run
+type Report struct {
+	RunID          string                `json:"runID"`
+	Server         string                `json:"server"`
+	Namespace      string                `json:"namespace,omitempty"`
+	ClientVersion  string                `json:"clientVersion,omitempty"`
+	Versions       *buildapi.VersionInfo `json:"versions,omitempty"`
+	Builds         []BuildCase           `json:"builds"`
+	StartTime      time.Time             `json:"startTime"`
+	CompletionTime time.Time             `json:"completionTime"`
+	Passed         int                   `json:"passed"`
+	Failed         int                   `json:"failed"`
+	Skipped        int                   `json:"skipped"`
+	Results        []Result              `json:"results"`
+	// BuildStatus is the last status of every build of the run; it is written to the bundle next
+	// to the report
+	BuildStatus map[string]*buildapi.BuildResponse `json:"-"`
+}
+
+// Succeeded reports whether no check failed
+func (r *Report) Succeeded() bool {
+	return r.Failed == 0
+}
+
+func (r *Report) add(res Result) {
+	r.Results = append(r.Results, res)
+	switch res.Status {
+	case StatusPassed:
+		r.Passed++
+	case StatusFailed:
+		r.Failed++
+	default:
+		r.Skipped++
+	}
+}
+
+// errSkipped makes a check skipped rather than failed
+type errSkipped struct{ reason string }
+
+func (e errSkipped) Error() string { return e.reason }
+
+type runner struct {
+	opts   Options
+	report *Report
+
+	mu     sync.Mutex
+	builds []string
+}
+
+// Run runs the suite. Failed checks are recorded in the report, which is returned even when the
+// deployment could not be reached at all.
+func Run(ctx context.Context, opts Options) *Report {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = 10 * time.Second
+	}
+	if opts.BuildTimeout == 0 {
+		opts.BuildTimeout = time.Hour
+	}
+	r := &runner{opts: opts, report: &Report{
+		RunID:         newRunID(),
+		Server:        opts.Server,
+		Namespace:     opts.Namespace,
+		ClientVersion: opts.ClientVersion,
+		Builds:        opts.Builds,
+		StartTime:     time.Now().UTC(),
+		BuildStatus:   map[string]*buildapi.BuildResponse{},
+	}}
+
+	r.record(r.check(ctx, "api/health", r.checkHealth))
+	r.record(r.check(ctx, "auth/anonymous-rejected", func(ctx context.Context) error {
+		return r.expectUnauthorized(ctx, "")
+	}))
+	r.record(r.check(ctx, "auth/invalid-token-rejected", func(ctx context.Context) error {
+		return r.expectUnauthorized(ctx, "caib-conformance-invalid-token")
+	}))
+	authenticated := r.check(ctx, "auth/authenticated", r.checkAuthenticated)
+	r.record(authenticated)
+	info := r.check(ctx, "api/info", r.checkInfo)
+	r.record(info)
+	r.record(r.check(ctx, "api/capabilities", r.checkCapabilities))
+
+	switch {
+	case len(opts.Builds) == 0:
+	case authenticated.Status != StatusPassed:
+		r.skipBuilds("the build API did not accept the credentials")
+	case info.Status != StatusPassed:
+		r.skipBuilds(info.Message)
+	default:
+		r.runBuilds(ctx)
+		r.record(r.check(ctx, "api/delete", r.deleteBuilds))
+	}
+
+	r.report.CompletionTime = time.Now().UTC()
+	return r.report
+}
+
+func newRunID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// check runs fn as the check name
+func (r *runner) check(ctx context.Context, name string, fn func(context.Context) error) Result {
+	start := time.Now()
+	err := fn(ctx)
+	res := Result{Name: name, Status: StatusPassed, Seconds: time.Since(start).Round(time.Millisecond).Seconds()}
+	var skipped errSkipped
+	switch {
+	case errors.As(err, &skipped):
+		res.Status, res.Message = StatusSkipped, skipped.reason
+	case err != nil:
+		res.Status, res.Message = StatusFailed, err.Error()
+	}
+	r.progress(res)
+	return res
+}
+
+func (r *runner) record(res Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.add(res)
+}
+
+func (r *runner) progress(res Result) {
+	if r.opts.Progress == nil {
+		return
+	}
+	line := fmt.Sprintf("%-7s %s (%.1fs)", strings.ToUpper(res.Status), res.Name, res.Seconds)
+	if res.Message != "" {
+		line += ": " + res.Message
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintln(r.opts.Progress, line)
+}
+
+func (r *runner) skipBuilds(reason string) {
+	for _, bc := range r.opts.Builds {
+		for _, name := range []string{"build/" + bc.String(), "download/" + bc.String()} {
+			res := Result{Name: name, Status: StatusSkipped, Message: reason}
+			r.progress(res)
+			r.record(res)
+		}
+	}
+	res := Result{Name: "upload/add-files", Status: StatusSkipped, Message: reason}
+	r.progress(res)
+	r.record(res)
+}
+
+// get sends an unauthenticated GET, or one with token, to path of the server
+func (r *runner) get(ctx context.Context, path, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(r.opts.Server, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r.opts.HTTPClient.Do(req)
+}
+
+func (r *runner) checkHealth(ctx context.Context) error {
+	resp, err := r.get(ctx, "/healthz", "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var health buildapi.HealthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return fmt.Errorf("/healthz answered %s without a health report", resp.Status)
+	}
+	if health.Status != "ok" {
+		var failing []string
+		for _, c := range health.Checks {
+			if c.Status != "ok" {
+				failing = append(failing, fmt.Sprintf("%s: %s", c.Name, c.Message))
+			}
+		}
+		return fmt.Errorf("health is %s: %s", health.Status, strings.Join(failing, "; "))
+	}
+	return nil
+}
+
+// expectUnauthorized checks that listing builds with token, or without one, is rejected
+func (r *runner) expectUnauthorized(ctx context.Context, token string) error {
+	resp, err := r.get(ctx, "/v1/builds", token)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		return fmt.Errorf("listing builds answered %s, want 401 Unauthorized", resp.Status)
+	}
+	return nil
+}
+
+func (r *runner) checkAuthenticated(ctx context.Context) error {
+	_, err := r.opts.Client.ListBuildsPage(ctx, buildapiclient.ListBuildsOptions{Limit: 1})
+	return err
+}
+
+func (r *runner) checkInfo(ctx context.Context) error {
+	info, err := r.opts.Client.GetInfo(ctx)
+	if err != nil {
+		return err
+	}
+	r.report.Versions = info.Versions
+	if info.ReadOnly {
+		return fmt.Errorf("the build API is read-only, builds are rejected: %s", info.Banner)
+	}
+	return nil
+}
+
+// checkCapabilities checks that the builder offers every requested build case
+func (r *runner) checkCapabilities(ctx context.Context) error {
+	caps, err := r.opts.Client.GetCapabilities(ctx, r.opts.AutomotiveImageBuilder)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, bc := range r.opts.Builds {
+		if !slices.Contains(caps.Targets, bc.Target) {
+			missing = append(missing, "target "+bc.Target)
+		}
+		if !slices.Contains(caps.ExportFormats, bc.ExportFormat) {
+			missing = append(missing, "export format "+bc.ExportFormat)
+		}
+		if !slices.Contains(caps.Architectures, bc.Architecture) {
+			missing = append(missing, "architecture "+bc.Architecture)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s does not offer %s", caps.AutomotiveImageBuilder, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// runBuilds builds every case concurrently and downloads the artifacts. The first case also
+// uploads a local file, so the upload server is covered.
+func (r *runner) runBuilds(ctx context.Context) {
+	results := make([][]Result, len(r.opts.Builds))
+	var wg sync.WaitGroup
+	for i, bc := range r.opts.Builds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.runBuildCase(ctx, i, bc)
+		}()
+	}
+	wg.Wait()
+	// results are recorded in the order of the cases, whichever build finished first
+	for _, rs := range results {
+		for _, res := range rs {
+			r.record(res)
+		}
+	}
+}
+
+func (r *runner) runBuildCase(ctx context.Context, i int, bc BuildCase) []Result {
+	name := fmt.Sprintf("conformance-%s-%d", r.report.RunID, i+1)
+	m := manifest.New("conformance")
+	var uploads []buildapiclient.Upload
+	if i == 0 {
+		local, err := writeUploadFile(r.report.RunID)
+		if err != nil {
+			res := Result{Name: "upload/add-files", Status: StatusFailed, Message: err.Error()}
+			r.progress(res)
+			return []Result{res}
+		}
+		defer os.RemoveAll(filepath.Dir(local))
+		m.Content.AddFile(manifest.File{Path: "/etc/caib-conformance/upload", SourcePath: "caib-conformance-upload"})
+		uploads = []buildapiclient.Upload{{SourcePath: local, DestPath: "caib-conformance-upload"}}
+	} else {
+		m.Content.AddFile(manifest.File{Path: "/etc/caib-conformance/run", Text: r.report.RunID + "\n"})
+	}
+
+	var uploaded error = errSkipped{"the build did not wait for uploads"}
+	build := r.check(ctx, "build/"+bc.String(), func(ctx context.Context) error {
+		st, err := r.build(ctx, name, bc, m, uploads, &uploaded)
+		if err != nil {
+			return err
+		}
+		if st.Phase != "Completed" {
+			return fmt.Errorf("build %s is %s: %s", name, st.Phase, st.Message)
+		}
+		return nil
+	})
+	results := []Result{build}
+	if i == 0 {
+		upload := r.check(ctx, "upload/add-files", func(context.Context) error {
+			if uploaded == nil && build.Status != StatusPassed {
+				return fmt.Errorf("the file was uploaded but build %s did not complete", name)
+			}
+			return uploaded
+		})
+		results = append(results, upload)
+	}
+	results = append(results, r.check(ctx, "download/"+bc.String(), func(ctx context.Context) error {
+		if build.Status != StatusPassed {
+			return errSkipped{"the build did not complete"}
+		}
+		return r.verifyDownload(ctx, name)
+	}))
+	return results
+}
+
+// writeUploadFile writes the file the upload check sends
+func writeUploadFile(runID string) (string, error) {
+	dir, err := os.MkdirTemp("", "caib-conformance-")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "upload")
+	content := fmt.Sprintf("caib conformance run %s\n", runID)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// build creates the build name, uploads the files of uploads once it waits for them, recording
+// the outcome in uploaded, and waits until it finished
+func (r *runner) build(ctx context.Context, name string, bc BuildCase, m *manifest.Manifest, uploads []buildapiclient.Upload, uploaded *error) (*buildapi.BuildResponse, error) {
+	data, err := m.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.opts.BuildTimeout)
+	defer cancel()
+	_, err = r.opts.Client.CreateBuild(ctx, buildapi.BuildRequest{
+		Name:                   name,
+		Manifest:               string(data),
+		Distro:                 buildapi.Distro(r.opts.Distro),
+		Target:                 buildapi.Target(bc.Target),
+		Architecture:           buildapi.Architecture(bc.Architecture),
+		ExportFormat:           buildapi.ExportFormat(bc.ExportFormat),
+		AutomotiveImageBuilder: r.opts.AutomotiveImageBuilder,
+		StorageClass:           r.opts.StorageClass,
+		Labels:                 map[string]string{RunLabel: r.report.RunID},
+		// the artifacts of builds kept with KeepBuilds, or left behind by an interrupted run, go
+		// away on their own
+		Retention: &buildapi.Retention{KeepFor: "24h"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.builds = append(r.builds, name)
+	r.mu.Unlock()
+
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		st, err := r.opts.Client.GetBuild(ctx, name)
+		if err == nil {
+			r.mu.Lock()
+			r.report.BuildStatus[name] = st
+			r.mu.Unlock()
+			switch st.Phase {
+			case "Completed", "Failed", "Cancelled":
+				return st, nil
+			case "Uploading":
+				if len(uploads) > 0 {
+					*uploaded = r.opts.Client.UploadFilesResumable(ctx, name, uploads, nil)
+					if *uploaded != nil {
+						return nil, fmt.Errorf("uploading to build %s: %w", name, *uploaded)
+					}
+					uploads = nil
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return nil, fmt.Errorf("build %s did not finish within %s: %w", name, r.opts.BuildTimeout, err)
+			}
+			return nil, fmt.Errorf("build %s did not finish within %s", name, r.opts.BuildTimeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// verifyDownload downloads the artifact of the build name and compares it with the size and
+// digest of its artifact manifest
+func (r *runner) verifyDownload(ctx context.Context, name string) error {
+	files, err := r.opts.Client.GetArtifactManifest(ctx, name)
+	if err != nil {
+		return err
+	}
+	for _, f := range files.Files {
+		if f.Kind != "artifact" {
+			continue
+		}
+		h := sha256.New()
+		counter := &countingWriter{w: h}
+		if err := r.opts.Client.DownloadArtifactFile(ctx, name, f.Name, counter); err != nil {
+			return err
+		}
+		if f.SizeBytes > 0 && counter.n != f.SizeBytes {
+			return fmt.Errorf("downloaded %d bytes of %s, the manifest lists %d", counter.n, f.Name, f.SizeBytes)
+		}
+		if got := hex.EncodeToString(h.Sum(nil)); f.SHA256 != "" && got != f.SHA256 {
+			return fmt.Errorf("%s has digest sha256:%s, the manifest lists sha256:%s", f.Name, got, f.SHA256)
+		}
+		return nil
+	}
+	return fmt.Errorf("the artifact manifest of build %s lists no artifact", name)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// deleteBuilds deletes the builds of the run unless they are kept
+func (r *runner) deleteBuilds(ctx context.Context) error {
+	if r.opts.KeepBuilds {
+		return errSkipped{fmt.Sprintf("builds labeled %s=%s are kept", RunLabel, r.report.RunID)}
+	}
+	if len(r.builds) == 0 {
+		return errSkipped{"no build was created"}
+	}
+	var errs []error
+	for _, name := range r.builds {
+		if _, err := r.opts.Client.DeleteBuild(ctx, name, buildapiclient.DeleteBuildOptions{Force: true}); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,193 @@
+package conformance_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi"
+	buildapiclient "github.com/centos-automotive-suite/automotive-dev-operator/internal/buildapi/client"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/conformance"
+)
+
+// fakeAPI answers the requests of the checks that do not build
+func fakeAPI(info buildapi.InfoResponse) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorized := r.Header.Get("Authorization") == "Bearer good"
+		switch {
+		case r.URL.Path == "/healthz":
+			_ = json.NewEncoder(w).Encode(buildapi.HealthResponse{Status: "ok"})
+		case r.URL.Path == "/v1/info":
+			_ = json.NewEncoder(w).Encode(info)
+		case !authorized:
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v1/builds":
+			_, _ = w.Write([]byte("[]"))
+		case r.URL.Path == "/v1/capabilities":
+			_ = json.NewEncoder(w).Encode(buildapi.CapabilitiesResponse{
+				AutomotiveImageBuilder: "aib", Targets: []string{"qemu"}, ExportFormats: []string{"qcow2"}, Architectures: []string{"amd64"},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func run(srv *httptest.Server, token string, builds ...conformance.BuildCase) *conformance.Report {
+	api, err := buildapiclient.New(srv.URL, buildapiclient.WithAuthToken(token))
+	Expect(err).NotTo(HaveOccurred())
+	return conformance.Run(context.Background(), conformance.Options{Server: srv.URL, Client: api, Builds: builds})
+}
+
+func statuses(report *conformance.Report) map[string]string {
+	out := map[string]string{}
+	for _, res := range report.Results {
+		out[res.Name] = res.Status
+	}
+	return out
+}
+
+var _ = Describe("Run", func() {
+	It("passes the checks of a conforming build API", func() {
+		srv := fakeAPI(buildapi.InfoResponse{Versions: &buildapi.VersionInfo{BuildAPI: "v1.2.3"}})
+		defer srv.Close()
+
+		report := run(srv, "good")
+		Expect(report.Succeeded()).To(BeTrue(), "%+v", report.Results)
+		Expect(report.Passed).To(Equal(6))
+		Expect(report.Versions.BuildAPI).To(Equal("v1.2.3"))
+	})
+
+	It("fails when the requested builds are not offered", func() {
+		srv := fakeAPI(buildapi.InfoResponse{})
+		defer srv.Close()
+
+		report := run(srv, "good", conformance.BuildCase{Target: "rpi4", ExportFormat: "qcow2", Architecture: "amd64"}, conformance.BuildCase{})
+		Expect(statuses(report)["api/capabilities"]).To(Equal(conformance.StatusFailed))
+	})
+
+	It("skips the builds when the credentials are rejected", func() {
+		srv := fakeAPI(buildapi.InfoResponse{})
+		defer srv.Close()
+
+		report := run(srv, "bad", conformance.BuildCase{Target: "qemu", ExportFormat: "qcow2", Architecture: "amd64"})
+		Expect(report.Succeeded()).To(BeFalse())
+		got := statuses(report)
+		Expect(got["auth/authenticated"]).To(Equal(conformance.StatusFailed))
+		Expect(got["build/qemu/qcow2/amd64"]).To(Equal(conformance.StatusSkipped))
+		Expect(got["upload/add-files"]).To(Equal(conformance.StatusSkipped))
+	})
+
+	It("skips the builds of a read-only build API", func() {
+		srv := fakeAPI(buildapi.InfoResponse{ReadOnly: true, Banner: "maintenance"})
+		defer srv.Close()
+
+		report := run(srv, "good", conformance.BuildCase{Target: "qemu", ExportFormat: "qcow2", Architecture: "amd64"})
+		Expect(statuses(report)).To(HaveKeyWithValue("download/qemu/qcow2/amd64", conformance.StatusSkipped))
+		Expect(statuses(report)).To(HaveKeyWithValue("api/info", conformance.StatusFailed))
+	})
+})
+
+var _ = Describe("results bundles", func() {
+	report := &conformance.Report{
+		RunID:          "0a1b2c3d",
+		Server:         "https://build-api.example",
+		StartTime:      time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		CompletionTime: time.Date(2026, 10, 1, 12, 30, 0, 0, time.UTC),
+		Results:        []conformance.Result{{Name: "api/health", Status: conformance.StatusPassed}, {Name: "build/qemu/qcow2/amd64", Status: conformance.StatusFailed, Message: "boom"}},
+		Passed:         1,
+		Failed:         1,
+		BuildStatus:    map[string]*buildapi.BuildResponse{"conformance-0a1b2c3d-1": {Name: "conformance-0a1b2c3d-1", Phase: "Failed"}},
+	}
+
+	// rewrite copies a bundle, replacing the content of the file name
+	rewrite := func(bundle []byte, name string, content []byte) []byte {
+		gz, err := gzip.NewReader(bytes.NewReader(bundle))
+		Expect(err).NotTo(HaveOccurred())
+		tr := tar.NewReader(gz)
+		var out bytes.Buffer
+		ogz := gzip.NewWriter(&out)
+		tw := tar.NewWriter(ogz)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+			data, _ := io.ReadAll(tr)
+			if hdr.Name == name {
+				data = content
+			}
+			hdr.Size = int64(len(data))
+			Expect(tw.WriteHeader(hdr)).To(Succeed())
+			_, _ = tw.Write(data)
+		}
+		Expect(tw.Close()).To(Succeed())
+		Expect(ogz.Close()).To(Succeed())
+		return out.Bytes()
+	}
+
+	It("verifies a bundle signed with a generated key", func() {
+		signer, err := conformance.GenerateSigner()
+		Expect(err).NotTo(HaveOccurred())
+		var buf bytes.Buffer
+		Expect(conformance.WriteBundle(&buf, report, signer)).To(Succeed())
+
+		got, pub, err := conformance.VerifyBundle(bytes.NewReader(buf.Bytes()), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.RunID).To(Equal("0a1b2c3d"))
+		Expect(got.Results).To(Equal(report.Results))
+		Expect(conformance.KeyFingerprint(pub)).To(Equal(must(conformance.KeyFingerprint(signer.Public()))))
+	})
+
+	It("checks the signer against a given public key", func() {
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		der, err := x509.MarshalECPrivateKey(ecKey)
+		Expect(err).NotTo(HaveOccurred())
+		signer, err := conformance.LoadSigner(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+		Expect(err).NotTo(HaveOccurred())
+		var buf bytes.Buffer
+		Expect(conformance.WriteBundle(&buf, report, signer)).To(Succeed())
+
+		_, _, err = conformance.VerifyBundle(bytes.NewReader(buf.Bytes()), &ecKey.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+
+		other, _ := conformance.GenerateSigner()
+		_, _, err = conformance.VerifyBundle(bytes.NewReader(buf.Bytes()), other.Public())
+		Expect(err).To(MatchError(ContainSubstring("is signed by key")))
+	})
+
+	It("rejects a bundle modified after signing", func() {
+		signer, _ := conformance.GenerateSigner()
+		var buf bytes.Buffer
+		Expect(conformance.WriteBundle(&buf, report, signer)).To(Succeed())
+
+		tampered := rewrite(buf.Bytes(), "report.json", []byte(`{"runID":"0a1b2c3d","failed":0}`))
+		_, _, err := conformance.VerifyBundle(bytes.NewReader(tampered), nil)
+		Expect(err).To(MatchError(ContainSubstring("report.json was modified")))
+
+		resigned := rewrite(buf.Bytes(), "SHA256SUMS", []byte("0000  report.json\n"))
+		_, _, err = conformance.VerifyBundle(bytes.NewReader(resigned), nil)
+		Expect(err).To(MatchError(ContainSubstring("signature of the bundle is invalid")))
+	})
+})
+
+func must(s string, err error) string {
+	Expect(err).NotTo(HaveOccurred())
+	return s
+}
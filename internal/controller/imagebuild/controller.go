@@ -1,31 +1,97 @@
 package imagebuild
 
 import (
+	"cmp"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	stderrors "errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/comparison"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/firstboot"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/hardening"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/remotefiles"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/storage"
 	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/tasks"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/controller/sharding"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/buildqueue"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/buildrecord"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/commitstatus"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/logarchive"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/plugin"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/webhook"
 	"github.com/go-logr/logr"
 	routev1 "github.com/openshift/api/route/v1"
 	pod "github.com/tektoncd/pipeline/pkg/apis/pipeline/pod"
 	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	nodev1 "k8s.io/api/node/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
 	OperatorNamespace = "automotive-dev-operator-system"
+
+	// cancelRequestedAnnotation is set by the build API to the user who asked to cancel a build
+	cancelRequestedAnnotation = "automotive.sdv.cloud.redhat.com/cancel-requested-by"
+)
+
+// Reasons of the Events recorded on an ImageBuild as it progresses, so `kubectl describe imagebuild`
+// shows the history of a build
+const (
+	EventReasonQueued                  = "Queued"
+	EventReasonWaitingForStorage       = "WaitingForStorage"
+	EventReasonWaitingForSlot          = "WaitingForBuildSlot"
+	EventReasonUploadReady             = "UploadReady"
+	EventReasonUploadsComplete         = "UploadsComplete"
+	EventReasonBuildStarted            = "BuildStarted"
+	EventReasonBuildSucceeded          = "BuildSucceeded"
+	EventReasonBuildFailed             = "BuildFailed"
+	EventReasonBuildCancelled          = "BuildCancelled"
+	EventReasonArtifactPublished       = "ArtifactPublished"
+	EventReasonArtifactExpired         = "ArtifactExpired"
+	EventReasonArtifactsDeleted        = "ArtifactsDeleted"
+	EventReasonPublished               = "Published"
+	EventReasonPublishRetried          = "PublishRetried"
+	EventReasonPublishFailed           = "PublishFailed"
+	EventReasonWorkspaceScrubbed       = "WorkspaceScrubbed"
+	EventReasonScrubFailed             = "WorkspaceScrubFailed"
+	EventReasonArtifactsArchived       = "ArtifactsArchived"
+	EventReasonArchiveFailed           = "ArtifactArchiveFailed"
+	EventReasonArtifactsRehydrated     = "ArtifactsRehydrated"
+	EventReasonArtifactSigned          = "ArtifactSigned"
+	EventReasonSigningFailed           = "ArtifactSigningFailed"
+	EventReasonBuildRecorded           = "BuildRecorded"
+	EventReasonBuildRecordAnchored     = "BuildRecordAnchored"
+	EventReasonBuildRecordAnchorFailed = "BuildRecordAnchorFailed"
 )
 
 // ImageBuildReconciler reconciles a ImageBuild object
@@ -33,12 +99,30 @@ type ImageBuildReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Log    logr.Logger
+
+	// Plugins are called when a build reaches Completed or Failed; nil disables plugins
+	Plugins *plugin.Registry
+
+	// Recorder records Events on ImageBuilds; nil disables Events
+	Recorder record.EventRecorder
+
+	// Shard limits the controller to the builds of one shard; the zero value reconciles every build
+	Shard sharding.Shard
+
+	// Clientset reads the logs of finished build pods into their log archives; nil disables log archives
+	Clientset kubernetes.Interface
+
+	// queueMu guards the Redis build queue, which is kept between reconciles
+	queueMu       sync.Mutex
+	redisQueue    *buildqueue.Redis
+	redisQueueKey redisQueueKey
 }
 
 // +kubebuilder:rbac:groups=automotive.sdv.cloud.redhat.com,resources=imagebuilds,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=automotive.sdv.cloud.redhat.com,resources=imagebuilds/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=automotive.sdv.cloud.redhat.com,resources=imagebuilds/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch;create;patch
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
@@ -50,8 +134,14 @@ type ImageBuildReconciler struct {
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
 // +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=resourcequotas,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=csistoragecapacities,verbs=get;list;watch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=node.k8s.io,resources=runtimeclasses,verbs=get;list;watch
 
 // Reconcile ImageBuild
 func (r *ImageBuildReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -62,8 +152,12 @@ func (r *ImageBuildReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if requestedBy, ok := imageBuild.Annotations[cancelRequestedAnnotation]; ok && !isFinishedPhase(imageBuild.Status.Phase) {
+		return r.cancelBuild(ctx, imageBuild, requestedBy)
+	}
+
 	switch imageBuild.Status.Phase {
-	case "":
+	case "", "Queued":
 		return r.handleInitialState(ctx, imageBuild)
 	case "Uploading":
 		return r.handleUploadingState(ctx, imageBuild)
@@ -71,17 +165,132 @@ func (r *ImageBuildReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return r.handleBuildingState(ctx, imageBuild)
 	case "Completed":
 		return r.handleCompletedState(ctx, imageBuild)
-	case "Failed":
-		return ctrl.Result{}, nil
+	case "Failed", "Cancelled":
+		recordResult, err := r.recordBuild(ctx, imageBuild)
+		if err != nil {
+			return recordResult, err
+		}
+		scrubResult, err := r.scrubWorkspace(ctx, imageBuild)
+		scrubResult.RequeueAfter = earliestRequeue(scrubResult.RequeueAfter, recordResult.RequeueAfter)
+		return scrubResult, err
 	default:
 		log.Info("Unknown phase", "phase", imageBuild.Status.Phase)
 		return ctrl.Result{}, nil
 	}
 }
 
+// cancelBuild stops the TaskRun and upload pod of a build, releases its workspace PVC and marks it Cancelled
+func (r *ImageBuildReconciler) cancelBuild(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild, requestedBy string) (ctrl.Result, error) {
+	log := r.Log.WithValues("imagebuild", types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace})
+
+	if name := imageBuild.Status.TaskRunName; name != "" {
+		taskRun := &tektonv1.TaskRun{}
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: imageBuild.Namespace}, taskRun)
+		if err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		if err == nil && !isTaskRunCompleted(taskRun) && taskRun.Spec.Status != tektonv1.TaskRunSpecStatusCancelled {
+			patch := client.MergeFrom(taskRun.DeepCopy())
+			taskRun.Spec.Status = tektonv1.TaskRunSpecStatusCancelled
+			if err := r.Patch(ctx, taskRun, patch); err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to cancel task run: %w", err)
+			}
+			log.Info("Cancelled TaskRun", "taskRun", name)
+		}
+	}
+
+	if err := r.shutdownUploadPod(ctx, imageBuild); err != nil {
+		return ctrl.Result{RequeueAfter: time.Second * 5}, err
+	}
+
+	// a workspace to scrub is released by the scrub once the build is Cancelled
+	if pvcName := imageBuild.Status.PVCName; pvcName != "" && !scrubRequested(imageBuild) {
+		pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: pvcName, Namespace: imageBuild.Namespace}}
+		if err := r.Delete(ctx, pvc); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{RequeueAfter: time.Second * 5}, fmt.Errorf("failed to release workspace PVC: %w", err)
+		}
+		log.Info("Released workspace PVC", "pvc", pvcName)
+	}
+
+	message := "Build cancelled"
+	if requestedBy != "" {
+		message = fmt.Sprintf("Build cancelled by %s", requestedBy)
+	}
+	if err := r.updateStatus(ctx, imageBuild, "Cancelled", message); err != nil {
+		return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
 func (r *ImageBuildReconciler) handleInitialState(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, error) {
+	if imageBuild.Status.Phase == "" {
+		r.recordEvent(imageBuild, corev1.EventTypeNormal, EventReasonQueued, "Build accepted by the controller")
+	}
+
+	if err := r.validatePartnerBuild(ctx, imageBuild); err != nil {
+		var invalid *invalidSpecError
+		if stderrors.As(err, &invalid) {
+			if err := r.updateStatus(ctx, imageBuild, "Failed", invalid.Error()); err != nil {
+				return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+			}
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to check the partner profile: %w", err)
+	}
+
+	if err := r.checkWorkspaceStorage(ctx, imageBuild); err != nil {
+		var invalid *invalidSpecError
+		if stderrors.As(err, &invalid) {
+			if err := r.updateStatus(ctx, imageBuild, "Failed", invalid.Error()); err != nil {
+				return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+			}
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to check workspace storage: %w", err)
+	}
+
+	position, waiting, err := r.buildSlotQueuePosition(ctx, imageBuild)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to check for a build slot: %w", err)
+	}
+	if position > 0 {
+		msg := fmt.Sprintf("Waiting for a build slot: position %d of %d", position, waiting)
+		if err := r.queueBuild(ctx, imageBuild, msg, position); err != nil {
+			return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+		}
+		return ctrl.Result{RequeueAfter: buildSlotRecheckInterval}, nil
+	}
+
+	capacity, err := r.checkStorageCapacity(ctx, imageBuild)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to check storage capacity: %w", err)
+	}
+	if capacity.Status == metav1.ConditionFalse {
+		if err := r.queueBuild(ctx, imageBuild, capacity.Message, 0); err != nil {
+			return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+		}
+		return ctrl.Result{RequeueAfter: storageCapacityRecheckInterval}, nil
+	}
+
 	if imageBuild.Spec.InputFilesServer {
+		if err := r.applyNetworkPolicy(ctx, imageBuild); err != nil {
+			var invalid *invalidSpecError
+			if stderrors.As(err, &invalid) {
+				if err := r.updateStatus(ctx, imageBuild, "Failed", invalid.Error()); err != nil {
+					return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+				}
+				return ctrl.Result{}, nil
+			}
+			return ctrl.Result{}, fmt.Errorf("failed to apply build network policy: %w", err)
+		}
 		if err := r.createUploadPod(ctx, imageBuild); err != nil {
+			var invalid *invalidSpecError
+			if stderrors.As(err, &invalid) {
+				if err := r.updateStatus(ctx, imageBuild, "Failed", invalid.Error()); err != nil {
+					return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+				}
+				return ctrl.Result{}, nil
+			}
 			return ctrl.Result{}, fmt.Errorf("failed to create upload server: %w", err)
 		}
 		if err := r.updateStatus(ctx, imageBuild, "Uploading", "Waiting for file uploads"); err != nil {
@@ -97,6 +306,25 @@ func (r *ImageBuildReconciler) handleInitialState(ctx context.Context, imageBuil
 }
 
 func (r *ImageBuildReconciler) handleUploadingState(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, error) {
+	if files := imageBuild.Spec.RemoteFiles; files != nil && len(files.URLs) > 0 {
+		fetched, failure, err := r.remoteFilesFetched(ctx, imageBuild)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to check remote file downloads: %w", err)
+		}
+		if failure != "" {
+			if err := r.shutdownUploadPod(ctx, imageBuild); err != nil {
+				return ctrl.Result{RequeueAfter: time.Second * 5}, err
+			}
+			if err := r.updateStatus(ctx, imageBuild, "Failed", failure); err != nil {
+				return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+			}
+			return ctrl.Result{}, nil
+		}
+		if !fetched {
+			return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		}
+	}
+
 	uploadsComplete := imageBuild.Annotations != nil &&
 		imageBuild.Annotations["automotive.sdv.cloud.redhat.com/uploads-complete"] == "true"
 
@@ -131,6 +359,18 @@ func (r *ImageBuildReconciler) handleBuildingState(ctx context.Context, imageBui
 	}
 
 	for _, tr := range taskRunList.Items {
+		if _, publish := tr.Labels[publishTargetLabel]; publish {
+			continue
+		}
+		if _, scrub := tr.Labels[workspaceScrubLabel]; scrub {
+			continue
+		}
+		if _, archive := tr.Labels[artifactArchiveLabel]; archive {
+			continue
+		}
+		if _, sign := tr.Labels[artifactSignLabel]; sign {
+			continue
+		}
 		if tr.DeletionTimestamp == nil {
 			log.Info("Found existing TaskRun for this ImageBuild", "taskRun", tr.Name)
 
@@ -159,6 +399,62 @@ func (r *ImageBuildReconciler) handleBuildingState(ctx context.Context, imageBui
 }
 
 func (r *ImageBuildReconciler) handleCompletedState(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, error) {
+	// the artifact is signed before it is published, archived or released
+	if signResult, err := r.signArtifact(ctx, imageBuild); err != nil || signing(imageBuild) {
+		return signResult, err
+	}
+	publishResult, err := r.reconcilePublications(ctx, imageBuild)
+	if err != nil {
+		return publishResult, err
+	}
+	recordResult, err := r.recordBuild(ctx, imageBuild)
+	if err != nil {
+		return recordResult, err
+	}
+	archiveResult, err := r.archiveArtifacts(ctx, imageBuild)
+	if err != nil {
+		return archiveResult, err
+	}
+	publishResult.RequeueAfter = earliestRequeue(publishResult.RequeueAfter, archiveResult.RequeueAfter)
+	retentionResult, expired, err := r.applyRetention(ctx, imageBuild)
+	if err != nil || expired {
+		return retentionResult, err
+	}
+	retentionResult.RequeueAfter = earliestRequeue(retentionResult.RequeueAfter, recordResult.RequeueAfter)
+	serveResult, err := r.expireServedArtifact(ctx, imageBuild)
+	serveResult.RequeueAfter = earliestRequeue(serveResult.RequeueAfter, publishResult.RequeueAfter)
+	if err != nil || serveResult.RequeueAfter > 0 || publicationsPending(imageBuild) {
+		serveResult.RequeueAfter = earliestRequeue(serveResult.RequeueAfter, retentionResult.RequeueAfter)
+		return serveResult, err
+	}
+	scrubResult, err := r.scrubWorkspace(ctx, imageBuild)
+	scrubResult.RequeueAfter = earliestRequeue(scrubResult.RequeueAfter, retentionResult.RequeueAfter)
+	return scrubResult, err
+}
+
+// earliestRequeue returns the shorter of two RequeueAfter durations, 0 meaning none
+func earliestRequeue(a, b time.Duration) time.Duration {
+	if a == 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// publicationsPending reports whether a publish TaskRun may still read the workspace
+func publicationsPending(imageBuild *automotivev1alpha1.ImageBuild) bool {
+	if len(publishTargets(imageBuild)) > len(imageBuild.Status.Publications) {
+		return true
+	}
+	for _, st := range imageBuild.Status.Publications {
+		if st.Phase != publicationSucceeded && st.Phase != publicationFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// expireServedArtifact stops serving the artifact of a completed build once spec.serveExpiryHours passed
+func (r *ImageBuildReconciler) expireServedArtifact(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, error) {
 	if !imageBuild.Spec.ServeArtifact {
 		return ctrl.Result{}, nil
 	}
@@ -180,6 +476,198 @@ func (r *ImageBuildReconciler) handleCompletedState(ctx context.Context, imageBu
 		return ctrl.Result{RequeueAfter: time.Until(expiryAt)}, nil
 	}
 
+	r.stopServingArtifact(ctx, imageBuild)
+
+	fresh := &automotivev1alpha1.ImageBuild{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err == nil {
+		patch := client.MergeFrom(fresh.DeepCopy())
+		// the status patch triggers another reconcile of the expired build, which must not record the Event again
+		served := fresh.Status.ArtifactFileName
+		fresh.Status.ArtifactURL = ""
+		fresh.Status.ArtifactFileName = ""
+		fresh.Status.ArtifactPath = ""
+		fresh.Status.Message = "Build expired"
+		if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+			log.Error(err, "failed to update ImageBuild status after expiry cleanup")
+		} else if served != "" {
+			r.recordEvent(fresh, corev1.EventTypeNormal, EventReasonArtifactExpired,
+				"Stopped serving %s after %d hours", served, expiryHours)
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+const (
+	retentionKeepFor  = "KeepFor"
+	retentionKeepLast = "KeepLast"
+)
+
+// applyRetention records when spec.retention expires the artifacts of a completed build and, once
+// they expired, deletes them. KeepLast is enforced by the newest builds of a group: their reconcile
+// marks the older builds beyond the limit as expired, which makes those delete their artifacts.
+// expired is true while the artifacts are being deleted and after they were.
+func (r *ImageBuildReconciler) applyRetention(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, bool, error) {
+	retention := imageBuild.Spec.Retention
+	st := imageBuild.Status.Retention
+	if st != nil && st.DeletionTime != nil {
+		return ctrl.Result{}, true, nil
+	}
+	if retention == nil && st == nil {
+		return ctrl.Result{}, false, nil
+	}
+	if imageBuild.Status.CompletionTime == nil {
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, false, nil
+	}
+
+	want := &automotivev1alpha1.RetentionStatus{}
+	if st != nil && st.Reason == retentionKeepLast {
+		// a KeepLast expiry marked by a newer build stands even when spec.retention changed since
+		want.Reason = st.Reason
+	}
+	var result ctrl.Result
+	if retention != nil && retention.KeepFor != nil {
+		expiry := metav1.NewTime(imageBuild.Status.CompletionTime.Add(retention.KeepFor.Duration))
+		want.ExpiryTime = &expiry
+		if until := time.Until(expiry.Time); until > 0 {
+			result.RequeueAfter = until
+		} else if want.Reason == "" {
+			want.Reason = retentionKeepFor
+		}
+	}
+	if retention != nil && retention.KeepLast > 0 {
+		beyond, err := r.markBeyondKeepLast(ctx, imageBuild)
+		if err != nil {
+			return ctrl.Result{}, false, err
+		}
+		if beyond && want.Reason == "" {
+			want.Reason = retentionKeepLast
+		}
+	}
+
+	if want.ExpiryTime == nil && want.Reason == "" {
+		want = nil
+	}
+	if !equality.Semantic.DeepEqual(st, want) {
+		fresh := &automotivev1alpha1.ImageBuild{}
+		if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+			return ctrl.Result{}, false, err
+		}
+		patch := client.MergeFrom(fresh.DeepCopy())
+		fresh.Status.Retention = want
+		if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+			return ctrl.Result{}, false, err
+		}
+		imageBuild.Status = fresh.Status
+	}
+	if want == nil || want.Reason == "" {
+		return result, false, nil
+	}
+	if publicationsPending(imageBuild) || archiving(imageBuild) {
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, true, nil
+	}
+	res, err := r.deleteArtifacts(ctx, imageBuild)
+	return res, true, err
+}
+
+// markBeyondKeepLast orders the completed builds of the KeepLast group of imageBuild newest first
+// and marks the ones beyond spec.retention.keepLast as expired. It reports whether imageBuild
+// itself is beyond the limit.
+func (r *ImageBuildReconciler) markBeyondKeepLast(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (bool, error) {
+	retention := imageBuild.Spec.Retention
+	builds := &automotivev1alpha1.ImageBuildList{}
+	if err := r.List(ctx, builds, client.InNamespace(imageBuild.Namespace)); err != nil {
+		return false, fmt.Errorf("listing image builds: %w", err)
+	}
+	var group []*automotivev1alpha1.ImageBuild
+	for i := range builds.Items {
+		build := &builds.Items[i]
+		if build.Status.Phase != "Completed" || build.Status.CompletionTime == nil ||
+			build.Spec.Retention == nil || build.Spec.Retention.KeepLast == 0 ||
+			build.Spec.Retention.NamePrefix != retention.NamePrefix ||
+			!strings.HasPrefix(build.Name, retention.NamePrefix) {
+			continue
+		}
+		group = append(group, build)
+	}
+	slices.SortFunc(group, func(a, b *automotivev1alpha1.ImageBuild) int {
+		if c := b.Status.CompletionTime.Compare(a.Status.CompletionTime.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(b.Name, a.Name)
+	})
+	beyond := false
+	for i := int(retention.KeepLast); i < len(group); i++ {
+		build := group[i]
+		if build.UID == imageBuild.UID {
+			beyond = true
+			continue
+		}
+		if build.Status.Retention != nil && build.Status.Retention.Reason != "" {
+			continue
+		}
+		patch := client.MergeFrom(build.DeepCopy())
+		if build.Status.Retention == nil {
+			build.Status.Retention = &automotivev1alpha1.RetentionStatus{}
+		}
+		build.Status.Retention.Reason = retentionKeepLast
+		if err := r.Status().Patch(ctx, build, patch); err != nil && !errors.IsNotFound(err) {
+			return false, fmt.Errorf("expiring the artifacts of %s: %w", build.Name, err)
+		}
+	}
+	return beyond, nil
+}
+
+// deleteArtifacts stops serving the artifacts of a build whose retention expired and deletes its
+// workspace PVC. Workspaces that spec.workspaceProtection asks to scrub are scrubbed instead, which
+// deletes the PVC once every file was overwritten; a failed scrub keeps it.
+func (r *ImageBuildReconciler) deleteArtifacts(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, error) {
+	r.stopServingArtifact(ctx, imageBuild)
+	if scrubRequested(imageBuild) {
+		result, err := r.scrubWorkspace(ctx, imageBuild)
+		if err != nil || result.RequeueAfter > 0 {
+			return result, err
+		}
+	} else if imageBuild.Status.PVCName != "" {
+		pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: imageBuild.Status.PVCName, Namespace: imageBuild.Namespace}}
+		if err := r.Delete(ctx, pvc); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{}, fmt.Errorf("failed to delete workspace PVC: %w", err)
+		}
+	}
+
+	fresh := &automotivev1alpha1.ImageBuild{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+		return ctrl.Result{}, err
+	}
+	if fresh.Status.Retention == nil || (scrubRequested(fresh) && fresh.Status.PVCName != "") {
+		// the scrub failed and kept the PVC, as its condition reports
+		return ctrl.Result{}, nil
+	}
+	patch := client.MergeFrom(fresh.DeepCopy())
+	reason := fresh.Status.Retention.Reason
+	now := metav1.Now()
+	fresh.Status.Retention.DeletionTime = &now
+	fresh.Status.PVCName = ""
+	fresh.Status.ArtifactURL = ""
+	fresh.Status.ArtifactFileName = ""
+	fresh.Status.ArtifactPath = ""
+	fresh.Status.Message = "Artifacts deleted by the retention policy"
+	if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+		return ctrl.Result{}, err
+	}
+	if reason == retentionKeepLast {
+		r.recordEvent(fresh, corev1.EventTypeNormal, EventReasonArtifactsDeleted, "Deleted the artifacts, newer builds replaced them")
+	} else {
+		r.recordEvent(fresh, corev1.EventTypeNormal, EventReasonArtifactsDeleted, "Deleted the artifacts, which expired at %s",
+			fresh.Status.Retention.ExpiryTime.Format(time.RFC3339))
+	}
+	return ctrl.Result{}, nil
+}
+
+// stopServingArtifact deletes the Service, Route, Pod and ConfigMap serving the artifact of a build
+func (r *ImageBuildReconciler) stopServingArtifact(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) {
+	log := r.Log.WithValues("imagebuild", types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace})
+
 	svcName := fmt.Sprintf("%s-artifact-service", imageBuild.Name)
 	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: svcName, Namespace: imageBuild.Namespace}}
 	if err := r.Delete(ctx, svc); err != nil && !errors.IsNotFound(err) {
@@ -203,122 +691,2986 @@ func (r *ImageBuildReconciler) handleCompletedState(ctx context.Context, imageBu
 	if err := r.Delete(ctx, cm); err != nil && !errors.IsNotFound(err) {
 		log.Error(err, "failed to delete nginx ConfigMap", "configMap", cmName)
 	}
-
-	fresh := &automotivev1alpha1.ImageBuild{}
-	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err == nil {
-		patch := client.MergeFrom(fresh.DeepCopy())
-		fresh.Status.ArtifactURL = ""
-		fresh.Status.ArtifactFileName = ""
-		fresh.Status.ArtifactPath = ""
-		fresh.Status.Message = "Build expired"
-		if err := r.Status().Patch(ctx, fresh, patch); err != nil {
-			log.Error(err, "failed to update ImageBuild status after expiry cleanup")
-		}
-	}
-
-	return ctrl.Result{}, nil
 }
 
-func (r *ImageBuildReconciler) checkBuildProgress(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, error) {
-	taskRun := &tektonv1.TaskRun{}
-	err := r.Get(ctx, types.NamespacedName{
-		Name:      imageBuild.Status.TaskRunName,
-		Namespace: imageBuild.Namespace,
-	}, taskRun)
-	if err != nil && !errors.IsNotFound(err) {
-		return ctrl.Result{}, err
-	}
+const (
+	// PublishedConditionType reports whether the artifact reached every target of spec.publishers
+	PublishedConditionType = "Published"
 
-	if errors.IsNotFound(err) {
-		return r.startNewBuild(ctx, imageBuild)
-	}
+	// publishTargetLabel tells the publish TaskRuns of a build apart from its build TaskRun
+	publishTargetLabel = "automotive.sdv.cloud.redhat.com/publish-target"
 
-	if !isTaskRunCompleted(taskRun) {
-		return ctrl.Result{RequeueAfter: time.Second * 30}, nil
-	}
+	// workspaceScrubLabel marks the TaskRun scrubbing the workspace of a build
+	workspaceScrubLabel = "automotive.sdv.cloud.redhat.com/workspace-scrub"
 
-	if isTaskRunSuccessful(taskRun) {
-		var artifactFileName string
-		for _, res := range taskRun.Status.TaskRunStatusFields.Results {
-			if res.Name == "artifact-filename" && res.Value.StringVal != "" {
-				artifactFileName = res.Value.StringVal
-				break
-			}
-		}
+	// artifactArchiveLabel marks the TaskRun copying the artifacts of a build to its archive PVC
+	artifactArchiveLabel = "automotive.sdv.cloud.redhat.com/artifact-archive"
 
-		if imageBuild.Spec.ServeArtifact {
-			if err := r.createArtifactPod(ctx, imageBuild); err != nil {
-				return ctrl.Result{}, err
-			}
+	// artifactSignLabel marks the TaskRun signing the artifact of a build
+	artifactSignLabel = "automotive.sdv.cloud.redhat.com/artifact-sign"
 
-			if imageBuild.Spec.ExposeRoute {
-				if err := r.createArtifactServingResources(ctx, imageBuild); err != nil {
-					return ctrl.Result{}, err
-				}
-			}
-		}
+	publicationPublishing = "Publishing"
+	publicationSucceeded  = "Succeeded"
+	publicationFailed     = "Failed"
 
-		fresh := &automotivev1alpha1.ImageBuild{}
-		if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
-			return ctrl.Result{}, err
-		}
+	// publicationReasonImmutableTag marks publications refused because the tag is immutable and exists
+	publicationReasonImmutableTag = "ImmutableTag"
 
-		patch := client.MergeFrom(fresh.DeepCopy())
+	defaultPublishRetries = 2
+)
 
-		if artifactFileName != "" {
-			fresh.Status.ArtifactFileName = artifactFileName
+// publishTargets returns the targets of spec.publishers; the registry publisher predating targets is
+// published as a target named registry unless a target of that name exists
+func publishTargets(imageBuild *automotivev1alpha1.ImageBuild) []automotivev1alpha1.PublishTarget {
+	publishers := imageBuild.Spec.Publishers
+	if publishers == nil {
+		return nil
+	}
+	var targets []automotivev1alpha1.PublishTarget
+	if publishers.Registry != nil {
+		named := false
+		for _, t := range publishers.Targets {
+			named = named || t.Name == tasks.PublishKindRegistry
 		}
-
-		fresh.Status.Phase = "Completed"
-		fresh.Status.Message = "Build completed successfully"
-		if fresh.Status.CompletionTime == nil {
-			now := metav1.Now()
-			fresh.Status.CompletionTime = &now
+		if !named {
+			targets = append(targets, automotivev1alpha1.PublishTarget{Name: tasks.PublishKindRegistry, Registry: publishers.Registry})
 		}
+	}
+	return append(targets, publishers.Targets...)
+}
 
-		if err := r.Status().Patch(ctx, fresh, patch); err != nil {
-			return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+// publishTargetTask returns the kind of publish task for target, the secret with its credentials
+// and the params of the task besides the artifact file name
+func publishTargetTask(target automotivev1alpha1.PublishTarget) (string, string, []tektonv1.Param) {
+	str := func(name, value string) tektonv1.Param {
+		return tektonv1.Param{Name: name, Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: value}}
+	}
+	switch {
+	case target.Registry != nil:
+		return tasks.PublishKindRegistry, target.Registry.Secret, []tektonv1.Param{
+			str("repository-url", target.Registry.RepositoryURL),
+			str("immutable-tags", strings.Join(target.Registry.ImmutableTags, " ")),
 		}
-
-		// Update artifact info after status is set
-		if imageBuild.Spec.ServeArtifact {
-			return r.updateArtifactInfo(ctx, imageBuild)
+	case target.S3 != nil:
+		return tasks.PublishKindS3, target.S3.Secret, []tektonv1.Param{
+			str("bucket", target.S3.Bucket),
+			str("prefix", target.S3.Prefix),
+			str("endpoint", target.S3.Endpoint),
+			str("region", target.S3.Region),
+		}
+	case target.PXE != nil:
+		return tasks.PublishKindPXE, target.PXE.Secret, []tektonv1.Param{
+			str("url", target.PXE.URL),
 		}
-
-		return ctrl.Result{}, nil
 	}
+	return "", "", nil
+}
 
-	if err := r.updateStatus(ctx, imageBuild, "Failed", "Build failed"); err != nil {
-		return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+// publishTaskRunName is the name of the TaskRun of one publish attempt. It is deterministic, so an
+// attempt whose status update was lost is adopted by the next reconcile rather than started twice.
+func publishTaskRunName(buildName, target string, attempt int32) string {
+	suffix := fmt.Sprintf("-publish-%s-%d", target, attempt)
+	if limit := validation.DNS1123LabelMaxLength - len(suffix); len(buildName) > limit {
+		buildName = strings.TrimRight(buildName[:limit], "-.")
 	}
-	return ctrl.Result{}, nil
+	return buildName + suffix
 }
 
-func (r *ImageBuildReconciler) startNewBuild(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, error) {
-	pvcName, err := r.getOrCreateWorkspacePVC(ctx, imageBuild)
-	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("failed to get or create workspace PVC: %w", err)
+// reconcilePublications publishes the artifact of a completed build to the targets of spec.publishers.
+// All targets are published at the same time, each by its own TaskRun, and a failed attempt is retried
+// up to the retries of its target. Progress is recorded per target in status.publications and
+// summarized by the Published condition.
+func (r *ImageBuildReconciler) reconcilePublications(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, error) {
+	targets := publishTargets(imageBuild)
+	if len(targets) == 0 {
+		return ctrl.Result{}, nil
+	}
+	log := r.Log.WithValues("imagebuild", types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace})
+
+	fresh := &automotivev1alpha1.ImageBuild{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	original := fresh.DeepCopy()
+
+	var publications []automotivev1alpha1.PublicationStatus
+	pending := false
+	for _, target := range targets {
+		st := automotivev1alpha1.PublicationStatus{Name: target.Name}
+		for _, existing := range fresh.Status.Publications {
+			if existing.Name == target.Name {
+				existing.DeepCopyInto(&st)
+			}
+		}
+		if err := r.reconcilePublication(ctx, fresh, target, &st); err != nil {
+			log.Error(err, "failed to publish artifact", "target", target.Name)
+			st.Message = err.Error()
+		}
+		pending = pending || (st.Phase != publicationSucceeded && st.Phase != publicationFailed)
+		publications = append(publications, st)
+	}
+	fresh.Status.Publications = publications
+	meta.SetStatusCondition(&fresh.Status.Conditions, publishedCondition(fresh))
+
+	if !equality.Semantic.DeepEqual(original.Status, fresh.Status) {
+		if err := r.Status().Patch(ctx, fresh, client.MergeFrom(original)); err != nil {
+			log.Error(err, "failed to update ImageBuild status with publications")
+			return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+		}
+	}
+	if pending {
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// reconcilePublication advances the publication of the artifact to target, whose progress is st:
+// it starts the first attempt, records the outcome of a finished attempt and retries failed ones
+func (r *ImageBuildReconciler) reconcilePublication(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild,
+	target automotivev1alpha1.PublishTarget, st *automotivev1alpha1.PublicationStatus) error {
+	st.Type, _, _ = publishTargetTask(target)
+	switch st.Phase {
+	case publicationSucceeded, publicationFailed:
+		return nil
+	case "":
+		return r.startPublication(ctx, imageBuild, target, st)
+	}
+
+	taskRun := &tektonv1.TaskRun{}
+	err := r.Get(ctx, types.NamespacedName{Name: st.TaskRunName, Namespace: imageBuild.Namespace}, taskRun)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	var message string
+	switch {
+	case errors.IsNotFound(err):
+		message = fmt.Sprintf("TaskRun %s was deleted before it finished", st.TaskRunName)
+	case !isTaskRunCompleted(taskRun):
+		return nil
+	case isTaskRunSuccessful(taskRun):
+		var rejected string
+		for _, res := range taskRun.Status.Results {
+			switch res.Name {
+			case "location":
+				st.Location = strings.TrimSpace(res.Value.StringVal)
+			case "rejected":
+				rejected = strings.TrimSpace(res.Value.StringVal)
+			}
+		}
+		now := metav1.Now()
+		if rejected != "" {
+			// retrying cannot help; the build has to be published under a new version
+			st.Phase = publicationFailed
+			st.Reason = publicationReasonImmutableTag
+			st.Message = rejected
+			st.CompletionTime = &now
+			r.recordEvent(imageBuild, corev1.EventTypeWarning, EventReasonPublishFailed,
+				"Publishing to target %s was refused: %s", target.Name, rejected)
+			return nil
+		}
+		st.Phase = publicationSucceeded
+		st.Message = fmt.Sprintf("Published in attempt %d", st.Attempts)
+		st.CompletionTime = &now
+		r.recordEvent(imageBuild, corev1.EventTypeNormal, EventReasonPublished,
+			"Published %s to target %s: %s", imageBuild.Status.ArtifactFileName, target.Name, st.Location)
+		return nil
+	default:
+		message = "TaskRun failed"
+		if conditions := taskRun.Status.Conditions; len(conditions) > 0 && conditions[0].Message != "" {
+			message = conditions[0].Message
+		}
+	}
+
+	retries := int32(defaultPublishRetries)
+	if target.Retries != nil {
+		retries = *target.Retries
+	}
+	if st.Attempts > retries {
+		now := metav1.Now()
+		st.Phase = publicationFailed
+		st.Message = message
+		st.CompletionTime = &now
+		r.recordEvent(imageBuild, corev1.EventTypeWarning, EventReasonPublishFailed,
+			"Publishing to target %s failed after %d attempts: %s", target.Name, st.Attempts, message)
+		return nil
+	}
+	r.recordEvent(imageBuild, corev1.EventTypeWarning, EventReasonPublishRetried,
+		"Attempt %d of publishing to target %s failed, retrying: %s", st.Attempts, target.Name, message)
+	return r.startPublication(ctx, imageBuild, target, st)
+}
+
+// startPublication creates the TaskRun of the next attempt to publish the artifact to target
+func (r *ImageBuildReconciler) startPublication(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild,
+	target automotivev1alpha1.PublishTarget, st *automotivev1alpha1.PublicationStatus) error {
+	if imageBuild.Status.PVCName == "" {
+		now := metav1.Now()
+		st.Phase = publicationFailed
+		st.Message = "The workspace holding the artifact was released"
+		st.CompletionTime = &now
+		return nil
+	}
+	fileName := imageBuild.Status.ArtifactFileName
+	if fileName == "" {
+		fileName = defaultArtifactFileName(imageBuild)
+	}
+
+	kind, secretRef, params := publishTargetTask(target)
+	task := tasks.GeneratePublishArtifactTask(imageBuild.Namespace, kind, secretRef)
+	attempt := st.Attempts + 1
+	taskRun := &tektonv1.TaskRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      publishTaskRunName(imageBuild.Name, target.Name, attempt),
+			Namespace: imageBuild.Namespace,
+			Labels: map[string]string{
+				tektonv1.ManagedByLabelKey:                        "automotive-dev-operator",
+				"automotive.sdv.cloud.redhat.com/imagebuild-name": imageBuild.Name,
+				publishTargetLabel:                                target.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(imageBuild, automotivev1alpha1.GroupVersion.WithKind("ImageBuild")),
+			},
+		},
+		Spec: tektonv1.TaskRunSpec{
+			TaskSpec: &task.Spec,
+			Params: append([]tektonv1.Param{
+				{Name: "artifact-filename", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: fileName}},
+			}, params...),
+			Workspaces: []tektonv1.WorkspaceBinding{
+				{
+					Name: "shared-workspace",
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: imageBuild.Status.PVCName,
+					},
+				},
+			},
+		},
+	}
+	taskRun.Spec.PodTemplate = artifactPodAffinity(imageBuild)
+
+	if err := r.Create(ctx, taskRun); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create publish TaskRun: %w", err)
+		}
+		existing := &tektonv1.TaskRun{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(taskRun), existing); err != nil {
+			return fmt.Errorf("failed to get existing publish TaskRun: %w", err)
+		}
+		if !metav1.IsControlledBy(existing, imageBuild) {
+			return fmt.Errorf("TaskRun %s belongs to another ImageBuild", taskRun.Name)
+		}
+	}
+
+	st.Phase = publicationPublishing
+	st.Attempts = attempt
+	st.TaskRunName = taskRun.Name
+	st.Message = fmt.Sprintf("Attempt %d running", attempt)
+	st.Reason = ""
+	st.Location = ""
+	st.CompletionTime = nil
+	return nil
+}
+
+// artifactPodAffinity schedules a TaskRun reading the workspace of a completed build next to the
+// artifact pod serving it, since a ReadWriteOnce workspace can only be mounted on that node. It is
+// nil for workspaces shared across nodes.
+func artifactPodAffinity(imageBuild *automotivev1alpha1.ImageBuild) *pod.PodTemplate {
+	if workspaceSharedAcrossNodes(imageBuild) {
+		return nil
+	}
+	return &pod.PodTemplate{
+		Affinity: &corev1.Affinity{
+			PodAffinity: &corev1.PodAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+					{
+						Weight: 100,
+						PodAffinityTerm: corev1.PodAffinityTerm{
+							LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+								"automotive.sdv.cloud.redhat.com/imagebuild-name": imageBuild.Name,
+								"app.kubernetes.io/name":                          "artifact-pod",
+							}},
+							TopologyKey: corev1.LabelHostname,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// publishedCondition summarizes status.publications: Unknown while targets are still publishing,
+// False when a target failed, with reason ImmutableTagExists when one refused to overwrite an
+// immutable tag, and True once all succeeded
+func publishedCondition(imageBuild *automotivev1alpha1.ImageBuild) metav1.Condition {
+	var failed, pending, immutable []string
+	for _, st := range imageBuild.Status.Publications {
+		switch st.Phase {
+		case publicationSucceeded:
+		case publicationFailed:
+			failed = append(failed, st.Name)
+			if st.Reason == publicationReasonImmutableTag {
+				immutable = append(immutable, st.Name)
+			}
+		default:
+			pending = append(pending, st.Name)
+		}
+	}
+	cond := metav1.Condition{
+		Type:               PublishedConditionType,
+		ObservedGeneration: imageBuild.Generation,
+	}
+	switch {
+	case len(pending) > 0:
+		cond.Status = metav1.ConditionUnknown
+		cond.Reason = "Publishing"
+		cond.Message = "Publishing to " + strings.Join(pending, ", ")
+	case len(immutable) > 0:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "ImmutableTagExists"
+		cond.Message = "Publishing to " + strings.Join(immutable, ", ") +
+			" would overwrite an immutable tag; publish a new version. See status.publications"
+	case len(failed) > 0:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "PublishFailed"
+		cond.Message = "Publishing to " + strings.Join(failed, ", ") + " failed; see status.publications"
+	default:
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = "AllTargetsPublished"
+		cond.Message = fmt.Sprintf("Published to %d targets", len(imageBuild.Status.Publications))
+	}
+	return cond
+}
+
+func (r *ImageBuildReconciler) checkBuildProgress(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, error) {
+	taskRun := &tektonv1.TaskRun{}
+	err := r.Get(ctx, types.NamespacedName{
+		Name:      imageBuild.Status.TaskRunName,
+		Namespace: imageBuild.Namespace,
+	}, taskRun)
+	if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	if errors.IsNotFound(err) {
+		return r.startNewBuild(ctx, imageBuild)
+	}
+
+	if !isTaskRunCompleted(taskRun) {
+		if err := r.updateDebugStatus(ctx, imageBuild, taskRun); err != nil {
+			r.Log.Error(err, "failed to record debug hold", "imagebuild", imageBuild.Name)
+		}
+		return ctrl.Result{RequeueAfter: time.Second * 30}, nil
+	}
+
+	environment := r.buildEnvironment(ctx, taskRun)
+	logArchive := r.archiveBuildLogs(ctx, imageBuild, taskRun)
+	if isTaskRunSuccessful(taskRun) {
+		var artifactFileName string
+		var stageTimings map[string]string
+		var firstBootFileName string
+		var hardeningReportFileName string
+		var packagesFileName string
+		var gitCommit string
+		var complianceResult string
+		var artifactSize int64
+		var artifactSHA256 string
+		var rootfsSize int64
+		var bootResult, bootAccelerator string
+		var bootTimings map[string]time.Duration
+		for _, res := range taskRun.Status.TaskRunStatusFields.Results {
+			switch {
+			case res.Name == "artifact-filename" && res.Value.StringVal != "":
+				artifactFileName = res.Value.StringVal
+			case res.Name == "stage-timings" && res.Value.StringVal != "":
+				stageTimings = parseStageTimings(res.Value.StringVal)
+			case res.Name == "firstboot-filename" && res.Value.StringVal != "":
+				firstBootFileName = strings.TrimSpace(res.Value.StringVal)
+			case res.Name == "hardening-report-filename" && res.Value.StringVal != "":
+				hardeningReportFileName = strings.TrimSpace(res.Value.StringVal)
+			case res.Name == "packages-filename" && res.Value.StringVal != "":
+				packagesFileName = strings.TrimSpace(res.Value.StringVal)
+			case res.Name == "git-commit" && res.Value.StringVal != "":
+				gitCommit = strings.TrimSpace(res.Value.StringVal)
+			case res.Name == "artifact-size" && res.Value.StringVal != "":
+				if n, err := strconv.ParseInt(strings.TrimSpace(res.Value.StringVal), 10, 64); err == nil {
+					artifactSize = n
+				}
+			case res.Name == "artifact-sha256" && res.Value.StringVal != "":
+				artifactSHA256 = strings.TrimSpace(res.Value.StringVal)
+			case res.Name == "compliance-result" && res.Value.StringVal != "":
+				complianceResult = strings.TrimSpace(res.Value.StringVal)
+			case res.Name == "boot-test-result" && res.Value.StringVal != "":
+				bootResult = strings.TrimSpace(res.Value.StringVal)
+			case res.Name == "boot-timings" && res.Value.StringVal != "":
+				bootTimings = parseBootTimings(res.Value.StringVal)
+			case res.Name == "boot-accelerator" && res.Value.StringVal != "":
+				bootAccelerator = strings.TrimSpace(res.Value.StringVal)
+			case res.Name == "rootfs-size" && res.Value.StringVal != "":
+				if n, err := strconv.ParseInt(strings.TrimSpace(res.Value.StringVal), 10, 64); err == nil {
+					rootfsSize = n
+				}
+			}
+		}
+
+		if imageBuild.Spec.ServeArtifact {
+			if err := r.createArtifactPod(ctx, imageBuild); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			// partner artifacts are only served through the build API
+			profile, err := r.partnerIsolation(ctx, imageBuild.Namespace)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if imageBuild.Spec.ExposeRoute && profile == nil {
+				if err := r.createArtifactServingResources(ctx, imageBuild); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+		}
+
+		fresh := &automotivev1alpha1.ImageBuild{}
+		if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		patch := client.MergeFrom(fresh.DeepCopy())
+
+		if artifactFileName != "" {
+			fresh.Status.ArtifactFileName = artifactFileName
+		}
+		if artifactSize > 0 {
+			fresh.Status.ArtifactSizeBytes = artifactSize
+		}
+		if artifactSHA256 != "" {
+			fresh.Status.ArtifactSHA256 = artifactSHA256
+		}
+		if len(stageTimings) > 0 {
+			fresh.Status.StageTimings = stageTimings
+		}
+		fresh.Status.Environment = environment
+		if logArchive != nil {
+			fresh.Status.LogArchive = logArchive
+		}
+		if firstBootFileName != "" {
+			fresh.Status.FirstBootFileName = firstBootFileName
+		}
+		if hardeningReportFileName != "" {
+			fresh.Status.HardeningReportFileName = hardeningReportFileName
+		}
+		if packagesFileName != "" {
+			fresh.Status.PackagesFileName = packagesFileName
+		}
+		if gitCommit != "" {
+			fresh.Status.GitCommit = gitCommit
+		}
+
+		previousPhase := fresh.Status.Phase
+		fresh.Status.Phase = "Completed"
+		fresh.Status.Message = "Build completed successfully"
+		if scan := fresh.Spec.Compliance; scan != nil {
+			setComplianceStatus(fresh, complianceResult)
+			if scan.Enforce && fresh.Status.Compliance.Result != complianceResultPass {
+				fresh.Status.Phase = "Failed"
+				fresh.Status.Message = fmt.Sprintf("Compliance scan against profile %s did not pass: %s", scan.Profile, fresh.Status.Compliance.Result)
+			}
+		}
+		if budget := fresh.Spec.SizeBudget; budget != nil {
+			setSizeStatus(fresh, rootfsSize)
+			if fresh.Status.Size.Exceeded {
+				exceeded := fmt.Sprintf("root filesystem uses %d bytes, over the size budget of %s", rootfsSize, budget.MaxSize)
+				switch {
+				case fresh.Status.Phase == "Failed":
+				case budget.Action == sizeBudgetActionWarn:
+					fresh.Status.Message = "Build completed successfully; " + exceeded
+				default:
+					fresh.Status.Phase = "Failed"
+					fresh.Status.Message = "Build failed: " + exceeded
+				}
+			}
+		}
+		if bootTest := fresh.Spec.BootTest; bootTest != nil {
+			passed := setBootTestStatus(fresh, bootResult, bootAccelerator, bootTimings)
+			if bootTest.Enforce && !passed && fresh.Status.Phase != "Failed" {
+				fresh.Status.Phase = "Failed"
+				fresh.Status.Message = "Boot test did not pass: " + meta.FindStatusCondition(fresh.Status.Conditions, BootTestConditionType).Message
+			}
+		}
+		if fresh.Status.CompletionTime == nil {
+			now := metav1.Now()
+			fresh.Status.CompletionTime = &now
+		}
+
+		if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+			return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+		}
+		r.recordPhaseEvent(fresh, previousPhase)
+		r.notifyWebhooks(fresh, previousPhase)
+		r.postCommitStatus(fresh)
+
+		// Update artifact info after status is set
+		if imageBuild.Spec.ServeArtifact {
+			result, err := r.updateArtifactInfo(ctx, imageBuild)
+			r.dispatchPlugins(imageBuild)
+			return result, err
+		}
+
+		r.dispatchPlugins(imageBuild)
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.recordBuildEnvironment(ctx, imageBuild, environment, logArchive); err != nil {
+		return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+	}
+	if err := r.updateStatus(ctx, imageBuild, "Failed", taskRunFailureMessage(taskRun)); err != nil {
+		return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// buildStepContainer is the container of the build-image step in the build pod
+const buildStepContainer = "step-build-image"
+
+// buildEnvironment describes where and with what the finished build TaskRun ran: the node of its
+// pod, the builder image the node ran and what the build step reported in its build-environment
+// result. Whatever cannot be read, e.g. the pod of a TaskRun that was pruned, is left out.
+func (r *ImageBuildReconciler) buildEnvironment(ctx context.Context, taskRun *tektonv1.TaskRun) *automotivev1alpha1.BuildEnvironment {
+	env := &automotivev1alpha1.BuildEnvironment{}
+	for _, res := range taskRun.Status.Results {
+		if res.Name == "build-environment" {
+			parseBuildEnvironment(res.Value.StringVal, env)
+		}
+	}
+
+	if taskRun.Status.PodName != "" {
+		pod := &corev1.Pod{}
+		err := r.Get(ctx, types.NamespacedName{Name: taskRun.Status.PodName, Namespace: taskRun.Namespace}, pod)
+		if err == nil {
+			env.NodeName = pod.Spec.NodeName
+			for _, cs := range pod.Status.ContainerStatuses {
+				if cs.Name == buildStepContainer {
+					env.BuilderImage = cs.Image
+					env.BuilderImageDigest = cs.ImageID
+				}
+			}
+		} else if !errors.IsNotFound(err) {
+			r.Log.Error(err, "failed to get build pod for the build environment", "pod", taskRun.Status.PodName)
+		}
+	}
+	if env.NodeName != "" {
+		node := &corev1.Node{}
+		if err := r.Get(ctx, types.NamespacedName{Name: env.NodeName}, node); err == nil {
+			env.KernelVersion = node.Status.NodeInfo.KernelVersion
+			env.OSImage = node.Status.NodeInfo.OSImage
+			env.ContainerRuntime = node.Status.NodeInfo.ContainerRuntimeVersion
+		} else if !errors.IsNotFound(err) {
+			r.Log.Error(err, "failed to get build node for the build environment", "node", env.NodeName)
+		}
+	}
+
+	if equality.Semantic.DeepEqual(env, &automotivev1alpha1.BuildEnvironment{}) {
+		return nil
+	}
+	return env
+}
+
+// parseBuildEnvironment fills env from the "key=value,..." build-environment task result
+func parseBuildEnvironment(raw string, env *automotivev1alpha1.BuildEnvironment) {
+	for _, pair := range strings.Split(strings.TrimSpace(raw), ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			continue
+		}
+		switch key = strings.TrimSpace(key); {
+		case key == "osbuild":
+			env.OSBuildVersion = value
+		case key == "aib":
+			env.AIBVersion = value
+		case key == "selinux":
+			env.SELinux = value
+		case strings.HasPrefix(key, "sysctl."):
+			if env.Sysctls == nil {
+				env.Sysctls = map[string]string{}
+			}
+			env.Sysctls[strings.TrimPrefix(key, "sysctl.")] = value
+		}
+	}
+}
+
+// recordBuildEnvironment stores env and the log archive in the status of a build that failed
+func (r *ImageBuildReconciler) recordBuildEnvironment(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild,
+	env *automotivev1alpha1.BuildEnvironment, logArchive *automotivev1alpha1.LogArchiveStatus) error {
+	if env == nil && logArchive == nil {
+		return nil
+	}
+	fresh := &automotivev1alpha1.ImageBuild{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+		return err
+	}
+	original := fresh.DeepCopy()
+	if env != nil {
+		fresh.Status.Environment = env
+	}
+	if logArchive != nil {
+		fresh.Status.LogArchive = logArchive
+	}
+	if equality.Semantic.DeepEqual(fresh.Status, original.Status) {
+		return nil
+	}
+	return r.Status().Patch(ctx, fresh, client.MergeFrom(original))
+}
+
+// logArchiveTimeout bounds reading the logs of a build pod into its archive
+const logArchiveTimeout = 2 * time.Minute
+
+// archiveBuildLogs keeps the step logs of the pod of a finished TaskRun in a gzip archive split
+// into ConfigMaps owned by the build, so they can be downloaded after the pod is gone. It returns
+// the status of the archive, or nil when there is none: log archives are disabled, the pod is gone
+// already or the archive could not be stored.
+func (r *ImageBuildReconciler) archiveBuildLogs(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild,
+	taskRun *tektonv1.TaskRun) *automotivev1alpha1.LogArchiveStatus {
+	if r.Clientset == nil || taskRun.Status.PodName == "" {
+		return nil
+	}
+	if imageBuild.Status.LogArchive != nil {
+		return imageBuild.Status.LogArchive
+	}
+	ctx, cancel := context.WithTimeout(ctx, logArchiveTimeout)
+	defer cancel()
+
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, types.NamespacedName{Name: taskRun.Status.PodName, Namespace: taskRun.Namespace}, pod); err != nil {
+		if !errors.IsNotFound(err) {
+			r.Log.Error(err, "failed to get build pod for the log archive", "pod", taskRun.Status.PodName)
+		}
+		return nil
+	}
+	w := logarchive.NewWriter()
+	for _, container := range pod.Spec.Containers {
+		if !strings.HasPrefix(container.Name, "step-") {
+			continue
+		}
+		step := strings.TrimPrefix(container.Name, "step-")
+		stream, err := r.Clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: container.Name}).Stream(ctx)
+		if err != nil {
+			err = w.AddNote(fmt.Sprintf("Logs of %s unavailable: %v", step, err))
+		} else {
+			err = w.AddStep(step, stream)
+			stream.Close()
+		}
+		if err != nil {
+			r.Log.Error(err, "failed to archive build logs", "imagebuild", imageBuild.Name, "step", step)
+			return nil
+		}
+	}
+	archive, err := w.Close()
+	if err != nil {
+		r.Log.Error(err, "failed to archive build logs", "imagebuild", imageBuild.Name)
+		return nil
+	}
+	parts := archive.Parts()
+	for i, data := range parts {
+		if err := r.storeLogArchivePart(ctx, imageBuild, i, data); err != nil {
+			r.Log.Error(err, "failed to store build log archive", "imagebuild", imageBuild.Name, "part", i)
+			return nil
+		}
+	}
+	// Parts of an earlier archive of the build beyond the new one are stale
+	for i := len(parts); i < logarchive.MaxParts; i++ {
+		stale := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: logarchive.PartName(imageBuild.Name, i), Namespace: imageBuild.Namespace}}
+		if err := r.Delete(ctx, stale); err != nil && !errors.IsNotFound(err) {
+			r.Log.Error(err, "failed to delete stale build log archive part", "configmap", stale.Name)
+		}
+	}
+	r.Log.Info("archived build logs", "imagebuild", imageBuild.Name, "bytes", len(archive.Data), "logBytes", archive.LogSize, "truncated", archive.Truncated)
+	return &automotivev1alpha1.LogArchiveStatus{
+		Parts:     int32(len(parts)),
+		Size:      int64(len(archive.Data)),
+		LogSize:   archive.LogSize,
+		SHA256:    archive.SHA256(),
+		Truncated: archive.Truncated,
+	}
+}
+
+// storeLogArchivePart creates or replaces the ConfigMap holding part i of the log archive of a build
+func (r *ImageBuildReconciler) storeLogArchivePart(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild, i int, data []byte) error {
+	cm := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: logarchive.PartName(imageBuild.Name, i), Namespace: imageBuild.Namespace}, cm)
+	switch {
+	case errors.IsNotFound(err):
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      logarchive.PartName(imageBuild.Name, i),
+				Namespace: imageBuild.Namespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/managed-by":                    "automotive-dev-operator",
+					"app.kubernetes.io/component":                     logarchive.ComponentLabel,
+					"automotive.sdv.cloud.redhat.com/imagebuild-name": imageBuild.Name,
+				},
+				Annotations: map[string]string{logarchive.PartAnnotation: strconv.Itoa(i)},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: imageBuild.APIVersion,
+						Kind:       imageBuild.Kind,
+						Name:       imageBuild.Name,
+						UID:        imageBuild.UID,
+						Controller: ptr.To(true),
+					},
+				},
+			},
+			BinaryData: map[string][]byte{logarchive.DataKey: data},
+		}
+		return r.Create(ctx, cm)
+	case err != nil:
+		return err
+	}
+	cm.BinaryData = map[string][]byte{logarchive.DataKey: data}
+	return r.Update(ctx, cm)
+}
+
+// taskRunFailureMessage is the status message of a build whose TaskRun failed. It includes the
+// reason Tekton reports, e.g. the step that exited non-zero, so failures can be told apart.
+func taskRunFailureMessage(taskRun *tektonv1.TaskRun) string {
+	if conditions := taskRun.Status.Conditions; len(conditions) > 0 && conditions[0].Message != "" {
+		return "Build failed: " + conditions[0].Message
+	}
+	return "Build failed"
+}
+
+func (r *ImageBuildReconciler) startNewBuild(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, error) {
+	pvcName, err := r.getOrCreateWorkspacePVC(ctx, imageBuild)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get or create workspace PVC: %w", err)
+	}
+
+	if imageBuild.Status.PVCName != pvcName {
+		fresh := &automotivev1alpha1.ImageBuild{}
+		if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to get fresh ImageBuild: %w", err)
+		}
+
+		fresh.Status.PVCName = pvcName
+		if err := r.Status().Update(ctx, fresh); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update ImageBuild status with PVC name: %w", err)
+		}
+
+		imageBuild.Status.PVCName = pvcName
+	}
+
+	if err := r.prepareFirstBoot(ctx, imageBuild); err != nil {
+		var invalid *invalidSpecError
+		if stderrors.As(err, &invalid) {
+			if err := r.updateStatus(ctx, imageBuild, "Failed", invalid.Error()); err != nil {
+				return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+			}
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to prepare firstBoot payload: %w", err)
+	}
+
+	if err := r.prepareHardening(ctx, imageBuild); err != nil {
+		var invalid *invalidSpecError
+		if stderrors.As(err, &invalid) {
+			if err := r.updateStatus(ctx, imageBuild, "Failed", invalid.Error()); err != nil {
+				return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+			}
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to prepare hardening profiles: %w", err)
+	}
+
+	if _, err := bootTestThreshold(imageBuild); err != nil {
+		if err := r.updateStatus(ctx, imageBuild, "Failed", err.Error()); err != nil {
+			return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if _, err := sizeBudgetBytes(imageBuild); err != nil {
+		if err := r.updateStatus(ctx, imageBuild, "Failed", err.Error()); err != nil {
+			return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.validateRuntimeClass(ctx, imageBuild); err != nil {
+		var invalid *invalidSpecError
+		if stderrors.As(err, &invalid) {
+			if err := r.updateStatus(ctx, imageBuild, "Failed", invalid.Error()); err != nil {
+				return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+			}
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to validate runtime class: %w", err)
+	}
+
+	if err := r.applyNetworkPolicy(ctx, imageBuild); err != nil {
+		var invalid *invalidSpecError
+		if stderrors.As(err, &invalid) {
+			if err := r.updateStatus(ctx, imageBuild, "Failed", invalid.Error()); err != nil {
+				return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+			}
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to apply build network policy: %w", err)
+	}
+
+	if err := r.createBuildTaskRun(ctx, imageBuild); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to create build task run: %w", err)
+	}
+
+	return ctrl.Result{RequeueAfter: time.Second * 30}, nil
+}
+
+// WorkspaceStorageConditionType reports whether the storage class of the build provides the
+// access mode its workspace PVC needs
+const WorkspaceStorageConditionType = "WorkspaceStorageReady"
+
+// checkWorkspaceStorage records the access mode of the workspace PVC in the status, along with the
+// WorkspaceStorageReady condition. Builds whose storage class cannot provide the requested mode, or
+// an encrypted workspace when spec.workspaceProtection requires one, get an invalidSpecError, so
+// they fail up front instead of waiting for a PVC that never binds.
+func (r *ImageBuildReconciler) checkWorkspaceStorage(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) error {
+	class, err := r.workspaceStorageClass(ctx, imageBuild)
+	if err != nil {
+		return err
+	}
+	sc, err := storage.Class(ctx, r, class)
+	var mode corev1.PersistentVolumeAccessMode
+	if err == nil {
+		mode, err = storage.WorkspaceAccessMode(sc, imageBuild.Spec.WorkspaceAccessMode)
+	}
+	encrypted := imageBuild.Spec.WorkspaceProtection != nil && imageBuild.Spec.WorkspaceProtection.RequireEncryption
+	if err == nil && encrypted {
+		err = storage.RequireEncryption(sc)
+	}
+	var unsupported *storage.UnsupportedError
+	if err != nil && !stderrors.As(err, &unsupported) {
+		return err
+	}
+
+	cond := metav1.Condition{
+		Type:               WorkspaceStorageConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             "AccessModeSupported",
+		ObservedGeneration: imageBuild.Generation,
+	}
+	switch {
+	case unsupported != nil:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = unsupported.Reason
+		cond.Message = unsupported.Message
+	case sc != nil && encrypted:
+		cond.Message = fmt.Sprintf("Storage class %s provides encrypted %s workspaces", sc.Name, mode)
+	case sc != nil:
+		cond.Message = fmt.Sprintf("Storage class %s provides %s workspaces", sc.Name, mode)
+	default:
+		cond.Message = fmt.Sprintf("No default storage class; the %s workspace needs a matching volume", mode)
+	}
+
+	fresh := &automotivev1alpha1.ImageBuild{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+		return err
+	}
+	patch := client.MergeFrom(fresh.DeepCopy())
+	fresh.Status.WorkspaceAccessMode = string(mode)
+	meta.SetStatusCondition(&fresh.Status.Conditions, cond)
+	if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+		return err
+	}
+	imageBuild.Status = fresh.Status
+
+	if unsupported != nil {
+		field := "workspaceAccessMode"
+		switch unsupported.Reason {
+		case storage.ReasonClassNotFound:
+			field = "storageClass"
+		case storage.ReasonNotEncrypted:
+			field = "workspaceProtection"
+		}
+		return &invalidSpecError{field, unsupported}
+	}
+	return nil
+}
+
+// buildSlotRecheckInterval is how often builds waiting for a build slot check again
+const buildSlotRecheckInterval = 15 * time.Second
+
+// Backends of spec.osBuilds.buildQueue
+const (
+	buildQueueInCluster = "InCluster"
+	buildQueueRedis     = "Redis"
+)
+
+// priorityRank orders the values of spec.priority; builds without one are normal
+func priorityRank(priority string) int {
+	switch priority {
+	case "high":
+		return 2
+	case "low":
+		return 0
+	}
+	return 1
+}
+
+// buildSlotQueuePosition returns the position of the build among the builds waiting for a build
+// slot, starting at 1, and how many builds are waiting, when spec.osBuilds.maxConcurrentBuilds of
+// the OperatorConfig is reached. The build queue of spec.osBuilds.buildQueue orders the waiting
+// builds of all namespaces by priority, then by creation time, and admits the first ones into the
+// free slots; a position of 0 means the build was admitted and may start.
+func (r *ImageBuildReconciler) buildSlotQueuePosition(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (int32, int, error) {
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	err := r.Get(ctx, types.NamespacedName{Name: "config", Namespace: OperatorNamespace}, operatorConfig)
+	if err != nil && !errors.IsNotFound(err) {
+		return 0, 0, err
+	}
+	if operatorConfig.Spec.OSBuilds == nil || operatorConfig.Spec.OSBuilds.MaxConcurrentBuilds <= 0 {
+		return 0, 0, nil
+	}
+	limit := int(operatorConfig.Spec.OSBuilds.MaxConcurrentBuilds)
+
+	queue, err := r.buildQueue(ctx, operatorConfig.Spec.OSBuilds.BuildQueue)
+	if err != nil {
+		return 0, 0, err
+	}
+	item := buildqueue.Item{
+		UID:     string(imageBuild.UID),
+		Key:     imageBuild.Namespace + "/" + imageBuild.Name,
+		Rank:    priorityRank(imageBuild.Spec.Priority),
+		Created: imageBuild.CreationTimestamp.Time,
+	}
+	admission, err := queue.Admit(ctx, item, limit)
+	if err != nil {
+		return 0, 0, fmt.Errorf("build queue: %w", err)
+	}
+	if !admission.Admitted {
+		// builds that ended or were deleted while in the queue may hold slots or positions
+		removed, err := r.pruneBuildQueue(ctx, queue)
+		if err != nil {
+			return 0, 0, fmt.Errorf("build queue: %w", err)
+		}
+		if removed > 0 {
+			if admission, err = queue.Admit(ctx, item, limit); err != nil {
+				return 0, 0, fmt.Errorf("build queue: %w", err)
+			}
+		}
+	}
+	if admission.Admitted {
+		return 0, 0, nil
+	}
+	return int32(admission.Position), admission.Waiting, nil
+}
+
+// pruneBuildQueue removes the builds that no longer wait for or hold a slot from queue and returns
+// how many it removed
+func (r *ImageBuildReconciler) pruneBuildQueue(ctx context.Context, queue buildqueue.Queue) (int, error) {
+	uids, err := queue.List(ctx)
+	if err != nil || len(uids) == 0 {
+		return 0, err
+	}
+	builds := &automotivev1alpha1.ImageBuildList{}
+	if err := r.List(ctx, builds); err != nil {
+		return 0, fmt.Errorf("listing image builds: %w", err)
+	}
+	queued := map[string]bool{}
+	for _, build := range builds.Items {
+		switch build.Status.Phase {
+		case "", "Queued", "Uploading", "Building":
+			queued[string(build.UID)] = true
+		}
+	}
+	removed := 0
+	for _, uid := range uids {
+		if queued[uid] {
+			continue
+		}
+		if err := queue.Release(ctx, uid); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// buildQueue returns the build queue of cfg, spec.osBuilds.buildQueue of the OperatorConfig. The
+// connection to a Redis server is kept until the configuration or its password changes.
+func (r *ImageBuildReconciler) buildQueue(ctx context.Context, cfg *automotivev1alpha1.BuildQueueConfig) (buildqueue.Queue, error) {
+	if cfg == nil || cfg.Backend == "" || cfg.Backend == buildQueueInCluster {
+		return clusterQueue{reader: r.Client}, nil
+	}
+	if cfg.Backend != buildQueueRedis {
+		return nil, fmt.Errorf("unknown build queue backend %q", cfg.Backend)
+	}
+	if cfg.Redis == nil {
+		return nil, fmt.Errorf("build queue backend Redis needs spec.osBuilds.buildQueue.redis")
+	}
+	key := redisQueueKey{config: *cfg.Redis}
+	if ref := cfg.Redis.PasswordSecretRef; ref != "" {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref, Namespace: OperatorNamespace}, secret); err != nil {
+			return nil, fmt.Errorf("reading build queue password Secret %s: %w", ref, err)
+		}
+		key.username = string(secret.Data["username"])
+		key.password = string(secret.Data["password"])
+	}
+
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	if r.redisQueue != nil && r.redisQueueKey == key {
+		return r.redisQueue, nil
+	}
+	if r.redisQueue != nil {
+		_ = r.redisQueue.Client.Close()
+	}
+	redisClient := &buildqueue.RedisClient{Address: cfg.Redis.Address, Username: key.username, Password: key.password}
+	if cfg.Redis.TLS {
+		redisClient.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	r.redisQueue = &buildqueue.Redis{Client: redisClient, KeyPrefix: cfg.Redis.KeyPrefix}
+	r.redisQueueKey = key
+	return r.redisQueue, nil
+}
+
+// redisQueueKey is what the connection of the Redis build queue was opened with
+type redisQueueKey struct {
+	config             automotivev1alpha1.RedisQueueConfig
+	username, password string
+}
+
+// clusterQueue is the InCluster build queue: the Queued ImageBuilds in the cache of the controller,
+// ordered again on every admission. Uploading and Building builds hold a slot. Builds admitted at
+// the same time by several replicas or shards may exceed the limit.
+type clusterQueue struct {
+	reader client.Reader
+}
+
+// Admit implements buildqueue.Queue
+func (q clusterQueue) Admit(ctx context.Context, item buildqueue.Item, limit int) (buildqueue.Admission, error) {
+	builds := &automotivev1alpha1.ImageBuildList{}
+	if err := q.reader.List(ctx, builds); err != nil {
+		return buildqueue.Admission{}, fmt.Errorf("listing image builds: %w", err)
+	}
+	running := 0
+	var waiting []buildqueue.Item
+	for i := range builds.Items {
+		build := &builds.Items[i]
+		switch build.Status.Phase {
+		case "Uploading", "Building":
+			running++
+		case "", "Queued":
+			if build.DeletionTimestamp == nil {
+				waiting = append(waiting, buildqueue.Item{
+					UID:     string(build.UID),
+					Key:     build.Namespace + "/" + build.Name,
+					Rank:    priorityRank(build.Spec.Priority),
+					Created: build.CreationTimestamp.Time,
+				})
+			}
+		}
+	}
+	slices.SortStableFunc(waiting, func(a, b buildqueue.Item) int {
+		if c := cmp.Compare(buildqueue.Score(a), buildqueue.Score(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+	// a build that is being deleted is not held back
+	index := slices.IndexFunc(waiting, func(b buildqueue.Item) bool { return b.UID == item.UID })
+	if limit <= 0 || index < 0 || index < limit-running {
+		return buildqueue.Admission{Admitted: true}, nil
+	}
+	return buildqueue.Admission{Position: index + 1, Waiting: len(waiting)}, nil
+}
+
+// Release implements buildqueue.Queue; the ImageBuilds are the queue, so there is nothing to release
+func (q clusterQueue) Release(context.Context, string) error {
+	return nil
+}
+
+// List implements buildqueue.Queue
+func (q clusterQueue) List(context.Context) ([]string, error) {
+	return nil, nil
+}
+
+// queueBuild keeps the build Queued with message and its position among the builds waiting for a
+// build slot, 0 when it waits for something else. The status is only written when it changed.
+func (r *ImageBuildReconciler) queueBuild(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild, message string, position int32) error {
+	if imageBuild.Status.Phase == "Queued" && imageBuild.Status.Message == message && imageBuild.Status.QueuePosition == position {
+		return nil
+	}
+	fresh := &automotivev1alpha1.ImageBuild{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+		return err
+	}
+	patch := client.MergeFrom(fresh.DeepCopy())
+	previousPhase := fresh.Status.Phase
+	waitedForSlot := fresh.Status.QueuePosition > 0
+	fresh.Status.Phase = "Queued"
+	fresh.Status.Message = message
+	fresh.Status.QueuePosition = position
+	if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+		return err
+	}
+	imageBuild.Status = fresh.Status
+	if previousPhase != "Queued" || waitedForSlot != (position > 0) {
+		r.recordPhaseEvent(fresh, previousPhase)
+	}
+	if previousPhase != "Queued" {
+		r.notifyWebhooks(fresh, previousPhase)
+		r.postCommitStatus(fresh)
+	}
+	return nil
+}
+
+// StorageCapacityConditionType reports whether the storage class of the build has room for its
+// workspace next to the workspaces of other builds that are still to be provisioned from it
+const StorageCapacityConditionType = "StorageCapacityAvailable"
+
+// storageCapacityRecheckInterval is how often builds queued for storage capacity check again
+const storageCapacityRecheckInterval = 30 * time.Second
+
+// checkStorageCapacity compares the capacity CSI storage capacity tracking reports for the storage
+// class of the build with its workspace size plus what other builds still claim from the class,
+// and records the result as the StorageCapacityAvailable condition. A false condition keeps the
+// build Queued instead of letting it start and fail with a provisioning error mid-run. Storage
+// classes whose driver does not track capacity admit every build, with an Unknown condition.
+func (r *ImageBuildReconciler) checkStorageCapacity(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (metav1.Condition, error) {
+	cond := metav1.Condition{
+		Type:               StorageCapacityConditionType,
+		Status:             metav1.ConditionUnknown,
+		Reason:             "CapacityNotTracked",
+		ObservedGeneration: imageBuild.Generation,
+	}
+	class, err := r.workspaceStorageClass(ctx, imageBuild)
+	if err != nil {
+		return cond, err
+	}
+	sc, err := storage.Class(ctx, r, class)
+	if err != nil {
+		return cond, err
+	}
+	available, tracked, err := storage.Capacity(ctx, r, sc)
+	if err != nil {
+		return cond, err
+	}
+	size := r.workspaceSize(ctx)
+	switch {
+	case sc == nil:
+		cond.Message = "No default storage class; the capacity for the workspace is not known"
+	case !tracked:
+		cond.Message = fmt.Sprintf("The driver of storage class %s does not report its capacity", sc.Name)
+	default:
+		demand, err := r.pendingWorkspaceDemand(ctx, sc, imageBuild)
+		if err != nil {
+			return cond, err
+		}
+		needed := size.DeepCopy()
+		needed.Add(demand)
+		if needed.Cmp(available) > 0 {
+			cond.Status = metav1.ConditionFalse
+			cond.Reason = "InsufficientCapacity"
+			cond.Message = fmt.Sprintf("Waiting for storage class %s: %s available, the %s workspace needs %s with %s claimed by other builds",
+				sc.Name, available.String(), size.String(), needed.String(), demand.String())
+		} else {
+			cond.Status = metav1.ConditionTrue
+			cond.Reason = "CapacityAvailable"
+			cond.Message = fmt.Sprintf("Storage class %s has %s available for the %s workspace", sc.Name, available.String(), size.String())
+		}
+	}
+
+	fresh := &automotivev1alpha1.ImageBuild{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+		return cond, err
+	}
+	patch := client.MergeFrom(fresh.DeepCopy())
+	if meta.SetStatusCondition(&fresh.Status.Conditions, cond) {
+		if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+			return cond, err
+		}
+	}
+	imageBuild.Status = fresh.Status
+	return cond, nil
+}
+
+// pendingWorkspaceDemand sums the storage other builds still claim from sc: workspace PVCs that
+// are not bound yet, which capacity tracking does not account for until they are provisioned, and
+// builds already admitted whose workspace PVC does not exist yet
+func (r *ImageBuildReconciler) pendingWorkspaceDemand(ctx context.Context, sc *storagev1.StorageClass, imageBuild *automotivev1alpha1.ImageBuild) (resource.Quantity, error) {
+	var demand resource.Quantity
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := r.List(ctx, pvcs, client.MatchingLabels{"app.kubernetes.io/managed-by": "automotive-dev-operator"}); err != nil {
+		return demand, fmt.Errorf("listing workspace PVCs: %w", err)
+	}
+	for _, pvc := range pvcs.Items {
+		if pvc.Status.Phase != corev1.ClaimPending || pvc.DeletionTimestamp != nil ||
+			pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName != sc.Name {
+			continue
+		}
+		if request, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+			demand.Add(request)
+		}
+	}
+
+	builds := &automotivev1alpha1.ImageBuildList{}
+	if err := r.List(ctx, builds); err != nil {
+		return demand, fmt.Errorf("listing image builds: %w", err)
+	}
+	size := r.workspaceSize(ctx)
+	defaultClass := r.defaultWorkspaceStorageClass(ctx)
+	for _, build := range builds.Items {
+		if build.UID == imageBuild.UID || build.Status.PVCName != "" {
+			continue
+		}
+		if build.Status.Phase != "Uploading" && build.Status.Phase != "Building" {
+			continue
+		}
+		class := build.Spec.StorageClass
+		if class == "" {
+			class = defaultClass
+		}
+		if class == sc.Name || (class == "" && storage.IsDefault(sc)) {
+			demand.Add(size)
+		}
+	}
+	return demand, nil
+}
+
+// workspaceSharedAcrossNodes reports whether pods on different nodes can mount the workspace PVC
+// at the same time. Builds created before access modes were chosen have ReadWriteOnce workspaces.
+func workspaceSharedAcrossNodes(imageBuild *automotivev1alpha1.ImageBuild) bool {
+	return imageBuild.Status.WorkspaceAccessMode == string(corev1.ReadWriteMany)
+}
+
+const (
+	// WorkspaceScrubbedConditionType reports whether the workspace was scrubbed as spec.workspaceProtection requests
+	WorkspaceScrubbedConditionType = "WorkspaceScrubbed"
+
+	scrubScrubbing = "Scrubbing"
+	scrubScrubbed  = "Scrubbed"
+	scrubFailed    = "Failed"
+)
+
+// scrubRequested reports whether the workspace of a build is scrubbed once the build no longer needs it
+func scrubRequested(imageBuild *automotivev1alpha1.ImageBuild) bool {
+	return imageBuild.Spec.WorkspaceProtection != nil && imageBuild.Spec.WorkspaceProtection.Scrub
+}
+
+// scrubWorkspace overwrites every file of the workspace of a finished build with a TaskRun, then
+// deletes the PVC. status.workspaceScrub and the WorkspaceScrubbed condition attest to the outcome.
+// A failed scrub keeps the PVC, so it can be inspected and scrubbed by hand.
+func (r *ImageBuildReconciler) scrubWorkspace(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, error) {
+	st := imageBuild.Status.WorkspaceScrub
+	if !scrubRequested(imageBuild) || (st == nil && imageBuild.Status.PVCName == "") {
+		return ctrl.Result{}, nil
+	}
+	if st != nil && st.Phase != scrubScrubbing {
+		return ctrl.Result{}, nil
+	}
+
+	fresh := &automotivev1alpha1.ImageBuild{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+		return ctrl.Result{}, err
+	}
+	patch := client.MergeFrom(fresh.DeepCopy())
+
+	if st == nil {
+		taskRun, err := r.startScrub(ctx, imageBuild)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		now := metav1.Now()
+		fresh.Status.WorkspaceScrub = &automotivev1alpha1.WorkspaceScrubStatus{
+			Phase:       scrubScrubbing,
+			PVCName:     imageBuild.Status.PVCName,
+			TaskRunName: taskRun.Name,
+			Method:      tasks.ScrubMethod,
+			StartTime:   &now,
+		}
+		meta.SetStatusCondition(&fresh.Status.Conditions, metav1.Condition{
+			Type:               WorkspaceScrubbedConditionType,
+			Status:             metav1.ConditionUnknown,
+			Reason:             scrubScrubbing,
+			Message:            fmt.Sprintf("TaskRun %s is scrubbing PVC %s", taskRun.Name, imageBuild.Status.PVCName),
+			ObservedGeneration: fresh.Generation,
+		})
+		if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: 15 * time.Second}, nil
+	}
+
+	taskRun := &tektonv1.TaskRun{}
+	err := r.Get(ctx, types.NamespacedName{Name: st.TaskRunName, Namespace: imageBuild.Namespace}, taskRun)
+	if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	if err == nil && !isTaskRunCompleted(taskRun) {
+		return ctrl.Result{RequeueAfter: 15 * time.Second}, nil
+	}
+
+	scrub := fresh.Status.WorkspaceScrub.DeepCopy()
+	now := metav1.Now()
+	scrub.CompletionTime = &now
+	cond := metav1.Condition{Type: WorkspaceScrubbedConditionType, ObservedGeneration: fresh.Generation}
+	switch {
+	case errors.IsNotFound(err):
+		scrub.Phase = scrubFailed
+		scrub.Message = fmt.Sprintf("TaskRun %s was deleted before it finished", st.TaskRunName)
+	case isTaskRunSuccessful(taskRun):
+		for _, res := range taskRun.Status.Results {
+			value, _ := strconv.ParseInt(strings.TrimSpace(res.Value.StringVal), 10, 64)
+			switch res.Name {
+			case "files":
+				scrub.FilesScrubbed = value
+			case "bytes":
+				scrub.BytesScrubbed = value
+			}
+		}
+		pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: scrub.PVCName, Namespace: imageBuild.Namespace}}
+		if err := r.Delete(ctx, pvc); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{}, fmt.Errorf("failed to delete scrubbed workspace PVC: %w", err)
+		}
+		scrub.Phase = scrubScrubbed
+		scrub.Message = fmt.Sprintf("Overwrote %d files (%d bytes) and deleted PVC %s", scrub.FilesScrubbed, scrub.BytesScrubbed, scrub.PVCName)
+		fresh.Status.PVCName = ""
+	default:
+		scrub.Phase = scrubFailed
+		scrub.Message = "Scrub TaskRun failed"
+		if conditions := taskRun.Status.Conditions; len(conditions) > 0 && conditions[0].Message != "" {
+			scrub.Message = conditions[0].Message
+		}
+	}
+	fresh.Status.WorkspaceScrub = scrub
+	cond.Message = scrub.Message
+	if scrub.Phase == scrubScrubbed {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = scrubScrubbed
+	} else {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "ScrubFailed"
+	}
+	meta.SetStatusCondition(&fresh.Status.Conditions, cond)
+	if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+		return ctrl.Result{}, err
+	}
+	if scrub.Phase == scrubScrubbed {
+		r.recordEvent(fresh, corev1.EventTypeNormal, EventReasonWorkspaceScrubbed, "%s", scrub.Message)
+	} else {
+		r.recordEvent(fresh, corev1.EventTypeWarning, EventReasonScrubFailed,
+			"Scrubbing PVC %s failed, the PVC is kept: %s", scrub.PVCName, scrub.Message)
+	}
+	return ctrl.Result{}, nil
+}
+
+// startScrub creates the TaskRun scrubbing the workspace of imageBuild, or adopts the one a
+// previous reconcile created
+func (r *ImageBuildReconciler) startScrub(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (*tektonv1.TaskRun, error) {
+	name := imageBuild.Name
+	const suffix = "-scrub"
+	if limit := validation.DNS1123LabelMaxLength - len(suffix); len(name) > limit {
+		name = strings.TrimRight(name[:limit], "-.")
+	}
+	builder := imageBuild.Spec.AutomotiveImageBuilder
+	if builder == "" {
+		builder = tasks.AutomotiveImageBuilder
+	}
+	task := tasks.GenerateScrubWorkspaceTask(imageBuild.Namespace)
+	taskRun := &tektonv1.TaskRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + suffix,
+			Namespace: imageBuild.Namespace,
+			Labels: map[string]string{
+				tektonv1.ManagedByLabelKey:                        "automotive-dev-operator",
+				"automotive.sdv.cloud.redhat.com/imagebuild-name": imageBuild.Name,
+				workspaceScrubLabel:                               "true",
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(imageBuild, automotivev1alpha1.GroupVersion.WithKind("ImageBuild")),
+			},
+		},
+		Spec: tektonv1.TaskRunSpec{
+			TaskSpec: &task.Spec,
+			Params: []tektonv1.Param{
+				{Name: "automotive-image-builder", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: builder}},
+			},
+			Workspaces: []tektonv1.WorkspaceBinding{
+				{
+					Name: "shared-workspace",
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: imageBuild.Status.PVCName,
+					},
+				},
+			},
+		},
+	}
+	if err := r.Create(ctx, taskRun); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("failed to create scrub TaskRun: %w", err)
+		}
+		existing := &tektonv1.TaskRun{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(taskRun), existing); err != nil {
+			return nil, fmt.Errorf("failed to get existing scrub TaskRun: %w", err)
+		}
+		if !metav1.IsControlledBy(existing, imageBuild) {
+			return nil, fmt.Errorf("TaskRun %s belongs to another ImageBuild", taskRun.Name)
+		}
+		return existing, nil
+	}
+	return taskRun, nil
+}
+
+const (
+	signSigning = "Signing"
+	signSigned  = "Signed"
+	signFailed  = "Failed"
+)
+
+// Extensions of Fulcio certificates holding the OIDC issuer of the identity they were issued for:
+// a DER encoded UTF8String, and the raw string of older certificates
+var (
+	fulcioIssuerV2OID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+	fulcioIssuerOID   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+)
+
+// signing reports whether the artifact of a build still has to be signed as spec.signing requests
+func signing(imageBuild *automotivev1alpha1.ImageBuild) bool {
+	if imageBuild.Spec.Signing == nil {
+		return false
+	}
+	st := imageBuild.Status.Signature
+	if st == nil {
+		return imageBuild.Status.PVCName != ""
+	}
+	return st.Phase == signSigning
+}
+
+// signArtifact signs the artifact of a completed build with cosign in a TaskRun, which writes the
+// bundle next to the artifact in the workspace. status.signature records who signed it: the
+// fingerprint of the key, or the subject and issuer of the Fulcio certificate of a keyless
+// signature. A failed signature is not retried; the artifact stays unsigned.
+func (r *ImageBuildReconciler) signArtifact(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, error) {
+	if !signing(imageBuild) {
+		return ctrl.Result{}, nil
+	}
+	st := imageBuild.Status.Signature
+
+	fresh := &automotivev1alpha1.ImageBuild{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+		return ctrl.Result{}, err
+	}
+	patch := client.MergeFrom(fresh.DeepCopy())
+
+	if st == nil {
+		sig, err := r.startSigning(ctx, imageBuild)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		fresh.Status.Signature = sig
+		if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+			return ctrl.Result{}, err
+		}
+		imageBuild.Status = fresh.Status
+		if sig.Phase == signFailed {
+			r.recordEvent(fresh, corev1.EventTypeWarning, EventReasonSigningFailed, "%s", sig.Message)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	taskRun := &tektonv1.TaskRun{}
+	err := r.Get(ctx, types.NamespacedName{Name: st.TaskRunName, Namespace: imageBuild.Namespace}, taskRun)
+	if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	if err == nil && !isTaskRunCompleted(taskRun) {
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	sig := fresh.Status.Signature.DeepCopy()
+	now := metav1.Now()
+	sig.CompletionTime = &now
+	switch {
+	case errors.IsNotFound(err):
+		sig.Phase = signFailed
+		sig.Message = fmt.Sprintf("TaskRun %s was deleted before it finished", st.TaskRunName)
+	case isTaskRunSuccessful(taskRun):
+		sig.Phase = signSigned
+		for _, res := range taskRun.Status.Results {
+			switch res.Name {
+			case "identity":
+				sig.Identity = strings.TrimSpace(res.Value.StringVal)
+			case "certificate":
+				if cert := strings.TrimSpace(res.Value.StringVal); cert != "" {
+					sig.Identity, sig.Issuer, err = certificateIdentity(cert)
+					if err != nil {
+						sig.Phase = signFailed
+						sig.Message = fmt.Sprintf("Invalid signing certificate: %v", err)
+					}
+				}
+			}
+		}
+		if sig.Phase == signSigned {
+			sig.Message = fmt.Sprintf("Signed by %s", sig.Identity)
+			if sig.Issuer != "" {
+				sig.Message += fmt.Sprintf(" (issuer %s)", sig.Issuer)
+			}
+		}
+	default:
+		sig.Phase = signFailed
+		sig.Message = "Signing TaskRun failed"
+		if conditions := taskRun.Status.Conditions; len(conditions) > 0 && conditions[0].Message != "" {
+			sig.Message = conditions[0].Message
+		}
+	}
+	fresh.Status.Signature = sig
+	if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+		return ctrl.Result{}, err
+	}
+	imageBuild.Status = fresh.Status
+	if sig.Phase == signSigned {
+		r.recordEvent(fresh, corev1.EventTypeNormal, EventReasonArtifactSigned, "%s", sig.Message)
+	} else {
+		r.recordEvent(fresh, corev1.EventTypeWarning, EventReasonSigningFailed, "Signing the artifact failed: %s", sig.Message)
+	}
+	return ctrl.Result{}, nil
+}
+
+// signingRekorURL is the transparency log the signature of spec is recorded in: for keyless
+// signatures the Rekor of spec, else that of the OperatorConfig, else the public one; for key-based
+// signatures only the Rekor of the OperatorConfig, and none without it
+func (r *ImageBuildReconciler) signingRekorURL(ctx context.Context, spec *automotivev1alpha1.ArtifactSigning) (string, error) {
+	if spec.Keyless && spec.RekorURL != "" {
+		return spec.RekorURL, nil
+	}
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	if err := r.Get(ctx, types.NamespacedName{Name: "config", Namespace: OperatorNamespace}, operatorConfig); client.IgnoreNotFound(err) != nil {
+		return "", fmt.Errorf("failed to get OperatorConfig configuration: %w", err)
+	}
+	if cfg := operatorConfig.Spec.OSBuilds; cfg != nil && cfg.Signing != nil && cfg.Signing.RekorURL != "" {
+		return cfg.Signing.RekorURL, nil
+	}
+	if spec.Keyless {
+		return tasks.PublicRekorURL, nil
+	}
+	return "", nil
+}
+
+// startSigning creates the TaskRun signing the artifact of imageBuild, or adopts the one a previous
+// reconcile created, and returns the status recording it. A missing key Secret fails signing
+// right away, since the TaskRun pod could not start without it.
+func (r *ImageBuildReconciler) startSigning(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (*automotivev1alpha1.ArtifactSignatureStatus, error) {
+	spec := imageBuild.Spec.Signing
+	now := metav1.Now()
+	sig := &automotivev1alpha1.ArtifactSignatureStatus{Phase: signSigning, Method: tasks.SigningMethodKey, StartTime: &now}
+	if spec.KeySecretRef == "" {
+		sig.Method = tasks.SigningMethodKeyless
+	}
+	fail := func(format string, args ...any) (*automotivev1alpha1.ArtifactSignatureStatus, error) {
+		sig.Phase = signFailed
+		sig.Message = fmt.Sprintf(format, args...)
+		sig.CompletionTime = &now
+		return sig, nil
+	}
+	switch {
+	case spec.KeySecretRef != "" && spec.Keyless:
+		return fail("spec.signing sets both keySecretRef and keyless")
+	case spec.KeySecretRef == "" && !spec.Keyless:
+		return fail("spec.signing needs keySecretRef or keyless")
+	}
+	if spec.KeySecretRef != "" {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: spec.KeySecretRef, Namespace: imageBuild.Namespace}, secret); err != nil {
+			if errors.IsNotFound(err) {
+				return fail("Secret %s with the signing key not found", spec.KeySecretRef)
+			}
+			return nil, err
+		}
+		if len(secret.Data["cosign.key"]) == 0 {
+			return fail("Secret %s has no cosign.key", spec.KeySecretRef)
+		}
+	}
+
+	fileName := imageBuild.Status.ArtifactFileName
+	if fileName == "" {
+		fileName = defaultArtifactFileName(imageBuild)
+	}
+	sig.BundleFileName = fileName + ".sigstore.json"
+
+	name := imageBuild.Name
+	const suffix = "-sign"
+	if limit := validation.DNS1123LabelMaxLength - len(suffix); len(name) > limit {
+		name = strings.TrimRight(name[:limit], "-.")
+	}
+	task := tasks.GenerateSignArtifactTask(imageBuild.Namespace, spec.KeySecretRef)
+	params := []tektonv1.Param{
+		{Name: "artifact-filename", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: fileName}},
+	}
+	if spec.FulcioURL != "" {
+		params = append(params, tektonv1.Param{Name: "fulcio-url", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: spec.FulcioURL}})
+	}
+	rekorURL, err := r.signingRekorURL(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	params = append(params, tektonv1.Param{Name: "rekor-url", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: rekorURL}})
+	taskRun := &tektonv1.TaskRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + suffix,
+			Namespace: imageBuild.Namespace,
+			Labels: map[string]string{
+				tektonv1.ManagedByLabelKey:                        "automotive-dev-operator",
+				"automotive.sdv.cloud.redhat.com/imagebuild-name": imageBuild.Name,
+				artifactSignLabel:                                 "true",
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(imageBuild, automotivev1alpha1.GroupVersion.WithKind("ImageBuild")),
+			},
+		},
+		Spec: tektonv1.TaskRunSpec{
+			TaskSpec:           &task.Spec,
+			Params:             params,
+			ServiceAccountName: spec.ServiceAccountName,
+			Workspaces: []tektonv1.WorkspaceBinding{
+				{
+					Name: "shared-workspace",
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: imageBuild.Status.PVCName,
+					},
+				},
+			},
+			PodTemplate: artifactPodAffinity(imageBuild),
+		},
+	}
+	sig.TaskRunName = taskRun.Name
+	if err := r.Create(ctx, taskRun); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("failed to create signing TaskRun: %w", err)
+		}
+		existing := &tektonv1.TaskRun{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(taskRun), existing); err != nil {
+			return nil, fmt.Errorf("failed to get existing signing TaskRun: %w", err)
+		}
+		if !metav1.IsControlledBy(existing, imageBuild) {
+			return nil, fmt.Errorf("TaskRun %s belongs to another ImageBuild", taskRun.Name)
+		}
+	}
+	sig.Message = fmt.Sprintf("TaskRun %s is signing %s", taskRun.Name, fileName)
+	return sig, nil
+}
+
+// certificateIdentity returns the subject a Fulcio certificate was issued for, its URI or email
+// SAN, and the OIDC issuer that vouched for it, as cosign verify-blob matches them with
+// --certificate-identity and --certificate-oidc-issuer
+func certificateIdentity(certPEM string) (string, string, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return "", "", fmt.Errorf("no PEM certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", "", err
+	}
+	var identity, issuer string
+	switch {
+	case len(cert.URIs) > 0:
+		identity = cert.URIs[0].String()
+	case len(cert.EmailAddresses) > 0:
+		identity = cert.EmailAddresses[0]
+	default:
+		return "", "", fmt.Errorf("certificate has no URI or email subject")
+	}
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(fulcioIssuerV2OID):
+			if _, err := asn1.UnmarshalWithParams(ext.Value, &issuer, "utf8"); err != nil {
+				return "", "", fmt.Errorf("invalid issuer extension: %w", err)
+			}
+		case ext.Id.Equal(fulcioIssuerOID) && issuer == "":
+			issuer = string(ext.Value)
+		}
+	}
+	return identity, issuer, nil
+}
+
+const (
+	// buildRecordsPrefix names the ConfigMaps of the build record history of a namespace: the head
+	// holding the last record, and the segments build-records-1, build-records-2 and so on
+	buildRecordsPrefix = "build-records"
+	buildRecordHeadKey = "head.json"
+
+	// buildRecordKeyPEM is the key of the ECDSA private key in the Secret of spec.osBuilds.buildRecords.anchor
+	buildRecordKeyPEM = "key.pem"
+
+	buildRecordAnchorRetry  = 5 * time.Minute
+	buildRecordAnchorGiveUp = 24 * time.Hour
+)
+
+// buildRecordsConfig is spec.osBuilds.buildRecords of the OperatorConfig, nil when builds are not recorded
+func (r *ImageBuildReconciler) buildRecordsConfig(ctx context.Context) *automotivev1alpha1.BuildRecordsConfig {
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	err := r.Get(ctx, types.NamespacedName{Name: "config", Namespace: OperatorNamespace}, operatorConfig)
+	if err != nil || operatorConfig.Spec.OSBuilds == nil {
+		return nil
+	}
+	return operatorConfig.Spec.OSBuilds.BuildRecords
+}
+
+// recordBuild appends the record of a finished build to the history of its namespace once its
+// publications ended, then anchors the digest of the record in Rekor when the OperatorConfig asks
+// for it. Failed anchoring is retried for a day; the record is kept either way.
+func (r *ImageBuildReconciler) recordBuild(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, error) {
+	cfg := r.buildRecordsConfig(ctx)
+	if cfg == nil {
+		return ctrl.Result{}, nil
+	}
+	if imageBuild.Status.BuildRecord == nil {
+		if imageBuild.Status.Phase == "Completed" && publicationsPending(imageBuild) {
+			return ctrl.Result{}, nil
+		}
+		record, err := r.appendBuildRecord(ctx, imageBuild)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		fresh := &automotivev1alpha1.ImageBuild{}
+		if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+			return ctrl.Result{}, err
+		}
+		patch := client.MergeFrom(fresh.DeepCopy())
+		fresh.Status.BuildRecord = &automotivev1alpha1.BuildRecordStatus{
+			Sequence: record.Sequence,
+			Digest:   record.Digest,
+			Segment:  buildrecord.SegmentName(buildRecordsPrefix, record.Sequence),
+		}
+		if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+			return ctrl.Result{}, err
+		}
+		imageBuild.Status = fresh.Status
+		r.recordEvent(imageBuild, corev1.EventTypeNormal, EventReasonBuildRecorded,
+			"Recorded as build record %d, %s", record.Sequence, record.Digest)
+	}
+	return r.anchorBuildRecord(ctx, imageBuild, cfg.Anchor)
+}
+
+// appendBuildRecord seals the record of imageBuild after the head of the history and advances the
+// head, which is updated by resourceVersion so concurrent builds line up. The head is written
+// before the segment; a record the head holds but its segment lacks is stored by the next append,
+// and a build whose record made it into the history before its status did gets that record back.
+func (r *ImageBuildReconciler) appendBuildRecord(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (*buildrecord.Record, error) {
+	head := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: buildRecordsPrefix, Namespace: imageBuild.Namespace}, head)
+	if errors.IsNotFound(err) {
+		// never start a new history over the segments of one whose head went missing
+		first := &corev1.ConfigMap{}
+		err = r.Get(ctx, types.NamespacedName{Name: buildrecord.SegmentName(buildRecordsPrefix, 1), Namespace: imageBuild.Namespace}, first)
+		if err == nil {
+			return nil, fmt.Errorf("build record head ConfigMap %s is missing but the history in %s exists; restore it to continue the history",
+				buildRecordsPrefix, first.Name)
+		}
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+		head = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:      buildRecordsPrefix,
+			Namespace: imageBuild.Namespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "automotive-dev-operator"},
+		}}
+		err = r.Create(ctx, head)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var previous *buildrecord.Record
+	if data := head.Data[buildRecordHeadKey]; data != "" {
+		previous = &buildrecord.Record{}
+		if err := json.Unmarshal([]byte(data), previous); err != nil {
+			return nil, fmt.Errorf("reading build record head %s: %w", head.Name, err)
+		}
+		if err := r.storeBuildRecordEntry(ctx, imageBuild.Namespace, previous.Sequence, buildrecord.SegmentKey(previous.Sequence), data); err != nil {
+			return nil, err
+		}
+		if existing, err := r.findBuildRecord(ctx, imageBuild.Namespace, previous, string(imageBuild.UID)); err != nil || existing != nil {
+			return existing, err
+		}
+	}
+
+	record := newBuildRecord(imageBuild)
+	record.Seal(previous)
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	if head.Data == nil {
+		head.Data = map[string]string{}
+	}
+	head.Data[buildRecordHeadKey] = string(data)
+	if err := r.Update(ctx, head); err != nil {
+		return nil, err
+	}
+	if err := r.storeBuildRecordEntry(ctx, imageBuild.Namespace, record.Sequence, buildrecord.SegmentKey(record.Sequence), string(data)); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// newBuildRecord returns the unsealed record of a finished build
+func newBuildRecord(imageBuild *automotivev1alpha1.ImageBuild) buildrecord.Record {
+	record := buildrecord.Record{
+		Time:           time.Now().UTC().Format(time.RFC3339),
+		Namespace:      imageBuild.Namespace,
+		Build:          imageBuild.Name,
+		UID:            string(imageBuild.UID),
+		Phase:          imageBuild.Status.Phase,
+		RequestedBy:    imageBuild.Annotations["automotive.sdv.cloud.redhat.com/requested-by"],
+		Distro:         imageBuild.Spec.Distro,
+		Target:         imageBuild.Spec.Target,
+		Architecture:   imageBuild.Spec.Architecture,
+		Artifact:       imageBuild.Status.ArtifactFileName,
+		ArtifactSHA256: imageBuild.Status.ArtifactSHA256,
+	}
+	if env := imageBuild.Status.Environment; env != nil {
+		record.BuilderImage = env.BuilderImage
+		if env.BuilderImageDigest != "" {
+			record.BuilderImage = env.BuilderImageDigest
+		}
+	}
+	if sig := imageBuild.Status.Signature; sig != nil && sig.Phase == "Signed" {
+		record.Signer = sig.Identity
+	}
+	for _, p := range imageBuild.Status.Publications {
+		record.Publications = append(record.Publications, buildrecord.Publication{Target: p.Name, Phase: p.Phase, Location: p.Location})
+	}
+	return record
+}
+
+// findBuildRecord looks for the record of the build with uid in the segments holding the head and
+// the records just before it
+func (r *ImageBuildReconciler) findBuildRecord(ctx context.Context, namespace string, head *buildrecord.Record, uid string) (*buildrecord.Record, error) {
+	if head.UID == uid {
+		return head, nil
+	}
+	names := []string{buildrecord.SegmentName(buildRecordsPrefix, head.Sequence)}
+	if head.Sequence > buildrecord.SegmentSize {
+		if before := buildrecord.SegmentName(buildRecordsPrefix, head.Sequence-buildrecord.SegmentSize); before != names[0] {
+			names = append(names, before)
+		}
+	}
+	for _, name := range names {
+		segment := &corev1.ConfigMap{}
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, segment); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		for key, data := range segment.Data {
+			if !strings.HasSuffix(key, ".json") || strings.HasSuffix(key, ".rekor.json") || !strings.Contains(data, uid) {
+				continue
+			}
+			record := &buildrecord.Record{}
+			if err := json.Unmarshal([]byte(data), record); err == nil && record.UID == uid {
+				return record, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// storeBuildRecordEntry writes key of the segment holding the record with sequence. Entries are
+// written once; an entry that differs from what is written is an error, never overwritten.
+func (r *ImageBuildReconciler) storeBuildRecordEntry(ctx context.Context, namespace string, sequence int64, key, data string) error {
+	segment := &corev1.ConfigMap{}
+	name := buildrecord.SegmentName(buildRecordsPrefix, sequence)
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, segment)
+	if errors.IsNotFound(err) {
+		segment = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "automotive-dev-operator"},
+			},
+			Data: map[string]string{key: data},
+		}
+		return r.Create(ctx, segment)
+	}
+	if err != nil {
+		return err
+	}
+	if existing, ok := segment.Data[key]; ok {
+		if existing != data {
+			return fmt.Errorf("build record segment %s holds a different %s", name, key)
+		}
+		return nil
+	}
+	if segment.Data == nil {
+		segment.Data = map[string]string{}
+	}
+	segment.Data[key] = data
+	return r.Update(ctx, segment)
+}
+
+// anchorBuildRecord logs the digest of the record of a build in the Rekor log of anchor and keeps
+// the entry next to the record in its segment
+func (r *ImageBuildReconciler) anchorBuildRecord(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild, anchor *automotivev1alpha1.BuildRecordAnchor) (ctrl.Result, error) {
+	st := imageBuild.Status.BuildRecord
+	if anchor == nil || st == nil || st.RekorLogIndex != nil {
+		return ctrl.Result{}, nil
+	}
+	segment := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{Name: st.Segment, Namespace: imageBuild.Namespace}, segment); err != nil {
+		return ctrl.Result{}, err
+	}
+	record := buildrecord.Record{}
+	if err := json.Unmarshal([]byte(segment.Data[buildrecord.SegmentKey(st.Sequence)]), &record); err != nil {
+		return ctrl.Result{}, fmt.Errorf("reading build record %d: %w", st.Sequence, err)
+	}
+	if recorded, err := time.Parse(time.RFC3339, record.Time); err == nil && time.Since(recorded) > buildRecordAnchorGiveUp {
+		return ctrl.Result{}, nil
+	}
+
+	entry, anchorErr := r.anchorRecord(ctx, anchor, record)
+	if anchorErr == nil {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.storeBuildRecordEntry(ctx, imageBuild.Namespace, st.Sequence, buildrecord.AnchorKey(st.Sequence), string(data)); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	fresh := &automotivev1alpha1.ImageBuild{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+		return ctrl.Result{}, err
+	}
+	if fresh.Status.BuildRecord == nil {
+		return ctrl.Result{}, nil
+	}
+	patch := client.MergeFrom(fresh.DeepCopy())
+	previousMessage := fresh.Status.BuildRecord.AnchorMessage
+	if anchorErr != nil {
+		fresh.Status.BuildRecord.AnchorMessage = anchorErr.Error()
+	} else {
+		fresh.Status.BuildRecord.RekorLogIndex = ptr.To(entry.LogIndex)
+		fresh.Status.BuildRecord.RekorUUID = entry.UUID
+		fresh.Status.BuildRecord.AnchorMessage = ""
+	}
+	if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+		return ctrl.Result{}, err
+	}
+	imageBuild.Status = fresh.Status
+	if anchorErr != nil {
+		if anchorErr.Error() != previousMessage {
+			r.recordEvent(imageBuild, corev1.EventTypeWarning, EventReasonBuildRecordAnchorFailed,
+				"Anchoring build record %d in Rekor failed, retrying: %v", st.Sequence, anchorErr)
+		}
+		return ctrl.Result{RequeueAfter: buildRecordAnchorRetry}, nil
+	}
+	r.recordEvent(imageBuild, corev1.EventTypeNormal, EventReasonBuildRecordAnchored,
+		"Build record %d anchored in %s at log index %d", st.Sequence, entry.RekorURL, entry.LogIndex)
+	return ctrl.Result{}, nil
+}
+
+// anchorRecord signs the digest of record with the key of anchor and logs it in its Rekor instance
+func (r *ImageBuildReconciler) anchorRecord(ctx context.Context, anchor *automotivev1alpha1.BuildRecordAnchor, record buildrecord.Record) (*buildrecord.Anchor, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: anchor.KeySecretRef, Namespace: OperatorNamespace}, secret); err != nil {
+		return nil, fmt.Errorf("reading anchor key Secret %s: %w", anchor.KeySecretRef, err)
+	}
+	key, err := buildrecord.ParsePrivateKey(secret.Data[buildRecordKeyPEM])
+	if err != nil {
+		return nil, fmt.Errorf("anchor key Secret %s: %s: %w", anchor.KeySecretRef, buildRecordKeyPEM, err)
+	}
+	return (&buildrecord.Anchorer{URL: anchor.RekorURL, Key: key}).Anchor(ctx, record)
+}
+
+const (
+	archiveArchiving = "Archiving"
+	archiveArchived  = "Archived"
+	archiveFailed    = "Failed"
+
+	// rehydrateRequestedAnnotation is set by the build API to the time a download asked for the
+	// artifacts of an archived build
+	rehydrateRequestedAnnotation = "automotive.sdv.cloud.redhat.com/rehydrate-requested"
+
+	defaultArchiveAfterDays  = 7
+	defaultRehydratedMinutes = 60
+)
+
+// archiving reports whether a TaskRun is copying the artifacts of a build to its archive PVC
+func archiving(imageBuild *automotivev1alpha1.ImageBuild) bool {
+	st := imageBuild.Status.ArtifactArchive
+	return st != nil && st.Phase == archiveArchiving
+}
+
+// artifactArchiveConfig is spec.osBuilds.artifactArchive of the OperatorConfig, nil when artifacts
+// are not archived
+func (r *ImageBuildReconciler) artifactArchiveConfig(ctx context.Context) *automotivev1alpha1.ArtifactArchiveConfig {
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	err := r.Get(ctx, types.NamespacedName{Name: "config", Namespace: OperatorNamespace}, operatorConfig)
+	if err != nil || operatorConfig.Spec.OSBuilds == nil {
+		return nil
+	}
+	return operatorConfig.Spec.OSBuilds.ArtifactArchive
+}
+
+// archiveDue is when the artifacts of a build completed at completion are archived
+func archiveDue(cfg *automotivev1alpha1.ArtifactArchiveConfig, completion time.Time) time.Time {
+	days := int32(defaultArchiveAfterDays)
+	if cfg.AfterDays != nil {
+		days = *cfg.AfterDays
+	}
+	return completion.Add(time.Duration(days) * 24 * time.Hour)
+}
+
+// archiveArtifacts moves the artifacts of a completed build to a PVC of the archive storage class
+// once spec.osBuilds.artifactArchive.afterDays of the OperatorConfig passed. A TaskRun copies and
+// verifies the files, then the archive PVC replaces the workspace PVC in status.pvcName and the
+// workspace PVC is deleted. The artifact pod is stopped for the copy; downloads start it again on
+// the archive PVC (rehydrateArtifacts). Workspaces that are scrubbed or whose retention expired are
+// not archived, and a failed copy keeps the workspace.
+func (r *ImageBuildReconciler) archiveArtifacts(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, error) {
+	st := imageBuild.Status.ArtifactArchive
+	switch {
+	case st != nil && st.Phase == archiveArchived:
+		return r.rehydrateArtifacts(ctx, imageBuild)
+	case st != nil && st.Phase == archiveFailed:
+		return ctrl.Result{}, nil
+	case st == nil && (scrubRequested(imageBuild) || imageBuild.Status.PVCName == "" || imageBuild.Status.CompletionTime == nil ||
+		publicationsPending(imageBuild) || (imageBuild.Status.Retention != nil && imageBuild.Status.Retention.Reason != "")):
+		return ctrl.Result{}, nil
+	}
+
+	if st == nil {
+		cfg := r.artifactArchiveConfig(ctx)
+		if cfg == nil {
+			return ctrl.Result{}, nil
+		}
+		if until := time.Until(archiveDue(cfg, imageBuild.Status.CompletionTime.Time)); until > 0 {
+			return ctrl.Result{RequeueAfter: until}, nil
+		}
+		// a ReadWriteOnce workspace can only be mounted on the node of the artifact pod
+		if err := r.deleteArtifactPod(ctx, imageBuild); err != nil {
+			return ctrl.Result{}, err
+		}
+		pvcName, err := r.getOrCreateArchivePVC(ctx, imageBuild, cfg.StorageClass)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		taskRun, err := r.startArchive(ctx, imageBuild, pvcName)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		fresh := &automotivev1alpha1.ImageBuild{}
+		if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+			return ctrl.Result{}, err
+		}
+		patch := client.MergeFrom(fresh.DeepCopy())
+		now := metav1.Now()
+		fresh.Status.ArtifactArchive = &automotivev1alpha1.ArtifactArchiveStatus{
+			Phase:        archiveArchiving,
+			StorageClass: cfg.StorageClass,
+			PVCName:      pvcName,
+			TaskRunName:  taskRun.Name,
+			StartTime:    &now,
+			Message:      fmt.Sprintf("TaskRun %s is copying PVC %s to PVC %s", taskRun.Name, imageBuild.Status.PVCName, pvcName),
+		}
+		if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+			return ctrl.Result{}, err
+		}
+		imageBuild.Status = fresh.Status
+		return ctrl.Result{RequeueAfter: 15 * time.Second}, nil
+	}
+
+	taskRun := &tektonv1.TaskRun{}
+	err := r.Get(ctx, types.NamespacedName{Name: st.TaskRunName, Namespace: imageBuild.Namespace}, taskRun)
+	if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	if err == nil && !isTaskRunCompleted(taskRun) {
+		return ctrl.Result{RequeueAfter: 15 * time.Second}, nil
+	}
+
+	fresh := &automotivev1alpha1.ImageBuild{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+		return ctrl.Result{}, err
+	}
+	patch := client.MergeFrom(fresh.DeepCopy())
+	archive := fresh.Status.ArtifactArchive.DeepCopy()
+	now := metav1.Now()
+	archive.CompletionTime = &now
+	switch {
+	case errors.IsNotFound(err):
+		archive.Phase = archiveFailed
+		archive.Message = fmt.Sprintf("TaskRun %s was deleted before it finished", st.TaskRunName)
+	case isTaskRunSuccessful(taskRun):
+		for _, res := range taskRun.Status.Results {
+			if res.Name == "bytes" {
+				archive.Bytes, _ = strconv.ParseInt(strings.TrimSpace(res.Value.StringVal), 10, 64)
+			}
+		}
+		workspace := fresh.Status.PVCName
+		pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: workspace, Namespace: fresh.Namespace}}
+		if err := r.Delete(ctx, pvc); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{}, fmt.Errorf("failed to delete archived workspace PVC: %w", err)
+		}
+		archive.Phase = archiveArchived
+		archive.Message = fmt.Sprintf("Moved %d bytes from PVC %s to PVC %s of storage class %s",
+			archive.Bytes, workspace, archive.PVCName, archive.StorageClass)
+		fresh.Status.PVCName = archive.PVCName
+	default:
+		archive.Phase = archiveFailed
+		archive.Message = "Archive TaskRun failed"
+		if conditions := taskRun.Status.Conditions; len(conditions) > 0 && conditions[0].Message != "" {
+			archive.Message = conditions[0].Message
+		}
+	}
+	if archive.Phase == archiveFailed {
+		pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: archive.PVCName, Namespace: fresh.Namespace}}
+		if err := r.Delete(ctx, pvc); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{}, fmt.Errorf("failed to delete archive PVC: %w", err)
+		}
+	}
+	fresh.Status.ArtifactArchive = archive
+	if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+		return ctrl.Result{}, err
+	}
+	imageBuild.Status = fresh.Status
+	if archive.Phase == archiveArchived {
+		r.recordEvent(fresh, corev1.EventTypeNormal, EventReasonArtifactsArchived, "%s", archive.Message)
+		return ctrl.Result{}, nil
+	}
+	r.recordEvent(fresh, corev1.EventTypeWarning, EventReasonArchiveFailed,
+		"Archiving the artifacts failed, they stay on PVC %s: %s", fresh.Status.PVCName, archive.Message)
+	if fresh.Spec.ServeArtifact && fresh.Status.ArtifactFileName != "" {
+		// serve the artifacts from the workspace again
+		if err := r.createArtifactPod(ctx, fresh); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// rehydrateArtifacts starts the artifact pod of an archived build on its archive PVC when a download
+// asked for the artifacts through the rehydrate-requested annotation, and stops it again once no
+// download asked for spec.osBuilds.artifactArchive.rehydratedMinutes
+func (r *ImageBuildReconciler) rehydrateArtifacts(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (ctrl.Result, error) {
+	if !imageBuild.Spec.ServeArtifact || imageBuild.Status.ArtifactFileName == "" {
+		// the artifacts are no longer served
+		return ctrl.Result{}, nil
+	}
+	st := imageBuild.Status.ArtifactArchive
+	var requested, rehydrated time.Time
+	if v, ok := imageBuild.Annotations[rehydrateRequestedAnnotation]; ok {
+		requested, _ = time.Parse(time.RFC3339, v)
+	}
+	if st.RehydrationTime != nil {
+		rehydrated = st.RehydrationTime.Time
+	}
+
+	if requested.After(rehydrated) {
+		if err := r.createArtifactPod(ctx, imageBuild); err != nil {
+			return ctrl.Result{}, err
+		}
+		fresh := &automotivev1alpha1.ImageBuild{}
+		if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+			return ctrl.Result{}, err
+		}
+		patch := client.MergeFrom(fresh.DeepCopy())
+		at := metav1.NewTime(requested)
+		fresh.Status.ArtifactArchive.RehydrationTime = &at
+		if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+			return ctrl.Result{}, err
+		}
+		imageBuild.Status = fresh.Status
+		if rehydrated.IsZero() || time.Since(rehydrated) > time.Minute {
+			r.recordEvent(fresh, corev1.EventTypeNormal, EventReasonArtifactsRehydrated,
+				"Started the artifact pod on archive PVC %s for a download", st.PVCName)
+		}
+		rehydrated = requested
+	}
+	if rehydrated.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	minutes := int32(defaultRehydratedMinutes)
+	if cfg := r.artifactArchiveConfig(ctx); cfg != nil && cfg.RehydratedMinutes > 0 {
+		minutes = cfg.RehydratedMinutes
+	}
+	if until := time.Until(rehydrated.Add(time.Duration(minutes) * time.Minute)); until > 0 {
+		return ctrl.Result{RequeueAfter: until}, nil
+	}
+	return ctrl.Result{}, r.deleteArtifactPod(ctx, imageBuild)
+}
+
+// deleteArtifactPod stops the artifact pod of a build, keeping the Service, Route and ConfigMap that
+// serve through it
+func (r *ImageBuildReconciler) deleteArtifactPod(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) error {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-artifact-pod", imageBuild.Name), Namespace: imageBuild.Namespace}}
+	if err := r.Delete(ctx, pod); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete artifact pod: %w", err)
+	}
+	return nil
+}
+
+// getOrCreateArchivePVC creates the PVC of storageClass the artifacts of imageBuild are archived to,
+// as large as its workspace PVC
+func (r *ImageBuildReconciler) getOrCreateArchivePVC(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild, storageClass string) (string, error) {
+	workspace := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Status.PVCName, Namespace: imageBuild.Namespace}, workspace); err != nil {
+		return "", fmt.Errorf("failed to get workspace PVC: %w", err)
+	}
+	size := r.workspaceSize(ctx)
+	if request, ok := workspace.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+		size = request
+	}
+
+	name := imageBuild.Name
+	const suffix = "-archive"
+	if limit := validation.DNS1123LabelMaxLength - len(suffix); len(name) > limit {
+		name = strings.TrimRight(name[:limit], "-.")
+	}
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + suffix,
+			Namespace: imageBuild.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by":                    "automotive-dev-operator",
+				"automotive.sdv.cloud.redhat.com/imagebuild-name": imageBuild.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(imageBuild, automotivev1alpha1.GroupVersion.WithKind("ImageBuild")),
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: &storageClass,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+			},
+		},
+	}
+	if err := r.Create(ctx, pvc); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return "", fmt.Errorf("failed to create archive PVC: %w", err)
+		}
+		existing := &corev1.PersistentVolumeClaim{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(pvc), existing); err != nil {
+			return "", fmt.Errorf("failed to get existing archive PVC: %w", err)
+		}
+		if !metav1.IsControlledBy(existing, imageBuild) {
+			return "", fmt.Errorf("PVC %s belongs to another ImageBuild", pvc.Name)
+		}
+	}
+	return pvc.Name, nil
+}
+
+// startArchive creates the TaskRun copying the workspace of imageBuild to the archive PVC pvcName,
+// or adopts the one a previous reconcile created
+func (r *ImageBuildReconciler) startArchive(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild, pvcName string) (*tektonv1.TaskRun, error) {
+	name := imageBuild.Name
+	const suffix = "-archive"
+	if limit := validation.DNS1123LabelMaxLength - len(suffix); len(name) > limit {
+		name = strings.TrimRight(name[:limit], "-.")
+	}
+	builder := imageBuild.Spec.AutomotiveImageBuilder
+	if builder == "" {
+		builder = tasks.AutomotiveImageBuilder
+	}
+	task := tasks.GenerateArchiveArtifactsTask(imageBuild.Namespace)
+	taskRun := &tektonv1.TaskRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + suffix,
+			Namespace: imageBuild.Namespace,
+			Labels: map[string]string{
+				tektonv1.ManagedByLabelKey:                        "automotive-dev-operator",
+				"automotive.sdv.cloud.redhat.com/imagebuild-name": imageBuild.Name,
+				artifactArchiveLabel:                              "true",
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(imageBuild, automotivev1alpha1.GroupVersion.WithKind("ImageBuild")),
+			},
+		},
+		Spec: tektonv1.TaskRunSpec{
+			TaskSpec: &task.Spec,
+			Params: []tektonv1.Param{
+				{Name: "automotive-image-builder", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: builder}},
+			},
+			Workspaces: []tektonv1.WorkspaceBinding{
+				{
+					Name: "shared-workspace",
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: imageBuild.Status.PVCName,
+					},
+				},
+				{
+					Name: "archive",
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: pvcName,
+					},
+				},
+			},
+		},
+	}
+	if err := r.Create(ctx, taskRun); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("failed to create archive TaskRun: %w", err)
+		}
+		existing := &tektonv1.TaskRun{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(taskRun), existing); err != nil {
+			return nil, fmt.Errorf("failed to get existing archive TaskRun: %w", err)
+		}
+		if !metav1.IsControlledBy(existing, imageBuild) {
+			return nil, fmt.Errorf("TaskRun %s belongs to another ImageBuild", taskRun.Name)
+		}
+		return existing, nil
+	}
+	return taskRun, nil
+}
+
+// invalidSpecError marks a spec field the build can never succeed with
+type invalidSpecError struct {
+	field string
+	err   error
+}
+
+func (e *invalidSpecError) Error() string {
+	return "invalid " + e.field + ": " + e.err.Error()
+}
+
+// prepareFirstBoot validates spec.firstBoot and copies inline or ConfigMap payloads into the
+// manifest ConfigMap, where the build step reads them. Uploaded files are checked by the build step.
+func (r *ImageBuildReconciler) prepareFirstBoot(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) error {
+	fb := imageBuild.Spec.FirstBoot
+	if fb == nil {
+		return nil
+	}
+
+	sources := 0
+	for _, set := range []bool{fb.Inline != "", fb.ConfigMapRef != nil, fb.FileName != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return &invalidSpecError{"firstBoot", fmt.Errorf("exactly one of inline, configMapRef or fileName must be set")}
+	}
+	if fb.FileName != "" {
+		if strings.Contains(fb.FileName, "/") || strings.Contains(fb.FileName, "..") {
+			return &invalidSpecError{"firstBoot", fmt.Errorf("fileName must be a plain file name")}
+		}
+		if err := firstboot.ValidateSpec(imageBuild.Spec.Distro, fb.Format, fb.Mode); err != nil {
+			return &invalidSpecError{"firstBoot", err}
+		}
+		return nil
+	}
+
+	payload := fb.Inline
+	if fb.ConfigMapRef != nil {
+		src := &corev1.ConfigMap{}
+		if err := r.Get(ctx, types.NamespacedName{Name: fb.ConfigMapRef.Name, Namespace: imageBuild.Namespace}, src); err != nil {
+			if errors.IsNotFound(err) {
+				return &invalidSpecError{"firstBoot", fmt.Errorf("ConfigMap %s not found", fb.ConfigMapRef.Name)}
+			}
+			return err
+		}
+		v, ok := src.Data[fb.ConfigMapRef.Key]
+		if !ok {
+			return &invalidSpecError{"firstBoot", fmt.Errorf("key %s not found in ConfigMap %s", fb.ConfigMapRef.Key, fb.ConfigMapRef.Name)}
+		}
+		payload = v
+	}
+	if err := firstboot.Validate(imageBuild.Spec.Distro, fb.Format, fb.Mode, payload); err != nil {
+		return &invalidSpecError{"firstBoot", err}
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Spec.ManifestConfigMap, Namespace: imageBuild.Namespace}, cm); err != nil {
+		return fmt.Errorf("failed to get manifest ConfigMap: %w", err)
+	}
+	if cm.Data[firstboot.ConfigMapKey] == payload {
+		return nil
+	}
+	patch := client.MergeFrom(cm.DeepCopy())
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[firstboot.ConfigMapKey] = payload
+	return r.Patch(ctx, cm, patch)
+}
+
+// prepareHardening resolves spec.hardeningProfiles and stores the manifest fragment and the
+// compliance report template in the manifest ConfigMap for the build step
+func (r *ImageBuildReconciler) prepareHardening(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) error {
+	if len(imageBuild.Spec.HardeningProfiles) == 0 {
+		return nil
+	}
+	selected, err := hardening.Resolve(imageBuild.Spec.HardeningProfiles)
+	if err != nil {
+		return &invalidSpecError{"hardeningProfiles", err}
+	}
+	fragment, err := hardening.Fragment(selected)
+	if err != nil {
+		return err
+	}
+	report, err := hardening.NewReport(imageBuild.Name, imageBuild.Namespace, imageBuild.Spec.Distro,
+		imageBuild.Spec.Target, imageBuild.Spec.Architecture, selected)
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Spec.ManifestConfigMap, Namespace: imageBuild.Namespace}, cm); err != nil {
+		return fmt.Errorf("failed to get manifest ConfigMap: %w", err)
+	}
+	if cm.Data[hardening.FragmentKey] == fragment && cm.Data[hardening.ReportKey] == report {
+		return nil
+	}
+	patch := client.MergeFrom(cm.DeepCopy())
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[hardening.FragmentKey] = fragment
+	cm.Data[hardening.ReportKey] = report
+	return r.Patch(ctx, cm, patch)
+}
+
+// hardeningParams returns the TaskRun params pointing the build steps at the hardening fragment and report
+func hardeningParams(imageBuild *automotivev1alpha1.ImageBuild) []tektonv1.Param {
+	var fragment, report string
+	if len(imageBuild.Spec.HardeningProfiles) > 0 {
+		fragment = "/workspace/manifest-config/" + hardening.FragmentKey
+		report = "/workspace/manifest-config/" + hardening.ReportKey
+	}
+	return []tektonv1.Param{
+		{Name: "hardening-fragment", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: fragment}},
+		{Name: "hardening-report", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: report}},
+	}
+}
+
+const (
+	complianceResultPass  = "pass"
+	complianceResultError = "error"
+
+	// ComplianceConditionType reports whether the OpenSCAP scan of the built image passed
+	ComplianceConditionType = "ComplianceScanPassed"
+)
+
+// setComplianceStatus records the scan outcome and the ComplianceScanPassed condition; a missing
+// result means the scan step never reported and counts as an error
+func setComplianceStatus(imageBuild *automotivev1alpha1.ImageBuild, result string) {
+	if result == "" {
+		result = complianceResultError
+	}
+	st := &automotivev1alpha1.ComplianceStatus{Profile: imageBuild.Spec.Compliance.Profile, Result: result}
+	if result != complianceResultError {
+		st.ARFFileName = "compliance-arf.xml"
+		st.ReportFileName = "compliance-report.html"
+	}
+	imageBuild.Status.Compliance = st
+
+	cond := metav1.Condition{
+		Type:               ComplianceConditionType,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: imageBuild.Generation,
+	}
+	switch result {
+	case complianceResultPass:
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = "ScanPassed"
+		cond.Message = fmt.Sprintf("All rules of profile %s passed", st.Profile)
+	case complianceResultError:
+		cond.Reason = "ScanError"
+		cond.Message = fmt.Sprintf("Profile %s could not be evaluated; see the build logs", st.Profile)
+	default:
+		cond.Reason = "ScanFailed"
+		cond.Message = fmt.Sprintf("At least one rule of profile %s failed; see %s", st.Profile, st.ReportFileName)
+	}
+	meta.SetStatusCondition(&imageBuild.Status.Conditions, cond)
+}
+
+// complianceParams returns the TaskRun params selecting the OpenSCAP scan, empty when none was requested
+func complianceParams(imageBuild *automotivev1alpha1.ImageBuild) []tektonv1.Param {
+	var profile, dataStream string
+	if scan := imageBuild.Spec.Compliance; scan != nil {
+		profile = scan.Profile
+		dataStream = scan.DataStream
+	}
+	return []tektonv1.Param{
+		{Name: "compliance-profile", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: profile}},
+		{Name: "compliance-datastream", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: dataStream}},
+	}
+}
+
+const (
+	sizeBudgetActionWarn = "Warn"
+	sizeReportFileName   = "size-report.json"
+
+	// SizeConditionType reports whether the built root filesystem fits spec.sizeBudget
+	SizeConditionType = "SizeWithinBudget"
+)
+
+// sizeBudgetBytes returns spec.sizeBudget.maxSize in bytes, 0 when no budget is set
+func sizeBudgetBytes(imageBuild *automotivev1alpha1.ImageBuild) (int64, error) {
+	budget := imageBuild.Spec.SizeBudget
+	if budget == nil {
+		return 0, nil
+	}
+	q, err := resource.ParseQuantity(budget.MaxSize)
+	if err != nil {
+		return 0, &invalidSpecError{"sizeBudget", fmt.Errorf("maxSize %q: %w", budget.MaxSize, err)}
+	}
+	if q.Sign() <= 0 {
+		return 0, &invalidSpecError{"sizeBudget", fmt.Errorf("maxSize must be positive")}
+	}
+	return q.Value(), nil
+}
+
+// setSizeStatus records the measured root filesystem size and the SizeWithinBudget condition; a
+// missing measurement leaves the condition Unknown
+func setSizeStatus(imageBuild *automotivev1alpha1.ImageBuild, rootfsBytes int64) {
+	budgetBytes, _ := sizeBudgetBytes(imageBuild)
+	st := &automotivev1alpha1.SizeStatus{RootFSBytes: rootfsBytes, BudgetBytes: budgetBytes}
+	cond := metav1.Condition{
+		Type:               SizeConditionType,
+		ObservedGeneration: imageBuild.Generation,
+	}
+	switch {
+	case rootfsBytes <= 0:
+		cond.Status = metav1.ConditionUnknown
+		cond.Reason = "NotMeasured"
+		cond.Message = "The root filesystem size could not be measured; see the build logs"
+	case rootfsBytes > budgetBytes:
+		st.Exceeded = true
+		st.ReportFileName = sizeReportFileName
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "BudgetExceeded"
+		cond.Message = fmt.Sprintf("Root filesystem uses %d of %d bytes; see %s", rootfsBytes, budgetBytes, sizeReportFileName)
+	default:
+		st.ReportFileName = sizeReportFileName
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = "WithinBudget"
+		cond.Message = fmt.Sprintf("Root filesystem uses %d of %d bytes", rootfsBytes, budgetBytes)
+	}
+	imageBuild.Status.Size = st
+	meta.SetStatusCondition(&imageBuild.Status.Conditions, cond)
+}
+
+// sizeBudgetParams returns the TaskRun param enabling the size breakdown, empty when no budget was set
+func sizeBudgetParams(imageBuild *automotivev1alpha1.ImageBuild) []tektonv1.Param {
+	var budget string
+	if n, err := sizeBudgetBytes(imageBuild); err == nil && n > 0 {
+		budget = strconv.FormatInt(n, 10)
+	}
+	return []tektonv1.Param{
+		{Name: "size-budget", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: budget}},
+	}
+}
+
+const (
+	bootResultBooted       = "booted"
+	bootConsoleLogName     = "boot-console.log"
+	defaultBootTestTimeout = 300
+
+	// BootTestConditionType reports whether the built image booted within spec.bootTest.maxKernelToReady
+	BootTestConditionType = "BootTestPassed"
+)
+
+// bootTestThreshold returns spec.bootTest.maxKernelToReady, 0 when no threshold is set
+func bootTestThreshold(imageBuild *automotivev1alpha1.ImageBuild) (time.Duration, error) {
+	bt := imageBuild.Spec.BootTest
+	if bt == nil {
+		return 0, nil
+	}
+	if strings.Contains(bt.ReadyMarker, "'") {
+		return 0, &invalidSpecError{"bootTest", fmt.Errorf("readyMarker must not contain single quotes")}
+	}
+	if bt.MaxKernelToReady == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(bt.MaxKernelToReady)
+	if err != nil || d <= 0 {
+		return 0, &invalidSpecError{"bootTest", fmt.Errorf("maxKernelToReady %q must be a positive duration such as 15s", bt.MaxKernelToReady)}
+	}
+	return d, nil
+}
+
+// parseBootTimings parses the boot-timings result ("firmware=1800,kernelToReady=6200,total=8000", in milliseconds)
+func parseBootTimings(raw string) map[string]time.Duration {
+	timings := map[string]time.Duration{}
+	for _, entry := range strings.Split(strings.TrimSpace(raw), ",") {
+		name, ms, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || name == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(ms), 10, 64)
+		if err != nil || n < 0 {
+			continue
+		}
+		timings[name] = time.Duration(n) * time.Millisecond
+	}
+	return timings
+}
+
+// setBootTestStatus records the boot test outcome and the BootTestPassed condition and reports whether it passed
+func setBootTestStatus(imageBuild *automotivev1alpha1.ImageBuild, result, accelerator string, timings map[string]time.Duration) bool {
+	if result == "" {
+		result = "error"
+	}
+	st := &automotivev1alpha1.BootTestStatus{Result: result, Accelerator: accelerator, ConsoleLogFileName: bootConsoleLogName}
+	for name, d := range timings {
+		if st.Timings == nil {
+			st.Timings = map[string]string{}
+		}
+		st.Timings[name] = d.String()
+	}
+	imageBuild.Status.Boot = st
+
+	threshold, _ := bootTestThreshold(imageBuild)
+	kernelToReady := timings["kernelToReady"]
+	cond := metav1.Condition{
+		Type:               BootTestConditionType,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: imageBuild.Generation,
+	}
+	switch {
+	case result != bootResultBooted:
+		cond.Reason = "BootFailed"
+		cond.Message = fmt.Sprintf("The image did not boot (%s); see %s", result, bootConsoleLogName)
+	case threshold > 0 && kernelToReady > threshold:
+		cond.Reason = "ThresholdExceeded"
+		cond.Message = fmt.Sprintf("Kernel to ready took %s, over the %s threshold (%s)", kernelToReady, threshold, accelerator)
+	default:
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = "Booted"
+		cond.Message = fmt.Sprintf("Kernel to ready took %s (%s)", kernelToReady, accelerator)
+	}
+	meta.SetStatusCondition(&imageBuild.Status.Conditions, cond)
+	return cond.Status == metav1.ConditionTrue
+}
+
+// bootTestParams returns the TaskRun params enabling the QEMU boot test, empty when none was requested
+func bootTestParams(imageBuild *automotivev1alpha1.ImageBuild) []tektonv1.Param {
+	var timeout string
+	marker := tasks.DefaultBootReadyMarker
+	if bt := imageBuild.Spec.BootTest; bt != nil {
+		seconds := bt.TimeoutSeconds
+		if seconds <= 0 {
+			seconds = defaultBootTestTimeout
+		}
+		timeout = strconv.Itoa(int(seconds))
+		if bt.ReadyMarker != "" {
+			marker = bt.ReadyMarker
+		}
+	}
+	return []tektonv1.Param{
+		{Name: "boot-test-timeout", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: timeout}},
+		{Name: "boot-ready-marker", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: marker}},
+	}
+}
+
+// defaultDebugHoldMinutes applies when spec.debug does not set holdMinutes
+const defaultDebugHoldMinutes = 60
+
+// debugHoldMinutes is how long a failed build pod is kept, 0 when debugging is off
+func debugHoldMinutes(imageBuild *automotivev1alpha1.ImageBuild) int32 {
+	if imageBuild.Spec.Debug == nil {
+		return 0
+	}
+	if imageBuild.Spec.Debug.HoldMinutes <= 0 {
+		return defaultDebugHoldMinutes
+	}
+	return imageBuild.Spec.Debug.HoldMinutes
+}
+
+// debugParams returns the TaskRun param that keeps a failed build pod for debugging
+func debugParams(imageBuild *automotivev1alpha1.ImageBuild) []tektonv1.Param {
+	var minutes string
+	if hold := debugHoldMinutes(imageBuild); hold > 0 {
+		minutes = strconv.Itoa(int(hold))
+	}
+	return []tektonv1.Param{
+		{Name: "debug-hold-minutes", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: minutes}},
+	}
+}
+
+// gitSourceParams returns the TaskRun params pointing the build at the git repository holding its
+// manifest, empty when the manifest ConfigMap holds it
+func gitSourceParams(imageBuild *automotivev1alpha1.ImageBuild) []tektonv1.Param {
+	var git automotivev1alpha1.GitSource
+	if src := imageBuild.Spec.Source; src != nil && src.Git != nil {
+		git = *src.Git
+	}
+	return []tektonv1.Param{
+		{Name: "git-url", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: git.URL}},
+		{Name: "git-ref", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: git.Ref}},
+		{Name: "git-path", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: git.Path}},
+	}
+}
+
+// updateDebugStatus records the held pod once the build step failed and debug-hold keeps the pod running
+func (r *ImageBuildReconciler) updateDebugStatus(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild, taskRun *tektonv1.TaskRun) error {
+	hold := debugHoldMinutes(imageBuild)
+	if hold == 0 || imageBuild.Status.Debug != nil || taskRun.Status.PodName == "" {
+		return nil
+	}
+	var failedAt *metav1.Time
+	var holding bool
+	for _, step := range taskRun.Status.Steps {
+		switch {
+		case step.Name == "build-image" && step.Terminated != nil && step.Terminated.ExitCode != 0:
+			failedAt = &step.Terminated.FinishedAt
+		case step.Name == "debug-hold" && step.Running != nil:
+			holding = true
+		}
+	}
+	if failedAt == nil || !holding {
+		return nil
+	}
+
+	fresh := &automotivev1alpha1.ImageBuild{}
+	if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
+		return err
+	}
+	patch := client.MergeFrom(fresh.DeepCopy())
+	heldUntil := metav1.NewTime(failedAt.Add(time.Duration(hold) * time.Minute))
+	fresh.Status.Debug = &automotivev1alpha1.DebugStatus{PodName: taskRun.Status.PodName, HeldUntil: heldUntil}
+	fresh.Status.Message = fmt.Sprintf("Build step failed; pod %s is kept for debugging until %s",
+		taskRun.Status.PodName, heldUntil.UTC().Format(time.RFC3339))
+	return r.Status().Patch(ctx, fresh, patch)
+}
+
+// firstBootParams returns the TaskRun params describing where the build step finds the payload
+func firstBootParams(imageBuild *automotivev1alpha1.ImageBuild) []tektonv1.Param {
+	fb := imageBuild.Spec.FirstBoot
+	var file, embedPath, artifact string
+	if fb != nil {
+		file = "/workspace/manifest-config/" + firstboot.ConfigMapKey
+		if fb.FileName != "" {
+			file = "/workspace/shared/" + fb.FileName
+		}
+		if fb.Mode == firstboot.ModeEmbed {
+			embedPath = firstboot.EmbedPath(fb.Format)
+		} else {
+			artifact = firstboot.ArtifactName(fb.Format)
+		}
+	}
+	return []tektonv1.Param{
+		{Name: "firstboot-file", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: file}},
+		{Name: "firstboot-embed-path", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: embedPath}},
+		{Name: "firstboot-artifact", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: artifact}},
+	}
+}
+
+// networkPolicyConfig is spec.osBuilds.networkPolicy of the OperatorConfig, nil when build pods are
+// not restricted. Errors reading the OperatorConfig other than its absence are returned, so a build
+// does not start unrestricted because of a passing API error.
+func (r *ImageBuildReconciler) networkPolicyConfig(ctx context.Context) (*automotivev1alpha1.BuildNetworkPolicyConfig, error) {
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	err := r.Get(ctx, types.NamespacedName{Name: "config", Namespace: OperatorNamespace}, operatorConfig)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OperatorConfig configuration: %w", err)
+	}
+	if operatorConfig.Spec.OSBuilds == nil {
+		return nil, nil
+	}
+	return operatorConfig.Spec.OSBuilds.NetworkPolicy, nil
+}
+
+// grantedEgress returns the egress allowlist followed by the exceptions named in
+// spec.networkExceptions. Exceptions that do not exist or are not granted to the namespace of the
+// build are an invalidSpecError.
+func grantedEgress(cfg *automotivev1alpha1.BuildNetworkPolicyConfig, imageBuild *automotivev1alpha1.ImageBuild) ([]automotivev1alpha1.BuildEgressRule, error) {
+	rules := slices.Clone(cfg.Egress)
+	for _, name := range imageBuild.Spec.NetworkExceptions {
+		i := slices.IndexFunc(cfg.Exceptions, func(e automotivev1alpha1.BuildEgressException) bool { return e.Name == name })
+		if i < 0 {
+			return nil, &invalidSpecError{field: "spec.networkExceptions", err: fmt.Errorf("no network exception %q is configured", name)}
+		}
+		exception := cfg.Exceptions[i]
+		if len(exception.BuildNamespaces) > 0 && !slices.Contains(exception.BuildNamespaces, imageBuild.Namespace) {
+			return nil, &invalidSpecError{field: "spec.networkExceptions", err: fmt.Errorf("network exception %q is not granted to namespace %s", name, imageBuild.Namespace)}
+		}
+		rules = append(rules, exception.BuildEgressRule)
+	}
+	return rules, nil
+}
+
+// egressPolicyRules converts egress rules to those of a NetworkPolicy, preceded by a rule allowing
+// DNS. Rules without destinations are skipped, as a NetworkPolicy rule without peers allows every
+// destination.
+func egressPolicyRules(rules []automotivev1alpha1.BuildEgressRule) []networkingv1.NetworkPolicyEgressRule {
+	udp, tcp := corev1.ProtocolUDP, corev1.ProtocolTCP
+	dns := intstr.FromInt32(53)
+	egress := []networkingv1.NetworkPolicyEgressRule{{
+		Ports: []networkingv1.NetworkPolicyPort{{Protocol: &udp, Port: &dns}, {Protocol: &tcp, Port: &dns}},
+	}}
+	for _, rule := range rules {
+		var peers []networkingv1.NetworkPolicyPeer
+		for _, cidr := range rule.CIDRs {
+			peers = append(peers, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+		}
+		for _, namespace := range rule.Namespaces {
+			peers = append(peers, networkingv1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{corev1.LabelMetadataName: namespace},
+			}})
+		}
+		if len(peers) == 0 {
+			continue
+		}
+		var ports []networkingv1.NetworkPolicyPort
+		for _, p := range rule.Ports {
+			port := intstr.FromInt32(p)
+			ports = append(ports, networkingv1.NetworkPolicyPort{Protocol: &tcp, Port: &port})
+		}
+		egress = append(egress, networkingv1.NetworkPolicyEgressRule{To: peers, Ports: ports})
+	}
+	return egress
+}
+
+// buildMountsAnnotation on a RuntimeClass declares that its runtime supports the mounts of the
+// build pod, overriding unsupportedRuntimeHandlers for runtimes configured to pass devices through
+const buildMountsAnnotation = "automotive.sdv.cloud.redhat.com/build-mounts"
+
+// unsupportedRuntimeHandlers are the runtime handlers known not to support the mounts of the build
+// pod: the /dev of the node in the privileged build step, for loop devices, and the workspace volume
+var unsupportedRuntimeHandlers = map[string]string{
+	"runsc":   "gVisor does not give containers the devices of the node, which the build step needs for loop devices",
+	"kata-fc": "Kata Containers on Firecracker cannot share the workspace volume and the /dev of the node with the sandbox",
+}
+
+// validateRuntimeClass checks the runtime class the build pod runs with: spec.runtimeClassName must
+// be allowed by spec.osBuilds.allowedRuntimeClasses of the OperatorConfig, and the class, or the
+// default of the OperatorConfig, must exist and support the mounts of the build pod. Builds in
+// partner namespaces always run with the class of the partner profile. Violations are an
+// invalidSpecError.
+func (r *ImageBuildReconciler) validateRuntimeClass(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) error {
+	var defaultClass string
+	var allowed []string
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	if err := r.Get(ctx, types.NamespacedName{Name: "config", Namespace: OperatorNamespace}, operatorConfig); err == nil && operatorConfig.Spec.OSBuilds != nil {
+		defaultClass = operatorConfig.Spec.OSBuilds.RuntimeClassName
+		allowed = operatorConfig.Spec.OSBuilds.AllowedRuntimeClasses
+	}
+
+	name := imageBuild.Spec.RuntimeClassName
+	profile, err := r.partnerIsolation(ctx, imageBuild.Namespace)
+	if err != nil {
+		return err
+	}
+	if profile != nil {
+		if name != "" && name != profile.RuntimeClassName {
+			return &invalidSpecError{field: "spec.runtimeClassName", err: fmt.Errorf("builds in partner namespace %s run with runtime class %s", imageBuild.Namespace, profile.RuntimeClassName)}
+		}
+		name = profile.RuntimeClassName
+	} else if name == "" {
+		name = defaultClass
+	} else if len(allowed) > 0 && name != defaultClass && !slices.Contains(allowed, name) {
+		return &invalidSpecError{field: "spec.runtimeClassName", err: fmt.Errorf("runtime class %s is not allowed for builds; allowed: %s", name, strings.Join(allowed, ", "))}
+	}
+	if name == "" {
+		return nil
+	}
+
+	runtimeClass := &nodev1.RuntimeClass{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name}, runtimeClass); err != nil {
+		if errors.IsNotFound(err) {
+			return &invalidSpecError{field: "spec.runtimeClassName", err: fmt.Errorf("runtime class %s does not exist", name)}
+		}
+		return err
+	}
+	if reason, ok := unsupportedRuntimeHandlers[runtimeClass.Handler]; ok && runtimeClass.Annotations[buildMountsAnnotation] != "supported" {
+		return &invalidSpecError{field: "spec.runtimeClassName", err: fmt.Errorf("runtime class %s cannot run builds: %s", name, reason)}
+	}
+	return nil
+}
+
+// applyNetworkPolicy creates or updates the NetworkPolicy restricting the egress of the pods of a
+// build to what spec.osBuilds.networkPolicy of the OperatorConfig grants the build, or to the egress
+// of the partner profile for builds in partner namespaces. It runs before the upload pod and the
+// build TaskRun are created, so no pod of the build runs unrestricted. The policy selects every pod
+// labeled with the build: the upload pod, which fetches remote files, and the pods of the build,
+// publishing, signing and flashing TaskRuns alike. The policy is owned by the ImageBuild.
+func (r *ImageBuildReconciler) applyNetworkPolicy(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) error {
+	cfg, err := r.networkPolicyConfig(ctx)
+	if err != nil {
+		return err
+	}
+	profile, err := r.partnerIsolation(ctx, imageBuild.Namespace)
+	if err != nil {
+		return err
+	}
+	if profile != nil {
+		if len(imageBuild.Spec.NetworkExceptions) > 0 {
+			return &invalidSpecError{field: "spec.networkExceptions", err: fmt.Errorf("network exceptions are not granted to builds in partner namespace %s", imageBuild.Namespace)}
+		}
+		cfg = &automotivev1alpha1.BuildNetworkPolicyConfig{Egress: profile.Egress}
+	}
+	if cfg == nil {
+		return nil
+	}
+	rules, err := grantedEgress(cfg, imageBuild)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-build-egress", imageBuild.Name)
+	spec := networkingv1.NetworkPolicySpec{
+		PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"automotive.sdv.cloud.redhat.com/imagebuild-name": imageBuild.Name}},
+		PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+		Egress:      egressPolicyRules(rules),
+	}
+	policy := &networkingv1.NetworkPolicy{}
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: imageBuild.Namespace}, policy)
+	switch {
+	case errors.IsNotFound(err):
+		policy = &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: imageBuild.Namespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/managed-by":                    "automotive-dev-operator",
+					"automotive.sdv.cloud.redhat.com/imagebuild-name": imageBuild.Name,
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: imageBuild.APIVersion,
+						Kind:       imageBuild.Kind,
+						Name:       imageBuild.Name,
+						UID:        imageBuild.UID,
+						Controller: ptr.To(true),
+					},
+				},
+			},
+			Spec: spec,
+		}
+		if err := r.Create(ctx, policy); err != nil {
+			return fmt.Errorf("failed to create NetworkPolicy: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to get NetworkPolicy: %w", err)
+	case !equality.Semantic.DeepEqual(policy.Spec, spec):
+		policy.Spec = spec
+		if err := r.Update(ctx, policy); err != nil {
+			return fmt.Errorf("failed to update NetworkPolicy: %w", err)
+		}
 	}
 
-	if imageBuild.Status.PVCName != pvcName {
+	if imageBuild.Status.NetworkPolicyName != name {
 		fresh := &automotivev1alpha1.ImageBuild{}
 		if err := r.Get(ctx, types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}, fresh); err != nil {
-			return ctrl.Result{}, fmt.Errorf("failed to get fresh ImageBuild: %w", err)
+			return fmt.Errorf("failed to get fresh ImageBuild: %w", err)
 		}
-
-		fresh.Status.PVCName = pvcName
+		fresh.Status.NetworkPolicyName = name
 		if err := r.Status().Update(ctx, fresh); err != nil {
-			return ctrl.Result{}, fmt.Errorf("failed to update ImageBuild status with PVC name: %w", err)
+			return fmt.Errorf("failed to update ImageBuild status with NetworkPolicy name: %w", err)
 		}
-
-		imageBuild.Status.PVCName = pvcName
+		imageBuild.Status.NetworkPolicyName = name
 	}
-
-	if err := r.createBuildTaskRun(ctx, imageBuild); err != nil {
-		return ctrl.Result{}, fmt.Errorf("failed to create build task run: %w", err)
-	}
-
-	return ctrl.Result{RequeueAfter: time.Second * 30}, nil
+	return nil
 }
 
 func (r *ImageBuildReconciler) createBuildTaskRun(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) error {
@@ -417,6 +3769,18 @@ func (r *ImageBuildReconciler) createBuildTaskRun(ctx context.Context, imageBuil
 			},
 		},
 	}
+	params = append(params, firstBootParams(imageBuild)...)
+	params = append(params, hardeningParams(imageBuild)...)
+	params = append(params, complianceParams(imageBuild)...)
+	params = append(params, sizeBudgetParams(imageBuild)...)
+	params = append(params, bootTestParams(imageBuild)...)
+	params = append(params, debugParams(imageBuild)...)
+	params = append(params, gitSourceParams(imageBuild)...)
+
+	requestID := imageBuild.Annotations["automotive.sdv.cloud.redhat.com/request-id"]
+	if requestID != "" {
+		params = append(params, tektonv1.Param{Name: "request-id", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: requestID}})
+	}
 
 	workspaces := []tektonv1.WorkspaceBinding{
 		{
@@ -462,6 +3826,13 @@ func (r *ImageBuildReconciler) createBuildTaskRun(ctx context.Context, imageBuil
 		log.Info("Setting RuntimeClassName from ImageBuild spec", "runtimeClassName", imageBuild.Spec.RuntimeClassName)
 		podTemplate.RuntimeClassName = &imageBuild.Spec.RuntimeClassName
 	}
+	profile, err := r.partnerIsolation(ctx, imageBuild.Namespace)
+	if err != nil {
+		return err
+	}
+	if profile != nil {
+		podTemplate.RuntimeClassName = &profile.RuntimeClassName
+	}
 	taskRun := &tektonv1.TaskRun{
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: fmt.Sprintf("%s-build-", imageBuild.Name),
@@ -488,6 +3859,16 @@ func (r *ImageBuildReconciler) createBuildTaskRun(ctx context.Context, imageBuil
 		},
 	}
 
+	// Tekton copies TaskRun labels to the pod, so cluster logging can find the build logs by request ID
+	if requestID != "" && len(validation.IsValidLabelValue(requestID)) == 0 {
+		taskRun.Labels["automotive.sdv.cloud.redhat.com/request-id"] = requestID
+	}
+
+	if hold := debugHoldMinutes(imageBuild); hold > 0 {
+		// Extend Tekton's default one hour timeout so the hold is not cut short
+		taskRun.Spec.Timeout = &metav1.Duration{Duration: time.Hour + time.Duration(hold)*time.Minute}
+	}
+
 	if err := r.Create(ctx, taskRun); err != nil {
 		return fmt.Errorf("failed to create TaskRun: %w", err)
 	}
@@ -503,6 +3884,7 @@ func (r *ImageBuildReconciler) createBuildTaskRun(ctx context.Context, imageBuil
 	}
 
 	log.Info("Successfully created TaskRun", "name", taskRun.Name)
+	r.recordEvent(fresh, corev1.EventTypeNormal, EventReasonBuildStarted, "Created TaskRun %s", taskRun.Name)
 	return nil
 }
 
@@ -523,21 +3905,7 @@ func (r *ImageBuildReconciler) updateArtifactInfo(ctx context.Context, imageBuil
 
 	// Only set ArtifactFileName if it's not already set (from Tekton results)
 	if latestImageBuild.Status.ArtifactFileName == "" {
-		var fileExtension string
-		switch latestImageBuild.Spec.ExportFormat {
-		case "image":
-			fileExtension = ".raw"
-		case "qcow2":
-			fileExtension = ".qcow2"
-		default:
-			fileExtension = fmt.Sprintf(".%s", latestImageBuild.Spec.ExportFormat)
-		}
-
-		fileName := fmt.Sprintf("%s-%s%s",
-			latestImageBuild.Spec.Distro,
-			latestImageBuild.Spec.Target,
-			fileExtension)
-		latestImageBuild.Status.ArtifactFileName = fileName
+		latestImageBuild.Status.ArtifactFileName = defaultArtifactFileName(latestImageBuild)
 	}
 
 	log.Info("Setting artifact info", "pvc", pvcName, "fileName", latestImageBuild.Status.ArtifactFileName)
@@ -604,11 +3972,32 @@ func (r *ImageBuildReconciler) updateArtifactInfo(ctx context.Context, imageBuil
 		}
 
 		log.Info("artifact serving resources created and status updated", "route", route.Status.Ingress[0].Host)
+		r.recordEvent(freshBuild, corev1.EventTypeNormal, EventReasonArtifactPublished,
+			"Serving %s at %s", freshBuild.Status.ArtifactFileName, artifactURL)
+		return ctrl.Result{}, nil
 	}
 
+	r.recordEvent(latestImageBuild, corev1.EventTypeNormal, EventReasonArtifactPublished,
+		"Serving %s from the artifact pod", latestImageBuild.Status.ArtifactFileName)
 	return ctrl.Result{}, nil
 }
 
+// defaultArtifactFileName is the name the build script exports the artifact as, for builds whose
+// TaskRun did not report the artifact-filename result
+func defaultArtifactFileName(imageBuild *automotivev1alpha1.ImageBuild) string {
+	var fileExtension string
+	switch imageBuild.Spec.ExportFormat {
+	case "image":
+		fileExtension = ".raw"
+	case "qcow2":
+		fileExtension = ".qcow2"
+	default:
+		fileExtension = fmt.Sprintf(".%s", imageBuild.Spec.ExportFormat)
+	}
+
+	return fmt.Sprintf("%s-%s%s", imageBuild.Spec.Distro, imageBuild.Spec.Target, fileExtension)
+}
+
 func (r *ImageBuildReconciler) createArtifactPod(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) error {
 	log := r.Log.WithValues("imagebuild", types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace})
 
@@ -821,13 +4210,37 @@ server {
 }
 
 func (r *ImageBuildReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// events of builds of other shards are dropped before they reach the work queue
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&automotivev1alpha1.ImageBuild{}).
-		Owns(&tektonv1.TaskRun{}).
-		Owns(&corev1.Pod{}).
+		Named(r.Shard.ControllerName("imagebuild")).
+		For(&automotivev1alpha1.ImageBuild{}, builder.WithPredicates(r.Shard.BuildPredicate())).
+		Owns(&tektonv1.TaskRun{}, builder.WithPredicates(r.Shard.OwnedPredicate())).
+		Owns(&corev1.Pod{}, builder.WithPredicates(r.Shard.OwnedPredicate())).
 		Complete(r)
 }
 
+// parseStageTimings converts the "stage=seconds,..." task result into durations
+func parseStageTimings(raw string) map[string]string {
+	timings := map[string]string{}
+	for _, entry := range strings.Split(strings.TrimSpace(raw), ",") {
+		stage, secs, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || stage == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(secs), 10, 64)
+		if err != nil || n < 0 {
+			continue
+		}
+		timings[stage] = (time.Duration(n) * time.Second).String()
+	}
+	return timings
+}
+
+// isFinishedPhase reports whether a build in phase will not change phase anymore
+func isFinishedPhase(phase string) bool {
+	return phase == "Completed" || phase == "Failed" || phase == "Cancelled"
+}
+
 func isTaskRunCompleted(taskRun *tektonv1.TaskRun) bool {
 	return taskRun.Status.CompletionTime != nil
 }
@@ -944,9 +4357,19 @@ func (r *ImageBuildReconciler) createUploadPod(ctx context.Context, imageBuild *
 		},
 	}
 
+	if err := r.validateRemoteFiles(ctx, imageBuild); err != nil {
+		return err
+	}
+	addRemoteFileContainers(pod, imageBuild.Spec.RemoteFiles)
+
 	if err := r.Create(ctx, pod); err != nil && !errors.IsAlreadyExists(err) {
 		return fmt.Errorf("failed to create upload pod: %w", err)
 	}
+	if files := imageBuild.Spec.RemoteFiles; files != nil && len(files.URLs) > 0 {
+		// the file server starts once the downloads finished, which handleUploadingState follows
+		log.Info("Upload pod is downloading remote files", "pod", podName)
+		return nil
+	}
 
 	log.Info("Waiting for upload pod to be ready")
 	err = wait.PollUntilContextTimeout(
@@ -969,6 +4392,133 @@ func (r *ImageBuildReconciler) createUploadPod(ctx context.Context, imageBuild *
 	return nil
 }
 
+// validateRemoteFiles rejects the remote files of a build whose URLs, or the s3 endpoint of their
+// Secret, are not on a host of spec.osBuilds.remoteFileHosts of the OperatorConfig
+func (r *ImageBuildReconciler) validateRemoteFiles(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) error {
+	files := imageBuild.Spec.RemoteFiles
+	if files == nil || len(files.URLs) == 0 {
+		return nil
+	}
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	if err := r.Get(ctx, types.NamespacedName{Name: "config", Namespace: OperatorNamespace}, operatorConfig); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to get OperatorConfig configuration: %w", err)
+	}
+	var hosts []string
+	if operatorConfig.Spec.OSBuilds != nil {
+		hosts = operatorConfig.Spec.OSBuilds.RemoteFileHosts
+	}
+	for _, url := range files.URLs {
+		if err := remotefiles.CheckURL(hosts, url); err != nil {
+			return &invalidSpecError{field: "spec.remoteFiles.urls", err: err}
+		}
+	}
+	if files.SecretRef == "" {
+		return nil
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: files.SecretRef, Namespace: imageBuild.Namespace}, secret); err != nil {
+		if errors.IsNotFound(err) {
+			return &invalidSpecError{field: "spec.remoteFiles.secretRef", err: fmt.Errorf("secret %s not found", files.SecretRef)}
+		}
+		return fmt.Errorf("failed to get remote files secret: %w", err)
+	}
+	if endpoint, ok := secret.Data["AWS_ENDPOINT_URL"]; ok {
+		if err := remotefiles.CheckURL(hosts, strings.TrimSpace(string(endpoint))); err != nil {
+			return &invalidSpecError{field: "spec.remoteFiles.secretRef", err: fmt.Errorf("AWS_ENDPOINT_URL: %w", err)}
+		}
+	}
+	return nil
+}
+
+// remoteFileContainerPrefix prefixes the init containers of the upload pod that download remote
+// files, followed by the index of the file in spec.remoteFiles.urls
+const remoteFileContainerPrefix = "fetch-"
+
+// addRemoteFileContainers adds an init container per remote file of a build to its upload pod, which
+// downloads the file to remotefiles.Path in the workspace, where the build finds it. The file
+// server starts once every file is stored; a download retries in its container and a failed one
+// fails the build.
+func addRemoteFileContainers(pod *corev1.Pod, files *automotivev1alpha1.RemoteFiles) {
+	if files == nil || len(files.URLs) == 0 {
+		return
+	}
+	mounts := []corev1.VolumeMount{{Name: "workspace", MountPath: "/workspace/shared"}}
+	if files.SecretRef != "" {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name:         "remote-credentials",
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: files.SecretRef}},
+		})
+		mounts = append(mounts, corev1.VolumeMount{Name: "remote-credentials", MountPath: "/workspace/remote-credentials", ReadOnly: true})
+	}
+	for i, url := range files.URLs {
+		image := "docker.io/curlimages/curl:8.8.0"
+		if strings.HasPrefix(strings.ToLower(url), "s3://") {
+			image = "docker.io/amazon/aws-cli:2.17.0"
+		}
+		pod.Spec.InitContainers = append(pod.Spec.InitContainers, corev1.Container{
+			Name:    fmt.Sprintf("%s%d", remoteFileContainerPrefix, i),
+			Image:   image,
+			Command: []string{"sh", "-c", tasks.FetchRemoteFileScript, "fetch", url, remotefiles.Path(url)},
+			// the aws CLI writes its cache to the home directory
+			Env:                      []corev1.EnvVar{{Name: "HOME", Value: "/tmp"}},
+			TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("64Mi"),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("512Mi"),
+				},
+			},
+			VolumeMounts: mounts,
+		})
+	}
+}
+
+// remoteFilesFetched reports whether the upload pod of a build downloaded every remote file. A
+// download that failed is reported as the failure message of the build; it is not retried, as
+// the download tools already retry transient errors.
+func (r *ImageBuildReconciler) remoteFilesFetched(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (bool, string, error) {
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-upload-pod", imageBuild.Name), Namespace: imageBuild.Namespace}, pod); err != nil {
+		if errors.IsNotFound(err) {
+			return false, "", nil
+		}
+		return false, "", err
+	}
+	urls := imageBuild.Spec.RemoteFiles.URLs
+	fetched := 0
+	for _, cs := range pod.Status.InitContainerStatuses {
+		index, err := strconv.Atoi(strings.TrimPrefix(cs.Name, remoteFileContainerPrefix))
+		if !strings.HasPrefix(cs.Name, remoteFileContainerPrefix) || err != nil || index < 0 || index >= len(urls) {
+			continue
+		}
+		if t := cs.State.Terminated; t != nil && t.ExitCode == 0 {
+			fetched++
+			continue
+		}
+		failed := cs.State.Terminated
+		if failed == nil {
+			failed = cs.LastTerminationState.Terminated
+		}
+		if failed != nil && failed.ExitCode != 0 {
+			return false, fmt.Sprintf("Failed to download %s: %s", urls[index], lastLine(failed.Message)), nil
+		}
+		if w := cs.State.Waiting; w != nil && (w.Reason == "ErrImagePull" || w.Reason == "ImagePullBackOff" || w.Reason == "CreateContainerConfigError") {
+			return false, fmt.Sprintf("Failed to download %s: %s: %s", urls[index], w.Reason, w.Message), nil
+		}
+	}
+	return fetched == len(urls), "", nil
+}
+
+// lastLine returns the last non-empty line of a container's termination message, where download
+// tools print their error
+func lastLine(message string) string {
+	lines := strings.Split(strings.TrimSpace(message), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
 func (r *ImageBuildReconciler) updateStatus(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild, phase, message string) error {
 	fresh := &automotivev1alpha1.ImageBuild{}
 	if err := r.Get(ctx, types.NamespacedName{
@@ -980,18 +4530,424 @@ func (r *ImageBuildReconciler) updateStatus(ctx context.Context, imageBuild *aut
 
 	patch := client.MergeFrom(fresh.DeepCopy())
 
+	previousPhase := fresh.Status.Phase
 	fresh.Status.Phase = phase
 	fresh.Status.Message = message
+	if phase != "Queued" {
+		fresh.Status.QueuePosition = 0
+	}
 
 	if phase == "Building" && fresh.Status.StartTime == nil {
 		now := metav1.Now()
 		fresh.Status.StartTime = &now
-	} else if (phase == "Completed" || phase == "Failed") && fresh.Status.CompletionTime == nil {
+	} else if isFinishedPhase(phase) && fresh.Status.CompletionTime == nil {
 		now := metav1.Now()
 		fresh.Status.CompletionTime = &now
 	}
+	if isFinishedPhase(phase) {
+		fresh.Status.Debug = nil
+	}
+	if phase == "Cancelled" && !scrubRequested(fresh) {
+		fresh.Status.PVCName = ""
+	}
 
-	return r.Status().Patch(ctx, fresh, patch)
+	if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+		return err
+	}
+	if phase != previousPhase {
+		r.recordPhaseEvent(fresh, previousPhase)
+		r.notifyWebhooks(fresh, previousPhase)
+		r.postCommitStatus(fresh)
+	}
+	if phase == "Failed" {
+		r.dispatchPlugins(imageBuild)
+	}
+	return nil
+}
+
+// recordEvent records an Event on imageBuild unless no Recorder is configured
+func (r *ImageBuildReconciler) recordEvent(imageBuild *automotivev1alpha1.ImageBuild, eventType, reason, messageFmt string, args ...any) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(imageBuild, eventType, reason, messageFmt, args...)
+}
+
+// recordPhaseEvent records the Event for a build that just moved from previousPhase to its
+// current phase. Entering Building is recorded once the TaskRun exists, as BuildStarted.
+func (r *ImageBuildReconciler) recordPhaseEvent(imageBuild *automotivev1alpha1.ImageBuild, previousPhase string) {
+	message := imageBuild.Status.Message
+	switch imageBuild.Status.Phase {
+	case "Queued":
+		reason := EventReasonWaitingForStorage
+		if imageBuild.Status.QueuePosition > 0 {
+			reason = EventReasonWaitingForSlot
+		}
+		r.recordEvent(imageBuild, corev1.EventTypeNormal, reason, "%s", message)
+	case "Uploading":
+		r.recordEvent(imageBuild, corev1.EventTypeNormal, EventReasonUploadReady, "Upload server is ready: %s", message)
+	case "Building":
+		if previousPhase == "Uploading" {
+			r.recordEvent(imageBuild, corev1.EventTypeNormal, EventReasonUploadsComplete, "Input files uploaded, upload server stopped")
+		}
+	case "Completed":
+		r.recordEvent(imageBuild, corev1.EventTypeNormal, EventReasonBuildSucceeded, "%s", message)
+	case "Failed":
+		r.recordEvent(imageBuild, corev1.EventTypeWarning, EventReasonBuildFailed, "%s", message)
+	case "Cancelled":
+		r.recordEvent(imageBuild, corev1.EventTypeNormal, EventReasonBuildCancelled, "%s", message)
+	}
+}
+
+// pluginDispatchTimeout bounds how long all plugins together may take for one build
+const pluginDispatchTimeout = 10 * time.Minute
+
+// dispatchPlugins calls the configured plugins for a build that just reached Completed or Failed
+// and records their results in the status. Plugins run in the background so a slow publisher does
+// not hold up reconciliation; a failing plugin never changes the build phase.
+func (r *ImageBuildReconciler) dispatchPlugins(imageBuild *automotivev1alpha1.ImageBuild) {
+	if r.Plugins == nil || r.Plugins.Len() == 0 {
+		return
+	}
+	key := types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace}
+	log := r.Log.WithValues("imagebuild", key)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), pluginDispatchTimeout)
+		defer cancel()
+
+		fresh := &automotivev1alpha1.ImageBuild{}
+		if err := r.Get(ctx, key, fresh); err != nil {
+			log.Error(err, "failed to get ImageBuild for plugins")
+			return
+		}
+		results := r.Plugins.Dispatch(ctx, pluginEvent(fresh))
+		if len(results) == 0 {
+			return
+		}
+
+		statusResults := make([]automotivev1alpha1.PluginResult, 0, len(results))
+		for _, res := range results {
+			if !res.Succeeded {
+				log.Info("plugin did not succeed", "plugin", res.Plugin, "kind", res.Kind, "message", res.Message)
+			}
+			statusResults = append(statusResults, automotivev1alpha1.PluginResult{
+				Plugin:    res.Plugin,
+				Kind:      string(res.Kind),
+				Succeeded: res.Succeeded,
+				Message:   res.Message,
+				Location:  res.Location,
+			})
+		}
+
+		if err := r.Get(ctx, key, fresh); err != nil {
+			log.Error(err, "failed to get ImageBuild to record plugin results")
+			return
+		}
+		patch := client.MergeFrom(fresh.DeepCopy())
+		fresh.Status.Plugins = statusResults
+		if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+			log.Error(err, "failed to record plugin results")
+		}
+	}()
+}
+
+// webhookDeliveryTimeout bounds how long the webhook deliveries of one phase change may take
+const webhookDeliveryTimeout = 2 * time.Minute
+
+// notifyWebhooks posts a phase change of a build to the webhooks of the build and of the
+// OperatorConfig. Deliveries run in the background; a failing webhook is logged and never
+// changes the build.
+func (r *ImageBuildReconciler) notifyWebhooks(imageBuild *automotivev1alpha1.ImageBuild, previousPhase string) {
+	build := imageBuild.DeepCopy()
+	log := r.Log.WithValues("imagebuild", types.NamespacedName{Name: build.Name, Namespace: build.Namespace})
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), webhookDeliveryTimeout)
+		defer cancel()
+
+		// Secrets of build webhooks live with the build, those of OperatorConfig webhooks with the operator
+		type target struct {
+			hook      automotivev1alpha1.Webhook
+			namespace string
+		}
+		var targets []target
+		// webhooks of partner builds would let partners have the controller send requests inside
+		// the cluster; builds naming them fail, and do not notify them when failing either
+		if profile, err := r.partnerIsolation(ctx, build.Namespace); err != nil {
+			log.Error(err, "failed to check the partner profile, skipping the webhooks of the build")
+		} else if profile == nil {
+			for _, hook := range build.Spec.Webhooks {
+				targets = append(targets, target{hook: hook, namespace: build.Namespace})
+			}
+		}
+		operatorConfig := &automotivev1alpha1.OperatorConfig{}
+		if err := r.Get(ctx, types.NamespacedName{Name: "config", Namespace: OperatorNamespace}, operatorConfig); err == nil {
+			for _, hook := range operatorConfig.Spec.Webhooks {
+				targets = append(targets, target{hook: hook, namespace: OperatorNamespace})
+			}
+		}
+		if len(targets) == 0 {
+			return
+		}
+
+		event := pluginEvent(build)
+		payload := webhook.Payload{
+			Event:         webhook.EventPhaseChanged,
+			DeliveryID:    fmt.Sprintf("%s-%s", build.UID, strings.ToLower(build.Status.Phase)),
+			Timestamp:     time.Now().UTC(),
+			PreviousPhase: previousPhase,
+			Build:         event.Build,
+			Artifact:      event.Artifact,
+		}
+		sender := &webhook.Sender{}
+		var wg sync.WaitGroup
+		for _, t := range targets {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				secret, err := r.webhookSecret(ctx, t.namespace, t.hook.SecretRef)
+				if err != nil {
+					log.Error(err, "failed to read webhook secret", "url", t.hook.URL)
+					return
+				}
+				if err := sender.Send(ctx, t.hook.URL, secret, payload); err != nil {
+					log.Error(err, "webhook delivery failed", "url", t.hook.URL, "phase", build.Status.Phase)
+				}
+			}()
+		}
+		wg.Wait()
+	}()
+}
+
+// webhookSecret returns the signing secret a webhook refers to, nil for unsigned webhooks
+func (r *ImageBuildReconciler) webhookSecret(ctx context.Context, namespace string, ref *corev1.SecretKeySelector) ([]byte, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, secret); err != nil {
+		return nil, err
+	}
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no key %s", ref.Name, ref.Key)
+	}
+	return value, nil
+}
+
+// commitStatusTimeout bounds how long posting the commit status of one phase change may take
+const commitStatusTimeout = 2 * time.Minute
+
+// commitStatusState is the state of the commit status of a build in phase
+func commitStatusState(phase string) commitstatus.State {
+	switch phase {
+	case "Building":
+		return commitstatus.StateRunning
+	case "Completed":
+		return commitstatus.StateSuccess
+	case "Failed":
+		return commitstatus.StateFailure
+	case "Cancelled":
+		return commitstatus.StateCancelled
+	default:
+		return commitstatus.StatePending
+	}
+}
+
+// postCommitStatus posts the phase of a build with spec.commitStatus as the status of its commit,
+// comparing completed builds with the golden build of the target branch, and records the outcome
+// in status.commitStatus. Posting runs in the background; a failure is recorded and never changes
+// the build.
+func (r *ImageBuildReconciler) postCommitStatus(imageBuild *automotivev1alpha1.ImageBuild) {
+	if imageBuild.Spec.CommitStatus == nil {
+		return
+	}
+	build := imageBuild.DeepCopy()
+	key := types.NamespacedName{Name: build.Name, Namespace: build.Namespace}
+	log := r.Log.WithValues("imagebuild", key)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), commitStatusTimeout)
+		defer cancel()
+
+		// partner builds may not use the credentials of the operator, see validatePartnerBuild
+		if profile, err := r.partnerIsolation(ctx, build.Namespace); err != nil || profile != nil {
+			if err != nil {
+				log.Error(err, "failed to check the partner profile, not posting the commit status")
+			}
+			return
+		}
+
+		cs := build.Spec.CommitStatus
+		cfg, err := r.commitStatusConfig(ctx)
+		if err == nil && !comparison.RepositoryGranted(cfg, build.Namespace, cs.Repository) {
+			err = fmt.Errorf("spec.commitStatus of the OperatorConfig grants namespace %s no statuses on %s", build.Namespace, cs.Repository)
+		}
+		status := commitstatus.Status{
+			State:       commitStatusState(build.Status.Phase),
+			Context:     comparison.StatusContext(cfg, build),
+			Description: build.Status.Phase,
+		}
+		report := &automotivev1alpha1.CommitStatusReport{State: string(status.State)}
+		switch build.Status.Phase {
+		case "Completed":
+			golden, err := r.goldenBuild(ctx, build)
+			if err != nil {
+				log.Error(err, "failed to find the golden build", "branch", cs.TargetBranch)
+			}
+			var goldenMeasurements *commitstatus.Measurements
+			if golden != nil {
+				m := comparison.Measure(golden)
+				goldenMeasurements = &m
+				report.GoldenBuild = golden.Name
+			}
+			status.Description = commitstatus.Description("Completed", comparison.Measure(build), goldenMeasurements, cs.TargetBranch)
+		case "Failed":
+			if build.Status.Message != "" {
+				status.Description = "Failed: " + build.Status.Message
+			}
+		case "", "Queued":
+			status.Description = "Waiting for a build slot"
+		}
+
+		var poster commitstatus.Poster
+		if err == nil {
+			poster, err = r.commitStatusPoster(ctx, cfg, cs.Provider)
+		}
+		if err == nil {
+			status.TargetURL, err = commitStatusTargetURL(cfg.TargetURL, build)
+		}
+		if err == nil {
+			err = poster.Post(ctx, cs.Repository, cs.SHA, status)
+		}
+		report.Description = status.Description
+		if err != nil {
+			log.Error(err, "failed to post commit status", "repository", cs.Repository, "sha", cs.SHA)
+			report.Error = err.Error()
+		}
+
+		fresh := &automotivev1alpha1.ImageBuild{}
+		if err := r.Get(ctx, key, fresh); err != nil {
+			log.Error(err, "failed to get ImageBuild to record the commit status")
+			return
+		}
+		// a later phase change records its own status
+		if fresh.Status.Phase != build.Status.Phase {
+			return
+		}
+		patch := client.MergeFrom(fresh.DeepCopy())
+		now := metav1.Now()
+		report.UpdateTime = &now
+		fresh.Status.CommitStatus = report
+		if err := r.Status().Patch(ctx, fresh, patch); err != nil {
+			log.Error(err, "failed to record the commit status")
+		}
+	}()
+}
+
+// goldenBuild returns the golden build of the target branch of a build with spec.commitStatus,
+// nil when the build names no target branch or the branch has none
+func (r *ImageBuildReconciler) goldenBuild(ctx context.Context, build *automotivev1alpha1.ImageBuild) (*automotivev1alpha1.ImageBuild, error) {
+	if build.Spec.CommitStatus.TargetBranch == "" {
+		return nil, nil
+	}
+	builds := &automotivev1alpha1.ImageBuildList{}
+	if err := r.List(ctx, builds, client.InNamespace(build.Namespace)); err != nil {
+		return nil, err
+	}
+	return comparison.GoldenBuild(build, build.Spec.CommitStatus.TargetBranch, builds.Items), nil
+}
+
+// commitStatusConfig is spec.commitStatus of the OperatorConfig, which builds need to post statuses
+func (r *ImageBuildReconciler) commitStatusConfig(ctx context.Context) (*automotivev1alpha1.CommitStatusConfig, error) {
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	if err := r.Get(ctx, types.NamespacedName{Name: "config", Namespace: OperatorNamespace}, operatorConfig); err != nil {
+		return nil, fmt.Errorf("failed to read the OperatorConfig: %w", err)
+	}
+	if operatorConfig.Spec.CommitStatus == nil {
+		return nil, fmt.Errorf("the OperatorConfig configures no commit statuses")
+	}
+	return operatorConfig.Spec.CommitStatus, nil
+}
+
+// commitStatusPoster returns the poster for provider with the credentials of cfg
+func (r *ImageBuildReconciler) commitStatusPoster(ctx context.Context, cfg *automotivev1alpha1.CommitStatusConfig, provider string) (commitstatus.Poster, error) {
+	switch {
+	case provider == commitstatus.ProviderGitHub && cfg.GitHub != nil:
+		pemKey, err := r.webhookSecret(ctx, OperatorNamespace, &cfg.GitHub.PrivateKeySecretRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the GitHub App private key: %w", err)
+		}
+		key, err := commitstatus.ParsePrivateKey(pemKey)
+		if err != nil {
+			return nil, err
+		}
+		return &commitstatus.GitHubApp{
+			APIURL:         cfg.GitHub.APIURL,
+			AppID:          cfg.GitHub.AppID,
+			InstallationID: cfg.GitHub.InstallationID,
+			PrivateKey:     key,
+		}, nil
+	case provider == commitstatus.ProviderGitLab && cfg.GitLab != nil:
+		token, err := r.webhookSecret(ctx, OperatorNamespace, &cfg.GitLab.TokenSecretRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the GitLab token: %w", err)
+		}
+		return &commitstatus.GitLab{URL: cfg.GitLab.URL, Token: strings.TrimSpace(string(token))}, nil
+	}
+	return nil, fmt.Errorf("spec.commitStatus of the OperatorConfig configures no %s credentials", provider)
+}
+
+// commitStatusTargetURL renders the link of the status of build, empty without a template
+func commitStatusTargetURL(tmpl string, build *automotivev1alpha1.ImageBuild) (string, error) {
+	if tmpl == "" {
+		return "", nil
+	}
+	t, err := template.New("targetURL").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid spec.commitStatus.targetURL of the OperatorConfig: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, struct{ Name, Namespace string }{build.Name, build.Namespace}); err != nil {
+		return "", fmt.Errorf("invalid spec.commitStatus.targetURL of the OperatorConfig: %w", err)
+	}
+	return b.String(), nil
+}
+
+// pluginEvent describes a finished build to plugins; only completed builds carry an artifact
+func pluginEvent(imageBuild *automotivev1alpha1.ImageBuild) plugin.Event {
+	event := plugin.Event{
+		Build: plugin.Build{
+			Name:         imageBuild.Name,
+			Namespace:    imageBuild.Namespace,
+			Phase:        imageBuild.Status.Phase,
+			Message:      imageBuild.Status.Message,
+			Distro:       imageBuild.Spec.Distro,
+			Target:       imageBuild.Spec.Target,
+			Architecture: imageBuild.Spec.Architecture,
+			ExportFormat: imageBuild.Spec.ExportFormat,
+			Mode:         imageBuild.Spec.Mode,
+			Labels:       imageBuild.Labels,
+		},
+	}
+	if t := imageBuild.Status.StartTime; t != nil {
+		event.Build.StartTime = &t.Time
+	}
+	if t := imageBuild.Status.CompletionTime; t != nil {
+		event.Build.CompletionTime = &t.Time
+	}
+	if imageBuild.Status.Phase == "Completed" && imageBuild.Status.ArtifactFileName != "" {
+		event.Artifact = &plugin.Artifact{
+			FileName:  imageBuild.Status.ArtifactFileName,
+			SizeBytes: imageBuild.Status.ArtifactSizeBytes,
+			SHA256:    imageBuild.Status.ArtifactSHA256,
+			URL:       imageBuild.Status.ArtifactURL,
+			PVCName:   imageBuild.Status.PVCName,
+			Path:      imageBuild.Status.ArtifactPath,
+		}
+	}
+	return event
 }
 
 func (r *ImageBuildReconciler) getOrCreateWorkspacePVC(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (string, error) {
@@ -1013,14 +4969,11 @@ func (r *ImageBuildReconciler) getOrCreateWorkspacePVC(ctx context.Context, imag
 			"old-pvc", imageBuild.Status.PVCName)
 	}
 
-	// Fetch OperatorConfig to get PVC size configuration
-	operatorConfig := &automotivev1alpha1.OperatorConfig{}
-	err := r.Get(ctx, types.NamespacedName{Name: "config", Namespace: OperatorNamespace}, operatorConfig)
+	storageSize := r.workspaceSize(ctx)
 
-	storageSize := resource.MustParse("8Gi")
-	if err == nil && operatorConfig.Spec.OSBuilds != nil && operatorConfig.Spec.OSBuilds.PVCSize != "" {
-		storageSize = resource.MustParse(operatorConfig.Spec.OSBuilds.PVCSize)
-		log.Info("Using OSBuilds PVCSize", "size", operatorConfig.Spec.OSBuilds.PVCSize)
+	accessMode := corev1.ReadWriteOnce
+	if imageBuild.Status.WorkspaceAccessMode != "" {
+		accessMode = corev1.PersistentVolumeAccessMode(imageBuild.Status.WorkspaceAccessMode)
 	}
 
 	timestamp := fmt.Sprintf("%d", time.Now().Unix())
@@ -1047,7 +5000,7 @@ func (r *ImageBuildReconciler) getOrCreateWorkspacePVC(ctx context.Context, imag
 		},
 		Spec: corev1.PersistentVolumeClaimSpec{
 			AccessModes: []corev1.PersistentVolumeAccessMode{
-				corev1.ReadWriteOnce,
+				accessMode,
 			},
 			Resources: corev1.VolumeResourceRequirements{
 				Requests: corev1.ResourceList{
@@ -1057,8 +5010,12 @@ func (r *ImageBuildReconciler) getOrCreateWorkspacePVC(ctx context.Context, imag
 		},
 	}
 
-	if imageBuild.Spec.StorageClass != "" {
-		pvc.Spec.StorageClassName = &imageBuild.Spec.StorageClass
+	class, err := r.workspaceStorageClass(ctx, imageBuild)
+	if err != nil {
+		return "", err
+	}
+	if class != "" {
+		pvc.Spec.StorageClassName = &class
 	}
 
 	if err := r.Create(ctx, pvc); err != nil {
@@ -1069,6 +5026,99 @@ func (r *ImageBuildReconciler) getOrCreateWorkspacePVC(ctx context.Context, imag
 	return uniquePVCName, nil
 }
 
+// workspaceSize is the size of workspace PVCs: spec.osBuilds.pvcSize of the OperatorConfig, or 8Gi
+func (r *ImageBuildReconciler) workspaceSize(ctx context.Context) resource.Quantity {
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	err := r.Get(ctx, types.NamespacedName{Name: "config", Namespace: OperatorNamespace}, operatorConfig)
+	if err == nil && operatorConfig.Spec.OSBuilds != nil && operatorConfig.Spec.OSBuilds.PVCSize != "" {
+		if size, err := resource.ParseQuantity(operatorConfig.Spec.OSBuilds.PVCSize); err == nil {
+			return size
+		}
+	}
+	return resource.MustParse("8Gi")
+}
+
+// workspaceStorageClass is the storage class of the workspace PVC of a build: the class of the
+// partner profile in partner namespaces, else spec.storageClass, else
+// spec.osBuilds.workspaceStorageClass of the OperatorConfig; empty for the default storage class
+func (r *ImageBuildReconciler) workspaceStorageClass(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) (string, error) {
+	profile, err := r.partnerIsolation(ctx, imageBuild.Namespace)
+	if err != nil {
+		return "", err
+	}
+	if profile != nil {
+		return profile.StorageClass, nil
+	}
+	if imageBuild.Spec.StorageClass != "" {
+		return imageBuild.Spec.StorageClass, nil
+	}
+	return r.defaultWorkspaceStorageClass(ctx), nil
+}
+
+// partnerIsolation is spec.osBuilds.partnerIsolation of the OperatorConfig when namespace is one of
+// its partner namespaces, else nil. Errors reading the OperatorConfig other than its absence are
+// returned, so a partner build never runs without its profile because of a passing API error.
+func (r *ImageBuildReconciler) partnerIsolation(ctx context.Context, namespace string) (*automotivev1alpha1.PartnerIsolationConfig, error) {
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	err := r.Get(ctx, types.NamespacedName{Name: "config", Namespace: OperatorNamespace}, operatorConfig)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OperatorConfig configuration: %w", err)
+	}
+	if operatorConfig.Spec.OSBuilds == nil {
+		return nil, nil
+	}
+	profile := operatorConfig.Spec.OSBuilds.PartnerIsolation
+	if profile == nil || !slices.Contains(profile.Namespaces, namespace) {
+		return nil, nil
+	}
+	return profile, nil
+}
+
+// validatePartnerBuild enforces the partner profile on builds in partner namespaces however they
+// were created, as partners may create ImageBuilds directly: what the build API rejects for them,
+// e.g. webhooks or remote files that make the controller or the upload pod send requests inside the
+// cluster, is an invalidSpecError.
+func (r *ImageBuildReconciler) validatePartnerBuild(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) error {
+	profile, err := r.partnerIsolation(ctx, imageBuild.Namespace)
+	if err != nil || profile == nil {
+		return err
+	}
+	spec := imageBuild.Spec
+	var field, reason string
+	switch {
+	case spec.Source != nil:
+		field, reason = "spec.source", "partner builds need the manifest in the build, not a source"
+	case len(spec.Webhooks) > 0:
+		field, reason = "spec.webhooks", "partner builds cannot notify webhooks"
+	case spec.CommitStatus != nil:
+		field, reason = "spec.commitStatus", "partner builds cannot post commit statuses with the credentials of the operator"
+	case spec.RemoteFiles != nil:
+		field, reason = "spec.remoteFiles", "partner builds cannot have files downloaded from URLs"
+	case spec.Debug != nil:
+		field, reason = "spec.debug", "partner builds cannot keep the build pod for debugging"
+	case spec.AutomotiveImageBuilder != "" && spec.AutomotiveImageBuilder != tasks.AutomotiveImageBuilder:
+		field, reason = "spec.automotiveImageBuilder", "partner builds run the default automotive-image-builder image"
+	case spec.StorageClass != "" && spec.StorageClass != profile.StorageClass:
+		field, reason = "spec.storageClass", "partner builds use storage class "+profile.StorageClass
+	default:
+		return nil
+	}
+	return &invalidSpecError{field: field, err: stderrors.New(reason)}
+}
+
+// defaultWorkspaceStorageClass is spec.osBuilds.workspaceStorageClass of the OperatorConfig
+func (r *ImageBuildReconciler) defaultWorkspaceStorageClass(ctx context.Context) string {
+	operatorConfig := &automotivev1alpha1.OperatorConfig{}
+	err := r.Get(ctx, types.NamespacedName{Name: "config", Namespace: OperatorNamespace}, operatorConfig)
+	if err != nil || operatorConfig.Spec.OSBuilds == nil {
+		return ""
+	}
+	return operatorConfig.Spec.OSBuilds.WorkspaceStorageClass
+}
+
 func (r *ImageBuildReconciler) shutdownUploadPod(ctx context.Context, imageBuild *automotivev1alpha1.ImageBuild) error {
 	log := r.Log.WithValues("imagebuild", types.NamespacedName{Name: imageBuild.Name, Namespace: imageBuild.Namespace})
 
@@ -0,0 +1,843 @@
+package imagebuild
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/tasks"
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/buildqueue"
+)
+
+var _ = Describe("publications", func() {
+	const namespace = "builds"
+	var (
+		ctx        context.Context
+		k8sClient  *memClient
+		recorder   *record.FakeRecorder
+		reconciler *ImageBuildReconciler
+		build      *automotivev1alpha1.ImageBuild
+		target     automotivev1alpha1.PublishTarget
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		target = automotivev1alpha1.PublishTarget{
+			Name:     "quay",
+			Registry: &automotivev1alpha1.RegistryPublisher{RepositoryURL: "quay.io/team/images", Secret: "quay-push"},
+		}
+		build = &automotivev1alpha1.ImageBuild{
+			ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: namespace, UID: "build-uid"},
+			Spec: automotivev1alpha1.ImageBuildSpec{
+				Publishers: &automotivev1alpha1.Publishers{Targets: []automotivev1alpha1.PublishTarget{target}},
+			},
+			Status: automotivev1alpha1.ImageBuildStatus{
+				Phase: "Completed", PVCName: "nightly-workspace", ArtifactFileName: "nightly.raw.gz",
+			},
+		}
+		k8sClient = newMemClient(build.DeepCopy())
+		recorder = record.NewFakeRecorder(10)
+		reconciler = &ImageBuildReconciler{Client: k8sClient, Log: logr.Discard(), Recorder: recorder}
+	})
+
+	// finish completes the TaskRun of st's current attempt
+	finish := func(st *automotivev1alpha1.PublicationStatus, succeeded bool, message string, results ...tektonv1.TaskRunResult) {
+		taskRun := &tektonv1.TaskRun{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: st.TaskRunName, Namespace: namespace}, taskRun)).To(Succeed())
+		status := corev1.ConditionFalse
+		if succeeded {
+			status = corev1.ConditionTrue
+		}
+		taskRun.Status.Conditions = duckv1.Conditions{{Type: apis.ConditionSucceeded, Status: status, Message: message}}
+		taskRun.Status.CompletionTime = &metav1.Time{Time: metav1.Now().Time}
+		taskRun.Status.Results = results
+		Expect(k8sClient.Update(ctx, taskRun)).To(Succeed())
+	}
+	result := func(name, value string) tektonv1.TaskRunResult {
+		return tektonv1.TaskRunResult{Name: name, Type: tektonv1.ResultsTypeString, Value: *tektonv1.NewStructuredValues(value)}
+	}
+	taskRunExists := func(name string) bool {
+		return k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, &tektonv1.TaskRun{}) == nil
+	}
+
+	It("starts the first attempt with a TaskRun owned by the build", func() {
+		st := &automotivev1alpha1.PublicationStatus{Name: target.Name}
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		Expect(st.Phase).To(Equal(publicationPublishing))
+		Expect(st.Attempts).To(BeEquivalentTo(1))
+		Expect(st.Type).To(Equal("registry"))
+		Expect(st.TaskRunName).To(Equal("nightly-publish-quay-1"))
+
+		taskRun := &tektonv1.TaskRun{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: st.TaskRunName, Namespace: namespace}, taskRun)).To(Succeed())
+		Expect(metav1.IsControlledBy(taskRun, build)).To(BeTrue())
+		Expect(taskRun.Spec.Workspaces[0].PersistentVolumeClaim.ClaimName).To(Equal("nightly-workspace"))
+		Expect(taskRun.Spec.Params[0].Value.StringVal).To(Equal("nightly.raw.gz"))
+	})
+
+	It("waits for a running attempt", func() {
+		st := &automotivev1alpha1.PublicationStatus{Name: target.Name}
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		Expect(st.Phase).To(Equal(publicationPublishing))
+		Expect(st.Attempts).To(BeEquivalentTo(1))
+	})
+
+	It("records the location of a successful attempt", func() {
+		st := &automotivev1alpha1.PublicationStatus{Name: target.Name}
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		finish(st, true, "", result("location", " quay.io/team/images:nightly\n"))
+
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		Expect(st.Phase).To(Equal(publicationSucceeded))
+		Expect(st.Location).To(Equal("quay.io/team/images:nightly"))
+		Expect(st.Message).To(Equal("Published in attempt 1"))
+		Expect(st.CompletionTime).NotTo(BeNil())
+		Expect(<-recorder.Events).To(HavePrefix("Normal Published"))
+	})
+
+	It("fails without retrying when the registry refuses to overwrite an immutable tag", func() {
+		target.Registry.ImmutableTags = []string{"nightly"}
+		st := &automotivev1alpha1.PublicationStatus{Name: target.Name}
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		// publish_registry.sh exits successfully and reports the refusal in the rejected result
+		finish(st, true, "", result("rejected", "quay.io/team/images:nightly exists and is immutable\n"))
+
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		Expect(st.Phase).To(Equal(publicationFailed))
+		Expect(st.Reason).To(Equal(publicationReasonImmutableTag))
+		Expect(st.Message).To(Equal("quay.io/team/images:nightly exists and is immutable"))
+		Expect(st.Attempts).To(BeEquivalentTo(1))
+		Expect(st.CompletionTime).NotTo(BeNil())
+		Expect(taskRunExists("nightly-publish-quay-2")).To(BeFalse())
+		Expect(<-recorder.Events).To(Equal(
+			"Warning PublishFailed Publishing to target quay was refused: quay.io/team/images:nightly exists and is immutable"))
+
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		Expect(st.Attempts).To(BeEquivalentTo(1))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("retries failed attempts up to the retries of the target", func() {
+		target.Retries = ptr.To[int32](1)
+		st := &automotivev1alpha1.PublicationStatus{Name: target.Name}
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+
+		finish(st, false, "registry unavailable")
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		Expect(st.Phase).To(Equal(publicationPublishing))
+		Expect(st.Attempts).To(BeEquivalentTo(2))
+		Expect(st.TaskRunName).To(Equal("nightly-publish-quay-2"))
+		Expect(<-recorder.Events).To(ContainSubstring("Attempt 1 of publishing to target quay failed, retrying: registry unavailable"))
+
+		finish(st, false, "registry still unavailable")
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		Expect(st.Phase).To(Equal(publicationFailed))
+		Expect(st.Attempts).To(BeEquivalentTo(2))
+		Expect(st.Message).To(Equal("registry still unavailable"))
+		Expect(st.CompletionTime).NotTo(BeNil())
+		Expect(taskRunExists("nightly-publish-quay-3")).To(BeFalse())
+		Expect(<-recorder.Events).To(ContainSubstring("failed after 2 attempts"))
+
+		// a failed publication stays failed
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		Expect(st.Phase).To(Equal(publicationFailed))
+	})
+
+	It("retries twice by default and not at all with zero retries", func() {
+		st := &automotivev1alpha1.PublicationStatus{Name: target.Name}
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		for attempt := 1; attempt <= defaultPublishRetries; attempt++ {
+			finish(st, false, "")
+			Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+			Expect(st.Phase).To(Equal(publicationPublishing))
+		}
+		finish(st, false, "")
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		Expect(st.Phase).To(Equal(publicationFailed))
+		Expect(st.Attempts).To(BeEquivalentTo(defaultPublishRetries + 1))
+		Expect(st.Message).To(Equal("TaskRun failed"))
+
+		target.Retries = ptr.To[int32](0)
+		st = &automotivev1alpha1.PublicationStatus{Name: target.Name}
+		build.Name = "other"
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		finish(st, false, "")
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		Expect(st.Phase).To(Equal(publicationFailed))
+		Expect(st.Attempts).To(BeEquivalentTo(1))
+	})
+
+	It("counts a deleted TaskRun as a failed attempt", func() {
+		target.Retries = ptr.To[int32](0)
+		st := &automotivev1alpha1.PublicationStatus{Name: target.Name}
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, &tektonv1.TaskRun{ObjectMeta: metav1.ObjectMeta{Name: st.TaskRunName, Namespace: namespace}})).To(Succeed())
+
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		Expect(st.Phase).To(Equal(publicationFailed))
+		Expect(st.Message).To(Equal("TaskRun nightly-publish-quay-1 was deleted before it finished"))
+	})
+
+	It("adopts the TaskRun of an attempt whose status update was lost", func() {
+		st := &automotivev1alpha1.PublicationStatus{Name: target.Name}
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+
+		lost := &automotivev1alpha1.PublicationStatus{Name: target.Name}
+		Expect(reconciler.reconcilePublication(ctx, build, target, lost)).To(Succeed())
+		Expect(lost.TaskRunName).To(Equal(st.TaskRunName))
+		Expect(lost.Attempts).To(BeEquivalentTo(1))
+
+		other := build.DeepCopy()
+		other.UID = "other-uid"
+		Expect(reconciler.reconcilePublication(ctx, other, target, &automotivev1alpha1.PublicationStatus{Name: target.Name})).
+			To(MatchError(ContainSubstring("belongs to another ImageBuild")))
+	})
+
+	It("fails when the workspace holding the artifact was released", func() {
+		build.Status.PVCName = ""
+		st := &automotivev1alpha1.PublicationStatus{Name: target.Name}
+		Expect(reconciler.reconcilePublication(ctx, build, target, st)).To(Succeed())
+		Expect(st.Phase).To(Equal(publicationFailed))
+		Expect(st.Message).To(Equal("The workspace holding the artifact was released"))
+		Expect(st.TaskRunName).To(BeEmpty())
+	})
+
+	It("records every target in the status and summarizes them in the Published condition", func() {
+		build.Spec.Publishers.Registry = &automotivev1alpha1.RegistryPublisher{RepositoryURL: "registry.example.com/images"}
+		Expect(k8sClient.Update(ctx, build)).To(Succeed())
+
+		result, err := reconciler.reconcilePublications(ctx, build)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).NotTo(BeZero())
+
+		stored := &automotivev1alpha1.ImageBuild{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: build.Name, Namespace: namespace}, stored)).To(Succeed())
+		Expect(stored.Status.Publications).To(HaveLen(2))
+		Expect(stored.Status.Publications[0].Name).To(Equal("registry"))
+		Expect(stored.Status.Publications[1].Name).To(Equal("quay"))
+		cond := publishedCondition(stored)
+		Expect(cond.Status).To(Equal(metav1.ConditionUnknown))
+		Expect(cond.Message).To(Equal("Publishing to registry, quay"))
+	})
+})
+
+var _ = DescribeTable("publishedCondition",
+	func(phases map[string][2]string, status metav1.ConditionStatus, reason, message string) {
+		build := &automotivev1alpha1.ImageBuild{ObjectMeta: metav1.ObjectMeta{Generation: 3}}
+		for _, name := range []string{"a", "b", "c"} {
+			if p, ok := phases[name]; ok {
+				build.Status.Publications = append(build.Status.Publications,
+					automotivev1alpha1.PublicationStatus{Name: name, Phase: p[0], Reason: p[1]})
+			}
+		}
+		cond := publishedCondition(build)
+		Expect(cond.Type).To(Equal(PublishedConditionType))
+		Expect(cond.ObservedGeneration).To(BeEquivalentTo(3))
+		Expect(cond.Status).To(Equal(status))
+		Expect(cond.Reason).To(Equal(reason))
+		Expect(cond.Message).To(Equal(message))
+	},
+	Entry("all published", map[string][2]string{"a": {publicationSucceeded}, "b": {publicationSucceeded}},
+		metav1.ConditionTrue, "AllTargetsPublished", "Published to 2 targets"),
+	Entry("one still publishing after a failure", map[string][2]string{"a": {publicationFailed}, "b": {publicationPublishing}, "c": {""}},
+		metav1.ConditionUnknown, "Publishing", "Publishing to b, c"),
+	Entry("failed", map[string][2]string{"a": {publicationSucceeded}, "b": {publicationFailed}, "c": {publicationFailed}},
+		metav1.ConditionFalse, "PublishFailed", "Publishing to b, c failed; see status.publications"),
+	Entry("immutable tag before other failures", map[string][2]string{"a": {publicationFailed}, "b": {publicationFailed, publicationReasonImmutableTag}},
+		metav1.ConditionFalse, "ImmutableTagExists", "Publishing to b would overwrite an immutable tag; publish a new version. See status.publications"),
+)
+
+var _ = Describe("publishTaskRunName", func() {
+	It("keeps short names whole", func() {
+		Expect(publishTaskRunName("nightly", "quay", 1)).To(Equal("nightly-publish-quay-1"))
+	})
+
+	It("shortens long build names to fit a DNS label and keeps the attempt", func() {
+		build := strings.Repeat("a", 40) + "-" + strings.Repeat("b", 40)
+		for _, attempt := range []int32{1, 10, 100} {
+			name := publishTaskRunName(build, "s3-archive", attempt)
+			Expect(len(name)).To(BeNumerically("<=", validation.DNS1123LabelMaxLength))
+			Expect(validation.IsDNS1123Label(name)).To(BeEmpty(), name)
+			Expect(name).To(HaveSuffix("-publish-s3-archive-%d", attempt))
+			Expect(publishTaskRunName(build, "s3-archive", attempt)).To(Equal(name))
+		}
+	})
+
+	It("does not leave a dash where the build name was cut", func() {
+		// the cut falls right after the dash of the build name
+		suffix := "-publish-quay-1"
+		build := strings.Repeat("a", validation.DNS1123LabelMaxLength-len(suffix)-1) + "-tail"
+		Expect(publishTaskRunName(build, "quay", 1)).To(Equal(strings.Repeat("a", validation.DNS1123LabelMaxLength-len(suffix)-1) + suffix))
+	})
+})
+
+var _ = DescribeTable("parseBuildEnvironment",
+	func(raw string, want automotivev1alpha1.BuildEnvironment) {
+		env := automotivev1alpha1.BuildEnvironment{}
+		parseBuildEnvironment(raw, &env)
+		Expect(env).To(Equal(want))
+	},
+	Entry("empty result", "", automotivev1alpha1.BuildEnvironment{}),
+	Entry("only whitespace", " \n", automotivev1alpha1.BuildEnvironment{}),
+	Entry("every key",
+		"osbuild=124,aib=1.0.0,selinux=Enforcing,sysctl.user.max_user_namespaces=63419,sysctl.fs.inotify.max_user_watches=8192",
+		automotivev1alpha1.BuildEnvironment{
+			OSBuildVersion: "124", AIBVersion: "1.0.0", SELinux: "Enforcing",
+			Sysctls: map[string]string{"user.max_user_namespaces": "63419", "fs.inotify.max_user_watches": "8192"},
+		}),
+	Entry("whitespace around pairs, keys and values",
+		" osbuild = 124 ,\taib=1.0.0\n, sysctl.fs.inotify.max_user_instances= 128\n",
+		automotivev1alpha1.BuildEnvironment{
+			OSBuildVersion: "124", AIBVersion: "1.0.0",
+			Sysctls: map[string]string{"fs.inotify.max_user_instances": "128"},
+		}),
+	Entry("pairs without a value or an equals sign are skipped",
+		"osbuild=,aib,selinux= ,=Enforcing,,sysctl.user.max_user_namespaces=",
+		automotivev1alpha1.BuildEnvironment{}),
+	Entry("malformed pairs do not hide the others",
+		"garbage,osbuild=124,=,aib=1.0.0",
+		automotivev1alpha1.BuildEnvironment{OSBuildVersion: "124", AIBVersion: "1.0.0"}),
+	Entry("values keep further equals signs", "aib=1.0.0=dev",
+		automotivev1alpha1.BuildEnvironment{AIBVersion: "1.0.0=dev"}),
+	Entry("the last of duplicate keys wins",
+		"osbuild=123,osbuild=124,sysctl.fs.inotify.max_user_watches=1,sysctl.fs.inotify.max_user_watches=2",
+		automotivev1alpha1.BuildEnvironment{
+			OSBuildVersion: "124",
+			Sysctls:        map[string]string{"fs.inotify.max_user_watches": "2"},
+		}),
+	Entry("unknown keys are ignored", "kernel=6.1,OSBUILD=124,sysctl=1",
+		automotivev1alpha1.BuildEnvironment{}),
+)
+
+var _ = Describe("build network policies", func() {
+	rule := func(name string, cidrs, namespaces []string, ports ...int32) automotivev1alpha1.BuildEgressRule {
+		return automotivev1alpha1.BuildEgressRule{Name: name, CIDRs: cidrs, Namespaces: namespaces, Ports: ports}
+	}
+	cfg := &automotivev1alpha1.BuildNetworkPolicyConfig{
+		Egress: []automotivev1alpha1.BuildEgressRule{rule("rpm-mirror", []string{"10.20.0.0/24"}, nil, 443)},
+		Exceptions: []automotivev1alpha1.BuildEgressException{
+			{BuildEgressRule: rule("proxy", nil, []string{"egress-proxy"}, 3128)},
+			{BuildEgressRule: rule("upstream", []string{"0.0.0.0/0"}, nil, 443), BuildNamespaces: []string{"release-eng"}},
+		},
+	}
+	buildIn := func(namespace string, exceptions ...string) *automotivev1alpha1.ImageBuild {
+		return &automotivev1alpha1.ImageBuild{
+			ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: namespace},
+			Spec:       automotivev1alpha1.ImageBuildSpec{NetworkExceptions: exceptions},
+		}
+	}
+
+	DescribeTable("grants the allowlist and the exceptions a build names",
+		func(build *automotivev1alpha1.ImageBuild, names []string) {
+			rules, err := grantedEgress(cfg, build)
+			Expect(err).NotTo(HaveOccurred())
+			var got []string
+			for _, r := range rules {
+				got = append(got, r.Name)
+			}
+			Expect(got).To(Equal(names))
+			Expect(cfg.Egress).To(HaveLen(1), "the allowlist of the OperatorConfig is not changed")
+		},
+		Entry("no exceptions", buildIn("team-a"), []string{"rpm-mirror"}),
+		Entry("an exception of every namespace", buildIn("team-a", "proxy"), []string{"rpm-mirror", "proxy"}),
+		Entry("an exception granted to the namespace", buildIn("release-eng", "upstream", "proxy"), []string{"rpm-mirror", "upstream", "proxy"}),
+	)
+
+	DescribeTable("rejects exceptions a build may not name",
+		func(build *automotivev1alpha1.ImageBuild, want string) {
+			_, err := grantedEgress(cfg, build)
+			var invalid *invalidSpecError
+			Expect(errors.As(err, &invalid)).To(BeTrue(), "%v", err)
+			Expect(invalid.field).To(Equal("spec.networkExceptions"))
+			Expect(err).To(MatchError(ContainSubstring(want)))
+		},
+		Entry("unknown", buildIn("team-a", "proxy", "anywhere"), `no network exception "anywhere" is configured`),
+		Entry("not granted to the namespace", buildIn("team-a", "upstream"), `network exception "upstream" is not granted to namespace team-a`),
+	)
+
+	It("converts egress rules to NetworkPolicy rules after a DNS rule", func() {
+		udp, tcp := corev1.ProtocolUDP, corev1.ProtocolTCP
+		dns, https, squid := intstr.FromInt32(53), intstr.FromInt32(443), intstr.FromInt32(3128)
+		Expect(egressPolicyRules([]automotivev1alpha1.BuildEgressRule{
+			rule("mirrors", []string{"10.20.0.0/24", "10.30.0.0/16"}, nil, 443),
+			// without destinations a NetworkPolicy rule would allow every destination
+			rule("ports-only", nil, nil, 22),
+			rule("proxy", nil, []string{"egress-proxy"}, 3128),
+			rule("registry", []string{"10.20.1.15/32"}, []string{"registry"}),
+		})).To(Equal([]networkingv1.NetworkPolicyEgressRule{
+			{Ports: []networkingv1.NetworkPolicyPort{{Protocol: &udp, Port: &dns}, {Protocol: &tcp, Port: &dns}}},
+			{
+				To: []networkingv1.NetworkPolicyPeer{
+					{IPBlock: &networkingv1.IPBlock{CIDR: "10.20.0.0/24"}},
+					{IPBlock: &networkingv1.IPBlock{CIDR: "10.30.0.0/16"}},
+				},
+				Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &https}},
+			},
+			{
+				To: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{corev1.LabelMetadataName: "egress-proxy"},
+				}}},
+				Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &squid}},
+			},
+			{
+				To: []networkingv1.NetworkPolicyPeer{
+					{IPBlock: &networkingv1.IPBlock{CIDR: "10.20.1.15/32"}},
+					{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{corev1.LabelMetadataName: "registry"}}},
+				},
+			},
+		}))
+		Expect(egressPolicyRules(nil)).To(HaveLen(1), "DNS stays allowed")
+	})
+
+	Describe("applyNetworkPolicy", func() {
+		var (
+			ctx        context.Context
+			k8sClient  *memClient
+			reconciler *ImageBuildReconciler
+			build      *automotivev1alpha1.ImageBuild
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			build = buildIn("team-a", "proxy")
+			build.UID = "build-uid"
+			k8sClient = newMemClient(build.DeepCopy(), &automotivev1alpha1.OperatorConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: OperatorNamespace},
+				Spec:       automotivev1alpha1.OperatorConfigSpec{OSBuilds: &automotivev1alpha1.OSBuildsConfig{NetworkPolicy: cfg}},
+			})
+			reconciler = &ImageBuildReconciler{Client: k8sClient, Log: logr.Discard()}
+		})
+
+		It("selects every pod of the build", func() {
+			Expect(reconciler.applyNetworkPolicy(ctx, build)).To(Succeed())
+			policy := &networkingv1.NetworkPolicy{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "nightly-build-egress", Namespace: "team-a"}, policy)).To(Succeed())
+			Expect(policy.Spec.PodSelector.MatchLabels).To(Equal(map[string]string{"automotive.sdv.cloud.redhat.com/imagebuild-name": "nightly"}))
+			Expect(policy.Spec.PolicyTypes).To(Equal([]networkingv1.PolicyType{networkingv1.PolicyTypeEgress}))
+			Expect(policy.Spec.Egress).To(HaveLen(3))
+			Expect(build.Status.NetworkPolicyName).To(Equal("nightly-build-egress"))
+		})
+
+		It("leaves builds unrestricted without an OperatorConfig", func() {
+			Expect(k8sClient.Delete(ctx, &automotivev1alpha1.OperatorConfig{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: OperatorNamespace}})).To(Succeed())
+			Expect(reconciler.applyNetworkPolicy(ctx, build)).To(Succeed())
+			Expect(build.Status.NetworkPolicyName).To(BeEmpty())
+		})
+
+		It("returns errors reading the OperatorConfig instead of leaving the build unrestricted", func() {
+			k8sClient.getError = func(_ client.ObjectKey, obj client.Object) error {
+				if _, ok := obj.(*automotivev1alpha1.OperatorConfig); ok {
+					return apierrors.NewServiceUnavailable("etcd is down")
+				}
+				return nil
+			}
+			Expect(reconciler.applyNetworkPolicy(ctx, build)).To(MatchError(ContainSubstring("etcd is down")))
+			Expect(build.Status.NetworkPolicyName).To(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("partner builds", func() {
+	var (
+		ctx        context.Context
+		k8sClient  *memClient
+		reconciler *ImageBuildReconciler
+	)
+	partnerBuild := func(spec automotivev1alpha1.ImageBuildSpec) *automotivev1alpha1.ImageBuild {
+		return &automotivev1alpha1.ImageBuild{ObjectMeta: metav1.ObjectMeta{Name: "radio", Namespace: "partner-acme"}, Spec: spec}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		k8sClient = newMemClient(&automotivev1alpha1.OperatorConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: OperatorNamespace},
+			Spec: automotivev1alpha1.OperatorConfigSpec{OSBuilds: &automotivev1alpha1.OSBuildsConfig{
+				PartnerIsolation: &automotivev1alpha1.PartnerIsolationConfig{
+					Namespaces: []string{"partner-acme"}, RuntimeClassName: "kata", StorageClass: "partner-encrypted",
+				},
+			}},
+		})
+		reconciler = &ImageBuildReconciler{Client: k8sClient, Log: logr.Discard()}
+	})
+
+	DescribeTable("rejects ImageBuilds created around the build API",
+		func(spec automotivev1alpha1.ImageBuildSpec, field string) {
+			err := reconciler.validatePartnerBuild(ctx, partnerBuild(spec))
+			var invalid *invalidSpecError
+			Expect(errors.As(err, &invalid)).To(BeTrue(), "%v", err)
+			Expect(invalid.field).To(Equal(field))
+
+			// the same spec is fine outside partner namespaces
+			build := partnerBuild(spec)
+			build.Namespace = "team-a"
+			Expect(reconciler.validatePartnerBuild(ctx, build)).To(Succeed())
+		},
+		Entry("a git source", automotivev1alpha1.ImageBuildSpec{Source: &automotivev1alpha1.BuildSource{
+			Git: &automotivev1alpha1.GitSource{URL: "https://git.example.com/os.git", Path: "a.aib.yml"},
+		}}, "spec.source"),
+		Entry("webhooks", automotivev1alpha1.ImageBuildSpec{Webhooks: []automotivev1alpha1.Webhook{{URL: "http://10.0.0.1/hook"}}}, "spec.webhooks"),
+		Entry("commit statuses", automotivev1alpha1.ImageBuildSpec{CommitStatus: &automotivev1alpha1.CommitStatus{
+			Provider: "github", Repository: "acme/os", SHA: "abc",
+		}}, "spec.commitStatus"),
+		Entry("remote files", automotivev1alpha1.ImageBuildSpec{RemoteFiles: &automotivev1alpha1.RemoteFiles{
+			URLs: []string{"http://169.254.169.254/latest/meta-data"},
+		}}, "spec.remoteFiles"),
+		Entry("debugging", automotivev1alpha1.ImageBuildSpec{Debug: &automotivev1alpha1.BuildDebug{HoldMinutes: 30}}, "spec.debug"),
+		Entry("another builder image", automotivev1alpha1.ImageBuildSpec{AutomotiveImageBuilder: "quay.io/acme/aib:latest"}, "spec.automotiveImageBuilder"),
+		Entry("another storage class", automotivev1alpha1.ImageBuildSpec{StorageClass: "fast"}, "spec.storageClass"),
+	)
+
+	It("accepts builds within the profile", func() {
+		Expect(reconciler.validatePartnerBuild(ctx, partnerBuild(automotivev1alpha1.ImageBuildSpec{
+			StorageClass: "partner-encrypted", AutomotiveImageBuilder: tasks.AutomotiveImageBuilder,
+		}))).To(Succeed())
+	})
+
+	It("returns errors reading the OperatorConfig instead of dropping the profile", func() {
+		k8sClient.getError = func(_ client.ObjectKey, obj client.Object) error {
+			if _, ok := obj.(*automotivev1alpha1.OperatorConfig); ok {
+				return apierrors.NewServiceUnavailable("etcd is down")
+			}
+			return nil
+		}
+		_, err := reconciler.partnerIsolation(ctx, "partner-acme")
+		Expect(err).To(MatchError(ContainSubstring("etcd is down")))
+		Expect(reconciler.validatePartnerBuild(ctx, partnerBuild(automotivev1alpha1.ImageBuildSpec{}))).To(MatchError(ContainSubstring("etcd is down")))
+		_, err = reconciler.workspaceStorageClass(ctx, partnerBuild(automotivev1alpha1.ImageBuildSpec{}))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("pins the workspace to the storage class of the profile", func() {
+		class, err := reconciler.workspaceStorageClass(ctx, partnerBuild(automotivev1alpha1.ImageBuildSpec{}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(class).To(Equal("partner-encrypted"))
+	})
+})
+
+var _ = Describe("remote files", func() {
+	var (
+		ctx        context.Context
+		reconciler *ImageBuildReconciler
+	)
+	build := func(urls ...string) *automotivev1alpha1.ImageBuild {
+		return &automotivev1alpha1.ImageBuild{
+			ObjectMeta: metav1.ObjectMeta{Name: "radio", Namespace: "team-a"},
+			Spec: automotivev1alpha1.ImageBuildSpec{RemoteFiles: &automotivev1alpha1.RemoteFiles{
+				URLs: urls, SecretRef: "s3-credentials",
+			}},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		reconciler = &ImageBuildReconciler{Client: newMemClient(&automotivev1alpha1.OperatorConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: OperatorNamespace},
+			Spec: automotivev1alpha1.OperatorConfigSpec{OSBuilds: &automotivev1alpha1.OSBuildsConfig{
+				RemoteFileHosts: []string{"artifacts.example.com", "ml-models", "minio.example.com"},
+			}},
+		}, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3-credentials", Namespace: "team-a"},
+			Data:       map[string][]byte{"AWS_ENDPOINT_URL": []byte("https://minio.example.com\n")},
+		}), Log: logr.Discard()}
+	})
+
+	It("downloads only from the hosts of the OperatorConfig", func() {
+		Expect(reconciler.validateRemoteFiles(ctx, build("https://artifacts.example.com/a.bin", "s3://ml-models/m.onnx"))).To(Succeed())
+
+		for _, url := range []string{"http://169.254.169.254/latest/meta-data", "http://kubernetes.default.svc/api", "https://other.example.com/a.bin"} {
+			err := reconciler.validateRemoteFiles(ctx, build("https://artifacts.example.com/a.bin", url))
+			var invalid *invalidSpecError
+			Expect(errors.As(err, &invalid)).To(BeTrue(), url)
+			Expect(invalid.field).To(Equal("spec.remoteFiles.urls"))
+		}
+	})
+
+	It("checks the s3 endpoint of the Secret", func() {
+		Expect(reconciler.Update(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3-credentials", Namespace: "team-a"},
+			Data:       map[string][]byte{"AWS_ENDPOINT_URL": []byte("http://169.254.169.254")},
+		})).To(Succeed())
+		err := reconciler.validateRemoteFiles(ctx, build("s3://ml-models/m.onnx"))
+		var invalid *invalidSpecError
+		Expect(errors.As(err, &invalid)).To(BeTrue())
+		Expect(invalid.field).To(Equal("spec.remoteFiles.secretRef"))
+	})
+
+	It("downloads in init containers to the path of each URL", func() {
+		pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "fileserver"}}}}
+		addRemoteFileContainers(pod, build("https://artifacts.example.com/radio/a.bin?sig=x", "s3://ml-models/m.onnx").Spec.RemoteFiles)
+		Expect(pod.Spec.RestartPolicy).To(BeEmpty())
+		Expect(pod.Spec.Containers).To(HaveLen(1))
+		Expect(pod.Spec.InitContainers).To(HaveLen(2))
+		Expect(pod.Spec.InitContainers[0].Name).To(Equal("fetch-0"))
+		Expect(pod.Spec.InitContainers[0].Command[3:]).To(Equal([]string{"fetch", "https://artifacts.example.com/radio/a.bin?sig=x", "remote-files/artifacts.example.com/radio/a.bin"}))
+		Expect(pod.Spec.InitContainers[1].Image).To(ContainSubstring("aws-cli"))
+	})
+
+	It("fails the build with the error of a failed download", func() {
+		b := build("https://artifacts.example.com/a.bin", "s3://ml-models/m.onnx")
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "radio-upload-pod", Namespace: "team-a"},
+			Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{
+				{Name: "fetch-0", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}},
+				{Name: "fetch-1", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+			}},
+		}
+		Expect(reconciler.Create(ctx, pod)).To(Succeed())
+		fetched, failure, err := reconciler.remoteFilesFetched(ctx, b)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetched).To(BeFalse())
+		Expect(failure).To(BeEmpty())
+
+		pod.Status.InitContainerStatuses[1] = corev1.ContainerStatus{
+			Name:                 "fetch-1",
+			State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: "download failed: 403 Forbidden"}},
+		}
+		Expect(reconciler.Update(ctx, pod)).To(Succeed())
+		_, failure, err = reconciler.remoteFilesFetched(ctx, b)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failure).To(Equal("Failed to download s3://ml-models/m.onnx: download failed: 403 Forbidden"))
+	})
+})
+
+var _ = Describe("signingRekorURL", func() {
+	It("records key-based signatures only in the Rekor of the OperatorConfig", func() {
+		ctx := context.Background()
+		key := &automotivev1alpha1.ArtifactSigning{KeySecretRef: "cosign-key"}
+		keyless := &automotivev1alpha1.ArtifactSigning{Keyless: true}
+		reconciler := &ImageBuildReconciler{Client: newMemClient(), Log: logr.Discard()}
+		Expect(reconciler.signingRekorURL(ctx, key)).To(BeEmpty())
+		Expect(reconciler.signingRekorURL(ctx, keyless)).To(Equal(tasks.PublicRekorURL))
+		Expect(reconciler.signingRekorURL(ctx, &automotivev1alpha1.ArtifactSigning{Keyless: true, RekorURL: "https://rekor.team-a.example.com"})).
+			To(Equal("https://rekor.team-a.example.com"))
+
+		reconciler.Client = newMemClient(&automotivev1alpha1.OperatorConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: OperatorNamespace},
+			Spec: automotivev1alpha1.OperatorConfigSpec{OSBuilds: &automotivev1alpha1.OSBuildsConfig{
+				Signing: &automotivev1alpha1.SigningConfig{RekorURL: "https://rekor.example.com"},
+			}},
+		})
+		Expect(reconciler.signingRekorURL(ctx, key)).To(Equal("https://rekor.example.com"))
+		Expect(reconciler.signingRekorURL(ctx, keyless)).To(Equal("https://rekor.example.com"))
+	})
+})
+
+var _ = DescribeTable("priorityRank",
+	func(priority string, rank int) {
+		Expect(priorityRank(priority)).To(Equal(rank))
+	},
+	Entry("high", "high", 2),
+	Entry("normal", "normal", 1),
+	Entry("unset", "", 1),
+	Entry("unknown", "urgent", 1),
+	Entry("low", "low", 0),
+)
+
+// queuedBuild is a build of the build slot tests, created minute minutes after 10:00
+func queuedBuild(namespace, name, phase, priority string, minute int) *automotivev1alpha1.ImageBuild {
+	return &automotivev1alpha1.ImageBuild{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         namespace,
+			UID:               types.UID(namespace + "/" + name),
+			CreationTimestamp: metav1.NewTime(time.Date(2026, 5, 1, 10, minute, 0, 0, time.UTC)),
+		},
+		Spec:   automotivev1alpha1.ImageBuildSpec{Priority: priority},
+		Status: automotivev1alpha1.ImageBuildStatus{Phase: phase},
+	}
+}
+
+var _ = Describe("clusterQueue", func() {
+	admit := func(builds []*automotivev1alpha1.ImageBuild, uid string, limit int) buildqueue.Admission {
+		k8sClient := newMemClient()
+		for _, b := range builds {
+			Expect(k8sClient.Create(context.Background(), b)).To(Succeed())
+		}
+		admission, err := clusterQueue{reader: k8sClient}.Admit(context.Background(), buildqueue.Item{UID: uid}, limit)
+		Expect(err).NotTo(HaveOccurred())
+		return admission
+	}
+
+	DescribeTable("orders the waiting builds",
+		func(builds []*automotivev1alpha1.ImageBuild, order []string) {
+			// one slot: the first build is admitted, the others wait in order
+			Expect(admit(builds, order[0], 1)).To(Equal(buildqueue.Admission{Admitted: true}), order[0])
+			for i, uid := range order[1:] {
+				Expect(admit(builds, uid, 1)).To(Equal(buildqueue.Admission{Position: i + 2, Waiting: len(order)}), uid)
+			}
+		},
+		Entry("by priority before age",
+			[]*automotivev1alpha1.ImageBuild{
+				queuedBuild("a", "low", "Queued", "low", 0),
+				queuedBuild("a", "normal", "Queued", "", 1),
+				queuedBuild("a", "high", "", "high", 2),
+			},
+			[]string{"a/high", "a/normal", "a/low"}),
+		Entry("by creation time within a priority",
+			[]*automotivev1alpha1.ImageBuild{
+				queuedBuild("b", "third", "Queued", "high", 9),
+				queuedBuild("a", "first", "Queued", "high", 3),
+				queuedBuild("c", "second", "Queued", "high", 5),
+			},
+			[]string{"a/first", "c/second", "b/third"}),
+		Entry("by namespace and name when created at the same time",
+			[]*automotivev1alpha1.ImageBuild{
+				queuedBuild("team-b", "x", "Queued", "", 0),
+				queuedBuild("team-a", "y", "Queued", "", 0),
+				queuedBuild("team-a", "x", "Queued", "", 0),
+			},
+			[]string{"team-a/x", "team-a/y", "team-b/x"}),
+	)
+
+	DescribeTable("admits into the slots running builds leave",
+		func(running, limit int, admitted []bool) {
+			var builds []*automotivev1alpha1.ImageBuild
+			for i := 0; i < running; i++ {
+				phase := "Building"
+				if i%2 == 1 {
+					phase = "Uploading"
+				}
+				builds = append(builds, queuedBuild("a", fmt.Sprintf("running-%d", i), phase, "", i))
+			}
+			// ended builds hold no slot
+			builds = append(builds, queuedBuild("a", "done", "Completed", "", 0), queuedBuild("a", "broken", "Failed", "", 0))
+			for i := range admitted {
+				builds = append(builds, queuedBuild("a", fmt.Sprintf("waiting-%d", i), "Queued", "", 10+i))
+			}
+			for i, want := range admitted {
+				uid := fmt.Sprintf("a/waiting-%d", i)
+				admission := admit(builds, uid, limit)
+				Expect(admission.Admitted).To(Equal(want), uid)
+				if !want {
+					Expect(admission.Position).To(Equal(i+1), uid)
+					Expect(admission.Waiting).To(Equal(len(admitted)), uid)
+				}
+			}
+		},
+		Entry("free slots", 1, 3, []bool{true, true, false}),
+		Entry("all slots taken", 2, 2, []bool{false, false}),
+		Entry("more running than the limit", 3, 2, []bool{false}),
+		Entry("no limit", 5, 0, []bool{true, true}),
+	)
+
+	It("admits builds that are not waiting", func() {
+		deleting := queuedBuild("a", "deleting", "Queued", "high", 0)
+		deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		deleting.Finalizers = []string{"test"}
+		builds := []*automotivev1alpha1.ImageBuild{
+			queuedBuild("a", "running", "Building", "", 0),
+			deleting,
+			queuedBuild("a", "waiting", "Queued", "", 1),
+		}
+		Expect(admit(builds, "a/deleting", 1).Admitted).To(BeTrue())
+		Expect(admit(builds, "a/unknown", 1).Admitted).To(BeTrue())
+		Expect(admit(builds, "a/waiting", 1)).To(Equal(buildqueue.Admission{Position: 1, Waiting: 1}))
+	})
+})
+
+// recordingQueue is a buildqueue.Queue holding uids that records the builds released from it
+type recordingQueue struct {
+	uids     []string
+	released []string
+}
+
+func (q *recordingQueue) Admit(context.Context, buildqueue.Item, int) (buildqueue.Admission, error) {
+	return buildqueue.Admission{Admitted: true}, nil
+}
+
+func (q *recordingQueue) Release(_ context.Context, uid string) error {
+	q.released = append(q.released, uid)
+	return nil
+}
+
+func (q *recordingQueue) List(context.Context) ([]string, error) {
+	return q.uids, nil
+}
+
+var _ = Describe("pruneBuildQueue", func() {
+	It("releases the builds that ended or were deleted", func() {
+		reconciler := &ImageBuildReconciler{Client: newMemClient(
+			queuedBuild("a", "new", "", "", 0),
+			queuedBuild("a", "queued", "Queued", "", 0),
+			queuedBuild("a", "uploading", "Uploading", "", 0),
+			queuedBuild("a", "building", "Building", "", 0),
+			queuedBuild("a", "completed", "Completed", "", 0),
+			queuedBuild("a", "failed", "Failed", "", 0),
+		)}
+		queue := &recordingQueue{uids: []string{"a/new", "a/queued", "a/uploading", "a/building", "a/completed", "a/failed", "a/deleted"}}
+
+		removed, err := reconciler.pruneBuildQueue(context.Background(), queue)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(removed).To(Equal(3))
+		Expect(queue.released).To(ConsistOf("a/completed", "a/failed", "a/deleted"))
+	})
+
+	It("does not list builds for an empty queue", func() {
+		reconciler := &ImageBuildReconciler{Client: &memClient{}}
+		removed, err := reconciler.pruneBuildQueue(context.Background(), &recordingQueue{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(removed).To(BeZero())
+	})
+})
+
+var _ = Describe("buildSlotQueuePosition", func() {
+	operatorConfig := func(limit int32) *automotivev1alpha1.OperatorConfig {
+		return &automotivev1alpha1.OperatorConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: OperatorNamespace},
+			Spec: automotivev1alpha1.OperatorConfigSpec{
+				OSBuilds: &automotivev1alpha1.OSBuildsConfig{MaxConcurrentBuilds: limit},
+			},
+		}
+	}
+
+	It("returns the position of a build waiting for a slot", func() {
+		first := queuedBuild("a", "first", "Queued", "", 1)
+		second := queuedBuild("a", "second", "Queued", "", 2)
+		reconciler := &ImageBuildReconciler{Client: newMemClient(operatorConfig(2),
+			queuedBuild("a", "running", "Building", "", 0), first, second)}
+
+		position, waiting, err := reconciler.buildSlotQueuePosition(context.Background(), first)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(position).To(BeZero())
+		Expect(waiting).To(BeZero())
+
+		position, waiting, err = reconciler.buildSlotQueuePosition(context.Background(), second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(position).To(BeEquivalentTo(2))
+		Expect(waiting).To(Equal(2))
+	})
+
+	It("admits every build without a limit", func() {
+		build := queuedBuild("a", "waiting", "Queued", "", 1)
+		for _, k8sClient := range []*memClient{
+			newMemClient(queuedBuild("a", "running", "Building", "", 0), build.DeepCopy()),
+			newMemClient(operatorConfig(0), queuedBuild("a", "running", "Building", "", 0), build.DeepCopy()),
+		} {
+			position, _, err := (&ImageBuildReconciler{Client: k8sClient}).buildSlotQueuePosition(context.Background(), build)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(position).To(BeZero())
+		}
+	})
+})
@@ -0,0 +1,145 @@
+package imagebuild
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+func TestImageBuild(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ImageBuild Controller Suite")
+}
+
+// memClient is an in-memory client.Client for the objects the reconciler functions under test read
+// and write; the envtest suite in internal/controller/test covers whole reconciles
+type memClient struct {
+	client.Client
+	mu      sync.Mutex
+	objects map[string]client.Object
+	uid     int
+	// getError, when set, fails the Gets it returns an error for
+	getError func(key client.ObjectKey, obj client.Object) error
+}
+
+func newMemClient(objs ...client.Object) *memClient {
+	m := &memClient{objects: map[string]client.Object{}}
+	for _, obj := range objs {
+		Expect(m.Create(context.Background(), obj)).To(Succeed())
+	}
+	return m
+}
+
+func memKey(obj runtime.Object, namespace, name string) string {
+	return fmt.Sprintf("%T/%s/%s", obj, namespace, name)
+}
+
+func notFound(obj runtime.Object, name string) error {
+	return apierrors.NewNotFound(schema.GroupResource{Resource: fmt.Sprintf("%T", obj)}, name)
+}
+
+func (m *memClient) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.getError != nil {
+		if err := m.getError(key, obj); err != nil {
+			return err
+		}
+	}
+	stored, ok := m.objects[memKey(obj, key.Namespace, key.Name)]
+	if !ok {
+		return notFound(obj, key.Name)
+	}
+	reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(stored.DeepCopyObject()).Elem())
+	return nil
+}
+
+func (m *memClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memKey(obj, obj.GetNamespace(), obj.GetName())
+	if _, ok := m.objects[key]; ok {
+		return apierrors.NewAlreadyExists(schema.GroupResource{Resource: fmt.Sprintf("%T", obj)}, obj.GetName())
+	}
+	if obj.GetUID() == "" {
+		m.uid++
+		obj.SetUID(types.UID(fmt.Sprintf("uid-%d", m.uid)))
+	}
+	if created := obj.GetCreationTimestamp(); created.IsZero() {
+		obj.SetCreationTimestamp(metav1.Now())
+	}
+	m.objects[key] = obj.DeepCopyObject().(client.Object)
+	return nil
+}
+
+func (m *memClient) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memKey(obj, obj.GetNamespace(), obj.GetName())
+	if _, ok := m.objects[key]; !ok {
+		return notFound(obj, obj.GetName())
+	}
+	m.objects[key] = obj.DeepCopyObject().(client.Object)
+	return nil
+}
+
+func (m *memClient) Delete(_ context.Context, obj client.Object, _ ...client.DeleteOption) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memKey(obj, obj.GetNamespace(), obj.GetName())
+	if _, ok := m.objects[key]; !ok {
+		return notFound(obj, obj.GetName())
+	}
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memClient) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	builds, ok := list.(*automotivev1alpha1.ImageBuildList)
+	if !ok {
+		return fmt.Errorf("memClient cannot list %T", list)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	builds.Items = nil
+	for _, obj := range m.objects {
+		if build, ok := obj.(*automotivev1alpha1.ImageBuild); ok {
+			builds.Items = append(builds.Items, *build.DeepCopy())
+		}
+	}
+	return nil
+}
+
+// Status writes the status as a whole; the reconciler patches the status of objects it just read
+func (m *memClient) Status() client.SubResourceWriter {
+	return memStatusWriter{m}
+}
+
+type memStatusWriter struct {
+	m *memClient
+}
+
+func (w memStatusWriter) Create(context.Context, client.Object, client.Object, ...client.SubResourceCreateOption) error {
+	return fmt.Errorf("memClient cannot create subresources")
+}
+
+func (w memStatusWriter) Update(ctx context.Context, obj client.Object, _ ...client.SubResourceUpdateOption) error {
+	return w.m.Update(ctx, obj)
+}
+
+func (w memStatusWriter) Patch(ctx context.Context, obj client.Object, _ client.Patch, _ ...client.SubResourcePatchOption) error {
+	return w.m.Update(ctx, obj)
+}
@@ -284,7 +284,7 @@ func (r *OperatorConfigReconciler) deployWebUI(ctx context.Context, owner *autom
 
 	// Create/update build-api deployment
 	r.Log.Info("Creating/updating build-api deployment")
-	buildAPIDeployment := r.buildBuildAPIDeployment(isOpenShift)
+	buildAPIDeployment := r.buildBuildAPIDeployment(isOpenShift, owner.Spec.RateLimit, owner.Spec.TrustedProxies, owner.Spec.Audit, owner.Spec.OIDC, owner.Spec.BuildNamespaces, owner.Spec.DrainPeriodSeconds)
 	if err := r.createOrUpdate(ctx, buildAPIDeployment, owner); err != nil {
 		r.Log.Error(err, "Failed to create/update build-api deployment")
 		return fmt.Errorf("failed to create/update build-api deployment: %w", err)
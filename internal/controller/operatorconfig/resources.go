@@ -3,6 +3,9 @@ package operatorconfig
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"slices"
+	"strconv"
+	"strings"
 
 	routev1 "github.com/openshift/api/route/v1"
 	appsv1 "k8s.io/api/apps/v1"
@@ -11,6 +14,9 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/version"
 )
 
 const (
@@ -152,8 +158,84 @@ func (r *OperatorConfigReconciler) buildWebUIContainers(isOpenShift bool) []core
 	return containers
 }
 
+// rateLimitEnv passes the rate limits of the OperatorConfig to the build API
+func rateLimitEnv(rateLimit *automotivev1alpha1.RateLimitConfig) []corev1.EnvVar {
+	if rateLimit == nil {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{Name: "BUILD_API_RATE_LIMIT", Value: strconv.Itoa(int(rateLimit.RequestsPerSecond))},
+		{Name: "BUILD_API_RATE_LIMIT_BURST", Value: strconv.Itoa(int(rateLimit.Burst))},
+		{Name: "BUILD_API_MAX_CONCURRENT_DOWNLOADS", Value: strconv.Itoa(int(rateLimit.MaxConcurrentDownloads))},
+		{Name: "BUILD_API_RATE_LIMIT_PER_IP", Value: strconv.Itoa(int(rateLimit.RequestsPerSecondPerIP))},
+	}
+}
+
+// auditLogDir holds the audit log of the file sink
+const auditLogDir = "/var/log/build-api"
+
+// auditEnv passes the audit sinks of the OperatorConfig to the build API
+func auditEnv(audit *automotivev1alpha1.AuditConfig) []corev1.EnvVar {
+	if audit == nil || len(audit.Sinks) == 0 {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{Name: "BUILD_API_AUDIT_SINKS", Value: strings.Join(audit.Sinks, ",")},
+		{Name: "BUILD_API_AUDIT_FILE", Value: auditLogDir + "/audit.log"},
+	}
+}
+
+// auditFileSink reports whether the build API writes its audit log to a file, which needs a volume
+func auditFileSink(audit *automotivev1alpha1.AuditConfig) bool {
+	return audit != nil && slices.Contains(audit.Sinks, "file")
+}
+
+// auditVolumes returns the volume of the audit log: the claim of the OperatorConfig or an emptyDir
+func auditVolumes(audit *automotivev1alpha1.AuditConfig) []corev1.Volume {
+	if !auditFileSink(audit) {
+		return nil
+	}
+	source := corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+	if audit.ClaimName != "" {
+		source = corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: audit.ClaimName}}
+	}
+	return []corev1.Volume{{Name: "audit-log", VolumeSource: source}}
+}
+
+// oidcCADir holds the CA certificate of the OIDC issuer
+const oidcCADir = "/etc/build-api/oidc-ca"
+
+// oidcEnv passes the OIDC issuer of the OperatorConfig to the build API
+func oidcEnv(oidc *automotivev1alpha1.OIDCConfig) []corev1.EnvVar {
+	if oidc == nil || oidc.IssuerURL == "" {
+		return nil
+	}
+	env := []corev1.EnvVar{
+		{Name: "BUILD_API_OIDC_ISSUER_URL", Value: oidc.IssuerURL},
+		{Name: "BUILD_API_OIDC_AUDIENCE", Value: oidc.Audience},
+		{Name: "BUILD_API_OIDC_USERNAME_CLAIM", Value: oidc.UsernameClaim},
+		{Name: "BUILD_API_OIDC_USERNAME_PREFIX", Value: oidc.UsernamePrefix},
+		{Name: "BUILD_API_OIDC_GROUPS_CLAIM", Value: oidc.GroupsClaim},
+		{Name: "BUILD_API_OIDC_GROUPS_PREFIX", Value: oidc.GroupsPrefix},
+	}
+	if oidc.CAConfigMap != "" {
+		env = append(env, corev1.EnvVar{Name: "BUILD_API_OIDC_CA_FILE", Value: oidcCADir + "/ca.crt"})
+	}
+	return env
+}
+
+// oidcVolumes returns the volume of the CA certificate of the OIDC issuer, if it has one
+func oidcVolumes(oidc *automotivev1alpha1.OIDCConfig) []corev1.Volume {
+	if oidc == nil || oidc.IssuerURL == "" || oidc.CAConfigMap == "" {
+		return nil
+	}
+	return []corev1.Volume{{Name: "oidc-ca", VolumeSource: corev1.VolumeSource{
+		ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: oidc.CAConfigMap}},
+	}}}
+}
+
 // buildBuildAPIContainers builds the container list for build-API deployment, conditionally including oauth-proxy
-func (r *OperatorConfigReconciler) buildBuildAPIContainers(isOpenShift bool) []corev1.Container {
+func (r *OperatorConfigReconciler) buildBuildAPIContainers(isOpenShift bool, rateLimit *automotivev1alpha1.RateLimitConfig, trustedProxies []string, audit *automotivev1alpha1.AuditConfig, oidc *automotivev1alpha1.OIDCConfig, buildNamespaces []string, drainPeriodSeconds int32) []corev1.Container {
 	containers := []corev1.Container{
 		{
 			Name:            "build-api",
@@ -179,6 +261,8 @@ func (r *OperatorConfigReconciler) buildBuildAPIContainers(isOpenShift bool) []c
 						},
 					},
 				},
+				// reported by /v1/info, so clients see a build API left behind by an operator upgrade
+				{Name: "BUILD_API_OPERATOR_VERSION", Value: version.Version},
 			},
 			Ports: []corev1.ContainerPort{
 				{
@@ -187,11 +271,57 @@ func (r *OperatorConfigReconciler) buildBuildAPIContainers(isOpenShift bool) []c
 					Protocol:      corev1.ProtocolTCP,
 				},
 			},
+			// /healthz only fails when the API cannot answer; /readyz also fails when the Kubernetes API,
+			// the ImageBuild permissions or workspace storage are unavailable, taking the pod out of the Service
+			LivenessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					HTTPGet: &corev1.HTTPGetAction{
+						Path: "/healthz",
+						Port: intstr.FromString("http"),
+					},
+				},
+				InitialDelaySeconds: 10,
+				PeriodSeconds:       20,
+				TimeoutSeconds:      5,
+			},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					HTTPGet: &corev1.HTTPGetAction{
+						Path: "/readyz",
+						Port: intstr.FromString("http"),
+					},
+				},
+				InitialDelaySeconds: 5,
+				PeriodSeconds:       10,
+				TimeoutSeconds:      5,
+			},
+			// the image has no shell, so the kubelet sleeps; SIGTERM then starts the drain
+			Lifecycle: &corev1.Lifecycle{
+				PreStop: &corev1.LifecycleHandler{
+					Sleep: &corev1.SleepAction{Seconds: buildAPIPreStopSeconds},
+				},
+			},
 			SecurityContext: &corev1.SecurityContext{
 				AllowPrivilegeEscalation: boolPtr(false),
 			},
 		},
 	}
+	containers[0].Env = append(containers[0].Env, rateLimitEnv(rateLimit)...)
+	containers[0].Env = append(containers[0].Env, auditEnv(audit)...)
+	if auditFileSink(audit) {
+		containers[0].VolumeMounts = append(containers[0].VolumeMounts, corev1.VolumeMount{Name: "audit-log", MountPath: auditLogDir})
+	}
+	containers[0].Env = append(containers[0].Env, oidcEnv(oidc)...)
+	if len(trustedProxies) > 0 {
+		containers[0].Env = append(containers[0].Env, corev1.EnvVar{Name: "BUILD_API_TRUSTED_PROXIES", Value: strings.Join(trustedProxies, ",")})
+	}
+	if len(buildNamespaces) > 0 {
+		containers[0].Env = append(containers[0].Env, corev1.EnvVar{Name: "BUILD_API_NAMESPACES", Value: strings.Join(buildNamespaces, ",")})
+	}
+	containers[0].Env = append(containers[0].Env, corev1.EnvVar{Name: "BUILD_API_DRAIN_PERIOD", Value: strconv.Itoa(int(drainPeriodSeconds)) + "s"})
+	if len(oidcVolumes(oidc)) > 0 {
+		containers[0].VolumeMounts = append(containers[0].VolumeMounts, corev1.VolumeMount{Name: "oidc-ca", MountPath: oidcCADir, ReadOnly: true})
+	}
 
 	// Only add oauth-proxy on OpenShift
 	if isOpenShift {
@@ -215,6 +345,12 @@ func (r *OperatorConfigReconciler) buildBuildAPIContainers(isOpenShift bool) []c
 				"--skip-provider-button=true",
 				"--upstream-timeout=0",
 			},
+			// keeps proxying the requests the build API drains, instead of exiting on SIGTERM with it
+			Lifecycle: &corev1.Lifecycle{
+				PreStop: &corev1.LifecycleHandler{
+					Exec: &corev1.ExecAction{Command: []string{"sleep", strconv.Itoa(buildAPIPreStopSeconds + int(drainPeriodSeconds) + drainGracePeriodSeconds)}},
+				},
+			},
 			Env: []corev1.EnvVar{
 				{
 					Name: "COOKIE_SECRET",
@@ -516,7 +652,22 @@ http {
 	}
 }
 
-func (r *OperatorConfigReconciler) buildBuildAPIDeployment(isOpenShift bool) *appsv1.Deployment {
+// defaultDrainPeriodSeconds is how long a stopping build API replica lets in-flight requests finish
+// when the OperatorConfig sets no drainPeriodSeconds
+const defaultDrainPeriodSeconds = 30
+
+// drainGracePeriodSeconds is the time the pod gets to stop on top of the preStop delay and the drain
+// period; it covers the 5 seconds the build API keeps serving with readiness failing once stopped
+const drainGracePeriodSeconds = 10
+
+// buildAPIPreStopSeconds is how long a deleted build API pod keeps serving before it is stopped,
+// while the endpoints of the Service and the routers drop it
+const buildAPIPreStopSeconds = 5
+
+func (r *OperatorConfigReconciler) buildBuildAPIDeployment(isOpenShift bool, rateLimit *automotivev1alpha1.RateLimitConfig, trustedProxies []string, audit *automotivev1alpha1.AuditConfig, oidc *automotivev1alpha1.OIDCConfig, buildNamespaces []string, drainPeriodSeconds int32) *appsv1.Deployment {
+	if drainPeriodSeconds <= 0 {
+		drainPeriodSeconds = defaultDrainPeriodSeconds
+	}
 	return &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "ado-build-api",
@@ -544,6 +695,8 @@ func (r *OperatorConfigReconciler) buildBuildAPIDeployment(isOpenShift bool) *ap
 				},
 				Spec: corev1.PodSpec{
 					ServiceAccountName: "ado-controller-manager",
+					// the build API drains in-flight requests before it exits
+					TerminationGracePeriodSeconds: int64Ptr(buildAPIPreStopSeconds + int64(drainPeriodSeconds) + drainGracePeriodSeconds),
 					InitContainers: []corev1.Container{
 						{
 							Name:            "init-secrets",
@@ -565,7 +718,8 @@ func (r *OperatorConfigReconciler) buildBuildAPIDeployment(isOpenShift bool) *ap
 							},
 						},
 					},
-					Containers: r.buildBuildAPIContainers(isOpenShift),
+					Containers: r.buildBuildAPIContainers(isOpenShift, rateLimit, trustedProxies, audit, oidc, buildNamespaces, drainPeriodSeconds),
+					Volumes:    append(auditVolumes(audit), oidcVolumes(oidc)...),
 				},
 			},
 		},
@@ -0,0 +1,162 @@
+// Package sharding splits the ImageBuilds among several controller manager replicas. Every build
+// belongs to one shard, chosen by a hash of its namespace and name, and each shard is reconciled
+// by the replicas started with its --shard-id, which elect their own leader. Controllers that are
+// not sharded run on shard 0 only.
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	automotivev1alpha1 "github.com/centos-automotive-suite/automotive-dev-operator/api/v1alpha1"
+)
+
+// Shard is the part of the ImageBuilds a controller manager reconciles
+type Shard struct {
+	// ID is the shard of this manager, from 0 to Count-1
+	ID int
+	// Count is the number of shards; 1 reconciles every build
+	Count int
+}
+
+// Sharded reports whether the builds are split among several managers
+func (s Shard) Sharded() bool {
+	return s.Count > 1
+}
+
+// Validate checks that ID is a shard of Count
+func (s Shard) Validate() error {
+	if s.Count < 1 {
+		return fmt.Errorf("--shards must be at least 1, got %d", s.Count)
+	}
+	if s.ID < 0 || s.ID >= s.Count {
+		return fmt.Errorf("--shard-id must be between 0 and %d, got %d", s.Count-1, s.ID)
+	}
+	return nil
+}
+
+// Of returns the shard of the build namespace/name among count shards
+func Of(namespace, name string, count int) int {
+	if count <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(namespace + "/" + name))
+	return int(h.Sum32() % uint32(count))
+}
+
+// Owns reports whether the build namespace/name belongs to this shard
+func (s Shard) Owns(namespace, name string) bool {
+	return !s.Sharded() || Of(namespace, name, s.Count) == s.ID
+}
+
+// IDFromPodName returns the shard of a StatefulSet pod, the ordinal its name ends with
+func IDFromPodName(podName string) (int, error) {
+	i := strings.LastIndex(podName, "-")
+	if i < 0 {
+		return 0, fmt.Errorf("pod name %q has no ordinal; set --shard-id", podName)
+	}
+	id, err := strconv.Atoi(podName[i+1:])
+	if err != nil {
+		return 0, fmt.Errorf("pod name %q has no ordinal; set --shard-id", podName)
+	}
+	return id, nil
+}
+
+// LeaderElectionID returns the lease the replicas of this shard elect their leader with, so each
+// shard has its own leader
+func (s Shard) LeaderElectionID(base string) string {
+	if !s.Sharded() {
+		return base
+	}
+	prefix, domain, _ := strings.Cut(base, ".")
+	return fmt.Sprintf("%s-shard-%d.%s", prefix, s.ID, domain)
+}
+
+// ControllerName returns the name of a sharded controller. Controller-runtime labels the work
+// queue and reconcile metrics of a controller with its name, so they are reported per shard.
+func (s Shard) ControllerName(base string) string {
+	if !s.Sharded() {
+		return base
+	}
+	return fmt.Sprintf("%s-shard-%d", base, s.ID)
+}
+
+// BuildPredicate passes the events of builds that belong to this shard
+func (s Shard) BuildPredicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return s.Owns(obj.GetNamespace(), obj.GetName())
+	})
+}
+
+// OwnedPredicate passes the events of objects whose controlling ImageBuild belongs to this shard,
+// e.g. the TaskRuns and Pods of its builds
+func (s Shard) OwnedPredicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		owner := metav1.GetControllerOf(obj)
+		if owner == nil || owner.Kind != "ImageBuild" {
+			return false
+		}
+		return s.Owns(obj.GetNamespace(), owner.Name)
+	})
+}
+
+// collectTimeout bounds listing the builds of a scrape
+const collectTimeout = 10 * time.Second
+
+var (
+	shardInfoDesc = prometheus.NewDesc("ado_controller_shard_info",
+		"Shard of the ImageBuilds this controller manager reconciles; 1 for the shard it runs", []string{"shard", "shards"}, nil)
+	shardBuildsDesc = prometheus.NewDesc("ado_controller_shard_builds",
+		"ImageBuilds of the shard by phase", []string{"shard", "phase"}, nil)
+)
+
+// Collector reports the shard of the manager and the builds of the shard by phase, read from the
+// manager's cache when scraped
+type Collector struct {
+	Shard  Shard
+	Reader client.Reader
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- shardInfoDesc
+	ch <- shardBuildsDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	shard := strconv.Itoa(c.Shard.ID)
+	ch <- prometheus.MustNewConstMetric(shardInfoDesc, prometheus.GaugeValue, 1, shard, strconv.Itoa(c.Shard.Count))
+
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	builds := &automotivev1alpha1.ImageBuildList{}
+	if err := c.Reader.List(ctx, builds); err != nil {
+		ch <- prometheus.NewInvalidMetric(shardBuildsDesc, err)
+		return
+	}
+	phases := map[string]int{}
+	for _, b := range builds.Items {
+		if !c.Shard.Owns(b.Namespace, b.Name) {
+			continue
+		}
+		phase := b.Status.Phase
+		if phase == "" {
+			phase = "Pending"
+		}
+		phases[phase]++
+	}
+	for phase, n := range phases {
+		ch <- prometheus.MustNewConstMetric(shardBuildsDesc, prometheus.GaugeValue, float64(n), shard, phase)
+	}
+}
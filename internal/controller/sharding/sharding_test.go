@@ -0,0 +1,115 @@
+package sharding
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Of", func() {
+	It("returns the same shard for a build every time", func() {
+		first := Of("team-a", "nightly-42", 5)
+		for i := 0; i < 10; i++ {
+			Expect(Of("team-a", "nightly-42", 5)).To(Equal(first))
+		}
+		Expect(first).To(BeNumerically(">=", 0))
+		Expect(first).To(BeNumerically("<", 5))
+	})
+
+	It("hashes the namespace with the name", func() {
+		Expect(Of("a", "b/c", 1<<20)).NotTo(Equal(Of("a/b", "c/d", 1<<20)))
+	})
+
+	It("spreads builds over every shard", func() {
+		const count, builds = 4, 4000
+		perShard := make([]int, count)
+		for i := 0; i < builds; i++ {
+			perShard[Of(fmt.Sprintf("ns-%d", i%7), fmt.Sprintf("build-%d", i), count)]++
+		}
+		for id, n := range perShard {
+			// a quarter each, give or take a fifth
+			Expect(n).To(BeNumerically("~", builds/count, builds/count/5), "shard %d", id)
+		}
+	})
+
+	It("puts every build in shard 0 without sharding", func() {
+		Expect(Of("team-a", "nightly-42", 1)).To(Equal(0))
+		Expect(Of("team-a", "nightly-42", 0)).To(Equal(0))
+	})
+})
+
+var _ = Describe("Shard", func() {
+	It("owns every build when there is one shard", func() {
+		shard := Shard{ID: 0, Count: 1}
+		Expect(shard.Sharded()).To(BeFalse())
+		for i := 0; i < 20; i++ {
+			Expect(shard.Owns("team-a", fmt.Sprintf("build-%d", i))).To(BeTrue())
+		}
+	})
+
+	It("owns a build in exactly one of several shards", func() {
+		for i := 0; i < 20; i++ {
+			name := fmt.Sprintf("build-%d", i)
+			owners := 0
+			for id := 0; id < 3; id++ {
+				if (Shard{ID: id, Count: 3}).Owns("team-a", name) {
+					owners++
+				}
+			}
+			Expect(owners).To(Equal(1), name)
+		}
+	})
+
+	DescribeTable("Validate",
+		func(shard Shard, message string) {
+			err := shard.Validate()
+			if message == "" {
+				Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("single shard", Shard{ID: 0, Count: 1}, ""),
+		Entry("last of several", Shard{ID: 2, Count: 3}, ""),
+		Entry("no shards", Shard{ID: 0, Count: 0}, "--shards must be at least 1"),
+		Entry("ID past the count", Shard{ID: 3, Count: 3}, "--shard-id must be between 0 and 2"),
+		Entry("negative ID", Shard{ID: -1, Count: 3}, "--shard-id must be between 0 and 2"),
+	)
+
+	It("names leases and controllers per shard only when sharded", func() {
+		single := Shard{ID: 0, Count: 1}
+		Expect(single.LeaderElectionID("930f6355.sdv.cloud.redhat.com")).To(Equal("930f6355.sdv.cloud.redhat.com"))
+		Expect(single.ControllerName("imagebuild")).To(Equal("imagebuild"))
+
+		shard := Shard{ID: 2, Count: 3}
+		Expect(shard.LeaderElectionID("930f6355.sdv.cloud.redhat.com")).To(Equal("930f6355-shard-2.sdv.cloud.redhat.com"))
+		Expect(shard.ControllerName("imagebuild")).To(Equal("imagebuild-shard-2"))
+	})
+})
+
+var _ = DescribeTable("IDFromPodName",
+	func(podName string, id int, message string) {
+		got, err := IDFromPodName(podName)
+		if message != "" {
+			Expect(err).To(MatchError(ContainSubstring(message)))
+			return
+		}
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got).To(Equal(id))
+	},
+	Entry("StatefulSet pod", "ado-controller-manager-2", 2, ""),
+	Entry("first pod", "ado-controller-manager-0", 0, ""),
+	Entry("no ordinal", "ado-controller-manager", 0, "has no ordinal; set --shard-id"),
+	Entry("Deployment pod", "ado-controller-manager-7d9f8-x2kqz", 0, "has no ordinal"),
+	Entry("trailing dash", "ado-controller-manager-", 0, "has no ordinal"),
+	Entry("no dash", "manager", 0, "has no ordinal"),
+)
+
+var _ = Describe("shard of a pod", func() {
+	It("rejects ordinals past the shard count", func() {
+		id, err := IDFromPodName("ado-controller-manager-5")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Shard{ID: id, Count: 3}.Validate()).To(MatchError(ContainSubstring("--shard-id must be between 0 and 2")))
+	})
+})
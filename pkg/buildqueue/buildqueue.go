@@ -0,0 +1,62 @@
+// Package buildqueue orders the builds waiting for a build slot and admits them into the
+// controller.
+//
+// A Queue decides which build starts once fewer than maxConcurrentBuilds builds run. Admission is
+// exactly once: admitting a build that was already admitted admits it again instead of taking
+// another slot, so a controller that crashed between admitting a build and starting it resumes it,
+// and replicas or shards admitting builds at the same time never exceed the limit when the backend
+// admits atomically, as the Redis backend does.
+package buildqueue
+
+import (
+	"context"
+	"time"
+)
+
+// Item is a build waiting for a build slot
+type Item struct {
+	// UID identifies the build in the queue
+	UID string
+	// Key is the namespace/name of the build, which orders builds created at the same time
+	Key string
+	// Rank orders the builds, higher first, e.g. 2 for high priority, 1 for normal and 0 for low
+	Rank int
+	// Created is when the build was created; older builds go first within a rank
+	Created time.Time
+}
+
+// Admission is the answer of a Queue to a build asking for a slot
+type Admission struct {
+	// Admitted is set when the build holds a slot
+	Admitted bool
+	// Position is the place of a build that was not admitted among the waiting builds, from 1
+	Position int
+	// Waiting is how many builds wait for a slot
+	Waiting int
+}
+
+// Queue holds the builds waiting for a slot and the builds that were admitted
+type Queue interface {
+	// Admit adds item to the queue unless it is there, then admits it when fewer than limit builds
+	// are admitted and no build ahead of it waits. A build that was admitted is admitted again. A
+	// limit of 0 or less admits every build.
+	Admit(ctx context.Context, item Item, limit int) (Admission, error)
+
+	// Release frees the slot of the build with uid, or removes it from the waiting builds
+	Release(ctx context.Context, uid string) error
+
+	// List returns the UIDs of the builds in the queue, waiting or admitted, so builds that ended or
+	// were deleted without being released can be removed; nil when the queue does not keep builds
+	List(ctx context.Context) ([]string, error)
+}
+
+// maxRank bounds Rank in Score
+const maxRank = 100
+
+// Score orders items: a lower score goes first. Higher ranks come before lower ones and, within a
+// rank, older builds before newer ones, to the millisecond.
+func Score(item Item) float64 {
+	rank := min(max(item.Rank, 0), maxRank)
+	// millisecond timestamps stay below 1e13 until the year 2286, well within float64 precision
+	return float64(maxRank-rank)*1e13 + float64(item.Created.UnixMilli())
+}
@@ -0,0 +1,13 @@
+package buildqueue
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestBuildQueue(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Build Queue Suite")
+}
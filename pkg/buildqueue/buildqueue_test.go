@@ -0,0 +1,299 @@
+package buildqueue
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeRedis answers the commands of each connection with its replies, in order, and records the
+// commands it received. A connection is closed once its replies are sent; a connection without
+// replies is dropped after reading a command.
+type fakeRedis struct {
+	listener net.Listener
+	conns    [][]string
+	commands chan []string
+}
+
+// newFakeRedis answers the commands of one connection with replies
+func newFakeRedis(replies ...string) *fakeRedis {
+	return newFakeRedisConns(replies)
+}
+
+func newFakeRedisConns(conns ...[]string) *fakeRedis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	f := &fakeRedis{listener: l, conns: conns, commands: make(chan []string, 16)}
+	go f.serve()
+	return f
+}
+
+func (f *fakeRedis) serve() {
+	for _, replies := range f.conns {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		f.answer(conn, replies)
+	}
+}
+
+func (f *fakeRedis) answer(conn net.Conn, replies []string) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	if len(replies) == 0 {
+		if cmd, err := f.read(rd); err == nil {
+			f.commands <- cmd
+		}
+		return
+	}
+	for _, reply := range replies {
+		cmd, err := f.read(rd)
+		if err != nil {
+			return
+		}
+		f.commands <- cmd
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) read(rd *bufio.Reader) ([]string, error) {
+	cmd, err := readReply(rd)
+	if err != nil {
+		return nil, err
+	}
+	var args []string
+	for _, a := range cmd.([]any) {
+		args = append(args, a.(string))
+	}
+	return args, nil
+}
+
+var _ = Describe("Score", func() {
+	It("should order by rank, then by creation", func() {
+		now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+		high := Score(Item{Rank: 2, Created: now.Add(time.Hour)})
+		normalOld := Score(Item{Rank: 1, Created: now})
+		normalNew := Score(Item{Rank: 1, Created: now.Add(time.Millisecond)})
+		low := Score(Item{Rank: 0, Created: now.Add(-24 * time.Hour)})
+		Expect(high).To(BeNumerically("<", normalOld))
+		Expect(normalOld).To(BeNumerically("<", normalNew))
+		Expect(normalNew).To(BeNumerically("<", low))
+	})
+})
+
+var _ = Describe("Redis", func() {
+	ctx := context.Background()
+
+	It("should authenticate and admit builds with the admission script", func() {
+		f := newFakeRedis("+OK\r\n", "*3\r\n:1\r\n:0\r\n:4\r\n", "*3\r\n:0\r\n:2\r\n:5\r\n")
+		defer f.listener.Close()
+		client := &RedisClient{Address: f.listener.Addr().String(), Username: "ado", Password: "secret"}
+		defer client.Close()
+		q := &Redis{Client: client, KeyPrefix: "test"}
+
+		admission, err := q.Admit(ctx, Item{UID: "uid-1", Key: "builds/b1", Rank: 1}, 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(admission).To(Equal(Admission{Admitted: true, Waiting: 4}))
+		Expect(<-f.commands).To(Equal([]string{"AUTH", "ado", "secret"}))
+		eval := <-f.commands
+		Expect(eval[0]).To(Equal("EVAL"))
+		Expect(eval[2:6]).To(Equal([]string{"3", "test:waiting", "test:admitted", "test:keys"}))
+		Expect(eval[6]).To(Equal("uid-1"))
+		Expect(eval[8:]).To(Equal([]string{"builds/b1", "2"}))
+
+		admission, err = q.Admit(ctx, Item{UID: "uid-2", Key: "builds/b2"}, 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(admission).To(Equal(Admission{Position: 2, Waiting: 5}))
+	})
+
+	It("should list and release waiting and admitted builds", func() {
+		f := newFakeRedis("*1\r\n$5\r\nuid-3\r\n", "*2\r\n$5\r\nuid-1\r\n$5\r\nuid-2\r\n", ":1\r\n")
+		defer f.listener.Close()
+		q := &Redis{Client: &RedisClient{Address: f.listener.Addr().String()}}
+		defer q.Client.Close()
+
+		uids, err := q.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(uids).To(Equal([]string{"uid-3", "uid-1", "uid-2"}))
+		Expect(<-f.commands).To(Equal([]string{"ZRANGE", "ado:buildqueue:waiting", "0", "-1"}))
+		Expect(<-f.commands).To(Equal([]string{"SMEMBERS", "ado:buildqueue:admitted"}))
+		Expect(q.Release(ctx, "uid-1")).To(Succeed())
+		release := <-f.commands
+		Expect(release[len(release)-1]).To(Equal("uid-1"))
+	})
+
+	It("should return error replies as RedisError", func() {
+		f := newFakeRedis("-NOSCRIPT No matching script\r\n")
+		defer f.listener.Close()
+		q := &Redis{Client: &RedisClient{Address: f.listener.Addr().String()}}
+		defer q.Client.Close()
+		_, err := q.Admit(ctx, Item{UID: "uid-1"}, 1)
+		Expect(err).To(MatchError(RedisError("NOSCRIPT No matching script")))
+	})
+
+	It("should keep the connection after error replies", func() {
+		f := newFakeRedis("-ERR unknown command\r\n", ":1\r\n")
+		defer f.listener.Close()
+		client := &RedisClient{Address: f.listener.Addr().String()}
+		defer client.Close()
+
+		_, err := client.Do(ctx, "NOPE")
+		Expect(err).To(MatchError(RedisError("ERR unknown command")))
+		// the fake serves a single connection, so this fails if the client reconnected
+		Expect(client.Do(ctx, "EXISTS", "k")).To(Equal(int64(1)))
+	})
+
+	It("should reject admission replies holding an error", func() {
+		f := newFakeRedis("*3\r\n:1\r\n-ERR script killed\r\n:4\r\n", "*2\r\n:1\r\n:0\r\n", ":1\r\n")
+		defer f.listener.Close()
+		q := &Redis{Client: &RedisClient{Address: f.listener.Addr().String()}}
+		defer q.Client.Close()
+
+		_, err := q.Admit(ctx, Item{UID: "uid-1"}, 1)
+		Expect(err).To(MatchError(ContainSubstring("unexpected admission reply")))
+		_, err = q.Admit(ctx, Item{UID: "uid-1"}, 1)
+		Expect(err).To(MatchError(ContainSubstring("unexpected admission reply")))
+		_, err = q.List(ctx)
+		Expect(err).To(MatchError(ContainSubstring("unexpected ZRANGE reply")))
+	})
+
+	It("should reconnect after the connection dropped", func() {
+		f := newFakeRedisConns(nil, []string{"*3\r\n:1\r\n:0\r\n:0\r\n"})
+		defer f.listener.Close()
+		q := &Redis{Client: &RedisClient{Address: f.listener.Addr().String()}}
+		defer q.Client.Close()
+
+		_, err := q.Admit(ctx, Item{UID: "uid-1"}, 1)
+		Expect(err).To(HaveOccurred())
+		Expect(err).NotTo(BeAssignableToTypeOf(RedisError("")))
+		Expect((<-f.commands)[0]).To(Equal("EVAL"))
+
+		admission, err := q.Admit(ctx, Item{UID: "uid-1"}, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(admission.Admitted).To(BeTrue())
+		Expect((<-f.commands)[0]).To(Equal("EVAL"))
+	})
+
+	It("should give up on a server that does not answer", func() {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer l.Close()
+		go func() {
+			conn, err := l.Accept()
+			if err == nil {
+				defer conn.Close()
+				time.Sleep(time.Second)
+			}
+		}()
+		client := &RedisClient{Address: l.Addr().String(), Timeout: 50 * time.Millisecond}
+		defer client.Close()
+
+		start := time.Now()
+		_, err = client.Do(ctx, "PING")
+		Expect(err).To(MatchError(ContainSubstring("timeout")))
+		Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+	})
+
+	It("should fail commands when the server is unreachable", func() {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		addr := l.Addr().String()
+		Expect(l.Close()).To(Succeed())
+
+		_, err = (&RedisClient{Address: addr}).Do(ctx, "PING")
+		Expect(err).To(MatchError(HavePrefix("redis: ")))
+	})
+
+	It("should not keep a connection whose authentication failed", func() {
+		f := newFakeRedisConns([]string{"-WRONGPASS invalid username-password pair\r\n"}, []string{"+OK\r\n", "+PONG\r\n"})
+		defer f.listener.Close()
+		client := &RedisClient{Address: f.listener.Addr().String(), Password: "secret"}
+		defer client.Close()
+
+		_, err := client.Do(ctx, "PING")
+		Expect(err).To(MatchError(RedisError("WRONGPASS invalid username-password pair")))
+		Expect(client.Do(ctx, "PING")).To(Equal("PONG"))
+	})
+
+	It("should read RESP replies", func() {
+		rd := bufio.NewReader(strings.NewReader("$-1\r\n*2\r\n+OK\r\n-ERR nested\r\n"))
+		v, err := readReply(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v).To(BeNil())
+		v, err = readReply(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v).To(Equal([]any{"OK", RedisError("ERR nested")}))
+
+		rd = bufio.NewReader(strings.NewReader("*2\r\n*2\r\n:3\r\n-ERR deep\r\n$0\r\n\r\n"))
+		v, err = readReply(rd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v).To(Equal([]any{[]any{int64(3), RedisError("ERR deep")}, ""}))
+	})
+})
+
+// The admission script runs in Redis, so it is tested against a server given by
+// BUILDQUEUE_REDIS_ADDR, e.g. "redis-server --port 6390" and BUILDQUEUE_REDIS_ADDR=localhost:6390
+var _ = Describe("Redis admission script", func() {
+	var q *Redis
+
+	BeforeEach(func() {
+		addr := os.Getenv("BUILDQUEUE_REDIS_ADDR")
+		if addr == "" {
+			Skip("BUILDQUEUE_REDIS_ADDR is not set")
+		}
+		q = &Redis{Client: &RedisClient{Address: addr}, KeyPrefix: fmt.Sprintf("ado-test:%d", time.Now().UnixNano())}
+		DeferCleanup(func() {
+			keys := q.keys()
+			_, _ = q.Client.Do(context.Background(), "DEL", keys[0], keys[1], keys[2])
+			_ = q.Client.Close()
+		})
+	})
+
+	item := func(uid string, rank, minute int) Item {
+		return Item{UID: uid, Key: "builds/" + uid, Rank: rank, Created: time.Date(2026, 5, 1, 10, minute, 0, 0, time.UTC)}
+	}
+
+	It("should admit up to the limit in score order and queue the rest", func() {
+		ctx := context.Background()
+		Expect(q.Admit(ctx, item("a", 1, 0), 2)).To(Equal(Admission{Admitted: true}))
+		Expect(q.Admit(ctx, item("b", 1, 1), 2)).To(Equal(Admission{Admitted: true}))
+		Expect(q.Admit(ctx, item("c", 1, 2), 2)).To(Equal(Admission{Position: 1, Waiting: 1}))
+		Expect(q.Admit(ctx, item("d", 1, 3), 2)).To(Equal(Admission{Position: 2, Waiting: 2}))
+		// a high priority build goes ahead of the waiting ones
+		Expect(q.Admit(ctx, item("e", 2, 4), 2)).To(Equal(Admission{Position: 1, Waiting: 3}))
+		Expect(q.Admit(ctx, item("c", 1, 2), 2)).To(Equal(Admission{Position: 2, Waiting: 3}))
+
+		// admitted builds are admitted again without taking another slot
+		Expect(q.Admit(ctx, item("a", 1, 0), 2)).To(Equal(Admission{Admitted: true, Waiting: 3}))
+
+		Expect(q.Release(ctx, "a")).To(Succeed())
+		Expect(q.Admit(ctx, item("c", 1, 2), 2)).To(Equal(Admission{Position: 2, Waiting: 3}))
+		Expect(q.Admit(ctx, item("e", 2, 4), 2)).To(Equal(Admission{Admitted: true, Waiting: 2}))
+		Expect(q.List(ctx)).To(ConsistOf("c", "d", "b", "e"))
+	})
+
+	It("should admit every build without a limit", func() {
+		ctx := context.Background()
+		for i, uid := range []string{"a", "b", "c"} {
+			Expect(q.Admit(ctx, item(uid, 1, i), 0)).To(Equal(Admission{Admitted: true}))
+		}
+	})
+
+	It("should remove released builds that are waiting", func() {
+		ctx := context.Background()
+		Expect(q.Admit(ctx, item("a", 1, 0), 1)).To(Equal(Admission{Admitted: true}))
+		Expect(q.Admit(ctx, item("b", 1, 1), 1)).To(Equal(Admission{Position: 1, Waiting: 1}))
+		Expect(q.Release(ctx, "b")).To(Succeed())
+		Expect(q.List(ctx)).To(Equal([]string{"a"}))
+	})
+})
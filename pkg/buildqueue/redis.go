@@ -0,0 +1,290 @@
+package buildqueue
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRedisKeyPrefix prefixes the keys of the Redis backend
+const DefaultRedisKeyPrefix = "ado:buildqueue"
+
+// admitScript admits a build atomically. KEYS are the sorted set of waiting builds, scored by
+// Score, the set of admitted builds and the hash of build keys; ARGV the UID, score, key and limit.
+// It returns {admitted, position, waiting}.
+const admitScript = `
+local waiting, admitted, keys = KEYS[1], KEYS[2], KEYS[3]
+local uid, score, key, limit = ARGV[1], ARGV[2], ARGV[3], tonumber(ARGV[4])
+if redis.call('SISMEMBER', admitted, uid) == 1 then
+  return {1, 0, redis.call('ZCARD', waiting)}
+end
+if redis.call('ZADD', waiting, 'NX', score, uid) == 1 then
+  redis.call('HSET', keys, uid, key)
+end
+local rank = redis.call('ZRANK', waiting, uid)
+local n = redis.call('ZCARD', waiting)
+if limit <= 0 or rank < limit - redis.call('SCARD', admitted) then
+  redis.call('ZREM', waiting, uid)
+  redis.call('SADD', admitted, uid)
+  return {1, 0, n - 1}
+end
+return {0, rank + 1, n}
+`
+
+// releaseScript removes a build from the queue. KEYS as for admitScript, ARGV the UID.
+const releaseScript = `
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('SREM', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+return 1
+`
+
+// Redis is a Queue kept in a Redis server, shared by every controller replica and shard. Builds
+// wait in a sorted set and are admitted by a script, which Redis runs atomically.
+type Redis struct {
+	Client *RedisClient
+	// KeyPrefix prefixes the keys; defaults to DefaultRedisKeyPrefix
+	KeyPrefix string
+}
+
+var _ Queue = &Redis{}
+
+func (q *Redis) keys() []string {
+	prefix := q.KeyPrefix
+	if prefix == "" {
+		prefix = DefaultRedisKeyPrefix
+	}
+	return []string{prefix + ":waiting", prefix + ":admitted", prefix + ":keys"}
+}
+
+// Admit implements Queue
+func (q *Redis) Admit(ctx context.Context, item Item, limit int) (Admission, error) {
+	keys := q.keys()
+	reply, err := q.Client.Do(ctx, "EVAL", admitScript, "3", keys[0], keys[1], keys[2],
+		item.UID, strconv.FormatFloat(Score(item), 'f', -1, 64), item.Key, strconv.Itoa(limit))
+	if err != nil {
+		return Admission{}, err
+	}
+	values, ok := reply.([]any)
+	if !ok || len(values) != 3 {
+		return Admission{}, fmt.Errorf("redis: unexpected admission reply %v", reply)
+	}
+	var n [3]int64
+	for i, v := range values {
+		if n[i], ok = v.(int64); !ok {
+			return Admission{}, fmt.Errorf("redis: unexpected admission reply %v", reply)
+		}
+	}
+	return Admission{Admitted: n[0] == 1, Position: int(n[1]), Waiting: int(n[2])}, nil
+}
+
+// Release implements Queue
+func (q *Redis) Release(ctx context.Context, uid string) error {
+	keys := q.keys()
+	_, err := q.Client.Do(ctx, "EVAL", releaseScript, "3", keys[0], keys[1], keys[2], uid)
+	return err
+}
+
+// List implements Queue
+func (q *Redis) List(ctx context.Context) ([]string, error) {
+	keys := q.keys()
+	var uids []string
+	for _, cmd := range [][]string{{"ZRANGE", keys[0], "0", "-1"}, {"SMEMBERS", keys[1]}} {
+		reply, err := q.Client.Do(ctx, cmd...)
+		if err != nil {
+			return nil, err
+		}
+		values, ok := reply.([]any)
+		if !ok {
+			return nil, fmt.Errorf("redis: unexpected %s reply %v", cmd[0], reply)
+		}
+		for _, v := range values {
+			if s, ok := v.(string); ok {
+				uids = append(uids, s)
+			}
+		}
+	}
+	return uids, nil
+}
+
+// RedisError is an error reply of the Redis server
+type RedisError string
+
+func (e RedisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// RedisClient sends commands to a Redis server over a single connection, which it opens on the
+// first command and again after a network error. It speaks RESP2, which every Redis version and
+// compatible server understands.
+type RedisClient struct {
+	// Address is host:port of the server
+	Address string
+	// TLS connects with TLS when set
+	TLS *tls.Config
+	// Username and Password authenticate the connection; Username needs Redis 6 ACLs
+	Username string
+	Password string
+	// Timeout bounds dialing and every command without a deadline in its context; defaults to 10s
+	Timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// Do sends a command and returns its reply: a string for simple and bulk strings, nil for a null
+// bulk string, an int64 for integers and a []any for arrays. Error replies are returned as
+// RedisError.
+func (c *RedisClient) Do(ctx context.Context, args ...string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		if err := c.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := c.roundTrip(ctx, args)
+	if err != nil {
+		if _, ok := err.(RedisError); !ok {
+			c.closeLocked()
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+// Close closes the connection; the next command opens a new one
+func (c *RedisClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeLocked()
+}
+
+func (c *RedisClient) closeLocked() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn, c.rd = nil, nil
+	return err
+}
+
+func (c *RedisClient) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return 10 * time.Second
+}
+
+func (c *RedisClient) connect(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: c.timeout()}
+	var conn net.Conn
+	var err error
+	if c.TLS != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: c.TLS}).DialContext(ctx, "tcp", c.Address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.Address)
+	}
+	if err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	c.conn, c.rd = conn, bufio.NewReader(conn)
+	if c.Password == "" {
+		return nil
+	}
+	auth := []string{"AUTH", c.Password}
+	if c.Username != "" {
+		auth = []string{"AUTH", c.Username, c.Password}
+	}
+	if _, err := c.roundTrip(ctx, auth); err != nil {
+		c.closeLocked()
+		return err
+	}
+	return nil
+}
+
+func (c *RedisClient) roundTrip(ctx context.Context, args []string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.timeout())
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	return readReply(c.rd)
+}
+
+// readReply reads one RESP2 reply
+func readReply(rd *bufio.Reader) (any, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, RedisError(line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid integer reply %q", line)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid bulk reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, fmt.Errorf("redis: %w", err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid array reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		values := make([]any, n)
+		for i := range values {
+			if values[i], err = readReply(rd); err != nil {
+				// a nested error reply is a value, not a failure of the command
+				if redisErr, ok := err.(RedisError); ok {
+					values[i] = redisErr
+					continue
+				}
+				return nil, err
+			}
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply %q", line)
+	}
+}
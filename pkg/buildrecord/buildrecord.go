@@ -0,0 +1,140 @@
+// Package buildrecord chains the records of what was built and published into a tamper-evident
+// history.
+//
+// Every Record carries the digest of the record before it, and its own digest covers that link,
+// so changing, removing or reordering a record breaks the chain from there on; Verify finds the
+// first record that does not fit. The digest of a record can be anchored in a Rekor transparency
+// log, so even a history rewritten from the start can be told apart from the one that was logged.
+package buildrecord
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+const (
+	// DigestPrefix is the algorithm prefix of record digests
+	DigestPrefix = "sha256:"
+
+	// SegmentSize is how many records a segment of the history holds; segments are stored as
+	// ConfigMaps, whose size is limited to 1 MiB
+	SegmentSize = 500
+)
+
+// Record is what a build produced and where it went, as the controller saw it once the build
+// finished and every publication ended
+type Record struct {
+	// Sequence numbers the records of a namespace from 1
+	Sequence int64 `json:"sequence"`
+	// Time is when the record was added, RFC 3339
+	Time      string `json:"time"`
+	Namespace string `json:"namespace"`
+	Build     string `json:"build"`
+	UID       string `json:"uid"`
+	// Phase is Completed, Failed or Cancelled
+	Phase        string `json:"phase"`
+	RequestedBy  string `json:"requestedBy,omitempty"`
+	Distro       string `json:"distro,omitempty"`
+	Target       string `json:"target,omitempty"`
+	Architecture string `json:"architecture,omitempty"`
+	// BuilderImage is the automotive-image-builder image, by digest when the node reported it
+	BuilderImage   string `json:"builderImage,omitempty"`
+	Artifact       string `json:"artifact,omitempty"`
+	ArtifactSHA256 string `json:"artifactSHA256,omitempty"`
+	// Signer is the identity the artifact was signed by
+	Signer       string        `json:"signer,omitempty"`
+	Publications []Publication `json:"publications,omitempty"`
+	// Previous is the digest of the record before, empty for the first record
+	Previous string `json:"previous,omitempty"`
+	// Digest is the SHA-256 of the record with an empty Digest, see ComputeDigest
+	Digest string `json:"digest"`
+}
+
+// Publication is a publish target of a build and its outcome
+type Publication struct {
+	Target   string `json:"target"`
+	Phase    string `json:"phase"`
+	Location string `json:"location,omitempty"`
+}
+
+// ComputeDigest returns the digest of r: the SHA-256 of its JSON encoding with an empty Digest
+func ComputeDigest(r Record) string {
+	r.Digest = ""
+	data, err := json.Marshal(r)
+	if err != nil {
+		// a Record only holds strings and numbers
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return DigestPrefix + hex.EncodeToString(sum[:])
+}
+
+// Seal links r to previous, the last record of the history or nil for the first one, and sets its
+// sequence and digest
+func (r *Record) Seal(previous *Record) {
+	r.Sequence = 1
+	r.Previous = ""
+	if previous != nil {
+		r.Sequence = previous.Sequence + 1
+		r.Previous = previous.Digest
+	}
+	r.Digest = ComputeDigest(*r)
+}
+
+// ChainError is the first record of a history that does not fit the records before it
+type ChainError struct {
+	Sequence int64
+	Reason   string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("record %d: %s", e.Sequence, e.Reason)
+}
+
+// Verify checks that records, in any order, form an unbroken chain starting at the first record.
+// It returns a *ChainError for the first record that was changed, removed or reordered.
+func Verify(records []Record) error {
+	sorted := make([]Record, len(records))
+	copy(sorted, records)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Sequence < sorted[j].Sequence })
+
+	var previous *Record
+	for i := range sorted {
+		r := &sorted[i]
+		want := int64(1)
+		if previous != nil {
+			want = previous.Sequence + 1
+		}
+		switch {
+		case r.Sequence != want:
+			return &ChainError{Sequence: want, Reason: fmt.Sprintf("missing, found record %d next", r.Sequence)}
+		case r.Digest != ComputeDigest(*r):
+			return &ChainError{Sequence: r.Sequence, Reason: "content does not match its digest"}
+		case previous == nil && r.Previous != "":
+			return &ChainError{Sequence: r.Sequence, Reason: "first record links to a previous record"}
+		case previous != nil && r.Previous != previous.Digest:
+			return &ChainError{Sequence: r.Sequence, Reason: fmt.Sprintf("links to %s, not to record %d", r.Previous, previous.Sequence)}
+		}
+		previous = r
+	}
+	return nil
+}
+
+// SegmentName is the name of the ConfigMap holding the record with sequence; the history of a
+// namespace is the ConfigMaps prefix-1, prefix-2 and so on
+func SegmentName(prefix string, sequence int64) string {
+	return fmt.Sprintf("%s-%d", prefix, (sequence-1)/SegmentSize+1)
+}
+
+// SegmentKey is the key of the record with sequence in its segment
+func SegmentKey(sequence int64) string {
+	return fmt.Sprintf("%08d.json", sequence)
+}
+
+// AnchorKey is the key of the Rekor anchor of the record with sequence in its segment
+func AnchorKey(sequence int64) string {
+	return fmt.Sprintf("%08d.rekor.json", sequence)
+}
@@ -0,0 +1,13 @@
+package buildrecord
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestBuildRecord(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Build Record Suite")
+}
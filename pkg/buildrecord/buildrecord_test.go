@@ -0,0 +1,140 @@
+package buildrecord
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// chain seals n records of builds named b1, b2 and so on
+func chain(n int) []Record {
+	var records []Record
+	var previous *Record
+	for i := 1; i <= n; i++ {
+		r := Record{
+			Time: "2026-10-16T12:00:00Z", Namespace: "builds", Build: fmt.Sprintf("b%d", i), UID: "uid",
+			Phase: "Completed", ArtifactSHA256: strings.Repeat("a", 64),
+			Publications: []Publication{{Target: "registry", Phase: "Succeeded", Location: "quay.io/acme/os:1"}},
+		}
+		r.Seal(previous)
+		records = append(records, r)
+		previous = &records[len(records)-1]
+	}
+	return records
+}
+
+var _ = Describe("Verify", func() {
+	It("should accept an unbroken chain in any order", func() {
+		records := chain(3)
+		Expect(records[0].Previous).To(BeEmpty())
+		Expect(records[2].Previous).To(Equal(records[1].Digest))
+		Expect(records[2].Sequence).To(Equal(int64(3)))
+		Expect(Verify(records)).To(Succeed())
+		Expect(Verify([]Record{records[2], records[0], records[1]})).To(Succeed())
+		Expect(Verify(nil)).To(Succeed())
+	})
+
+	It("should find a changed record", func() {
+		records := chain(3)
+		records[1].ArtifactSHA256 = strings.Repeat("b", 64)
+		err := Verify(records)
+		Expect(err).To(MatchError(ContainSubstring("record 2: content does not match")))
+	})
+
+	It("should find a record that was changed and sealed again", func() {
+		records := chain(3)
+		records[1].Phase = "Failed"
+		records[1].Digest = ComputeDigest(records[1])
+		var chainErr *ChainError
+		Expect(Verify(records)).To(BeAssignableToTypeOf(chainErr))
+		Expect(Verify(records).(*ChainError).Sequence).To(Equal(int64(3)))
+	})
+
+	It("should find removed records", func() {
+		records := chain(3)
+		Expect(Verify([]Record{records[0], records[2]})).To(MatchError(ContainSubstring("record 2: missing")))
+		Expect(Verify(records[1:])).To(MatchError(ContainSubstring("record 1: missing")))
+	})
+
+	It("should name the segment of a record", func() {
+		Expect(SegmentName("build-records", 1)).To(Equal("build-records-1"))
+		Expect(SegmentName("build-records", SegmentSize)).To(Equal("build-records-1"))
+		Expect(SegmentName("build-records", SegmentSize+1)).To(Equal("build-records-2"))
+		Expect(SegmentKey(42)).To(Equal("00000042.json"))
+	})
+})
+
+var _ = Describe("Anchorer", func() {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	It("should log a hashedrekord entry the public key verifies", func() {
+		record := chain(1)[0]
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/api/v1/log/entries"))
+			var entry hashedRekord
+			Expect(json.NewDecoder(r.Body).Decode(&entry)).To(Succeed())
+			Expect(entry.Kind).To(Equal("hashedrekord"))
+			Expect(DigestPrefix + entry.Spec.Data.Hash.Value).To(Equal(record.Digest))
+
+			sig, _ := base64.StdEncoding.DecodeString(entry.Spec.Signature.Content)
+			pubPEM, _ := base64.StdEncoding.DecodeString(entry.Spec.Signature.PublicKey.Content)
+			block, _ := pem.Decode(pubPEM)
+			pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+			Expect(err).NotTo(HaveOccurred())
+			sum, _ := hex.DecodeString(entry.Spec.Data.Hash.Value)
+			Expect(ecdsa.VerifyASN1(pub.(*ecdsa.PublicKey), sum, sig)).To(BeTrue())
+
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"24296fb24b8ad77a": {"logIndex": 1234, "integratedTime": 1792152000}}`))
+		}))
+		defer srv.Close()
+
+		anchor, err := (&Anchorer{URL: srv.URL, Key: key}).Anchor(context.Background(), record)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(anchor.UUID).To(Equal("24296fb24b8ad77a"))
+		Expect(anchor.LogIndex).To(Equal(int64(1234)))
+		Expect(anchor.IntegratedTime.Unix()).To(Equal(int64(1792152000)))
+	})
+
+	It("should return the existing entry of a digest the log holds", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				w.Header().Set("Location", "/api/v1/log/entries/24296fb24b8ad77a")
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			Expect(r.URL.Path).To(Equal("/api/v1/log/entries/24296fb24b8ad77a"))
+			_, _ = w.Write([]byte(`{"24296fb24b8ad77a": {"logIndex": 7, "integratedTime": 1792152000}}`))
+		}))
+		defer srv.Close()
+
+		anchor, err := (&Anchorer{URL: srv.URL, Key: key}).Anchor(context.Background(), chain(1)[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(anchor.LogIndex).To(Equal(int64(7)))
+	})
+
+	It("should read PKCS #8 and SEC 1 keys", func() {
+		sec1, _ := x509.MarshalECPrivateKey(key)
+		pkcs8, _ := x509.MarshalPKCS8PrivateKey(key)
+		for _, block := range []*pem.Block{{Type: "EC PRIVATE KEY", Bytes: sec1}, {Type: "PRIVATE KEY", Bytes: pkcs8}} {
+			parsed, err := ParsePrivateKey(pem.EncodeToMemory(block))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parsed.Equal(key)).To(BeTrue())
+		}
+		_, err := ParsePrivateKey([]byte("not a key"))
+		Expect(err).To(HaveOccurred())
+	})
+})
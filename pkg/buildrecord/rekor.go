@@ -0,0 +1,194 @@
+package buildrecord
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultRekorURL is the public Rekor instance of sigstore
+const DefaultRekorURL = "https://rekor.sigstore.dev"
+
+// Anchor is the entry of the digest of a record in a Rekor transparency log
+type Anchor struct {
+	RekorURL string `json:"rekorURL"`
+	UUID     string `json:"uuid"`
+	LogIndex int64  `json:"logIndex"`
+	// IntegratedTime is when the log accepted the entry
+	IntegratedTime time.Time `json:"integratedTime"`
+}
+
+// Anchorer records the digests of records in a Rekor log as hashedrekord entries signed with an
+// ECDSA key, which rekor-cli and the Rekor API look up by the digest or the log index
+type Anchorer struct {
+	// URL of the Rekor instance; defaults to DefaultRekorURL
+	URL string
+	Key *ecdsa.PrivateKey
+	// Client defaults to a client with a 30 second timeout
+	Client *http.Client
+}
+
+// ParsePrivateKey reads an unencrypted ECDSA private key in PKCS #8 or SEC 1 PEM form
+func ParsePrivateKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM private key")
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("not an unencrypted PKCS #8 or SEC 1 private key: %w", err)
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is %T, not ECDSA", key)
+	}
+	return ecKey, nil
+}
+
+// hashedRekord is the body of a hashedrekord entry: a signature over data with the given digest
+type hashedRekord struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       struct {
+		Signature struct {
+			Content   string `json:"content"`
+			PublicKey struct {
+				Content string `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+	} `json:"spec"`
+}
+
+// logEntry is an entry as the Rekor API returns it
+type logEntry struct {
+	LogIndex       int64 `json:"logIndex"`
+	IntegratedTime int64 `json:"integratedTime"`
+}
+
+// Anchor signs the digest of r and records it in the Rekor log. A digest the log already holds
+// returns the existing entry.
+func (a *Anchorer) Anchor(ctx context.Context, r Record) (*Anchor, error) {
+	hexDigest, ok := strings.CutPrefix(r.Digest, DigestPrefix)
+	if !ok {
+		return nil, fmt.Errorf("record %d has no %s digest", r.Sequence, DigestPrefix)
+	}
+	sum, err := hex.DecodeString(hexDigest)
+	if err != nil {
+		return nil, fmt.Errorf("record %d: invalid digest: %w", r.Sequence, err)
+	}
+	sig, err := ecdsa.SignASN1(rand.Reader, a.Key, sum)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&a.Key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry hashedRekord
+	entry.APIVersion = "0.0.1"
+	entry.Kind = "hashedrekord"
+	entry.Spec.Signature.Content = base64.StdEncoding.EncodeToString(sig)
+	entry.Spec.Signature.PublicKey.Content = base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+	entry.Spec.Data.Hash.Algorithm = "sha256"
+	entry.Spec.Data.Hash.Value = hexDigest
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(a.URL, "/")
+	if base == "" {
+		base = DefaultRekorURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/api/v1/log/entries", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := a.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusOK:
+		return readEntry(resp.Body, base)
+	case http.StatusConflict:
+		// the log holds the digest already; Location is its entry
+		location := resp.Header.Get("Location")
+		if location == "" {
+			return nil, fmt.Errorf("rekor: entry exists but no location was returned")
+		}
+		return a.getEntry(ctx, base, location)
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("rekor: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+}
+
+func (a *Anchorer) getEntry(ctx context.Context, base, location string) (*Anchor, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("rekor: invalid entry location %q: %w", location, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.ResolveReference(ref).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := a.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rekor: fetching existing entry: %s", resp.Status)
+	}
+	return readEntry(resp.Body, base)
+}
+
+// readEntry reads the single entry of a Rekor response, keyed by its UUID
+func readEntry(r io.Reader, base string) (*Anchor, error) {
+	var entries map[string]logEntry
+	if err := json.NewDecoder(io.LimitReader(r, 1<<20)).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("rekor: invalid response: %w", err)
+	}
+	for uuid, e := range entries {
+		return &Anchor{RekorURL: base, UUID: uuid, LogIndex: e.LogIndex, IntegratedTime: time.Unix(e.IntegratedTime, 0).UTC()}, nil
+	}
+	return nil, fmt.Errorf("rekor: response holds no entry")
+}
+
+func (a *Anchorer) client() *http.Client {
+	if a.Client != nil {
+		return a.Client
+	}
+	return &http.Client{Timeout: 30 * time.Second}
+}
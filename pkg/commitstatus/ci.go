@@ -0,0 +1,67 @@
+package commitstatus
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// CI is the commit a CI job builds, as its environment describes it
+type CI struct {
+	Provider   string
+	Repository string
+	SHA        string
+	// Branch is set for jobs of a branch, TargetBranch for jobs of a pull or merge request
+	Branch       string
+	TargetBranch string
+}
+
+// FromCI reads the commit of the GitHub Actions or GitLab CI job running with the environment
+// getenv reads; nil outside of these. Jobs of pull requests report the head commit of the pull
+// request rather than the merge commit GitHub checks out, so the status shows on the pull request.
+func FromCI(getenv func(string) string) *CI {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		ci := &CI{Provider: ProviderGitHub, Repository: getenv("GITHUB_REPOSITORY"), SHA: getenv("GITHUB_SHA")}
+		if base := getenv("GITHUB_BASE_REF"); base != "" {
+			ci.TargetBranch = base
+			if head := pullRequestHead(getenv("GITHUB_EVENT_PATH")); head != "" {
+				ci.SHA = head
+			}
+		} else if strings.HasPrefix(getenv("GITHUB_REF"), "refs/heads/") {
+			ci.Branch = getenv("GITHUB_REF_NAME")
+		}
+		return ci
+	case getenv("GITLAB_CI") == "true":
+		return &CI{
+			Provider:     ProviderGitLab,
+			Repository:   getenv("CI_PROJECT_PATH"),
+			SHA:          getenv("CI_COMMIT_SHA"),
+			Branch:       getenv("CI_COMMIT_BRANCH"),
+			TargetBranch: getenv("CI_MERGE_REQUEST_TARGET_BRANCH_NAME"),
+		}
+	}
+	return nil
+}
+
+// pullRequestHead returns the head commit of the pull request of a GitHub Actions event file
+func pullRequestHead(eventPath string) string {
+	if eventPath == "" {
+		return ""
+	}
+	data, err := os.ReadFile(eventPath)
+	if err != nil {
+		return ""
+	}
+	var event struct {
+		PullRequest struct {
+			Head struct {
+				SHA string `json:"sha"`
+			} `json:"head"`
+		} `json:"pull_request"`
+	}
+	if json.Unmarshal(data, &event) != nil {
+		return ""
+	}
+	return event.PullRequest.Head.SHA
+}
@@ -0,0 +1,290 @@
+// Package commitstatus reports the outcome of image builds as commit statuses on GitHub and
+// GitLab, so pull and merge requests that change a manifest show whether the image still builds
+// and how it compares with the image of the branch they target.
+//
+// A Poster sets the status of a commit: GitHubApp authenticates as an installation of a GitHub
+// App, GitLab with an access token. Compare and Description turn the measurements of a build and
+// of the golden build of the target branch into the deltas and the one-line summary of the status.
+package commitstatus
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State is the state of a commit status
+type State string
+
+const (
+	StatePending   State = "pending"
+	StateRunning   State = "running"
+	StateSuccess   State = "success"
+	StateFailure   State = "failure"
+	StateError     State = "error"
+	StateCancelled State = "cancelled"
+)
+
+// Providers that commit statuses can be posted to
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+)
+
+// maxDescription is the longest description GitHub accepts
+const maxDescription = 140
+
+// Status is the status of a commit for one check
+type Status struct {
+	State State
+	// Context names the check, e.g. automotive/qemu-x86_64; statuses with the same context replace
+	// each other
+	Context     string
+	Description string
+	// TargetURL is linked from the status, e.g. to the build in the web UI
+	TargetURL string
+}
+
+// Poster sets the status of commit sha of repository, owner/name on GitHub or the path of the
+// project on GitLab
+type Poster interface {
+	Post(ctx context.Context, repository, sha string, status Status) error
+}
+
+// GitHubApp posts statuses as an installation of a GitHub App, which needs the commit statuses
+// write permission on the repositories
+type GitHubApp struct {
+	// APIURL defaults to https://api.github.com; GitHub Enterprise Server uses https://<host>/api/v3
+	APIURL         string
+	AppID          int64
+	InstallationID int64
+	PrivateKey     *rsa.PrivateKey
+	// Client defaults to a client with a 10 second timeout
+	Client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Post sets the status of a commit with the statuses API
+func (g *GitHubApp) Post(ctx context.Context, repository, sha string, status Status) error {
+	owner, name, ok := strings.Cut(repository, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid GitHub repository %q, expected owner/name", repository)
+	}
+	token, err := g.installationToken(ctx)
+	if err != nil {
+		return err
+	}
+	state := status.State
+	switch state {
+	case StateRunning:
+		state = StatePending
+	case StateCancelled:
+		state = StateError
+	}
+	body := map[string]string{
+		"state":       string(state),
+		"context":     status.Context,
+		"description": truncate(status.Description),
+	}
+	if status.TargetURL != "" {
+		body["target_url"] = status.TargetURL
+	}
+	endpoint := g.apiURL() + "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(name) + "/statuses/" + url.PathEscape(sha)
+	return postJSON(ctx, g.client(), endpoint, map[string]string{
+		"Authorization": "Bearer " + token,
+		"Accept":        "application/vnd.github+json",
+	}, body, nil)
+}
+
+func (g *GitHubApp) apiURL() string {
+	if g.APIURL == "" {
+		return "https://api.github.com"
+	}
+	return strings.TrimSuffix(g.APIURL, "/")
+}
+
+func (g *GitHubApp) client() *http.Client {
+	if g.Client == nil {
+		return &http.Client{Timeout: 10 * time.Second}
+	}
+	return g.Client
+}
+
+// installationToken returns an access token of the installation, reusing it until shortly
+// before it expires
+func (g *GitHubApp) installationToken(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && time.Until(g.expires) > time.Minute {
+		return g.token, nil
+	}
+	jwt, err := AppJWT(g.AppID, g.PrivateKey, time.Now())
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	endpoint := g.apiURL() + "/app/installations/" + strconv.FormatInt(g.InstallationID, 10) + "/access_tokens"
+	if err := postJSON(ctx, g.client(), endpoint, map[string]string{
+		"Authorization": "Bearer " + jwt,
+		"Accept":        "application/vnd.github+json",
+	}, nil, &out); err != nil {
+		return "", fmt.Errorf("GitHub App installation token: %w", err)
+	}
+	g.token, g.expires = out.Token, out.ExpiresAt
+	return g.token, nil
+}
+
+// AppJWT returns the JSON Web Token a GitHub App authenticates with, valid for 9 minutes from now
+// with an issue time a minute early against clock drift
+func AppJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	if key == nil {
+		return "", fmt.Errorf("no GitHub App private key")
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// ParsePrivateKey reads the PEM encoded private key of a GitHub App, in PKCS #1 as GitHub
+// generates it or PKCS #8
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key of a GitHub App must be an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// GitLab posts statuses with a project, group or personal access token with the api scope
+type GitLab struct {
+	// URL defaults to https://gitlab.com
+	URL   string
+	Token string
+	// Client defaults to a client with a 10 second timeout
+	Client *http.Client
+}
+
+// Post sets the status of a commit with the commit statuses API
+func (g *GitLab) Post(ctx context.Context, repository, sha string, status Status) error {
+	if strings.Trim(repository, "/") == "" {
+		return fmt.Errorf("no GitLab project")
+	}
+	state := string(status.State)
+	switch status.State {
+	case StateFailure, StateError:
+		state = "failed"
+	case StateCancelled:
+		state = "canceled"
+	}
+	body := map[string]string{
+		"state":       state,
+		"name":        status.Context,
+		"description": truncate(status.Description),
+	}
+	if status.TargetURL != "" {
+		body["target_url"] = status.TargetURL
+	}
+	base := "https://gitlab.com"
+	if g.URL != "" {
+		base = strings.TrimSuffix(g.URL, "/")
+	}
+	endpoint := base + "/api/v4/projects/" + url.PathEscape(strings.Trim(repository, "/")) + "/statuses/" + url.PathEscape(sha)
+	client := g.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	err := postJSON(ctx, client, endpoint, map[string]string{"PRIVATE-TOKEN": g.Token}, body, nil)
+	// GitLab refuses to set the state a status already has
+	if err != nil && strings.Contains(err.Error(), "Cannot transition status") {
+		return nil
+	}
+	return err
+}
+
+// postJSON posts body and decodes the response into out unless out is nil
+func postJSON(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, body, out any) error {
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "automotive-dev-operator-commit-status")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s answered %s: %s", endpoint, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// truncate shortens a description to what GitHub accepts
+func truncate(s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxDescription {
+		return s
+	}
+	return string(runes[:maxDescription-1]) + "…"
+}
@@ -0,0 +1,13 @@
+package commitstatus
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCommitStatus(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CommitStatus Suite")
+}
@@ -0,0 +1,176 @@
+package commitstatus
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GitHubApp", func() {
+	var key *rsa.PrivateKey
+
+	BeforeEach(func() {
+		var err error
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should sign an app JWT", func() {
+		now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+		jwt, err := AppJWT(42, key, now)
+		Expect(err).NotTo(HaveOccurred())
+		parts := strings.Split(jwt, ".")
+		Expect(parts).To(HaveLen(3))
+		claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(claims)).To(MatchJSON(`{"iat":1792151940,"exp":1792152540,"iss":"42"}`))
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		Expect(err).NotTo(HaveOccurred())
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		Expect(rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature)).To(Succeed())
+	})
+
+	It("should read PKCS #1 and PKCS #8 keys", func() {
+		pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		parsed, err := ParsePrivateKey(pkcs1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed.Equal(key)).To(BeTrue())
+
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		parsed, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed.Equal(key)).To(BeTrue())
+
+		_, err = ParsePrivateKey([]byte("not a key"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should post statuses with a cached installation token", func() {
+		var tokens atomic.Int32
+		var got map[string]string
+		var path, auth string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/app/installations/7/access_tokens" {
+				tokens.Add(1)
+				Expect(r.Header.Get("Authorization")).To(HavePrefix("Bearer ey"))
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"token":"ghs_1","expires_at":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `"}`))
+				return
+			}
+			path, auth = r.URL.Path, r.Header.Get("Authorization")
+			Expect(json.NewDecoder(r.Body).Decode(&got)).To(Succeed())
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer srv.Close()
+
+		app := &GitHubApp{APIURL: srv.URL, AppID: 1, InstallationID: 7, PrivateKey: key}
+		status := Status{State: StateRunning, Context: "automotive/qemu", Description: "Building", TargetURL: "https://ui/builds/b"}
+		Expect(app.Post(context.Background(), "acme/manifests", "abc123", status)).To(Succeed())
+		Expect(path).To(Equal("/repos/acme/manifests/statuses/abc123"))
+		Expect(auth).To(Equal("Bearer ghs_1"))
+		Expect(got).To(Equal(map[string]string{"state": "pending", "context": "automotive/qemu", "description": "Building", "target_url": "https://ui/builds/b"}))
+
+		status.State = StateCancelled
+		Expect(app.Post(context.Background(), "acme/manifests", "abc123", status)).To(Succeed())
+		Expect(got["state"]).To(Equal("error"))
+		Expect(tokens.Load()).To(Equal(int32(1)))
+
+		Expect(app.Post(context.Background(), "manifests", "abc123", status)).To(MatchError(ContainSubstring("owner/name")))
+	})
+})
+
+var _ = Describe("GitLab", func() {
+	It("should post statuses to the project", func() {
+		var got map[string]string
+		var path, token string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path, token = r.URL.EscapedPath(), r.Header.Get("PRIVATE-TOKEN")
+			Expect(json.NewDecoder(r.Body).Decode(&got)).To(Succeed())
+			if got["state"] == "running" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"message":"Cannot transition status via :run from :running"}`))
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer srv.Close()
+
+		gitlab := &GitLab{URL: srv.URL, Token: "glpat"}
+		Expect(gitlab.Post(context.Background(), "acme/os/manifests", "abc123", Status{State: StateError, Context: "automotive/qemu"})).To(Succeed())
+		Expect(path).To(Equal("/api/v4/projects/acme%2Fos%2Fmanifests/statuses/abc123"))
+		Expect(token).To(Equal("glpat"))
+		Expect(got).To(HaveKeyWithValue("state", "failed"))
+		Expect(got).To(HaveKeyWithValue("name", "automotive/qemu"))
+
+		Expect(gitlab.Post(context.Background(), "acme/os/manifests", "abc123", Status{State: StateRunning})).To(Succeed())
+	})
+})
+
+var _ = Describe("Description", func() {
+	build := Measurements{ArtifactBytes: 3 << 20, RootFSBytes: 900 << 20, Boot: 8200 * time.Millisecond, BootAccelerator: "kvm"}
+
+	It("should describe the deltas to the golden build", func() {
+		golden := Measurements{ArtifactBytes: 2 << 20, RootFSBytes: 901 << 20, Boot: 8500 * time.Millisecond, BootAccelerator: "kvm"}
+		Expect(Description("Completed", build, &golden, "main")).To(Equal(
+			"Completed: artifact 3.0 MiB (+1.0 MiB), rootfs 900.0 MiB (-1.0 MiB), boot 8.2s (-0.3s) vs main"))
+	})
+
+	It("should not compare boot times of different accelerators", func() {
+		golden := Measurements{ArtifactBytes: 3 << 20, Boot: time.Second, BootAccelerator: "tcg"}
+		d := Compare(build, golden)
+		Expect(*d.ArtifactBytes).To(BeZero())
+		Expect(d.RootFSBytes).To(BeNil())
+		Expect(d.Boot).To(BeNil())
+	})
+
+	It("should tell when the branch has no golden build", func() {
+		Expect(Description("Completed", Measurements{ArtifactBytes: 512}, nil, "main")).To(Equal("Completed: artifact 512 B; no golden build of main"))
+		long := Description("Failed: "+strings.Repeat("x", 200), Measurements{}, nil, "main")
+		Expect([]rune(long)).To(HaveLen(140))
+		Expect(long).To(HaveSuffix("x…"))
+	})
+})
+
+var _ = Describe("FromCI", func() {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+
+	It("should read GitHub Actions pull request jobs", func() {
+		event := filepath.Join(GinkgoT().TempDir(), "event.json")
+		Expect(os.WriteFile(event, []byte(`{"pull_request":{"head":{"sha":"feed123"}}}`), 0o600)).To(Succeed())
+		Expect(FromCI(env(map[string]string{
+			"GITHUB_ACTIONS": "true", "GITHUB_REPOSITORY": "acme/manifests", "GITHUB_SHA": "merge123",
+			"GITHUB_BASE_REF": "main", "GITHUB_REF": "refs/pull/7/merge", "GITHUB_EVENT_PATH": event,
+		}))).To(Equal(&CI{Provider: "github", Repository: "acme/manifests", SHA: "feed123", TargetBranch: "main"}))
+	})
+
+	It("should read GitHub Actions branch jobs and GitLab CI jobs", func() {
+		Expect(FromCI(env(map[string]string{
+			"GITHUB_ACTIONS": "true", "GITHUB_REPOSITORY": "acme/manifests", "GITHUB_SHA": "abc1234",
+			"GITHUB_REF": "refs/heads/main", "GITHUB_REF_NAME": "main",
+		}))).To(Equal(&CI{Provider: "github", Repository: "acme/manifests", SHA: "abc1234", Branch: "main"}))
+		Expect(FromCI(env(map[string]string{
+			"GITLAB_CI": "true", "CI_PROJECT_PATH": "acme/os/manifests", "CI_COMMIT_SHA": "abc1234",
+			"CI_MERGE_REQUEST_TARGET_BRANCH_NAME": "main",
+		}))).To(Equal(&CI{Provider: "gitlab", Repository: "acme/os/manifests", SHA: "abc1234", TargetBranch: "main"}))
+		Expect(FromCI(env(nil))).To(BeNil())
+	})
+})
@@ -0,0 +1,105 @@
+package commitstatus
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Measurements are what a build measured about its image; zero values were not measured
+type Measurements struct {
+	// ArtifactBytes is the size of the compressed artifact
+	ArtifactBytes int64
+	// RootFSBytes is the size of the root filesystem content, measured for builds with a size budget
+	RootFSBytes int64
+	// Boot is the total boot time of the boot test, and BootAccelerator kvm or tcg
+	Boot            time.Duration
+	BootAccelerator string
+}
+
+// Deltas are the differences of a build to the golden build; nil where either build did not
+// measure, or, for the boot time, measured with different accelerators
+type Deltas struct {
+	ArtifactBytes *int64
+	RootFSBytes   *int64
+	Boot          *time.Duration
+}
+
+// Compare returns the deltas of build to golden
+func Compare(build, golden Measurements) Deltas {
+	var d Deltas
+	if build.ArtifactBytes > 0 && golden.ArtifactBytes > 0 {
+		delta := build.ArtifactBytes - golden.ArtifactBytes
+		d.ArtifactBytes = &delta
+	}
+	if build.RootFSBytes > 0 && golden.RootFSBytes > 0 {
+		delta := build.RootFSBytes - golden.RootFSBytes
+		d.RootFSBytes = &delta
+	}
+	if build.Boot > 0 && golden.Boot > 0 && build.BootAccelerator == golden.BootAccelerator {
+		delta := build.Boot - golden.Boot
+		d.Boot = &delta
+	}
+	return d
+}
+
+// Description summarizes the outcome of a build and its measurements for a commit status, with
+// the deltas to golden, the golden build of branch, when there is one, e.g.
+// "Completed: artifact 812.4 MiB (+1.2 MiB), boot 8.2s (-0.3s) vs main"
+func Description(outcome string, build Measurements, golden *Measurements, branch string) string {
+	var d Deltas
+	if golden != nil {
+		d = Compare(build, *golden)
+	}
+	var parts []string
+	if build.ArtifactBytes > 0 {
+		parts = append(parts, "artifact "+byteSize(build.ArtifactBytes)+signedBytes(d.ArtifactBytes))
+	}
+	if build.RootFSBytes > 0 {
+		parts = append(parts, "rootfs "+byteSize(build.RootFSBytes)+signedBytes(d.RootFSBytes))
+	}
+	if build.Boot > 0 {
+		boot := fmt.Sprintf("boot %.1fs", build.Boot.Seconds())
+		if d.Boot != nil {
+			boot += fmt.Sprintf(" (%+.1fs)", d.Boot.Seconds())
+		}
+		parts = append(parts, boot)
+	}
+	if len(parts) == 0 {
+		return truncate(outcome)
+	}
+	description := outcome + ": " + strings.Join(parts, ", ")
+	switch {
+	case branch == "":
+	case golden != nil:
+		description += " vs " + branch
+	default:
+		description += "; no golden build of " + branch
+	}
+	return truncate(description)
+}
+
+// signedBytes formats a size delta as " (+1.2 MiB)", empty for nil
+func signedBytes(delta *int64) string {
+	if delta == nil {
+		return ""
+	}
+	if *delta < 0 {
+		return " (-" + byteSize(-*delta) + ")"
+	}
+	return " (+" + byteSize(*delta) + ")"
+}
+
+// byteSize formats n in binary units
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,281 @@
+// Package devsync copies the add_files content of a manifest into a booted image, so userspace
+// changes can be tried without rebuilding the image.
+//
+// Files collects the files the manifest adds, Stage lays them out under a directory as they are
+// placed in the image, for rsync to transfer what differs, and Restarts finds the systemd units
+// to restart for the files that changed. A target that received files no longer matches the build
+// it was flashed with; the Marker written to it records what was synced since.
+package devsync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/manifest"
+)
+
+const (
+	// QMRoot is where the root filesystem of the QM partition is mounted in the image
+	QMRoot = "/usr/lib/qm/rootfs"
+
+	// MarkerPath holds the Marker of a target that received synced files
+	MarkerPath = "/var/lib/caib/dirty.json"
+	// MotdPath is the login notice of a target that received synced files
+	MotdPath = "/etc/motd.d/99-caib-dirty"
+)
+
+// File is a file of the manifest as placed in the image
+type File struct {
+	// Path is where the file is in the image; files of the QM partition are below QMRoot
+	Path string
+	// Source is the local file, relative paths are relative to the directory of the manifest;
+	// empty for files given as text
+	Source string
+	Text   string
+	// QM is set for files of the QM partition
+	QM bool
+}
+
+// Files returns the files the add_files entries of m place in the image and of its QM partition.
+// Entries downloaded from a url are skipped and returned as such, as they do not change locally.
+func Files(m *manifest.Manifest, manifestDir string) (files []File, skipped []string, err error) {
+	add := func(content *manifest.Content, qm bool) error {
+		if content == nil {
+			return nil
+		}
+		for _, entry := range content.AddFiles {
+			dest := path.Clean("/" + entry.Path)
+			if qm {
+				dest = QMRoot + dest
+			}
+			switch {
+			case entry.URL != "":
+				skipped = append(skipped, entry.Path)
+			case entry.SourceGlob != "":
+				matches, err := filepath.Glob(localPath(manifestDir, entry.SourceGlob))
+				if err != nil {
+					return fmt.Errorf("invalid source_glob %q: %w", entry.SourceGlob, err)
+				}
+				for _, match := range matches {
+					if info, err := os.Stat(match); err != nil || info.IsDir() {
+						continue
+					}
+					files = append(files, File{Path: path.Join(dest, filepath.Base(match)), Source: match, QM: qm})
+				}
+			case entry.SourcePath != "":
+				files = append(files, File{Path: dest, Source: localPath(manifestDir, entry.SourcePath), QM: qm})
+			default:
+				files = append(files, File{Path: dest, Text: entry.Text, QM: qm})
+			}
+		}
+		return nil
+	}
+	if err := add(m.Content, false); err != nil {
+		return nil, nil, err
+	}
+	if m.QM != nil {
+		if err := add(m.QM.Content, true); err != nil {
+			return nil, nil, err
+		}
+	}
+	return files, skipped, nil
+}
+
+// localPath resolves a source path of the manifest
+func localPath(manifestDir, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(manifestDir, filepath.FromSlash(p))
+}
+
+// Stage copies files to dir at their path in the image, so dir mirrors the root of the image
+func Stage(files []File, dir string) error {
+	for _, f := range files {
+		dest := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return err
+		}
+		if f.Source == "" {
+			if err := os.WriteFile(dest, []byte(f.Text), 0o644); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := os.ReadFile(f.Source)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(f.Source)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(dest, data, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Changed reads the paths rsync reports with --out-format=%n for a transfer of a staged
+// directory, which are relative to the root of the image; directories are left out
+func Changed(out string) []string {
+	var changed []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasSuffix(line, "/") {
+			continue
+		}
+		changed = append(changed, "/"+strings.TrimPrefix(line, "./"))
+	}
+	return changed
+}
+
+// Restart is what has to be restarted for changed files, in the image or in its QM partition
+type Restart struct {
+	// DaemonReload is set when unit files changed
+	DaemonReload bool
+	Units        []string
+}
+
+// Empty reports whether nothing has to be restarted
+func (r Restart) Empty() bool {
+	return !r.DaemonReload && len(r.Units) == 0
+}
+
+// unitDirs are the directories systemd loads units from
+var unitDirs = []string{"/etc/systemd/system/", "/usr/lib/systemd/system/"}
+
+// quadletDir holds the Quadlet files podman generates services from
+const quadletDir = "/etc/containers/systemd/"
+
+// Restarts returns the units to restart for changed paths: units whose unit file or drop-in
+// changed, and the services of changed Quadlet files. Paths below QMRoot are units of the QM
+// partition.
+func Restarts(changed []string) (host, qm Restart) {
+	for _, p := range changed {
+		r := &host
+		if rest, ok := strings.CutPrefix(p, QMRoot+"/"); ok {
+			r, p = &qm, "/"+rest
+		}
+		unit := ""
+		for _, dir := range unitDirs {
+			if rest, ok := strings.CutPrefix(p, dir); ok {
+				// drop-ins are in <unit>.d/
+				unit, _, _ = strings.Cut(rest, "/")
+				unit = strings.TrimSuffix(unit, ".d")
+			}
+		}
+		if rest, ok := strings.CutPrefix(p, quadletDir); ok {
+			unit = quadletService(path.Base(rest))
+		}
+		if unit == "" {
+			continue
+		}
+		r.DaemonReload = true
+		// templates, targets and the like are not restarted
+		if strings.HasSuffix(unit, ".service") && !strings.Contains(unit, "@.") && !slices.Contains(r.Units, unit) {
+			r.Units = append(r.Units, unit)
+		}
+	}
+	return host, qm
+}
+
+// quadletService is the service podman generates from a Quadlet file, empty for other files
+func quadletService(name string) string {
+	base, ext := strings.TrimSuffix(name, path.Ext(name)), path.Ext(name)
+	switch ext {
+	case ".container", ".kube":
+		return base + ".service"
+	case ".pod":
+		return base + "-pod.service"
+	case ".volume", ".network", ".image", ".build":
+		return base + "-" + strings.TrimPrefix(ext, ".") + ".service"
+	}
+	return ""
+}
+
+// Marker records on a target what was synced to it since it was flashed with Build
+type Marker struct {
+	Build string `json:"build"`
+	// BuildRecord is the digest of the build record of Build, when the namespace keeps records
+	BuildRecord string `json:"buildRecord,omitempty"`
+	// FirstSync and LastSync are RFC 3339 times
+	FirstSync string `json:"firstSync"`
+	LastSync  string `json:"lastSync"`
+	// Files maps the path of every file synced to the SHA-256 of its last synced content
+	Files map[string]string `json:"files"`
+}
+
+// Update returns the marker of a target after files were synced to it at now, continuing the
+// marker it had, data, unless that was written for another build
+func Update(data []byte, build, buildRecord string, files []File, now time.Time) (*Marker, error) {
+	m := &Marker{}
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := json.Unmarshal(data, m); err != nil {
+			return nil, fmt.Errorf("invalid marker %s on the target: %w", MarkerPath, err)
+		}
+	}
+	stamp := now.UTC().Format(time.RFC3339)
+	if m.Build != build || m.FirstSync == "" {
+		m = &Marker{Build: build, FirstSync: stamp}
+	}
+	if buildRecord != "" {
+		m.BuildRecord = buildRecord
+	}
+	m.LastSync = stamp
+	if m.Files == nil {
+		m.Files = map[string]string{}
+	}
+	for _, f := range files {
+		sum, err := f.SHA256()
+		if err != nil {
+			return nil, err
+		}
+		m.Files[f.Path] = sum
+	}
+	return m, nil
+}
+
+// SHA256 returns the hex encoded SHA-256 of the content of the file
+func (f File) SHA256() (string, error) {
+	data := []byte(f.Text)
+	if f.Source != "" {
+		var err error
+		if data, err = os.ReadFile(f.Source); err != nil {
+			return "", err
+		}
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Motd is the login notice telling that the target differs from its build
+func (m *Marker) Motd() string {
+	paths := make([]string, 0, len(m.Files))
+	for p := range m.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	record := ""
+	if m.BuildRecord != "" {
+		record = " (build record " + m.BuildRecord + ")"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "DIRTY: this system differs from build %s%s.\n", m.Build, record)
+	fmt.Fprintf(&b, "%d files were synced with caib sync since %s, last at %s:\n", len(paths), m.FirstSync, m.LastSync)
+	for _, p := range paths {
+		fmt.Fprintf(&b, "  %s\n", p)
+	}
+	fmt.Fprintf(&b, "See %s; reflash to return to the build.\n", MarkerPath)
+	return b.String()
+}
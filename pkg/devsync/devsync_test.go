@@ -0,0 +1,133 @@
+package devsync
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/manifest"
+)
+
+var _ = Describe("Files", func() {
+	It("should place the files of the image and of the QM partition", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(dir, "units"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "radio.conf"), []byte("volume=3\n"), 0o600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "units", "radio.service"), []byte("[Service]\n"), 0o644)).To(Succeed())
+		m, err := manifest.Parse([]byte(`name: radio
+content:
+  add_files:
+    - path: /etc/radio.conf
+      source_path: radio.conf
+    - path: /etc/systemd/system
+      source_glob: units/*.service
+    - path: /etc/motd
+      text: hello
+    - path: /usr/share/firmware.bin
+      url: https://example.com/firmware.bin
+qm:
+  content:
+    add_files:
+      - path: /etc/containers/systemd/player.container
+        text: "[Container]\n"
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		files, skipped, err := Files(m, dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(skipped).To(ConsistOf("/usr/share/firmware.bin"))
+		Expect(files).To(ConsistOf(
+			File{Path: "/etc/radio.conf", Source: filepath.Join(dir, "radio.conf")},
+			File{Path: "/etc/systemd/system/radio.service", Source: filepath.Join(dir, "units", "radio.service")},
+			File{Path: "/etc/motd", Text: "hello"},
+			File{Path: QMRoot + "/etc/containers/systemd/player.container", Text: "[Container]\n", QM: true},
+		))
+
+		stage := GinkgoT().TempDir()
+		Expect(Stage(files, stage)).To(Succeed())
+		data, err := os.ReadFile(filepath.Join(stage, "etc", "radio.conf"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("volume=3\n"))
+		info, err := os.Stat(filepath.Join(stage, "etc", "radio.conf"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+		data, err = os.ReadFile(filepath.Join(stage, "usr", "lib", "qm", "rootfs", "etc", "containers", "systemd", "player.container"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("[Container]\n"))
+	})
+})
+
+var _ = Describe("Changed", func() {
+	It("should read the transferred files and leave out directories", func() {
+		Expect(Changed("./\netc/\netc/radio.conf\nusr/lib/qm/rootfs/etc/motd\n")).To(Equal(
+			[]string{"/etc/radio.conf", "/usr/lib/qm/rootfs/etc/motd"}))
+		Expect(Changed("")).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Restarts", func() {
+	It("should restart the units of changed unit files, drop-ins and Quadlet files", func() {
+		host, qm := Restarts([]string{
+			"/etc/radio.conf",
+			"/etc/systemd/system/radio.service",
+			"/usr/lib/systemd/system/tuner.service.d/override.conf",
+			"/etc/systemd/system/radio.service",
+			"/etc/systemd/system/getty@.service",
+			"/etc/systemd/system/multi-user.target.wants/radio.service",
+			QMRoot + "/etc/containers/systemd/player.container",
+			QMRoot + "/etc/containers/systemd/media.pod",
+		})
+		Expect(host.DaemonReload).To(BeTrue())
+		Expect(host.Units).To(Equal([]string{"radio.service", "tuner.service"}))
+		Expect(qm.DaemonReload).To(BeTrue())
+		Expect(qm.Units).To(Equal([]string{"player.service", "media-pod.service"}))
+	})
+
+	It("should restart nothing for other files", func() {
+		host, qm := Restarts([]string{"/etc/radio.conf", QMRoot + "/etc/motd"})
+		Expect(host.Empty()).To(BeTrue())
+		Expect(qm.Empty()).To(BeTrue())
+	})
+})
+
+var _ = Describe("Update", func() {
+	files := []File{{Path: "/etc/motd", Text: "hello"}}
+	first := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	It("should start a marker and continue it for the same build", func() {
+		m, err := Update(nil, "radio", "sha256:abc", files, first)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.FirstSync).To(Equal("2026-03-01T10:00:00Z"))
+		Expect(m.Files).To(HaveKeyWithValue("/etc/motd", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"))
+		data, err := json.Marshal(m)
+		Expect(err).NotTo(HaveOccurred())
+
+		m, err = Update(data, "radio", "", []File{{Path: "/etc/issue", Text: "x"}}, first.Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.FirstSync).To(Equal("2026-03-01T10:00:00Z"))
+		Expect(m.LastSync).To(Equal("2026-03-01T11:00:00Z"))
+		Expect(m.BuildRecord).To(Equal("sha256:abc"))
+		Expect(m.Files).To(HaveLen(2))
+		Expect(m.Motd()).To(ContainSubstring("DIRTY: this system differs from build radio (build record sha256:abc)"))
+		Expect(m.Motd()).To(ContainSubstring("  /etc/issue\n  /etc/motd\n"))
+	})
+
+	It("should start over for a target flashed with another build", func() {
+		data, err := json.Marshal(&Marker{Build: "old", FirstSync: "2026-01-01T00:00:00Z", Files: map[string]string{"/etc/a": "x"}})
+		Expect(err).NotTo(HaveOccurred())
+		m, err := Update(data, "radio", "", files, first)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Build).To(Equal("radio"))
+		Expect(m.FirstSync).To(Equal("2026-03-01T10:00:00Z"))
+		Expect(m.Files).To(HaveLen(1))
+	})
+
+	It("should reject a marker it cannot read", func() {
+		_, err := Update([]byte("{"), "radio", "", files, first)
+		Expect(err).To(HaveOccurred())
+	})
+})
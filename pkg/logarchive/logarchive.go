@@ -0,0 +1,164 @@
+// Package logarchive compresses the step logs of a build pod into a gzip archive that outlives
+// the pod.
+//
+// The archive holds the steps in the order they ran, each under the banner of the plain log
+// stream of the build API, so the archive reads like a complete log stream. It is kept in
+// ConfigMaps of at most PartSize bytes each; logs beyond MaxSize bytes compressed are left out and
+// the archive says so at its end.
+package logarchive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	// PartSize is how many bytes of the archive a ConfigMap holds, below the 1 MiB object limit
+	PartSize = 768 << 10
+	// MaxParts is how many ConfigMaps an archive is split into at most
+	MaxParts = 16
+	// MaxSize is the largest archive in bytes
+	MaxSize = PartSize * MaxParts
+
+	// DataKey is the binaryData key of the part of the archive in a ConfigMap
+	DataKey = "build.log.gz"
+	// PartAnnotation is the index of the part a ConfigMap holds, from 0
+	PartAnnotation = "automotive.sdv.cloud.redhat.com/log-archive-part"
+	// ComponentLabel is the app.kubernetes.io/component label value of the ConfigMaps of archives
+	ComponentLabel = "build-log-archive"
+
+	// MediaType is the media type of an archive
+	MediaType = "application/gzip"
+
+	// truncationMargin is the room left for the data the compressor holds back and the truncation
+	// notice: deflate emits at most a stored block of 64 KiB beyond what it wrote out
+	truncationMargin = 128 << 10
+)
+
+// Archive is the compressed log of a build
+type Archive struct {
+	Data []byte
+	// LogSize is the size of the log before compression
+	LogSize int64
+	// Truncated is set when the end of the log was left out to stay below MaxSize
+	Truncated bool
+}
+
+// SHA256 returns the hex encoded SHA-256 of the archive
+func (a *Archive) SHA256() string {
+	sum := sha256.Sum256(a.Data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Parts splits the archive into the data of its ConfigMaps
+func (a *Archive) Parts() [][]byte {
+	var parts [][]byte
+	for data := a.Data; len(data) > 0; {
+		n := min(len(data), PartSize)
+		parts = append(parts, data[:n])
+		data = data[n:]
+	}
+	return parts
+}
+
+// PartName is the name of the ConfigMap holding part i of the log archive of build
+func PartName(build string, i int) string {
+	return build + "-logs-" + strconv.Itoa(i)
+}
+
+// Writer compresses step logs into an Archive
+type Writer struct {
+	buf bytes.Buffer
+	gz  *gzip.Writer
+	log int64
+	// truncated is set once the archive is full
+	truncated bool
+}
+
+// NewWriter returns a Writer of an empty archive
+func NewWriter() *Writer {
+	w := &Writer{}
+	w.gz = gzip.NewWriter(&w.buf)
+	return w
+}
+
+// AddStep appends the log of a step, read from r, under its banner. Nothing is added once the
+// archive is full.
+func (w *Writer) AddStep(step string, r io.Reader) error {
+	if w.truncated {
+		return nil
+	}
+	if err := w.write([]byte("\n===== Logs from " + step + " =====\n\n")); err != nil {
+		return err
+	}
+	chunk := make([]byte, 32<<10)
+	for !w.truncated {
+		n, err := r.Read(chunk)
+		if n > 0 {
+			if werr := w.write(chunk[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddNote appends a line that is not part of a step log, e.g. why the log of a step is missing
+func (w *Writer) AddNote(note string) error {
+	if w.truncated {
+		return nil
+	}
+	return w.write([]byte("\n[" + note + "]\n"))
+}
+
+// write compresses p and marks the archive truncated once it nears MaxSize
+func (w *Writer) write(p []byte) error {
+	if _, err := w.gz.Write(p); err != nil {
+		return err
+	}
+	w.log += int64(len(p))
+	if w.buf.Len() > MaxSize-truncationMargin {
+		w.truncated = true
+	}
+	return nil
+}
+
+// Close completes the archive
+func (w *Writer) Close() (*Archive, error) {
+	if w.truncated {
+		note := fmt.Sprintf("\n[Log truncated: the archive is limited to %d bytes]\n", MaxSize)
+		if _, err := w.gz.Write([]byte(note)); err != nil {
+			return nil, err
+		}
+		w.log += int64(len(note))
+	}
+	if err := w.gz.Close(); err != nil {
+		return nil, err
+	}
+	if w.buf.Len() > MaxSize {
+		return nil, fmt.Errorf("log archive of %d bytes exceeds %d bytes", w.buf.Len(), MaxSize)
+	}
+	return &Archive{Data: w.buf.Bytes(), LogSize: w.log, Truncated: w.truncated}, nil
+}
+
+// Join reassembles an archive from the data of its parts, in order, and checks it against the
+// SHA-256 it was stored with
+func Join(parts [][]byte, sha256Hex string) ([]byte, error) {
+	data := bytes.Join(parts, nil)
+	sum := sha256.Sum256(data)
+	if sha256Hex != "" && hex.EncodeToString(sum[:]) != sha256Hex {
+		return nil, fmt.Errorf("log archive does not match its SHA-256 %s", sha256Hex)
+	}
+	return data, nil
+}
@@ -0,0 +1,13 @@
+package logarchive
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestLogArchive(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Log Archive Suite")
+}
@@ -0,0 +1,76 @@
+package logarchive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// gunzip returns the log of an archive
+func gunzip(data []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	Expect(err).NotTo(HaveOccurred())
+	out, err := io.ReadAll(r)
+	Expect(err).NotTo(HaveOccurred())
+	return string(out)
+}
+
+var _ = Describe("Writer", func() {
+	It("should archive the steps in order under their banners", func() {
+		w := NewWriter()
+		Expect(w.AddStep("prepare", strings.NewReader("cloning\n"))).To(Succeed())
+		Expect(w.AddNote("Logs of build-image unavailable: container not found")).To(Succeed())
+		Expect(w.AddStep("push", strings.NewReader("pushed\n"))).To(Succeed())
+		archive, err := w.Close()
+		Expect(err).NotTo(HaveOccurred())
+
+		log := "\n===== Logs from prepare =====\n\ncloning\n" +
+			"\n[Logs of build-image unavailable: container not found]\n" +
+			"\n===== Logs from push =====\n\npushed\n"
+		Expect(gunzip(archive.Data)).To(Equal(log))
+		Expect(archive.LogSize).To(Equal(int64(len(log))))
+		Expect(archive.Truncated).To(BeFalse())
+		Expect(archive.Parts()).To(HaveLen(1))
+	})
+
+	It("should leave out what does not fit and say so", func() {
+		w := NewWriter()
+		// random data does not compress
+		Expect(w.AddStep("build-image", io.LimitReader(rand.Reader, MaxSize+1<<20))).To(Succeed())
+		Expect(w.AddStep("push", strings.NewReader("pushed\n"))).To(Succeed())
+		archive, err := w.Close()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(archive.Truncated).To(BeTrue())
+		Expect(len(archive.Data)).To(BeNumerically("<=", MaxSize))
+		Expect(archive.Parts()).To(HaveLen(MaxParts))
+
+		log := gunzip(archive.Data)
+		Expect(log).To(HaveSuffix("[Log truncated: the archive is limited to 12582912 bytes]\n"))
+		Expect(log).NotTo(ContainSubstring("Logs from push"))
+	})
+})
+
+var _ = Describe("Join", func() {
+	It("should reassemble the parts of an archive", func() {
+		archive := &Archive{Data: bytes.Repeat([]byte("x"), 2*PartSize+10)}
+		parts := archive.Parts()
+		Expect(parts).To(HaveLen(3))
+		Expect(parts[2]).To(HaveLen(10))
+
+		data, err := Join(parts, archive.SHA256())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(archive.Data))
+
+		_, err = Join(parts[:2], archive.SHA256())
+		Expect(err).To(MatchError(ContainSubstring("does not match")))
+	})
+
+	It("should name the parts after the build", func() {
+		Expect(PartName("my-build", 0)).To(Equal("my-build-logs-0"))
+	})
+})
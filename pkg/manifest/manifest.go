@@ -0,0 +1,417 @@
+// Package manifest builds automotive-image-builder manifests (.aib.yml) from Go values, so
+// programs that generate manifests, e.g. one per hardware variant, do not have to template YAML.
+//
+// A Manifest covers the sections builds commonly set: content and qm.content (rpms, repos,
+// container images, added files and systemd services), kernel options and image settings.
+// Sections and fields it does not model are kept in the Extra maps, so a manifest read with Parse
+// is written back by Marshal without losing them.
+//
+//	m := manifest.New("ecu-gateway")
+//	m.Content.AddRPMs("openssh-server")
+//	m.Content.AddFile(manifest.File{Path: "/etc/motd", Text: "gateway\n"})
+//	m.KernelCmdline("console=ttyS0")
+//	out, err := m.Marshal()
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest is an automotive-image-builder manifest
+type Manifest struct {
+	Name    string   `yaml:"name"`
+	Version string   `yaml:"version,omitempty"`
+	Image   *Image   `yaml:"image,omitempty"`
+	Content *Content `yaml:"content,omitempty"`
+	QM      *QM      `yaml:"qm,omitempty"`
+	Kernel  *Kernel  `yaml:"kernel,omitempty"`
+	// Extra holds the top-level sections not modeled here, e.g. auth or network
+	Extra map[string]any `yaml:",inline"`
+}
+
+// Image holds settings of the image as a whole
+type Image struct {
+	// ImageSize is the size of the disk image, e.g. "8 GiB"
+	ImageSize   string         `yaml:"image_size,omitempty"`
+	SELinuxMode string         `yaml:"selinux_mode,omitempty"`
+	Hostname    string         `yaml:"hostname,omitempty"`
+	Extra       map[string]any `yaml:",inline"`
+}
+
+// Content is what is installed into the root filesystem, or into the QM partition as qm.content
+type Content struct {
+	Repos           []Repo           `yaml:"repos,omitempty"`
+	EnableRepos     []string         `yaml:"enable_repos,omitempty"`
+	RPMs            []string         `yaml:"rpms,omitempty"`
+	ContainerImages []ContainerImage `yaml:"container_images,omitempty"`
+	AddFiles        []File           `yaml:"add_files,omitempty"`
+	Systemd         *Systemd         `yaml:"systemd,omitempty"`
+	// Extra holds the content fields not modeled here, e.g. chmod_files or make_dirs
+	Extra map[string]any `yaml:",inline"`
+}
+
+// Repo is an additional RPM repository
+type Repo struct {
+	ID      string `yaml:"id"`
+	BaseURL string `yaml:"baseurl"`
+}
+
+// ContainerImage is a container image embedded into the image. Tag and Digest are exclusive;
+// neither means latest.
+type ContainerImage struct {
+	Source    string `yaml:"source"`
+	Tag       string `yaml:"tag,omitempty"`
+	Digest    string `yaml:"digest,omitempty"`
+	Name      string `yaml:"name,omitempty"`
+	Transport string `yaml:"containers-transport,omitempty"`
+}
+
+// File is a file added to the image. Exactly one of Text, URL, SourcePath and SourceGlob gives
+// its content; files with SourcePath or SourceGlob are uploaded by caib from the local machine.
+type File struct {
+	Path       string `yaml:"path"`
+	Text       string `yaml:"text,omitempty"`
+	URL        string `yaml:"url,omitempty"`
+	SourcePath string `yaml:"source_path,omitempty"`
+	SourceGlob string `yaml:"source_glob,omitempty"`
+}
+
+// Systemd enables and disables services of the image
+type Systemd struct {
+	EnabledServices  []string `yaml:"enabled_services,omitempty"`
+	DisabledServices []string `yaml:"disabled_services,omitempty"`
+}
+
+// QM configures the QM partition that runs mixed-criticality workloads
+type QM struct {
+	MemoryLimit *QMMemoryLimit `yaml:"memory_limit,omitempty"`
+	CPUWeight   *QMCPUWeight   `yaml:"cpu_weight,omitempty"`
+	Content     *Content       `yaml:"content,omitempty"`
+	Extra       map[string]any `yaml:",inline"`
+}
+
+// QMMemoryLimit limits the memory of the QM partition, e.g. Max "50%"
+type QMMemoryLimit struct {
+	Max  string `yaml:"max,omitempty"`
+	High string `yaml:"high,omitempty"`
+}
+
+// QMCPUWeight weighs the CPU time of the QM partition against the rest of the system
+type QMCPUWeight struct {
+	Idle   int `yaml:"idle,omitempty"`
+	Normal int `yaml:"normal,omitempty"`
+}
+
+// Kernel holds kernel options
+type Kernel struct {
+	// Cmdline are arguments appended to the kernel command line
+	Cmdline       []string       `yaml:"cmdline,omitempty"`
+	DebugLogging  bool           `yaml:"debug_logging,omitempty"`
+	Loglevel      *int           `yaml:"loglevel,omitempty"`
+	KernelPackage string         `yaml:"kernel_package,omitempty"`
+	KernelVersion string         `yaml:"kernel_version,omitempty"`
+	RemoveModules []string       `yaml:"remove_modules,omitempty"`
+	Extra         map[string]any `yaml:",inline"`
+}
+
+// New returns a manifest named name with an empty content section
+func New(name string) *Manifest {
+	return &Manifest{Name: name, Content: &Content{}}
+}
+
+// Parse reads a manifest
+func Parse(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	if err := yaml.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("error parsing manifest: %w", err)
+	}
+	return m, nil
+}
+
+// Marshal validates the manifest and returns it as YAML
+func (m *Manifest) Marshal() ([]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(m); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// QMContent returns the content of the QM partition, adding the qm section when missing
+func (m *Manifest) QMContent() *Content {
+	if m.QM == nil {
+		m.QM = &QM{}
+	}
+	if m.QM.Content == nil {
+		m.QM.Content = &Content{}
+	}
+	return m.QM.Content
+}
+
+// KernelCmdline appends args to the kernel command line
+func (m *Manifest) KernelCmdline(args ...string) *Manifest {
+	if m.Kernel == nil {
+		m.Kernel = &Kernel{}
+	}
+	m.Kernel.Cmdline = append(m.Kernel.Cmdline, args...)
+	return m
+}
+
+// AddRPMs adds packages to install
+func (c *Content) AddRPMs(names ...string) *Content {
+	c.RPMs = append(c.RPMs, names...)
+	return c
+}
+
+// AddFile adds a file to the image
+func (c *Content) AddFile(f File) *Content {
+	c.AddFiles = append(c.AddFiles, f)
+	return c
+}
+
+// AddContainerImage embeds a container image
+func (c *Content) AddContainerImage(img ContainerImage) *Content {
+	c.ContainerImages = append(c.ContainerImages, img)
+	return c
+}
+
+// EnableServices enables systemd services
+func (c *Content) EnableServices(names ...string) *Content {
+	if c.Systemd == nil {
+		c.Systemd = &Systemd{}
+	}
+	c.Systemd.EnabledServices = append(c.Systemd.EnabledServices, names...)
+	return c
+}
+
+// DisableServices disables systemd services
+func (c *Content) DisableServices(names ...string) *Content {
+	if c.Systemd == nil {
+		c.Systemd = &Systemd{}
+	}
+	c.Systemd.DisabledServices = append(c.Systemd.DisabledServices, names...)
+	return c
+}
+
+// Problem is something automotive-image-builder would reject in a manifest
+type Problem struct {
+	// Field is the path of the offending field, e.g. content.add_files[0]; empty when the problem
+	// is with the manifest as a whole
+	Field string
+	// Line and Column locate the field in the YAML given to Lint, counting from 1; 0 when unknown
+	Line   int
+	Column int
+	// Message describes the problem
+	Message string
+}
+
+func (p Problem) Error() string {
+	if p.Field == "" {
+		return p.Message
+	}
+	return p.Field + ": " + p.Message
+}
+
+func problemf(field, format string, args ...any) Problem {
+	return Problem{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// yamlErrorLine matches the line yaml.v3 prefixes its syntax and type errors with
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// Lint reads data as a manifest and reports its problems with the line and column of the offending
+// field: YAML syntax errors, fields of the wrong type and everything Validate reports. Unlike Parse
+// it does not stop at the first field of the wrong type. Lint returns nil for a valid manifest.
+func Lint(data []byte) []Problem {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []Problem{yamlProblem(err.Error())}
+	}
+	if len(doc.Content) == 0 {
+		return []Problem{{Line: 1, Column: 1, Message: "manifest is empty"}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return []Problem{{Line: root.Line, Column: root.Column, Message: "manifest must be a mapping"}}
+	}
+
+	var problems []Problem
+	m := &Manifest{}
+	if err := root.Decode(m); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return []Problem{yamlProblem(err.Error())}
+		}
+		for _, msg := range typeErr.Errors {
+			problems = append(problems, yamlProblem(msg))
+		}
+	}
+	for _, p := range m.problems() {
+		node := lookup(root, p.Field)
+		p.Line, p.Column = node.Line, node.Column
+		problems = append(problems, p)
+	}
+	return problems
+}
+
+func yamlProblem(msg string) Problem {
+	match := yamlErrorLine.FindStringSubmatch(msg)
+	if match == nil {
+		return Problem{Message: strings.TrimPrefix(msg, "yaml: ")}
+	}
+	line, _ := strconv.Atoi(match[1])
+	return Problem{Line: line, Message: match[2]}
+}
+
+// lookup returns the node of field, e.g. content.add_files[0], or of its closest parent present
+// in the document
+func lookup(node *yaml.Node, field string) *yaml.Node {
+	if field == "" {
+		return node
+	}
+	for _, part := range strings.Split(field, ".") {
+		key, index, indexed := strings.Cut(part, "[")
+		next := mappingValue(node, key)
+		if next == nil {
+			return node
+		}
+		node = next
+		if !indexed {
+			continue
+		}
+		i, err := strconv.Atoi(strings.TrimSuffix(index, "]"))
+		if err != nil || node.Kind != yaml.SequenceNode || i >= len(node.Content) {
+			return node
+		}
+		node = node.Content[i]
+	}
+	return node
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// Validate reports what automotive-image-builder would reject in the manifest, every problem
+// at once
+func (m *Manifest) Validate() error {
+	var errs []error
+	for _, p := range m.problems() {
+		errs = append(errs, p)
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Manifest) problems() []Problem {
+	var problems []Problem
+	if strings.TrimSpace(m.Name) == "" {
+		problems = append(problems, Problem{Message: "name is required"})
+	}
+	problems = append(problems, m.Content.problems("content")...)
+	if m.QM != nil {
+		problems = append(problems, m.QM.Content.problems("qm.content")...)
+	}
+	if k := m.Kernel; k != nil {
+		for i, arg := range k.Cmdline {
+			if strings.TrimSpace(arg) == "" || strings.ContainsAny(arg, " \t\n") {
+				problems = append(problems, problemf(fmt.Sprintf("kernel.cmdline[%d]", i), "%q must be a single argument", arg))
+			}
+		}
+		if k.Loglevel != nil && (*k.Loglevel < 0 || *k.Loglevel > 7) {
+			problems = append(problems, problemf("kernel.loglevel", "%d is not between 0 and 7", *k.Loglevel))
+		}
+		if k.KernelVersion != "" && k.KernelPackage == "" {
+			problems = append(problems, problemf("kernel.kernel_version", "requires kernel.kernel_package"))
+		}
+	}
+	if img := m.Image; img != nil {
+		switch img.SELinuxMode {
+		case "", "enforcing", "permissive":
+		default:
+			problems = append(problems, problemf("image.selinux_mode", "%q is not enforcing or permissive", img.SELinuxMode))
+		}
+	}
+	return problems
+}
+
+func (c *Content) problems(field string) []Problem {
+	if c == nil {
+		return nil
+	}
+	var problems []Problem
+	for i, rpm := range c.RPMs {
+		if strings.TrimSpace(rpm) == "" {
+			problems = append(problems, problemf(fmt.Sprintf("%s.rpms[%d]", field, i), "package name is empty"))
+		}
+	}
+	for i, repo := range c.Repos {
+		if repo.ID == "" || repo.BaseURL == "" {
+			problems = append(problems, problemf(fmt.Sprintf("%s.repos[%d]", field, i), "id and baseurl are required"))
+		}
+	}
+	for i, img := range c.ContainerImages {
+		at := fmt.Sprintf("%s.container_images[%d]", field, i)
+		switch {
+		case strings.TrimSpace(img.Source) == "":
+			problems = append(problems, problemf(at, "source is required"))
+		case img.Tag != "" && img.Digest != "":
+			problems = append(problems, problemf(at, "tag and digest are exclusive"))
+		case img.Digest != "" && !strings.Contains(img.Digest, ":"):
+			problems = append(problems, problemf(at, "digest %q is not of the form algorithm:hex", img.Digest))
+		}
+	}
+	added := map[string]bool{}
+	for i, f := range c.AddFiles {
+		at := fmt.Sprintf("%s.add_files[%d]", field, i)
+		switch {
+		case !path.IsAbs(f.Path):
+			problems = append(problems, problemf(at, "path %q must be absolute", f.Path))
+		case f.SourceGlob != "":
+			// the path of a glob is the directory its files are copied to, which several may share
+		case added[path.Clean(f.Path)]:
+			problems = append(problems, problemf(at, "path %q is added more than once", f.Path))
+		default:
+			added[path.Clean(f.Path)] = true
+		}
+		sources := 0
+		for _, s := range []string{f.Text, f.URL, f.SourcePath, f.SourceGlob} {
+			if s != "" {
+				sources++
+			}
+		}
+		if sources != 1 {
+			problems = append(problems, problemf(at, "exactly one of text, url, source_path and source_glob is required"))
+		}
+		for _, local := range []string{f.SourcePath, f.SourceGlob} {
+			if local != "" && (path.IsAbs(local) || slices.Contains(strings.Split(local, "/"), "..")) {
+				problems = append(problems, problemf(at, "%q must be relative to the manifest and not leave its directory", local))
+			}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,130 @@
+package manifest
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manifest", func() {
+	It("should marshal a built manifest", func() {
+		m := New("gateway")
+		m.Content.AddRPMs("openssh-server").
+			AddFile(File{Path: "/etc/motd", Text: "gateway\n"}).
+			EnableServices("sshd.service")
+		m.QMContent().AddContainerImage(ContainerImage{Source: "quay.io/example/app", Tag: "1.0", Name: "app"})
+		m.KernelCmdline("console=ttyS0", "quiet")
+
+		out, err := m.Marshal()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(out)).To(Equal(`name: gateway
+content:
+  rpms:
+    - openssh-server
+  add_files:
+    - path: /etc/motd
+      text: |
+        gateway
+  systemd:
+    enabled_services:
+      - sshd.service
+qm:
+  content:
+    container_images:
+      - source: quay.io/example/app
+        tag: "1.0"
+        name: app
+kernel:
+  cmdline:
+    - console=ttyS0
+    - quiet
+`))
+	})
+
+	It("should keep sections it does not model", func() {
+		m, err := Parse([]byte(`name: ecu
+auth:
+  root_password: $6$xyz
+content:
+  rpms: [vim]
+  make_dirs:
+    - path: /var/app
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Content.RPMs).To(Equal([]string{"vim"}))
+
+		out, err := m.Marshal()
+		Expect(err).NotTo(HaveOccurred())
+		again, err := Parse(out)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again.Extra).To(HaveKey("auth"))
+		Expect(again.Content.Extra).To(HaveKey("make_dirs"))
+	})
+
+	It("should report every problem", func() {
+		loglevel := 9
+		m := &Manifest{
+			Content: &Content{
+				AddFiles: []File{
+					{Path: "etc/motd", Text: "x"},
+					{Path: "/etc/issue", Text: "x", URL: "https://example.com/issue"},
+				},
+				ContainerImages: []ContainerImage{{Source: "quay.io/a", Tag: "1", Digest: "sha256:00"}},
+			},
+			QM:     &QM{Content: &Content{RPMs: []string{""}}},
+			Kernel: &Kernel{Cmdline: []string{"a b"}, Loglevel: &loglevel},
+			Image:  &Image{SELinuxMode: "disabled"},
+		}
+		err := m.Validate()
+		Expect(err).To(HaveOccurred())
+		for _, problem := range []string{
+			"name is required",
+			"content.add_files[0]: path \"etc/motd\" must be absolute",
+			"content.add_files[1]: exactly one of",
+			"content.container_images[0]: tag and digest are exclusive",
+			"qm.content.rpms[0]",
+			"kernel.cmdline[0]",
+			"kernel.loglevel",
+			"image.selinux_mode",
+		} {
+			Expect(err.Error()).To(ContainSubstring(problem))
+		}
+
+		_, err = m.Marshal()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should locate the problems it lints", func() {
+		problems := Lint([]byte(`name: gateway
+content:
+  add_files:
+    - path: /etc/motd
+      text: hi
+    - path: /etc/motd
+      source_path: ../secrets/motd
+kernel:
+  loglevel: 9
+`))
+		Expect(problems).To(ConsistOf(
+			Problem{Field: "content.add_files[1]", Line: 6, Column: 7, Message: `path "/etc/motd" is added more than once`},
+			Problem{Field: "content.add_files[1]", Line: 6, Column: 7, Message: `"../secrets/motd" must be relative to the manifest and not leave its directory`},
+			Problem{Field: "kernel.loglevel", Line: 9, Column: 13, Message: "9 is not between 0 and 7"},
+		))
+	})
+
+	It("should report fields of the wrong type and carry on", func() {
+		problems := Lint([]byte("name: gateway\nkernel:\n  loglevel: high\n  cmdline:\n    - a b\n"))
+		Expect(problems).To(HaveLen(2))
+		Expect(problems[0].Line).To(Equal(3))
+		Expect(problems[0].Message).To(ContainSubstring("cannot unmarshal"))
+		Expect(problems[1]).To(Equal(Problem{Field: "kernel.cmdline[0]", Line: 5, Column: 7, Message: `"a b" must be a single argument`}))
+	})
+
+	It("should report syntax errors with their line", func() {
+		problems := Lint([]byte("name: gateway\ncontent:\n  rpms: [a\n"))
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].Line).To(BeNumerically(">", 0))
+
+		Expect(Lint([]byte("- a\n"))).To(Equal([]Problem{{Line: 1, Column: 1, Message: "manifest must be a mapping"}}))
+		Expect(Lint([]byte("name: gateway\n"))).To(BeEmpty())
+	})
+})
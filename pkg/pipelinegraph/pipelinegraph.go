@@ -0,0 +1,329 @@
+// Package pipelinegraph exports the dependency graph of a Tekton Pipeline, such as the build
+// pipeline the operator deploys, with the status of every task in one of its runs. The graph is
+// served as JSON or Graphviz DOT and printed as text by caib.
+//
+// A task depends on the tasks of its runAfter and on the tasks whose results its params or when
+// expressions reference, as Tekton orders them. Finally tasks depend on the last tasks.
+package pipelinegraph
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+)
+
+// Statuses of a task in a run
+const (
+	StatusPending   = "Pending"
+	StatusRunning   = "Running"
+	StatusSucceeded = "Succeeded"
+	StatusFailed    = "Failed"
+	StatusCancelled = "Cancelled"
+	StatusSkipped   = "Skipped"
+)
+
+// Formats of the graph
+const (
+	FormatJSON = "json"
+	FormatDOT  = "dot"
+	FormatText = "text"
+)
+
+// MediaTypeDOT is the media type of the DOT format
+const MediaTypeDOT = "text/vnd.graphviz"
+
+// Graph is the dependency graph of a pipeline
+type Graph struct {
+	Pipeline string `json:"pipeline"`
+	// Run is the PipelineRun the statuses are of; empty when the pipeline has not run
+	Run   string `json:"run,omitempty"`
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// Node is a task of the pipeline
+type Node struct {
+	Name string `json:"name"`
+	// Task is the Task the pipeline task runs, when it references one by name
+	Task string `json:"task,omitempty"`
+	// When are the conditions of the task, e.g. "$(params.repository-url) notin [, null]"
+	When    []string `json:"when,omitempty"`
+	Finally bool     `json:"finally,omitempty"`
+	Status  string   `json:"status"`
+	TaskRun string   `json:"taskRun,omitempty"`
+	// Message explains a failed or skipped task
+	Message string `json:"message,omitempty"`
+}
+
+// Edge says To runs after From
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// resultRef matches a reference to a result of another pipeline task
+var resultRef = regexp.MustCompile(`\$\(tasks\.([a-z0-9-]+)\.results\.`)
+
+// New returns the graph of pipeline with every task pending
+func New(pipeline *tektonv1.Pipeline) *Graph {
+	g := &Graph{Pipeline: pipeline.Name, Nodes: []Node{}, Edges: []Edge{}}
+	edges := map[Edge]bool{}
+	add := func(from, to string) {
+		if from != to && !edges[Edge{from, to}] {
+			edges[Edge{from, to}] = true
+			g.Edges = append(g.Edges, Edge{From: from, To: to})
+		}
+	}
+	for _, finally := range []bool{false, true} {
+		tasks := pipeline.Spec.Tasks
+		if finally {
+			tasks = pipeline.Spec.Finally
+		}
+		for _, task := range tasks {
+			node := Node{Name: task.Name, Task: taskName(task), Finally: finally, Status: StatusPending}
+			for _, when := range task.When {
+				node.When = append(node.When, fmt.Sprintf("%s %s [%s]", when.Input, when.Operator, strings.Join(when.Values, ", ")))
+			}
+			g.Nodes = append(g.Nodes, node)
+			for _, after := range task.RunAfter {
+				add(after, task.Name)
+			}
+			for _, ref := range taskReferences(task) {
+				add(ref, task.Name)
+			}
+		}
+	}
+
+	// finally tasks run once every other task ended
+	for _, node := range g.Nodes {
+		if node.Finally || slices.ContainsFunc(g.Edges, func(e Edge) bool { return e.From == node.Name }) {
+			continue
+		}
+		for _, finally := range pipeline.Spec.Finally {
+			add(node.Name, finally.Name)
+		}
+	}
+	return g
+}
+
+// taskName is the Task a pipeline task runs: its taskRef, or the name param of a cluster resolver
+func taskName(task tektonv1.PipelineTask) string {
+	if task.TaskRef == nil {
+		return ""
+	}
+	if task.TaskRef.Name != "" {
+		return task.TaskRef.Name
+	}
+	for _, p := range task.TaskRef.Params {
+		if p.Name == "name" {
+			return p.Value.StringVal
+		}
+	}
+	return ""
+}
+
+// taskReferences returns the pipeline tasks whose results task uses
+func taskReferences(task tektonv1.PipelineTask) []string {
+	var values []string
+	for _, p := range task.Params {
+		values = append(values, p.Value.StringVal)
+		values = append(values, p.Value.ArrayVal...)
+		for _, v := range p.Value.ObjectVal {
+			values = append(values, v)
+		}
+	}
+	for _, when := range task.When {
+		values = append(values, when.Input)
+		values = append(values, when.Values...)
+	}
+	var refs []string
+	for _, v := range values {
+		for _, m := range resultRef.FindAllStringSubmatch(v, -1) {
+			if !slices.Contains(refs, m[1]) {
+				refs = append(refs, m[1])
+			}
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// SetRun records the status of every task in run, whose TaskRuns are taskRuns
+func (g *Graph) SetRun(run *tektonv1.PipelineRun, taskRuns []tektonv1.TaskRun) {
+	g.Run = run.Name
+	byName := map[string]*tektonv1.TaskRun{}
+	for i := range taskRuns {
+		byName[taskRuns[i].Name] = &taskRuns[i]
+	}
+	children := map[string]string{}
+	for _, child := range run.Status.ChildReferences {
+		if child.Kind == "" || child.Kind == "TaskRun" {
+			children[child.PipelineTaskName] = child.Name
+		}
+	}
+	skipped := map[string]string{}
+	for _, s := range run.Status.SkippedTasks {
+		skipped[s.Name] = string(s.Reason)
+	}
+	for i := range g.Nodes {
+		node := &g.Nodes[i]
+		node.Status, node.TaskRun, node.Message = StatusPending, "", ""
+		if reason, ok := skipped[node.Name]; ok {
+			node.Status, node.Message = StatusSkipped, reason
+			continue
+		}
+		name, ok := children[node.Name]
+		if !ok {
+			continue
+		}
+		node.TaskRun = name
+		if tr, ok := byName[name]; ok {
+			node.Status, node.Message = taskRunStatus(tr)
+		}
+	}
+}
+
+// taskRunStatus is the status of tr and, when it failed, why
+func taskRunStatus(tr *tektonv1.TaskRun) (string, string) {
+	cond := tr.Status.GetCondition(apis.ConditionSucceeded)
+	switch {
+	case cond == nil:
+		return StatusPending, ""
+	case cond.Status == corev1.ConditionTrue:
+		return StatusSucceeded, ""
+	case cond.Status == corev1.ConditionFalse && cond.Reason == tektonv1.TaskRunReasonCancelled.String():
+		return StatusCancelled, cond.Message
+	case cond.Status == corev1.ConditionFalse:
+		return StatusFailed, cond.Message
+	case cond.Reason == tektonv1.TaskRunReasonRunning.String() || tr.Status.StartTime != nil:
+		return StatusRunning, ""
+	}
+	return StatusPending, ""
+}
+
+// Stages groups the tasks into the order they run in: every task is in the stage after the last
+// of the tasks it depends on, and finally tasks come last
+func (g *Graph) Stages() [][]Node {
+	depends := map[string][]string{}
+	for _, e := range g.Edges {
+		depends[e.To] = append(depends[e.To], e.From)
+	}
+	level := map[string]int{}
+	var levelOf func(name string, seen map[string]bool) int
+	levelOf = func(name string, seen map[string]bool) int {
+		if l, ok := level[name]; ok {
+			return l
+		}
+		if seen[name] {
+			// Tekton rejects cycles; do not loop on a graph built by hand
+			return 0
+		}
+		seen[name] = true
+		l := 0
+		for _, dep := range depends[name] {
+			l = max(l, levelOf(dep, seen)+1)
+		}
+		level[name] = l
+		return l
+	}
+	var stages [][]Node
+	for _, node := range g.Nodes {
+		l := levelOf(node.Name, map[string]bool{})
+		for len(stages) <= l {
+			stages = append(stages, nil)
+		}
+		stages[l] = append(stages[l], node)
+	}
+	return stages
+}
+
+// DOT renders the graph in the Graphviz DOT language, tasks colored by status
+func (g *Graph) DOT() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "digraph %s {\n", dotQuote(g.Pipeline))
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"sans-serif\"];\n")
+	for _, node := range g.Nodes {
+		label := []string{node.Name}
+		if node.Task != "" && node.Task != node.Name {
+			label = append(label, node.Task)
+		}
+		label = append(label, node.Status)
+		attrs := fmt.Sprintf("label=%s, fillcolor=%s", dotQuote(strings.Join(label, "\n")), dotQuote(statusColors[node.Status]))
+		if node.Finally {
+			attrs += ", peripheries=2"
+		}
+		if len(node.When) > 0 {
+			attrs += ", tooltip=" + dotQuote("when "+strings.Join(node.When, " and "))
+		}
+		fmt.Fprintf(&sb, "  %s [%s];\n", dotQuote(node.Name), attrs)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "  %s -> %s;\n", dotQuote(e.From), dotQuote(e.To))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// statusColors are the fill colors of the statuses in DOT
+var statusColors = map[string]string{
+	StatusPending:   "white",
+	StatusRunning:   "lightblue",
+	StatusSucceeded: "palegreen",
+	StatusFailed:    "salmon",
+	StatusCancelled: "lightgrey",
+	StatusSkipped:   "lightyellow",
+}
+
+// dotQuote quotes s as a DOT ID; newlines become line breaks of labels
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// Text renders the graph for a terminal: the stages in order, each task with its status, what it
+// runs after and its conditions
+func (g *Graph) Text() string {
+	var sb strings.Builder
+	if g.Run != "" {
+		fmt.Fprintf(&sb, "Pipeline %s, run %s\n", g.Pipeline, g.Run)
+	} else {
+		fmt.Fprintf(&sb, "Pipeline %s, not run yet\n", g.Pipeline)
+	}
+	depends := map[string][]string{}
+	for _, e := range g.Edges {
+		depends[e.To] = append(depends[e.To], e.From)
+	}
+	width := 0
+	for _, node := range g.Nodes {
+		width = max(width, len(node.Name))
+	}
+	for i, stage := range g.Stages() {
+		fmt.Fprintf(&sb, "Stage %d\n", i+1)
+		for _, node := range stage {
+			line := fmt.Sprintf("  %-11s %-*s", "["+node.Status+"]", width, node.Name)
+			if node.Task != "" && node.Task != node.Name {
+				line += "  task " + node.Task
+			}
+			if after := depends[node.Name]; len(after) > 0 {
+				line += "  after " + strings.Join(after, ", ")
+			}
+			if node.Finally {
+				line += "  (finally)"
+			}
+			sb.WriteString(strings.TrimRight(line, " ") + "\n")
+			for _, when := range node.When {
+				fmt.Fprintf(&sb, "  %-11s %-*s  when %s\n", "", width, "", when)
+			}
+			if node.Message != "" {
+				fmt.Fprintf(&sb, "  %-11s %-*s  %s\n", "", width, "", node.Message)
+			}
+		}
+	}
+	return sb.String()
+}
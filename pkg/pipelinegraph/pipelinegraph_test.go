@@ -0,0 +1,153 @@
+package pipelinegraph
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/internal/common/tasks"
+)
+
+// taskRun returns a TaskRun whose Succeeded condition has status and reason
+func taskRun(name string, status corev1.ConditionStatus, reason, message string) tektonv1.TaskRun {
+	tr := tektonv1.TaskRun{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if status != "" {
+		tr.Status.Status = duckv1.Status{Conditions: duckv1.Conditions{{
+			Type: apis.ConditionSucceeded, Status: status, Reason: reason, Message: message,
+		}}}
+	}
+	return tr
+}
+
+// chained is a pipeline with a fan-out, a result reference and a finally task
+func chained() *tektonv1.Pipeline {
+	return &tektonv1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: "chained"},
+		Spec: tektonv1.PipelineSpec{
+			Tasks: []tektonv1.PipelineTask{
+				{Name: "fetch", TaskRef: &tektonv1.TaskRef{Name: "git-clone"}},
+				{Name: "build-a", RunAfter: []string{"fetch"}},
+				{Name: "build-b", RunAfter: []string{"fetch"}},
+				{Name: "publish", Params: tektonv1.Params{{
+					Name: "image", Value: *tektonv1.NewStructuredValues("$(tasks.build-a.results.image)"),
+				}}, RunAfter: []string{"build-b"}},
+			},
+			Finally: []tektonv1.PipelineTask{{Name: "notify"}},
+		},
+	}
+}
+
+var _ = Describe("New", func() {
+	It("should export the build pipeline of the operator", func() {
+		g := New(tasks.GenerateTektonPipeline("automotive-build-pipeline", "automotive-dev-operator-system"))
+		Expect(g.Pipeline).To(Equal("automotive-build-pipeline"))
+		Expect(g.Nodes).To(HaveLen(2))
+		Expect(g.Nodes[0].Name).To(Equal("build-image"))
+		Expect(g.Nodes[0].Task).To(Equal("build-automotive-image"))
+		Expect(g.Nodes[0].Status).To(Equal(StatusPending))
+		Expect(g.Nodes[1].Name).To(Equal("push-registry"))
+		Expect(g.Nodes[1].Task).To(Equal("push-artifact-registry"))
+		Expect(g.Nodes[1].When).To(ContainElement("$(params.repository-url) notin [, null]"))
+		Expect(g.Edges).To(Equal([]Edge{{From: "build-image", To: "push-registry"}}))
+	})
+
+	It("should order tasks by runAfter, result references and finally", func() {
+		g := New(chained())
+		Expect(g.Edges).To(ConsistOf(
+			Edge{"fetch", "build-a"}, Edge{"fetch", "build-b"},
+			Edge{"build-b", "publish"}, Edge{"build-a", "publish"},
+			Edge{"publish", "notify"},
+		))
+		Expect(g.Nodes[4].Finally).To(BeTrue())
+
+		var names [][]string
+		for _, stage := range g.Stages() {
+			var stageNames []string
+			for _, node := range stage {
+				stageNames = append(stageNames, node.Name)
+			}
+			names = append(names, stageNames)
+		}
+		Expect(names).To(Equal([][]string{{"fetch"}, {"build-a", "build-b"}, {"publish"}, {"notify"}}))
+	})
+})
+
+var _ = Describe("SetRun", func() {
+	It("should take the status of every task from its TaskRun", func() {
+		g := New(chained())
+		run := &tektonv1.PipelineRun{ObjectMeta: metav1.ObjectMeta{Name: "chained-run-1"}}
+		run.Status.ChildReferences = []tektonv1.ChildStatusReference{
+			{Name: "chained-run-1-fetch", PipelineTaskName: "fetch"},
+			{Name: "chained-run-1-build-a", PipelineTaskName: "build-a"},
+			{Name: "chained-run-1-build-b", PipelineTaskName: "build-b"},
+			{Name: "chained-run-1-notify", PipelineTaskName: "notify"},
+		}
+		run.Status.SkippedTasks = []tektonv1.SkippedTask{{Name: "publish", Reason: tektonv1.ParentTasksSkip}}
+		running := taskRun("chained-run-1-build-b", corev1.ConditionUnknown, "Running", "")
+		running.Status.StartTime = &metav1.Time{Time: time.Now()}
+		g.SetRun(run, []tektonv1.TaskRun{
+			taskRun("chained-run-1-fetch", corev1.ConditionTrue, "Succeeded", ""),
+			taskRun("chained-run-1-build-a", corev1.ConditionFalse, "Failed", "step build exited with 1"),
+			running,
+			taskRun("chained-run-1-notify", corev1.ConditionFalse, "TaskRunCancelled", "cancelled"),
+		})
+
+		Expect(g.Run).To(Equal("chained-run-1"))
+		statuses := map[string]string{}
+		for _, node := range g.Nodes {
+			statuses[node.Name] = node.Status
+		}
+		Expect(statuses).To(Equal(map[string]string{
+			"fetch": StatusSucceeded, "build-a": StatusFailed, "build-b": StatusRunning,
+			"publish": StatusSkipped, "notify": StatusCancelled,
+		}))
+		Expect(g.Nodes[1].TaskRun).To(Equal("chained-run-1-build-a"))
+		Expect(g.Nodes[1].Message).To(Equal("step build exited with 1"))
+		Expect(g.Nodes[3].Message).To(Equal(string(tektonv1.ParentTasksSkip)))
+	})
+
+	It("should leave tasks without a TaskRun pending", func() {
+		g := New(chained())
+		g.SetRun(&tektonv1.PipelineRun{ObjectMeta: metav1.ObjectMeta{Name: "chained-run-2"}}, nil)
+		for _, node := range g.Nodes {
+			Expect(node.Status).To(Equal(StatusPending))
+		}
+	})
+})
+
+var _ = Describe("rendering", func() {
+	It("should render DOT with quoted names and status colors", func() {
+		g := New(tasks.GenerateTektonPipeline("automotive-build-pipeline", "ns"))
+		g.Nodes[0].Status = StatusFailed
+		g.Nodes[0].Message = `exit "1"`
+		dot := g.DOT()
+		Expect(dot).To(HavePrefix(`digraph "automotive-build-pipeline" {`))
+		Expect(dot).To(ContainSubstring(`"build-image" [label="build-image\nbuild-automotive-image\nFailed", fillcolor="salmon"];`))
+		Expect(dot).To(ContainSubstring(`"build-image" -> "push-registry";`))
+		Expect(dot).To(ContainSubstring(`tooltip="when $(params.repository-url) notin [, null]`))
+		Expect(dotQuote(`a"b\c`)).To(Equal(`"a\"b\\c"`))
+	})
+
+	It("should render the stages as text", func() {
+		g := New(chained())
+		g.Run = "chained-run-1"
+		g.Nodes[0].Status = StatusSucceeded
+		Expect(g.Text()).To(Equal(`Pipeline chained, run chained-run-1
+Stage 1
+  [Succeeded] fetch    task git-clone
+Stage 2
+  [Pending]   build-a  after fetch
+  [Pending]   build-b  after fetch
+Stage 3
+  [Pending]   publish  after build-b, build-a
+Stage 4
+  [Pending]   notify   after publish  (finally)
+`))
+	})
+})
@@ -0,0 +1,185 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// ServiceName is the gRPC service sidecar plugins serve. Messages are the JSON encoding of the
+// types in this package, so plugins can also be written in other languages with any gRPC
+// library that supports custom codecs (content-subtype "json").
+const ServiceName = "automotive.plugin.v1.Plugin"
+
+// codec marshals messages as JSON; the plugin protocol has no protobuf definitions
+type codec struct{}
+
+func (codec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (codec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (codec) Name() string                       { return "json" }
+
+type empty struct{}
+
+// Serve serves p on lis until the listener fails. Sidecar plugins call it from their main function,
+// typically on a unix socket in a volume shared with the operator container.
+func Serve(lis net.Listener, p Plugin) error {
+	return NewServer(p).Serve(lis)
+}
+
+// NewServer returns a gRPC server with p registered as the plugin service
+func NewServer(p Plugin, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(append([]grpc.ServerOption{grpc.ForceServerCodec(codec{})}, opts...)...)
+	s.RegisterService(&serviceDesc, &server{plugin: p})
+	return s
+}
+
+type server struct {
+	plugin Plugin
+}
+
+func (s *server) describe(ctx context.Context, _ *empty) (*Info, error) {
+	kinds, err := kindsOf(ctx, s.plugin)
+	if err != nil {
+		return nil, err
+	}
+	return &Info{Name: s.plugin.Name(), Kinds: kinds}, nil
+}
+
+func (s *server) notify(ctx context.Context, event *Event) (*empty, error) {
+	n, ok := s.plugin.(Notifier)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "plugin %s is not a notifier", s.plugin.Name())
+	}
+	return &empty{}, n.Notify(ctx, *event)
+}
+
+func (s *server) publish(ctx context.Context, event *Event) (*PublishResult, error) {
+	p, ok := s.plugin.(Publisher)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "plugin %s is not a publisher", s.plugin.Name())
+	}
+	return p.Publish(ctx, *event)
+}
+
+func (s *server) scan(ctx context.Context, event *Event) (*ScanResult, error) {
+	sc, ok := s.plugin.(Scanner)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "plugin %s is not a scanner", s.plugin.Name())
+	}
+	return sc.Scan(ctx, *event)
+}
+
+// unaryHandler adapts a typed server method to a grpc.MethodDesc handler
+func unaryHandler[Req, Resp any](method string, fn func(*server, context.Context, *Req) (*Resp, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: method,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(Req)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			s := srv.(*server)
+			if interceptor == nil {
+				return fn(s, ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/" + method}
+			return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return fn(s, ctx, req.(*Req))
+			})
+		},
+	}
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryHandler("Describe", (*server).describe),
+		unaryHandler("Notify", (*server).notify),
+		unaryHandler("Publish", (*server).publish),
+		unaryHandler("Scan", (*server).scan),
+	},
+	Metadata: "plugin.proto",
+}
+
+// Client calls a plugin served by a sidecar. It implements Notifier, Publisher, Scanner and
+// Describer; which of them the sidecar supports is reported by Describe.
+type Client struct {
+	name string
+	conn *grpc.ClientConn
+
+	mu   sync.Mutex
+	info *Info
+}
+
+// NewClient returns a Client for the sidecar listening on target, e.g. "unix:///var/run/plugins/ota.sock"
+// or "localhost:9000". The connection is established lazily, so the sidecar may start after the operator.
+func NewClient(name, target string) (*Client, error) {
+	conn, err := grpc.NewClient(target,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec{})),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("connect to plugin %s at %s: %w", name, target, err)
+	}
+	return &Client{name: name, conn: conn}, nil
+}
+
+// Name returns the name the plugin was configured with
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Close closes the connection to the sidecar
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Describe asks the sidecar which kinds it supports; a successful answer is cached
+func (c *Client) Describe(ctx context.Context) (*Info, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.info != nil {
+		return c.info, nil
+	}
+	info := &Info{}
+	if err := c.invoke(ctx, "Describe", &empty{}, info); err != nil {
+		return nil, err
+	}
+	c.info = info
+	return info, nil
+}
+
+// Notify implements Notifier
+func (c *Client) Notify(ctx context.Context, event Event) error {
+	return c.invoke(ctx, "Notify", &event, &empty{})
+}
+
+// Publish implements Publisher
+func (c *Client) Publish(ctx context.Context, event Event) (*PublishResult, error) {
+	out := &PublishResult{}
+	if err := c.invoke(ctx, "Publish", &event, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Scan implements Scanner
+func (c *Client) Scan(ctx context.Context, event Event) (*ScanResult, error) {
+	out := &ScanResult{}
+	if err := c.invoke(ctx, "Scan", &event, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) invoke(ctx context.Context, method string, in, out any) error {
+	return c.conn.Invoke(ctx, "/"+ServiceName+"/"+method, in, out)
+}
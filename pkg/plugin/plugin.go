@@ -0,0 +1,274 @@
+// Package plugin defines the interfaces the operator uses to hand finished image builds to
+// integrations such as OTA backends, ticketing systems or artifact scanners, and a gRPC
+// transport that lets those integrations run out of process as sidecars of the operator.
+//
+// A plugin implements one or more of Notifier, Publisher and Scanner. Plugins compiled into the
+// operator are registered with Register; plugins running as sidecars are served with Serve and
+// reached through a Client.
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kind is a capability a plugin provides
+type Kind string
+
+const (
+	// KindNotifier plugins are told about every finished build
+	KindNotifier Kind = "notifier"
+	// KindPublisher plugins publish the artifact of a successful build
+	KindPublisher Kind = "publisher"
+	// KindScanner plugins scan the artifact of a successful build
+	KindScanner Kind = "scanner"
+)
+
+// Build describes the ImageBuild an event is about
+type Build struct {
+	Name         string            `json:"name"`
+	Namespace    string            `json:"namespace"`
+	Phase        string            `json:"phase"`
+	Message      string            `json:"message,omitempty"`
+	Distro       string            `json:"distro,omitempty"`
+	Target       string            `json:"target,omitempty"`
+	Architecture string            `json:"architecture,omitempty"`
+	ExportFormat string            `json:"exportFormat,omitempty"`
+	Mode         string            `json:"mode,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	StartTime    *time.Time        `json:"startTime,omitempty"`
+	// CompletionTime is when the build reached its final phase
+	CompletionTime *time.Time `json:"completionTime,omitempty"`
+}
+
+// Artifact describes where the output of a successful build can be fetched
+type Artifact struct {
+	FileName  string `json:"fileName"`
+	SizeBytes int64  `json:"sizeBytes,omitempty"`
+	// SHA256 is the hex digest of the artifact file
+	SHA256 string `json:"sha256,omitempty"`
+	// URL is set when the build serves its artifact through a route
+	URL string `json:"url,omitempty"`
+	// PVCName and Path locate the artifact on the build workspace volume
+	PVCName string `json:"pvcName,omitempty"`
+	Path    string `json:"path,omitempty"`
+}
+
+// Event is sent to plugins when a build reaches Completed or Failed
+type Event struct {
+	Build Build `json:"build"`
+	// Artifact is nil for failed builds
+	Artifact *Artifact `json:"artifact,omitempty"`
+}
+
+// PublishResult is returned by a Publisher
+type PublishResult struct {
+	// Location identifies where the artifact was published, e.g. an OTA release ID or URL
+	Location string `json:"location,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// Finding is a single issue reported by a Scanner
+type Finding struct {
+	ID       string `json:"id"`
+	Severity string `json:"severity,omitempty"`
+	Title    string `json:"title,omitempty"`
+}
+
+// ScanResult is returned by a Scanner
+type ScanResult struct {
+	Passed   bool      `json:"passed"`
+	Summary  string    `json:"summary,omitempty"`
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// Plugin is implemented by every plugin
+type Plugin interface {
+	// Name identifies the plugin in logs and in the ImageBuild status
+	Name() string
+}
+
+// Notifier is told about every build that reaches Completed or Failed
+type Notifier interface {
+	Plugin
+	Notify(ctx context.Context, event Event) error
+}
+
+// Publisher is handed the artifact of every build that reaches Completed
+type Publisher interface {
+	Plugin
+	Publish(ctx context.Context, event Event) (*PublishResult, error)
+}
+
+// Scanner is handed the artifact of every build that reaches Completed
+type Scanner interface {
+	Plugin
+	Scan(ctx context.Context, event Event) (*ScanResult, error)
+}
+
+// Describer is implemented by plugins whose kinds are only known at runtime, such as a Client
+// for a sidecar. Plugins without it provide the kinds of the interfaces they implement.
+type Describer interface {
+	Describe(ctx context.Context) (*Info, error)
+}
+
+// Info describes a plugin
+type Info struct {
+	Name  string `json:"name"`
+	Kinds []Kind `json:"kinds"`
+}
+
+// Result is the outcome of calling one plugin for one event
+type Result struct {
+	Plugin string `json:"plugin"`
+	Kind   Kind   `json:"kind"`
+	// Succeeded is false when the call failed or a scan did not pass
+	Succeeded bool   `json:"succeeded"`
+	Message   string `json:"message,omitempty"`
+	Location  string `json:"location,omitempty"`
+}
+
+// Registry holds the plugins the operator dispatches build events to
+type Registry struct {
+	mu      sync.RWMutex
+	plugins map[string]Plugin
+}
+
+// NewRegistry returns an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{plugins: map[string]Plugin{}}
+}
+
+// DefaultRegistry is the registry used by the operator
+var DefaultRegistry = NewRegistry()
+
+// Register adds p to DefaultRegistry. It is meant to be called from init functions of
+// plugins compiled into the operator and panics if a plugin with the same name exists.
+func Register(p Plugin) {
+	if err := DefaultRegistry.Register(p); err != nil {
+		panic(err)
+	}
+}
+
+// Register adds p to the registry
+func (r *Registry) Register(p Plugin) error {
+	if p == nil || p.Name() == "" {
+		return fmt.Errorf("plugin must have a name")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.plugins[p.Name()]; exists {
+		return fmt.Errorf("plugin %q is already registered", p.Name())
+	}
+	r.plugins[p.Name()] = p
+	return nil
+}
+
+// Len returns the number of registered plugins
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.plugins)
+}
+
+// Dispatch calls every registered plugin for event and returns one Result per call, ordered by
+// plugin name. Notifiers are called for every event; publishers and scanners only for events
+// that carry an artifact. A plugin that cannot be described is reported as a failed notifier.
+func (r *Registry) Dispatch(ctx context.Context, event Event) []Result {
+	r.mu.RLock()
+	plugins := make([]Plugin, 0, len(r.plugins))
+	for _, p := range r.plugins {
+		plugins = append(plugins, p)
+	}
+	r.mu.RUnlock()
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name() < plugins[j].Name() })
+
+	var results []Result
+	for _, p := range plugins {
+		kinds, err := kindsOf(ctx, p)
+		if err != nil {
+			results = append(results, Result{Plugin: p.Name(), Kind: KindNotifier, Message: err.Error()})
+			continue
+		}
+		for _, kind := range kinds {
+			if kind != KindNotifier && event.Artifact == nil {
+				continue
+			}
+			results = append(results, call(ctx, p, kind, event))
+		}
+	}
+	return results
+}
+
+func kindsOf(ctx context.Context, p Plugin) ([]Kind, error) {
+	if d, ok := p.(Describer); ok {
+		info, err := d.Describe(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describe plugin: %w", err)
+		}
+		return info.Kinds, nil
+	}
+	var kinds []Kind
+	if _, ok := p.(Notifier); ok {
+		kinds = append(kinds, KindNotifier)
+	}
+	if _, ok := p.(Publisher); ok {
+		kinds = append(kinds, KindPublisher)
+	}
+	if _, ok := p.(Scanner); ok {
+		kinds = append(kinds, KindScanner)
+	}
+	return kinds, nil
+}
+
+func call(ctx context.Context, p Plugin, kind Kind, event Event) Result {
+	res := Result{Plugin: p.Name(), Kind: kind}
+	var err error
+	switch kind {
+	case KindNotifier:
+		n, ok := p.(Notifier)
+		if !ok {
+			err = fmt.Errorf("plugin does not implement %s", kind)
+			break
+		}
+		err = n.Notify(ctx, event)
+	case KindPublisher:
+		pub, ok := p.(Publisher)
+		if !ok {
+			err = fmt.Errorf("plugin does not implement %s", kind)
+			break
+		}
+		var out *PublishResult
+		if out, err = pub.Publish(ctx, event); err == nil && out != nil {
+			res.Location = out.Location
+			res.Message = out.Message
+		}
+	case KindScanner:
+		s, ok := p.(Scanner)
+		if !ok {
+			err = fmt.Errorf("plugin does not implement %s", kind)
+			break
+		}
+		var out *ScanResult
+		if out, err = s.Scan(ctx, event); err == nil && out != nil {
+			res.Message = out.Summary
+			if !out.Passed {
+				if res.Message == "" {
+					res.Message = fmt.Sprintf("scan reported %d findings", len(out.Findings))
+				}
+				return res
+			}
+		}
+	default:
+		err = fmt.Errorf("unknown plugin kind %q", kind)
+	}
+	if err != nil {
+		res.Message = err.Error()
+		return res
+	}
+	res.Succeeded = true
+	return res
+}
@@ -0,0 +1,109 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type fakeOTA struct {
+	notified []Event
+}
+
+func (f *fakeOTA) Name() string { return "ota" }
+
+func (f *fakeOTA) Notify(_ context.Context, event Event) error {
+	f.notified = append(f.notified, event)
+	return nil
+}
+
+func (f *fakeOTA) Publish(_ context.Context, event Event) (*PublishResult, error) {
+	if event.Artifact.URL == "" {
+		return nil, fmt.Errorf("artifact is not served")
+	}
+	return &PublishResult{Location: "release/" + event.Build.Name}, nil
+}
+
+type fakeScanner struct{}
+
+func (fakeScanner) Name() string { return "scanner" }
+
+func (fakeScanner) Scan(context.Context, Event) (*ScanResult, error) {
+	return &ScanResult{Passed: false, Findings: []Finding{{ID: "CVE-2025-0001", Severity: "high"}}}, nil
+}
+
+var _ = Describe("Registry", func() {
+	completed := Event{
+		Build:    Build{Name: "build-1", Namespace: "ns", Phase: "Completed"},
+		Artifact: &Artifact{FileName: "autosd-qemu.raw", URL: "https://artifacts.example.com"},
+	}
+
+	It("should reject duplicate plugin names", func() {
+		r := NewRegistry()
+		Expect(r.Register(&fakeOTA{})).To(Succeed())
+		Expect(r.Register(&fakeOTA{})).To(MatchError(ContainSubstring("already registered")))
+	})
+
+	It("should call every kind a plugin implements, ordered by name", func() {
+		r := NewRegistry()
+		ota := &fakeOTA{}
+		Expect(r.Register(fakeScanner{})).To(Succeed())
+		Expect(r.Register(ota)).To(Succeed())
+
+		Expect(r.Dispatch(context.Background(), completed)).To(Equal([]Result{
+			{Plugin: "ota", Kind: KindNotifier, Succeeded: true},
+			{Plugin: "ota", Kind: KindPublisher, Succeeded: true, Location: "release/build-1"},
+			{Plugin: "scanner", Kind: KindScanner, Message: "scan reported 1 findings"},
+		}))
+		Expect(ota.notified).To(HaveLen(1))
+	})
+
+	It("should only notify about failed builds", func() {
+		r := NewRegistry()
+		Expect(r.Register(&fakeOTA{})).To(Succeed())
+		Expect(r.Register(fakeScanner{})).To(Succeed())
+
+		failed := Event{Build: Build{Name: "build-2", Phase: "Failed"}}
+		Expect(r.Dispatch(context.Background(), failed)).To(Equal([]Result{
+			{Plugin: "ota", Kind: KindNotifier, Succeeded: true},
+		}))
+	})
+})
+
+var _ = Describe("Sidecar transport", func() {
+	It("should call a plugin served over gRPC", func() {
+		socket := filepath.Join(GinkgoT().TempDir(), "ota.sock")
+		lis, err := net.Listen("unix", socket)
+		Expect(err).NotTo(HaveOccurred())
+		ota := &fakeOTA{}
+		srv := NewServer(ota)
+		go func() { _ = srv.Serve(lis) }()
+		DeferCleanup(srv.Stop)
+
+		c, err := NewClient("ota-sidecar", "unix://"+socket)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(c.Close)
+
+		info, err := c.Describe(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info).To(Equal(&Info{Name: "ota", Kinds: []Kind{KindNotifier, KindPublisher}}))
+
+		r := NewRegistry()
+		Expect(r.Register(c)).To(Succeed())
+		Expect(r.Dispatch(context.Background(), Event{
+			Build:    Build{Name: "build-1", Phase: "Completed"},
+			Artifact: &Artifact{FileName: "autosd-qemu.raw"},
+		})).To(Equal([]Result{
+			{Plugin: "ota-sidecar", Kind: KindNotifier, Succeeded: true},
+			{Plugin: "ota-sidecar", Kind: KindPublisher, Message: "rpc error: code = Unknown desc = artifact is not served"},
+		}))
+		Expect(ota.notified).To(HaveLen(1))
+
+		_, err = c.Scan(context.Background(), Event{})
+		Expect(err).To(MatchError(ContainSubstring("not a scanner")))
+	})
+})
@@ -0,0 +1,160 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around changes
+const diffContext = 3
+
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// Diff returns a unified diff of the lines of a and b, or "" when they are equal. Removed lines
+// are red, added lines green and the file and hunk headers faint.
+func (r Renderer) Diff(aName, bName, a, b string) string {
+	lines := diffLines(splitLines(a), splitLines(b))
+	hunks := diffHunks(lines)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(r.paint(faint, resetFaint, "--- "+aName) + "\n")
+	sb.WriteString(r.paint(faint, resetFaint, "+++ "+bName) + "\n")
+	for _, h := range hunks {
+		sb.WriteString(r.paint(faint, resetFaint, h.header()) + "\n")
+		for _, l := range lines[h.start:h.end] {
+			text := string(l.op) + l.text
+			switch l.op {
+			case '-':
+				text = r.paint(red, resetColor, text)
+			case '+':
+				text = r.paint(green, resetColor, text)
+			}
+			sb.WriteString(text + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func (r Renderer) paint(color, reset, s string) string {
+	if !r.Color {
+		return s
+	}
+	return color + s + reset
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines aligns a and b along their longest common subsequence
+func diffLines(a, b []string) []diffLine {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, diffLine{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, diffLine{'-', a[i]})
+			i++
+		default:
+			out = append(out, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	return out
+}
+
+// diffHunk is a range of diff lines with the line numbers they start at in a and b
+type diffHunk struct {
+	start, end     int
+	aStart, bStart int
+	aLen, bLen     int
+}
+
+func (h diffHunk) header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.aStart, h.aLen), hunkRange(h.bStart, h.bLen))
+}
+
+// hunkRange formats a range of a hunk header; empty ranges start at the line before them
+func hunkRange(start, n int) string {
+	if n == 0 {
+		start--
+	}
+	if n == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+// diffHunks groups changed lines with diffContext lines around them, merging groups whose
+// context would overlap
+func diffHunks(lines []diffLine) []diffHunk {
+	var hunks []diffHunk
+	for i, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		start, end := max(0, i-diffContext), min(len(lines), i+diffContext+1)
+		if n := len(hunks); n > 0 && start <= hunks[n-1].end {
+			hunks[n-1].end = end
+			continue
+		}
+		hunks = append(hunks, diffHunk{start: start, end: end})
+	}
+
+	aLine, bLine, pos := 1, 1, 0
+	for k := range hunks {
+		h := &hunks[k]
+		for ; pos < h.start; pos++ {
+			aLine, bLine = advance(lines[pos].op, aLine, bLine)
+		}
+		h.aStart, h.bStart = aLine, bLine
+		for ; pos < h.end; pos++ {
+			if lines[pos].op != '+' {
+				h.aLen++
+			}
+			if lines[pos].op != '-' {
+				h.bLen++
+			}
+			aLine, bLine = advance(lines[pos].op, aLine, bLine)
+		}
+	}
+	return hunks
+}
+
+func advance(op byte, aLine, bLine int) (int, int) {
+	if op != '+' {
+		aLine++
+	}
+	if op != '-' {
+		bLine++
+	}
+	return aLine, bLine
+}
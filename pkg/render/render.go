@@ -0,0 +1,162 @@
+// Package render formats builds for people reading caib output: phases colored by outcome,
+// durations rounded to what matters at their scale and timestamps relative to now.
+//
+// Colors follow the NO_COLOR convention (https://no-color.org): they are off when NO_COLOR is set
+// to a non-empty value, when TERM is dumb, when the user asked for no color or when the output is
+// not a terminal.
+package render
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	green  = "\033[32m"
+	red    = "\033[31m"
+	yellow = "\033[33m"
+	faint  = "\033[2m"
+	// resetColor and resetFaint end a single attribute rather than all of them, so a colored
+	// phase can sit inside a row that is highlighted as a whole
+	resetColor = "\033[39m"
+	resetFaint = "\033[22m"
+)
+
+// Renderer formats build information, using colors when Color is set
+type Renderer struct {
+	Color bool
+}
+
+// New returns a Renderer for output written to w; noColor is the user's request for plain output
+func New(w io.Writer, noColor bool) Renderer {
+	return Renderer{Color: ColorEnabled(w, noColor)}
+}
+
+// ColorEnabled reports whether output written to w may use colors
+func ColorEnabled(w io.Writer, noColor bool) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// PhaseName shows builds the controller has not picked up yet as Pending
+func PhaseName(phase string) string {
+	if phase == "" {
+		return "Pending"
+	}
+	return phase
+}
+
+// Phase returns the name of phase colored by outcome: green when Completed, red when Failed,
+// yellow while the build is in progress and faint when Cancelled
+func (r Renderer) Phase(phase string) string {
+	return r.PaddedPhase(phase, 0)
+}
+
+// PaddedPhase is Phase left-aligned in width columns. The padding is added before the colors,
+// whose escape sequences would otherwise count towards the width of table columns.
+func (r Renderer) PaddedPhase(phase string, width int) string {
+	name := fmt.Sprintf("%-*s", width, PhaseName(phase))
+	if !r.Color {
+		return name
+	}
+	switch phase {
+	case "Completed":
+		return green + name + resetColor
+	case "Failed":
+		return red + name + resetColor
+	case "Queued", "Uploading", "Building":
+		return yellow + name + resetColor
+	case "Cancelled":
+		return faint + name + resetFaint
+	}
+	return name
+}
+
+// Duration formats d with its two most significant units, e.g. 45s, 3m12s, 1h5m or 2d3h.
+// Negative durations, from clocks that disagree, are shown as 0s.
+func Duration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < 0 {
+		d = 0
+	}
+	const day = 24 * time.Hour
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", d/time.Second)
+	case d < time.Hour:
+		return units(int64(d/time.Minute), "m", int64(d%time.Minute/time.Second), "s")
+	case d < day:
+		return units(int64(d/time.Hour), "h", int64(d%time.Hour/time.Minute), "m")
+	default:
+		return units(int64(d/day), "d", int64(d%day/time.Hour), "h")
+	}
+}
+
+func units(major int64, majorUnit string, minor int64, minorUnit string) string {
+	if minor == 0 {
+		return fmt.Sprintf("%d%s", major, majorUnit)
+	}
+	return fmt.Sprintf("%d%s%d%s", major, majorUnit, minor, minorUnit)
+}
+
+// Ago describes t relative to now in its most significant unit, e.g. "just now", "5m ago" or
+// "3d ago". Times more than 30 days ago are shown as dates.
+func Ago(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < -time.Minute:
+		// the server clock is ahead of ours
+		return "in " + coarse(-d)
+	case d < 10*time.Second:
+		return "just now"
+	case d > 30*24*time.Hour:
+		return t.Local().Format("2006-01-02")
+	}
+	return coarse(d) + " ago"
+}
+
+func coarse(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", d/time.Second)
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dd", d/(24*time.Hour))
+}
+
+// Timestamp is Ago for an RFC 3339 timestamp as returned by the build API. Empty timestamps are
+// shown as "-" and ones that do not parse as they are.
+func Timestamp(raw string, now time.Time) string {
+	if raw == "" {
+		return "-"
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return raw
+	}
+	return Ago(t, now)
+}
+
+// Elapsed is the Duration between the RFC 3339 timestamps start and end, or up to now while end
+// is empty, e.g. for builds still running. It is "-" when start is not set.
+func Elapsed(start, end string, now time.Time) string {
+	from, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		return "-"
+	}
+	to := now
+	if t, err := time.Parse(time.RFC3339, end); err == nil {
+		to = t
+	}
+	return Duration(to.Sub(from))
+}
@@ -0,0 +1,130 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Renderer", func() {
+	It("should color phases by outcome", func() {
+		r := Renderer{Color: true}
+		Expect(r.Phase("Completed")).To(Equal("\033[32mCompleted\033[39m"))
+		Expect(r.Phase("Failed")).To(Equal("\033[31mFailed\033[39m"))
+		Expect(r.Phase("Building")).To(Equal("\033[33mBuilding\033[39m"))
+		Expect(r.Phase("Queued")).To(Equal("\033[33mQueued\033[39m"))
+		Expect(r.Phase("Cancelled")).To(Equal("\033[2mCancelled\033[22m"))
+		Expect(r.Phase("")).To(Equal("Pending"))
+	})
+
+	It("should pad phases before coloring them", func() {
+		Expect(Renderer{Color: true}.PaddedPhase("Failed", 8)).To(Equal("\033[31mFailed  \033[39m"))
+		Expect(Renderer{}.PaddedPhase("", 9)).To(Equal("Pending  "))
+	})
+
+	It("should not use colors without a terminal or with NO_COLOR", func() {
+		Expect(ColorEnabled(&bytes.Buffer{}, false)).To(BeFalse())
+		GinkgoT().Setenv("NO_COLOR", "1")
+		Expect(ColorEnabled(nil, false)).To(BeFalse())
+		Expect(New(&bytes.Buffer{}, false).Phase("Failed")).To(Equal("Failed"))
+	})
+})
+
+var _ = Describe("Duration", func() {
+	DescribeTable("should keep the two most significant units",
+		func(d time.Duration, want string) {
+			Expect(Duration(d)).To(Equal(want))
+		},
+		Entry("seconds", 45*time.Second+300*time.Millisecond, "45s"),
+		Entry("minutes", 3*time.Minute+12*time.Second, "3m12s"),
+		Entry("whole minutes", 2*time.Minute, "2m"),
+		Entry("hours", time.Hour+5*time.Minute+40*time.Second, "1h5m"),
+		Entry("days", 51*time.Hour+30*time.Minute, "2d3h"),
+		Entry("negative", -5*time.Second, "0s"),
+	)
+})
+
+var _ = Describe("Ago", func() {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	DescribeTable("should describe times relative to now",
+		func(t time.Time, want string) {
+			Expect(Ago(t, now)).To(Equal(want))
+		},
+		Entry("just now", now.Add(-3*time.Second), "just now"),
+		Entry("slightly ahead", now.Add(20*time.Second), "just now"),
+		Entry("seconds", now.Add(-42*time.Second), "42s ago"),
+		Entry("minutes", now.Add(-5*time.Minute-50*time.Second), "5m ago"),
+		Entry("hours", now.Add(-3*time.Hour), "3h ago"),
+		Entry("days", now.Add(-50*time.Hour), "2d ago"),
+		Entry("ahead", now.Add(10*time.Minute), "in 10m"),
+	)
+
+	It("should show old times as dates", func() {
+		t := now.AddDate(0, -2, 0)
+		Expect(Ago(t, now)).To(Equal(t.Local().Format("2006-01-02")))
+	})
+
+	It("should render API timestamps", func() {
+		Expect(Timestamp("2026-10-16T11:00:00Z", now)).To(Equal("1h ago"))
+		Expect(Timestamp("", now)).To(Equal("-"))
+		Expect(Timestamp("yesterday", now)).To(Equal("yesterday"))
+	})
+
+	It("should measure elapsed time between API timestamps", func() {
+		Expect(Elapsed("2026-10-16T11:00:00Z", "2026-10-16T11:12:30Z", now)).To(Equal("12m30s"))
+		Expect(Elapsed("2026-10-16T11:30:00Z", "", now)).To(Equal("30m"))
+		Expect(Elapsed("", "", now)).To(Equal("-"))
+	})
+})
+
+var _ = Describe("Diff", func() {
+	It("should be empty for equal texts", func() {
+		Expect(Renderer{}.Diff("a", "b", "x\ny\n", "x\ny\n")).To(BeEmpty())
+	})
+
+	It("should show changes with three lines of context", func() {
+		old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+		changed := strings.Replace(old, "5\n", "five\n", 1) + "sixteen\n"
+		Expect(Renderer{}.Diff("old", "new", old, changed)).To(Equal(`--- old
++++ new
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+@@ -13,3 +13,4 @@
+ 13
+ 14
+ 15
++sixteen
+`))
+	})
+
+	It("should merge changes whose context overlaps", func() {
+		Expect(Renderer{}.Diff("a", "b", "1\n2\n3\n", "0\n1\n3\n")).To(Equal(`--- a
++++ b
+@@ -1,3 +1,3 @@
++0
+ 1
+-2
+ 3
+`))
+	})
+
+	It("should count lines from zero for an empty side", func() {
+		Expect(Renderer{}.Diff("a", "b", "", "x\n")).To(Equal("--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n"))
+	})
+
+	It("should color removed and added lines", func() {
+		Expect(Renderer{Color: true}.Diff("a", "b", "x\n", "y\n")).To(Equal(
+			"\033[2m--- a\033[22m\n\033[2m+++ b\033[22m\n\033[2m@@ -1 +1 @@\033[22m\n\033[31m-x\033[39m\n\033[32m+y\033[39m\n"))
+	})
+})
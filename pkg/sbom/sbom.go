@@ -0,0 +1,350 @@
+// Package sbom renders the RPMs installed in an image as a software bill of materials, in SPDX 2.3
+// or CycloneDX 1.5 JSON.
+//
+// The build lists the packages of the image's RPM database into an Inventory; the documents are
+// generated from it on request, so one inventory serves both formats.
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Formats of the bill of materials
+const (
+	FormatSPDX      = "spdx"
+	FormatCycloneDX = "cyclonedx"
+)
+
+// Media types of the formats
+const (
+	MediaTypeSPDX      = "application/spdx+json"
+	MediaTypeCycloneDX = "application/vnd.cyclonedx+json"
+)
+
+// Inventory is the package list the build writes for an image
+type Inventory struct {
+	OS       OS        `json:"os"`
+	Packages []Package `json:"packages"`
+}
+
+// OS identifies the operating system of the image, from its os-release
+type OS struct {
+	// ID is the os-release ID, e.g. centos or rhel
+	ID        string `json:"id,omitempty"`
+	VersionID string `json:"versionId,omitempty"`
+	// Name is the os-release PRETTY_NAME
+	Name string `json:"name,omitempty"`
+}
+
+// Package is an installed RPM
+type Package struct {
+	Name    string `json:"name"`
+	Epoch   string `json:"epoch,omitempty"`
+	Version string `json:"version"`
+	Release string `json:"release"`
+	Arch    string `json:"arch"`
+	// License is the License tag of the RPM, which may not be an SPDX license expression
+	License   string `json:"license,omitempty"`
+	Vendor    string `json:"vendor,omitempty"`
+	SourceRPM string `json:"sourceRpm,omitempty"`
+}
+
+// EVR is the epoch:version-release of p, without the epoch when it is empty or 0
+func (p Package) EVR() string {
+	if p.Epoch == "" || p.Epoch == "0" {
+		return p.Version + "-" + p.Release
+	}
+	return p.Epoch + ":" + p.Version + "-" + p.Release
+}
+
+// Subject is the image a bill of materials describes
+type Subject struct {
+	// Name names the document, e.g. the build name
+	Name string
+	// Namespace and UID make the document namespace unique
+	Namespace string
+	UID       string
+	// Artifact is the file name of the image and ArtifactSHA256 its hex digest
+	Artifact       string
+	ArtifactSHA256 string
+	// Created is when the image was built
+	Created time.Time
+	// Tool is the name and version of the generator, e.g. automotive-dev-operator-v1.2.0
+	Tool string
+}
+
+// purlNamespaces maps os-release IDs to the purl namespace of their RPMs
+var purlNamespaces = map[string]string{
+	"rhel":   "redhat",
+	"centos": "centos",
+	"autosd": "centos",
+	"fedora": "fedora",
+}
+
+// PURL returns the package URL of p on os, e.g.
+// pkg:rpm/centos/bash@5.1.8-9.el9?arch=aarch64&distro=centos-9
+func PURL(p Package, os OS) string {
+	namespace := purlNamespaces[os.ID]
+	if namespace == "" {
+		namespace = strings.ToLower(os.ID)
+	}
+	q := url.Values{}
+	if p.Arch != "" {
+		q.Set("arch", p.Arch)
+	}
+	if p.Epoch != "" && p.Epoch != "0" {
+		q.Set("epoch", p.Epoch)
+	}
+	if os.ID != "" && os.VersionID != "" {
+		q.Set("distro", os.ID+"-"+os.VersionID)
+	}
+	purl := "pkg:rpm/"
+	if namespace != "" {
+		purl += url.PathEscape(namespace) + "/"
+	}
+	purl += url.PathEscape(p.Name) + "@" + url.PathEscape(p.Version+"-"+p.Release)
+	if len(q) > 0 {
+		purl += "?" + q.Encode()
+	}
+	return purl
+}
+
+// Generate renders inv as a bill of materials of subject in format
+func Generate(format string, subject Subject, inv Inventory) ([]byte, error) {
+	switch format {
+	case FormatSPDX:
+		return json.MarshalIndent(spdx(subject, inv), "", "  ")
+	case FormatCycloneDX:
+		return json.MarshalIndent(cycloneDX(subject, inv), "", "  ")
+	default:
+		return nil, fmt.Errorf("unknown SBOM format %q: must be %s or %s", format, FormatSPDX, FormatCycloneDX)
+	}
+}
+
+// MediaType returns the media type of format
+func MediaType(format string) string {
+	if format == FormatCycloneDX {
+		return MediaTypeCycloneDX
+	}
+	return MediaTypeSPDX
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID                string            `json:"SPDXID"`
+	Name                  string            `json:"name"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	Supplier              string            `json:"supplier,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	Checksums             []spdxChecksum    `json:"checksums,omitempty"`
+	LicenseConcluded      string            `json:"licenseConcluded"`
+	LicenseDeclared       string            `json:"licenseDeclared"`
+	LicenseComments       string            `json:"licenseComments,omitempty"`
+	CopyrightText         string            `json:"copyrightText"`
+	SourceInfo            string            `json:"sourceInfo,omitempty"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+const noAssertion = "NOASSERTION"
+
+// spdxIDChars are the characters an SPDX identifier may not contain
+var spdxIDChars = regexp.MustCompile(`[^A-Za-z0-9.-]`)
+
+func spdx(subject Subject, inv Inventory) spdxDocument {
+	image := spdxPackage{
+		SPDXID:                "SPDXRef-Image",
+		Name:                  subject.Name,
+		DownloadLocation:      noAssertion,
+		LicenseConcluded:      noAssertion,
+		LicenseDeclared:       noAssertion,
+		CopyrightText:         noAssertion,
+		PrimaryPackagePurpose: "OPERATING-SYSTEM",
+	}
+	if subject.Artifact != "" {
+		image.Name = subject.Artifact
+	}
+	if inv.OS.Name != "" {
+		image.VersionInfo = inv.OS.Name
+	}
+	if subject.ArtifactSHA256 != "" {
+		image.Checksums = []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: subject.ArtifactSHA256}}
+	}
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              subject.Name,
+		DocumentNamespace: fmt.Sprintf("https://automotive.sdv.cloud.redhat.com/spdx/%s/%s-%s", url.PathEscape(subject.Namespace), url.PathEscape(subject.Name), url.PathEscape(subject.UID)),
+		CreationInfo: spdxCreationInfo{
+			Created:  subject.Created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + toolName(subject)},
+		},
+		Packages:      []spdxPackage{image},
+		Relationships: []spdxRelationship{{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: image.SPDXID}},
+	}
+	for i, p := range inv.Packages {
+		pkg := spdxPackage{
+			SPDXID:           fmt.Sprintf("SPDXRef-RPM-%d-%s", i+1, spdxIDChars.ReplaceAllString(p.Name, "-")),
+			Name:             p.Name,
+			VersionInfo:      p.EVR(),
+			Supplier:         noAssertion,
+			DownloadLocation: noAssertion,
+			LicenseConcluded: noAssertion,
+			LicenseDeclared:  noAssertion,
+			CopyrightText:    noAssertion,
+			ExternalRefs:     []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: PURL(p, inv.OS)}},
+		}
+		if p.Vendor != "" {
+			pkg.Supplier = "Organization: " + p.Vendor
+		}
+		if p.License != "" {
+			// RPM License tags are not always valid SPDX expressions, so they are kept as a comment
+			pkg.LicenseComments = "RPM License tag: " + p.License
+		}
+		if p.SourceRPM != "" {
+			pkg.SourceInfo = "built from " + p.SourceRPM
+		}
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{SPDXElementID: image.SPDXID, RelationshipType: "CONTAINS", RelatedSPDXElement: pkg.SPDXID})
+	}
+	return doc
+}
+
+type cdxBOM struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber,omitempty"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     cdxTools     `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type      string       `json:"type"`
+	BOMRef    string       `json:"bom-ref,omitempty"`
+	Name      string       `json:"name"`
+	Version   string       `json:"version,omitempty"`
+	Publisher string       `json:"publisher,omitempty"`
+	PURL      string       `json:"purl,omitempty"`
+	Hashes    []cdxHash    `json:"hashes,omitempty"`
+	Licenses  []cdxLicense `json:"licenses,omitempty"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxLicense struct {
+	License cdxLicenseName `json:"license"`
+}
+
+type cdxLicenseName struct {
+	Name string `json:"name"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// uuidPattern matches Kubernetes UIDs, which are UUIDs
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func cycloneDX(subject Subject, inv Inventory) cdxBOM {
+	image := cdxComponent{Type: "operating-system", BOMRef: "image", Name: subject.Name, Version: inv.OS.Name}
+	if subject.Artifact != "" {
+		image.Name = subject.Artifact
+	}
+	if subject.ArtifactSHA256 != "" {
+		image.Hashes = []cdxHash{{Alg: "SHA-256", Content: subject.ArtifactSHA256}}
+	}
+	bom := cdxBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: cdxMetadata{
+			Timestamp: subject.Created.UTC().Format(time.RFC3339),
+			Tools:     cdxTools{Components: []cdxComponent{{Type: "application", Name: toolName(subject)}}},
+			Component: image,
+		},
+		Components: []cdxComponent{},
+	}
+	if uuidPattern.MatchString(subject.UID) {
+		bom.SerialNumber = "urn:uuid:" + strings.ToLower(subject.UID)
+	}
+	seen := map[string]bool{}
+	imageDeps := cdxDependency{Ref: image.BOMRef, DependsOn: []string{}}
+	for _, p := range inv.Packages {
+		purl := PURL(p, inv.OS)
+		if seen[purl] {
+			continue
+		}
+		seen[purl] = true
+		component := cdxComponent{Type: "library", BOMRef: purl, Name: p.Name, Version: p.EVR(), Publisher: p.Vendor, PURL: purl}
+		if p.License != "" {
+			component.Licenses = []cdxLicense{{License: cdxLicenseName{Name: p.License}}}
+		}
+		bom.Components = append(bom.Components, component)
+		imageDeps.DependsOn = append(imageDeps.DependsOn, purl)
+	}
+	bom.Dependencies = []cdxDependency{imageDeps}
+	return bom
+}
+
+func toolName(subject Subject) string {
+	if subject.Tool != "" {
+		return subject.Tool
+	}
+	return "automotive-dev-operator"
+}
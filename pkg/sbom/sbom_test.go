@@ -0,0 +1,99 @@
+package sbom
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var inventory = Inventory{
+	OS: OS{ID: "centos", VersionID: "9", Name: "CentOS Stream 9"},
+	Packages: []Package{
+		{Name: "bash", Version: "5.1.8", Release: "9.el9", Arch: "aarch64", License: "GPLv3+", Vendor: "CentOS", SourceRPM: "bash-5.1.8-9.el9.src.rpm"},
+		{Name: "shadow-utils", Epoch: "2", Version: "4.9", Release: "8.el9", Arch: "aarch64", License: "BSD and GPLv2+"},
+	},
+}
+
+var subject = Subject{
+	Name: "my-build", Namespace: "builds", UID: "6F9619FF-8B86-D011-B42D-00C04FC964FF",
+	Artifact: "my-build.qcow2", ArtifactSHA256: "abc123",
+	Created: time.Date(2026, 10, 16, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600)),
+}
+
+var _ = Describe("PURL", func() {
+	It("should name the package on its distribution", func() {
+		Expect(PURL(inventory.Packages[0], inventory.OS)).To(Equal("pkg:rpm/centos/bash@5.1.8-9.el9?arch=aarch64&distro=centos-9"))
+		Expect(PURL(inventory.Packages[1], inventory.OS)).To(Equal("pkg:rpm/centos/shadow-utils@4.9-8.el9?arch=aarch64&distro=centos-9&epoch=2"))
+	})
+
+	It("should use the purl namespace of the distribution", func() {
+		p := Package{Name: "bash", Version: "5.1.8", Release: "9.el9", Arch: "x86_64"}
+		Expect(PURL(p, OS{ID: "rhel", VersionID: "9.4"})).To(Equal("pkg:rpm/redhat/bash@5.1.8-9.el9?arch=x86_64&distro=rhel-9.4"))
+		Expect(PURL(p, OS{})).To(Equal("pkg:rpm/bash@5.1.8-9.el9?arch=x86_64"))
+	})
+
+	It("should leave out an empty or zero epoch", func() {
+		Expect(Package{Epoch: "0", Version: "1", Release: "2"}.EVR()).To(Equal("1-2"))
+		Expect(Package{Epoch: "3", Version: "1", Release: "2"}.EVR()).To(Equal("3:1-2"))
+	})
+})
+
+var _ = Describe("Generate", func() {
+	It("should render an SPDX document of the packages", func() {
+		data, err := Generate(FormatSPDX, subject, inventory)
+		Expect(err).NotTo(HaveOccurred())
+		var doc spdxDocument
+		Expect(json.Unmarshal(data, &doc)).To(Succeed())
+		Expect(doc.SPDXVersion).To(Equal("SPDX-2.3"))
+		Expect(doc.DocumentNamespace).To(Equal("https://automotive.sdv.cloud.redhat.com/spdx/builds/my-build-6F9619FF-8B86-D011-B42D-00C04FC964FF"))
+		Expect(doc.CreationInfo.Created).To(Equal("2026-10-16T10:00:00Z"))
+		Expect(doc.Packages).To(HaveLen(3))
+		Expect(doc.Packages[0].Name).To(Equal("my-build.qcow2"))
+		Expect(doc.Packages[0].Checksums).To(ConsistOf(spdxChecksum{Algorithm: "SHA256", ChecksumValue: "abc123"}))
+		Expect(doc.Packages[2].SPDXID).To(Equal("SPDXRef-RPM-2-shadow-utils"))
+		Expect(doc.Packages[2].VersionInfo).To(Equal("2:4.9-8.el9"))
+		Expect(doc.Packages[2].LicenseComments).To(Equal("RPM License tag: BSD and GPLv2+"))
+		Expect(doc.Packages[1].Supplier).To(Equal("Organization: CentOS"))
+		Expect(doc.Packages[1].ExternalRefs[0].ReferenceLocator).To(Equal("pkg:rpm/centos/bash@5.1.8-9.el9?arch=aarch64&distro=centos-9"))
+		Expect(doc.Relationships).To(ContainElements(
+			spdxRelationship{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: "SPDXRef-Image"},
+			spdxRelationship{SPDXElementID: "SPDXRef-Image", RelationshipType: "CONTAINS", RelatedSPDXElement: "SPDXRef-RPM-1-bash"},
+		))
+	})
+
+	It("should render a CycloneDX document of the packages", func() {
+		data, err := Generate(FormatCycloneDX, subject, inventory)
+		Expect(err).NotTo(HaveOccurred())
+		var bom cdxBOM
+		Expect(json.Unmarshal(data, &bom)).To(Succeed())
+		Expect(bom.BOMFormat).To(Equal("CycloneDX"))
+		Expect(bom.SpecVersion).To(Equal("1.5"))
+		Expect(bom.SerialNumber).To(Equal("urn:uuid:6f9619ff-8b86-d011-b42d-00c04fc964ff"))
+		Expect(bom.Metadata.Component.Type).To(Equal("operating-system"))
+		Expect(bom.Metadata.Component.Hashes).To(ConsistOf(cdxHash{Alg: "SHA-256", Content: "abc123"}))
+		Expect(bom.Components).To(HaveLen(2))
+		Expect(bom.Components[0].Licenses).To(ConsistOf(cdxLicense{License: cdxLicenseName{Name: "GPLv3+"}}))
+		Expect(bom.Dependencies).To(HaveLen(1))
+		Expect(bom.Dependencies[0].DependsOn).To(HaveLen(2))
+	})
+
+	It("should list a package installed twice once in CycloneDX", func() {
+		inv := inventory
+		inv.Packages = append([]Package{}, inventory.Packages...)
+		inv.Packages = append(inv.Packages, inventory.Packages[0])
+		data, err := Generate(FormatCycloneDX, subject, inv)
+		Expect(err).NotTo(HaveOccurred())
+		var bom cdxBOM
+		Expect(json.Unmarshal(data, &bom)).To(Succeed())
+		Expect(bom.Components).To(HaveLen(2))
+	})
+
+	It("should reject an unknown format", func() {
+		_, err := Generate("swid", subject, inventory)
+		Expect(err).To(MatchError(ContainSubstring("unknown SBOM format")))
+		Expect(MediaType(FormatCycloneDX)).To(Equal(MediaTypeCycloneDX))
+		Expect(MediaType(FormatSPDX)).To(Equal(MediaTypeSPDX))
+	})
+})
@@ -0,0 +1,140 @@
+// Package webhook sends notifications about ImageBuild phase changes to HTTP endpoints and lets
+// the receivers verify them.
+//
+// A notification is a POST request with a JSON Payload. When the webhook has a secret, the body
+// is signed with HMAC-SHA256 and the hex digest is sent as "sha256=<digest>" in the
+// X-ADO-Signature-256 header; receivers check it with Verify before trusting the payload.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/plugin"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 signature of the body of signed notifications
+	SignatureHeader = "X-ADO-Signature-256"
+	// EventHeader carries the Event of the payload
+	EventHeader = "X-ADO-Event"
+	// DeliveryHeader carries the DeliveryID of the payload; retries of a notification keep it
+	DeliveryHeader = "X-ADO-Delivery"
+)
+
+// EventPhaseChanged is sent when a build moves to Uploading, Building, Completed, Failed or Cancelled
+const EventPhaseChanged = "build.phase_changed"
+
+// Payload is the body of a notification
+type Payload struct {
+	Event string `json:"event"`
+	// DeliveryID identifies the notification, so receivers can ignore retries they already handled
+	DeliveryID string    `json:"deliveryID"`
+	Timestamp  time.Time `json:"timestamp"`
+	// PreviousPhase is empty for builds the controller just picked up
+	PreviousPhase string       `json:"previousPhase,omitempty"`
+	Build         plugin.Build `json:"build"`
+	// Artifact is set once a build completed
+	Artifact *plugin.Artifact `json:"artifact,omitempty"`
+}
+
+// Sign returns the value of SignatureHeader for body signed with secret
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature, the value of SignatureHeader, is the signature of body with secret
+func Verify(secret, body []byte, signature string) bool {
+	digest, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// Sender posts payloads to webhooks, retrying deliveries that fail for reasons that may pass
+type Sender struct {
+	// Client defaults to a client with a 10 second timeout
+	Client *http.Client
+	// Attempts is how often a delivery is tried; defaults to 3
+	Attempts int
+	// Backoff is the wait before the first retry, doubled for every further retry; defaults to 2s
+	Backoff time.Duration
+}
+
+// Send posts payload to url, signed with secret unless secret is empty. Network errors, 429 and
+// 5xx responses are retried; other responses outside 2xx fail the delivery immediately.
+func (s *Sender) Send(ctx context.Context, url string, secret []byte, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	httpClient := s.Client
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	attempts := s.Attempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+	backoff := s.Backoff
+	if backoff <= 0 {
+		backoff = 2 * time.Second
+	}
+
+	for attempt := 1; ; attempt++ {
+		retry, err := s.post(ctx, httpClient, url, secret, payload, body)
+		if err == nil || !retry || attempt == attempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (last attempt: %v)", ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post makes one delivery attempt and reports whether a failure is worth retrying
+func (s *Sender) post(ctx context.Context, httpClient *http.Client, url string, secret []byte, payload Payload, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "automotive-dev-operator-webhook")
+	req.Header.Set(EventHeader, payload.Event)
+	req.Header.Set(DeliveryHeader, payload.DeliveryID)
+	if len(secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(secret, body))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook %s answered %s", url, resp.Status)
+}
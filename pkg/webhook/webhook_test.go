@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/centos-automotive-suite/automotive-dev-operator/pkg/plugin"
+)
+
+var _ = Describe("Sender", func() {
+	secret := []byte("s3cret")
+	payload := Payload{
+		Event:         EventPhaseChanged,
+		DeliveryID:    "d-1",
+		Timestamp:     time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+		PreviousPhase: "Building",
+		Build:         plugin.Build{Name: "nightly", Namespace: "builds", Phase: "Completed"},
+		Artifact:      &plugin.Artifact{FileName: "nightly.raw.gz", SizeBytes: 42},
+	}
+	sender := &Sender{Backoff: time.Millisecond}
+
+	It("should post a signed payload", func() {
+		var got Payload
+		var headers http.Header
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			headers = r.Header
+			Expect(Verify(secret, body, r.Header.Get(SignatureHeader))).To(BeTrue())
+			Expect(Verify([]byte("other"), body, r.Header.Get(SignatureHeader))).To(BeFalse())
+			Expect(json.Unmarshal(body, &got)).To(Succeed())
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer srv.Close()
+
+		Expect(sender.Send(context.Background(), srv.URL, secret, payload)).To(Succeed())
+		Expect(got).To(Equal(payload))
+		Expect(headers.Get(EventHeader)).To(Equal(EventPhaseChanged))
+		Expect(headers.Get(DeliveryHeader)).To(Equal("d-1"))
+	})
+
+	It("should not sign without a secret", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get(SignatureHeader)).To(BeEmpty())
+		}))
+		defer srv.Close()
+		Expect(sender.Send(context.Background(), srv.URL, nil, payload)).To(Succeed())
+	})
+
+	It("should retry server errors but not client errors", func() {
+		var calls atomic.Int32
+		flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}))
+		defer flaky.Close()
+		Expect(sender.Send(context.Background(), flaky.URL, secret, payload)).To(Succeed())
+		Expect(calls.Load()).To(Equal(int32(3)))
+
+		calls.Store(0)
+		rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer rejecting.Close()
+		Expect(sender.Send(context.Background(), rejecting.URL, secret, payload)).To(MatchError(ContainSubstring("400")))
+		Expect(calls.Load()).To(Equal(int32(1)))
+	})
+
+	It("should reject malformed signatures", func() {
+		Expect(Verify(secret, []byte("{}"), "")).To(BeFalse())
+		Expect(Verify(secret, []byte("{}"), "sha256=zz")).To(BeFalse())
+		Expect(Verify(secret, []byte("{}"), Sign(secret, []byte("{}")))).To(BeTrue())
+	})
+})